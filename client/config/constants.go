@@ -29,6 +29,8 @@ const (
 	DefaultPerPeerDownloadLimit = 512 * unit.MB
 	DefaultTotalDownloadLimit   = 1024 * unit.MB
 	DefaultUploadLimit          = 1024 * unit.MB
+	DefaultPeerUploadLimitFloor = 10 * unit.MB
+	DefaultPeerUploadBurst      = 4 * unit.MB
 	DefaultMinRate              = 20 * unit.MB
 )
 
@@ -39,6 +41,11 @@ const (
 	DefaultDaemonAliveTime = 5 * time.Minute
 	DefaultScheduleTimeout = 5 * time.Minute
 
+	DefaultSchedulerRegisterTimeout = 2 * time.Minute
+	DefaultSchedulerReportTimeout   = 2 * time.Minute
+	DefaultSchedulerLeaveTimeout    = 2 * time.Minute
+	DefaultSchedulerConnectTimeout  = 30 * time.Second
+
 	DefaultSchedulerIP   = "127.0.0.1"
 	DefaultSchedulerPort = 8002
 
@@ -46,12 +53,33 @@ const (
 	DefaultObjectMaxReplicas          = 3
 )
 
+// Piece size of object storage.
+const (
+	// MinObjectStoragePieceSize is the minimum piece size of object storage.
+	MinObjectStoragePieceSize = 1 * unit.MB
+
+	// MaxObjectStoragePieceSize is the maximum piece size of object storage.
+	MaxObjectStoragePieceSize = 64 * unit.MB
+)
+
 // Store strategy.
 const (
 	SimpleLocalTaskStoreStrategy  = StoreStrategy("io.d7y.storage.v2.simple")
 	AdvanceLocalTaskStoreStrategy = StoreStrategy("io.d7y.storage.v2.advance")
 )
 
+// Seed peer selection strategy of object storage.
+const (
+	// AllSeedPeerSelection imports object to all seed peers until max replicas succeed.
+	AllSeedPeerSelection = SeedPeerSelection("all")
+
+	// RoundRobinSeedPeerSelection imports object to max replicas seed peers in turn.
+	RoundRobinSeedPeerSelection = SeedPeerSelection("round-robin")
+
+	// NearestSeedPeerSelection imports object to max replicas seed peers nearest to the host by idc and location.
+	NearestSeedPeerSelection = SeedPeerSelection("nearest")
+)
+
 // Dfcache subcommand names.
 const (
 	CmdStat   = "stat"
@@ -84,6 +112,12 @@ var (
 	DefaultCertValidityPeriod = 180 * 24 * time.Hour
 )
 
+var (
+	// DefaultManifestIdentityHeaders is the default identity headers of the registry manifest url, the
+	// manifest list and the single manifest of the same reference are negotiated by Accept.
+	DefaultManifestIdentityHeaders = []string{"Accept"}
+)
+
 var (
 	// DefaultAnnouncerSchedulerInterval is default interface of announcing scheduler.
 	DefaultAnnouncerSchedulerInterval = 30 * time.Second
@@ -92,6 +126,20 @@ var (
 const (
 	// DefaultProbeInterval is the default interval of probing host.
 	DefaultProbeInterval = 20 * time.Minute
+
+	// DefaultParentProbeInterval is the default interval of probing the same parent host.
+	DefaultParentProbeInterval = 5 * time.Minute
+
+	// DefaultParentProbeTimeout is the default timeout of probing a parent host.
+	DefaultParentProbeTimeout = 1 * time.Second
+
+	// DefaultParentProbeConcurrency is the default number of concurrent parent probes.
+	DefaultParentProbeConcurrency = 8
+)
+
+const (
+	// DefaultPieceCompressionLevel is the default compression level of pieces, it is the fastest level.
+	DefaultPieceCompressionLevel = 1
 )
 
 const (
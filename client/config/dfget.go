@@ -18,7 +18,9 @@
 package config
 
 import (
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -28,13 +30,15 @@ import (
 	"syscall"
 	"time"
 
-	"d7y.io/dragonfly/v2/client/util"
+	"golang.org/x/time/rate"
+
 	"d7y.io/dragonfly/v2/cmd/dependency/base"
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/net/url"
 	"d7y.io/dragonfly/v2/pkg/os/user"
 	pkgstrings "d7y.io/dragonfly/v2/pkg/strings"
+	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/pkg/unit"
 )
 
@@ -64,6 +68,13 @@ type ClientOption struct {
 	// DigestValue indicates digest value
 	DigestValue string `yaml:"digestValue,omitempty" mapstructure:"digestValue,omitempty"`
 
+	// SkipVerify indicates whether to skip verifying the digest of the output file after downloading,
+	// the verification reads the whole output file again which may be slow for very large files.
+	SkipVerify bool `yaml:"skipVerify,omitempty" mapstructure:"skip-verify,omitempty"`
+
+	// KeepCorrupted indicates whether to keep the output file when its digest is not matched.
+	KeepCorrupted bool `yaml:"keepCorrupted,omitempty" mapstructure:"keep-corrupted,omitempty"`
+
 	// Tag identify download task, it is available merely when md5 param not exist.
 	Tag string `yaml:"tag,omitempty" mapstructure:"tag,omitempty"`
 
@@ -88,12 +99,28 @@ type ClientOption struct {
 	// eg: --header='Accept: *' --header='Host: abc'.
 	Header []string `yaml:"header,omitempty" mapstructure:"header,omitempty"`
 
+	// IdentityHeaders are the names of the headers whose values are folded into the task id,
+	// in this way, the variants of the same url, e.g. negotiated by Accept, are different tasks.
+	// eg: --identity-header=Accept.
+	IdentityHeaders []string `yaml:"identityHeaders,omitempty" mapstructure:"identity-header,omitempty"`
+
 	// DisableBackSource indicates whether to not back source to download when p2p fails.
 	DisableBackSource bool `yaml:"disableBackSource,omitempty" mapstructure:"disable-back-source,omitempty"`
 
+	// PreferSeed indicates whether the scheduler prefers the seed peers to the normal peers as parents,
+	// for the low latency of the first byte.
+	PreferSeed bool `yaml:"preferSeed,omitempty" mapstructure:"prefer-seed,omitempty"`
+
 	// Insecure indicates whether skip secure verify when supernode interact with the source.
 	Insecure bool `yaml:"insecure,omitempty" mapstructure:"insecure,omitempty"`
 
+	// ClientCert is the PEM encoded client certificate file presented to the source
+	// which requires client certificate, it is only used for the requests of this task.
+	ClientCert string `yaml:"clientCert,omitempty" mapstructure:"client-cert,omitempty"`
+
+	// ClientKey is the PEM encoded private key file of ClientCert.
+	ClientKey string `yaml:"clientKey,omitempty" mapstructure:"client-key,omitempty"`
+
 	// ShowProgress shows progress bar, it's conflict with `--console`.
 	ShowProgress bool `yaml:"show-progress,omitempty" mapstructure:"show-progress,omitempty"`
 
@@ -118,7 +145,7 @@ type ClientOption struct {
 	// WorkHome is working directory of dfget.
 	WorkHome string `yaml:"workHome,omitempty" mapstructure:"workHome,omitempty"`
 
-	RateLimit util.RateLimit `yaml:"rateLimit,omitempty" mapstructure:"rateLimit,omitempty"`
+	RateLimit types.RateLimit `yaml:"rateLimit,omitempty" mapstructure:"rateLimit,omitempty"`
 
 	// Config file paths,
 	// default:["/etc/dragonfly/dfget.yaml","/etc/dragonfly.conf"].
@@ -147,6 +174,9 @@ type ClientOption struct {
 
 	// Range stands download range for url, like: 0-9, will download 10 bytes from 0 to 9 ([0:9])
 	Range string `yaml:"range,omitempty" mapstructure:"range,omitempty"`
+
+	// DryRun resolves the task of url and prints the download plan without downloading.
+	DryRun bool `yaml:"dryRun,omitempty" mapstructure:"dry-run,omitempty"`
 }
 
 func NewDfgetConfig() *ClientOption {
@@ -178,10 +208,18 @@ func (cfg *ClientOption) Validate() error {
 		return fmt.Errorf("output %s: %w", err.Error(), dferrors.ErrInvalidHeader)
 	}
 
-	if int64(cfg.RateLimit.Limit) < DefaultMinRate.ToNumber() {
+	if cfg.RateLimit.Limit < rate.Limit(DefaultMinRate) {
 		return fmt.Errorf("rate limit must be greater than %s: %w", DefaultMinRate.String(), dferrors.ErrInvalidArgument)
 	}
 
+	if err := cfg.checkClientCertificate(); err != nil {
+		return fmt.Errorf("client certificate %s: %w", err.Error(), dferrors.ErrInvalidArgument)
+	}
+
+	if cfg.DryRun && cfg.Recursive {
+		return fmt.Errorf("dry run does not support recursive download: %w", dferrors.ErrInvalidArgument)
+	}
+
 	return nil
 }
 
@@ -240,6 +278,23 @@ func (cfg *ClientOption) checkHeader() error {
 	return nil
 }
 
+// checkClientCertificate is for checking the client certificate and private key.
+func (cfg *ClientOption) checkClientCertificate() error {
+	if cfg.ClientCert == "" && cfg.ClientKey == "" {
+		return nil
+	}
+
+	if cfg.ClientCert == "" || cfg.ClientKey == "" {
+		return errors.New("requires both cert and key")
+	}
+
+	if _, err := tls.LoadX509KeyPair(cfg.ClientCert, cfg.ClientKey); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // This function must be called after checkURL
 func (cfg *ClientOption) checkOutput() error {
 	if !filepath.IsAbs(cfg.Output) {
@@ -21,7 +21,7 @@ package config
 import (
 	"golang.org/x/time/rate"
 
-	"d7y.io/dragonfly/v2/client/util"
+	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/pkg/unit"
 )
 
@@ -30,7 +30,7 @@ var dfgetConfig = ClientOption{
 	Output:        "",
 	Timeout:       0,
 	BenchmarkRate: 128 * unit.KB,
-	RateLimit: util.RateLimit{
+	RateLimit: types.RateLimit{
 		Limit: rate.Limit(DefaultTotalDownloadLimit),
 	},
 	Md5:               "",
@@ -21,14 +21,14 @@ package config
 import (
 	"golang.org/x/time/rate"
 
-	"d7y.io/dragonfly/v2/client/util"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 var dfgetConfig = ClientOption{
 	URL:     "",
 	Output:  "",
 	Timeout: 0,
-	RateLimit: util.RateLimit{
+	RateLimit: types.RateLimit{
 		Limit: rate.Limit(DefaultTotalDownloadLimit),
 	},
 	Md5:               "",
@@ -24,8 +24,9 @@ import (
 
 	testifyassert "github.com/stretchr/testify/assert"
 
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/cmd/dependency/base"
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 func TestDfgetConfig_Validate(t *testing.T) {
@@ -45,7 +46,7 @@ func TestDfgetConfig_Validate(t *testing.T) {
 					"Accept: *",
 					"Host: abc",
 				},
-				RateLimit: util.RateLimit{Limit: 20971520},
+				RateLimit: types.RateLimit{Limit: 20971520},
 			},
 			expect: func(t *testing.T, err error) {
 				assert := testifyassert.New(t)
@@ -123,13 +124,54 @@ func TestDfgetConfig_Validate(t *testing.T) {
 			cfg: &ClientOption{
 				URL:       "http://path",
 				Output:    "/tmp/df/test",
-				RateLimit: util.RateLimit{Limit: 20971519},
+				RateLimit: types.RateLimit{Limit: 20971519},
 			},
 			expect: func(t *testing.T, err error) {
 				assert := testifyassert.New(t)
 				assert.EqualError(err, "rate limit must be greater than 20.0MB: invalid argument")
 			},
 		},
+		{
+			name: "client certificate requires both cert and key",
+			cfg: &ClientOption{
+				URL:        "http://path",
+				Output:     "/tmp/df/test",
+				RateLimit:  types.RateLimit{Limit: 20971520},
+				ClientCert: "/etc/dragonfly/client.crt",
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "client certificate requires both cert and key: invalid argument")
+			},
+		},
+		{
+			name: "client certificate not found",
+			cfg: &ClientOption{
+				URL:        "http://path",
+				Output:     "/tmp/df/test",
+				RateLimit:  types.RateLimit{Limit: 20971520},
+				ClientCert: "/tmp/df/foo.crt",
+				ClientKey:  "/tmp/df/foo.key",
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.ErrorIs(err, dferrors.ErrInvalidArgument)
+			},
+		},
+		{
+			name: "dry run does not support recursive download",
+			cfg: &ClientOption{
+				URL:       "http://path",
+				Output:    "/tmp/df/test",
+				RateLimit: types.RateLimit{Limit: 20971520},
+				DryRun:    true,
+				Recursive: true,
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "dry run does not support recursive download: invalid argument")
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -20,6 +20,7 @@ package config
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc/credentials"
@@ -27,6 +28,7 @@ import (
 
 	managerv1 "d7y.io/api/v2/pkg/apis/manager/v1"
 
+	"d7y.io/dragonfly/v2/pkg/featureflag"
 	managerclient "d7y.io/dragonfly/v2/pkg/rpc/manager/client"
 )
 
@@ -47,6 +49,7 @@ type DynconfigData struct {
 	SeedPeers     []*managerv1.SeedPeer
 	Schedulers    []*managerv1.Scheduler
 	ObjectStorage *managerv1.ObjectStorage
+	BucketACLs    []*BucketACL
 }
 
 type Dynconfig interface {
@@ -68,6 +71,13 @@ type Dynconfig interface {
 	// Get the dynamic config.
 	Get() (*DynconfigData, error)
 
+	// GetFeatureFlag returns the feature flag forwarded by the scheduler, it is the
+	// default value of the flag if the scheduler does not forward the flag.
+	GetFeatureFlag(name string) featureflag.Value
+
+	// SetFeatureFlags replaces the feature flags forwarded by the scheduler.
+	SetFeatureFlags(featureflag.Flags)
+
 	// Refresh refreshes dynconfig in cache.
 	Refresh() error
 
@@ -98,6 +108,26 @@ type dynconfig struct {
 	transportCredentials credentials.TransportCredentials
 }
 
+// featureFlags stores the feature flags forwarded by the scheduler, it is shared by the dynconfig implementations.
+type featureFlags struct {
+	mu    sync.RWMutex
+	flags featureflag.Flags
+}
+
+// GetFeatureFlag returns the feature flag forwarded by the scheduler.
+func (f *featureFlags) GetFeatureFlag(name string) featureflag.Value {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.flags.Get(name)
+}
+
+// SetFeatureFlags replaces the feature flags forwarded by the scheduler.
+func (f *featureFlags) SetFeatureFlags(flags featureflag.Flags) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.flags = flags
+}
+
 type Observer interface {
 	// OnNotify allows an event to be published to interface implementations.
 	OnNotify(*DynconfigData)
@@ -32,6 +32,7 @@ import (
 	managerv1 "d7y.io/api/v2/pkg/apis/manager/v1"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/balancer"
 	healthclient "d7y.io/dragonfly/v2/pkg/rpc/health/client"
 )
 
@@ -40,6 +41,7 @@ var (
 )
 
 type dynconfigLocal struct {
+	featureFlags
 	config               *DaemonOption
 	observers            map[Observer]struct{}
 	done                 chan struct{}
@@ -90,10 +92,10 @@ func (d *dynconfigLocal) GetResolveSchedulerAddrs() ([]resolver.Address, error)
 			continue
 		}
 
-		resolveAddrs = append(resolveAddrs, resolver.Address{
+		resolveAddrs = append(resolveAddrs, balancer.SetWeight(resolver.Address{
 			ServerName: host,
 			Addr:       addr,
-		})
+		}, schedulerAddr.GetWeight()))
 		addrs[addr] = true
 	}
 
@@ -142,7 +144,7 @@ func (d *dynconfigLocal) Deregister(l Observer) {
 
 // Notify publishes new events to listeners.
 func (d *dynconfigLocal) Notify() error {
-	data := &DynconfigData{}
+	data := &DynconfigData{BucketACLs: d.config.ObjectStorage.BucketACLs}
 	for _, schedulerAddr := range d.config.Scheduler.NetAddrs {
 		addr := schedulerAddr.Addr
 		host, port, err := net.SplitHostPort(addr)
@@ -26,6 +26,7 @@ import (
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/resolver"
 
+	"d7y.io/dragonfly/v2/pkg/balancer"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
 )
 
@@ -85,6 +86,27 @@ func TestDynconfigLocal_GetResolveSchedulerAddrs(t *testing.T) {
 				assert.EqualError(err, "can not found available scheduler addresses")
 			},
 		},
+		{
+			name: "get weighted scheduler addrs",
+			config: &DaemonOption{
+				Scheduler: SchedulerOption{
+					NetAddrs: []dfnet.NetAddr{
+						{
+							Addr:   "127.0.0.1:3000",
+							Weight: 3,
+						},
+					},
+				},
+			},
+			expect: func(t *testing.T, dynconfig Dynconfig, config *DaemonOption) {
+				assert := assert.New(t)
+				result, err := dynconfig.GetResolveSchedulerAddrs()
+				assert.NoError(err)
+				assert.Len(result, 1)
+				assert.Equal(result[0].Addr, "127.0.0.1:3000")
+				assert.Equal(balancer.GetWeight(result[0]), 3)
+			},
+		},
 		{
 			name: "config has duplicate scheduler addrs",
 			config: &DaemonOption{
@@ -214,3 +236,29 @@ func TestDynconfigLocal_Notify(t *testing.T) {
 		})
 	}
 }
+
+type fakeObserver struct {
+	data *DynconfigData
+}
+
+func (o *fakeObserver) OnNotify(data *DynconfigData) {
+	o.data = data
+}
+
+func TestDynconfigLocal_NotifyBucketACLs(t *testing.T) {
+	assert := assert.New(t)
+	dynconfig, err := NewDynconfig(LocalSourceType, &DaemonOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	observer := &fakeObserver{}
+	dynconfig.Register(observer)
+	assert.NoError(dynconfig.Notify())
+	assert.Empty(observer.data.BucketACLs)
+
+	acls := []*BucketACL{{Bucket: "foo", Deny: []string{"GET"}}}
+	dynconfig.OnNotify(&DaemonOption{ObjectStorage: ObjectStorageOption{BucketACLs: acls}})
+	assert.NoError(dynconfig.Notify())
+	assert.EqualValues(acls, observer.data.BucketACLs)
+}
@@ -47,6 +47,7 @@ import (
 var cacheFileName = "daemon"
 
 type dynconfigManager struct {
+	featureFlags
 	config *DaemonOption
 	internaldynconfig.Dynconfig[DynconfigData]
 	observers            map[Observer]struct{}
@@ -238,8 +239,11 @@ func (d *dynconfigManager) Notify() error {
 		return err
 	}
 
+	// The access control rules of buckets are always loaded from the local config.
+	notified := *data
+	notified.BucketACLs = d.config.ObjectStorage.BucketACLs
 	for o := range d.observers {
-		o.OnNotify(data)
+		o.OnNotify(&notified)
 	}
 
 	return nil
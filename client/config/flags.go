@@ -23,11 +23,8 @@ import (
 	"strings"
 	"time"
 
-	"github.com/docker/go-units"
-	"golang.org/x/time/rate"
-
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 // SchedulersValue implements the pflag.Value interface.
@@ -47,6 +44,11 @@ func NewNetAddrsValue(n *[]dfnet.NetAddr) *NetAddrsValue {
 func (nv *NetAddrsValue) String() string {
 	var result []string
 	for _, v := range *nv.n {
+		if v.Weight > 0 {
+			result = append(result, fmt.Sprintf("%s=%d", v.Addr, v.Weight))
+			continue
+		}
+
 		result = append(result, v.Addr)
 	}
 
@@ -54,6 +56,11 @@ func (nv *NetAddrsValue) String() string {
 }
 
 func (nv *NetAddrsValue) Set(value string) error {
+	value, weight, err := dfnet.ParseWeightedAddr(value)
+	if err != nil {
+		return err
+	}
+
 	vv := strings.Split(value, ":")
 	if len(vv) > 2 || len(vv) == 0 {
 		return errors.New("invalid net address")
@@ -62,15 +69,17 @@ func (nv *NetAddrsValue) Set(value string) error {
 		value = fmt.Sprintf("%s:%d", value, DefaultSchedulerPort)
 	}
 
-	if !nv.isSet && len(*nv.n) > 0 {
+	// The first set replaces the default addresses, the following sets append to them.
+	if !nv.isSet {
 		*nv.n = []dfnet.NetAddr{}
 		nv.isSet = true
 	}
 
 	*nv.n = append(*nv.n,
 		dfnet.NetAddr{
-			Type: dfnet.TCP,
-			Addr: value,
+			Type:   dfnet.TCP,
+			Addr:   value,
+			Weight: weight,
 		})
 
 	return nil
@@ -81,24 +90,19 @@ func (nv *NetAddrsValue) Type() string {
 }
 
 type RateLimitValue struct {
-	rate *util.RateLimit
+	rate *types.RateLimit
 }
 
-func NewLimitRateValue(rate *util.RateLimit) *RateLimitValue {
+func NewLimitRateValue(rate *types.RateLimit) *RateLimitValue {
 	return &RateLimitValue{rate: rate}
 }
 
 func (r *RateLimitValue) String() string {
-	return fmt.Sprintf("%f", r.rate.Limit)
+	return r.rate.String()
 }
 
 func (r *RateLimitValue) Set(s string) error {
-	bs, err := units.RAMInBytes(s)
-	if err != nil {
-		return err
-	}
-	r.rate.Limit = rate.Limit(bs)
-	return nil
+	return r.rate.Set(s)
 }
 
 func (r *RateLimitValue) Type() string {
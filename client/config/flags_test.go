@@ -0,0 +1,147 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+
+	"d7y.io/dragonfly/v2/pkg/dfnet"
+	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/pkg/unit"
+)
+
+func TestNetAddrsValue_Set(t *testing.T) {
+	tests := []struct {
+		name     string
+		defaults []dfnet.NetAddr
+		values   []string
+		expect   func(t *testing.T, netAddrs []dfnet.NetAddr, nv *NetAddrsValue, err error)
+	}{
+		{
+			name:   "set addrs",
+			values: []string{"127.0.0.1:8002", "127.0.0.2"},
+			expect: func(t *testing.T, netAddrs []dfnet.NetAddr, nv *NetAddrsValue, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(netAddrs, []dfnet.NetAddr{
+					{Type: dfnet.TCP, Addr: "127.0.0.1:8002"},
+					{Type: dfnet.TCP, Addr: "127.0.0.2:8002"},
+				})
+			},
+		},
+		{
+			name:   "set weighted addrs",
+			values: []string{"127.0.0.1:8002=3", "127.0.0.2=2", "127.0.0.3:8002"},
+			expect: func(t *testing.T, netAddrs []dfnet.NetAddr, nv *NetAddrsValue, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(netAddrs, []dfnet.NetAddr{
+					{Type: dfnet.TCP, Addr: "127.0.0.1:8002", Weight: 3},
+					{Type: dfnet.TCP, Addr: "127.0.0.2:8002", Weight: 2},
+					{Type: dfnet.TCP, Addr: "127.0.0.3:8002"},
+				})
+				assert.Equal(nv.String(), "127.0.0.1:8002=3,127.0.0.2:8002=2,127.0.0.3:8002")
+			},
+		},
+		{
+			name:     "set addrs replaces default addrs",
+			defaults: []dfnet.NetAddr{{Type: dfnet.TCP, Addr: "127.0.0.9:8002"}},
+			values:   []string{"127.0.0.1:8002", "127.0.0.2"},
+			expect: func(t *testing.T, netAddrs []dfnet.NetAddr, nv *NetAddrsValue, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(netAddrs, []dfnet.NetAddr{
+					{Type: dfnet.TCP, Addr: "127.0.0.1:8002"},
+					{Type: dfnet.TCP, Addr: "127.0.0.2:8002"},
+				})
+			},
+		},
+		{
+			name:   "set addr with invalid weight",
+			values: []string{"127.0.0.1:8002=-1"},
+			expect: func(t *testing.T, netAddrs []dfnet.NetAddr, nv *NetAddrsValue, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid weight of addr \"127.0.0.1:8002=-1\"")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				netAddrs = tc.defaults
+				err      error
+			)
+			nv := NewNetAddrsValue(&netAddrs)
+			for _, value := range tc.values {
+				if err = nv.Set(value); err != nil {
+					break
+				}
+			}
+
+			tc.expect(t, netAddrs, nv, err)
+		})
+	}
+}
+
+func TestRateLimitValue_Set(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		expect func(t *testing.T, limit types.RateLimit, err error)
+	}{
+		{
+			name:   "set rate limit in previously accepted forms",
+			values: []string{"20M", "20MB", "20m", "20mb", "20 M", "20971520"},
+			expect: func(t *testing.T, limit types.RateLimit, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(rate.Limit(20*unit.MB), limit.Limit)
+			},
+		},
+		{
+			name:   "set rate limit in iec forms",
+			values: []string{"20Mi", "20MiB"},
+			expect: func(t *testing.T, limit types.RateLimit, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(rate.Limit(20*unit.MB), limit.Limit)
+			},
+		},
+		{
+			name:   "set invalid rate limit",
+			values: []string{"20X"},
+			expect: func(t *testing.T, limit types.RateLimit, err error) {
+				assert := assert.New(t)
+				assert.ErrorContains(err, "invalid rate limit")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, value := range tc.values {
+				var limit types.RateLimit
+				err := NewLimitRateValue(&limit).Set(value)
+				tc.expect(t, limit, err)
+			}
+		})
+	}
+}
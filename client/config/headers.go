@@ -37,6 +37,16 @@ const (
 	HeaderDragonflyObjectMetaStorageClass = "X-Dragonfly-Object-Meta-Storage-Class"
 	// HeaderDragonflyObjectOperation is used for object storage operation.
 	HeaderDragonflyObjectOperation = "X-Dragonfly-Object-Operation"
+	// HeaderDragonflyObjectToken is used for access control of object storage buckets.
+	HeaderDragonflyObjectToken = "X-Dragonfly-Object-Token"
+	// HeaderDragonflyCache is used for the cache status of the stream task, it can be hit, partial or miss.
+	HeaderDragonflyCache = "X-Dragonfly-Cache"
+	// HeaderDragonflyTaskID is used for the task id of object storage response.
+	HeaderDragonflyTaskID = "X-Dragonfly-Task-ID"
+	// HeaderDragonflyPieceSize is used for the piece size hint of the task downloaded back-to-source.
+	HeaderDragonflyPieceSize = "X-Dragonfly-Piece-Size"
+	// HeaderDragonflyDigestAlgorithm is used for the digest algorithm of the object uploaded to object storage.
+	HeaderDragonflyDigestAlgorithm = "X-Dragonfly-Digest-Algo"
 	// HeaderDragonflyForwardedFor is used to mark http request forwarded from other peers
 	HeaderDragonflyForwardedFor = "X-Dragonfly-Forwarded-For"
 )
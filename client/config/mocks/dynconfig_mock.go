@@ -14,6 +14,7 @@ import (
 
 	manager "d7y.io/api/v2/pkg/apis/manager/v1"
 	config "d7y.io/dragonfly/v2/client/config"
+	featureflag "d7y.io/dragonfly/v2/pkg/featureflag"
 	gomock "go.uber.org/mock/gomock"
 	resolver "google.golang.org/grpc/resolver"
 )
@@ -68,6 +69,20 @@ func (mr *MockDynconfigMockRecorder) Get() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockDynconfig)(nil).Get))
 }
 
+// GetFeatureFlag mocks base method.
+func (m *MockDynconfig) GetFeatureFlag(arg0 string) featureflag.Value {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeatureFlag", arg0)
+	ret0, _ := ret[0].(featureflag.Value)
+	return ret0
+}
+
+// GetFeatureFlag indicates an expected call of GetFeatureFlag.
+func (mr *MockDynconfigMockRecorder) GetFeatureFlag(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeatureFlag", reflect.TypeOf((*MockDynconfig)(nil).GetFeatureFlag), arg0)
+}
+
 // GetObjectStorage mocks base method.
 func (m *MockDynconfig) GetObjectStorage() (*manager.ObjectStorage, error) {
 	m.ctrl.T.Helper()
@@ -208,6 +223,18 @@ func (mr *MockDynconfigMockRecorder) Serve() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Serve", reflect.TypeOf((*MockDynconfig)(nil).Serve))
 }
 
+// SetFeatureFlags mocks base method.
+func (m *MockDynconfig) SetFeatureFlags(arg0 featureflag.Flags) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetFeatureFlags", arg0)
+}
+
+// SetFeatureFlags indicates an expected call of SetFeatureFlags.
+func (mr *MockDynconfigMockRecorder) SetFeatureFlags(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetFeatureFlags", reflect.TypeOf((*MockDynconfig)(nil).SetFeatureFlags), arg0)
+}
+
 // Stop mocks base method.
 func (m *MockDynconfig) Stop() error {
 	m.ctrl.T.Helper()
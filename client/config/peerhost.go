@@ -20,23 +20,28 @@ package config
 import (
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
 
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/cmd/dependency/base"
+	"d7y.io/dragonfly/v2/pkg/compression"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
+	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/net/ip"
 	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/pkg/unit"
@@ -48,9 +53,11 @@ type DaemonOption struct {
 	// AliveTime indicates alive duration for which daemon keeps no accessing by any uploading and download requests,
 	// after this period daemon will automatically exit
 	// when AliveTime == 0, will run infinitely
-	AliveTime  util.Duration `mapstructure:"aliveTime" yaml:"aliveTime"`
-	GCInterval util.Duration `mapstructure:"gcInterval" yaml:"gcInterval"`
-	Metrics    string        `mapstructure:"metrics" yaml:"metrics"`
+	AliveTime  types.Duration `mapstructure:"aliveTime" yaml:"aliveTime"`
+	GCInterval types.Duration `mapstructure:"gcInterval" yaml:"gcInterval"`
+	Metrics    string         `mapstructure:"metrics" yaml:"metrics"`
+	// ReadyFile is touched when daemon is ready to serve, and is removed when daemon stops.
+	ReadyFile string `mapstructure:"readyFile" yaml:"readyFile"`
 
 	WorkHome      string `mapstructure:"workHome" yaml:"workHome"`
 	WorkHomeMode  uint32 `mapstructure:"workHomeMode" yaml:"workHomeMode"`
@@ -189,10 +196,83 @@ func (p *DaemonOption) Validate() error {
 		return fmt.Errorf("rate limit must be greater than %s", DefaultMinRate.String())
 	}
 
+	if p.Download.PieceCompression.Enable {
+		if p.Download.PieceCompression.Level < compression.MinLevel || p.Download.PieceCompression.Level > compression.MaxLevel {
+			return fmt.Errorf("piece compression level must be between %d and %d", compression.MinLevel, compression.MaxLevel)
+		}
+	}
+
+	for application, name := range p.Download.HeaderProviders {
+		if name == "" {
+			return fmt.Errorf("header provider of application %s is empty", application)
+		}
+	}
+
 	if p.ObjectStorage.Enable {
 		if p.ObjectStorage.MaxReplicas <= 0 {
 			return errors.New("max replicas must be greater than 0")
 		}
+
+		switch p.ObjectStorage.SeedPeerSelection {
+		case "", AllSeedPeerSelection, RoundRobinSeedPeerSelection, NearestSeedPeerSelection:
+		default:
+			return fmt.Errorf("invalid seed peer selection %s", p.ObjectStorage.SeedPeerSelection)
+		}
+
+		switch p.ObjectStorage.DigestAlgorithm {
+		case "", digest.AlgorithmMD5, digest.AlgorithmSHA256:
+		default:
+			return fmt.Errorf("invalid digest algorithm %s of object storage", p.ObjectStorage.DigestAlgorithm)
+		}
+
+		if p.ObjectStorage.PieceSize != 0 &&
+			(p.ObjectStorage.PieceSize < MinObjectStoragePieceSize || p.ObjectStorage.PieceSize > MaxObjectStoragePieceSize) {
+			return fmt.Errorf("piece size must be between %s and %s", MinObjectStoragePieceSize, MaxObjectStoragePieceSize)
+		}
+
+		if p.ObjectStorage.MaxObjectSize < 0 {
+			return errors.New("max object size must be greater than or equal to 0")
+		}
+
+		if p.ObjectStorage.ReadTimeout < 0 || p.ObjectStorage.WriteTimeout < 0 {
+			return errors.New("read timeout and write timeout of object storage must be greater than or equal to 0")
+		}
+
+		for _, acl := range p.ObjectStorage.BucketACLs {
+			if acl.Bucket == "" {
+				return errors.New("bucket acl requires parameter bucket")
+			}
+
+			for _, method := range append(append([]string{}, acl.Allow...), acl.Deny...) {
+				switch strings.ToUpper(method) {
+				case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodPost, http.MethodDelete:
+				default:
+					return fmt.Errorf("invalid method %s in acl of bucket %s", method, acl.Bucket)
+				}
+			}
+		}
+	}
+
+	if p.Proxy != nil {
+		for _, mirror := range append([]*RegistryMirror{p.Proxy.RegistryMirror}, p.Proxy.ExtraRegistryMirrors...) {
+			if mirror == nil {
+				continue
+			}
+
+			for _, rule := range mirror.Rules {
+				if rule.Host == "" {
+					return errors.New("registry mirror rule requires parameter host")
+				}
+
+				if _, err := path.Match(rule.Host, ""); err != nil {
+					return fmt.Errorf("invalid host %s in registry mirror rule", rule.Host)
+				}
+
+				if rule.Remote == nil || rule.Remote.URL == nil {
+					return fmt.Errorf("registry mirror rule of %s requires parameter url", rule.Host)
+				}
+			}
+		}
 	}
 
 	if p.Reload.Interval.Duration > 0 && p.Reload.Interval.Duration < time.Second {
@@ -225,6 +305,20 @@ func (p *DaemonOption) Validate() error {
 		if p.NetworkTopology.Probe.Interval <= 0 {
 			return errors.New("probe requires parameter interval")
 		}
+
+		if p.NetworkTopology.ParentProbe.Enable {
+			if p.NetworkTopology.ParentProbe.Interval <= 0 {
+				return errors.New("parentProbe requires parameter interval")
+			}
+
+			if p.NetworkTopology.ParentProbe.Timeout <= 0 {
+				return errors.New("parentProbe requires parameter timeout")
+			}
+
+			if p.NetworkTopology.ParentProbe.Concurrency <= 0 {
+				return errors.New("parentProbe requires parameter concurrency")
+			}
+		}
 	}
 
 	return nil
@@ -265,10 +359,31 @@ type SchedulerOption struct {
 	Manager ManagerOption `mapstructure:"manager" yaml:"manager"`
 	// NetAddrs is scheduler addresses.
 	NetAddrs []dfnet.NetAddr `mapstructure:"netAddrs" yaml:"netAddrs"`
+	// FallbackNetAddrs is static scheduler addresses used when no scheduler can be resolved dynamically.
+	FallbackNetAddrs []dfnet.NetAddr `mapstructure:"fallbackNetAddrs" yaml:"fallbackNetAddrs"`
 	// ScheduleTimeout is request timeout.
-	ScheduleTimeout util.Duration `mapstructure:"scheduleTimeout" yaml:"scheduleTimeout"`
+	ScheduleTimeout types.Duration `mapstructure:"scheduleTimeout" yaml:"scheduleTimeout"`
 	// DisableAutoBackSource indicates not back source normally, only scheduler says back source.
 	DisableAutoBackSource bool `mapstructure:"disableAutoBackSource" yaml:"disableAutoBackSource"`
+	// Timeouts is the deadlines of the requests to scheduler.
+	Timeouts SchedulerTimeoutOption `mapstructure:"timeouts" yaml:"timeouts"`
+	// GzipCompression is to compress the messages of the scheduler client with gzip,
+	// it reduces the bandwidth when the scheduler is in another region.
+	GzipCompression bool `mapstructure:"gzipCompression" yaml:"gzipCompression"`
+}
+
+// SchedulerTimeoutOption is the deadlines of the requests to scheduler, they are applied
+// only when the context of the request has no deadline, and the zero value uses the default.
+type SchedulerTimeoutOption struct {
+	// Register is the timeout of registering peer task.
+	Register time.Duration `mapstructure:"register" yaml:"register"`
+	// Report is the timeout of reporting the result of peer.
+	Report time.Duration `mapstructure:"report" yaml:"report"`
+	// Leave is the timeout of leaving task and host.
+	Leave time.Duration `mapstructure:"leave" yaml:"leave"`
+	// Connect is the timeout of establishing the stream of reporting piece results,
+	// it does not limit the lifetime of the stream.
+	Connect time.Duration `mapstructure:"connect" yaml:"connect"`
 }
 
 type ManagerOption struct {
@@ -280,6 +395,8 @@ type ManagerOption struct {
 	RefreshInterval time.Duration `mapstructure:"refreshInterval" yaml:"refreshInterval"`
 	// SeedPeer configuration.
 	SeedPeer SeedPeerOption `mapstructure:"seedPeer" yaml:"seedPeer"`
+	// GzipCompression is to compress the messages of the manager client with gzip.
+	GzipCompression bool `mapstructure:"gzipCompression" yaml:"gzipCompression"`
 }
 
 type SeedPeerOption struct {
@@ -310,8 +427,8 @@ type HostOption struct {
 }
 
 type DownloadOption struct {
-	TotalRateLimit       util.RateLimit    `mapstructure:"totalRateLimit" yaml:"totalRateLimit"`
-	PerPeerRateLimit     util.RateLimit    `mapstructure:"perPeerRateLimit" yaml:"perPeerRateLimit"`
+	TotalRateLimit       types.RateLimit   `mapstructure:"totalRateLimit" yaml:"totalRateLimit"`
+	PerPeerRateLimit     types.RateLimit   `mapstructure:"perPeerRateLimit" yaml:"perPeerRateLimit"`
 	TrafficShaperType    string            `mapstructure:"trafficShaperType" yaml:"trafficShaperType"`
 	PieceDownloadTimeout time.Duration     `mapstructure:"pieceDownloadTimeout" yaml:"pieceDownloadTimeout"`
 	GRPCDialTimeout      time.Duration     `mapstructure:"grpcDialTimeout" yaml:"grpcDialTimeout"`
@@ -330,6 +447,23 @@ type DownloadOption struct {
 
 	RecursiveConcurrent    RecursiveConcurrent `mapstructure:"recursiveConcurrent" yaml:"recursiveConcurrent"`
 	CacheRecursiveMetadata time.Duration       `mapstructure:"cacheRecursiveMetadata" yaml:"cacheRecursiveMetadata"`
+
+	// PieceCompression is the compression option of pieces transferred between peers.
+	PieceCompression PieceCompressionOption `mapstructure:"pieceCompression" yaml:"pieceCompression"`
+
+	// HeaderProviders is the name of the header provider by the application of tasks, the header
+	// provider refreshes the header of back-to-source requests when the source rejects it with 401 or 403.
+	// The header provider is loaded from the plugin d7y-header-plugin-{name}.so in the plugin dir.
+	HeaderProviders map[string]string `mapstructure:"headerProviders" yaml:"headerProviders"`
+}
+
+type PieceCompressionOption struct {
+	// Enable advertises the supported codecs to scheduler, then compresses pieces
+	// uploaded to other peers and accepts compressed pieces from other peers.
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// Level is the compression level, from 1 (fastest) to 4 (best compression),
+	// higher level costs more cpu.
+	Level int `mapstructure:"level" yaml:"level"`
 }
 
 type ResourceClientsOption map[string]any
@@ -346,7 +480,7 @@ type TransportOption struct {
 
 type ConcurrentOption struct {
 	// ThresholdSize indicates the threshold to download pieces concurrently
-	ThresholdSize util.Size `mapstructure:"thresholdSize" yaml:"thresholdSize"`
+	ThresholdSize types.RateLimit `mapstructure:"thresholdSize" yaml:"thresholdSize"`
 	// ThresholdSpeed indicates the threshold download speed to download pieces concurrently
 	ThresholdSpeed unit.Bytes `mapstructure:"thresholdSpeed" yaml:"thresholdSpeed"`
 	// GoroutineCount indicates the concurrent goroutine count for every task
@@ -490,8 +624,21 @@ func (p *ProxyOption) unmarshal(unmarshal func(in []byte, out any) (err error),
 }
 
 type UploadOption struct {
-	ListenOption `yaml:",inline" mapstructure:",squash"`
-	RateLimit    util.RateLimit `mapstructure:"rateLimit" yaml:"rateLimit"`
+	ListenOption  `yaml:",inline" mapstructure:",squash"`
+	RateLimit     types.RateLimit     `mapstructure:"rateLimit" yaml:"rateLimit"`
+	PeerRateLimit PeerRateLimitOption `mapstructure:"peerRateLimit" yaml:"peerRateLimit"`
+}
+
+type PeerRateLimitOption struct {
+	// Enable shares the upload rate limit fairly among the peers downloading from the daemon.
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+	// Floor is the minimum upload rate limit of a single peer.
+	Floor types.RateLimit `mapstructure:"floor" yaml:"floor"`
+	// Ceiling is the maximum upload rate limit of a single peer, zero means no ceiling.
+	// It can be overridden by the scheduler cluster client config.
+	Ceiling types.RateLimit `mapstructure:"ceiling" yaml:"ceiling"`
+	// Burst is the maximum bytes a single peer can upload at once.
+	Burst unit.Bytes `mapstructure:"burst" yaml:"burst"`
 }
 
 type ObjectStorageOption struct {
@@ -503,6 +650,29 @@ type ObjectStorageOption struct {
 	Filter string `mapstructure:"filter" yaml:"filter"`
 	// MaxReplicas is the maximum number of replicas of an object cache in seed peers.
 	MaxReplicas int `mapstructure:"maxReplicas" yaml:"maxReplicas"`
+	// SeedPeerSelection is the strategy of selecting seed peers to import object,
+	// it can be all, round-robin or nearest.
+	SeedPeerSelection SeedPeerSelection `mapstructure:"seedPeerSelection" yaml:"seedPeerSelection"`
+	// PieceSize is the default piece size of the task downloaded by object storage,
+	// it only takes effect when the task is downloaded back-to-source. If it is zero,
+	// the piece size is computed by the content length.
+	PieceSize unit.Bytes `mapstructure:"pieceSize" yaml:"pieceSize"`
+	// DigestAlgorithm is the algorithm of the digest computed for the uploaded object,
+	// it can be md5 or sha256 and is overridden by X-Dragonfly-Digest-Algo header.
+	// If it is empty, md5 is used.
+	DigestAlgorithm string `mapstructure:"digestAlgorithm" yaml:"digestAlgorithm"`
+	// MaxObjectSize is the maximum size of the request body of uploading object, the upload
+	// exceeding it is rejected. If it is zero, the size is not limited.
+	MaxObjectSize unit.Bytes `mapstructure:"maxObjectSize" yaml:"maxObjectSize"`
+	// ReadTimeout is the maximum duration of reading the entire request including the body,
+	// the upload of the slow client is aborted when it expires. If it is zero, there is no timeout.
+	ReadTimeout time.Duration `mapstructure:"readTimeout" yaml:"readTimeout"`
+	// WriteTimeout is the maximum duration of writing the response, it is reset when the
+	// request header is read. If it is zero, there is no timeout.
+	WriteTimeout time.Duration `mapstructure:"writeTimeout" yaml:"writeTimeout"`
+	// BucketACLs are the access control rules of buckets, the request of the bucket
+	// without matched rule is allowed. It is reloaded when the config changes.
+	BucketACLs []*BucketACL `mapstructure:"bucketACLs" yaml:"bucketACLs"`
 	// ListenOption is object storage service listener.
 	ListenOption `yaml:",inline" mapstructure:",squash"`
 }
@@ -529,6 +699,14 @@ type TCPListenOption struct {
 	// Namespace stands the linux net namespace, like /proc/1/ns/net
 	// It's useful for running daemon in pod with ip allocated and listen in host
 	Namespace string `mapstructure:"namespace" yaml:"namespace"`
+
+	// ReusePort sets SO_REUSEADDR and SO_REUSEPORT of the listener where available,
+	// so that the restarted daemon can listen the port while the previous one is exiting.
+	ReusePort bool `mapstructure:"reusePort" yaml:"reusePort"`
+
+	// KeepAlive is the keep-alive period of the accepted connections,
+	// zero means the default period and negative disables keep-alive.
+	KeepAlive types.Duration `mapstructure:"keepAlive" yaml:"keepAlive"`
 }
 
 type TCPListenPortRange struct {
@@ -629,12 +807,19 @@ type StorageOption struct {
 	DataPath string `mapstructure:"dataPath" yaml:"dataPath"`
 	// TaskExpireTime indicates caching duration for which cached file keeps no accessed by any process,
 	// after this period cache file will be gc
-	TaskExpireTime util.Duration `mapstructure:"taskExpireTime" yaml:"taskExpireTime"`
+	TaskExpireTime types.Duration `mapstructure:"taskExpireTime" yaml:"taskExpireTime"`
 	// DiskGCThreshold indicates the threshold to gc the oldest tasks
 	DiskGCThreshold unit.Bytes `mapstructure:"diskGCThreshold" yaml:"diskGCThreshold"`
 	// DiskGCThresholdPercent indicates the threshold to gc the oldest tasks according the disk usage
 	// Eg, DiskGCThresholdPercent=80, when the disk usage is above 80%, start to gc the oldest tasks
 	DiskGCThresholdPercent float64 `mapstructure:"diskGCThresholdPercent" yaml:"diskGCThresholdPercent"`
+	// DataDirMaxBytes indicates the quota of all tasks in data directory, 0 means no quota,
+	// when the quota is exceeded, the least recently used completed tasks are evicted synchronously
+	DataDirMaxBytes unit.Bytes `mapstructure:"dataDirMaxBytes" yaml:"dataDirMaxBytes"`
+	// DataDirLowWatermarkPercent indicates the percent of DataDirMaxBytes the tasks are evicted down to, default is 90
+	DataDirLowWatermarkPercent float64 `mapstructure:"dataDirLowWatermarkPercent" yaml:"dataDirLowWatermarkPercent"`
+	// TaskMaxBytes indicates the quota of one task, 0 means no quota
+	TaskMaxBytes unit.Bytes `mapstructure:"taskMaxBytes" yaml:"taskMaxBytes"`
 	// Multiplex indicates reusing underlying storage for same task id
 	Multiplex     bool          `mapstructure:"multiplex" yaml:"multiplex"`
 	StoreStrategy StoreStrategy `mapstructure:"strategy" yaml:"strategy"`
@@ -647,13 +832,28 @@ type StorageOption struct {
 
 type StoreStrategy string
 
+type SeedPeerSelection string
+
+// BucketACL is the access control rule of the bucket in object storage.
+type BucketACL struct {
+	// Bucket is the name of the bucket, * matches the buckets without their own rule.
+	Bucket string `mapstructure:"bucket" yaml:"bucket"`
+	// Allow is the http methods allowed to access the bucket, all methods are allowed when it is empty.
+	Allow []string `mapstructure:"allow" yaml:"allow"`
+	// Deny is the http methods denied to access the bucket, it takes precedence over allow.
+	Deny []string `mapstructure:"deny" yaml:"deny"`
+	// Tokens are the tokens allowed to access the bucket, the token is passed by
+	// the X-Dragonfly-Object-Token header and is not required when it is empty.
+	Tokens []string `mapstructure:"tokens" yaml:"tokens"`
+}
+
 type HealthOption struct {
 	ListenOption `yaml:",inline" mapstructure:",squash"`
 	Path         string `mapstructure:"path" yaml:"path"`
 }
 
 type ReloadOption struct {
-	Interval util.Duration `mapstructure:"interval" yaml:"interval"`
+	Interval types.Duration `mapstructure:"interval" yaml:"interval"`
 }
 
 type tlsConfigFiles struct {
@@ -726,6 +926,26 @@ type RegistryMirror struct {
 
 	// Whether to use proxies to decide when to use dragonfly
 	UseProxies bool `yaml:"useProxies" mapstructure:"useProxies"`
+
+	// Rules are the mirrors of different registries, the registry of the request is matched
+	// against the rules in order and the first matched rule is used,
+	// if no rule is matched, the request is sent to Remote.
+	Rules []*RegistryMirrorRule `yaml:"rules" mapstructure:"rules"`
+}
+
+// MatchRule returns the first rule matching the registry host, it returns nil if no rule is matched.
+func (r *RegistryMirror) MatchRule(host string) *RegistryMirrorRule {
+	if r == nil || host == "" {
+		return nil
+	}
+
+	for _, rule := range r.Rules {
+		if rule.Match(host) {
+			return rule
+		}
+	}
+
+	return nil
 }
 
 // TLSConfig returns the tls.Config used to communicate with the mirror.
@@ -742,6 +962,67 @@ func (r *RegistryMirror) TLSConfig() *tls.Config {
 	return cfg
 }
 
+// RegistryMirrorRule configures the mirror of the registry.
+type RegistryMirrorRule struct {
+	// Host of the registry, e.g. docker.io, wildcard is supported, e.g. *.gcr.io.
+	Host string `yaml:"host" mapstructure:"host"`
+
+	// Remote url for the registry mirror.
+	Remote *URL `yaml:"url" mapstructure:"url"`
+
+	// Username of the basic auth to the mirror.
+	Username string `yaml:"username" mapstructure:"username"`
+
+	// Password of the basic auth to the mirror.
+	Password string `yaml:"password" mapstructure:"password"`
+
+	// TokenEnv is the name of the environment variable holding the bearer token to the mirror,
+	// it is used when username is empty.
+	TokenEnv string `yaml:"tokenEnv" mapstructure:"tokenEnv"`
+
+	// Optional certificates if the mirror uses self-signed certificates
+	Certs *CertPool `yaml:"certs" mapstructure:"certs"`
+
+	// Whether to ignore certificates errors for the mirror
+	Insecure bool `yaml:"insecure" mapstructure:"insecure"`
+
+	// Request the mirror directly.
+	Direct bool `yaml:"direct" mapstructure:"direct"`
+}
+
+// Match returns whether the rule matches the registry host.
+func (r *RegistryMirrorRule) Match(host string) bool {
+	matched, err := path.Match(strings.ToLower(r.Host), strings.ToLower(host))
+	return err == nil && matched
+}
+
+// Authorization returns the value of the authorization header to the mirror,
+// it returns empty string if the mirror requires no credentials.
+func (r *RegistryMirrorRule) Authorization() string {
+	if r.Username != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(r.Username+":"+r.Password))
+	}
+
+	if r.TokenEnv != "" {
+		if token := os.Getenv(r.TokenEnv); token != "" {
+			return "Bearer " + token
+		}
+	}
+
+	return ""
+}
+
+// TLSConfig returns the tls.Config used to communicate with the mirror.
+func (r *RegistryMirrorRule) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		InsecureSkipVerify: r.Insecure,
+	}
+	if r.Certs != nil {
+		cfg.RootCAs = r.Certs.CertPool
+	}
+	return cfg
+}
+
 // URL is simple wrapper around url.URL to make it unmarshallable from a string.
 type URL struct {
 	*url.URL
@@ -851,6 +1132,10 @@ type ProxyRule struct {
 
 	// Redirect is the host to redirect to, if not empty
 	Redirect string `yaml:"redirect" mapstructure:"redirect"`
+
+	// IdentityHeaders are the names of the request headers whose values are folded into the task id
+	// of the matched url, the registry manifest url uses DefaultManifestIdentityHeaders if it is empty.
+	IdentityHeaders []string `yaml:"identityHeaders" mapstructure:"identityHeaders"`
 }
 
 func NewProxyRule(regx string, useHTTPS bool, direct bool, redirect string) (*ProxyRule, error) {
@@ -965,6 +1250,9 @@ type NetworkTopologyOption struct {
 
 	// Probe is the configuration of probe.
 	Probe ProbeOption `mapstructure:"probe" yaml:"probe"`
+
+	// ParentProbe is the configuration of probing the parents scheduled by the scheduler.
+	ParentProbe ParentProbeOption `mapstructure:"parentProbe" yaml:"parentProbe"`
 }
 
 type ProbeOption struct {
@@ -972,6 +1260,21 @@ type ProbeOption struct {
 	Interval time.Duration `mapstructure:"interval" yaml:"interval"`
 }
 
+type ParentProbeOption struct {
+	// Enable probes the latency of the upload servers of the parents after receiving their pieces,
+	// so the new parents without piece cost history can be ranked by the scheduler.
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+
+	// Interval is the min interval of probing the same parent host.
+	Interval time.Duration `mapstructure:"interval" yaml:"interval"`
+
+	// Timeout is the timeout of probing a parent host.
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+
+	// Concurrency is the max number of concurrent probes.
+	Concurrency int `mapstructure:"concurrency" yaml:"concurrency"`
+}
+
 type PeerExchangeOption struct {
 	// Enable peer exchange service.
 	Enable bool `mapstructure:"enable" yaml:"enable"`
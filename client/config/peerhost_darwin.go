@@ -23,7 +23,7 @@ import (
 
 	"golang.org/x/time/rate"
 
-	"d7y.io/dragonfly/v2/client/util"
+	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/net/fqdn"
 	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/pkg/types"
@@ -31,8 +31,8 @@ import (
 
 var peerHostConfig = func() *DaemonOption {
 	return &DaemonOption{
-		AliveTime:   util.Duration{Duration: DefaultDaemonAliveTime},
-		GCInterval:  util.Duration{Duration: DefaultGCInterval},
+		AliveTime:   types.Duration{Duration: DefaultDaemonAliveTime},
+		GCInterval:  types.Duration{Duration: DefaultGCInterval},
 		KeepStorage: false,
 		Scheduler: SchedulerOption{
 			Manager: ManagerOption{
@@ -47,7 +47,13 @@ var peerHostConfig = func() *DaemonOption {
 					},
 				},
 			},
-			ScheduleTimeout: util.Duration{Duration: DefaultScheduleTimeout},
+			ScheduleTimeout: types.Duration{Duration: DefaultScheduleTimeout},
+			Timeouts: SchedulerTimeoutOption{
+				Register: DefaultSchedulerRegisterTimeout,
+				Report:   DefaultSchedulerReportTimeout,
+				Leave:    DefaultSchedulerLeaveTimeout,
+				Connect:  DefaultSchedulerConnectTimeout,
+			},
 		},
 		Host: HostOption{
 			Hostname: fqdn.FQDNHostname,
@@ -63,10 +69,13 @@ var peerHostConfig = func() *DaemonOption {
 			RecursiveConcurrent: RecursiveConcurrent{
 				GoroutineCount: 32,
 			},
-			TotalRateLimit: util.RateLimit{
+			PieceCompression: PieceCompressionOption{
+				Level: DefaultPieceCompressionLevel,
+			},
+			TotalRateLimit: types.RateLimit{
 				Limit: rate.Limit(DefaultTotalDownloadLimit),
 			},
-			PerPeerRateLimit: util.RateLimit{
+			PerPeerRateLimit: types.RateLimit{
 				Limit: rate.Limit(DefaultPerPeerDownloadLimit),
 			},
 			DownloadGRPC: ListenOption{
@@ -91,9 +100,16 @@ var peerHostConfig = func() *DaemonOption {
 			SplitRunningTasks: false,
 		},
 		Upload: UploadOption{
-			RateLimit: util.RateLimit{
+			RateLimit: types.RateLimit{
 				Limit: rate.Limit(DefaultUploadLimit),
 			},
+			PeerRateLimit: PeerRateLimitOption{
+				Enable: false,
+				Floor: types.RateLimit{
+					Limit: rate.Limit(DefaultPeerUploadLimitFloor),
+				},
+				Burst: DefaultPeerUploadBurst,
+			},
 			ListenOption: ListenOption{
 				Security: SecurityOption{
 					Insecure:  true,
@@ -108,9 +124,11 @@ var peerHostConfig = func() *DaemonOption {
 			},
 		},
 		ObjectStorage: ObjectStorageOption{
-			Enable:      false,
-			Filter:      "Expires&Signature&ns",
-			MaxReplicas: DefaultObjectMaxReplicas,
+			Enable:            false,
+			Filter:            "Expires&Signature&ns",
+			MaxReplicas:       DefaultObjectMaxReplicas,
+			SeedPeerSelection: AllSeedPeerSelection,
+			DigestAlgorithm:   digest.AlgorithmMD5,
 			ListenOption: ListenOption{
 				Security: SecurityOption{
 					Insecure:  true,
@@ -136,7 +154,7 @@ var peerHostConfig = func() *DaemonOption {
 			},
 		},
 		Storage: StorageOption{
-			TaskExpireTime: util.Duration{
+			TaskExpireTime: types.Duration{
 				Duration: DefaultTaskExpireTime,
 			},
 			StoreStrategy:          SimpleLocalTaskStoreStrategy,
@@ -160,7 +178,7 @@ var peerHostConfig = func() *DaemonOption {
 			Path: "/server/ping",
 		},
 		Reload: ReloadOption{
-			Interval: util.Duration{
+			Interval: types.Duration{
 				Duration: time.Minute,
 			},
 		},
@@ -186,6 +204,12 @@ var peerHostConfig = func() *DaemonOption {
 			Probe: ProbeOption{
 				Interval: DefaultProbeInterval,
 			},
+			ParentProbe: ParentProbeOption{
+				Enable:      true,
+				Interval:    DefaultParentProbeInterval,
+				Timeout:     DefaultParentProbeTimeout,
+				Concurrency: DefaultParentProbeConcurrency,
+			},
 		},
 		LogMaxSize:    DefaultLogRotateMaxSize,
 		LogMaxAge:     DefaultLogRotateMaxAge,
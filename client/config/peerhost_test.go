@@ -30,7 +30,6 @@ import (
 	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
 
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/cmd/dependency/base"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
 	"d7y.io/dragonfly/v2/pkg/types"
@@ -74,9 +73,9 @@ port:
 timeout: 1000000000
 `,
 			target: &struct {
-				Timeout util.Duration `yaml:"timeout"`
+				Timeout types.Duration `yaml:"timeout"`
 			}{
-				Timeout: util.Duration{
+				Timeout: types.Duration{
 					Duration: time.Second,
 				},
 			},
@@ -86,9 +85,9 @@ timeout: 1000000000
 timeout: 1s
 `,
 			target: &struct {
-				Timeout util.Duration `yaml:"timeout"`
+				Timeout types.Duration `yaml:"timeout"`
 			}{
-				Timeout: util.Duration{
+				Timeout: types.Duration{
 					Duration: time.Second,
 				},
 			},
@@ -98,9 +97,9 @@ timeout: 1s
 limit: 100Mi
 `,
 			target: &struct {
-				Limit util.RateLimit `yaml:"limit"`
+				Limit types.RateLimit `yaml:"limit"`
 			}{
-				Limit: util.RateLimit{
+				Limit: types.RateLimit{
 					Limit: 100 * 1024 * 1024,
 				},
 			},
@@ -110,9 +109,9 @@ limit: 100Mi
 limit: 2097152
 `,
 			target: &struct {
-				Limit util.RateLimit `yaml:"limit"`
+				Limit types.RateLimit `yaml:"limit"`
 			}{
-				Limit: util.RateLimit{
+				Limit: types.RateLimit{
 					Limit: 2 * 1024 * 1024,
 				},
 			},
@@ -205,8 +204,8 @@ schedulers2:
 		Regx        *Regexp            `yaml:"regx"`
 		Port1       TCPListenPortRange `yaml:"port1"`
 		Port2       TCPListenPortRange `yaml:"port2"`
-		Timeout     util.Duration      `yaml:"timeout"`
-		Limit       util.RateLimit     `yaml:"limit"`
+		Timeout     types.Duration     `yaml:"timeout"`
+		Limit       types.RateLimit    `yaml:"limit"`
 		Type        dfnet.NetworkType  `yaml:"type"`
 		Proxy1      ProxyOption        `yaml:"proxy1"`
 		Proxy2      ProxyOption        `yaml:"proxy2"`
@@ -241,10 +240,10 @@ func TestPeerHostOption_Load(t *testing.T) {
 				ServiceName: "bar",
 			},
 		},
-		AliveTime: util.Duration{
+		AliveTime: types.Duration{
 			Duration: 0,
 		},
-		GCInterval: util.Duration{
+		GCInterval: types.Duration{
 			Duration: 60000000000,
 		},
 		Metrics:       ":8000",
@@ -285,10 +284,17 @@ func TestPeerHostOption_Load(t *testing.T) {
 					Addr: "127.0.0.1:8002",
 				},
 			},
-			ScheduleTimeout: util.Duration{
+			ScheduleTimeout: types.Duration{
 				Duration: 0,
 			},
 			DisableAutoBackSource: true,
+			Timeouts: SchedulerTimeoutOption{
+				Register: time.Minute,
+				Report:   time.Minute,
+				Leave:    30 * time.Second,
+				Connect:  10 * time.Second,
+			},
+			GzipCompression: true,
 		},
 		Host: HostOption{
 			Hostname:    "d7y.io",
@@ -297,10 +303,10 @@ func TestPeerHostOption_Load(t *testing.T) {
 			AdvertiseIP: net.IPv4zero,
 		},
 		Download: DownloadOption{
-			TotalRateLimit: util.RateLimit{
+			TotalRateLimit: types.RateLimit{
 				Limit: 1024 * 1024 * 1024,
 			},
-			PerPeerRateLimit: util.RateLimit{
+			PerPeerRateLimit: types.RateLimit{
 				Limit: 512 * 1024 * 1024,
 			},
 			PieceDownloadTimeout: 30 * time.Second,
@@ -349,7 +355,7 @@ func TestPeerHostOption_Load(t *testing.T) {
 			Prefetch:          true,
 			WatchdogTimeout:   time.Second,
 			Concurrent: &ConcurrentOption{
-				ThresholdSize: util.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1,
 				},
 				ThresholdSpeed: unit.Bytes(1),
@@ -360,7 +366,7 @@ func TestPeerHostOption_Load(t *testing.T) {
 			},
 		},
 		Upload: UploadOption{
-			RateLimit: util.RateLimit{
+			RateLimit: types.RateLimit{
 				Limit: 1024 * 1024 * 1024,
 			},
 			ListenOption: ListenOption{
@@ -377,13 +383,27 @@ func TestPeerHostOption_Load(t *testing.T) {
 						Start: 65002,
 						End:   0,
 					},
+					ReusePort: true,
+					KeepAlive: types.Duration{Duration: 30 * time.Second},
 				},
 			},
 		},
 		ObjectStorage: ObjectStorageOption{
-			Enable:      true,
-			Filter:      "Expires&Signature&ns",
-			MaxReplicas: 3,
+			Enable:          true,
+			Filter:          "Expires&Signature&ns",
+			MaxReplicas:     3,
+			PieceSize:       16 * unit.MB,
+			DigestAlgorithm: "sha256",
+			MaxObjectSize:   unit.GB,
+			ReadTimeout:     10 * time.Minute,
+			WriteTimeout:    10 * time.Minute,
+			BucketACLs: []*BucketACL{
+				{
+					Bucket: "foo",
+					Allow:  []string{"GET", "HEAD"},
+					Tokens: []string{"bar"},
+				},
+			},
 			ListenOption: ListenOption{
 				Security: SecurityOption{
 					Insecure:  true,
@@ -403,13 +423,16 @@ func TestPeerHostOption_Load(t *testing.T) {
 		},
 		Storage: StorageOption{
 			DataPath: "/tmp/storage/data",
-			TaskExpireTime: util.Duration{
+			TaskExpireTime: types.Duration{
 				Duration: 180000000000,
 			},
-			StoreStrategy:          StoreStrategy("io.d7y.storage.v2.simple"),
-			DiskGCThreshold:        60 * unit.MB,
-			DiskGCThresholdPercent: 0.6,
-			Multiplex:              true,
+			StoreStrategy:              StoreStrategy("io.d7y.storage.v2.simple"),
+			DiskGCThreshold:            60 * unit.MB,
+			DiskGCThresholdPercent:     0.6,
+			DataDirMaxBytes:            100 * unit.MB,
+			DataDirLowWatermarkPercent: 80,
+			TaskMaxBytes:               10 * unit.MB,
+			Multiplex:                  true,
 		},
 		Health: &HealthOption{
 			Path: "/health",
@@ -450,6 +473,31 @@ func TestPeerHostOption_Load(t *testing.T) {
 				UseProxies:    true,
 				Insecure:      true,
 				Direct:        false,
+				Rules: []*RegistryMirrorRule{
+					{
+						Host: "docker.io",
+						Remote: &URL{
+							&url.URL{
+								Host:   "docker.mirror.d7y.io",
+								Scheme: "https",
+							},
+						},
+						Username: "foo",
+						Password: "bar",
+						Insecure: true,
+					},
+					{
+						Host: "*.gcr.io",
+						Remote: &URL{
+							&url.URL{
+								Host:   "gcr.mirror.d7y.io",
+								Scheme: "https",
+							},
+						},
+						TokenEnv: "GCR_MIRROR_TOKEN",
+						Direct:   true,
+					},
+				},
 			},
 			WhiteList: []*WhiteList{
 				{
@@ -463,10 +511,11 @@ func TestPeerHostOption_Load(t *testing.T) {
 			},
 			ProxyRules: []*ProxyRule{
 				{
-					Regx:     proxyExp,
-					UseHTTPS: false,
-					Direct:   false,
-					Redirect: "d7y.io",
+					Regx:            proxyExp,
+					UseHTTPS:        false,
+					Direct:          false,
+					Redirect:        "d7y.io",
+					IdentityHeaders: []string{"Accept"},
 				},
 			},
 			HijackHTTPS: &HijackConfig{
@@ -497,7 +546,7 @@ func TestPeerHostOption_Load(t *testing.T) {
 			},
 		},
 		Reload: ReloadOption{
-			Interval: util.Duration{
+			Interval: types.Duration{
 				Duration: 180000000000,
 			},
 		},
@@ -523,6 +572,12 @@ func TestPeerHostOption_Load(t *testing.T) {
 			Probe: ProbeOption{
 				Interval: 20 * time.Minute,
 			},
+			ParentProbe: ParentProbeOption{
+				Enable:      true,
+				Interval:    5 * time.Minute,
+				Timeout:     1 * time.Second,
+				Concurrency: 8,
+			},
 		},
 	}
 
@@ -653,6 +708,195 @@ func TestPeerHostOption_Validate(t *testing.T) {
 				assert.EqualError(err, "max replicas must be greater than 0")
 			},
 		},
+		{
+			name:   "digest algorithm of object storage is invalid",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.ObjectStorage.Enable = true
+				cfg.ObjectStorage.MaxReplicas = 1
+				cfg.ObjectStorage.DigestAlgorithm = "sha1"
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid digest algorithm sha1 of object storage")
+			},
+		},
+		{
+			name:   "piece size of object storage is invalid",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.ObjectStorage.Enable = true
+				cfg.ObjectStorage.MaxReplicas = 1
+				cfg.ObjectStorage.PieceSize = 128 * unit.MB
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				msg := fmt.Sprintf("piece size must be between %s and %s", MinObjectStoragePieceSize, MaxObjectStoragePieceSize)
+				assert.EqualError(err, msg)
+			},
+		},
+		{
+			name:   "header provider is empty",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.Download.HeaderProviders = map[string]string{"foo": ""}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "header provider of application foo is empty")
+			},
+		},
+		{
+			name:   "max object size of object storage is invalid",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.ObjectStorage.Enable = true
+				cfg.ObjectStorage.MaxReplicas = 1
+				cfg.ObjectStorage.MaxObjectSize = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "max object size must be greater than or equal to 0")
+			},
+		},
+		{
+			name:   "read timeout of object storage is invalid",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.ObjectStorage.Enable = true
+				cfg.ObjectStorage.MaxReplicas = 1
+				cfg.ObjectStorage.ReadTimeout = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "read timeout and write timeout of object storage must be greater than or equal to 0")
+			},
+		},
+		{
+			name:   "bucket acl requires parameter bucket",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.ObjectStorage.Enable = true
+				cfg.ObjectStorage.MaxReplicas = 1
+				cfg.ObjectStorage.BucketACLs = []*BucketACL{{Allow: []string{"GET"}}}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "bucket acl requires parameter bucket")
+			},
+		},
+		{
+			name:   "invalid method in bucket acl",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.ObjectStorage.Enable = true
+				cfg.ObjectStorage.MaxReplicas = 1
+				cfg.ObjectStorage.BucketACLs = []*BucketACL{{Bucket: "foo", Deny: []string{"PATCH"}}}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid method PATCH in acl of bucket foo")
+			},
+		},
+		{
+			name:   "registry mirror rule requires parameter host",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.Proxy.RegistryMirror = &RegistryMirror{
+					Rules: []*RegistryMirrorRule{{Remote: &URL{&url.URL{Scheme: "https", Host: "mirror.d7y.io"}}}},
+				}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "registry mirror rule requires parameter host")
+			},
+		},
+		{
+			name:   "invalid host in registry mirror rule",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.Proxy.RegistryMirror = &RegistryMirror{
+					Rules: []*RegistryMirrorRule{{Host: "[docker.io", Remote: &URL{&url.URL{Scheme: "https", Host: "mirror.d7y.io"}}}},
+				}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid host [docker.io in registry mirror rule")
+			},
+		},
+		{
+			name:   "registry mirror rule requires parameter url",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.Proxy.RegistryMirror = &RegistryMirror{
+					Rules: []*RegistryMirrorRule{{Host: "docker.io"}},
+				}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "registry mirror rule of docker.io requires parameter url")
+			},
+		},
 		{
 			name:   "reload interval too short, must great than 1 second",
 			config: NewDaemonConfig(),
@@ -781,6 +1025,60 @@ func TestPeerHostOption_Validate(t *testing.T) {
 				assert.EqualError(err, "probe requires parameter interval")
 			},
 		},
+		{
+			name:   "parentProbe requires parameter interval",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.NetworkTopology.Enable = true
+				cfg.NetworkTopology.ParentProbe.Interval = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "parentProbe requires parameter interval")
+			},
+		},
+		{
+			name:   "parentProbe requires parameter timeout",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.NetworkTopology.Enable = true
+				cfg.NetworkTopology.ParentProbe.Timeout = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "parentProbe requires parameter timeout")
+			},
+		},
+		{
+			name:   "parentProbe requires parameter concurrency",
+			config: NewDaemonConfig(),
+			mock: func(cfg *DaemonConfig) {
+				cfg.Scheduler.NetAddrs = []dfnet.NetAddr{
+					{
+						Type: dfnet.TCP,
+						Addr: "127.0.0.1:8002",
+					},
+				}
+				cfg.NetworkTopology.Enable = true
+				cfg.NetworkTopology.ParentProbe.Concurrency = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "parentProbe requires parameter concurrency")
+			},
+		},
 	}
 
 	for _, tc := range tests {
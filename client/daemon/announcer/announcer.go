@@ -35,6 +35,7 @@ import (
 
 	"d7y.io/dragonfly/v2/client/config"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	managerclient "d7y.io/dragonfly/v2/pkg/rpc/manager/client"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
 	"d7y.io/dragonfly/v2/pkg/types"
@@ -60,6 +61,7 @@ type announcer struct {
 	daemonObjectStoragePort int32
 	schedulerClient         schedulerclient.V1
 	managerClient           managerclient.V1
+	startedAt               time.Time
 	done                    chan struct{}
 }
 
@@ -89,6 +91,7 @@ func New(cfg *config.DaemonOption, dynconfig config.Dynconfig, hostID string, da
 		daemonPort:         daemonPort,
 		daemonDownloadPort: daemonDownloadPort,
 		schedulerClient:    schedulerClient,
+		startedAt:          time.Now(),
 		done:               make(chan struct{}),
 	}
 
@@ -129,7 +132,8 @@ func (a *announcer) announceToScheduler() error {
 		return err
 	}
 
-	if err := a.schedulerClient.AnnounceHost(context.Background(), req); err != nil {
+	// The start time and the uptime let the scheduler reap the peers created before the daemon restarted.
+	if err := a.schedulerClient.AnnounceHost(rpc.AppendHostStartedAt(context.Background(), a.startedAt), req); err != nil {
 		logger.Errorf("announce for the first time failed: %s", err.Error())
 	}
 
@@ -144,7 +148,7 @@ func (a *announcer) announceToScheduler() error {
 				break
 			}
 
-			if err := a.schedulerClient.AnnounceHost(context.Background(), req); err != nil {
+			if err := a.schedulerClient.AnnounceHost(rpc.AppendHostStartedAt(context.Background(), a.startedAt), req); err != nil {
 				logger.Error(err)
 				break
 			}
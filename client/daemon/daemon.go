@@ -34,11 +34,13 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/johanbrandhorst/certify"
 	"github.com/spf13/viper"
+	"go.uber.org/atomic"
 	"golang.org/x/sync/errgroup"
 	"golang.org/x/time/rate"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/resolver"
 	zapadapter "logur.dev/adapter/zap"
 
 	"d7y.io/api/v2/pkg/apis/dfdaemon/v1"
@@ -61,12 +63,15 @@ import (
 	"d7y.io/dragonfly/v2/cmd/dependency"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	internaldynconfig "d7y.io/dragonfly/v2/internal/dynconfig"
+	pkgbalancer "d7y.io/dragonfly/v2/pkg/balancer"
 	"d7y.io/dragonfly/v2/pkg/cache"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
 	"d7y.io/dragonfly/v2/pkg/dfpath"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/issuer"
 	"d7y.io/dragonfly/v2/pkg/net/ip"
+	"d7y.io/dragonfly/v2/pkg/net/listen"
+	pkgresolver "d7y.io/dragonfly/v2/pkg/resolver"
 	"d7y.io/dragonfly/v2/pkg/rpc"
 	managerclient "d7y.io/dragonfly/v2/pkg/rpc/manager/client"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
@@ -76,6 +81,16 @@ import (
 	"d7y.io/dragonfly/v2/pkg/types"
 )
 
+// Names of the sockets activated by systemd, they match FileDescriptorName of systemd.socket.
+const (
+	downloadSocketName      = "download"
+	peerSocketName          = "peer"
+	uploadSocketName        = "upload"
+	objectStorageSocketName = "object-storage"
+	proxySocketName         = "proxy"
+	healthSocketName        = "health"
+)
+
 type Daemon interface {
 	Serve() error
 	Stop()
@@ -113,6 +128,11 @@ type clientDaemon struct {
 	certifyClient   *certify.Certify
 	announcer       announcer.Announcer
 	networkTopology networktopology.NetworkTopology
+	parentProber    networktopology.ParentProber
+
+	// leaveTaskDisabled disables leaving task in storage gc callback,
+	// it is set when all tasks are left in batch during shutting down.
+	leaveTaskDisabled *atomic.Bool
 }
 
 func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
@@ -133,7 +153,7 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 	}
 
 	host := &schedulerv1.PeerHost{
-		Id:       idgen.HostIDV2(opt.Host.AdvertiseIP.String(), opt.Host.Hostname),
+		Id:       idgen.HostID(opt.Host.Hostname, opt.Host.AdvertiseIP.String()),
 		Ip:       opt.Host.AdvertiseIP.String(),
 		RpcPort:  int32(opt.Download.PeerGRPC.TCPListen.PortRange.Start),
 		DownPort: 0,
@@ -160,8 +180,13 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 			grpcCredentials = insecure.NewCredentials()
 		}
 
+		managerDialOptions := []grpc.DialOption{grpc.WithTransportCredentials(grpcCredentials)}
+		if opt.Scheduler.Manager.GzipCompression {
+			managerDialOptions = append(managerDialOptions, rpc.WithGzipCompression())
+		}
+
 		managerClient, err = managerclient.GetV1ByNetAddrs(
-			context.Background(), opt.Scheduler.Manager.NetAddrs, grpc.WithTransportCredentials(grpcCredentials))
+			context.Background(), opt.Scheduler.Manager.NetAddrs, managerDialOptions...)
 		if err != nil {
 			return nil, err
 		}
@@ -227,7 +252,30 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 		}
 	}
 
-	schedulerClient, err := schedulerclient.GetV1(context.Background(), dynconfig, grpc.WithTransportCredentials(grpcCredentials))
+	schedulerDialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(grpcCredentials),
+		grpc.WithChainUnaryInterceptor(metrics.SchedulerRPCFailureUnaryClientInterceptor),
+		grpc.WithChainStreamInterceptor(metrics.SchedulerRPCFailureStreamClientInterceptor),
+	}
+	if opt.Scheduler.GzipCompression {
+		schedulerDialOptions = append(schedulerDialOptions, rpc.WithGzipCompression())
+	}
+
+	if len(opt.Scheduler.FallbackNetAddrs) > 0 {
+		var fallbackAddrs []resolver.Address
+		for _, netAddr := range opt.Scheduler.FallbackNetAddrs {
+			host, _, err := net.SplitHostPort(netAddr.Addr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid scheduler fallback address %s: %w", netAddr.Addr, err)
+			}
+
+			fallbackAddrs = append(fallbackAddrs, pkgbalancer.SetWeight(resolver.Address{ServerName: host, Addr: netAddr.Addr}, netAddr.GetWeight()))
+		}
+
+		schedulerDialOptions = append(schedulerDialOptions, grpc.WithResolvers(pkgresolver.NewScheduler(dynconfig, pkgresolver.WithFallbackAddrs(fallbackAddrs))))
+	}
+
+	schedulerClient, err := schedulerclient.GetV1(context.Background(), dynconfig, opt.Scheduler.Timeouts, schedulerDialOptions...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get schedulers: %w", err)
 	}
@@ -271,7 +319,12 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 
 	// Storage.Option.DataPath is same with Daemon DataDir
 	opt.Storage.DataPath = d.DataDir()
+	leaveTaskDisabled := atomic.NewBool(false)
 	gcCallback := func(request storage.CommonTaskRequest) {
+		if leaveTaskDisabled.Load() {
+			return
+		}
+
 		er := schedulerClient.LeaveTask(context.Background(), &schedulerv1.PeerTarget{
 			TaskId: request.TaskID,
 			PeerId: request.PeerID,
@@ -304,6 +357,7 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 		peer.WithCalculateDigest(opt.Download.CalculateDigest),
 		peer.WithTransportOption(opt.Download.Transport),
 		peer.WithConcurrentOption(opt.Download.Concurrent),
+		peer.WithHeaderProviders(opt.Download.HeaderProviders),
 	}
 
 	if opt.Download.SyncPieceViaHTTPS && opt.Scheduler.Manager.Enable {
@@ -315,16 +369,23 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 		return nil, err
 	}
 
+	var parentProber networktopology.ParentProber
+	if opt.NetworkTopology.Enable && opt.NetworkTopology.ParentProbe.Enable {
+		parentProber = networktopology.NewParentProber(opt.NetworkTopology.ParentProbe, host, schedulerClient)
+	}
+
 	peerTaskManagerOption := &peer.TaskManagerOption{
 		TaskOption: peer.TaskOption{
-			PeerHost:        host,
-			SchedulerOption: opt.Scheduler,
-			PieceManager:    pieceManager,
-			StorageManager:  storageManager,
-			WatchdogTimeout: opt.Download.WatchdogTimeout,
-			CalculateDigest: opt.Download.CalculateDigest,
-			GRPCCredentials: grpcCredentials,
-			GRPCDialTimeout: opt.Download.GRPCDialTimeout,
+			PeerHost:         host,
+			SchedulerOption:  opt.Scheduler,
+			PieceManager:     pieceManager,
+			StorageManager:   storageManager,
+			WatchdogTimeout:  opt.Download.WatchdogTimeout,
+			CalculateDigest:  opt.Download.CalculateDigest,
+			GRPCCredentials:  grpcCredentials,
+			GRPCDialTimeout:  opt.Download.GRPCDialTimeout,
+			PieceCompression: opt.Download.PieceCompression.Enable,
+			ParentProber:     parentProber,
 		},
 		SchedulerClient:       schedulerClient,
 		PerPeerRateLimit:      opt.Download.PerPeerRateLimit.Limit,
@@ -376,10 +437,23 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 		upload.WithLimiter(rate.NewLimiter(opt.Upload.RateLimit.Limit, int(opt.Upload.RateLimit.Limit))),
 	}
 
+	if opt.Upload.PeerRateLimit.Enable {
+		peerLimiter := upload.NewPeerLimiter(opt.Upload.RateLimit.Limit, opt.Upload.PeerRateLimit.Floor.Limit,
+			opt.Upload.PeerRateLimit.Ceiling.Limit, int(opt.Upload.PeerRateLimit.Burst))
+		uploadOpts = append(uploadOpts, upload.WithPeerLimiter(peerLimiter))
+
+		// Register notify for the peer upload rate ceiling of scheduler cluster.
+		dynconfig.Register(peerLimiter)
+	}
+
 	if opt.Security.AutoIssueCert && opt.Scheduler.Manager.Enable {
 		uploadOpts = append(uploadOpts, upload.WithCertify(certifyClient))
 	}
 
+	if opt.Download.PieceCompression.Enable {
+		uploadOpts = append(uploadOpts, upload.WithPieceCompression(opt.Download.PieceCompression.Level))
+	}
+
 	uploadManager, err := upload.NewUploadManager(opt, storageManager, d.LogDir(), uploadOpts...)
 	if err != nil {
 		return nil, err
@@ -391,6 +465,9 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 		if err != nil {
 			return nil, err
 		}
+
+		// Register notify for the access control rules of buckets.
+		dynconfig.Register(objectStorage)
 	}
 
 	return &clientDaemon{
@@ -413,6 +490,9 @@ func New(opt *config.DaemonOption, d dfpath.Dfpath) (Daemon, error) {
 		securityClient:  securityClient,
 		schedulerClient: schedulerClient,
 		certifyClient:   certifyClient,
+		parentProber:    parentProber,
+
+		leaveTaskDisabled: leaveTaskDisabled,
 	}, nil
 }
 
@@ -509,7 +589,7 @@ func loadGPRCTLSCredentialsWithOptions(baseConfig *tls.Config, security config.G
 	}
 }
 
-func (*clientDaemon) prepareTCPListener(opt config.ListenOption, withTLS bool) (net.Listener, int, error) {
+func (*clientDaemon) prepareTCPListener(name string, opt config.ListenOption, withTLS bool) (net.Listener, int, error) {
 	if len(opt.TCPListen.Namespace) > 0 {
 		runtime.LockOSThread()
 		defer runtime.UnlockOSThread()
@@ -535,7 +615,13 @@ func (*clientDaemon) prepareTCPListener(opt config.ListenOption, withTLS bool) (
 		return nil, -1, errors.New("empty tcp listen option")
 	}
 
-	ln, port, err = rpc.ListenWithPortRange(opt.TCPListen.Listen, opt.TCPListen.PortRange.Start, opt.TCPListen.PortRange.End)
+	ln, port, err = listen.ListenWithPortRange(listen.Option{
+		Name:      name,
+		Network:   dfnet.TCP,
+		Addr:      opt.TCPListen.Listen,
+		ReusePort: opt.TCPListen.ReusePort,
+		KeepAlive: opt.TCPListen.KeepAlive.Duration,
+	}, opt.TCPListen.PortRange.Start, opt.TCPListen.PortRange.End)
 	if err != nil {
 		return nil, -1, err
 	}
@@ -591,10 +677,10 @@ func (cd *clientDaemon) Serve() error {
 	if cd.Option.Download.DownloadGRPC.UnixListen == nil {
 		return errors.New("download grpc unix listen option is empty")
 	}
-	_ = os.Remove(cd.dfpath.DaemonSockPath())
-	downloadListener, err := rpc.Listen(dfnet.NetAddr{
-		Type: dfnet.UNIX,
-		Addr: cd.dfpath.DaemonSockPath(),
+	downloadListener, err := listen.Listen(listen.Option{
+		Name:    downloadSocketName,
+		Network: dfnet.UNIX,
+		Addr:    cd.dfpath.DaemonSockPath(),
 	})
 	if err != nil {
 		logger.Errorf("failed to listen for download grpc service: %v", err)
@@ -605,7 +691,7 @@ func (cd *clientDaemon) Serve() error {
 	if cd.Option.Download.PeerGRPC.TCPListen == nil {
 		return errors.New("peer grpc tcp listen option is empty")
 	}
-	peerListener, peerPort, err := cd.prepareTCPListener(cd.Option.Download.PeerGRPC, false)
+	peerListener, peerPort, err := cd.prepareTCPListener(peerSocketName, cd.Option.Download.PeerGRPC, false)
 	if err != nil {
 		logger.Errorf("failed to listen for peer grpc service: %v", err)
 		return err
@@ -616,7 +702,7 @@ func (cd *clientDaemon) Serve() error {
 	if cd.Option.Upload.TCPListen == nil {
 		return errors.New("upload tcp listen option is empty")
 	}
-	uploadListener, uploadPort, err := cd.prepareTCPListener(cd.Option.Upload.ListenOption, true)
+	uploadListener, uploadPort, err := cd.prepareTCPListener(uploadSocketName, cd.Option.Upload.ListenOption, true)
 	if err != nil {
 		logger.Errorf("failed to listen for upload service: %v", err)
 		return err
@@ -632,7 +718,7 @@ func (cd *clientDaemon) Serve() error {
 		if cd.Option.ObjectStorage.TCPListen == nil {
 			return errors.New("object storage tcp listen option is empty")
 		}
-		objectStorageListener, objectStoragePort, err = cd.prepareTCPListener(cd.Option.ObjectStorage.ListenOption, true)
+		objectStorageListener, objectStoragePort, err = cd.prepareTCPListener(objectStorageSocketName, cd.Option.ObjectStorage.ListenOption, true)
 		if err != nil {
 			logger.Errorf("failed to listen for object storage service: %v", err)
 			return err
@@ -671,7 +757,7 @@ func (cd *clientDaemon) Serve() error {
 		var (
 			proxyListener net.Listener
 		)
-		proxyListener, proxyPort, err = cd.prepareTCPListener(cd.Option.Proxy.ListenOption, true)
+		proxyListener, proxyPort, err = cd.prepareTCPListener(proxySocketName, cd.Option.Proxy.ListenOption, true)
 		if err != nil {
 			logger.Errorf("failed to listen for proxy service: %v", err)
 			return err
@@ -691,7 +777,7 @@ func (cd *clientDaemon) Serve() error {
 		// serve proxy sni service
 		if cd.Option.Proxy.HijackHTTPS != nil && len(cd.Option.Proxy.HijackHTTPS.SNI) > 0 {
 			for _, opt := range cd.Option.Proxy.HijackHTTPS.SNI {
-				listener, port, err := cd.prepareTCPListener(config.ListenOption{
+				listener, port, err := cd.prepareTCPListener("", config.ListenOption{
 					TCPListen: opt,
 				}, false)
 				if err != nil {
@@ -796,6 +882,12 @@ func (cd *clientDaemon) Serve() error {
 		go cd.networkTopology.Serve()
 	}
 
+	// serve parent prober
+	if cd.parentProber != nil {
+		logger.Infof("serve parent prober")
+		go cd.parentProber.Serve()
+	}
+
 	if cd.Option.AliveTime.Duration > 0 {
 		g.Go(func() error {
 			for {
@@ -836,10 +928,9 @@ func (cd *clientDaemon) Serve() error {
 		return nil
 	})
 
-	// when there is no manager configured, watch schedulers in local config
-	if cd.managerClient == nil {
-		watchers = append(watchers, cd.dynconfig.OnNotify)
-	}
+	// watch local config in dynconfig, e.g. schedulers when there is no manager configured
+	// and the access control rules of buckets
+	watchers = append(watchers, cd.dynconfig.OnNotify)
 
 	if cd.Option.Metrics != "" {
 		metricsServer := metrics.New(cd.Option.Metrics)
@@ -864,7 +955,7 @@ func (cd *clientDaemon) Serve() error {
 			c.JSON(http.StatusOK, http.StatusText(http.StatusOK))
 		})
 
-		listener, _, err := cd.prepareTCPListener(cd.Option.Health.ListenOption, false)
+		listener, _, err := cd.prepareTCPListener(healthSocketName, cd.Option.Health.ListenOption, false)
 		if err != nil {
 			logger.Fatalf("init health http server error: %v", err)
 		}
@@ -893,15 +984,88 @@ func (cd *clientDaemon) Serve() error {
 		}()
 	}
 
+	// notify daemon is ready after services are listening
+	go cd.notifyReady()
+
 	werr := g.Wait()
 	cd.Stop()
 	return werr
 }
 
+// notifyReady touches the ready file and notifies systemd after the download grpc
+// service is listening and at least one scheduler is healthy.
+func (cd *clientDaemon) notifyReady() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-cd.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := waitSchedulerReady(ctx, cd.dynconfig, defaultReadinessInterval); err != nil {
+		logger.Warnf("daemon stopped before ready: %v", err)
+		return
+	}
+
+	if cd.Option.ReadyFile != "" {
+		if err := touchReadyFile(cd.Option.ReadyFile); err != nil {
+			logger.Errorf("touch ready file %s failed: %v", cd.Option.ReadyFile, err)
+			return
+		}
+	}
+
+	if ok, err := sdNotify(sdNotifyReady); err != nil {
+		logger.Errorf("notify systemd ready failed: %v", err)
+	} else if ok {
+		logger.Info("notify systemd ready")
+	}
+
+	logger.Info("daemon is ready")
+}
+
+// leaveTasks leaves all peers in local storage with scheduler in batch before the
+// scheduler client is closed, and disables leaving task one by one when cleaning up storage.
+func (cd *clientDaemon) leaveTasks() {
+	cd.leaveTaskDisabled.Store(true)
+
+	var targets []*schedulerv1.PeerTarget
+	for _, peers := range cd.StorageManager.ListAllPeers(0) {
+		for _, peer := range peers {
+			targets = append(targets, &schedulerv1.PeerTarget{
+				TaskId: peer.TaskId,
+				PeerId: peer.PeerId,
+			})
+		}
+	}
+
+	if len(targets) == 0 {
+		return
+	}
+
+	logger.Infof("leave %d tasks with scheduler client", len(targets))
+	if err := cd.schedulerClient.LeaveTasks(context.Background(), targets); err != nil {
+		logger.Errorf("leave tasks with scheduler client failed: %s", err.Error())
+	}
+}
+
 func (cd *clientDaemon) Stop() {
 	cd.once.Do(func() {
 		close(cd.done)
 
+		if cd.Option.ReadyFile != "" {
+			if err := removeReadyFile(cd.Option.ReadyFile); err != nil {
+				logger.Errorf("remove ready file %s failed: %v", cd.Option.ReadyFile, err)
+			}
+		}
+
+		if _, err := sdNotify(sdNotifyStopping); err != nil {
+			logger.Errorf("notify systemd stopping failed: %v", err)
+		}
+
 		if cd.ProxyManager.IsEnabled() {
 			if err := cd.ProxyManager.Stop(); err != nil {
 				logger.Errorf("proxy manager stop failed %s", err)
@@ -910,6 +1074,8 @@ func (cd *clientDaemon) Stop() {
 
 		if cd.schedulerClient != nil {
 			if !cd.Option.KeepStorage {
+				cd.leaveTasks()
+
 				logger.Info("leave host with scheduler client")
 				if err := cd.schedulerClient.LeaveHost(context.Background(), &schedulerv1.LeaveHostRequest{Id: cd.schedPeerHost.Id}); err != nil {
 					logger.Errorf("leave host with scheduler client failed: %s", err.Error())
@@ -952,6 +1118,10 @@ func (cd *clientDaemon) Stop() {
 			cd.networkTopology.Stop()
 		}
 
+		if cd.parentProber != nil {
+			cd.parentProber.Stop()
+		}
+
 		if err := cd.dynconfig.Stop(); err != nil {
 			logger.Errorf("dynconfig client closed failed %s", err)
 		} else {
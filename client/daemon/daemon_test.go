@@ -0,0 +1,96 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"go.uber.org/mock/gomock"
+
+	dfdaemonv1 "d7y.io/api/v2/pkg/apis/dfdaemon/v1"
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+
+	storagemocks "d7y.io/dragonfly/v2/client/daemon/storage/mocks"
+	schedulerclientmocks "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client/mocks"
+)
+
+func TestClientDaemon_leaveTasks(t *testing.T) {
+	tests := []struct {
+		name string
+		mock func(sm *storagemocks.MockManagerMockRecorder, sc *schedulerclientmocks.MockV1MockRecorder)
+	}{
+		{
+			name: "leave tasks in batch",
+			mock: func(sm *storagemocks.MockManagerMockRecorder, sc *schedulerclientmocks.MockV1MockRecorder) {
+				sm.ListAllPeers(0).Return([][]*dfdaemonv1.PeerMetadata{
+					{
+						{TaskId: "foo", PeerId: "foo-peer"},
+						{TaskId: "bar", PeerId: "bar-peer"},
+					},
+					{
+						{TaskId: "baz", PeerId: "baz-peer"},
+					},
+				}).Times(1)
+				sc.LeaveTasks(gomock.Any(), []*schedulerv1.PeerTarget{
+					{TaskId: "foo", PeerId: "foo-peer"},
+					{TaskId: "bar", PeerId: "bar-peer"},
+					{TaskId: "baz", PeerId: "baz-peer"},
+				}).Return(nil).Times(1)
+			},
+		},
+		{
+			name: "leave tasks failed",
+			mock: func(sm *storagemocks.MockManagerMockRecorder, sc *schedulerclientmocks.MockV1MockRecorder) {
+				sm.ListAllPeers(0).Return([][]*dfdaemonv1.PeerMetadata{
+					{
+						{TaskId: "foo", PeerId: "foo-peer"},
+					},
+				}).Times(1)
+				sc.LeaveTasks(gomock.Any(), gomock.Any()).Return(errors.New("foo")).Times(1)
+			},
+		},
+		{
+			name: "storage without peers",
+			mock: func(sm *storagemocks.MockManagerMockRecorder, sc *schedulerclientmocks.MockV1MockRecorder) {
+				sm.ListAllPeers(0).Return(nil).Times(1)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			storageManager := storagemocks.NewMockManager(ctl)
+			schedulerClient := schedulerclientmocks.NewMockV1(ctl)
+			tc.mock(storageManager.EXPECT(), schedulerClient.EXPECT())
+
+			cd := &clientDaemon{
+				StorageManager:    storageManager,
+				schedulerClient:   schedulerClient,
+				leaveTaskDisabled: atomic.NewBool(false),
+			}
+			cd.leaveTasks()
+			assert.True(cd.leaveTaskDisabled.Load())
+		})
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SchedulerRPCFailureUnaryClientInterceptor records failed unary calls to scheduler.
+func SchedulerRPCFailureUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	recordSchedulerRPCFailure(method, err)
+	return err
+}
+
+// SchedulerRPCFailureStreamClientInterceptor records failed stream calls to scheduler,
+// including the errors returned when receiving from an established stream.
+func SchedulerRPCFailureStreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		recordSchedulerRPCFailure(method, err)
+		return nil, err
+	}
+
+	return &failureRecordingClientStream{ClientStream: stream, method: method}, nil
+}
+
+// failureRecordingClientStream wraps grpc.ClientStream to record receive failures.
+type failureRecordingClientStream struct {
+	grpc.ClientStream
+	method string
+}
+
+// RecvMsg records the failure unless the stream is closed normally.
+func (s *failureRecordingClientStream) RecvMsg(m any) error {
+	err := s.ClientStream.RecvMsg(m)
+	if !errors.Is(err, io.EOF) {
+		recordSchedulerRPCFailure(s.method, err)
+	}
+
+	return err
+}
+
+// recordSchedulerRPCFailure increases the failure counter, errors caused by
+// canceling the call are ignored.
+func recordSchedulerRPCFailure(method string, err error) {
+	if err == nil || status.Code(err) == codes.Canceled || errors.Is(err, context.Canceled) {
+		return
+	}
+
+	SchedulerRPCFailureCount.WithLabelValues(path.Base(method)).Inc()
+}
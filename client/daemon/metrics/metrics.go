@@ -179,6 +179,55 @@ var (
 		Help:      "Total bytes of back source.",
 	})
 
+	UploadPeerThrottledBytesCount = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.DfdaemonMetricsName,
+		Name:      "upload_peer_throttled_bytes_total",
+		Help:      "Counter of the total bytes throttled by the per-peer upload rate limiter.",
+	})
+
+	UploadTraffic = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.DfdaemonMetricsName,
+		Name:      "upload_traffic",
+		Help:      "Counter of the total bytes uploaded to other peers.",
+	})
+
+	DownloadTraffic = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.DfdaemonMetricsName,
+		Name:      "download_traffic",
+		Help:      "Counter of the total bytes downloaded from other peers.",
+	})
+
+	PeerTaskRunningCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.DfdaemonMetricsName,
+		Name:      "peer_task_running_total",
+		Help:      "Current count of the running peer tasks.",
+	})
+
+	StorageUsedBytesGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.DfdaemonMetricsName,
+		Name:      "storage_used_bytes",
+		Help:      "Gauge of the bytes used by tasks in data directory.",
+	})
+
+	StorageQuotaBytesGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.DfdaemonMetricsName,
+		Name:      "storage_quota_bytes",
+		Help:      "Gauge of the quota bytes of data directory, 0 means no quota.",
+	})
+
+	SchedulerRPCFailureCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.DfdaemonMetricsName,
+		Name:      "scheduler_rpc_failure_total",
+		Help:      "Counter of the total failed rpc calls to scheduler.",
+	}, []string{"method"})
+
 	VersionGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: types.MetricsNamespace,
 		Subsystem: types.DfdaemonMetricsName,
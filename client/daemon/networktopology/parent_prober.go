@@ -0,0 +1,283 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+//go:generate mockgen -destination mocks/parent_prober_mock.go -source parent_prober.go -package mocks
+
+package networktopology
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	v1 "d7y.io/api/v2/pkg/apis/common/v1"
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
+)
+
+const (
+	// parentProbePath is the health check path of the upload server of the parent.
+	parentProbePath = "/healthy"
+
+	// parentProbeQueueSize is the max count of the probes waiting to be reported.
+	parentProbeQueueSize = 1024
+)
+
+// ParentProber probes the latency of the parents scheduled by the scheduler, and reports
+// the probes to the scheduler. The new parents have no piece cost history, so the scheduler
+// ranks them by the latency in the network topology.
+type ParentProber interface {
+	// Serve starts to report the probes to the scheduler.
+	Serve()
+
+	// Stop stops the parent prober.
+	Stop()
+
+	// Probe probes the parent in the background, addr is the address of the upload server of
+	// the parent, which serves the pieces, and the parent probed within the interval is skipped.
+	Probe(peerID, addr string)
+}
+
+// parentProber implements ParentProber.
+type parentProber struct {
+	config          config.ParentProbeOption
+	host            *v1.Host
+	schedulerClient schedulerclient.V1
+
+	// httpClient sends the probe requests to the upload servers of the parents.
+	httpClient *http.Client
+
+	// sem bounds the number of concurrent probes.
+	sem chan struct{}
+
+	// mu protects probedAt.
+	mu sync.Mutex
+
+	// probedAt is the latest probe time by the address of the parent.
+	probedAt map[string]time.Time
+
+	// requests is the probes waiting to be reported.
+	requests chan *schedulerv1.SyncProbesRequest
+
+	// stream reports the probes, it is reused by the reports and reopened after failure,
+	// it is only accessed by Serve.
+	stream schedulerv1.Scheduler_SyncProbesClient
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewParentProber returns a new ParentProber interface.
+func NewParentProber(cfg config.ParentProbeOption, host *schedulerv1.PeerHost, schedulerClient schedulerclient.V1) ParentProber {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &parentProber{
+		config: cfg,
+		host: &v1.Host{
+			Id:           host.Id,
+			Ip:           host.Ip,
+			Hostname:     host.Hostname,
+			Port:         host.RpcPort,
+			DownloadPort: host.DownPort,
+			Location:     host.Location,
+			Idc:          host.Idc,
+		},
+		schedulerClient: schedulerClient,
+		httpClient: &http.Client{
+			Timeout: cfg.Timeout,
+			// The connection is not reused, so the latency of every probe contains the connect time.
+			Transport: &http.Transport{DisableKeepAlives: true},
+		},
+		sem:      make(chan struct{}, cfg.Concurrency),
+		probedAt: make(map[string]time.Time),
+		requests: make(chan *schedulerv1.SyncProbesRequest, parentProbeQueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Serve starts to report the probes to the scheduler.
+func (p *parentProber) Serve() {
+	for {
+		select {
+		case req := <-p.requests:
+			if err := p.report(req); err != nil {
+				logger.Warnf("report parent probes failed: %s", err.Error())
+			}
+		case <-p.ctx.Done():
+			p.closeStream()
+			return
+		}
+	}
+}
+
+// Stop stops the parent prober.
+func (p *parentProber) Stop() {
+	p.cancel()
+}
+
+// Probe probes the parent in the background, addr is the address of the upload server of
+// the parent, which serves the pieces, and the parent probed within the interval is skipped.
+func (p *parentProber) Probe(peerID, addr string) {
+	if peerID == "" || addr == "" || !p.mark(addr) {
+		return
+	}
+
+	go func() {
+		select {
+		case p.sem <- struct{}{}:
+		case <-p.ctx.Done():
+			return
+		}
+
+		req := p.probe(peerID, addr)
+		<-p.sem
+
+		select {
+		case p.requests <- req:
+		default:
+			logger.Warnf("drop the probe of parent %s, too many probes are waiting to be reported", peerID)
+		}
+	}()
+}
+
+// mark marks the address of the parent as probed, it returns false if the parent is probed within the interval.
+func (p *parentProber) mark(addr string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	for a, probedAt := range p.probedAt {
+		if now.Sub(probedAt) >= p.config.Interval {
+			delete(p.probedAt, a)
+		}
+	}
+
+	if _, ok := p.probedAt[addr]; ok {
+		return false
+	}
+
+	p.probedAt[addr] = now
+	return true
+}
+
+// probe measures the latency of the parent and returns the request of reporting the probe.
+func (p *parentProber) probe(peerID, addr string) *schedulerv1.SyncProbesRequest {
+	// The peer packet has no host id of the parent, so the host of the probe
+	// is identified by the peer id, and the scheduler resolves the host of the peer.
+	host := &v1.Host{Id: peerID}
+	if ip, port, err := net.SplitHostPort(addr); err == nil {
+		host.Ip = ip
+		if downloadPort, err := strconv.Atoi(port); err == nil {
+			host.DownloadPort = int32(downloadPort)
+		}
+	}
+
+	rtt, err := p.measure(addr)
+	if err != nil {
+		return &schedulerv1.SyncProbesRequest{
+			Host: p.host,
+			Request: &schedulerv1.SyncProbesRequest_ProbeFailedRequest{
+				ProbeFailedRequest: &schedulerv1.ProbeFailedRequest{
+					Probes: []*schedulerv1.FailedProbe{{
+						Host:        host,
+						Description: err.Error(),
+					}},
+				},
+			},
+		}
+	}
+
+	return &schedulerv1.SyncProbesRequest{
+		Host: p.host,
+		Request: &schedulerv1.SyncProbesRequest_ProbeFinishedRequest{
+			ProbeFinishedRequest: &schedulerv1.ProbeFinishedRequest{
+				Probes: []*schedulerv1.Probe{{
+					Host:      host,
+					Rtt:       durationpb.New(rtt),
+					CreatedAt: timestamppb.New(time.Now()),
+				}},
+			},
+		},
+	}
+}
+
+// measure returns the latency of the http HEAD request to the upload server of the parent, it is
+// the same path as downloading pieces, so it is not blocked when only the rpc port is reachable.
+func (p *parentProber) measure(addr string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(p.ctx, http.MethodHead, fmt.Sprintf("http://%s%s", addr, parentProbePath), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	start := time.Now()
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	rtt := time.Since(start)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return rtt, nil
+}
+
+// report reports the probe to the scheduler with the stream, the stream is opened
+// on the first report, and reopened if the scheduler closed it.
+func (p *parentProber) report(req *schedulerv1.SyncProbesRequest) error {
+	if p.stream != nil {
+		if err := p.stream.Send(req); err == nil {
+			return nil
+		}
+
+		p.closeStream()
+	}
+
+	// The stream lives until the parent prober is stopped, and the request
+	// is sent by the scheduler client when opening the stream.
+	stream, err := p.schedulerClient.SyncProbes(p.ctx, req)
+	if err != nil {
+		return err
+	}
+
+	p.stream = stream
+	return nil
+}
+
+// closeStream closes the stream of reporting the probes.
+func (p *parentProber) closeStream() {
+	if p.stream == nil {
+		return
+	}
+
+	if err := p.stream.CloseSend(); err != nil {
+		logger.Debugf("close parent probes stream failed: %s", err.Error())
+	}
+
+	p.stream = nil
+}
@@ -0,0 +1,338 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package networktopology
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+	schedulerv1mocks "d7y.io/api/v2/pkg/apis/scheduler/v1/mocks"
+
+	"d7y.io/dragonfly/v2/client/config"
+	schedulerclientmocks "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client/mocks"
+)
+
+var (
+	mockParentProbeConfig = config.ParentProbeOption{
+		Enable:      true,
+		Interval:    time.Minute,
+		Timeout:     time.Second,
+		Concurrency: 2,
+	}
+
+	mockPeerHost = &schedulerv1.PeerHost{
+		Id:       mockSeedHost.Id,
+		Ip:       mockSeedHost.Ip,
+		Hostname: mockSeedHost.Hostname,
+		RpcPort:  mockSeedHost.Port,
+		DownPort: mockSeedHost.DownloadPort,
+		Location: mockSeedHost.Location,
+		Idc:      mockSeedHost.Idc,
+	}
+)
+
+func TestParentProber_Probe(t *testing.T) {
+	healthy := newMockParent(t, http.StatusOK)
+	unhealthy := newMockParent(t, http.StatusInternalServerError)
+	unreachable := newUnreachableParent(t)
+
+	tests := []struct {
+		name   string
+		addr   string
+		expect func(t *testing.T, req *schedulerv1.SyncProbesRequest)
+	}{
+		{
+			name: "report probe of healthy parent",
+			addr: healthy,
+			expect: func(t *testing.T, req *schedulerv1.SyncProbesRequest) {
+				assert := assert.New(t)
+				assert.Equal(mockSeedHost.Id, req.Host.Id)
+				probes := req.GetProbeFinishedRequest().GetProbes()
+				assert.Len(probes, 1)
+				assert.Equal("foo", probes[0].Host.Id)
+				assert.Equal("127.0.0.1", probes[0].Host.Ip)
+				assert.Equal(mockParentPort(t, healthy), probes[0].Host.DownloadPort)
+				assert.Greater(probes[0].Rtt.AsDuration(), time.Duration(0))
+			},
+		},
+		{
+			name: "report failed probe of unhealthy parent",
+			addr: unhealthy,
+			expect: func(t *testing.T, req *schedulerv1.SyncProbesRequest) {
+				assert := assert.New(t)
+				assert.Nil(req.GetProbeFinishedRequest())
+				failedProbes := req.GetProbeFailedRequest().GetProbes()
+				assert.Len(failedProbes, 1)
+				assert.Equal("foo", failedProbes[0].Host.Id)
+				assert.Contains(failedProbes[0].Description, "500")
+			},
+		},
+		{
+			name: "report failed probe of unreachable parent",
+			addr: unreachable,
+			expect: func(t *testing.T, req *schedulerv1.SyncProbesRequest) {
+				assert := assert.New(t)
+				assert.Nil(req.GetProbeFinishedRequest())
+				failedProbes := req.GetProbeFailedRequest().GetProbes()
+				assert.Len(failedProbes, 1)
+				assert.NotEmpty(failedProbes[0].Description)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			reqs := make(chan *schedulerv1.SyncProbesRequest, 1)
+			schedulerClient := schedulerclientmocks.NewMockV1(ctl)
+			stream := schedulerv1mocks.NewMockScheduler_SyncProbesClient(ctl)
+			gomock.InOrder(
+				schedulerClient.EXPECT().SyncProbes(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, req *schedulerv1.SyncProbesRequest, opts ...grpc.CallOption) (schedulerv1.Scheduler_SyncProbesClient, error) {
+						reqs <- req
+						return stream, nil
+					}).Times(1),
+				stream.EXPECT().CloseSend().Return(nil).Times(1),
+			)
+
+			p := NewParentProber(mockParentProbeConfig, mockPeerHost, schedulerClient)
+			served := make(chan struct{})
+			go func() {
+				p.Serve()
+				close(served)
+			}()
+
+			p.Probe("foo", tc.addr)
+			select {
+			case req := <-reqs:
+				tc.expect(t, req)
+			case <-time.After(5 * time.Second):
+				t.Fatal("probe is not reported")
+			}
+
+			p.Stop()
+			<-served
+		})
+	}
+}
+
+func TestParentProber_report(t *testing.T) {
+	mockRequest := &schedulerv1.SyncProbesRequest{Host: mockSeedHost}
+
+	tests := []struct {
+		name   string
+		mock   func(mv *schedulerclientmocks.MockV1MockRecorder, stream *schedulerv1mocks.MockScheduler_SyncProbesClient, ms *schedulerv1mocks.MockScheduler_SyncProbesClientMockRecorder)
+		expect func(t *testing.T, p *parentProber)
+	}{
+		{
+			name: "reuse stream",
+			mock: func(mv *schedulerclientmocks.MockV1MockRecorder, stream *schedulerv1mocks.MockScheduler_SyncProbesClient, ms *schedulerv1mocks.MockScheduler_SyncProbesClientMockRecorder) {
+				gomock.InOrder(
+					mv.SyncProbes(gomock.Any(), gomock.Eq(mockRequest)).Return(stream, nil).Times(1),
+					ms.Send(gomock.Eq(mockRequest)).Return(nil).Times(1),
+				)
+			},
+			expect: func(t *testing.T, p *parentProber) {
+				assert := assert.New(t)
+				assert.NoError(p.report(mockRequest))
+				assert.NoError(p.report(mockRequest))
+				assert.NotNil(p.stream)
+			},
+		},
+		{
+			name: "reopen stream after sending failed",
+			mock: func(mv *schedulerclientmocks.MockV1MockRecorder, stream *schedulerv1mocks.MockScheduler_SyncProbesClient, ms *schedulerv1mocks.MockScheduler_SyncProbesClientMockRecorder) {
+				gomock.InOrder(
+					mv.SyncProbes(gomock.Any(), gomock.Eq(mockRequest)).Return(stream, nil).Times(1),
+					ms.Send(gomock.Eq(mockRequest)).Return(errors.New("foo")).Times(1),
+					ms.CloseSend().Return(nil).Times(1),
+					mv.SyncProbes(gomock.Any(), gomock.Eq(mockRequest)).Return(stream, nil).Times(1),
+				)
+			},
+			expect: func(t *testing.T, p *parentProber) {
+				assert := assert.New(t)
+				assert.NoError(p.report(mockRequest))
+				assert.NoError(p.report(mockRequest))
+				assert.NotNil(p.stream)
+			},
+		},
+		{
+			name: "open stream failed",
+			mock: func(mv *schedulerclientmocks.MockV1MockRecorder, stream *schedulerv1mocks.MockScheduler_SyncProbesClient, ms *schedulerv1mocks.MockScheduler_SyncProbesClientMockRecorder) {
+				mv.SyncProbes(gomock.Any(), gomock.Eq(mockRequest)).Return(nil, errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, p *parentProber) {
+				assert := assert.New(t)
+				assert.EqualError(p.report(mockRequest), "foo")
+				assert.Nil(p.stream)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			schedulerClient := schedulerclientmocks.NewMockV1(ctl)
+			stream := schedulerv1mocks.NewMockScheduler_SyncProbesClient(ctl)
+			tc.mock(schedulerClient.EXPECT(), stream, stream.EXPECT())
+
+			p := NewParentProber(mockParentProbeConfig, mockPeerHost, schedulerClient).(*parentProber)
+			tc.expect(t, p)
+		})
+	}
+}
+
+func TestParentProber_ProbeInterval(t *testing.T) {
+	assert := assert.New(t)
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	p := NewParentProber(config.ParentProbeOption{
+		Enable:      true,
+		Interval:    200 * time.Millisecond,
+		Timeout:     time.Second,
+		Concurrency: 1,
+	}, mockPeerHost, schedulerclientmocks.NewMockV1(ctl)).(*parentProber)
+
+	// The parent probed within the interval is skipped.
+	assert.True(p.mark("127.0.0.1:65002"))
+	assert.False(p.mark("127.0.0.1:65002"))
+	assert.True(p.mark("127.0.0.2:65002"))
+	assert.Len(p.probedAt, 2)
+
+	// The parent is probed again after the interval.
+	time.Sleep(250 * time.Millisecond)
+	assert.True(p.mark("127.0.0.1:65002"))
+	assert.Len(p.probedAt, 1)
+}
+
+func TestParentProber_ProbeConcurrency(t *testing.T) {
+	assert := assert.New(t)
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	var running, maxRunning atomic.Int32
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	})
+
+	reqs := make(chan *schedulerv1.SyncProbesRequest, 10)
+	schedulerClient := schedulerclientmocks.NewMockV1(ctl)
+	stream := schedulerv1mocks.NewMockScheduler_SyncProbesClient(ctl)
+	schedulerClient.EXPECT().SyncProbes(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *schedulerv1.SyncProbesRequest, opts ...grpc.CallOption) (schedulerv1.Scheduler_SyncProbesClient, error) {
+			reqs <- req
+			return stream, nil
+		}).Times(1)
+	stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(req *schedulerv1.SyncProbesRequest) error {
+		reqs <- req
+		return nil
+	}).Times(9)
+	stream.EXPECT().CloseSend().Return(nil).Times(1)
+
+	p := NewParentProber(mockParentProbeConfig, mockPeerHost, schedulerClient)
+	served := make(chan struct{})
+	go func() {
+		p.Serve()
+		close(served)
+	}()
+
+	for i := 0; i < 10; i++ {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		p.Probe("foo", server.Listener.Addr().String())
+	}
+
+	for i := 0; i < 10; i++ {
+		select {
+		case req := <-reqs:
+			assert.Len(req.GetProbeFinishedRequest().GetProbes(), 1)
+		case <-time.After(5 * time.Second):
+			t.Fatal("probes are not reported")
+		}
+	}
+
+	p.Stop()
+	<-served
+	assert.Equal(int32(mockParentProbeConfig.Concurrency), maxRunning.Load())
+}
+
+// newMockParent returns the address of the upload server of the parent, which responds the probe with the status code.
+func newMockParent(t *testing.T, statusCode int) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead || r.URL.Path != parentProbePath {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+
+	return server.Listener.Addr().String()
+}
+
+// newUnreachableParent returns the address of the closed port.
+func newUnreachableParent(t *testing.T) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lis.Close()
+
+	return lis.Addr().String()
+}
+
+// mockParentPort returns the port of the address of the parent.
+func mockParentPort(t *testing.T, addr string) int32 {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return int32(n)
+}
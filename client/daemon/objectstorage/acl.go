@@ -0,0 +1,110 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objectstorage
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+const (
+	// BucketACLWildcard matches the buckets without their own access control rule.
+	BucketACLWildcard = "*"
+)
+
+// OnNotify reloads the access control rules of buckets when the dynconfig changes.
+func (o *objectStorage) OnNotify(data *config.DynconfigData) {
+	if acls, _ := o.bucketACLs.Load().([]*config.BucketACL); reflect.DeepEqual(acls, data.BucketACLs) {
+		return
+	}
+
+	logger.Infof("update access control rules of %d buckets", len(data.BucketACLs))
+	o.bucketACLs.Store(data.BucketACLs)
+}
+
+// checkBucketAccess is the middleware that denies the request of the bucket
+// which does not pass the access control rule.
+func (o *objectStorage) checkBucketAccess(ctx *gin.Context) {
+	acls, _ := o.bucketACLs.Load().([]*config.BucketACL)
+	bucketName := ctx.Param("id")
+	if err := checkBucketACL(acls, bucketName, ctx.Request.Method, ctx.GetHeader(config.HeaderDragonflyObjectToken)); err != nil {
+		logger.Warnf("access bucket %s denied: %s", bucketName, err.Error())
+		ctx.Error(NewError(ErrorCodeAccessDenied, err)) // nolint: errcheck
+		ctx.Abort()
+		return
+	}
+
+	ctx.Next()
+}
+
+// checkBucketACL checks the request of the bucket with the access control rules,
+// the rule of the bucket takes precedence over the wildcard rule.
+func checkBucketACL(acls []*config.BucketACL, bucketName, method, token string) error {
+	var matched *config.BucketACL
+	for _, acl := range acls {
+		if acl.Bucket == bucketName {
+			matched = acl
+			break
+		}
+
+		if acl.Bucket == BucketACLWildcard && matched == nil {
+			matched = acl
+		}
+	}
+
+	if matched == nil {
+		return nil
+	}
+
+	if containsMethod(matched.Deny, method) {
+		return fmt.Errorf("method %s is denied in bucket %s", method, bucketName)
+	}
+
+	if len(matched.Allow) > 0 && !containsMethod(matched.Allow, method) {
+		return fmt.Errorf("method %s is not allowed in bucket %s", method, bucketName)
+	}
+
+	if len(matched.Tokens) > 0 {
+		for _, t := range matched.Tokens {
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("invalid token of bucket %s", bucketName)
+	}
+
+	return nil
+}
+
+// containsMethod returns whether the http method is in methods case-insensitively.
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,195 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objectstorage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/config"
+)
+
+func TestObjectStorage_checkBucketAccess(t *testing.T) {
+	acls := []*config.BucketACL{
+		{
+			Bucket: "foo",
+			Allow:  []string{"GET", "HEAD"},
+		},
+		{
+			Bucket: "bar",
+			Deny:   []string{"delete"},
+			Tokens: []string{"baz"},
+		},
+		{
+			Bucket: BucketACLWildcard,
+			Deny:   []string{"GET", "PUT", "DELETE"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		acls   []*config.BucketACL
+		method string
+		bucket string
+		token  string
+		expect func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "allowed without acls",
+			acls:   nil,
+			method: http.MethodDelete,
+			bucket: "foo",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+		{
+			name:   "allowed by method",
+			acls:   acls,
+			method: http.MethodGet,
+			bucket: "foo",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+		{
+			name:   "allowed with token",
+			acls:   acls,
+			method: http.MethodPut,
+			bucket: "bar",
+			token:  "baz",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+		{
+			name:   "denied by method not in allow list",
+			acls:   acls,
+			method: http.MethodPut,
+			bucket: "foo",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusForbidden, w.Code)
+				assert.Contains(w.Body.String(), string(ErrorCodeAccessDenied))
+				assert.Contains(w.Body.String(), "method PUT is not allowed in bucket foo")
+			},
+		},
+		{
+			name:   "denied by method in deny list",
+			acls:   acls,
+			method: http.MethodDelete,
+			bucket: "bar",
+			token:  "baz",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusForbidden, w.Code)
+				assert.Contains(w.Body.String(), "method DELETE is denied in bucket bar")
+			},
+		},
+		{
+			name:   "denied by invalid token",
+			acls:   acls,
+			method: http.MethodGet,
+			bucket: "bar",
+			token:  "foo",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusForbidden, w.Code)
+				assert.Contains(w.Body.String(), "invalid token of bucket bar")
+			},
+		},
+		{
+			name:   "denied by bucket wildcard",
+			acls:   acls,
+			method: http.MethodGet,
+			bucket: "baz",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusForbidden, w.Code)
+				assert.Contains(w.Body.String(), "method GET is denied in bucket baz")
+			},
+		},
+		{
+			name:   "allowed by bucket wildcard",
+			acls:   acls,
+			method: http.MethodHead,
+			bucket: "baz",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &objectStorage{}
+			o.OnNotify(&config.DynconfigData{BucketACLs: tc.acls})
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			b := r.Group(RouterGroupBuckets, o.checkBucketAccess)
+			b.Handle(tc.method, ":id/objects/*object_key", func(ctx *gin.Context) {
+				ctx.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tc.method, "/buckets/"+tc.bucket+"/objects/foo", nil)
+			if tc.token != "" {
+				req.Header.Set(config.HeaderDragonflyObjectToken, tc.token)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			tc.expect(t, w)
+		})
+	}
+}
+
+func TestObjectStorage_OnNotify(t *testing.T) {
+	assert := assert.New(t)
+	o := &objectStorage{}
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(ErrorHandler())
+	b := r.Group(RouterGroupBuckets, o.checkBucketAccess)
+	b.GET(":id/objects/*object_key", func(ctx *gin.Context) {
+		ctx.Status(http.StatusOK)
+	})
+
+	get := func() int {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buckets/foo/objects/bar", nil))
+		return w.Code
+	}
+
+	assert.Equal(http.StatusOK, get())
+
+	o.OnNotify(&config.DynconfigData{BucketACLs: []*config.BucketACL{{Bucket: "foo", Deny: []string{"GET"}}}})
+	assert.Equal(http.StatusForbidden, get())
+
+	o.OnNotify(&config.DynconfigData{})
+	assert.Equal(http.StatusOK, get())
+}
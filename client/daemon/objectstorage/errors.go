@@ -0,0 +1,174 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objectstorage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	"d7y.io/dragonfly/v2/pkg/digest"
+)
+
+// ErrorCode is the code of the object storage error response.
+type ErrorCode string
+
+const (
+	// ErrorCodeBackendError is the code of errors returned by the object storage backend.
+	ErrorCodeBackendError ErrorCode = "backend_error"
+
+	// ErrorCodeNotFound is the code of errors when the object or task is not found.
+	ErrorCodeNotFound ErrorCode = "not_found"
+
+	// ErrorCodeAlreadyExists is the code of errors when the object already exists.
+	ErrorCodeAlreadyExists ErrorCode = "already_exists"
+
+	// ErrorCodeBadDigest is the code of errors when the digest of the object does not match.
+	ErrorCodeBadDigest ErrorCode = "bad_digest"
+
+	// ErrorCodeP2PUnavailable is the code of errors when the p2p network is unavailable,
+	// e.g. the scheduler is unreachable.
+	ErrorCodeP2PUnavailable ErrorCode = "p2p_unavailable"
+
+	// ErrorCodeValidationFailed is the code of errors when the request is invalid.
+	ErrorCodeValidationFailed ErrorCode = "validation_failed"
+
+	// ErrorCodeAccessDenied is the code of errors when the request is denied by the bucket acl.
+	ErrorCodeAccessDenied ErrorCode = "access_denied"
+
+	// ErrorCodeEntityTooLarge is the code of errors when the uploaded object exceeds the size limit.
+	ErrorCodeEntityTooLarge ErrorCode = "entity_too_large"
+)
+
+// errorCodeStatus is the http status of the error code.
+var errorCodeStatus = map[ErrorCode]int{
+	ErrorCodeBackendError:     http.StatusInternalServerError,
+	ErrorCodeNotFound:         http.StatusNotFound,
+	ErrorCodeAlreadyExists:    http.StatusConflict,
+	ErrorCodeBadDigest:        http.StatusBadRequest,
+	ErrorCodeP2PUnavailable:   http.StatusServiceUnavailable,
+	ErrorCodeValidationFailed: http.StatusUnprocessableEntity,
+	ErrorCodeAccessDenied:     http.StatusForbidden,
+	ErrorCodeEntityTooLarge:   http.StatusRequestEntityTooLarge,
+}
+
+// ErrorResponse is the body of the object storage error response.
+type ErrorResponse struct {
+	// Code is the error code.
+	Code ErrorCode `json:"code"`
+
+	// Message is the error message.
+	Message string `json:"message"`
+
+	// Details is the error details, e.g. the error codes of every seed peer.
+	Details map[string]ErrorCode `json:"details,omitempty"`
+}
+
+// Error is the error with the code of object storage error response.
+type Error struct {
+	// Code is the error code.
+	Code ErrorCode
+
+	// Status is the http status of the response, the status
+	// of the code is used when it is zero.
+	Status int
+
+	// Err is the underlying error.
+	Err error
+
+	// Details is the error details.
+	Details map[string]ErrorCode
+}
+
+// NewError returns a new Error with the code.
+func NewError(code ErrorCode, err error) *Error {
+	return &Error{
+		Code: code,
+		Err:  err,
+	}
+}
+
+// Error returns the message of the error.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorFrom maps err to the Error with the code of object storage error response,
+// errors that can not be recognized are regarded as backend errors.
+func ErrorFrom(err error) *Error {
+	var oerr *Error
+	if errors.As(err, &oerr) {
+		return oerr
+	}
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return NewError(ErrorCodeEntityTooLarge, fmt.Errorf("object exceeds the size limit of %d bytes", maxBytesErr.Limit))
+	}
+
+	// The content of the object does not match the digest in the request.
+	if errors.Is(err, digest.ErrDigestMismatch) {
+		return &Error{
+			Code:   ErrorCodeBadDigest,
+			Status: http.StatusUnprocessableEntity,
+			Err:    err,
+		}
+	}
+
+	switch {
+	case errors.Is(err, storage.ErrTaskNotFound), errors.Is(err, storage.ErrPieceNotFound):
+		return NewError(ErrorCodeNotFound, err)
+	case errors.Is(err, storage.ErrInvalidDigest), errors.Is(err, storage.ErrDigestNotSet):
+		return NewError(ErrorCodeBadDigest, err)
+	case errors.Is(err, storage.ErrBadRequest):
+		return NewError(ErrorCodeValidationFailed, err)
+	default:
+		return NewError(ErrorCodeBackendError, err)
+	}
+}
+
+// ErrorHandler is the middleware that writes the last error of the handler as ErrorResponse,
+// handlers report errors with ctx.Error instead of writing the response by themselves.
+func ErrorHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+		err := ctx.Errors.Last()
+		if err == nil {
+			return
+		}
+
+		oerr := ErrorFrom(err.Err)
+		status := oerr.Status
+		if status == 0 {
+			status = errorCodeStatus[oerr.Code]
+		}
+
+		ctx.AbortWithStatusJSON(status, ErrorResponse{
+			Code:    oerr.Code,
+			Message: oerr.Error(),
+			Details: oerr.Details,
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objectstorage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	"d7y.io/dragonfly/v2/pkg/digest"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		expect func(t *testing.T, code int, resp ErrorResponse)
+	}{
+		{
+			name: "validation failed",
+			err:  NewError(ErrorCodeValidationFailed, errors.New("foo")),
+			expect: func(t *testing.T, code int, resp ErrorResponse) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, code)
+				assert.Equal(ErrorCodeValidationFailed, resp.Code)
+				assert.Equal("foo", resp.Message)
+			},
+		},
+		{
+			name: "status overrides the status of code",
+			err:  &Error{Code: ErrorCodeValidationFailed, Status: http.StatusRequestedRangeNotSatisfiable, Err: errors.New("foo")},
+			expect: func(t *testing.T, code int, resp ErrorResponse) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusRequestedRangeNotSatisfiable, code)
+				assert.Equal(ErrorCodeValidationFailed, resp.Code)
+			},
+		},
+		{
+			name: "task not found in storage",
+			err:  fmt.Errorf("foo: %w", storage.ErrTaskNotFound),
+			expect: func(t *testing.T, code int, resp ErrorResponse) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotFound, code)
+				assert.Equal(ErrorCodeNotFound, resp.Code)
+			},
+		},
+		{
+			name: "invalid digest in storage",
+			err:  storage.ErrInvalidDigest,
+			expect: func(t *testing.T, code int, resp ErrorResponse) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusBadRequest, code)
+				assert.Equal(ErrorCodeBadDigest, resp.Code)
+			},
+		},
+		{
+			name: "digest of object does not match",
+			err:  fmt.Errorf("foo: %w", digest.ErrDigestMismatch),
+			expect: func(t *testing.T, code int, resp ErrorResponse) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, code)
+				assert.Equal(ErrorCodeBadDigest, resp.Code)
+			},
+		},
+		{
+			name: "p2p unavailable with details",
+			err: &Error{
+				Code:    ErrorCodeP2PUnavailable,
+				Err:     errors.New("foo"),
+				Details: map[string]ErrorCode{"127.0.0.1:65004": ErrorCodeBackendError},
+			},
+			expect: func(t *testing.T, code int, resp ErrorResponse) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusServiceUnavailable, code)
+				assert.Equal(ErrorCodeP2PUnavailable, resp.Code)
+				assert.Equal(map[string]ErrorCode{"127.0.0.1:65004": ErrorCodeBackendError}, resp.Details)
+			},
+		},
+		{
+			name: "unknown error is backend error",
+			err:  errors.New("foo"),
+			expect: func(t *testing.T, code int, resp ErrorResponse) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusInternalServerError, code)
+				assert.Equal(ErrorCodeBackendError, resp.Code)
+				assert.Equal("foo", resp.Message)
+			},
+		},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/", func(ctx *gin.Context) {
+				ctx.Error(tc.err) // nolint: errcheck
+			})
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			var resp ErrorResponse
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			tc.expect(t, w.Code, resp)
+		})
+	}
+}
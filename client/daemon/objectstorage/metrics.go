@@ -0,0 +1,60 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objectstorage
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// getObjectCacheStatusNone is the cache status of the getting object request
+// which ends before the stream task is started, e.g. the object is not found.
+const getObjectCacheStatusNone = "none"
+
+var (
+	// GetObjectCount is the count of getting object requests, labeled by the cache status
+	// of the stream task and the bucket.
+	GetObjectCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: PrometheusSubsystemName,
+		Name:      "get_object_requests_total",
+		Help:      "Counter of the number of getting object requests.",
+	}, []string{"cache_status", "bucket"})
+
+	// GetObjectServedBytes is the bytes served by getting object requests, labeled by the cache
+	// status of the stream task and the bucket.
+	GetObjectServedBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: PrometheusSubsystemName,
+		Name:      "get_object_bytes_served_total",
+		Help:      "Counter of the bytes served by getting object requests.",
+	}, []string{"cache_status", "bucket"})
+
+	// GetObjectCacheHitCount is the count of getting object requests served by the local
+	// or peer cache without downloading from the source, labeled by the bucket.
+	GetObjectCacheHitCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: PrometheusSubsystemName,
+		Name:      "get_object_cache_hit_total",
+		Help:      "Counter of the number of getting object requests served by the p2p cache.",
+	}, []string{"bucket"})
+
+	// GetObjectCacheMissCount is the count of getting object requests which download
+	// from the source, labeled by the bucket.
+	GetObjectCacheMissCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Subsystem: PrometheusSubsystemName,
+		Name:      "get_object_cache_miss_total",
+		Help:      "Counter of the number of getting object requests downloaded from the source.",
+	}, []string{"bucket"})
+)
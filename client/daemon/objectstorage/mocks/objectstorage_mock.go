@@ -13,6 +13,7 @@ import (
 	net "net"
 	reflect "reflect"
 
+	config "d7y.io/dragonfly/v2/client/config"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -66,3 +67,15 @@ func (mr *MockObjectStorageMockRecorder) Stop() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockObjectStorage)(nil).Stop))
 }
+
+// OnNotify mocks base method.
+func (m *MockObjectStorage) OnNotify(arg0 *config.DynconfigData) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnNotify", arg0)
+}
+
+// OnNotify indicates an expected call of OnNotify.
+func (mr *MockObjectStorageMockRecorder) OnNotify(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnNotify", reflect.TypeOf((*MockObjectStorage)(nil).OnNotify), arg0)
+}
@@ -21,6 +21,8 @@ package objectstorage
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -33,6 +35,7 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -48,9 +51,9 @@ import (
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/idgen"
+	pkgio "d7y.io/dragonfly/v2/pkg/io"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 	"d7y.io/dragonfly/v2/pkg/objectstorage"
-	pkgstrings "d7y.io/dragonfly/v2/pkg/strings"
 )
 
 const (
@@ -80,6 +83,12 @@ var GinLogFileName = "gin-object-storage.log"
 const (
 	// defaultSignExpireTime is default expire of sign url.
 	defaultSignExpireTime = 5 * time.Minute
+
+	// readinessTimeout is the timeout of checking the backend by the readiness probe.
+	readinessTimeout = 5 * time.Second
+
+	// maxObjectRanges is the max number of ranges in the range header of getting object.
+	maxObjectRanges = 64
 )
 
 // ObjectStorage is the interface used for object storage server.
@@ -89,6 +98,9 @@ type ObjectStorage interface {
 
 	// Stop object storage server.
 	Stop() error
+
+	// OnNotify reloads the access control rules of buckets.
+	OnNotify(*config.DynconfigData)
 }
 
 // objectStorage provides object storage function.
@@ -100,6 +112,8 @@ type objectStorage struct {
 	peerTaskManager     peer.TaskManager
 	storageManager      storage.Manager
 	peerIDGenerator     peer.IDGenerator
+	seedPeerSelector    *seedPeerSelector
+	bucketACLs          atomic.Value
 }
 
 // New returns a new ObjectStorage instance.
@@ -124,11 +138,15 @@ func New(cfg *config.DaemonOption, dynconfig config.Dynconfig, peerTaskManager p
 		peerTaskManager:     peerTaskManager,
 		storageManager:      storageManager,
 		peerIDGenerator:     peer.NewPeerIDGenerator(cfg.Host.AdvertiseIP.String()),
+		seedPeerSelector:    newSeedPeerSelector(cfg.ObjectStorage.SeedPeerSelection, cfg.Host.IDC, cfg.Host.Location),
 	}
+	o.bucketACLs.Store(cfg.ObjectStorage.BucketACLs)
 
 	router := o.initRouter(cfg, logDir)
 	o.Server = &http.Server{
-		Handler: router,
+		Handler:      router,
+		ReadTimeout:  cfg.ObjectStorage.ReadTimeout,
+		WriteTimeout: cfg.ObjectStorage.WriteTimeout,
 	}
 
 	return o, nil
@@ -164,6 +182,7 @@ func (o *objectStorage) initRouter(cfg *config.DaemonOption, logDir string) *gin
 	// Middleware.
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
+	r.Use(ErrorHandler())
 
 	// Prometheus metrics.
 	p := ginprometheus.NewPrometheus(PrometheusSubsystemName)
@@ -185,18 +204,20 @@ func (o *objectStorage) initRouter(cfg *config.DaemonOption, logDir string) *gin
 
 	// Health Check.
 	r.GET("/healthy", o.getHealth)
+	r.GET("/readyz", o.getReadiness)
 
 	// Object Storage.
 	r.GET("/metadata", o.getObjectStorageMetadata)
 
 	// Buckets.
-	b := r.Group(RouterGroupBuckets)
+	b := r.Group(RouterGroupBuckets, o.checkBucketAccess)
 	b.POST(":id", o.createBucket)
 	b.GET(":id/metadatas", o.getObjectMetadatas)
 	b.HEAD(":id/objects/*object_key", o.headObject)
 	b.GET(":id/objects/*object_key", o.getObject)
 	b.DELETE(":id/objects/*object_key", o.destroyObject)
 	b.PUT(":id/objects/*object_key", o.putObject)
+	b.POST(":id/objects/*object_key", o.postObject)
 
 	return r
 }
@@ -206,6 +227,24 @@ func (o *objectStorage) getHealth(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, http.StatusText(http.StatusOK))
 }
 
+// getReadiness uses to check whether the backend is reachable, it responds service unavailable
+// if the backend can not list buckets, so that the traffic is not routed to the broken instance.
+func (o *objectStorage) getReadiness(ctx *gin.Context) {
+	readinessCtx, cancel := context.WithTimeout(ctx, readinessTimeout)
+	defer cancel()
+
+	if _, err := o.objectStorageClient.ListBucketMetadatas(readinessCtx); err != nil {
+		ctx.Error(&Error{ // nolint: errcheck
+			Code:   ErrorCodeBackendError,
+			Status: http.StatusServiceUnavailable,
+			Err:    fmt.Errorf("backend is unreachable: %w", err),
+		})
+		return
+	}
+
+	ctx.JSON(http.StatusOK, http.StatusText(http.StatusOK))
+}
+
 // getObjectStorageMetadata uses to get object storage metadata.
 func (o *objectStorage) getObjectStorageMetadata(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, o.objectStorageClient.GetMetadata(ctx))
@@ -215,7 +254,7 @@ func (o *objectStorage) getObjectStorageMetadata(ctx *gin.Context) {
 func (o *objectStorage) headObject(ctx *gin.Context) {
 	var params ObjectParams
 	if err := ctx.ShouldBindUri(&params); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
@@ -226,12 +265,12 @@ func (o *objectStorage) headObject(ctx *gin.Context) {
 
 	meta, isExist, err := o.objectStorageClient.GetObjectMetadata(ctx, bucketName, objectKey)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
 		return
 	}
 
 	if !isExist {
-		ctx.JSON(http.StatusNotFound, gin.H{"errors": http.StatusText(http.StatusNotFound)})
+		ctx.Error(NewError(ErrorCodeNotFound, fmt.Errorf("object %s not found in bucket %s", objectKey, bucketName))) // nolint: errcheck
 		return
 	}
 
@@ -251,55 +290,103 @@ func (o *objectStorage) headObject(ctx *gin.Context) {
 
 // getObject uses to download object data.
 func (o *objectStorage) getObject(ctx *gin.Context) {
+	// The request is counted on every exit, including the failed ones.
+	var (
+		bucketName  = ctx.Param("id")
+		cacheStatus = getObjectCacheStatusNone
+	)
+	defer func() {
+		GetObjectCount.WithLabelValues(cacheStatus, bucketName).Inc()
+	}()
+
 	var params ObjectParams
 	if err := ctx.ShouldBindUri(&params); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
 	var query GetObjectQuery
 	if err := ctx.ShouldBindQuery(&query); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
 	var (
-		bucketName = params.ID
-		objectKey  = strings.TrimPrefix(params.ObjectKey, string(os.PathSeparator))
-		filter     = query.Filter
-		err        error
+		objectKey = strings.TrimPrefix(params.ObjectKey, string(os.PathSeparator))
+		filter    = query.Filter
+		err       error
 	)
 
-	// Initialize request of the stream task.
-	req := &peer.StreamTaskRequest{
-		PeerID: o.peerIDGenerator.PeerID(),
+	pieceSize, err := o.pieceSize(ctx)
+	if err != nil {
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
+		return
 	}
 
-	// Initialize filter field.
-	urlMeta := &commonv1.UrlMeta{Filter: o.config.ObjectStorage.Filter}
-	if filter != "" {
-		urlMeta.Filter = filter
+	// Initialize request of the stream task.
+	req := &peer.StreamTaskRequest{
+		PeerID:    o.peerIDGenerator.PeerID(),
+		PieceSize: pieceSize,
 	}
 
 	meta, isExist, err := o.objectStorageClient.GetObjectMetadata(ctx, bucketName, objectKey)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
 		return
 	}
 
 	if !isExist {
-		ctx.JSON(http.StatusNotFound, gin.H{"errors": http.StatusText(http.StatusNotFound)})
+		ctx.Error(NewError(ErrorCodeNotFound, fmt.Errorf("object %s not found in bucket %s", objectKey, bucketName))) // nolint: errcheck
 		return
 	}
 
-	urlMeta.Digest = meta.Digest
+	urlMeta := o.objectURLMeta(meta, filter)
+
+	// Conditional headers are evaluated before the range header, if the object
+	// is not modified, return without starting the stream task.
+	extraHeaders := objectValidatorHeaders(meta)
+	if isObjectNotModified(ctx.Request, meta) {
+		for k, v := range extraHeaders {
+			ctx.Header(k, v)
+		}
+
+		ctx.Status(http.StatusNotModified)
+		return
+	}
 
-	// Parse http range header.
+	// Parse http range header, the range header is ignored when If-Range does not match.
 	rangeHeader := ctx.GetHeader(headers.Range)
+	if len(rangeHeader) > 0 && !isIfRangeMatched(ctx.Request, meta) {
+		logger.Infof("if-range of object %s in bucket %s does not match, ignore range %s", objectKey, bucketName, rangeHeader)
+		rangeHeader = ""
+	}
+
+	// Multiple ranges are resolved with the content length of the object and coalesced,
+	// if more than one range remains, they are served in multipart/byteranges.
+	var ranges []nethttp.Range
 	if len(rangeHeader) > 0 {
+		if rs, err := nethttp.ParseRange(rangeHeader, meta.ContentLength); err == nil && len(rs) > 1 {
+			if len(rs) > maxObjectRanges {
+				ctx.Error(&Error{Code: ErrorCodeValidationFailed, Status: http.StatusRequestedRangeNotSatisfiable, Err: fmt.Errorf("range header has more than %d ranges", maxObjectRanges)}) // nolint: errcheck
+				return
+			}
+
+			ranges = nethttp.CoalesceRanges(rs)
+			if len(ranges) == 1 {
+				rangeHeader = ranges[0].String()
+				ranges = nil
+			}
+		}
+	}
+
+	if len(ranges) > 1 {
+		// When the request has a range header,
+		// there is no need to calculate md5, set this value to empty.
+		urlMeta.Digest = ""
+	} else if len(rangeHeader) > 0 {
 		rangeValue, err := nethttp.ParseOneRange(rangeHeader, math.MaxInt64)
 		if err != nil {
-			ctx.JSON(http.StatusRequestedRangeNotSatisfiable, gin.H{"errors": err.Error()})
+			ctx.Error(&Error{Code: ErrorCodeValidationFailed, Status: http.StatusRequestedRangeNotSatisfiable, Err: err}) // nolint: errcheck
 			return
 		}
 		req.Range = &rangeValue
@@ -315,22 +402,55 @@ func (o *objectStorage) getObject(ctx *gin.Context) {
 
 	signURL, err := o.objectStorageClient.GetSignURL(ctx, bucketName, objectKey, objectstorage.MethodGet, defaultSignExpireTime)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
 		return
 	}
 	req.URL = signURL
 
+	if len(ranges) > 1 {
+		cacheStatus = o.getObjectRanges(ctx, req, meta, ranges, extraHeaders, bucketName, objectKey)
+		return
+	}
+
+	// Pass through the content encoding of object, so the client decodes the raw bytes.
+	if meta.ContentEncoding != "" {
+		extraHeaders[headers.ContentEncoding] = meta.ContentEncoding
+	}
+
 	taskID := req.TaskID()
 	log := logger.WithTaskID(taskID)
 	log.Infof("get object %s meta: %s %#v", objectKey, signURL, urlMeta)
+	ctx.Header(config.HeaderDragonflyTaskID, taskID)
 
 	reader, attr, err := o.peerTaskManager.StartStreamTask(ctx, req)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		cacheStatus = peer.CacheStatusMiss
+		ctx.Header(config.HeaderDragonflyCache, cacheStatus)
+		ctx.Error(NewError(ErrorCodeP2PUnavailable, err)) // nolint: errcheck
 		return
 	}
 	defer reader.Close()
 
+	cacheStatus = attr[config.HeaderDragonflyCache]
+	if cacheStatus == "" {
+		cacheStatus = peer.CacheStatusMiss
+	}
+	extraHeaders[config.HeaderDragonflyCache] = cacheStatus
+
+	countingReader := pkgio.NewCountingReadCloser(reader)
+	defer func() {
+		GetObjectServedBytes.WithLabelValues(cacheStatus, bucketName).Add(float64(countingReader.BytesRead()))
+
+		// The request is a cache hit when the stream is served without downloading from the source,
+		// it is known after the stream is finished.
+		if r, ok := reader.(peer.BackSourceReporter); ok && r.BackSource() {
+			GetObjectCacheMissCount.WithLabelValues(bucketName).Inc()
+			return
+		}
+
+		GetObjectCacheHitCount.WithLabelValues(bucketName).Inc()
+	}()
+
 	var contentLength int64 = -1
 	if l, ok := attr[headers.ContentLength]; ok {
 		if i, err := strconv.ParseInt(l, 10, 64); err == nil {
@@ -338,15 +458,278 @@ func (o *objectStorage) getObject(ctx *gin.Context) {
 		}
 	}
 
-	log.Infof("object content length is %d and content type is %s", contentLength, attr[headers.ContentType])
-	ctx.DataFromReader(http.StatusOK, contentLength, attr[headers.ContentType], reader, nil)
+	log.Infof("object content length is %d, content type is %s and cache status is %s", contentLength, attr[headers.ContentType], cacheStatus)
+	ctx.DataFromReader(http.StatusOK, contentLength, attr[headers.ContentType], countingReader, extraHeaders)
+}
+
+// getObjectRanges serves the multiple ranges of the object in multipart/byteranges, the ranges are
+// read in order from one stream task spanning them, refer to https://www.rfc-editor.org/rfc/rfc9110#section-14.6.
+// It returns the cache status of the stream task.
+func (o *objectStorage) getObjectRanges(ctx *gin.Context, req *peer.StreamTaskRequest, meta *objectstorage.ObjectMetadata,
+	ranges []nethttp.Range, extraHeaders map[string]string, bucketName, objectKey string) (cacheStatus string) {
+	log := logger.With("bucket", bucketName, "object", objectKey)
+	boundary := multipart.NewWriter(io.Discard).Boundary()
+	contentLength, err := nethttp.MultipartByterangesSize(ranges, boundary, meta.ContentType, meta.ContentLength)
+	if err != nil {
+		log.Warnf("compute content length of ranges failed: %s", err)
+		contentLength = -1
+	}
+
+	// The stream task is started before writing the response header,
+	// so that the error of the p2p network is returned with the status code.
+	last := ranges[len(ranges)-1]
+	span := nethttp.Range{Start: ranges[0].Start, Length: last.Start + last.Length - ranges[0].Start}
+	reader, attr, err := o.startRangeStreamTask(ctx, req, span, meta, log)
+	if err != nil {
+		cacheStatus = peer.CacheStatusMiss
+		ctx.Header(config.HeaderDragonflyCache, cacheStatus)
+		ctx.Error(NewError(ErrorCodeP2PUnavailable, err)) // nolint: errcheck
+		return
+	}
+	defer reader.Close()
+
+	cacheStatus = attr[config.HeaderDragonflyCache]
+	if cacheStatus == "" {
+		cacheStatus = peer.CacheStatusMiss
+	}
+	extraHeaders[config.HeaderDragonflyCache] = cacheStatus
+
+	var servedBytes int64
+	defer func() {
+		GetObjectServedBytes.WithLabelValues(cacheStatus, bucketName).Add(float64(servedBytes))
+		if r, ok := reader.(peer.BackSourceReporter); ok && r.BackSource() {
+			GetObjectCacheMissCount.WithLabelValues(bucketName).Inc()
+			return
+		}
+
+		GetObjectCacheHitCount.WithLabelValues(bucketName).Inc()
+	}()
+
+	for k, v := range extraHeaders {
+		ctx.Header(k, v)
+	}
+	if contentLength >= 0 {
+		ctx.Header(headers.ContentLength, strconv.FormatInt(contentLength, 10))
+	}
+	ctx.Header(headers.ContentType, "multipart/byteranges; boundary="+boundary)
+	ctx.Status(http.StatusPartialContent)
+
+	log.Infof("get object in %d ranges, content length is %d and cache status is %s", len(ranges), contentLength, cacheStatus)
+	mw := multipart.NewWriter(ctx.Writer)
+	if err := mw.SetBoundary(boundary); err != nil {
+		log.Errorf("set boundary failed: %s", err)
+		return
+	}
+
+	// The response header is written, the response is aborted if any range fails.
+	offset := span.Start
+	for _, rg := range ranges {
+		// Skip the bytes between the ranges, the ranges are sorted and not overlapping.
+		if _, err := io.CopyN(io.Discard, reader, rg.Start-offset); err != nil {
+			log.Errorf("skip to range %s failed: %s", rg.String(), err)
+			return
+		}
+
+		part, err := mw.CreatePart(rg.MIMEHeader(meta.ContentType, meta.ContentLength))
+		if err != nil {
+			log.Errorf("create part of range %s failed: %s", rg.String(), err)
+			return
+		}
+
+		n, err := io.CopyN(part, reader, rg.Length)
+		servedBytes += n
+		if err != nil {
+			log.Errorf("write part of range %s failed: %s", rg.String(), err)
+			return
+		}
+
+		offset = rg.Start + rg.Length
+	}
+
+	if err := mw.Close(); err != nil {
+		log.Errorf("close multipart writer failed: %s", err)
+	}
+
+	return
+}
+
+// objectURLMeta returns the url meta of downloading the object through the p2p network, the filter
+// of the request overrides the default filter. The object stored with content encoding is requested
+// with the same encoding explicitly, so the http client of back-to-source does not decode it
+// transparently, and the cached bytes are the raw bytes of the object.
+func (o *objectStorage) objectURLMeta(meta *objectstorage.ObjectMetadata, filter string) *commonv1.UrlMeta {
+	urlMeta := &commonv1.UrlMeta{Filter: o.config.ObjectStorage.Filter, Digest: meta.Digest}
+	if filter != "" {
+		urlMeta.Filter = filter
+	}
+
+	if meta.ContentEncoding != "" {
+		urlMeta.Header = map[string]string{headers.AcceptEncoding: meta.ContentEncoding}
+	}
+
+	return urlMeta
+}
+
+// startRangeStreamTask starts the stream task of the range with the url meta of the request,
+// the task of the whole object is started if the range covers it, so that the cache is shared
+// with the requests without range header.
+func (o *objectStorage) startRangeStreamTask(ctx context.Context, req *peer.StreamTaskRequest, rg nethttp.Range, meta *objectstorage.ObjectMetadata,
+	log *logger.SugaredLoggerOnWith) (io.ReadCloser, map[string]string, error) {
+	rangeReq := &peer.StreamTaskRequest{
+		URL: req.URL,
+		URLMeta: &commonv1.UrlMeta{
+			Digest:      meta.Digest,
+			Tag:         req.URLMeta.Tag,
+			Filter:      req.URLMeta.Filter,
+			Header:      req.URLMeta.Header,
+			Application: req.URLMeta.Application,
+			Priority:    req.URLMeta.Priority,
+		},
+		PeerID:    o.peerIDGenerator.PeerID(),
+		PieceSize: req.PieceSize,
+	}
+
+	if rg.Start != 0 || rg.Length != meta.ContentLength {
+		// Range in dragonfly is without "bytes=".
+		rangeReq.URLMeta.Range = rg.URLMetaString()
+		rangeReq.Range = &rg
+
+		// The digest of the object does not apply to the range.
+		rangeReq.URLMeta.Digest = ""
+	}
+
+	log.Infof("start stream task %s of range %s", rangeReq.TaskID(), rg.String())
+	return o.peerTaskManager.StartStreamTask(ctx, rangeReq)
+}
+
+// postObject handles the action on the object, the action is the last segment of the object path,
+// e.g. POST /buckets/:id/objects/:object_key/preheat.
+func (o *objectStorage) postObject(ctx *gin.Context) {
+	if strings.HasSuffix(ctx.Param("object_key"), "/"+PreheatAction) {
+		o.preheatObject(ctx)
+		return
+	}
+
+	ctx.Error(NewError(ErrorCodeNotFound, fmt.Errorf("action of object %s is not found", ctx.Param("object_key")))) // nolint: errcheck
+}
+
+// preheatObject starts a stream task to download the object into the p2p cache,
+// the content is discarded in background, so that the first request of the object
+// does not pay for back-to-source.
+func (o *objectStorage) preheatObject(ctx *gin.Context) {
+	var params ObjectParams
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
+		return
+	}
+
+	var query GetObjectQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
+		return
+	}
+
+	var (
+		bucketName = params.ID
+		objectKey  = strings.TrimSuffix(strings.TrimPrefix(params.ObjectKey, string(os.PathSeparator)), "/"+PreheatAction)
+		filter     = query.Filter
+	)
+
+	pieceSize, err := o.pieceSize(ctx)
+	if err != nil {
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
+		return
+	}
+
+	meta, isExist, err := o.objectStorageClient.GetObjectMetadata(ctx, bucketName, objectKey)
+	if err != nil {
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
+		return
+	}
+
+	if !isExist {
+		ctx.Error(NewError(ErrorCodeNotFound, fmt.Errorf("object %s not found in bucket %s", objectKey, bucketName))) // nolint: errcheck
+		return
+	}
+
+	// The url meta is the same as getObject without range, so that the preheated task is reused.
+	urlMeta := o.objectURLMeta(meta, filter)
+
+	signURL, err := o.objectStorageClient.GetSignURL(ctx, bucketName, objectKey, objectstorage.MethodGet, defaultSignExpireTime)
+	if err != nil {
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
+		return
+	}
+
+	req := &peer.StreamTaskRequest{
+		URL:       signURL,
+		URLMeta:   urlMeta,
+		PeerID:    o.peerIDGenerator.PeerID(),
+		PieceSize: pieceSize,
+	}
+
+	taskID := req.TaskID()
+	log := logger.WithTaskID(taskID)
+	log.Infof("preheat object %s in bucket %s: %s %#v", objectKey, bucketName, signURL, urlMeta)
+
+	// The stream task outlives the request, it is not canceled when the response is returned.
+	reader, attr, err := o.peerTaskManager.StartStreamTask(context.WithoutCancel(ctx.Request.Context()), req)
+	if err != nil {
+		ctx.Error(NewError(ErrorCodeP2PUnavailable, err)) // nolint: errcheck
+		return
+	}
+
+	cacheStatus := attr[config.HeaderDragonflyCache]
+	if cacheStatus == "" {
+		cacheStatus = peer.CacheStatusMiss
+	}
+
+	if cacheStatus == peer.CacheStatusHit {
+		// The object is already in the local storage.
+		reader.Close()
+	} else {
+		go func() {
+			defer reader.Close()
+			n, err := io.Copy(io.Discard, reader)
+			if err != nil {
+				log.Errorf("preheat object %s in bucket %s failed: %s", objectKey, bucketName, err)
+				return
+			}
+
+			log.Infof("preheat object %s in bucket %s finished, %d bytes", objectKey, bucketName, n)
+		}()
+	}
+
+	ctx.Header(config.HeaderDragonflyTaskID, taskID)
+	ctx.Header(config.HeaderDragonflyCache, cacheStatus)
+	ctx.JSON(http.StatusAccepted, &PreheatObjectResponse{
+		TaskID:      taskID,
+		CacheStatus: cacheStatus,
+	})
+}
+
+// pieceSize returns the piece size hint of the stream task, the X-Dragonfly-Piece-Size
+// header takes precedence over the piece size in config.
+func (o *objectStorage) pieceSize(ctx *gin.Context) (uint32, error) {
+	pieceSize := o.config.ObjectStorage.PieceSize
+	if value := ctx.GetHeader(config.HeaderDragonflyPieceSize); value != "" {
+		if err := pieceSize.Set(value); err != nil {
+			return 0, err
+		}
+
+		if pieceSize < config.MinObjectStoragePieceSize || pieceSize > config.MaxObjectStoragePieceSize {
+			return 0, fmt.Errorf("piece size must be between %s and %s", config.MinObjectStoragePieceSize, config.MaxObjectStoragePieceSize)
+		}
+	}
+
+	return uint32(pieceSize), nil
 }
 
 // destroyObject uses to delete object data.
 func (o *objectStorage) destroyObject(ctx *gin.Context) {
 	var params ObjectParams
 	if err := ctx.ShouldBindUri(&params); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
@@ -357,7 +740,7 @@ func (o *objectStorage) destroyObject(ctx *gin.Context) {
 
 	logger.Infof("destroy object %s in bucket %s", objectKey, bucketName)
 	if err := o.objectStorageClient.DeleteObject(ctx, bucketName, objectKey); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
 		return
 	}
 
@@ -375,13 +758,25 @@ func (o *objectStorage) putObject(ctx *gin.Context) {
 
 	var params ObjectParams
 	if err := ctx.ShouldBindUri(&params); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
+	// Limit the size of the request body, so that the upload can not exhaust the disk.
+	if maxObjectSize := o.config.ObjectStorage.MaxObjectSize; maxObjectSize > 0 {
+		ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxObjectSize.ToNumber())
+	}
+
 	var form PutObjectRequest
 	if err := ctx.ShouldBind(&form); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		// The error of exceeding the size limit is mapped to entity too large by ErrorHandler.
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			ctx.Error(err) // nolint: errcheck
+			return
+		}
+
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
@@ -394,15 +789,38 @@ func (o *objectStorage) putObject(ctx *gin.Context) {
 		fileHeader  = form.File
 	)
 
+	// The digest provided by the client is verified while importing the object to local storage,
+	// otherwise the digest is calculated from the uploaded file.
+	var (
+		dgst      *digest.Digest
+		algorithm string
+		err       error
+	)
+	if form.Digest != "" {
+		if dgst, err = parseObjectDigest(form.Digest); err != nil {
+			ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
+			return
+		}
+	} else if algorithm, err = o.digestAlgorithm(ctx.Request.Header); err != nil {
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
+		return
+	}
+
 	signURL, err := o.objectStorageClient.GetSignURL(ctx, bucketName, objectKey, objectstorage.MethodGet, defaultSignExpireTime)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
 		return
 	}
 
+	if dgst == nil {
+		if dgst, err = o.digestFromFileHeader(fileHeader, algorithm); err != nil {
+			ctx.Error(err) // nolint: errcheck
+			return
+		}
+	}
+
 	// Initialize url meta.
 	urlMeta := &commonv1.UrlMeta{Filter: o.config.ObjectStorage.Filter}
-	dgst := o.md5FromFileHeader(fileHeader)
 	urlMeta.Digest = dgst.String()
 	if filter != "" {
 		urlMeta.Filter = filter
@@ -413,6 +831,18 @@ func (o *objectStorage) putObject(ctx *gin.Context) {
 		maxReplicas = o.config.ObjectStorage.MaxReplicas
 	}
 
+	// The retry of uploading the same object is an idempotent success,
+	// and the object is not written back to the backend again. The existing
+	// object is overwritten unless the request has If-None-Match: *.
+	var writtenBack bool
+	if mode == WriteBack || mode == AsyncWriteBack {
+		ifNoneMatch := ctx.GetHeader(headers.IfNoneMatch) == "*"
+		if writtenBack, err = o.isObjectWrittenBack(ctx, bucketName, objectKey, dgst, ifNoneMatch); err != nil {
+			ctx.Error(err) // nolint: errcheck
+			return
+		}
+	}
+
 	// Initialize task id and peer id.
 	taskID := idgen.TaskIDV1(signURL, urlMeta)
 	peerID := o.peerIDGenerator.PeerID()
@@ -422,9 +852,9 @@ func (o *objectStorage) putObject(ctx *gin.Context) {
 
 	// Import object to local storage.
 	log.Infof("import object %s to local storage", objectKey)
-	if err := o.importObjectToLocalStorage(ctx, taskID, peerID, fileHeader); err != nil {
+	if err := o.importObjectToLocalStorage(ctx, taskID, peerID, dgst, fileHeader, log); err != nil {
 		log.Error(err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(err) // nolint: errcheck
 		return
 	}
 
@@ -435,41 +865,58 @@ func (o *objectStorage) putObject(ctx *gin.Context) {
 		PeerID: peerID,
 	}, signURL, commonv1.TaskType_DfStore, urlMeta); err != nil {
 		log.Error(err)
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeP2PUnavailable, err)) // nolint: errcheck
 		return
 	}
 
 	// Handle task for backend.
+	token := ctx.GetHeader(config.HeaderDragonflyObjectToken)
 	switch mode {
 	case Ephemeral:
-		ctx.Status(http.StatusOK)
+		ctx.JSON(http.StatusOK, PutObjectResponse{Digest: dgst.String()})
 		return
 	case WriteBack:
-		// Import object to seed peer.
+		// Import object to seed peers while importing object to object storage, the failure
+		// of seed peers is returned to the client with the error code of every seed peer.
+		seedPeersErrCh := make(chan error, 1)
 		go func() {
-			if err := o.importObjectToSeedPeers(context.Background(), bucketName, objectKey, urlMeta.Filter, Ephemeral, fileHeader, maxReplicas, log); err != nil {
-				log.Errorf("import object %s to seed peers failed: %s", objectKey, err)
-			}
+			seedPeersErrCh <- o.importObjectToSeedPeers(context.Background(), bucketName, objectKey, urlMeta.Filter, token, dgst, Ephemeral, fileHeader, maxReplicas, log)
 		}()
 
-		// Import object to object storage.
-		log.Infof("import object %s to bucket %s", objectKey, bucketName)
-		if err := o.importObjectToBackend(ctx, bucketName, objectKey, dgst, fileHeader); err != nil {
-			log.Error(err)
-			ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		if writtenBack {
+			log.Infof("object %s has been written back to bucket %s", objectKey, bucketName)
+		} else {
+			// Import object to object storage.
+			log.Infof("import object %s to bucket %s", objectKey, bucketName)
+			if err := o.importObjectToBackend(ctx, bucketName, objectKey, dgst, fileHeader); err != nil {
+				log.Error(err)
+				ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
+				return
+			}
+		}
+
+		if err := <-seedPeersErrCh; err != nil {
+			log.Errorf("import object %s to seed peers failed: %s", objectKey, err)
+			ctx.Error(err) // nolint: errcheck
 			return
 		}
 
-		ctx.Status(http.StatusOK)
+		ctx.JSON(http.StatusOK, PutObjectResponse{Digest: dgst.String()})
 		return
 	case AsyncWriteBack:
 		// Import object to seed peer.
 		go func() {
-			if err := o.importObjectToSeedPeers(context.Background(), bucketName, objectKey, urlMeta.Filter, Ephemeral, fileHeader, maxReplicas, log); err != nil {
+			if err := o.importObjectToSeedPeers(context.Background(), bucketName, objectKey, urlMeta.Filter, token, dgst, Ephemeral, fileHeader, maxReplicas, log); err != nil {
 				log.Errorf("import object %s to seed peers failed: %s", objectKey, err)
 			}
 		}()
 
+		if writtenBack {
+			log.Infof("object %s has been written back to bucket %s", objectKey, bucketName)
+			ctx.JSON(http.StatusOK, PutObjectResponse{Digest: dgst.String()})
+			return
+		}
+
 		// Import object to object storage.
 		go func() {
 			log.Infof("import object %s to bucket %s", objectKey, bucketName)
@@ -479,11 +926,11 @@ func (o *objectStorage) putObject(ctx *gin.Context) {
 			}
 		}()
 
-		ctx.Status(http.StatusOK)
+		ctx.JSON(http.StatusOK, PutObjectResponse{Digest: dgst.String()})
 		return
 	}
 
-	ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": fmt.Sprintf("unknow mode %d", mode)})
+	ctx.Error(NewError(ErrorCodeValidationFailed, fmt.Errorf("unknow mode %d", mode))) // nolint: errcheck
 	return
 }
 
@@ -491,7 +938,7 @@ func (o *objectStorage) putObject(ctx *gin.Context) {
 func (o *objectStorage) createBucket(ctx *gin.Context) {
 	var params BucketParams
 	if err := ctx.ShouldBindUri(&params); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
@@ -499,7 +946,7 @@ func (o *objectStorage) createBucket(ctx *gin.Context) {
 
 	logger.Infof("create bucket %s ", bucketName)
 	if err := o.objectStorageClient.CreateBucket(ctx, bucketName); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
 		return
 	}
 
@@ -510,13 +957,13 @@ func (o *objectStorage) createBucket(ctx *gin.Context) {
 func (o *objectStorage) getObjectMetadatas(ctx *gin.Context) {
 	var params BucketParams
 	if err := ctx.ShouldBindUri(&params); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
 	var query GetObjectMetadatasQuery
 	if err := ctx.ShouldBindQuery(&query); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
@@ -531,7 +978,7 @@ func (o *objectStorage) getObjectMetadatas(ctx *gin.Context) {
 	logger.Infof("get object metadatas in bucket %s", bucketName)
 	metadatas, err := o.objectStorageClient.GetObjectMetadatas(ctx, bucketName, prefix, marker, delimiter, limit)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
 		return
 	}
 
@@ -542,13 +989,13 @@ func (o *objectStorage) getObjectMetadatas(ctx *gin.Context) {
 func (o *objectStorage) copyObject(ctx *gin.Context) {
 	var params ObjectParams
 	if err := ctx.ShouldBindUri(&params); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
 	var form CopyObjectRequest
 	if err := ctx.ShouldBind(&form); err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeValidationFailed, err)) // nolint: errcheck
 		return
 	}
 
@@ -560,29 +1007,202 @@ func (o *objectStorage) copyObject(ctx *gin.Context) {
 
 	logger.Infof("copy object from %s to %s", source, destination)
 	if err := o.objectStorageClient.CopyObject(ctx, bucketName, source, destination); err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+		ctx.Error(NewError(ErrorCodeBackendError, err)) // nolint: errcheck
 		return
 	}
 
 	ctx.Status(http.StatusOK)
 }
 
-// getAvailableSeedPeer uses to calculate md5 with file header.
-func (o *objectStorage) md5FromFileHeader(fileHeader *multipart.FileHeader) (dgst *digest.Digest) {
+// objectETag returns the quoted entity tag of the object, the digest is used
+// when the backend does not return ETag.
+func objectETag(meta *objectstorage.ObjectMetadata) string {
+	etag := meta.ETag
+	if etag == "" {
+		etag = meta.Digest
+	}
+
+	if etag == "" || strings.HasPrefix(etag, "\"") || strings.HasPrefix(etag, "W/\"") {
+		return etag
+	}
+
+	return fmt.Sprintf("%q", etag)
+}
+
+// objectValidatorHeaders returns the validator headers of the object in response.
+func objectValidatorHeaders(meta *objectstorage.ObjectMetadata) map[string]string {
+	validators := map[string]string{}
+	if etag := objectETag(meta); etag != "" {
+		validators[headers.ETag] = etag
+	}
+
+	if !meta.LastModifiedTime.IsZero() {
+		validators[headers.LastModified] = meta.LastModifiedTime.UTC().Format(http.TimeFormat)
+	}
+
+	return validators
+}
+
+// isObjectNotModified evaluates If-None-Match and If-Modified-Since of the request,
+// If-Modified-Since is ignored when the request has If-None-Match, refer to
+// https://www.rfc-editor.org/rfc/rfc9110#section-13.2.2.
+func isObjectNotModified(req *http.Request, meta *objectstorage.ObjectMetadata) bool {
+	if ifNoneMatch := req.Header.Get(headers.IfNoneMatch); ifNoneMatch != "" {
+		etag := objectETag(meta)
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" {
+				return true
+			}
+
+			// If-None-Match uses the weak comparison.
+			if etag != "" && strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	ifModifiedSince := req.Header.Get(headers.IfModifiedSince)
+	if ifModifiedSince == "" || meta.LastModifiedTime.IsZero() {
+		return false
+	}
+
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+
+	// Last-Modified has the precision of seconds.
+	return !meta.LastModifiedTime.Truncate(time.Second).After(since)
+}
+
+// isIfRangeMatched evaluates If-Range of the request, returns true if the request
+// has no If-Range or the validator matches, refer to https://www.rfc-editor.org/rfc/rfc9110#section-13.1.5.
+func isIfRangeMatched(req *http.Request, meta *objectstorage.ObjectMetadata) bool {
+	ifRange := req.Header.Get(headers.IfRange)
+	if ifRange == "" {
+		return true
+	}
+
+	// If-Range with entity tag uses the strong comparison.
+	if strings.HasPrefix(ifRange, "\"") || strings.HasPrefix(ifRange, "W/\"") {
+		etag := objectETag(meta)
+		return etag != "" && !strings.HasPrefix(etag, "W/") && ifRange == etag
+	}
+
+	if meta.LastModifiedTime.IsZero() {
+		return false
+	}
+
+	modified, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+
+	return meta.LastModifiedTime.Truncate(time.Second).Equal(modified)
+}
+
+// digestAlgorithm returns the digest algorithm of the uploaded object,
+// the algorithm in the header overrides the config.
+func (o *objectStorage) digestAlgorithm(header http.Header) (string, error) {
+	algorithm := header.Get(config.HeaderDragonflyDigestAlgorithm)
+	if algorithm == "" {
+		algorithm = o.config.ObjectStorage.DigestAlgorithm
+	}
+
+	switch strings.ToLower(algorithm) {
+	case "", digest.AlgorithmMD5:
+		return digest.AlgorithmMD5, nil
+	case digest.AlgorithmSHA256:
+		return digest.AlgorithmSHA256, nil
+	default:
+		return "", fmt.Errorf("unsupported digest algorithm %s", algorithm)
+	}
+}
+
+// parseObjectDigest parses the digest of the uploaded object provided by the client. The crc32c
+// is not supported, it is not collision resistant enough to address the content of the task.
+func parseObjectDigest(value string) (*digest.Digest, error) {
+	dgst, err := digest.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest %s: %w", value, err)
+	}
+
+	switch dgst.Algorithm {
+	case digest.AlgorithmMD5, digest.AlgorithmSHA1, digest.AlgorithmSHA256:
+	default:
+		return nil, fmt.Errorf("unsupported digest algorithm %s", dgst.Algorithm)
+	}
+
+	if _, err := hex.DecodeString(dgst.Encoded); err != nil {
+		return nil, fmt.Errorf("invalid digest %s: %w", value, err)
+	}
+
+	dgst.Encoded = strings.ToLower(dgst.Encoded)
+	return dgst, nil
+}
+
+// digestFromFileHeader uses to calculate digest with file header.
+func (o *objectStorage) digestFromFileHeader(fileHeader *multipart.FileHeader, algorithm string) (*digest.Digest, error) {
 	f, err := fileHeader.Open()
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	defer f.Close()
+
+	h, err := digest.NewHash(algorithm)
+	if err != nil {
+		return nil, err
 	}
-	defer func() {
-		if err := f.Close(); err != nil {
-			dgst = nil
+
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, err
+	}
+
+	return digest.New(algorithm, hex.EncodeToString(h.Sum(nil))), nil
+}
+
+// isObjectWrittenBack returns whether the object with the digest has been written back to the backend.
+// The object with a different or unknown digest is overwritten, unless ifNoneMatch is true,
+// then ErrorCodeAlreadyExists error is returned.
+func (o *objectStorage) isObjectWrittenBack(ctx context.Context, bucketName, objectKey string, dgst *digest.Digest, ifNoneMatch bool) (bool, error) {
+	meta, isExist, err := o.objectStorageClient.GetObjectMetadata(ctx, bucketName, objectKey)
+	if err != nil {
+		return false, NewError(ErrorCodeBackendError, err)
+	}
+
+	if !isExist {
+		return false, nil
+	}
+
+	// Use the digest of the object with the same algorithm, otherwise use the ETag which is
+	// the md5 of the object uploaded in a single part, e.g. the object is not uploaded by dragonfly.
+	var encoded string
+	if meta.Digest != "" {
+		if d, err := digest.Parse(meta.Digest); err == nil && d.Algorithm == dgst.Algorithm {
+			encoded = d.Encoded
 		}
-	}()
+	}
+
+	if etag := strings.Trim(strings.TrimPrefix(meta.ETag, "W/"), "\""); encoded == "" && dgst.Algorithm == digest.AlgorithmMD5 && len(etag) == 32 {
+		encoded = etag
+	}
+
+	if encoded != "" && strings.EqualFold(encoded, dgst.Encoded) {
+		return true, nil
+	}
+
+	if ifNoneMatch {
+		return false, NewError(ErrorCodeAlreadyExists, fmt.Errorf("object %s already exists in bucket %s", objectKey, bucketName))
+	}
 
-	return digest.New(digest.AlgorithmMD5, digest.MD5FromReader(f))
+	return false, nil
 }
 
-// importObjectToBackend uses to import object to backend.
+// importObjectToBackend uses to import object to backend,
+// and verifies the digest of the object while uploading.
 func (o *objectStorage) importObjectToBackend(ctx context.Context, bucketName, objectKey string, dgst *digest.Digest, fileHeader *multipart.FileHeader) (err error) {
 	f, err := fileHeader.Open()
 	if err != nil {
@@ -593,14 +1213,96 @@ func (o *objectStorage) importObjectToBackend(ctx context.Context, bucketName, o
 	// so there is no error checking for file close.
 	defer f.Close()
 
-	return o.objectStorageClient.PutObject(ctx, bucketName, objectKey, dgst.String(), f)
+	reader, err := newBackendReader(f, dgst.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	if err := o.objectStorageClient.PutObject(ctx, bucketName, objectKey, dgst.String(), reader); err != nil {
+		return err
+	}
+
+	if reader.BytesRead() != fileHeader.Size {
+		return fmt.Errorf("uploaded %d bytes of object to backend, but content length is %d", reader.BytesRead(), fileHeader.Size)
+	}
+
+	return verifyObjectDigest(reader.Digest(), dgst)
+}
+
+// backendReader counts the bytes and computes the digest of the object while it is uploaded to the backend.
+// The SDK of the backend may seek the body to compute the content length or to retry the request,
+// so the counter and the digest are reset when the body is rewound to the start.
+type backendReader struct {
+	file      multipart.File
+	algorithm string
+	counter   *pkgio.CountingReadCloser
+	digester  *pkgio.TeeDigestReader
+}
+
+// newBackendReader returns a backendReader of the file.
+func newBackendReader(file multipart.File, algorithm string) (*backendReader, error) {
+	r := &backendReader{file: file, algorithm: algorithm}
+	if err := r.reset(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Read reads the file, and counts and digests the bytes read.
+func (r *backendReader) Read(p []byte) (int, error) {
+	return r.digester.Read(p)
+}
+
+// Seek seeks the file, and resets the counter and the digest if the file is rewound to the start.
+func (r *backendReader) Seek(offset int64, whence int) (int64, error) {
+	n, err := r.file.Seek(offset, whence)
+	if err != nil {
+		return n, err
+	}
+
+	if n == 0 {
+		if err := r.reset(); err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// Close closes the file.
+func (r *backendReader) Close() error {
+	return r.file.Close()
 }
 
-// importObjectToSeedPeers uses to import object to local storage.
-func (o *objectStorage) importObjectToLocalStorage(ctx context.Context, taskID, peerID string, fileHeader *multipart.FileHeader) (err error) {
+// BytesRead returns the number of bytes read since the file is rewound to the start.
+func (r *backendReader) BytesRead() int64 {
+	return r.counter.BytesRead()
+}
+
+// Digest returns the digest of the bytes read since the file is rewound to the start.
+func (r *backendReader) Digest() *digest.Digest {
+	return r.digester.Digest()
+}
+
+// reset resets the counter and the digest.
+func (r *backendReader) reset() error {
+	r.counter = pkgio.NewCountingReadCloser(r.file)
+	digester, err := pkgio.NewTeeDigestReader(r.counter, r.algorithm)
+	if err != nil {
+		return err
+	}
+
+	r.digester = digester
+	return nil
+}
+
+// importObjectToLocalStorage uses to import object to local storage,
+// and verifies the digest of the object while importing.
+func (o *objectStorage) importObjectToLocalStorage(ctx context.Context, taskID, peerID string, dgst *digest.Digest, fileHeader *multipart.FileHeader, log *logger.SugaredLoggerOnWith) (err error) {
 	f, err := fileHeader.Open()
 	if err != nil {
-		return nil
+		return err
 	}
 	defer func() {
 		if cerr := f.Close(); cerr != nil {
@@ -621,32 +1323,158 @@ func (o *objectStorage) importObjectToLocalStorage(ctx context.Context, taskID,
 		return err
 	}
 
-	// Import task data to dfdaemon.
-	return o.peerTaskManager.GetPieceManager().Import(ctx, meta, tsd, fileHeader.Size, f)
+	// Unregister the task if the import fails, so that the partial or mismatched
+	// object is not kept under the task id.
+	defer func() {
+		if err != nil {
+			if uerr := o.storageManager.UnregisterTask(ctx, storage.CommonTaskRequest{
+				PeerID: meta.PeerID,
+				TaskID: meta.TaskID,
+			}); uerr != nil {
+				log.Errorf("unregister task failed: %s", uerr)
+			}
+		}
+	}()
+
+	// The empty object has no pieces, so the task is stored with zero content length
+	// directly instead of being imported by the piece manager.
+	if fileHeader.Size == 0 {
+		h, err := digest.NewHash(dgst.Algorithm)
+		if err != nil {
+			return err
+		}
+
+		if err := verifyObjectDigest(digest.New(dgst.Algorithm, hex.EncodeToString(h.Sum(nil))), dgst); err != nil {
+			return err
+		}
+
+		if err := tsd.UpdateTask(ctx, &storage.UpdateTaskRequest{
+			PeerTaskMetadata: meta,
+			ContentLength:    0,
+			TotalPieces:      0,
+		}); err != nil {
+			return err
+		}
+
+		log.Info("imported empty object to local storage")
+		return tsd.Store(ctx, &storage.StoreRequest{
+			CommonTaskRequest: storage.CommonTaskRequest{
+				PeerID: meta.PeerID,
+				TaskID: meta.TaskID,
+			},
+			MetadataOnly: true,
+		})
+	}
+
+	// Import task data to dfdaemon, count the bytes and verify the digest while streaming.
+	// The content length and the digest are verified before the task is stored as completed,
+	// so that the truncated or mismatched object is never cached under the task id, and the
+	// scheduler never announces a wrong content length of the task. The counting reader is
+	// wrapped to hide its Close, so that verifying the digest does not close the file twice.
+	countingReader := pkgio.NewCountingReadCloser(f)
+	verifyingReader := digest.NewVerifyingReader(struct{ io.Reader }{countingReader}, dgst)
+	verify := func() error {
+		if countingReader.BytesRead() != fileHeader.Size {
+			return NewError(ErrorCodeBackendError, fmt.Errorf("imported %d bytes of object to local storage, but content length is %d",
+				countingReader.BytesRead(), fileHeader.Size))
+		}
+
+		return verifyingReader.(io.Closer).Close()
+	}
+
+	verifiedTSD := &verifiedTaskStorageDriver{
+		TaskStorageDriver: tsd,
+		verify:            verify,
+	}
+	if err := o.peerTaskManager.GetPieceManager().Import(ctx, meta, verifiedTSD, fileHeader.Size, verifyingReader); err != nil {
+		// The piece manager wraps the error of storing task, so return the verification error directly.
+		if verifiedTSD.err != nil {
+			return verifiedTSD.err
+		}
+
+		// The verifying reader fails the read at the end of the object if the digest does not match.
+		if countingReader.BytesRead() == fileHeader.Size {
+			if verr := verify(); verr != nil {
+				return verr
+			}
+		}
+
+		return err
+	}
+	log.Infof("imported %d bytes to local storage", countingReader.BytesRead())
+	return nil
+}
+
+// verifiedTaskStorageDriver verifies the imported object before the task is stored,
+// so that the object which fails the verification is never stored as completed.
+type verifiedTaskStorageDriver struct {
+	storage.TaskStorageDriver
+
+	// verify verifies the imported object.
+	verify func() error
+
+	// err is the error of the verification.
+	err error
+}
+
+// Store stores the task only if the imported object is verified.
+func (v *verifiedTaskStorageDriver) Store(ctx context.Context, req *storage.StoreRequest) error {
+	if v.err = v.verify(); v.err != nil {
+		return v.err
+	}
+
+	return v.TaskStorageDriver.Store(ctx, req)
+}
+
+// verifyObjectDigest returns ErrorCodeBadDigest error with unprocessable entity status
+// if the digest of the imported object does not match the expected one.
+func verifyObjectDigest(actual, expected *digest.Digest) error {
+	if actual.Encoded != expected.Encoded {
+		return &Error{
+			Code:   ErrorCodeBadDigest,
+			Status: http.StatusUnprocessableEntity,
+			Err:    fmt.Errorf("digest of imported object %s does not match %s", actual.String(), expected.String()),
+		}
+	}
+
+	return nil
 }
 
 // importObjectToSeedPeers uses to import object to available seed peers.
-func (o *objectStorage) importObjectToSeedPeers(ctx context.Context, bucketName, objectKey, filter string, mode int, fileHeader *multipart.FileHeader, maxReplicas int, log *logger.SugaredLoggerOnWith) error {
+func (o *objectStorage) importObjectToSeedPeers(ctx context.Context, bucketName, objectKey, filter, token string, dgst *digest.Digest, mode int, fileHeader *multipart.FileHeader, maxReplicas int, log *logger.SugaredLoggerOnWith) error {
 	schedulers, err := o.dynconfig.GetSchedulers()
 	if err != nil {
 		return err
 	}
 
-	var seedPeerHosts []string
+	var (
+		seedPeerHosts []seedPeerHost
+		visited       = map[string]bool{}
+	)
 	for _, scheduler := range schedulers {
 		for _, seedPeer := range scheduler.SeedPeers {
 			if o.config.Host.AdvertiseIP.String() != seedPeer.Ip && seedPeer.ObjectStoragePort > 0 {
-				seedPeerHosts = append(seedPeerHosts, fmt.Sprintf("%s:%d", seedPeer.Ip, seedPeer.ObjectStoragePort))
+				addr := fmt.Sprintf("%s:%d", seedPeer.Ip, seedPeer.ObjectStoragePort)
+				if visited[addr] {
+					continue
+				}
+
+				visited[addr] = true
+				seedPeerHosts = append(seedPeerHosts, seedPeerHost{Addr: addr, IDC: seedPeer.Idc, Location: seedPeer.Location})
 			}
 		}
 	}
-	seedPeerHosts = pkgstrings.Unique(seedPeerHosts)
 
-	var replicas int
-	for _, seedPeerHost := range seedPeerHosts {
+	var (
+		replicas int
+		details  = map[string]ErrorCode{}
+	)
+	for _, host := range o.seedPeerSelector.Select(seedPeerHosts, maxReplicas) {
+		seedPeerHost := host.Addr
 		log.Infof("import object %s to seed peer %s", objectKey, seedPeerHost)
-		if err := o.importObjectToSeedPeer(ctx, seedPeerHost, bucketName, objectKey, filter, mode, fileHeader); err != nil {
+		if err := o.importObjectToSeedPeer(ctx, seedPeerHost, bucketName, objectKey, filter, token, dgst, mode, fileHeader); err != nil {
 			log.Errorf("import object %s to seed peer %s failed: %s", objectKey, seedPeerHost, err)
+			details[seedPeerHost] = ErrorFrom(err).Code
 			continue
 		}
 
@@ -657,11 +1485,19 @@ func (o *objectStorage) importObjectToSeedPeers(ctx context.Context, bucketName,
 	}
 
 	log.Infof("import %d object %s to seed peers", replicas, objectKey)
+	if replicas == 0 && len(details) > 0 {
+		return &Error{
+			Code:    ErrorCodeP2PUnavailable,
+			Err:     fmt.Errorf("import object %s to all %d seed peers failed", objectKey, len(details)),
+			Details: details,
+		}
+	}
+
 	return nil
 }
 
 // importObjectToSeedPeer uses to import object to seed peer.
-func (o *objectStorage) importObjectToSeedPeer(ctx context.Context, seedPeerHost, bucketName, objectKey, filter string, mode int, fileHeader *multipart.FileHeader) (err error) {
+func (o *objectStorage) importObjectToSeedPeer(ctx context.Context, seedPeerHost, bucketName, objectKey, filter, token string, dgst *digest.Digest, mode int, fileHeader *multipart.FileHeader) (err error) {
 	f, err := fileHeader.Open()
 	if err != nil {
 		return err
@@ -685,12 +1521,18 @@ func (o *objectStorage) importObjectToSeedPeer(ctx context.Context, seedPeerHost
 		}
 	}
 
+	// Seed peer verifies the content with the verified digest instead of computing it again.
+	if err = writer.WriteField("digest", dgst.String()); err != nil {
+		return err
+	}
+
 	part, err := writer.CreateFormFile("file", fileHeader.Filename)
 	if err != nil {
 		return err
 	}
 
-	if _, err = io.Copy(part, f); err != nil {
+	// Seed peer trusts the digest, so verify the content while copying it.
+	if _, err = io.Copy(part, digest.NewVerifyingReader(f, dgst)); err != nil {
 		return err
 	}
 
@@ -710,6 +1552,14 @@ func (o *objectStorage) importObjectToSeedPeer(ctx context.Context, seedPeerHost
 	}
 	req.Header.Add(headers.ContentType, writer.FormDataContentType())
 
+	// Seed peer of old version computes the digest with the same algorithm, so that the task id is the same.
+	req.Header.Add(config.HeaderDragonflyDigestAlgorithm, dgst.Algorithm)
+
+	// Seed peer checks the access control rules of the bucket with the same token.
+	if token != "" {
+		req.Header.Add(config.HeaderDragonflyObjectToken, token)
+	}
+
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
@@ -717,7 +1567,14 @@ func (o *objectStorage) importObjectToSeedPeer(ctx context.Context, seedPeerHost
 	defer resp.Body.Close()
 
 	if resp.StatusCode/100 != 2 {
-		return fmt.Errorf("bad response status %s", resp.Status)
+		// Keep the error code of the seed peer, the response which
+		// is not an ErrorResponse is regarded as a backend error.
+		var errResp ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&errResp); err != nil || errResp.Code == "" {
+			errResp.Code = ErrorCodeBackendError
+		}
+
+		return NewError(errResp.Code, fmt.Errorf("bad response status %s", resp.Status))
 	}
 
 	return nil
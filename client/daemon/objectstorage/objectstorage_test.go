@@ -0,0 +1,2250 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objectstorage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-http-utils/headers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+	managerv1 "d7y.io/api/v2/pkg/apis/manager/v1"
+
+	"d7y.io/dragonfly/v2/client/config"
+	configmocks "d7y.io/dragonfly/v2/client/config/mocks"
+	"d7y.io/dragonfly/v2/client/daemon/peer"
+	"d7y.io/dragonfly/v2/client/daemon/storage"
+	storagemocks "d7y.io/dragonfly/v2/client/daemon/storage/mocks"
+	"d7y.io/dragonfly/v2/cmd/dependency/base"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/digest"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/objectstorage"
+	objectstoragemocks "d7y.io/dragonfly/v2/pkg/objectstorage/mocks"
+	"d7y.io/dragonfly/v2/pkg/unit"
+)
+
+var mockObjectContent = []byte("dragonfly object content")
+
+func mockFileHeader(t *testing.T, content []byte) *multipart.FileHeader {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("file", "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := part.Write(content); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	form, err := multipart.NewReader(&buf, writer.Boundary()).ReadForm(int64(len(content)) + 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+
+	return form.File["file"][0]
+}
+
+func TestObjectStorage_importObjectToLocalStorage(t *testing.T) {
+	tests := []struct {
+		name string
+		dgst *digest.Digest
+		mock func(ctx context.Context, tsd storage.TaskStorageDriver, reader io.Reader) error
+		// stored is whether the task is stored as completed.
+		stored bool
+		// unregistered is whether the task is unregistered.
+		unregistered bool
+		expect       func(t *testing.T, err error)
+	}{
+		{
+			name: "import object",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			mock: func(ctx context.Context, tsd storage.TaskStorageDriver, reader io.Reader) error {
+				if _, err := io.Copy(io.Discard, reader); err != nil {
+					return err
+				}
+
+				return tsd.Store(ctx, &storage.StoreRequest{MetadataOnly: true})
+			},
+			stored: true,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "imported bytes do not match content length",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			mock: func(ctx context.Context, tsd storage.TaskStorageDriver, reader io.Reader) error {
+				if _, err := io.CopyN(io.Discard, reader, int64(len(mockObjectContent)/2)); err != nil {
+					return err
+				}
+
+				// The truncated object is not stored as completed.
+				if err := tsd.Store(ctx, &storage.StoreRequest{MetadataOnly: true}); err != nil {
+					return fmt.Errorf("store task failed: %s", err)
+				}
+
+				return nil
+			},
+			unregistered: true,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				oerr := ErrorFrom(err)
+				assert.Equal(ErrorCodeBackendError, oerr.Code)
+				assert.Equal(http.StatusInternalServerError, errorCodeStatus[oerr.Code])
+				assert.Contains(err.Error(), "content length is 24")
+			},
+		},
+		{
+			name: "digest does not match",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes([]byte("foo"))),
+			mock: func(ctx context.Context, tsd storage.TaskStorageDriver, reader io.Reader) error {
+				if _, err := io.Copy(io.Discard, reader); err != nil {
+					return err
+				}
+
+				// The piece manager wraps the error of storing task.
+				if err := tsd.Store(ctx, &storage.StoreRequest{MetadataOnly: true}); err != nil {
+					return fmt.Errorf("store task failed: %s", err)
+				}
+
+				return nil
+			},
+			unregistered: true,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.Equal(ErrorCodeBadDigest, ErrorFrom(err).Code)
+			},
+		},
+		{
+			name: "import object failed",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			mock: func(ctx context.Context, tsd storage.TaskStorageDriver, reader io.Reader) error {
+				return errors.New("foo")
+			},
+			unregistered: true,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "foo")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			taskStorageDriver := storagemocks.NewMockTaskStorageDriver(ctl)
+			if tc.stored {
+				taskStorageDriver.EXPECT().Store(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			}
+
+			storageManager := storagemocks.NewMockManager(ctl)
+			storageManager.EXPECT().RegisterTask(gomock.Any(), gomock.Any()).Return(taskStorageDriver, nil).Times(1)
+			if tc.unregistered {
+				storageManager.EXPECT().UnregisterTask(gomock.Any(), storage.CommonTaskRequest{PeerID: "bar", TaskID: "foo"}).Return(nil).Times(1)
+			}
+
+			pieceManager := peer.NewMockPieceManager(ctl)
+			pieceManager.EXPECT().Import(gomock.Any(), gomock.Any(), gomock.Any(), int64(len(mockObjectContent)), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, ptm storage.PeerTaskMetadata, tsd storage.TaskStorageDriver, contentLength int64, reader io.Reader) error {
+					return tc.mock(ctx, tsd, reader)
+				}).Times(1)
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			peerTaskManager.EXPECT().GetPieceManager().Return(pieceManager).Times(1)
+
+			o := &objectStorage{
+				peerTaskManager: peerTaskManager,
+				storageManager:  storageManager,
+			}
+
+			tc.expect(t, o.importObjectToLocalStorage(context.Background(), "foo", "bar", tc.dgst,
+				mockFileHeader(t, mockObjectContent), logger.WithTaskAndPeerID("foo", "bar")))
+		})
+	}
+}
+
+func TestObjectStorage_getObject(t *testing.T) {
+	lastModified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	meta := &objectstorage.ObjectMetadata{
+		Key:              "bar",
+		ContentLength:    int64(len(mockObjectContent)),
+		ETag:             "foo",
+		Digest:           "md5:foo",
+		LastModifiedTime: lastModified,
+	}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		stream bool
+		expect func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest)
+	}{
+		{
+			name:   "get object without conditions",
+			header: http.Header{},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal(mockObjectContent, w.Body.Bytes())
+				assert.Equal(`"foo"`, w.Header().Get(headers.ETag))
+				assert.Equal(lastModified.Format(http.TimeFormat), w.Header().Get(headers.LastModified))
+				assert.Nil(req.Range)
+			},
+		},
+		{
+			name:   "if-none-match matches etag",
+			header: http.Header{headers.IfNoneMatch: {`"foo"`}},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotModified, w.Code)
+				assert.Empty(w.Body.Bytes())
+				assert.Equal(`"foo"`, w.Header().Get(headers.ETag))
+			},
+		},
+		{
+			name:   "if-none-match matches weak etag in list",
+			header: http.Header{headers.IfNoneMatch: {`"bar", W/"foo"`}},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotModified, w.Code)
+			},
+		},
+		{
+			name:   "if-none-match matches any",
+			header: http.Header{headers.IfNoneMatch: {"*"}},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotModified, w.Code)
+			},
+		},
+		{
+			name:   "if-none-match does not match etag",
+			header: http.Header{headers.IfNoneMatch: {`"bar"`}},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal(mockObjectContent, w.Body.Bytes())
+			},
+		},
+		{
+			name: "if-none-match takes precedence over if-modified-since",
+			header: http.Header{
+				headers.IfNoneMatch:     {`"bar"`},
+				headers.IfModifiedSince: {lastModified.Add(time.Hour).Format(http.TimeFormat)},
+			},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+		{
+			name:   "object is not modified since",
+			header: http.Header{headers.IfModifiedSince: {lastModified.Format(http.TimeFormat)}},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotModified, w.Code)
+				assert.Equal(lastModified.Format(http.TimeFormat), w.Header().Get(headers.LastModified))
+			},
+		},
+		{
+			name:   "object is modified since",
+			header: http.Header{headers.IfModifiedSince: {lastModified.Add(-time.Hour).Format(http.TimeFormat)}},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+		{
+			name:   "invalid if-modified-since is ignored",
+			header: http.Header{headers.IfModifiedSince: {"foo"}},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+		{
+			name: "range with matched if-none-match",
+			header: http.Header{
+				headers.Range:       {"bytes=0-3"},
+				headers.IfNoneMatch: {`"foo"`},
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotModified, w.Code)
+			},
+		},
+		{
+			name: "range with matched if-range",
+			header: http.Header{
+				headers.Range:   {"bytes=0-3"},
+				headers.IfRange: {`"foo"`},
+			},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.NotNil(req.Range)
+				assert.Equal("0-3", req.URLMeta.Range)
+				assert.Empty(req.URLMeta.Digest)
+			},
+		},
+		{
+			name: "range with matched if-range date",
+			header: http.Header{
+				headers.Range:   {"bytes=0-3"},
+				headers.IfRange: {lastModified.Format(http.TimeFormat)},
+			},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.NotNil(req.Range)
+			},
+		},
+		{
+			name: "range with weak if-range",
+			header: http.Header{
+				headers.Range:   {"bytes=0-3"},
+				headers.IfRange: {`W/"foo"`},
+			},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Nil(req.Range)
+			},
+		},
+		{
+			name: "range with mismatched if-range",
+			header: http.Header{
+				headers.Range:   {"bytes=0-3"},
+				headers.IfRange: {`"bar"`},
+			},
+			stream: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Nil(req.Range)
+				assert.Empty(req.URLMeta.Range)
+				assert.Equal("md5:foo", req.URLMeta.Digest)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			objectStorageClient.EXPECT().GetObjectMetadata(gomock.Any(), "foo", "bar").Return(meta, true, nil).Times(1)
+
+			var streamReq *peer.StreamTaskRequest
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			if tc.stream {
+				objectStorageClient.EXPECT().GetSignURL(gomock.Any(), "foo", "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar", nil).Times(1)
+				peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+						streamReq = req
+						return io.NopCloser(bytes.NewReader(mockObjectContent)), map[string]string{
+							headers.ContentLength: fmt.Sprint(len(mockObjectContent)),
+						}, nil
+					}).Times(1)
+			}
+
+			o := &objectStorage{
+				config:              &config.DaemonOption{},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/buckets/:id/objects/*object_key", o.getObject)
+
+			req := httptest.NewRequest(http.MethodGet, "/buckets/foo/objects/bar", nil)
+			req.Header = tc.header
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			tc.expect(t, w, streamReq)
+		})
+	}
+}
+
+func TestObjectStorage_getObjectContentEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write(mockObjectContent); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	content := buf.Bytes()
+
+	tests := []struct {
+		name            string
+		contentEncoding string
+		expect          func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest)
+	}{
+		{
+			name:            "get gzip encoded object",
+			contentEncoding: "gzip",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal("gzip", w.Header().Get(headers.ContentEncoding))
+				assert.Equal(fmt.Sprint(len(content)), w.Header().Get(headers.ContentLength))
+				assert.Equal(content, w.Body.Bytes())
+				assert.Equal(map[string]string{headers.AcceptEncoding: "gzip"}, req.URLMeta.Header)
+
+				gr, err := gzip.NewReader(w.Body)
+				assert.NoError(err)
+				data, err := io.ReadAll(gr)
+				assert.NoError(err)
+				assert.Equal(mockObjectContent, data)
+			},
+		},
+		{
+			name: "get object without content encoding",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Empty(w.Header().Get(headers.ContentEncoding))
+				assert.Equal(content, w.Body.Bytes())
+				assert.Nil(req.URLMeta.Header)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			objectStorageClient.EXPECT().GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{
+				Key:             "bar",
+				ContentLength:   int64(len(content)),
+				ContentEncoding: tc.contentEncoding,
+			}, true, nil).Times(1)
+			objectStorageClient.EXPECT().GetSignURL(gomock.Any(), "foo", "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar", nil).Times(1)
+
+			var streamReq *peer.StreamTaskRequest
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+					streamReq = req
+					return io.NopCloser(bytes.NewReader(content)), map[string]string{
+						headers.ContentLength: fmt.Sprint(len(content)),
+					}, nil
+				}).Times(1)
+
+			o := &objectStorage{
+				config:              &config.DaemonOption{},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/buckets/:id/objects/*object_key", o.getObject)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buckets/foo/objects/bar", nil))
+			tc.expect(t, w, streamReq)
+		})
+	}
+}
+
+// counterValue returns the value of the counter with labels in the default registry.
+func counterValue(t *testing.T, name string, labels map[string]string) float64 {
+	metricFamilies, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, metricFamily := range metricFamilies {
+		if metricFamily.GetName() != name {
+			continue
+		}
+
+		for _, metric := range metricFamily.GetMetric() {
+			matched := 0
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] == label.GetValue() {
+					matched++
+				}
+			}
+
+			if matched == len(labels) {
+				return metric.GetCounter().GetValue()
+			}
+		}
+	}
+
+	return 0
+}
+
+// mockBackSourceReadCloser is the reader of the stream task which reports back source.
+type mockBackSourceReadCloser struct {
+	io.ReadCloser
+	backSource bool
+}
+
+func (r *mockBackSourceReadCloser) BackSource() bool {
+	return r.backSource
+}
+
+func TestObjectStorage_getObjectCacheStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket string
+		mock   func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error)
+		expect func(t *testing.T, w *httptest.ResponseRecorder, taskID string)
+	}{
+		{
+			name:   "get pre-imported object",
+			bucket: "hit",
+			mock: func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+				return io.NopCloser(bytes.NewReader(mockObjectContent)), map[string]string{
+					headers.ContentLength:       fmt.Sprint(len(mockObjectContent)),
+					config.HeaderDragonflyCache: peer.CacheStatusHit,
+				}, nil
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, taskID string) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal(peer.CacheStatusHit, w.Header().Get(config.HeaderDragonflyCache))
+				assert.Equal(taskID, w.Header().Get(config.HeaderDragonflyTaskID))
+				assert.Equal(float64(1), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_requests_total",
+					map[string]string{"cache_status": peer.CacheStatusHit, "bucket": "hit"}))
+				assert.Equal(float64(1), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_cache_hit_total",
+					map[string]string{"bucket": "hit"}))
+				assert.Equal(float64(len(mockObjectContent)), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_bytes_served_total",
+					map[string]string{"cache_status": peer.CacheStatusHit, "bucket": "hit"}))
+			},
+		},
+		{
+			name:   "get cold object",
+			bucket: "miss",
+			mock: func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+				return io.NopCloser(bytes.NewReader(mockObjectContent)), map[string]string{
+					headers.ContentLength:       fmt.Sprint(len(mockObjectContent)),
+					config.HeaderDragonflyCache: peer.CacheStatusMiss,
+				}, nil
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, taskID string) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal(peer.CacheStatusMiss, w.Header().Get(config.HeaderDragonflyCache))
+				assert.Equal(taskID, w.Header().Get(config.HeaderDragonflyTaskID))
+				assert.Equal(float64(1), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_requests_total",
+					map[string]string{"cache_status": peer.CacheStatusMiss, "bucket": "miss"}))
+				assert.Equal(float64(0), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_requests_total",
+					map[string]string{"cache_status": peer.CacheStatusHit, "bucket": "miss"}))
+				assert.Equal(float64(len(mockObjectContent)), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_bytes_served_total",
+					map[string]string{"cache_status": peer.CacheStatusMiss, "bucket": "miss"}))
+				assert.Equal(float64(0), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_bytes_served_total",
+					map[string]string{"cache_status": peer.CacheStatusHit, "bucket": "miss"}))
+			},
+		},
+		{
+			name:   "get object resumed from running task",
+			bucket: "partial",
+			mock: func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+				return io.NopCloser(bytes.NewReader(mockObjectContent)), map[string]string{
+					config.HeaderDragonflyCache: peer.CacheStatusPartial,
+				}, nil
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, taskID string) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal(peer.CacheStatusPartial, w.Header().Get(config.HeaderDragonflyCache))
+				assert.Equal(float64(1), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_requests_total",
+					map[string]string{"cache_status": peer.CacheStatusPartial, "bucket": "partial"}))
+			},
+		},
+		{
+			name:   "start stream task failed",
+			bucket: "failed",
+			mock: func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+				return nil, nil, errors.New("foo")
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, taskID string) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusServiceUnavailable, w.Code)
+				assert.Equal(peer.CacheStatusMiss, w.Header().Get(config.HeaderDragonflyCache))
+				assert.Equal(taskID, w.Header().Get(config.HeaderDragonflyTaskID))
+				assert.Equal(float64(1), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_requests_total",
+					map[string]string{"cache_status": peer.CacheStatusMiss, "bucket": "failed"}))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			objectStorageClient.EXPECT().GetObjectMetadata(gomock.Any(), tc.bucket, "bar").Return(&objectstorage.ObjectMetadata{
+				Key:           "bar",
+				ContentLength: int64(len(mockObjectContent)),
+			}, true, nil).Times(1)
+			objectStorageClient.EXPECT().GetSignURL(gomock.Any(), tc.bucket, "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/bar", nil).Times(1)
+
+			var taskID string
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+					taskID = req.TaskID()
+					return tc.mock(ctx, req)
+				}).Times(1)
+
+			o := &objectStorage{
+				config:              &config.DaemonOption{},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/buckets/:id/objects/*object_key", o.getObject)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buckets/"+tc.bucket+"/objects/bar", nil))
+			tc.expect(t, w, taskID)
+		})
+	}
+}
+
+func TestObjectStorage_getObjectCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket string
+		mock   func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder)
+		expect func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "object not found",
+			bucket: "notfound",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "notfound", "bar").Return(nil, false, nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotFound, w.Code)
+				assert.Equal(float64(1), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_requests_total",
+					map[string]string{"cache_status": getObjectCacheStatusNone, "bucket": "notfound"}))
+			},
+		},
+		{
+			name:   "get object metadata failed",
+			bucket: "backenderror",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "backenderror", "bar").Return(nil, false, errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusInternalServerError, w.Code)
+				assert.Equal(float64(1), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_requests_total",
+					map[string]string{"cache_status": getObjectCacheStatusNone, "bucket": "backenderror"}))
+			},
+		},
+		{
+			name:   "get sign url failed",
+			bucket: "signerror",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "signerror", "bar").Return(&objectstorage.ObjectMetadata{
+					Key:           "bar",
+					ContentLength: int64(len(mockObjectContent)),
+				}, true, nil).Times(1)
+				objectStorageClient.GetSignURL(gomock.Any(), "signerror", "bar", objectstorage.MethodGet, gomock.Any()).Return("", errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusInternalServerError, w.Code)
+				assert.Equal(float64(1), counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_requests_total",
+					map[string]string{"cache_status": getObjectCacheStatusNone, "bucket": "signerror"}))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			tc.mock(objectStorageClient.EXPECT())
+
+			o := &objectStorage{
+				config:              &config.DaemonOption{},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peer.NewMockTaskManager(ctl),
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/buckets/:id/objects/*object_key", o.getObject)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buckets/"+tc.bucket+"/objects/bar", nil))
+			tc.expect(t, w)
+		})
+	}
+}
+
+func TestObjectStorage_getObjectCacheHitMiss(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket string
+		reader func() io.ReadCloser
+		hit    float64
+		miss   float64
+	}{
+		{
+			name:   "served by reused task",
+			bucket: "reused",
+			reader: func() io.ReadCloser {
+				return io.NopCloser(bytes.NewReader(mockObjectContent))
+			},
+			hit:  1,
+			miss: 0,
+		},
+		{
+			name:   "served by peers",
+			bucket: "peers",
+			reader: func() io.ReadCloser {
+				return &mockBackSourceReadCloser{ReadCloser: io.NopCloser(bytes.NewReader(mockObjectContent)), backSource: false}
+			},
+			hit:  1,
+			miss: 0,
+		},
+		{
+			name:   "downloaded from source",
+			bucket: "source",
+			reader: func() io.ReadCloser {
+				return &mockBackSourceReadCloser{ReadCloser: io.NopCloser(bytes.NewReader(mockObjectContent)), backSource: true}
+			},
+			hit:  0,
+			miss: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			objectStorageClient.EXPECT().GetObjectMetadata(gomock.Any(), tc.bucket, "bar").Return(&objectstorage.ObjectMetadata{Key: "bar"}, true, nil).Times(1)
+			objectStorageClient.EXPECT().GetSignURL(gomock.Any(), tc.bucket, "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/bar", nil).Times(1)
+
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).Return(tc.reader(), map[string]string{}, nil).Times(1)
+
+			o := &objectStorage{
+				config:              &config.DaemonOption{},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/buckets/:id/objects/*object_key", o.getObject)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/buckets/"+tc.bucket+"/objects/bar", nil))
+			assert.Equal(http.StatusOK, w.Code)
+			assert.Equal(mockObjectContent, w.Body.Bytes())
+			assert.Equal(tc.hit, counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_cache_hit_total", map[string]string{"bucket": tc.bucket}))
+			assert.Equal(tc.miss, counterValue(t, "dragonfly_dfdaemon_object_storage_get_object_cache_miss_total", map[string]string{"bucket": tc.bucket}))
+		})
+	}
+}
+
+func TestObjectStorage_getObjectPieceSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		pieceSize unit.Bytes
+		header    string
+		expect    func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest)
+	}{
+		{
+			name: "piece size is not set",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal(uint32(0), req.PieceSize)
+			},
+		},
+		{
+			name:      "piece size is set by config",
+			pieceSize: 16 * unit.MB,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal(uint32(16*unit.MB), req.PieceSize)
+			},
+		},
+		{
+			name:      "piece size is overridden by header",
+			pieceSize: 16 * unit.MB,
+			header:    "32Mi",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal(uint32(32*unit.MB), req.PieceSize)
+			},
+		},
+		{
+			name:   "piece size in header is out of range",
+			header: "128Mi",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+				assert.Nil(req)
+			},
+		},
+		{
+			name:   "piece size in header is invalid",
+			header: "foo",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, req *peer.StreamTaskRequest) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+				assert.Nil(req)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			objectStorageClient.EXPECT().GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{Key: "bar"}, true, nil).AnyTimes()
+			objectStorageClient.EXPECT().GetSignURL(gomock.Any(), "foo", "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/bar", nil).AnyTimes()
+
+			var streamTaskRequest *peer.StreamTaskRequest
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+					streamTaskRequest = req
+					return io.NopCloser(bytes.NewReader(mockObjectContent)), map[string]string{}, nil
+				}).AnyTimes()
+
+			o := &objectStorage{
+				config: &config.DaemonOption{
+					ObjectStorage: config.ObjectStorageOption{PieceSize: tc.pieceSize},
+				},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/buckets/:id/objects/*object_key", o.getObject)
+
+			req := httptest.NewRequest(http.MethodGet, "/buckets/foo/objects/bar", nil)
+			if tc.header != "" {
+				req.Header.Set(config.HeaderDragonflyPieceSize, tc.header)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			tc.expect(t, w, streamTaskRequest)
+		})
+	}
+}
+
+func TestObjectStorage_getObjectRanges(t *testing.T) {
+	meta := &objectstorage.ObjectMetadata{
+		Key:           "bar",
+		ContentLength: int64(len(mockObjectContent)),
+		ContentType:   "text/plain",
+		ETag:          "foo",
+		Digest:        "md5:foo",
+	}
+
+	type part struct {
+		contentRange string
+		data         string
+	}
+
+	tests := []struct {
+		name       string
+		rangeValue string
+		streamErr  error
+		// rejected is whether the request is rejected before the object is signed.
+		rejected bool
+		expect   func(t *testing.T, w *httptest.ResponseRecorder, reqs []*peer.StreamTaskRequest, parts []part)
+	}{
+		{
+			name:       "unsorted ranges",
+			rangeValue: "bytes=17-23,0-8",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reqs []*peer.StreamTaskRequest, parts []part) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusPartialContent, w.Code)
+				assert.Equal(fmt.Sprint(w.Body.Len()), w.Header().Get(headers.ContentLength))
+				assert.Equal([]part{
+					{contentRange: "bytes 0-8/24", data: "dragonfly"},
+					{contentRange: "bytes 17-23/24", data: "content"},
+				}, parts)
+				require := require.New(t)
+				require.Len(reqs, 1)
+				assert.Nil(reqs[0].Range)
+				assert.Empty(reqs[0].URLMeta.Range)
+				assert.Equal("md5:foo", reqs[0].URLMeta.Digest)
+				assert.Equal("dfdaemon", reqs[0].URLMeta.Filter)
+			},
+		},
+		{
+			name:       "ranges within object",
+			rangeValue: "bytes=10-12,2-4",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reqs []*peer.StreamTaskRequest, parts []part) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusPartialContent, w.Code)
+				assert.Equal(fmt.Sprint(w.Body.Len()), w.Header().Get(headers.ContentLength))
+				assert.Equal([]part{
+					{contentRange: "bytes 2-4/24", data: "ago"},
+					{contentRange: "bytes 10-12/24", data: "obj"},
+				}, parts)
+				require := require.New(t)
+				require.Len(reqs, 1)
+				assert.Equal("2-12", reqs[0].URLMeta.Range)
+				assert.Equal(int64(11), reqs[0].Range.Length)
+				assert.Empty(reqs[0].URLMeta.Digest)
+				assert.Equal("dfdaemon", reqs[0].URLMeta.Filter)
+			},
+		},
+		{
+			name:       "overlapping ranges with suffix range",
+			rangeValue: "bytes=0-3,2-8,-7",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reqs []*peer.StreamTaskRequest, parts []part) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusPartialContent, w.Code)
+				assert.Equal([]part{
+					{contentRange: "bytes 0-8/24", data: "dragonfly"},
+					{contentRange: "bytes 17-23/24", data: "content"},
+				}, parts)
+				assert.Len(reqs, 1)
+			},
+		},
+		{
+			name:       "ranges coalesced into one range",
+			rangeValue: "bytes=4-8,0-5",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reqs []*peer.StreamTaskRequest, parts []part) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Nil(parts)
+				assert.Len(reqs, 1)
+				assert.Equal("0-8", reqs[0].URLMeta.Range)
+				assert.Equal(int64(9), reqs[0].Range.Length)
+			},
+		},
+		{
+			name:       "p2p network is unavailable",
+			rangeValue: "bytes=17-23,0-8",
+			streamErr:  errors.New("foo"),
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reqs []*peer.StreamTaskRequest, parts []part) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusServiceUnavailable, w.Code)
+				assert.Len(reqs, 1)
+			},
+		},
+		{
+			name:       "too many ranges",
+			rangeValue: "bytes=" + strings.Repeat("0-0,", maxObjectRanges) + "0-0",
+			rejected:   true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reqs []*peer.StreamTaskRequest, parts []part) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusRequestedRangeNotSatisfiable, w.Code)
+				assert.Empty(reqs)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			objectStorageClient.EXPECT().GetObjectMetadata(gomock.Any(), "foo", "bar").Return(meta, true, nil).Times(1)
+			if !tc.rejected {
+				objectStorageClient.EXPECT().GetSignURL(gomock.Any(), "foo", "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar", nil).Times(1)
+			}
+
+			var streamReqs []*peer.StreamTaskRequest
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+					streamReqs = append(streamReqs, req)
+					if tc.streamErr != nil {
+						return nil, nil, tc.streamErr
+					}
+
+					data := mockObjectContent
+					if req.Range != nil {
+						data = mockObjectContent[req.Range.Start : req.Range.Start+req.Range.Length]
+					}
+
+					return io.NopCloser(bytes.NewReader(data)), map[string]string{
+						headers.ContentLength: fmt.Sprint(len(data)),
+					}, nil
+				}).AnyTimes()
+
+			o := &objectStorage{
+				config:              &config.DaemonOption{ObjectStorage: config.ObjectStorageOption{Filter: "dfdaemon"}},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/buckets/:id/objects/*object_key", o.getObject)
+
+			req := httptest.NewRequest(http.MethodGet, "/buckets/foo/objects/bar", nil)
+			req.Header.Set(headers.Range, tc.rangeValue)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			var parts []part
+			if mediaType, params, err := mime.ParseMediaType(w.Header().Get(headers.ContentType)); err == nil && mediaType == "multipart/byteranges" {
+				mr := multipart.NewReader(bytes.NewReader(w.Body.Bytes()), params["boundary"])
+				for {
+					p, err := mr.NextPart()
+					if err == io.EOF {
+						break
+					}
+
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					assert.Equal(t, "text/plain", p.Header.Get(headers.ContentType))
+					data, err := io.ReadAll(p)
+					if err != nil {
+						t.Fatal(err)
+					}
+
+					parts = append(parts, part{contentRange: p.Header.Get(headers.ContentRange), data: string(data)})
+				}
+			}
+
+			tc.expect(t, w, streamReqs, parts)
+		})
+	}
+}
+
+// mockNotifyReadCloser is the reader of the stream task which notifies when it is closed.
+type mockNotifyReadCloser struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (r *mockNotifyReadCloser) Close() error {
+	close(r.closed)
+	return nil
+}
+
+func TestObjectStorage_preheatObject(t *testing.T) {
+	meta := &objectstorage.ObjectMetadata{
+		Key:           "bar/baz",
+		ContentLength: int64(len(mockObjectContent)),
+		Digest:        "md5:foo",
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		mock   func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, peerTaskManager *peer.MockTaskManagerMockRecorder, reader *mockNotifyReadCloser)
+		expect func(t *testing.T, w *httptest.ResponseRecorder, reader *mockNotifyReadCloser)
+	}{
+		{
+			name: "preheat object",
+			path: "/buckets/foo/objects/bar/baz/preheat",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, peerTaskManager *peer.MockTaskManagerMockRecorder, reader *mockNotifyReadCloser) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "foo", "bar/baz").Return(meta, true, nil).Times(1)
+				objectStorageClient.GetSignURL(gomock.Any(), "foo", "bar/baz", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar/baz", nil).Times(1)
+				peerTaskManager.StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+						assert := assert.New(t)
+						assert.Equal("http://example.com/foo/bar/baz", req.URL)
+						assert.Equal("md5:foo", req.URLMeta.Digest)
+						assert.Nil(req.Range)
+						assert.Equal(idgen.TaskIDV1("http://example.com/foo/bar/baz", &commonv1.UrlMeta{Digest: "md5:foo"}), req.TaskID())
+						return reader, map[string]string{config.HeaderDragonflyCache: peer.CacheStatusMiss}, nil
+					}).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reader *mockNotifyReadCloser) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusAccepted, w.Code)
+
+				var resp PreheatObjectResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(idgen.TaskIDV1("http://example.com/foo/bar/baz", &commonv1.UrlMeta{Digest: "md5:foo"}), resp.TaskID)
+				assert.Equal(peer.CacheStatusMiss, resp.CacheStatus)
+				assert.Equal(resp.TaskID, w.Header().Get(config.HeaderDragonflyTaskID))
+
+				select {
+				case <-reader.closed:
+				case <-time.After(5 * time.Second):
+					t.Fatal("stream task is not drained")
+				}
+			},
+		},
+		{
+			name: "preheat object with content encoding",
+			path: "/buckets/foo/objects/bar/baz/preheat",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, peerTaskManager *peer.MockTaskManagerMockRecorder, reader *mockNotifyReadCloser) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "foo", "bar/baz").Return(&objectstorage.ObjectMetadata{
+					Key:             "bar/baz",
+					ContentLength:   int64(len(mockObjectContent)),
+					ContentEncoding: "gzip",
+					Digest:          "md5:foo",
+				}, true, nil).Times(1)
+				objectStorageClient.GetSignURL(gomock.Any(), "foo", "bar/baz", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar/baz", nil).Times(1)
+				peerTaskManager.StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+						assert := assert.New(t)
+						assert.Equal(map[string]string{headers.AcceptEncoding: "gzip"}, req.URLMeta.Header)
+						assert.Equal(idgen.TaskIDV1("http://example.com/foo/bar/baz", &commonv1.UrlMeta{Digest: "md5:foo"}), req.TaskID())
+						return reader, map[string]string{config.HeaderDragonflyCache: peer.CacheStatusMiss}, nil
+					}).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reader *mockNotifyReadCloser) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusAccepted, w.Code)
+
+				select {
+				case <-reader.closed:
+				case <-time.After(5 * time.Second):
+					t.Fatal("stream task is not drained")
+				}
+			},
+		},
+		{
+			name: "preheat object in cache",
+			path: "/buckets/foo/objects/bar/baz/preheat",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, peerTaskManager *peer.MockTaskManagerMockRecorder, reader *mockNotifyReadCloser) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "foo", "bar/baz").Return(meta, true, nil).Times(1)
+				objectStorageClient.GetSignURL(gomock.Any(), "foo", "bar/baz", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar/baz", nil).Times(1)
+				peerTaskManager.StartStreamTask(gomock.Any(), gomock.Any()).Return(reader, map[string]string{config.HeaderDragonflyCache: peer.CacheStatusHit}, nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reader *mockNotifyReadCloser) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusAccepted, w.Code)
+				assert.Equal(peer.CacheStatusHit, w.Header().Get(config.HeaderDragonflyCache))
+
+				select {
+				case <-reader.closed:
+				default:
+					t.Fatal("stream task is not closed")
+				}
+			},
+		},
+		{
+			name: "object not found",
+			path: "/buckets/foo/objects/bar/baz/preheat",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, peerTaskManager *peer.MockTaskManagerMockRecorder, reader *mockNotifyReadCloser) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "foo", "bar/baz").Return(nil, false, nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reader *mockNotifyReadCloser) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotFound, w.Code)
+			},
+		},
+		{
+			name: "p2p network is unavailable",
+			path: "/buckets/foo/objects/bar/baz/preheat",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, peerTaskManager *peer.MockTaskManagerMockRecorder, reader *mockNotifyReadCloser) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "foo", "bar/baz").Return(meta, true, nil).Times(1)
+				objectStorageClient.GetSignURL(gomock.Any(), "foo", "bar/baz", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar/baz", nil).Times(1)
+				peerTaskManager.StartStreamTask(gomock.Any(), gomock.Any()).Return(nil, nil, errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reader *mockNotifyReadCloser) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusServiceUnavailable, w.Code)
+			},
+		},
+		{
+			name: "unknown action",
+			path: "/buckets/foo/objects/bar/baz/foo",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, peerTaskManager *peer.MockTaskManagerMockRecorder, reader *mockNotifyReadCloser) {
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, reader *mockNotifyReadCloser) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotFound, w.Code)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			reader := &mockNotifyReadCloser{Reader: bytes.NewReader(mockObjectContent), closed: make(chan struct{})}
+			tc.mock(objectStorageClient.EXPECT(), peerTaskManager.EXPECT(), reader)
+
+			o := &objectStorage{
+				config:              &config.DaemonOption{},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.POST("/buckets/:id/objects/*object_key", o.postObject)
+
+			req := httptest.NewRequest(http.MethodPost, tc.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			tc.expect(t, w, reader)
+		})
+	}
+}
+
+func TestObjectStorage_isObjectWrittenBack(t *testing.T) {
+	dgst := digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent))
+
+	tests := []struct {
+		name        string
+		ifNoneMatch bool
+		mock        func(m *objectstoragemocks.MockObjectStorageMockRecorder)
+		expect      func(t *testing.T, writtenBack bool, err error)
+	}{
+		{
+			name: "object does not exist",
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(nil, false, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.False(writtenBack)
+			},
+		},
+		{
+			name: "object exists with the same digest",
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{Digest: dgst.String()}, true, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(writtenBack)
+			},
+		},
+		{
+			name: "object exists with the same etag",
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{ETag: fmt.Sprintf("%q", dgst.Encoded)}, true, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(writtenBack)
+			},
+		},
+		{
+			name: "object exists with the digest of another algorithm and the same etag",
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{
+					Digest: digest.New(digest.AlgorithmCRC32C, digest.CRC32CFromBytes(mockObjectContent)).String(),
+					ETag:   fmt.Sprintf("%q", dgst.Encoded),
+				}, true, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(writtenBack)
+			},
+		},
+		{
+			name: "object exists with a different digest",
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{
+					Digest: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes([]byte("foo"))).String(),
+				}, true, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.False(writtenBack)
+			},
+		},
+		{
+			name:        "object exists with a different digest and if-none-match",
+			ifNoneMatch: true,
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{
+					Digest: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes([]byte("foo"))).String(),
+				}, true, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.False(writtenBack)
+				oerr := ErrorFrom(err)
+				assert.Equal(ErrorCodeAlreadyExists, oerr.Code)
+				assert.Equal(http.StatusConflict, errorCodeStatus[oerr.Code])
+			},
+		},
+		{
+			name:        "object exists with the same digest and if-none-match",
+			ifNoneMatch: true,
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{Digest: dgst.String()}, true, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(writtenBack)
+			},
+		},
+		{
+			name:        "object exists with unknown digest and if-none-match",
+			ifNoneMatch: true,
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{ETag: "\"foo-2\""}, true, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.False(writtenBack)
+				assert.Equal(ErrorCodeAlreadyExists, ErrorFrom(err).Code)
+			},
+		},
+		{
+			name: "object exists with unknown digest",
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(&objectstorage.ObjectMetadata{ETag: "\"foo-2\""}, true, nil).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.False(writtenBack)
+			},
+		},
+		{
+			name: "get object metadata failed",
+			mock: func(m *objectstoragemocks.MockObjectStorageMockRecorder) {
+				m.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(nil, false, errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, writtenBack bool, err error) {
+				assert := assert.New(t)
+				assert.False(writtenBack)
+				assert.Equal(ErrorCodeBackendError, ErrorFrom(err).Code)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			tc.mock(objectStorageClient.EXPECT())
+
+			o := &objectStorage{
+				objectStorageClient: objectStorageClient,
+			}
+
+			writtenBack, err := o.isObjectWrittenBack(context.Background(), "foo", "bar", dgst, tc.ifNoneMatch)
+			tc.expect(t, writtenBack, err)
+		})
+	}
+}
+
+func TestObjectStorage_importObjectToBackend(t *testing.T) {
+	tests := []struct {
+		name   string
+		dgst   *digest.Digest
+		mock   func(reader io.Reader) error
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name: "import object",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			mock: func(reader io.Reader) error {
+				_, err := io.Copy(io.Discard, reader)
+				return err
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "import object after backend rewinds the body",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			mock: func(reader io.Reader) error {
+				seeker, ok := reader.(io.ReadSeeker)
+				if !ok {
+					return errors.New("body is not seekable")
+				}
+
+				if _, err := io.CopyN(io.Discard, seeker, 4); err != nil {
+					return err
+				}
+
+				if _, err := seeker.Seek(0, io.SeekEnd); err != nil {
+					return err
+				}
+
+				if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+					return err
+				}
+
+				_, err := io.Copy(io.Discard, seeker)
+				return err
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "uploaded bytes do not match content length",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			mock: func(reader io.Reader) error {
+				_, err := io.CopyN(io.Discard, reader, int64(len(mockObjectContent)/2))
+				return err
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "uploaded 12 bytes of object to backend, but content length is 24")
+			},
+		},
+		{
+			name: "digest does not match",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes([]byte("foo"))),
+			mock: func(reader io.Reader) error {
+				_, err := io.Copy(io.Discard, reader)
+				return err
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.Equal(ErrorCodeBadDigest, ErrorFrom(err).Code)
+			},
+		},
+		{
+			name: "backend returns error",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			mock: func(reader io.Reader) error {
+				return errors.New("foo")
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "foo")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			objectStorageClient.EXPECT().PutObject(gomock.Any(), "foo", "bar", tc.dgst.String(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, bucketName, objectKey, digest string, reader io.Reader) error {
+					return tc.mock(reader)
+				}).Times(1)
+
+			o := &objectStorage{objectStorageClient: objectStorageClient}
+			tc.expect(t, o.importObjectToBackend(context.Background(), "foo", "bar", tc.dgst, mockFileHeader(t, mockObjectContent)))
+		})
+	}
+}
+
+func TestObjectStorage_digestFromFileHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		header    http.Header
+		expect    func(t *testing.T, dgst *digest.Digest, err error)
+	}{
+		{
+			name: "default algorithm",
+			expect: func(t *testing.T, dgst *digest.Digest, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)), dgst)
+			},
+		},
+		{
+			name:      "sha256 algorithm in config",
+			algorithm: digest.AlgorithmSHA256,
+			expect: func(t *testing.T, dgst *digest.Digest, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(digest.New(digest.AlgorithmSHA256, digest.SHA256FromBytes(mockObjectContent)), dgst)
+			},
+		},
+		{
+			name:      "sha256 algorithm in header",
+			algorithm: digest.AlgorithmMD5,
+			header:    http.Header{config.HeaderDragonflyDigestAlgorithm: []string{"SHA256"}},
+			expect: func(t *testing.T, dgst *digest.Digest, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(digest.New(digest.AlgorithmSHA256, digest.SHA256FromBytes(mockObjectContent)), dgst)
+			},
+		},
+		{
+			name:   "md5 algorithm in header",
+			header: http.Header{config.HeaderDragonflyDigestAlgorithm: []string{"md5"}},
+			expect: func(t *testing.T, dgst *digest.Digest, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)), dgst)
+			},
+		},
+		{
+			name:   "crc32c algorithm is not supported",
+			header: http.Header{config.HeaderDragonflyDigestAlgorithm: []string{"crc32c"}},
+			expect: func(t *testing.T, dgst *digest.Digest, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "unsupported digest algorithm crc32c")
+			},
+		},
+		{
+			name:   "unsupported algorithm",
+			header: http.Header{config.HeaderDragonflyDigestAlgorithm: []string{"sha1"}},
+			expect: func(t *testing.T, dgst *digest.Digest, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "unsupported digest algorithm sha1")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &objectStorage{
+				config: &config.DaemonOption{
+					ObjectStorage: config.ObjectStorageOption{
+						DigestAlgorithm: tc.algorithm,
+					},
+				},
+			}
+
+			algorithm, err := o.digestAlgorithm(tc.header)
+			if err != nil {
+				tc.expect(t, nil, err)
+				return
+			}
+
+			dgst, err := o.digestFromFileHeader(mockFileHeader(t, mockObjectContent), algorithm)
+			tc.expect(t, dgst, err)
+		})
+	}
+}
+
+func TestObjectStorage_importObjectToSeedPeer(t *testing.T) {
+	tests := []struct {
+		name   string
+		dgst   *digest.Digest
+		token  string
+		expect func(t *testing.T, received []byte, token string, err error)
+	}{
+		{
+			name: "import object with matching digest",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			expect: func(t *testing.T, received []byte, token string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockObjectContent, received)
+				assert.Empty(token)
+			},
+		},
+		{
+			name:  "import object with token",
+			dgst:  digest.New(digest.AlgorithmMD5, digest.MD5FromBytes(mockObjectContent)),
+			token: "baz",
+			expect: func(t *testing.T, received []byte, token string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockObjectContent, received)
+				assert.Equal("baz", token)
+			},
+		},
+		{
+			name: "import object with mismatching digest",
+			dgst: digest.New(digest.AlgorithmMD5, digest.MD5FromBytes([]byte("foo"))),
+			expect: func(t *testing.T, received []byte, token string, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, digest.ErrDigestMismatch)
+				assert.Equal(ErrorCodeBadDigest, ErrorFrom(err).Code)
+				assert.Nil(received)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				received []byte
+				token    string
+			)
+			seedPeer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				token = r.Header.Get(config.HeaderDragonflyObjectToken)
+				f, _, err := r.FormFile("file")
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				defer f.Close()
+
+				if received, err = io.ReadAll(f); err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+			}))
+			defer seedPeer.Close()
+
+			o := &objectStorage{}
+			err := o.importObjectToSeedPeer(context.Background(), seedPeer.Listener.Addr().String(), "foo", "bar", "", tc.token,
+				tc.dgst, 0, mockFileHeader(t, mockObjectContent))
+			tc.expect(t, received, token, err)
+		})
+	}
+}
+
+func TestObjectStorage_putEmptyObject(t *testing.T) {
+	emptyDigest := digest.New(digest.AlgorithmMD5, digest.MD5FromBytes([]byte{}))
+
+	tests := []struct {
+		name string
+		mode int
+		// async is the number of the calls in the background.
+		async int
+		mock  func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, dynconfig *configmocks.MockDynconfigMockRecorder, done func())
+	}{
+		{
+			name: "put empty object in ephemeral mode",
+			mode: Ephemeral,
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, dynconfig *configmocks.MockDynconfigMockRecorder, done func()) {
+			},
+		},
+		{
+			name:  "put empty object in write back mode",
+			mode:  WriteBack,
+			async: 1,
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, dynconfig *configmocks.MockDynconfigMockRecorder, done func()) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(nil, false, nil).Times(1)
+				objectStorageClient.PutObject(gomock.Any(), "foo", "bar", emptyDigest.String(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, bucketName, objectKey, digest string, reader io.Reader) error {
+						data, err := io.ReadAll(reader)
+						assert.Empty(t, data)
+						return err
+					}).Times(1)
+				dynconfig.GetSchedulers().DoAndReturn(func() ([]*managerv1.Scheduler, error) {
+					defer done()
+					return nil, nil
+				}).Times(1)
+			},
+		},
+		{
+			name:  "put empty object in async write back mode",
+			mode:  AsyncWriteBack,
+			async: 2,
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder, dynconfig *configmocks.MockDynconfigMockRecorder, done func()) {
+				objectStorageClient.GetObjectMetadata(gomock.Any(), "foo", "bar").Return(nil, false, nil).Times(1)
+				objectStorageClient.PutObject(gomock.Any(), "foo", "bar", emptyDigest.String(), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, bucketName, objectKey, digest string, reader io.Reader) error {
+						defer done()
+						data, err := io.ReadAll(reader)
+						assert.Empty(t, data)
+						return err
+					}).Times(1)
+				dynconfig.GetSchedulers().DoAndReturn(func() ([]*managerv1.Scheduler, error) {
+					defer done()
+					return nil, nil
+				}).Times(1)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			var wg sync.WaitGroup
+			wg.Add(tc.async)
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			dynconfig := configmocks.NewMockDynconfig(ctl)
+			tc.mock(objectStorageClient.EXPECT(), dynconfig.EXPECT(), wg.Done)
+			objectStorageClient.EXPECT().GetSignURL(gomock.Any(), "foo", "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar", nil).Times(1)
+
+			taskStorageDriver := storagemocks.NewMockTaskStorageDriver(ctl)
+			taskStorageDriver.EXPECT().UpdateTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *storage.UpdateTaskRequest) error {
+					assert := assert.New(t)
+					assert.Equal(int64(0), req.ContentLength)
+					assert.Equal(int32(0), req.TotalPieces)
+					return nil
+				}).Times(1)
+			taskStorageDriver.EXPECT().Store(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			storageManager := storagemocks.NewMockManager(ctl)
+			storageManager.EXPECT().RegisterTask(gomock.Any(), gomock.Any()).Return(taskStorageDriver, nil).Times(1)
+
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			peerTaskManager.EXPECT().AnnouncePeerTask(gomock.Any(), gomock.Any(), "http://example.com/foo/bar", commonv1.TaskType_DfStore, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, meta storage.PeerTaskMetadata, url string, taskType commonv1.TaskType, urlMeta *commonv1.UrlMeta) error {
+					assert.Equal(t, emptyDigest.String(), urlMeta.Digest)
+					return nil
+				}).Times(1)
+
+			o := &objectStorage{
+				config: &config.DaemonOption{
+					ObjectStorage: config.ObjectStorageOption{
+						MaxReplicas: 1,
+					},
+				},
+				dynconfig:           dynconfig,
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				storageManager:      storageManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.PUT("/buckets/:id/objects/*object_key", o.putObject)
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			assert.NoError(t, writer.WriteField("mode", fmt.Sprint(tc.mode)))
+			_, err := writer.CreateFormFile("file", "bar")
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Close())
+
+			req := httptest.NewRequest(http.MethodPut, "/buckets/foo/objects/bar", &body)
+			req.Header.Set(headers.ContentType, writer.FormDataContentType())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			done := make(chan struct{})
+			go func() {
+				wg.Wait()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("background import of empty object is not done")
+			}
+		})
+	}
+}
+
+func TestObjectStorage_putObjectSeedPeerFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		// seedPeerCode is the error code of seed peer, seed peer succeeds if it is empty.
+		seedPeerCode ErrorCode
+		expect       func(t *testing.T, w *httptest.ResponseRecorder, seedPeerAddr string)
+	}{
+		{
+			name: "import object to seed peers",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, seedPeerAddr string) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+		{
+			name:         "import object to all seed peers failed",
+			seedPeerCode: ErrorCodeBackendError,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder, seedPeerAddr string) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusServiceUnavailable, w.Code)
+
+				var resp ErrorResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(ErrorCodeP2PUnavailable, resp.Code)
+				assert.Equal(map[string]ErrorCode{seedPeerAddr: ErrorCodeBackendError}, resp.Details)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			seedPeer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tc.seedPeerCode != "" {
+					w.WriteHeader(errorCodeStatus[tc.seedPeerCode])
+					json.NewEncoder(w).Encode(ErrorResponse{Code: tc.seedPeerCode}) // nolint: errcheck
+				}
+			}))
+			defer seedPeer.Close()
+			seedPeerAddr := seedPeer.Listener.Addr().(*net.TCPAddr)
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			objectStorageClient.EXPECT().GetSignURL(gomock.Any(), "foo", "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar", nil).Times(1)
+			objectStorageClient.EXPECT().GetObjectMetadata(gomock.Any(), "foo", "bar").Return(nil, false, nil).Times(1)
+			objectStorageClient.EXPECT().PutObject(gomock.Any(), "foo", "bar", gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+			dynconfig := configmocks.NewMockDynconfig(ctl)
+			dynconfig.EXPECT().GetSchedulers().Return([]*managerv1.Scheduler{{
+				SeedPeers: []*managerv1.SeedPeer{{
+					Ip:                seedPeerAddr.IP.String(),
+					ObjectStoragePort: int32(seedPeerAddr.Port),
+				}},
+			}}, nil).Times(1)
+
+			taskStorageDriver := storagemocks.NewMockTaskStorageDriver(ctl)
+			taskStorageDriver.EXPECT().UpdateTask(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			taskStorageDriver.EXPECT().Store(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			storageManager := storagemocks.NewMockManager(ctl)
+			storageManager.EXPECT().RegisterTask(gomock.Any(), gomock.Any()).Return(taskStorageDriver, nil).Times(1)
+
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			peerTaskManager.EXPECT().AnnouncePeerTask(gomock.Any(), gomock.Any(), "http://example.com/foo/bar", commonv1.TaskType_DfStore, gomock.Any()).Return(nil).Times(1)
+
+			o := &objectStorage{
+				config: &config.DaemonOption{
+					ObjectStorage: config.ObjectStorageOption{
+						MaxReplicas: 1,
+					},
+				},
+				dynconfig:           dynconfig,
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				storageManager:      storageManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+				seedPeerSelector:    newSeedPeerSelector(config.AllSeedPeerSelection, "", ""),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.PUT("/buckets/:id/objects/*object_key", o.putObject)
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			assert.NoError(t, writer.WriteField("mode", fmt.Sprint(WriteBack)))
+			_, err := writer.CreateFormFile("file", "bar")
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Close())
+
+			req := httptest.NewRequest(http.MethodPut, "/buckets/foo/objects/bar", &body)
+			req.Header.Set(headers.ContentType, writer.FormDataContentType())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			tc.expect(t, w, seedPeerAddr.String())
+		})
+	}
+}
+
+func TestObjectStorage_putObjectWithDigest(t *testing.T) {
+	tests := []struct {
+		name   string
+		digest string
+		// imported is whether the object is imported to local storage.
+		imported bool
+		// announced is whether the object is announced to scheduler.
+		announced bool
+		expect    func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name:      "sha256 digest matches",
+			digest:    digest.New(digest.AlgorithmSHA256, digest.SHA256FromBytes(mockObjectContent)).String(),
+			imported:  true,
+			announced: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+
+				var resp PutObjectResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(digest.New(digest.AlgorithmSHA256, digest.SHA256FromBytes(mockObjectContent)).String(), resp.Digest)
+			},
+		},
+		{
+			name:      "sha1 digest matches",
+			digest:    "sha1:" + strings.ToUpper(mockSHA1(mockObjectContent)),
+			imported:  true,
+			announced: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+
+				var resp PutObjectResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal("sha1:"+mockSHA1(mockObjectContent), resp.Digest)
+			},
+		},
+		{
+			name:     "digest does not match",
+			digest:   digest.New(digest.AlgorithmMD5, digest.MD5FromBytes([]byte("foo"))).String(),
+			imported: true,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+
+				var resp ErrorResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(ErrorCodeBadDigest, resp.Code)
+			},
+		},
+		{
+			name:   "unsupported digest algorithm",
+			digest: "sha512:" + strings.Repeat("0", 128),
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+
+				var resp ErrorResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(ErrorCodeValidationFailed, resp.Code)
+				assert.Equal("unsupported digest algorithm sha512", resp.Message)
+			},
+		},
+		{
+			name:   "invalid digest",
+			digest: "md5:foo",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+
+				var resp ErrorResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(ErrorCodeValidationFailed, resp.Code)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			storageManager := storagemocks.NewMockManager(ctl)
+			peerTaskManager := peer.NewMockTaskManager(ctl)
+			if tc.imported {
+				objectStorageClient.EXPECT().GetSignURL(gomock.Any(), "foo", "bar", objectstorage.MethodGet, gomock.Any()).Return("http://example.com/foo/bar", nil).Times(1)
+
+				taskStorageDriver := storagemocks.NewMockTaskStorageDriver(ctl)
+				storageManager.EXPECT().RegisterTask(gomock.Any(), gomock.Any()).Return(taskStorageDriver, nil).Times(1)
+
+				// The mismatched object is unregistered instead of being stored.
+				if tc.announced {
+					taskStorageDriver.EXPECT().Store(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				} else {
+					storageManager.EXPECT().UnregisterTask(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+				}
+
+				pieceManager := peer.NewMockPieceManager(ctl)
+				pieceManager.EXPECT().Import(gomock.Any(), gomock.Any(), gomock.Any(), int64(len(mockObjectContent)), gomock.Any()).DoAndReturn(
+					func(ctx context.Context, ptm storage.PeerTaskMetadata, tsd storage.TaskStorageDriver, contentLength int64, reader io.Reader) error {
+						if _, err := io.Copy(io.Discard, reader); err != nil {
+							return err
+						}
+
+						return tsd.Store(ctx, &storage.StoreRequest{MetadataOnly: true})
+					}).Times(1)
+				peerTaskManager.EXPECT().GetPieceManager().Return(pieceManager).Times(1)
+			}
+
+			if tc.announced {
+				peerTaskManager.EXPECT().AnnouncePeerTask(gomock.Any(), gomock.Any(), "http://example.com/foo/bar", commonv1.TaskType_DfStore, gomock.Any()).Return(nil).Times(1)
+			}
+
+			o := &objectStorage{
+				config:              &config.DaemonOption{},
+				objectStorageClient: objectStorageClient,
+				peerTaskManager:     peerTaskManager,
+				storageManager:      storageManager,
+				peerIDGenerator:     peer.NewPeerIDGenerator("127.0.0.1"),
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.PUT("/buckets/:id/objects/*object_key", o.putObject)
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			assert.NoError(t, writer.WriteField("mode", fmt.Sprint(Ephemeral)))
+			assert.NoError(t, writer.WriteField("digest", tc.digest))
+			part, err := writer.CreateFormFile("file", "bar")
+			assert.NoError(t, err)
+			_, err = part.Write(mockObjectContent)
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Close())
+
+			req := httptest.NewRequest(http.MethodPut, "/buckets/foo/objects/bar", &body)
+			req.Header.Set(headers.ContentType, writer.FormDataContentType())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			tc.expect(t, w)
+		})
+	}
+}
+
+// mockSHA1 returns the hex encoded sha1 of the data.
+func mockSHA1(data []byte) string {
+	h := sha1.Sum(data)
+	return hex.EncodeToString(h[:])
+}
+
+func TestObjectStorage_getReadiness(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder)
+		expect func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name: "backend is reachable",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder) {
+				objectStorageClient.ListBucketMetadatas(gomock.Any()).DoAndReturn(
+					func(ctx context.Context) ([]*objectstorage.BucketMetadata, error) {
+						_, ok := ctx.Deadline()
+						assert.True(t, ok)
+						return []*objectstorage.BucketMetadata{{Name: "foo"}}, nil
+					}).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+			},
+		},
+		{
+			name: "backend is unreachable",
+			mock: func(objectStorageClient *objectstoragemocks.MockObjectStorageMockRecorder) {
+				objectStorageClient.ListBucketMetadatas(gomock.Any()).Return(nil, errors.New("connection refused")).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusServiceUnavailable, w.Code)
+
+				var resp ErrorResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(ErrorCodeBackendError, resp.Code)
+				assert.Equal("backend is unreachable: connection refused", resp.Message)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			objectStorageClient := objectstoragemocks.NewMockObjectStorage(ctl)
+			tc.mock(objectStorageClient.EXPECT())
+			o := &objectStorage{objectStorageClient: objectStorageClient}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.GET("/healthy", o.getHealth)
+			r.GET("/readyz", o.getReadiness)
+
+			// The liveness probe does not check the backend.
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthy", nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			tc.expect(t, w)
+		})
+	}
+}
+
+func TestObjectStorage_putObjectSizeLimit(t *testing.T) {
+	tests := []struct {
+		name          string
+		maxObjectSize unit.Bytes
+		expect        func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name:          "object exceeds the size limit",
+			maxObjectSize: unit.KB,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusRequestEntityTooLarge, w.Code)
+
+				var resp ErrorResponse
+				assert.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+				assert.Equal(ErrorCodeEntityTooLarge, resp.Code)
+				assert.Equal("object exceeds the size limit of 1024 bytes", resp.Message)
+			},
+		},
+		{
+			name:          "object is within the size limit",
+			maxObjectSize: unit.MB,
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				// The request is bound and rejected by the invalid digest.
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
+		{
+			name: "size is not limited",
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			o := &objectStorage{
+				config: &config.DaemonOption{
+					ObjectStorage: config.ObjectStorageOption{
+						MaxReplicas:   1,
+						MaxObjectSize: tc.maxObjectSize,
+					},
+				},
+			}
+
+			gin.SetMode(gin.TestMode)
+			r := gin.New()
+			r.Use(ErrorHandler())
+			r.PUT("/buckets/:id/objects/*object_key", o.putObject)
+
+			var body bytes.Buffer
+			writer := multipart.NewWriter(&body)
+			assert.NoError(t, writer.WriteField("digest", "foo"))
+			part, err := writer.CreateFormFile("file", "bar")
+			assert.NoError(t, err)
+			_, err = part.Write(bytes.Repeat([]byte{'a'}, 4*int(unit.KB)))
+			assert.NoError(t, err)
+			assert.NoError(t, writer.Close())
+
+			req := httptest.NewRequest(http.MethodPut, "/buckets/foo/objects/bar", &body)
+			req.Header.Set(headers.ContentType, writer.FormDataContentType())
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			tc.expect(t, w)
+		})
+	}
+}
+
+func TestObjectStorage_ReadTimeout(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	dynconfig := configmocks.NewMockDynconfig(ctl)
+	dynconfig.EXPECT().GetObjectStorage().Return(&managerv1.ObjectStorage{
+		Name:     objectstorage.ServiceNameS3,
+		Region:   "foo",
+		Endpoint: "http://127.0.0.1:9000",
+	}, nil).Times(1)
+
+	o, err := New(&config.DaemonOption{
+		Options: base.Options{Console: true},
+		ObjectStorage: config.ObjectStorageOption{
+			MaxReplicas: 1,
+			ReadTimeout: 100 * time.Millisecond,
+		},
+	}, dynconfig, nil, nil, t.TempDir())
+	assert.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	go o.Serve(lis) // nolint: errcheck
+	defer o.Stop()  // nolint: errcheck
+
+	conn, err := net.Dial("tcp", lis.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// The slow client sends the header and a part of the body, then stalls.
+	_, err = fmt.Fprintf(conn, "PUT /buckets/foo/objects/bar HTTP/1.1\r\nHost: %s\r\nContent-Type: multipart/form-data; boundary=foo\r\nContent-Length: 1048576\r\n\r\n--foo\r\n", lis.Addr())
+	assert.NoError(t, err)
+
+	// The server gives up reading the request when the read timeout expires,
+	// instead of waiting for the client forever.
+	start := time.Now()
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = io.ReadAll(conn)
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		assert.False(t, netErr.Timeout())
+	}
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
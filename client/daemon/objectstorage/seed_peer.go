@@ -0,0 +1,122 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objectstorage
+
+import (
+	"sort"
+	"strings"
+
+	"go.uber.org/atomic"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/pkg/types"
+)
+
+// maxLocationElementLen is the maximum number of location elements matched.
+const maxLocationElementLen = 5
+
+// seedPeerHost is the seed peer which object is imported to.
+type seedPeerHost struct {
+	// Addr is the object storage address of seed peer.
+	Addr string
+
+	// IDC is the idc of seed peer.
+	IDC string
+
+	// Location is the location of seed peer.
+	Location string
+}
+
+// seedPeerSelector selects seed peers to import object.
+type seedPeerSelector struct {
+	strategy config.SeedPeerSelection
+	idc      string
+	location string
+	cursor   *atomic.Uint64
+}
+
+// newSeedPeerSelector returns a new seedPeerSelector, idc and location are used by nearest strategy.
+func newSeedPeerSelector(strategy config.SeedPeerSelection, idc, location string) *seedPeerSelector {
+	return &seedPeerSelector{
+		strategy: strategy,
+		idc:      idc,
+		location: location,
+		cursor:   atomic.NewUint64(0),
+	}
+}
+
+// Select returns the seed peers to import object in order. Strategy all returns all seed peers,
+// and import stops when max replicas succeed. Strategy round-robin and nearest return
+// at most max replicas seed peers.
+func (s *seedPeerSelector) Select(hosts []seedPeerHost, maxReplicas int) []seedPeerHost {
+	if len(hosts) == 0 {
+		return nil
+	}
+
+	switch s.strategy {
+	case config.RoundRobinSeedPeerSelection:
+		start := int((s.cursor.Inc() - 1) % uint64(len(hosts)))
+		selected := make([]seedPeerHost, 0, len(hosts))
+		selected = append(selected, hosts[start:]...)
+		selected = append(selected, hosts[:start]...)
+		return limitSeedPeerHosts(selected, maxReplicas)
+	case config.NearestSeedPeerSelection:
+		selected := make([]seedPeerHost, len(hosts))
+		copy(selected, hosts)
+		sort.SliceStable(selected, func(i, j int) bool {
+			return s.distance(selected[i]) < s.distance(selected[j])
+		})
+
+		return limitSeedPeerHosts(selected, maxReplicas)
+	default:
+		return hosts
+	}
+}
+
+// distance returns the distance between host and seed peer, the same idc is nearest,
+// then the more location elements matched, the nearer it is.
+func (s *seedPeerSelector) distance(host seedPeerHost) int {
+	if s.idc != "" && strings.EqualFold(s.idc, host.IDC) {
+		return 0
+	}
+
+	distance := maxLocationElementLen + 1
+	if s.location == "" || host.Location == "" {
+		return distance
+	}
+
+	srcElements := strings.Split(s.location, types.AffinitySeparator)
+	dstElements := strings.Split(host.Location, types.AffinitySeparator)
+	for i := 0; i < len(srcElements) && i < len(dstElements) && i < maxLocationElementLen; i++ {
+		if !strings.EqualFold(srcElements[i], dstElements[i]) {
+			break
+		}
+
+		distance--
+	}
+
+	return distance
+}
+
+// limitSeedPeerHosts returns at most limit seed peers.
+func limitSeedPeerHosts(hosts []seedPeerHost, limit int) []seedPeerHost {
+	if limit > 0 && len(hosts) > limit {
+		return hosts[:limit]
+	}
+
+	return hosts
+}
@@ -0,0 +1,153 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package objectstorage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/client/config"
+)
+
+var mockSeedPeerHosts = []seedPeerHost{
+	{Addr: "127.0.0.1:65004", IDC: "idc-1", Location: "cn|zj|hz"},
+	{Addr: "127.0.0.2:65004", IDC: "idc-2", Location: "cn|sh"},
+	{Addr: "127.0.0.3:65004", IDC: "idc-3", Location: "cn|zj|nb"},
+	{Addr: "127.0.0.4:65004", IDC: "idc-4", Location: "us"},
+}
+
+func TestSeedPeerSelector_Select(t *testing.T) {
+	tests := []struct {
+		name        string
+		strategy    config.SeedPeerSelection
+		idc         string
+		location    string
+		hosts       []seedPeerHost
+		maxReplicas int
+		times       int
+		expect      func(t *testing.T, selected [][]seedPeerHost)
+	}{
+		{
+			name:        "select all seed peers",
+			strategy:    config.AllSeedPeerSelection,
+			hosts:       mockSeedPeerHosts,
+			maxReplicas: 2,
+			times:       1,
+			expect: func(t *testing.T, selected [][]seedPeerHost) {
+				assert := assert.New(t)
+				assert.Equal(mockSeedPeerHosts, selected[0])
+			},
+		},
+		{
+			name:        "select all seed peers with empty strategy",
+			hosts:       mockSeedPeerHosts,
+			maxReplicas: 2,
+			times:       1,
+			expect: func(t *testing.T, selected [][]seedPeerHost) {
+				assert := assert.New(t)
+				assert.Equal(mockSeedPeerHosts, selected[0])
+			},
+		},
+		{
+			name:        "select seed peers in round-robin",
+			strategy:    config.RoundRobinSeedPeerSelection,
+			hosts:       mockSeedPeerHosts,
+			maxReplicas: 2,
+			times:       5,
+			expect: func(t *testing.T, selected [][]seedPeerHost) {
+				assert := assert.New(t)
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[0], mockSeedPeerHosts[1]}, selected[0])
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[1], mockSeedPeerHosts[2]}, selected[1])
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[2], mockSeedPeerHosts[3]}, selected[2])
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[3], mockSeedPeerHosts[0]}, selected[3])
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[0], mockSeedPeerHosts[1]}, selected[4])
+			},
+		},
+		{
+			name:        "select seed peers in round-robin with max replicas greater than seed peers",
+			strategy:    config.RoundRobinSeedPeerSelection,
+			hosts:       mockSeedPeerHosts[:2],
+			maxReplicas: 3,
+			times:       2,
+			expect: func(t *testing.T, selected [][]seedPeerHost) {
+				assert := assert.New(t)
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[0], mockSeedPeerHosts[1]}, selected[0])
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[1], mockSeedPeerHosts[0]}, selected[1])
+			},
+		},
+		{
+			name:        "select nearest seed peers by idc",
+			strategy:    config.NearestSeedPeerSelection,
+			idc:         "idc-3",
+			location:    "us",
+			hosts:       mockSeedPeerHosts,
+			maxReplicas: 2,
+			times:       1,
+			expect: func(t *testing.T, selected [][]seedPeerHost) {
+				assert := assert.New(t)
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[2], mockSeedPeerHosts[3]}, selected[0])
+			},
+		},
+		{
+			name:        "select nearest seed peers by location",
+			strategy:    config.NearestSeedPeerSelection,
+			location:    "cn|zj|nb",
+			hosts:       mockSeedPeerHosts,
+			maxReplicas: 3,
+			times:       1,
+			expect: func(t *testing.T, selected [][]seedPeerHost) {
+				assert := assert.New(t)
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[2], mockSeedPeerHosts[0], mockSeedPeerHosts[1]}, selected[0])
+			},
+		},
+		{
+			name:        "select nearest seed peers without idc and location",
+			strategy:    config.NearestSeedPeerSelection,
+			hosts:       mockSeedPeerHosts,
+			maxReplicas: 2,
+			times:       1,
+			expect: func(t *testing.T, selected [][]seedPeerHost) {
+				assert := assert.New(t)
+				assert.Equal([]seedPeerHost{mockSeedPeerHosts[0], mockSeedPeerHosts[1]}, selected[0])
+			},
+		},
+		{
+			name:        "select seed peers without seed peers",
+			strategy:    config.NearestSeedPeerSelection,
+			maxReplicas: 2,
+			times:       1,
+			expect: func(t *testing.T, selected [][]seedPeerHost) {
+				assert := assert.New(t)
+				assert.Empty(selected[0])
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			selector := newSeedPeerSelector(tc.strategy, tc.idc, tc.location)
+
+			var selected [][]seedPeerHost
+			for i := 0; i < tc.times; i++ {
+				selected = append(selected, selector.Select(tc.hosts, tc.maxReplicas))
+			}
+
+			tc.expect(t, selected)
+		})
+	}
+}
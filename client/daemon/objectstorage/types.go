@@ -21,6 +21,9 @@ import "mime/multipart"
 const (
 	// CopyOperation is the operation of copying object.
 	CopyOperation = "copy"
+
+	// PreheatAction is the action of preheating object, it is the last segment of the object path.
+	PreheatAction = "preheat"
 )
 
 type BucketParams struct {
@@ -46,15 +49,32 @@ type PutObjectRequest struct {
 	// MaxReplicas is the max replicas of the object.
 	MaxReplicas int `form:"maxReplicas" binding:"omitempty,gt=0,lte=100"`
 
+	// Digest is the expected digest of the object in format of algorithm:encoded,
+	// the algorithm can be md5, sha1 or sha256.
+	Digest string `form:"digest" binding:"omitempty"`
+
 	// File is the file of the object.
 	File *multipart.FileHeader `form:"file" binding:"required"`
 }
 
+type PutObjectResponse struct {
+	// Digest is the verified digest of the object.
+	Digest string `json:"digest"`
+}
+
 type GetObjectQuery struct {
 	// Filter is the filter of the object.
 	Filter string `form:"filter" binding:"omitempty"`
 }
 
+type PreheatObjectResponse struct {
+	// TaskID is the id of the task preheating the object.
+	TaskID string `json:"taskID"`
+
+	// CacheStatus is the cache status of the object when the preheating starts.
+	CacheStatus string `json:"cacheStatus"`
+}
+
 type GetObjectMetadatasQuery struct {
 	// A delimiter is a character used to group keys.
 	Delimiter string `form:"delimiter" binding:"omitempty"`
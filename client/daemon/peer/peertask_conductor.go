@@ -26,12 +26,15 @@ import (
 	"sync"
 	"time"
 
+	"github.com/bits-and-blooms/bitset"
 	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/atomic"
 	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
@@ -41,12 +44,15 @@ import (
 
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	"d7y.io/dragonfly/v2/client/daemon/networktopology"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/compression"
 	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/piece"
 	"d7y.io/dragonfly/v2/pkg/rpc/common"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
 	"d7y.io/dragonfly/v2/pkg/source"
@@ -96,6 +102,9 @@ type peerTaskConductor struct {
 
 	schedulerClient schedulerclient.V1
 
+	// pieceCodec is the codec of pieces downloaded from other peers, enabled by scheduler
+	pieceCodec string
+
 	// peer task meta info
 	peerID          string
 	taskID          string
@@ -174,6 +183,11 @@ type TaskOption struct {
 	GRPCDialTimeout time.Duration
 	// WatchdogTimeout > 0 indicates to start watch dog for every single peer task
 	WatchdogTimeout time.Duration
+	// PieceCompression indicates to advertise the supported codecs to scheduler
+	// and accept compressed pieces from other peers
+	PieceCompression bool
+	// ParentProber probes the latency of the scheduled parents, nil indicates not to probe
+	ParentProber networktopology.ParentProber
 }
 
 func (ptm *peerTaskManager) newPeerTaskConductor(
@@ -270,12 +284,32 @@ func (pt *peerTaskConductor) register() error {
 	pt.Infof("step 1: peer %s start to register", pt.request.PeerId)
 	pt.schedulerClient = pt.peerTaskManager.SchedulerClient
 
-	result, err := pt.schedulerClient.RegisterPeerTask(regCtx, pt.request)
+	var (
+		registerHeader metadata.MD
+		registerOpts   []grpc.CallOption
+	)
+	if pt.PieceCompression {
+		regCtx = compression.AppendCodecsToOutgoingContext(regCtx)
+		registerOpts = append(registerOpts, grpc.Header(&registerHeader))
+	}
+
+	// When registering again, e.g. the scheduler restarted, claim the finished pieces,
+	// so that the peer is a candidate parent before reporting the pieces again.
+	if finished := pt.finishedPieces(regCtx); finished != nil {
+		if ctx, err := piece.AppendFinishedToOutgoingContext(regCtx, finished); err != nil {
+			pt.Warnf("claim finished pieces failed: %s", err)
+		} else {
+			pt.Infof("claim %d finished pieces", finished.Pieces.Count())
+			regCtx = ctx
+		}
+	}
+
+	result, err := pt.schedulerClient.RegisterPeerTask(regCtx, pt.request, registerOpts...)
 	regSpan.RecordError(err)
 	regSpan.End()
 
 	if err != nil {
-		if errors.Is(err, context.DeadlineExceeded) {
+		if schedulerclient.IsTimeout(err) {
 			pt.Errorf("scheduler did not response in %s", pt.SchedulerOption.ScheduleTimeout.Duration)
 		}
 		pt.Errorf("step 1: peer %s register failed: %s", pt.request.PeerId, err)
@@ -291,11 +325,23 @@ func (pt *peerTaskConductor) register() error {
 			return err
 		}
 
+		// The registration is throttled by scheduler, falling back to the source moves the storm
+		// of registrations to the source, so the peer task fails with the retryable code.
+		if schedulerclient.IsThrottled(err) {
+			pt.Errorf("register peer task throttled: %s, peer id: %s, do not back source", err, pt.request.PeerId)
+			pt.span.RecordError(err)
+			pt.cancel(commonv1.Code_ResourceLacked, err.Error())
+			return err
+		}
+
 		needBackSource = true
 		result = &schedulerv1.RegisterResult{TaskId: pt.taskID}
 		pt.Warnf("register peer task failed: %s, peer id: %s, try to back source", err, pt.request.PeerId)
 	} else {
 		pt.Infof("register task success, SizeScope: %s", commonv1.SizeScope_name[int32(result.SizeScope)])
+		if pt.pieceCodec = compression.Negotiate(registerHeader.Get(compression.PieceCodecMetadataKey)); pt.pieceCodec != "" {
+			pt.Infof("piece compression is enabled with codec %s", pt.pieceCodec)
+		}
 	}
 
 	var header map[string]string
@@ -348,6 +394,9 @@ func (pt *peerTaskConductor) register() error {
 	peerPacketStream, err := pt.schedulerClient.ReportPieceResult(pt.ctx, pt.request)
 	pt.Infof("step 2: start report piece result")
 	if err != nil {
+		if schedulerclient.IsTimeout(err) {
+			pt.Errorf("scheduler did not establish the stream of piece results in %s", pt.SchedulerOption.Timeouts.Connect)
+		}
 		// when peer register failed, some actions need to do with peerPacketStream
 		pt.peerPacketStream = &dummyPeerPacketStream{}
 		pt.span.RecordError(err)
@@ -865,6 +914,50 @@ func (pt *peerTaskConductor) confirmReceivePeerPacketError(err error) (cont bool
 	return false
 }
 
+// finishedPieces returns the pieces which are both finished by the peer task and stored in the local storage,
+// it returns nil if there is no finished piece or the piece size is unknown.
+func (pt *peerTaskConductor) finishedPieces(ctx context.Context) *piece.Finished {
+	totalPieces := pt.GetTotalPieces()
+	if totalPieces <= 0 || pt.readyPieces.Settled() == 0 || pt.GetStorage() == nil {
+		return nil
+	}
+
+	piecePacket, err := pt.GetStorage().GetPieces(ctx, &commonv1.PieceTaskRequest{
+		TaskId:   pt.taskID,
+		SrcPid:   pt.peerID,
+		DstPid:   pt.peerID,
+		StartNum: 0,
+		Limit:    uint32(totalPieces),
+	})
+	if err != nil {
+		pt.Warnf("get finished pieces failed: %s", err)
+		return nil
+	}
+
+	finished := &piece.Finished{
+		TaskID: pt.taskID,
+		Pieces: &bitset.BitSet{},
+	}
+	for _, pieceInfo := range piecePacket.PieceInfos {
+		if !pt.readyPieces.IsSet(pieceInfo.PieceNum) {
+			continue
+		}
+
+		finished.Pieces.Set(uint(pieceInfo.PieceNum))
+		if pieceInfo.PieceNum < totalPieces-1 {
+			finished.PieceSize = pieceInfo.RangeSize
+		} else if finished.PieceSize == 0 && pieceInfo.PieceNum > 0 {
+			finished.PieceSize = uint32(pieceInfo.RangeStart / uint64(pieceInfo.PieceNum))
+		}
+	}
+
+	if finished.Pieces.Count() == 0 || finished.PieceSize == 0 {
+		return nil
+	}
+
+	return finished
+}
+
 func (pt *peerTaskConductor) isExitPeerPacketCode(pp *schedulerv1.PeerPacket) bool {
 	switch pp.Code {
 	case commonv1.Code_ResourceLacked, commonv1.Code_BadRequest,
@@ -937,13 +1030,14 @@ func (pt *peerTaskConductor) pullSinglePiece() {
 	}
 
 	request = &DownloadPieceRequest{
-		storage: pt.GetStorage(),
-		piece:   pt.singlePiece.PieceInfo,
-		log:     pt.Log(),
-		TaskID:  pt.GetTaskID(),
-		PeerID:  pt.GetPeerID(),
-		DstPid:  pt.singlePiece.DstPid,
-		DstAddr: pt.singlePiece.DstAddr,
+		storage:    pt.GetStorage(),
+		piece:      pt.singlePiece.PieceInfo,
+		log:        pt.Log(),
+		TaskID:     pt.GetTaskID(),
+		PeerID:     pt.GetPeerID(),
+		DstPid:     pt.singlePiece.DstPid,
+		DstAddr:    pt.singlePiece.DstAddr,
+		PieceCodec: pt.pieceCodec,
 	}
 
 	result, err = pt.PieceManager.DownloadPiece(ctx, request)
@@ -0,0 +1,149 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package peer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	testifyassert "github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"go.uber.org/mock/gomock"
+
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+
+	"d7y.io/dragonfly/v2/client/daemon/storage/mocks"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/piece"
+)
+
+func TestPeerTaskConductor_finishedPieces(t *testing.T) {
+	var testCases = []struct {
+		name        string
+		totalPieces int32
+		readyPieces []int32
+		mock        func(ms *mocks.MockTaskStorageDriverMockRecorder)
+		expect      func(t *testing.T, finished *piece.Finished)
+	}{
+		{
+			name:        "claim pieces finished by peer task and stored",
+			totalPieces: 3,
+			readyPieces: []int32{0, 2},
+			mock: func(ms *mocks.MockTaskStorageDriverMockRecorder) {
+				ms.GetPieces(gomock.Any(), gomock.Any()).Return(&commonv1.PiecePacket{
+					PieceInfos: []*commonv1.PieceInfo{
+						{PieceNum: 0, RangeStart: 0, RangeSize: 4},
+						{PieceNum: 1, RangeStart: 4, RangeSize: 4},
+						{PieceNum: 2, RangeStart: 8, RangeSize: 2},
+					},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, finished *piece.Finished) {
+				assert := testifyassert.New(t)
+				assert.Equal("foo", finished.TaskID)
+				assert.Equal(uint32(4), finished.PieceSize)
+				assert.Equal(uint(2), finished.Pieces.Count())
+				assert.True(finished.Pieces.Test(0))
+				assert.False(finished.Pieces.Test(1))
+				assert.True(finished.Pieces.Test(2))
+			},
+		},
+		{
+			name:        "compute piece size with last piece",
+			totalPieces: 3,
+			readyPieces: []int32{2},
+			mock: func(ms *mocks.MockTaskStorageDriverMockRecorder) {
+				ms.GetPieces(gomock.Any(), gomock.Any()).Return(&commonv1.PiecePacket{
+					PieceInfos: []*commonv1.PieceInfo{
+						{PieceNum: 2, RangeStart: 8, RangeSize: 2},
+					},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, finished *piece.Finished) {
+				assert := testifyassert.New(t)
+				assert.Equal(uint32(4), finished.PieceSize)
+				assert.Equal(uint(1), finished.Pieces.Count())
+			},
+		},
+		{
+			name:        "ready pieces are not stored",
+			totalPieces: 3,
+			readyPieces: []int32{1},
+			mock: func(ms *mocks.MockTaskStorageDriverMockRecorder) {
+				ms.GetPieces(gomock.Any(), gomock.Any()).Return(&commonv1.PiecePacket{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, finished *piece.Finished) {
+				assert := testifyassert.New(t)
+				assert.Nil(finished)
+			},
+		},
+		{
+			name:        "get pieces failed",
+			totalPieces: 3,
+			readyPieces: []int32{0},
+			mock: func(ms *mocks.MockTaskStorageDriverMockRecorder) {
+				ms.GetPieces(gomock.Any(), gomock.Any()).Return(nil, errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, finished *piece.Finished) {
+				assert := testifyassert.New(t)
+				assert.Nil(finished)
+			},
+		},
+		{
+			name:        "no ready pieces",
+			totalPieces: 3,
+			mock:        func(ms *mocks.MockTaskStorageDriverMockRecorder) {},
+			expect: func(t *testing.T, finished *piece.Finished) {
+				assert := testifyassert.New(t)
+				assert.Nil(finished)
+			},
+		},
+		{
+			name:        "total pieces is unknown",
+			totalPieces: -1,
+			readyPieces: []int32{0},
+			mock:        func(ms *mocks.MockTaskStorageDriverMockRecorder) {},
+			expect: func(t *testing.T, finished *piece.Finished) {
+				assert := testifyassert.New(t)
+				assert.Nil(finished)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			taskStorage := mocks.NewMockTaskStorageDriver(ctrl)
+			tc.mock(taskStorage.EXPECT())
+
+			pt := &peerTaskConductor{
+				taskID:              "foo",
+				peerID:              "bar",
+				totalPiece:          atomic.NewInt32(tc.totalPieces),
+				readyPieces:         NewBitmap(),
+				storage:             taskStorage,
+				SugaredLoggerOnWith: logger.With("peer", "bar", "task", "foo"),
+			}
+			pt.readyPieces.Sets(tc.readyPieces...)
+
+			tc.expect(t, pt.finishedPieces(context.Background()))
+		})
+	}
+}
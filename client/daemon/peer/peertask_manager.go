@@ -35,6 +35,7 @@ import (
 	dfdaemonv1 "d7y.io/api/v2/pkg/apis/dfdaemon/v1"
 	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
 
+	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/metrics"
 	"d7y.io/dragonfly/v2/client/daemon/pex"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
@@ -236,6 +237,7 @@ retry:
 	}
 	ptm.conductorLock.Unlock()
 	metrics.PeerTaskCount.Add(1)
+	metrics.PeerTaskRunningCount.Inc()
 	logger.Debugf("peer task created: %s/%s", ptc.taskID, ptc.peerID)
 
 	// wait parent RegisterTask done
@@ -270,6 +272,7 @@ func (ptm *peerTaskManager) createSplitedPeerTaskConductor(
 
 	ptm.runningPeerTasks.Store(taskID+"/"+ptc.peerID, ptc)
 	metrics.PeerTaskCount.Add(1)
+	metrics.PeerTaskRunningCount.Inc()
 	logger.Debugf("standalone peer task created: %s/%s", ptc.taskID, ptc.peerID)
 
 	err := ptc.registerStorage(desiredLocation)
@@ -355,6 +358,10 @@ func (ptm *peerTaskManager) StartFileTask(ctx context.Context, req *FileTaskRequ
 }
 
 func (ptm *peerTaskManager) StartStreamTask(ctx context.Context, req *StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+	if req.PieceSize > 0 {
+		req.URLMeta = withPieceSizeHint(req.URLMeta, req.PieceSize)
+	}
+
 	peerTaskRequest := &schedulerv1.PeerTaskRequest{
 		Url:         req.URL,
 		UrlMeta:     req.URLMeta,
@@ -375,7 +382,12 @@ func (ptm *peerTaskManager) StartStreamTask(ctx context.Context, req *StreamTask
 				// only allow resume for range from breakpoint to end
 				if req.Range.Start+req.Range.Length == parentTask.GetContentLength() {
 					pt := ptm.newResumeStreamTask(ctx, parentTask, req.Range)
-					return pt.Start(ctx)
+					readCloser, attribute, err := pt.Start(ctx)
+					if err != nil {
+						return nil, nil, err
+					}
+
+					return &backSourceReadCloser{ReadCloser: readCloser, ptc: parentTask}, withCacheStatus(attribute, CacheStatusPartial), nil
 				}
 			}
 		}
@@ -384,7 +396,7 @@ func (ptm *peerTaskManager) StartStreamTask(ctx context.Context, req *StreamTask
 		r, attr, ok := ptm.tryReuseStreamPeerTask(ctx, taskID, req)
 		if ok {
 			metrics.PeerTaskCacheHitCount.Add(1)
-			return r, attr, nil
+			return r, withCacheStatus(attr, CacheStatusHit), nil
 		}
 	}
 
@@ -395,7 +407,21 @@ func (ptm *peerTaskManager) StartStreamTask(ctx context.Context, req *StreamTask
 
 	// FIXME when failed due to SchedulerClient error, relocate SchedulerClient and retry
 	readCloser, attribute, err := pt.Start(ctx)
-	return readCloser, attribute, err
+	if err != nil {
+		return readCloser, attribute, err
+	}
+
+	return &backSourceReadCloser{ReadCloser: readCloser, ptc: pt.peerTaskConductor}, withCacheStatus(attribute, CacheStatusMiss), nil
+}
+
+// withCacheStatus sets the cache status of the stream task in attribute.
+func withCacheStatus(attr map[string]string, status string) map[string]string {
+	if attr == nil {
+		attr = map[string]string{}
+	}
+
+	attr[config.HeaderDragonflyCache] = status
+	return attr
 }
 
 func (ptm *peerTaskManager) StartSeedTask(ctx context.Context, req *SeedTaskRequest) (response *SeedTaskResponse, reuse bool, err error) {
@@ -463,7 +489,9 @@ func (ptm *peerTaskManager) Stop(ctx context.Context) error {
 func (ptm *peerTaskManager) PeerTaskDone(taskID, peerID string) {
 	key := ptm.getRunningTaskKey(taskID, peerID)
 	logger.Debugf("delete done task %s in running tasks", key)
-	ptm.runningPeerTasks.Delete(key)
+	if _, loaded := ptm.runningPeerTasks.LoadAndDelete(key); loaded {
+		metrics.PeerTaskRunningCount.Dec()
+	}
 	if ptm.trafficShaper != nil {
 		ptm.trafficShaper.RemoveTask(key)
 	}
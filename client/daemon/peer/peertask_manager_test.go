@@ -53,7 +53,6 @@ import (
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/test"
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
@@ -67,6 +66,7 @@ import (
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/source/clients/httpprotocol"
 	sourcemocks "d7y.io/dragonfly/v2/pkg/source/mocks"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 func TestMain(m *testing.M) {
@@ -265,7 +265,7 @@ func setupPeerTaskManagerComponents(ctrl *gomock.Controller, opt componentsOptio
 		config.SimpleLocalTaskStoreStrategy,
 		&config.StorageOption{
 			DataPath: tempDir,
-			TaskExpireTime: util.Duration{
+			TaskExpireTime: types.Duration{
 				Duration: -1 * time.Second,
 			},
 		}, func(request storage.CommonTaskRequest) {}, os.FileMode(0700))
@@ -289,9 +289,9 @@ func (m *mockManager) CleanUp() {
 
 func setupMockManager(ctrl *gomock.Controller, ts *testSpec, opt componentsOption) *mockManager {
 	schedulerClient, storageManager := setupPeerTaskManagerComponents(ctrl, opt)
-	scheduleTimeout := util.Duration{Duration: 10 * time.Minute}
+	scheduleTimeout := types.Duration{Duration: 10 * time.Minute}
 	if ts.scheduleTimeout > 0 {
-		scheduleTimeout = util.Duration{Duration: ts.scheduleTimeout}
+		scheduleTimeout = types.Duration{Duration: ts.scheduleTimeout}
 	}
 	ptm := &peerTaskManager{
 		conductorLock:    &sync.Mutex{},
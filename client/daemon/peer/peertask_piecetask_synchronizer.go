@@ -341,6 +341,11 @@ func (s *pieceTaskSynchronizer) closeGRPC() {
 func (s *pieceTaskSynchronizer) dispatchPieceRequest(piecePacket *commonv1.PiecePacket) {
 	s.peerTaskConductor.updateMetadata(piecePacket)
 
+	// Probe the upload server of the parent, the parent probed within the interval is skipped.
+	if s.peerTaskConductor.ParentProber != nil {
+		s.peerTaskConductor.ParentProber.Probe(piecePacket.DstPid, piecePacket.DstAddr)
+	}
+
 	pieceCount := len(piecePacket.PieceInfos)
 	s.Debugf("dispatch piece request, piece count: %d, dest peer: %s", pieceCount, s.dstPeer.PeerId)
 	// peers maybe send zero piece info, but with total piece count and content length
@@ -361,13 +366,14 @@ func (s *pieceTaskSynchronizer) dispatchPieceRequest(piecePacket *commonv1.Piece
 		}
 		s.peerTaskConductor.requestedPiecesLock.Unlock()
 		req := &DownloadPieceRequest{
-			storage: s.peerTaskConductor.GetStorage(),
-			piece:   piece,
-			log:     s.peerTaskConductor.Log(),
-			TaskID:  s.peerTaskConductor.GetTaskID(),
-			PeerID:  s.peerTaskConductor.GetPeerID(),
-			DstPid:  piecePacket.DstPid,
-			DstAddr: piecePacket.DstAddr,
+			storage:    s.peerTaskConductor.GetStorage(),
+			piece:      piece,
+			log:        s.peerTaskConductor.Log(),
+			TaskID:     s.peerTaskConductor.GetTaskID(),
+			PeerID:     s.peerTaskConductor.GetPeerID(),
+			DstPid:     piecePacket.DstPid,
+			DstAddr:    piecePacket.DstAddr,
+			PieceCodec: s.peerTaskConductor.pieceCodec,
 		}
 
 		s.pieceRequestQueue.Put(req)
@@ -46,7 +46,41 @@ type StreamTaskRequest struct {
 	Range *http.Range
 	// peer's id and must be global uniqueness
 	PeerID string
-	taskID string
+	// piece size hint of the task downloaded back-to-source,
+	// when it is zero, the piece size is computed by the content length
+	PieceSize uint32
+	taskID    string
+}
+
+const (
+	// CacheStatusHit indicates the stream task is served by the completed task in local storage.
+	CacheStatusHit = "hit"
+
+	// CacheStatusPartial indicates the stream task is resumed from the running parent task,
+	// the data before the range is already in local storage.
+	CacheStatusPartial = "partial"
+
+	// CacheStatusMiss indicates the stream task is downloaded by a new peer task.
+	CacheStatusMiss = "miss"
+)
+
+// BackSourceReporter is implemented by the reader of the stream task which is downloaded
+// by a peer task, it reports whether the peer task downloads from the source instead of
+// other peers. The reader of the reused task does not implement it.
+type BackSourceReporter interface {
+	// BackSource returns true if the peer task downloads from the source.
+	BackSource() bool
+}
+
+// backSourceReadCloser is the reader of the stream task which reports back source of the peer task.
+type backSourceReadCloser struct {
+	io.ReadCloser
+	ptc *peerTaskConductor
+}
+
+// BackSource returns true if the peer task downloads from the source.
+func (r *backSourceReadCloser) BackSource() bool {
+	return r.ptc.needBackSource.Load()
 }
 
 func (req *StreamTaskRequest) TaskID() string {
@@ -342,7 +376,11 @@ func (s *resumeStreamTask) Start(ctx context.Context) (io.ReadCloser, map[string
 	attr[config.HeaderDragonflyTask] = s.peerTaskConductor.taskID
 	attr[config.HeaderDragonflyPeer] = s.peerTaskConductor.peerID
 
-	pieceSize := s.computePieceSize(s.peerTaskConductor.GetContentLength())
+	// the parent task downloaded back-to-source with piece size hint, use the same piece size
+	pieceSize := pieceSizeHint(s.peerTaskConductor.request.UrlMeta)
+	if pieceSize == 0 {
+		pieceSize = s.computePieceSize(s.peerTaskConductor.GetContentLength())
+	}
 	nextPiece := int32(s.skipBytes / int64(pieceSize))
 	skipBytesInNextPiece := s.skipBytes % int64(pieceSize)
 
@@ -47,7 +47,6 @@ import (
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/test"
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
 	"d7y.io/dragonfly/v2/pkg/digest"
@@ -58,6 +57,7 @@ import (
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/source/clients/httpprotocol"
 	sourcemocks "d7y.io/dragonfly/v2/pkg/source/mocks"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 func setupBackSourcePartialComponents(ctrl *gomock.Controller, testBytes []byte, opt componentsOption) (
@@ -221,7 +221,7 @@ func setupBackSourcePartialComponents(ctrl *gomock.Controller, testBytes []byte,
 		config.SimpleLocalTaskStoreStrategy,
 		&config.StorageOption{
 			DataPath: tempDir,
-			TaskExpireTime: util.Duration{
+			TaskExpireTime: types.Duration{
 				Duration: -1 * time.Second,
 			},
 		}, func(request storage.CommonTaskRequest) {}, os.FileMode(0700))
@@ -301,7 +301,7 @@ func TestStreamPeerTask_BackSource_Partial_WithContentLength(t *testing.T) {
 				PieceManager:   pm,
 				StorageManager: storageManager,
 				SchedulerOption: config.SchedulerOption{
-					ScheduleTimeout: util.Duration{Duration: 10 * time.Minute},
+					ScheduleTimeout: types.Duration{Duration: 10 * time.Minute},
 				},
 				GRPCDialTimeout: time.Second,
 				GRPCCredentials: insecure.NewCredentials(),
@@ -39,7 +39,6 @@ import (
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/test"
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	"d7y.io/dragonfly/v2/pkg/net/http"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
@@ -47,6 +46,7 @@ import (
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/source/clients/httpprotocol"
 	sourcemocks "d7y.io/dragonfly/v2/pkg/source/mocks"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 func setupResumeStreamTaskComponents(ctrl *gomock.Controller, opt componentsOption) (
@@ -87,7 +87,7 @@ func setupResumeStreamTaskComponents(ctrl *gomock.Controller, opt componentsOpti
 		config.SimpleLocalTaskStoreStrategy,
 		&config.StorageOption{
 			DataPath: tempDir,
-			TaskExpireTime: util.Duration{
+			TaskExpireTime: types.Duration{
 				Duration: -1 * time.Second,
 			},
 		}, func(request storage.CommonTaskRequest) {},
@@ -195,7 +195,7 @@ func TestStreamPeerTask_Resume(t *testing.T) {
 				PieceManager:   pm,
 				StorageManager: storageManager,
 				SchedulerOption: config.SchedulerOption{
-					ScheduleTimeout: util.Duration{Duration: 10 * time.Minute},
+					ScheduleTimeout: types.Duration{Duration: 10 * time.Minute},
 				},
 				GRPCDialTimeout: time.Second,
 				GRPCCredentials: insecure.NewCredentials(),
@@ -138,3 +138,28 @@ func TestBroker(t *testing.T) {
 		})
 	}
 }
+
+// BenchmarkPieceBroker_PublishWithSlowSubscriber measures the latency of the fast subscribers
+// when one subscriber never receives the pieces, the fan-out is non-blocking,
+// so the slow subscriber does not add latency to the other subscribers.
+func BenchmarkPieceBroker_PublishWithSlowSubscriber(b *testing.B) {
+	broker := newPieceBroker()
+	go broker.Start()
+	defer broker.Stop()
+
+	// The slow subscriber never receives the pieces.
+	_ = broker.Subscribe()
+
+	fastChs := make([]chan *PieceInfo, 10)
+	for i := range fastChs {
+		fastChs[i] = broker.Subscribe()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		broker.Publish(&PieceInfo{Num: int32(i)})
+		for _, ch := range fastChs {
+			<-ch
+		}
+	}
+}
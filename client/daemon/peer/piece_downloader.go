@@ -29,6 +29,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/go-http-utils/headers"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 	"google.golang.org/grpc/status"
@@ -37,6 +38,7 @@ import (
 
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/compression"
 	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/source"
 )
@@ -50,6 +52,9 @@ type DownloadPieceRequest struct {
 	DstPid     string
 	DstAddr    string
 	CalcDigest bool
+	// PieceCodec is the codec accepted for compressed piece, the digest
+	// is always calculated over the uncompressed piece.
+	PieceCodec string
 }
 
 type DownloadPieceResult struct {
@@ -189,9 +194,25 @@ func (p *pieceDownloader) DownloadPiece(ctx context.Context, req *DownloadPieceR
 		}
 	}
 	reader, closer := resp.Body.(io.Reader), resp.Body.(io.Closer)
+	if codec := resp.Header.Get(headers.ContentEncoding); codec != "" {
+		decoder, err := compression.NewReader(resp.Body, codec)
+		if err != nil {
+			_ = resp.Body.Close()
+			return nil, nil, &pieceDownloadError{
+				target:          httpRequest.URL.String(),
+				err:             err,
+				connectionError: false,
+				status:          resp.Status,
+				statusCode:      resp.StatusCode,
+			}
+		}
+
+		reader, closer = decoder, &pieceDecoderCloser{decoder: decoder, body: resp.Body}
+	}
+
 	if req.CalcDigest {
 		req.log.Debugf("calculate digest for piece %d, digest: %s", req.piece.PieceNum, req.piece.PieceMd5)
-		reader, err = digest.NewReader(digest.AlgorithmMD5, io.LimitReader(resp.Body, int64(req.piece.RangeSize)), digest.WithEncoded(req.piece.PieceMd5), digest.WithLogger(req.log))
+		reader, err = digest.NewReader(digest.AlgorithmMD5, io.LimitReader(reader, int64(req.piece.RangeSize)), digest.WithEncoded(req.piece.PieceMd5), digest.WithLogger(req.log))
 		if err != nil {
 			_ = closer.Close()
 			req.log.Errorf("init digest reader error: %s", err.Error())
@@ -201,6 +222,17 @@ func (p *pieceDownloader) DownloadPiece(ctx context.Context, req *DownloadPieceR
 	return reader, closer, nil
 }
 
+// pieceDecoderCloser closes both the decoder and the response body of compressed piece.
+type pieceDecoderCloser struct {
+	decoder io.Closer
+	body    io.Closer
+}
+
+func (c *pieceDecoderCloser) Close() error {
+	_ = c.decoder.Close()
+	return c.body.Close()
+}
+
 func (p *pieceDownloader) buildDownloadPieceHTTPRequest(ctx context.Context, d *DownloadPieceRequest) (*http.Request, error) {
 	if len(d.TaskID) <= 3 {
 		return nil, fmt.Errorf("invalid task id")
@@ -220,6 +252,12 @@ func (p *pieceDownloader) buildDownloadPieceHTTPRequest(ctx context.Context, d *
 	req.Header.Add("Range", fmt.Sprintf("bytes=%d-%d",
 		d.piece.RangeStart, d.piece.RangeStart+uint64(d.piece.RangeSize)-1))
 
+	// Accept compressed piece, Accept-Encoding is set explicitly, so the
+	// response body is not decompressed by http transport.
+	if d.PieceCodec != "" {
+		req.Header.Set(headers.AcceptEncoding, d.PieceCodec)
+	}
+
 	// inject trace id into request header
 	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 	return req, nil
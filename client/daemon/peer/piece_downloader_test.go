@@ -40,6 +40,7 @@ import (
 
 	"d7y.io/dragonfly/v2/client/daemon/test"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/compression"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/source/clients/httpprotocol"
@@ -305,3 +306,89 @@ func TestPieceDownloader_DownloadPiece(t *testing.T) {
 		server.Close()
 	}
 }
+
+func TestPieceDownloader_DownloadCompressedPiece(t *testing.T) {
+	testData, err := os.ReadFile(test.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	compressedHandler := func(w http.ResponseWriter, r *http.Request) {
+		rg := nethttp.MustParseRange(r.Header.Get("Range"), math.MaxInt64)
+		codec := compression.Negotiate(compression.ParseCodecs(r.Header.Values(headers.AcceptEncoding)...))
+		if codec == "" {
+			w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", rg.Length))
+			w.Write(testData[rg.Start : rg.Start+rg.Length]) // nolint: errcheck
+			return
+		}
+
+		w.Header().Set(headers.ContentEncoding, codec)
+		writer, err := compression.NewWriter(w, codec, compression.MinLevel)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer writer.Close()
+
+		writer.Write(testData[rg.Start : rg.Start+rg.Length]) // nolint: errcheck
+	}
+
+	plainHandler := func(w http.ResponseWriter, r *http.Request) {
+		rg := nethttp.MustParseRange(r.Header.Get("Range"), math.MaxInt64)
+		w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", rg.Length))
+		w.Write(testData[rg.Start : rg.Start+rg.Length]) // nolint: errcheck
+	}
+
+	tests := []struct {
+		name       string
+		handleFunc http.HandlerFunc
+		pieceCodec string
+	}{
+		{
+			name:       "both peers support compression",
+			handleFunc: compressedHandler,
+			pieceCodec: compression.CodecZstd,
+		},
+		{
+			name:       "parent does not support compression",
+			handleFunc: plainHandler,
+			pieceCodec: compression.CodecZstd,
+		},
+		{
+			name:       "child does not support compression",
+			handleFunc: compressedHandler,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			server := httptest.NewServer(tc.handleFunc)
+			defer server.Close()
+
+			addr, _ := url.Parse(server.URL)
+			hash := md5.New()
+			hash.Write(testData[:1024])
+			pd := NewPieceDownloader(30*time.Second, nil)
+			r, c, err := pd.DownloadPiece(context.Background(), &DownloadPieceRequest{
+				TaskID:     "task-0",
+				DstAddr:    addr.Host,
+				CalcDigest: true,
+				PieceCodec: tc.pieceCodec,
+				piece: &commonv1.PieceInfo{
+					RangeStart: 0,
+					RangeSize:  1024,
+					PieceMd5:   hex.EncodeToString(hash.Sum(nil)),
+					PieceStyle: commonv1.PieceStyle_PLAIN,
+				},
+				log: logger.With("test", "test"),
+			})
+			assert.NoError(err)
+			defer c.Close()
+
+			data, err := io.ReadAll(r)
+			assert.NoError(err)
+			assert.Equal(testData[:1024], data)
+		})
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 
@@ -69,6 +70,8 @@ type pieceManager struct {
 	concurrentOption  *config.ConcurrentOption
 	syncPieceViaHTTPS bool
 	certPool          *x509.CertPool
+	// headerProviders is the name of the header provider by the application of tasks
+	headerProviders map[string]string
 }
 
 type PieceManagerOption func(*pieceManager)
@@ -156,6 +159,14 @@ func WithConcurrentOption(opt *config.ConcurrentOption) func(*pieceManager) {
 	}
 }
 
+// WithHeaderProviders sets the header providers by the application of tasks, the header provider
+// refreshes the header of back source requests when the source rejects it with 401 or 403.
+func WithHeaderProviders(headerProviders map[string]string) func(*pieceManager) {
+	return func(pm *pieceManager) {
+		pm.headerProviders = headerProviders
+	}
+}
+
 func WithSyncPieceViaHTTPS(caCertPEM string) func(*pieceManager) {
 	return func(pm *pieceManager) {
 		logger.Infof("enable syncPieceViaHTTPS for piece manager")
@@ -234,6 +245,8 @@ func (pm *pieceManager) DownloadPiece(ctx context.Context, request *DownloadPiec
 			request.piece.PieceNum, result.Size, err)
 		return result, err
 	}
+
+	metrics.DownloadTraffic.Add(float64(result.Size))
 	return result, nil
 }
 
@@ -318,7 +331,9 @@ func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, peerTaskReq
 	log := pt.Log()
 	log.Infof("start to download from source")
 
-	backSourceRequest, err := source.NewRequestWithContext(ctx, peerTaskRequest.Url, peerTaskRequest.UrlMeta.Header)
+	header := pm.newBackSourceHeader(log, peerTaskRequest)
+	h, _ := header.Load()
+	backSourceRequest, err := source.NewRequestWithContext(ctx, peerTaskRequest.Url, h)
 	if err != nil {
 		return err
 	}
@@ -371,14 +386,14 @@ func (pm *pieceManager) DownloadSource(ctx context.Context, pt Task, peerTaskReq
 					return err
 				}
 				// use concurrent piece download mode
-				return pm.concurrentDownloadSource(ctx, pt, peerTaskRequest, parsedRange, 0)
+				return pm.concurrentDownloadSource(ctx, pt, peerTaskRequest, header, parsedRange, 0)
 			}
 		}
 	}
 
 singleDownload:
 	// 1. download pieces from source
-	response, err := source.Download(backSourceRequest)
+	response, err := pm.downloadFromSource(ctx, log, peerTaskRequest.Url, header, "")
 	// TODO update expire info
 	if err != nil {
 		return err
@@ -416,7 +431,7 @@ singleDownload:
 	}
 	contentLength := response.ContentLength
 	// we must calculate piece size
-	pieceSize := pm.computePieceSize(contentLength)
+	pieceSize := pm.computeSourcePieceSize(peerTaskRequest.UrlMeta, contentLength)
 	if contentLength < 0 {
 		log.Warnf("can not get content length for %s", peerTaskRequest.Url)
 	} else {
@@ -475,12 +490,25 @@ singleDownload:
 		log.Infof("update range length: %d", parsedRange.Length)
 	}
 
-	return pm.downloadKnownLengthSource(ctx, pt, contentLength, pieceSize, reader, response, peerTaskRequest, parsedRange, supportConcurrent)
+	return pm.downloadKnownLengthSource(ctx, pt, contentLength, pieceSize, reader, response, peerTaskRequest, header, parsedRange, supportConcurrent)
 }
 
-func (pm *pieceManager) downloadKnownLengthSource(ctx context.Context, pt Task, contentLength int64, pieceSize uint32, reader io.Reader, response *source.Response, peerTaskRequest *schedulerv1.PeerTaskRequest, parsedRange *nethttp.Range, supportConcurrent bool) error {
+func (pm *pieceManager) downloadKnownLengthSource(ctx context.Context, pt Task, contentLength int64, pieceSize uint32, reader io.Reader, response *source.Response, peerTaskRequest *schedulerv1.PeerTaskRequest, header *backSourceHeader, parsedRange *nethttp.Range, supportConcurrent bool) error {
 	log := pt.Log()
 	maxPieceNum := pt.GetTotalPieces()
+
+	// resume resumes the download from the failed piece with range requests when the header is refreshable,
+	// the connection is usually closed by the source when the credentials expire during downloading.
+	resume := func(pieceNum int32, err error) error {
+		if !supportConcurrent || !header.Refreshable() || errors.Is(err, context.Canceled) {
+			return err
+		}
+
+		log.Infof("resume download from piece %d after error: %s", pieceNum, err)
+		response.Body.Close()
+		return pm.concurrentDownloadSource(ctx, pt, peerTaskRequest, header, parsedRange, pieceNum)
+	}
+
 	for pieceNum := int32(0); pieceNum < maxPieceNum; pieceNum++ {
 		size := pieceSize
 		offset := uint64(pieceNum) * uint64(pieceSize)
@@ -510,13 +538,13 @@ func (pm *pieceManager) downloadKnownLengthSource(ctx context.Context, pt Task,
 		if err != nil {
 			log.Errorf("download piece %d error: %s", pieceNum, err)
 			pt.ReportPieceResult(request, result, detectBackSourceError(err))
-			return err
+			return resume(pieceNum, err)
 		}
 
 		if result.Size != int64(size) {
 			log.Errorf("download piece %d size not match, desired: %d, actual: %d", pieceNum, size, result.Size)
 			pt.ReportPieceResult(request, result, detectBackSourceError(err))
-			return storage.ErrShortRead
+			return resume(pieceNum, storage.ErrShortRead)
 		}
 
 		metrics.BackSourceTotal.Add(float64(result.Size))
@@ -527,7 +555,7 @@ func (pm *pieceManager) downloadKnownLengthSource(ctx context.Context, pt Task,
 			speed := float64(pieceSize) / float64((result.FinishTime-result.BeginTime)/1000000)
 			if speed < float64(pm.concurrentOption.ThresholdSpeed) {
 				response.Body.Close()
-				return pm.concurrentDownloadSource(ctx, pt, peerTaskRequest, parsedRange, pieceNum+1)
+				return pm.concurrentDownloadSource(ctx, pt, peerTaskRequest, header, parsedRange, pieceNum+1)
 			}
 		}
 	}
@@ -793,9 +821,9 @@ func (pm *pieceManager) Import(ctx context.Context, ptm storage.PeerTaskMetadata
 	return nil
 }
 
-func (pm *pieceManager) concurrentDownloadSource(ctx context.Context, pt Task, peerTaskRequest *schedulerv1.PeerTaskRequest, parsedRange *nethttp.Range, continuePieceNum int32) error {
+func (pm *pieceManager) concurrentDownloadSource(ctx context.Context, pt Task, peerTaskRequest *schedulerv1.PeerTaskRequest, header *backSourceHeader, parsedRange *nethttp.Range, continuePieceNum int32) error {
 	// parsedRange is always exist
-	pieceSize := pm.computePieceSize(parsedRange.Length)
+	pieceSize := pm.computeSourcePieceSize(peerTaskRequest.UrlMeta, parsedRange.Length)
 	pieceCount := util.ComputePieceCount(parsedRange.Length, pieceSize)
 
 	pt.SetContentLength(parsedRange.Length)
@@ -812,11 +840,11 @@ func (pm *pieceManager) concurrentDownloadSource(ctx context.Context, pt Task, p
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	return pm.concurrentDownloadSourceByPieceGroup(ctx, pt, peerTaskRequest, parsedRange, continuePieceNum, pieceCount, pieceCountToDownload, con, pieceSize, cancel)
+	return pm.concurrentDownloadSourceByPieceGroup(ctx, pt, peerTaskRequest, header, parsedRange, continuePieceNum, pieceCount, pieceCountToDownload, con, pieceSize, cancel)
 }
 
 func (pm *pieceManager) concurrentDownloadSourceByPieceGroup(
-	ctx context.Context, pt Task, peerTaskRequest *schedulerv1.PeerTaskRequest,
+	ctx context.Context, pt Task, peerTaskRequest *schedulerv1.PeerTaskRequest, header *backSourceHeader,
 	parsedRange *nethttp.Range, startPieceNum int32, pieceCount int32, pieceCountToDownload int32,
 	con int, pieceSize uint32, cancel context.CancelFunc) error {
 	log := pt.Log()
@@ -849,7 +877,7 @@ func (pm *pieceManager) concurrentDownloadSourceByPieceGroup(
 				pm.concurrentOption.MaxAttempts,
 				func() (data any, cancel bool, err error) {
 					err = pm.downloadPieceGroupFromSource(ctx, pt, log,
-						peerTaskRequest, pg, pieceCount, pieceCountToDownload, downloadedPieces)
+						peerTaskRequest, header, pg, pieceCount, pieceCountToDownload, downloadedPieces)
 					return nil, errors.Is(err, context.Canceled), err
 				})
 			if retryErr != nil {
@@ -922,7 +950,7 @@ func newPieceGroup(i int32, reminderPieces int32, startPieceNum int32, minPieceC
 }
 
 func (pm *pieceManager) concurrentDownloadSourceByPiece(
-	ctx context.Context, pt Task, peerTaskRequest *schedulerv1.PeerTaskRequest,
+	ctx context.Context, pt Task, peerTaskRequest *schedulerv1.PeerTaskRequest, header *backSourceHeader,
 	parsedRange *nethttp.Range, startPieceNum int32, pieceCount int32, pieceCountToDownload int32,
 	con int, pieceSize uint32, cancel context.CancelFunc) error {
 
@@ -954,7 +982,7 @@ func (pm *pieceManager) concurrentDownloadSourceByPiece(
 						pm.concurrentOption.MaxAttempts,
 						func() (data any, cancel bool, err error) {
 							err = pm.downloadPieceFromSource(ctx, pt, log,
-								peerTaskRequest, pieceSize, pieceNum,
+								peerTaskRequest, header, pieceSize, pieceNum,
 								parsedRange, pieceCount, downloadedPieceCount)
 							return nil, err == context.Canceled, err
 						})
@@ -1001,15 +1029,11 @@ func (pm *pieceManager) concurrentDownloadSourceByPiece(
 func (pm *pieceManager) downloadPieceFromSource(ctx context.Context,
 	pt Task, log *logger.SugaredLoggerOnWith,
 	peerTaskRequest *schedulerv1.PeerTaskRequest,
+	header *backSourceHeader,
 	pieceSize uint32, pieceNum int32,
 	parsedRange *nethttp.Range,
 	totalPieceCount int32,
 	downloadedPieceCount *atomic.Int32) error {
-	backSourceRequest, err := source.NewRequestWithContext(ctx, peerTaskRequest.Url, peerTaskRequest.UrlMeta.Header)
-	if err != nil {
-		log.Errorf("build piece %d back source request error: %s", pieceNum, err)
-		return err
-	}
 	size := pieceSize
 	offset := uint64(pieceNum) * uint64(pieceSize)
 	// calculate piece size for last piece
@@ -1020,12 +1044,7 @@ func (pm *pieceManager) downloadPieceFromSource(ctx context.Context,
 	// offset is the position for current peer task, if this peer task already has range
 	// we need add the start to the offset when download from source
 	rg := fmt.Sprintf("%d-%d", offset+uint64(parsedRange.Start), offset+uint64(parsedRange.Start)+uint64(size)-1)
-	// FIXME refactor source package, normal Range header is enough
-	backSourceRequest.Header.Set(source.Range, rg)
-	backSourceRequest.Header.Set(headers.Range, "bytes="+rg)
-	log.Debugf("piece %d back source header: %#v", pieceNum, backSourceRequest.Header)
-
-	response, err := source.Download(backSourceRequest)
+	response, err := pm.downloadFromSource(ctx, log, peerTaskRequest.Url, header, rg)
 	if err != nil {
 		log.Errorf("piece %d back source response error: %s", pieceNum, err)
 		return err
@@ -1077,25 +1096,20 @@ func (pm *pieceManager) downloadPieceFromSource(ctx context.Context,
 func (pm *pieceManager) downloadPieceGroupFromSource(ctx context.Context,
 	pt Task, log *logger.SugaredLoggerOnWith,
 	peerTaskRequest *schedulerv1.PeerTaskRequest,
+	header *backSourceHeader,
 	pg *pieceGroup,
 	totalPieceCount int32,
 	totalPieceCountToDownload int32,
 	downloadedPieces mapset.Set[int32]) error {
-
-	backSourceRequest, err := source.NewRequestWithContext(ctx, peerTaskRequest.Url, peerTaskRequest.UrlMeta.Header)
-	if err != nil {
-		log.Errorf("build piece %d-%d back source request error: %s", pg.start, pg.end, err)
-		return err
+	// the pieces of group downloaded by the previous attempts are kept,
+	// so the retry resumes from the first unfinished piece of group
+	if pg.start > pg.end {
+		return nil
 	}
 
-	pieceGroupRange := fmt.Sprintf("%d-%d", pg.startByte, pg.endByte)
-	// FIXME refactor source package, normal Range header is enough
-	backSourceRequest.Header.Set(source.Range, pieceGroupRange)
-	backSourceRequest.Header.Set(headers.Range, "bytes="+pieceGroupRange)
-
-	log.Debugf("piece %d-%d back source header: %#v", pg.start, pg.end, backSourceRequest.Header)
-
-	response, err := source.Download(backSourceRequest)
+	start, startByte := pg.start, pg.startByte
+	pieceGroupRange := fmt.Sprintf("%d-%d", startByte, pg.endByte)
+	response, err := pm.downloadFromSource(ctx, log, peerTaskRequest.Url, header, pieceGroupRange)
 	if err != nil {
 		log.Errorf("piece %d-%d back source response error: %s", pg.start, pg.end, err)
 		return err
@@ -1110,9 +1124,9 @@ func (pm *pieceManager) downloadPieceGroupFromSource(ctx context.Context,
 
 	log.Debugf("piece %d-%d back source response ok", pg.start, pg.end)
 
-	for i := pg.start; i <= pg.end; i++ {
+	for i := start; i <= pg.end; i++ {
 		pieceNum := i
-		offset := uint64(pg.startByte) + uint64(i-pg.start)*uint64(pg.pieceSize)
+		offset := uint64(startByte) + uint64(i-start)*uint64(pg.pieceSize)
 		size := pg.pieceSize
 		// update last piece size
 		if offset+uint64(size)-1 > uint64(pg.endByte) {
@@ -1153,8 +1167,209 @@ func (pm *pieceManager) downloadPieceGroupFromSource(ctx context.Context,
 		metrics.BackSourceTotal.Add(float64(result.Size))
 		pt.ReportPieceResult(request, result, nil)
 		pt.PublishPieceInfo(pieceNum, uint32(result.Size))
+		pg.start, pg.startByte = pieceNum+1, int64(offset)+int64(size)
 
 		log.Debugf("piece %d done", pieceNum)
 	}
 	return nil
 }
+
+// backSourceHeader is the header of back source requests of a task, it is shared by the
+// concurrent workers and refreshed by the header provider when the source rejects it.
+type backSourceHeader struct {
+	url      string
+	provider source.HeaderProvider
+
+	mu         sync.RWMutex
+	header     map[string]string
+	generation int
+}
+
+// newBackSourceHeader returns the back source header of the task, the header provider
+// is selected by the application of the task.
+func (pm *pieceManager) newBackSourceHeader(log *logger.SugaredLoggerOnWith, peerTaskRequest *schedulerv1.PeerTaskRequest) *backSourceHeader {
+	header := &backSourceHeader{
+		url:    peerTaskRequest.Url,
+		header: sourceHeader(peerTaskRequest.UrlMeta.Header),
+	}
+
+	name, ok := pm.headerProviders[peerTaskRequest.UrlMeta.Application]
+	if !ok {
+		return header
+	}
+
+	provider, ok := source.LoadHeaderProvider(name)
+	if !ok {
+		log.Warnf("header provider %s of application %s is not found", name, peerTaskRequest.UrlMeta.Application)
+		return header
+	}
+
+	header.provider = provider
+	return header
+}
+
+// Refreshable returns whether the header can be refreshed by the header provider.
+func (h *backSourceHeader) Refreshable() bool {
+	return h.provider != nil
+}
+
+// Load returns the header and the generation of the header, the header must not be modified.
+func (h *backSourceHeader) Load() (map[string]string, int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return h.header, h.generation
+}
+
+// Refresh refreshes the header rejected by the source, the header is refreshed only once
+// when the concurrent workers are rejected with the same generation of the header.
+func (h *backSourceHeader) Refresh(ctx context.Context, generation int) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.generation != generation {
+		return nil
+	}
+
+	header := make(map[string]string, len(h.header))
+	for k, v := range h.header {
+		header[k] = v
+	}
+
+	refreshed, err := h.provider.RefreshHeader(ctx, h.url, header)
+	if err != nil {
+		return fmt.Errorf("refresh back source header error: %w", err)
+	}
+
+	for k, v := range refreshed {
+		header[k] = v
+	}
+
+	h.header = header
+	h.generation++
+	return nil
+}
+
+// downloadFromSource downloads the range of the source, the whole content is downloaded if rg is empty.
+// When the source rejects the header with 401 or 403, the header is refreshed by the header provider
+// and the range is requested again, so the download resumes without the pieces downloaded before.
+// The response is not validated.
+func (pm *pieceManager) downloadFromSource(ctx context.Context, log *logger.SugaredLoggerOnWith, url string, header *backSourceHeader, rg string) (*source.Response, error) {
+	h, generation := header.Load()
+	response, err := downloadRangeFromSource(ctx, log, url, h, rg)
+	if err != nil || !header.Refreshable() {
+		return response, err
+	}
+
+	if response.StatusCode != http.StatusUnauthorized && response.StatusCode != http.StatusForbidden {
+		return response, nil
+	}
+
+	log.Infof("back source range %q is rejected with status code %d, refresh header", rg, response.StatusCode)
+	response.Body.Close()
+	if err := header.Refresh(ctx, generation); err != nil {
+		return nil, err
+	}
+
+	h, _ = header.Load()
+	return downloadRangeFromSource(ctx, log, url, h, rg)
+}
+
+// downloadRangeFromSource downloads the range of the source with the header.
+func downloadRangeFromSource(ctx context.Context, log *logger.SugaredLoggerOnWith, url string, header map[string]string, rg string) (*source.Response, error) {
+	backSourceRequest, err := source.NewRequestWithContext(ctx, url, header)
+	if err != nil {
+		return nil, err
+	}
+
+	if rg != "" {
+		// FIXME refactor source package, normal Range header is enough
+		backSourceRequest.Header.Set(source.Range, rg)
+		backSourceRequest.Header.Set(headers.Range, "bytes="+rg)
+	}
+
+	log.Debugf("back source range %q header: %#v", rg, backSourceRequest.Header.Redact())
+	return source.Download(backSourceRequest)
+}
+
+// computeSourcePieceSize returns the piece size hint in url meta if exists,
+// otherwise computes the piece size by the content length.
+func (pm *pieceManager) computeSourcePieceSize(urlMeta *commonv1.UrlMeta, contentLength int64) uint32 {
+	if pieceSize := pieceSizeHint(urlMeta); pieceSize > 0 {
+		return pieceSize
+	}
+
+	return pm.computePieceSize(contentLength)
+}
+
+// withPieceSizeHint sets the piece size hint in url meta header, the hint is passed
+// to the seed peers with url meta and does not change the task id.
+func withPieceSizeHint(urlMeta *commonv1.UrlMeta, pieceSize uint32) *commonv1.UrlMeta {
+	if urlMeta == nil {
+		urlMeta = &commonv1.UrlMeta{}
+	}
+
+	if urlMeta.Header == nil {
+		urlMeta.Header = map[string]string{}
+	}
+
+	urlMeta.Header[config.HeaderDragonflyPieceSize] = strconv.FormatUint(uint64(pieceSize), 10)
+	return urlMeta
+}
+
+// pieceSizeHint returns the piece size hint in url meta, it returns zero when there is no valid hint.
+func pieceSizeHint(urlMeta *commonv1.UrlMeta) uint32 {
+	if urlMeta == nil {
+		return 0
+	}
+
+	value, ok := urlMeta.Header[config.HeaderDragonflyPieceSize]
+	if !ok {
+		return 0
+	}
+
+	pieceSize, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		logger.Warnf("invalid piece size hint %q: %s", value, err)
+		return 0
+	}
+
+	if pieceSize < uint64(config.MinObjectStoragePieceSize) || pieceSize > uint64(config.MaxObjectStoragePieceSize) {
+		logger.Warnf("piece size hint %d is out of range", pieceSize)
+		return 0
+	}
+
+	return uint32(pieceSize)
+}
+
+// dragonflyOnlyHeaders are the headers of the url meta only used by dragonfly, e.g. the piece size hint,
+// the seed peer preference and the identity headers of the task id, they are not sent to the source.
+var dragonflyOnlyHeaders = map[string]struct{}{
+	config.HeaderDragonflyPieceSize: {},
+	nethttp.HeaderPreferSeed:        {},
+	nethttp.HeaderIdentityHeaders:   {},
+}
+
+// sourceHeader returns the header of back source request without the dragonfly only headers.
+func sourceHeader(header map[string]string) map[string]string {
+	var found bool
+	for k := range dragonflyOnlyHeaders {
+		if _, ok := header[k]; ok {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return header
+	}
+
+	h := make(map[string]string, len(header))
+	for k, v := range header {
+		if _, ok := dragonflyOnlyHeaders[k]; !ok {
+			h[k] = v
+		}
+	}
+
+	return h
+}
@@ -27,6 +27,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
@@ -43,7 +44,6 @@ import (
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/test"
-	clientutil "d7y.io/dragonfly/v2/client/util"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/internal/util"
 	"d7y.io/dragonfly/v2/pkg/digest"
@@ -51,6 +51,7 @@ import (
 	_ "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/server"
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/source/clients/httpprotocol"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 func TestPieceManager_DownloadSource(t *testing.T) {
@@ -73,7 +74,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 		config.SimpleLocalTaskStoreStrategy,
 		&config.StorageOption{
 			DataPath: t.TempDir(),
-			TaskExpireTime: clientutil.Duration{
+			TaskExpireTime: types.Duration{
 				Duration: -1 * time.Second,
 			},
 		}, func(request storage.CommonTaskRequest) {}, os.FileMode(0700))
@@ -88,7 +89,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 		withContentLength  bool
 		checkDigest        bool
 		recordDownloadTime bool
-		bandwidth          clientutil.Size
+		bandwidth          types.RateLimit
 		concurrentOption   *config.ConcurrentOption
 	}{
 		{
@@ -112,7 +113,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			recordDownloadTime: true,
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 2,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024,
 				},
 			},
@@ -125,7 +126,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			recordDownloadTime: true,
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 4,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024,
 				},
 			},
@@ -138,7 +139,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			recordDownloadTime: true,
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 8,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024,
 				},
 			},
@@ -151,7 +152,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			recordDownloadTime: true,
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 16,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024,
 				},
 			},
@@ -162,7 +163,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 2048},
+			bandwidth:          types.RateLimit{Limit: 2048},
 		},
 		{
 			name:               "multiple pieces with content length, concurrent download with 2 goroutines, download bandwidth 2KB/s",
@@ -170,10 +171,10 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 2048},
+			bandwidth:          types.RateLimit{Limit: 2048},
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 2,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024 * 1024,
 				},
 				ThresholdSpeed: 8192,
@@ -185,10 +186,10 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 2048},
+			bandwidth:          types.RateLimit{Limit: 2048},
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 4,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024 * 1024,
 				},
 				ThresholdSpeed: 8192,
@@ -200,10 +201,10 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 2048},
+			bandwidth:          types.RateLimit{Limit: 2048},
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 8,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024 * 1024,
 				},
 				ThresholdSpeed: 8192,
@@ -215,10 +216,10 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 2048},
+			bandwidth:          types.RateLimit{Limit: 2048},
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 16,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024 * 1024,
 				},
 				ThresholdSpeed: 8192,
@@ -230,7 +231,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 4096},
+			bandwidth:          types.RateLimit{Limit: 4096},
 		},
 		{
 			name:               "multiple pieces with content length, concurrent download with download bandwidth 4KB/s",
@@ -238,10 +239,10 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 4096},
+			bandwidth:          types.RateLimit{Limit: 4096},
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 4,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024 * 1024,
 				},
 				ThresholdSpeed: 8192,
@@ -253,7 +254,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 8192},
+			bandwidth:          types.RateLimit{Limit: 8192},
 		},
 		{
 			name:               "multiple pieces with content length, concurrent download with download bandwidth 8KB/s",
@@ -261,10 +262,10 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 8192},
+			bandwidth:          types.RateLimit{Limit: 8192},
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 4,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024 * 1024,
 				},
 				ThresholdSpeed: 8192,
@@ -276,7 +277,7 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 16384},
+			bandwidth:          types.RateLimit{Limit: 16384},
 		},
 		{
 			name:               "multiple pieces with content length, concurrent download with download bandwidth 16KB/s",
@@ -284,10 +285,10 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 			checkDigest:        false,
 			withContentLength:  true,
 			recordDownloadTime: true,
-			bandwidth:          clientutil.Size{Limit: 16384},
+			bandwidth:          types.RateLimit{Limit: 16384},
 			concurrentOption: &config.ConcurrentOption{
 				GoroutineCount: 4,
-				ThresholdSize: clientutil.Size{
+				ThresholdSize: types.RateLimit{
 					Limit: 1024 * 1024,
 				},
 				ThresholdSpeed: 8192,
@@ -489,6 +490,179 @@ func TestPieceManager_DownloadSource(t *testing.T) {
 	}
 }
 
+func TestPieceManager_DownloadSourceRefreshHeader(t *testing.T) {
+	assert := testifyassert.New(t)
+	ctrl := gomock.NewController(t)
+	source.UnRegister("http")
+	require.Nil(t, source.Register("http", httpprotocol.NewHTTPSourceClient(), httpprotocol.Adapter))
+	defer source.UnRegister("http")
+	testBytes, err := os.ReadFile(test.File)
+	assert.Nil(err, "load test file")
+
+	var (
+		peerID    = "peer0"
+		taskID    = "task0"
+		output    = "../test/testdata/test.output"
+		pieceSize = uint32(1024)
+	)
+
+	storageManager, _ := storage.NewStorageManager(
+		config.SimpleLocalTaskStoreStrategy,
+		&config.StorageOption{
+			DataPath: t.TempDir(),
+			TaskExpireTime: types.Duration{
+				Duration: -1 * time.Second,
+			},
+		}, func(request storage.CommonTaskRequest) {}, os.FileMode(0700))
+
+	var (
+		mu            sync.Mutex
+		totalPieces   = &atomic.Int32{}
+		taskStorage   storage.TaskStorageDriver
+		succeedPieces = map[int32]int{}
+	)
+	mockPeerTask := NewMockTask(ctrl)
+	mockPeerTask.EXPECT().SetContentLength(gomock.Any()).AnyTimes()
+	mockPeerTask.EXPECT().SetTotalPieces(gomock.Any()).AnyTimes().DoAndReturn(
+		func(arg0 int32) {
+			totalPieces.Store(arg0)
+		})
+	mockPeerTask.EXPECT().GetTotalPieces().AnyTimes().DoAndReturn(
+		func() int32 {
+			return totalPieces.Load()
+		})
+	mockPeerTask.EXPECT().GetPeerID().AnyTimes().Return(peerID)
+	mockPeerTask.EXPECT().GetTaskID().AnyTimes().Return(taskID)
+	mockPeerTask.EXPECT().GetStorage().AnyTimes().DoAndReturn(
+		func() storage.TaskStorageDriver {
+			return taskStorage
+		})
+	mockPeerTask.EXPECT().AddTraffic(gomock.Any()).AnyTimes()
+	mockPeerTask.EXPECT().ReportPieceResult(gomock.Any(), gomock.Any(), gomock.Any()).AnyTimes()
+	mockPeerTask.EXPECT().PublishPieceInfo(gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(
+		func(pieceNum int32, size uint32) {
+			mu.Lock()
+			defer mu.Unlock()
+			succeedPieces[pieceNum]++
+		})
+	mockPeerTask.EXPECT().Context().AnyTimes().Return(context.Background())
+	mockPeerTask.EXPECT().Log().AnyTimes().Return(logger.With("test case", t.Name()))
+
+	taskStorage, err = storageManager.RegisterTask(context.Background(),
+		&storage.RegisterTaskRequest{
+			PeerTaskMetadata: storage.PeerTaskMetadata{
+				PeerID: peerID,
+				TaskID: taskID,
+			},
+			DesiredLocation: output,
+			ContentLength:   int64(len(testBytes)),
+		})
+	assert.Nil(err)
+	defer storageManager.CleanUp()
+	defer os.Remove(output)
+
+	// the source requires the session cookie, the old session expires after
+	// half of the content is sent and the source closes the connection.
+	var (
+		session        = "old"
+		refreshedRange []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		current := session
+		mu.Unlock()
+		if r.Header.Get(headers.Cookie) != "session="+current {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		parsedRange, err := nethttp.ParseRange(r.Header.Get(headers.Range), int64(len(testBytes)))
+		assert.Nil(err)
+		start, length := parsedRange[0].Start, parsedRange[0].Length
+		w.Header().Set(headers.ContentLength, fmt.Sprintf("%d", length))
+		w.Header().Set(headers.ContentRange, fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, len(testBytes)))
+		w.WriteHeader(http.StatusPartialContent)
+
+		if current == "old" && length > 1 {
+			w.Write(testBytes[start : start+length/2])
+			w.(http.Flusher).Flush()
+
+			mu.Lock()
+			session = "new"
+			mu.Unlock()
+			panic(http.ErrAbortHandler)
+		}
+
+		if current == "new" {
+			mu.Lock()
+			refreshedRange = append(refreshedRange, r.Header.Get(headers.Range))
+			mu.Unlock()
+		}
+		w.Write(testBytes[start : start+length])
+	}))
+	defer ts.Close()
+
+	refreshCount := &atomic.Int32{}
+	require.Nil(t, source.RegisterHeaderProvider("cookie", source.HeaderProviderFunc(
+		func(ctx context.Context, url string, header map[string]string) (map[string]string, error) {
+			refreshCount.Inc()
+			return map[string]string{headers.Cookie: "session=new"}, nil
+		})))
+	defer source.UnRegisterHeaderProvider("cookie")
+
+	pm, err := NewPieceManager(30*time.Second,
+		WithConcurrentOption(&config.ConcurrentOption{
+			GoroutineCount: 1,
+			ThresholdSize: types.RateLimit{
+				Limit: 1,
+			},
+			InitBackoff: 0.01,
+			MaxBackoff:  0.01,
+			MaxAttempts: 3,
+		}),
+		WithHeaderProviders(map[string]string{"foo": "cookie"}))
+	assert.Nil(err)
+	pm.(*pieceManager).computePieceSize = func(length int64) uint32 {
+		return pieceSize
+	}
+
+	err = pm.DownloadSource(context.Background(), mockPeerTask, &schedulerv1.PeerTaskRequest{
+		Url: ts.URL,
+		UrlMeta: &commonv1.UrlMeta{
+			Application: "foo",
+			Header: map[string]string{
+				headers.Cookie: "session=old",
+			},
+		},
+	}, nil)
+	assert.Nil(err)
+
+	// the header is refreshed once and the download resumes from the first unfinished piece
+	finishedBytes := len(testBytes) / 2 / int(pieceSize) * int(pieceSize)
+	assert.Equal(int32(1), refreshCount.Load())
+	assert.Equal([]string{fmt.Sprintf("bytes=%d-%d", finishedBytes, len(testBytes)-1)}, refreshedRange)
+
+	pieceCount := util.ComputePieceCount(int64(len(testBytes)), pieceSize)
+	assert.Len(succeedPieces, int(pieceCount))
+	for pieceNum := int32(0); pieceNum < pieceCount; pieceNum++ {
+		assert.Equal(1, succeedPieces[pieceNum], "piece %d must be downloaded once", pieceNum)
+	}
+
+	err = storageManager.Store(context.Background(),
+		&storage.StoreRequest{
+			CommonTaskRequest: storage.CommonTaskRequest{
+				PeerID:      peerID,
+				TaskID:      taskID,
+				Destination: output,
+			},
+		})
+	assert.Nil(err)
+
+	outputBytes, err := os.ReadFile(output)
+	assert.Nil(err, "load output file")
+	assert.Equal(string(testBytes), string(outputBytes), "output and desired output must match")
+}
+
 func TestDetectBackSourceError(t *testing.T) {
 	assert := testifyassert.New(t)
 	testCases := []struct {
@@ -814,3 +988,72 @@ func TestPieceGroup(t *testing.T) {
 		})
 	}
 }
+
+func TestPieceManager_computeSourcePieceSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		urlMeta       *commonv1.UrlMeta
+		contentLength int64
+		pieceSize     uint32
+		header        map[string]string
+	}{
+		{
+			name:          "without url meta",
+			contentLength: 1024,
+			pieceSize:     util.DefaultPieceSize,
+		},
+		{
+			name:          "without piece size hint",
+			urlMeta:       &commonv1.UrlMeta{Header: map[string]string{"foo": "bar"}},
+			contentLength: 1024,
+			pieceSize:     util.DefaultPieceSize,
+			header:        map[string]string{"foo": "bar"},
+		},
+		{
+			name:          "with piece size hint",
+			urlMeta:       withPieceSizeHint(&commonv1.UrlMeta{Header: map[string]string{"foo": "bar"}}, 32*1024*1024),
+			contentLength: 1024,
+			pieceSize:     32 * 1024 * 1024,
+			header:        map[string]string{"foo": "bar"},
+		},
+		{
+			name:          "with invalid piece size hint",
+			urlMeta:       &commonv1.UrlMeta{Header: map[string]string{config.HeaderDragonflyPieceSize: "foo"}},
+			contentLength: 1024,
+			pieceSize:     util.DefaultPieceSize,
+			header:        map[string]string{},
+		},
+		{
+			name:          "with seed peer preference",
+			urlMeta:       &commonv1.UrlMeta{Header: map[string]string{"foo": "bar", nethttp.HeaderPreferSeed: "true"}},
+			contentLength: 1024,
+			pieceSize:     util.DefaultPieceSize,
+			header:        map[string]string{"foo": "bar"},
+		},
+		{
+			name:          "with identity headers",
+			urlMeta:       &commonv1.UrlMeta{Header: map[string]string{"Accept": "foo", nethttp.HeaderIdentityHeaders: "Accept"}},
+			contentLength: 1024,
+			pieceSize:     util.DefaultPieceSize,
+			header:        map[string]string{"Accept": "foo"},
+		},
+		{
+			name:          "with piece size hint out of range",
+			urlMeta:       withPieceSizeHint(nil, 1024),
+			contentLength: 1024,
+			pieceSize:     util.DefaultPieceSize,
+			header:        map[string]string{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			pm := &pieceManager{computePieceSize: util.ComputePieceSize}
+			assert.Equal(tc.pieceSize, pm.computeSourcePieceSize(tc.urlMeta, tc.contentLength))
+			if tc.urlMeta != nil {
+				assert.Equal(tc.header, sourceHeader(tc.urlMeta.Header))
+			}
+		})
+	}
+}
@@ -48,7 +48,6 @@ import (
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/test"
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
@@ -62,6 +61,7 @@ import (
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/source/clients/httpprotocol"
 	sourcemocks "d7y.io/dragonfly/v2/pkg/source/mocks"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 type taskOption struct {
@@ -227,7 +227,7 @@ func trafficShaperSetupPeerTaskManagerComponents(ctrl *gomock.Controller, opt tr
 		config.SimpleLocalTaskStoreStrategy,
 		&config.StorageOption{
 			DataPath: tempDir,
-			TaskExpireTime: util.Duration{
+			TaskExpireTime: types.Duration{
 				Duration: -1 * time.Second,
 			},
 		}, func(request storage.CommonTaskRequest) {}, os.FileMode(0700))
@@ -250,7 +250,7 @@ func (m *trafficShaperMockManager) CleanUp() {
 
 func trafficShaperSetupMockManager(ctrl *gomock.Controller, ts *trafficShaperTestSpec, opt trafficShaperComponentsOption) *trafficShaperMockManager {
 	schedulerClient, storageManager := trafficShaperSetupPeerTaskManagerComponents(ctrl, opt)
-	scheduleTimeout := util.Duration{Duration: 10 * time.Minute}
+	scheduleTimeout := types.Duration{Duration: 10 * time.Minute}
 	ptm := &peerTaskManager{
 		conductorLock:    &sync.Mutex{},
 		runningPeerTasks: sync.Map{},
@@ -21,6 +21,7 @@ import (
 	"crypto/tls"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
 	"mime"
 	"net"
@@ -279,7 +280,7 @@ func NewProxy(options ...Option) (*Proxy, error) {
 
 func (proxy *Proxy) updateMirrorHandler() {
 	h := proxy.directHandler
-	if proxy.registry == nil || proxy.registry.Remote == nil || proxy.registry.Remote.URL == nil {
+	if proxy.registry == nil || (!proxy.hasRegistryRemote() && len(proxy.registry.Rules) == 0) {
 		logger.Warnf("registry mirror url is empty, registry mirror feature is disabled")
 		h.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "registry mirror feature is disabled", http.StatusNotFound)
@@ -291,6 +292,11 @@ func (proxy *Proxy) updateMirrorHandler() {
 	h.HandleFunc("/", proxy.mirrorRegistry)
 }
 
+// hasRegistryRemote returns whether the default remote of the registry mirror is configured.
+func (proxy *Proxy) hasRegistryRemote() bool {
+	return proxy.registry.Remote != nil && proxy.registry.Remote.URL != nil
+}
+
 func isBasicAuthMatch(basicAuth *config.BasicAuth, user, pass string) bool {
 	usernameOK := subtle.ConstantTimeCompare([]byte(basicAuth.Username), []byte(user)) == 1
 	passwordOK := subtle.ConstantTimeCompare([]byte(basicAuth.Password), []byte(pass)) == 1
@@ -575,6 +581,7 @@ func (proxy *Proxy) newTransport(tlsConfig *tls.Config) http.RoundTripper {
 		transport.WithDefaultApplication(proxy.defaultApplication),
 		transport.WithDefaultPriority(proxy.defaultPriority),
 		transport.WithDumpHTTPContent(proxy.dumpHTTPContent),
+		transport.WithIdentityHeaders(proxy.identityHeaders),
 	}
 	if proxy.peerSearcher != nil {
 		opts = append(opts, transport.WithPeerSearcher(proxy.peerSearcher))
@@ -583,17 +590,41 @@ func (proxy *Proxy) newTransport(tlsConfig *tls.Config) http.RoundTripper {
 }
 
 func (proxy *Proxy) mirrorRegistry(w http.ResponseWriter, r *http.Request) {
-	reverseProxy := newReverseProxy(proxy.registry)
+	var (
+		reverseProxy *httputil.ReverseProxy
+		tlsConfig    *tls.Config
+		condition    func(req *http.Request) bool
+	)
+
+	// use the mirror of the matched rule, otherwise fall back to the default remote
+	host := registryHost(r)
+	if rule := proxy.registry.MatchRule(host); rule != nil {
+		logger.Debugf("registry %s matches mirror rule %s, mirror: %s", host, rule.Host, rule.Remote)
+		reverseProxy = newRuleReverseProxy(rule)
+		tlsConfig = rule.TLSConfig()
+		condition = func(req *http.Request) bool {
+			return proxy.shouldUseDragonflyForMirrorRule(rule, req)
+		}
+	} else if proxy.hasRegistryRemote() {
+		reverseProxy = newReverseProxy(proxy.registry)
+		tlsConfig = proxy.registry.TLSConfig()
+		condition = proxy.shouldUseDragonflyForMirror
+	} else {
+		http.Error(w, fmt.Sprintf("registry mirror of %q is not found", host), http.StatusNotFound)
+		return
+	}
+
 	opts := []transport.Option{
 		transport.WithPeerIDGenerator(proxy.peerIDGenerator),
 		transport.WithPeerTaskManager(proxy.peerTaskManager),
-		transport.WithTLS(proxy.registry.TLSConfig()),
-		transport.WithCondition(proxy.shouldUseDragonflyForMirror),
+		transport.WithTLS(tlsConfig),
+		transport.WithCondition(condition),
 		transport.WithDefaultFilter(proxy.defaultFilter),
 		transport.WithDefaultTag(proxy.defaultTag),
 		transport.WithDefaultApplication(proxy.defaultApplication),
 		transport.WithDefaultPriority(proxy.defaultPriority),
 		transport.WithDumpHTTPContent(proxy.dumpHTTPContent),
+		transport.WithIdentityHeaders(proxy.identityHeaders),
 	}
 	if proxy.peerSearcher != nil {
 		opts = append(opts, transport.WithPeerSearcher(proxy.peerSearcher))
@@ -688,6 +719,18 @@ func (proxy *Proxy) shouldUseDragonfly(req *http.Request) bool {
 	return false
 }
 
+// identityHeaders returns the identity headers of the first matched proxy rule,
+// the values of them are folded into the task id of the request.
+func (proxy *Proxy) identityHeaders(req *http.Request) []string {
+	for _, rule := range proxy.rules.Load().([]*config.ProxyRule) {
+		if rule.Match(req.URL.String()) {
+			return rule.IdentityHeaders
+		}
+	}
+
+	return nil
+}
+
 // shouldUseDragonflyForMirror returns whether we should use dragonfly to proxy a request
 // when we use registry mirror.
 func (proxy *Proxy) shouldUseDragonflyForMirror(req *http.Request) bool {
@@ -700,6 +743,30 @@ func (proxy *Proxy) shouldUseDragonflyForMirror(req *http.Request) bool {
 	return transport.NeedUseDragonfly(req)
 }
 
+// shouldUseDragonflyForMirrorRule returns whether we should use dragonfly to proxy a request
+// when we use the mirror of the registry mirror rule.
+func (proxy *Proxy) shouldUseDragonflyForMirrorRule(rule *config.RegistryMirrorRule, req *http.Request) bool {
+	if rule.Direct {
+		return false
+	}
+	if proxy.registry.UseProxies {
+		return proxy.shouldUseDragonfly(req)
+	}
+	return transport.NeedUseDragonfly(req)
+}
+
+// registryHost returns the host of the registry which the mirror request is for,
+// it is parsed from header "X-Dragonfly-Registry" or the query "ns" appended by containerd.
+func registryHost(r *http.Request) string {
+	if reg := r.Header.Get(config.HeaderDragonflyRegistry); reg != "" {
+		if u, err := url.Parse(reg); err == nil && u.Host != "" {
+			return u.Host
+		}
+	}
+
+	return r.URL.Query().Get("ns")
+}
+
 // tunnelHTTPS handles the CONNECT request and proxy the https request through http tunnel.
 func tunnelHTTPS(w http.ResponseWriter, r *http.Request) {
 	metrics.ProxyRequestNotViaDragonflyCount.Add(1)
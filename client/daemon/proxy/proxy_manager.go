@@ -84,6 +84,9 @@ func NewProxyManager(peerHost *schedulerv1.PeerHost, peerTaskManager peer.TaskMa
 
 	if registry != nil {
 		logger.Infof("registry mirror: %s", registry.Remote)
+		for i, rule := range registry.Rules {
+			logger.Infof("[%d] registry mirror of %s: %s", i+1, rule.Host, rule.Remote)
+		}
 		options = append(options, WithRegistryMirror(registry))
 	}
 
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strconv"
 	"strings"
@@ -298,6 +299,150 @@ func TestProxyEventStream(t *testing.T) {
 		TestEventStream(t)
 }
 
+func TestMirrorRegistryWithRules(t *testing.T) {
+	newMirror := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Mirror-Authorization", r.Header.Get("Authorization"))
+			w.Header().Set("X-Mirror-Path", r.URL.Path)
+			_, _ = w.Write([]byte(name))
+		}))
+	}
+
+	docker := newMirror("docker")
+	defer docker.Close()
+	wildcard := newMirror("wildcard")
+	defer wildcard.Close()
+	fallback := newMirror("fallback")
+	defer fallback.Close()
+
+	remote := func(rawURL string) *config.URL {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &config.URL{URL: u}
+	}
+
+	t.Setenv("TEST_MIRROR_TOKEN", "baz")
+	rules := []*config.RegistryMirrorRule{
+		{
+			Host:     "docker.io",
+			Remote:   remote(docker.URL),
+			Username: "foo",
+			Password: "bar",
+		},
+		{
+			Host:     "*.io",
+			Remote:   remote(wildcard.URL),
+			TokenEnv: "TEST_MIRROR_TOKEN",
+			Direct:   true,
+		},
+	}
+
+	tests := []struct {
+		name     string
+		registry *config.RegistryMirror
+		path     string
+		header   http.Header
+		expect   func(t *testing.T, resp *http.Response)
+	}{
+		{
+			name:     "match the first rule and inject basic auth",
+			registry: &config.RegistryMirror{Remote: remote(fallback.URL), Rules: rules},
+			path:     "/v2/library/alpine/manifests/latest",
+			header:   http.Header{config.HeaderDragonflyRegistry: []string{"https://docker.io"}},
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := assert.New(t)
+				body, _ := io.ReadAll(resp.Body)
+				assert.Equal("docker", string(body))
+				assert.Equal("Basic Zm9vOmJhcg==", resp.Header.Get("X-Mirror-Authorization"))
+				assert.Equal("/v2/library/alpine/manifests/latest", resp.Header.Get("X-Mirror-Path"))
+			},
+		},
+		{
+			name:     "match the wildcard rule with query ns and inject bearer token",
+			registry: &config.RegistryMirror{Remote: remote(fallback.URL), Rules: rules},
+			path:     "/v2/coreos/etcd/manifests/latest?ns=quay.io",
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := assert.New(t)
+				body, _ := io.ReadAll(resp.Body)
+				assert.Equal("wildcard", string(body))
+				assert.Equal("Bearer baz", resp.Header.Get("X-Mirror-Authorization"))
+			},
+		},
+		{
+			name:     "bypass dragonfly for the layer of the direct rule",
+			registry: &config.RegistryMirror{Remote: remote(fallback.URL), Rules: rules},
+			path:     "/v2/coreos/etcd/blobs/sha256:c71d239df91726fc519c6eb72d318ec65820627232b2f796219e87dcf35d0ab4",
+			header:   http.Header{config.HeaderDragonflyRegistry: []string{"https://quay.io"}},
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := assert.New(t)
+				body, _ := io.ReadAll(resp.Body)
+				assert.Equal("wildcard", string(body))
+				assert.Equal(http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name:     "fall back to the remote if no rule is matched",
+			registry: &config.RegistryMirror{Remote: remote(fallback.URL), Rules: rules},
+			path:     "/v2/pause/manifests/latest",
+			header:   http.Header{config.HeaderDragonflyRegistry: []string{"https://k8s.gcr.com"}},
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := assert.New(t)
+				body, _ := io.ReadAll(resp.Body)
+				assert.Equal("fallback", string(body))
+				assert.Equal("", resp.Header.Get("X-Mirror-Authorization"))
+			},
+		},
+		{
+			name:     "registry mirror is not found without remote",
+			registry: &config.RegistryMirror{Rules: rules},
+			path:     "/v2/pause/manifests/latest",
+			header:   http.Header{config.HeaderDragonflyRegistry: []string{"https://k8s.gcr.com"}},
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotFound, resp.StatusCode)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tp, err := NewProxy(WithRegistryMirror(tc.registry))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			for k, v := range tc.header {
+				req.Header[k] = v
+			}
+
+			w := httptest.NewRecorder()
+			tp.directHandler.ServeHTTP(w, req)
+			resp := w.Result()
+			defer resp.Body.Close()
+			tc.expect(t, resp)
+		})
+	}
+}
+
+func TestShouldUseDragonflyForMirrorRule(t *testing.T) {
+	assert := assert.New(t)
+	tp, err := NewProxy(WithRegistryMirror(&config.RegistryMirror{}))
+	if !assert.Nil(err) {
+		return
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "http://h/v2/library/alpine/blobs/sha256:c71d239df91726fc519c6eb72d318ec65820627232b2f796219e87dcf35d0ab4", nil)
+	if !assert.Nil(err) {
+		return
+	}
+
+	assert.True(tp.shouldUseDragonflyForMirrorRule(&config.RegistryMirrorRule{}, req))
+	assert.False(tp.shouldUseDragonflyForMirrorRule(&config.RegistryMirrorRule{Direct: true}, req))
+}
+
 type mockResponseWriter struct {
 	flushCount int
 }
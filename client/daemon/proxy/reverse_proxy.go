@@ -22,6 +22,8 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/go-http-utils/headers"
+
 	"d7y.io/dragonfly/v2/client/config"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 )
@@ -34,6 +36,20 @@ func newReverseProxy(mirror *config.RegistryMirror) *httputil.ReverseProxy {
 	return reverseProxy
 }
 
+// newRuleReverseProxy returns the reverse proxy to the mirror of the rule,
+// the credentials of the rule are injected into the requests to the mirror.
+func newRuleReverseProxy(rule *config.RegistryMirrorRule) *httputil.ReverseProxy {
+	reverseProxy := httputil.NewSingleHostReverseProxy(rule.Remote.URL)
+	director := reverseProxy.Director
+	reverseProxy.Director = func(req *http.Request) {
+		director(req)
+		if auth := rule.Authorization(); auth != "" {
+			req.Header.Set(headers.Authorization, auth)
+		}
+	}
+	return reverseProxy
+}
+
 func newDynamicDirector(remote *url.URL) func(*http.Request) {
 	director := func(req *http.Request) {
 		var target = remote
@@ -0,0 +1,104 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"d7y.io/dragonfly/v2/client/config"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+const (
+	// notifySocketEnv is the environment variable of systemd notify socket.
+	notifySocketEnv = "NOTIFY_SOCKET"
+
+	// sdNotifyReady tells systemd that daemon startup is finished.
+	sdNotifyReady = "READY=1"
+
+	// sdNotifyStopping tells systemd that daemon is beginning its shutdown.
+	sdNotifyStopping = "STOPPING=1"
+)
+
+// defaultReadinessInterval is the default interval of checking daemon readiness.
+var defaultReadinessInterval = 500 * time.Millisecond
+
+// waitSchedulerReady waits until at least one scheduler is healthy, the resolved
+// scheduler addresses of dynconfig have passed the health check.
+func waitSchedulerReady(ctx context.Context, dynconfig config.Dynconfig, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		addrs, err := dynconfig.GetResolveSchedulerAddrs()
+		if err == nil && len(addrs) > 0 {
+			return nil
+		}
+
+		logger.Debugf("wait for healthy scheduler: %v", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// touchReadyFile creates the ready file with pid of daemon.
+func touchReadyFile(path string) error {
+	return os.WriteFile(path, []byte(fmt.Sprintf("%d", os.Getpid())), 0644)
+}
+
+// removeReadyFile removes the ready file.
+func removeReadyFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// sdNotify sends state to systemd notify socket, it returns false
+// if the notify socket is not set.
+func sdNotify(state string) (bool, error) {
+	socket := os.Getenv(notifySocketEnv)
+	if socket == "" {
+		return false, nil
+	}
+
+	// Abstract unix socket starts with '@'.
+	addr := &net.UnixAddr{Name: socket, Net: "unixgram"}
+	if socket[0] == '@' {
+		addr.Name = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix(addr.Net, nil, addr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,174 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/resolver"
+
+	configmocks "d7y.io/dragonfly/v2/client/config/mocks"
+)
+
+func TestReadiness_sdNotify(t *testing.T) {
+	tests := []struct {
+		name   string
+		socket func(t *testing.T) string
+		listen bool
+		expect func(t *testing.T, conn *net.UnixConn, ok bool, err error)
+	}{
+		{
+			name: "notify socket is not set",
+			socket: func(t *testing.T) string {
+				return ""
+			},
+			expect: func(t *testing.T, conn *net.UnixConn, ok bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.False(ok)
+			},
+		},
+		{
+			name: "notify socket is set",
+			socket: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "notify.sock")
+			},
+			listen: true,
+			expect: func(t *testing.T, conn *net.UnixConn, ok bool, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(ok)
+
+				buf := make([]byte, 64)
+				n, err := conn.Read(buf)
+				assert.NoError(err)
+				assert.Equal(sdNotifyReady, string(buf[:n]))
+			},
+		},
+		{
+			name: "notify socket does not exist",
+			socket: func(t *testing.T) string {
+				return filepath.Join(t.TempDir(), "notfound.sock")
+			},
+			expect: func(t *testing.T, conn *net.UnixConn, ok bool, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+				assert.False(ok)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			socket := tc.socket(t)
+			t.Setenv(notifySocketEnv, socket)
+
+			var conn *net.UnixConn
+			if tc.listen {
+				var err error
+				conn, err = net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer conn.Close()
+			}
+
+			ok, err := sdNotify(sdNotifyReady)
+			tc.expect(t, conn, ok, err)
+		})
+	}
+}
+
+func TestReadiness_ReadyFile(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "daemon.ready")
+
+	assert.NoError(touchReadyFile(path))
+	_, err := os.Stat(path)
+	assert.NoError(err)
+
+	assert.NoError(removeReadyFile(path))
+	_, err = os.Stat(path)
+	assert.True(os.IsNotExist(err))
+
+	// Remove a missing ready file is not an error.
+	assert.NoError(removeReadyFile(path))
+}
+
+func TestReadiness_waitSchedulerReady(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(m *configmocks.MockDynconfigMockRecorder)
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name: "scheduler is healthy",
+			mock: func(m *configmocks.MockDynconfigMockRecorder) {
+				m.GetResolveSchedulerAddrs().Return([]resolver.Address{{Addr: "127.0.0.1:8002"}}, nil).Times(1)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "scheduler becomes healthy",
+			mock: func(m *configmocks.MockDynconfigMockRecorder) {
+				gomock.InOrder(
+					m.GetResolveSchedulerAddrs().Return(nil, errors.New("foo")).Times(1),
+					m.GetResolveSchedulerAddrs().Return([]resolver.Address{}, nil).Times(1),
+					m.GetResolveSchedulerAddrs().Return([]resolver.Address{{Addr: "127.0.0.1:8002"}}, nil).Times(1),
+				)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "scheduler is unhealthy",
+			mock: func(m *configmocks.MockDynconfigMockRecorder) {
+				m.GetResolveSchedulerAddrs().Return(nil, errors.New("foo")).AnyTimes()
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, context.DeadlineExceeded)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			dynconfig := configmocks.NewMockDynconfig(ctl)
+			tc.mock(dynconfig.EXPECT())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			tc.expect(t, waitSchedulerReady(ctx, dynconfig, 10*time.Millisecond))
+		})
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -45,6 +46,7 @@ import (
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
 	grpcpeer "google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
@@ -64,6 +66,7 @@ import (
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/net/http"
 	"d7y.io/dragonfly/v2/pkg/os/user"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	dfdaemonserver "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/server"
 	schedulerclient "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client"
 	"d7y.io/dragonfly/v2/pkg/safe"
@@ -155,7 +158,26 @@ func (s *server) Stop() {
 }
 
 func (s *server) GetPieceTasks(ctx context.Context, request *commonv1.PieceTaskRequest) (*commonv1.PiecePacket, error) {
+	p, err := s.getPieceTasks(ctx, request)
+	if err != nil {
+		return p, err
+	}
+
+	// the pieces are a consistent snapshot only when the task is done, otherwise new pieces may appear after this page
+	if err := grpc.SetHeader(ctx, metadata.Pairs(rpc.PieceSnapshotMetadataKey, strconv.FormatBool(p.TotalPiece > -1))); err != nil {
+		logger.Debugf("set piece snapshot metadata error: %s, task id: %s", err, request.TaskId)
+	}
+	return p, nil
+}
+
+func (s *server) getPieceTasks(ctx context.Context, request *commonv1.PieceTaskRequest) (*commonv1.PiecePacket, error) {
 	s.Keep()
+	// limit the page size when the caller pages through the remaining pieces with the next start num,
+	// the older callers without the paging metadata fetch all pieces in one request
+	if rpc.IsPiecePaging(ctx) && request.Limit > rpc.MaxPieceTaskPageSize {
+		request.Limit = rpc.MaxPieceTaskPageSize
+	}
+
 	p, err := s.storageManager.GetPieces(ctx, request)
 	if err != nil {
 		code := commonv1.Code_UnknownError
@@ -291,7 +313,7 @@ func (s *server) SyncPieceTasks(sync dfdaemonv1.Daemon_SyncPieceTasksServer) err
 	)
 
 	getPieces := func(ctx context.Context, request *commonv1.PieceTaskRequest) (*commonv1.PiecePacket, error) {
-		p, e := s.GetPieceTasks(ctx, request)
+		p, e := s.getPieceTasks(ctx, request)
 		if e != nil {
 			return nil, e
 		}
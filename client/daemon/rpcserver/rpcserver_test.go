@@ -30,6 +30,7 @@ import (
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
 
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
 	dfdaemonv1 "d7y.io/api/v2/pkg/apis/dfdaemon/v1"
@@ -44,6 +45,7 @@ import (
 	"d7y.io/dragonfly/v2/pkg/dfnet"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/net/ip"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	dfdaemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
 	dfdaemonserver "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/server"
 	schedulerclientmocks "d7y.io/dragonfly/v2/pkg/rpc/scheduler/client/mocks"
@@ -817,6 +819,30 @@ func TestServer_ServePeer(t *testing.T) {
 		assert.Nil(err, "client get piece tasks grpc call should be ok")
 		assert.Equal(tc.responsePieceSize, len(response.PieceInfos))
 	}
+
+	request := &commonv1.PieceTaskRequest{
+		TaskId: idgen.TaskIDV1("http://www.test.com", &commonv1.UrlMeta{}),
+		SrcPid: idgen.PeerIDV1(ip.IPv4.String()),
+		DstPid: idgen.PeerIDV1(ip.IPv4.String()),
+		Limit:  rpc.MaxPieceTaskPageSize * 2,
+	}
+
+	var header metadata.MD
+	_, err = client.GetPieceTasks(context.Background(), request, grpc.Header(&header))
+	assert.Nil(err, "client get piece tasks grpc call should be ok")
+	assert.Equal([]string{"true"}, header.Get(rpc.PieceSnapshotMetadataKey))
+
+	var pieceNums []int32
+	it := dfdaemonclient.PieceTaskIterator(context.Background(), client, request, rpc.WithPieceTaskPageSize(3))
+	assert.Nil(it.Range(func(packet *commonv1.PiecePacket) bool {
+		for _, piece := range packet.PieceInfos {
+			pieceNums = append(pieceNums, piece.PieceNum)
+		}
+		return true
+	}))
+	assert.Equal([]int32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, pieceNums)
+	assert.Equal(int32(maxPieceNum), it.Total())
+	assert.True(it.Consistent())
 }
 
 func TestServer_SyncPieceTasks(t *testing.T) {
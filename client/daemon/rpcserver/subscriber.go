@@ -22,6 +22,7 @@ import (
 	"sync"
 
 	"go.uber.org/atomic"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -31,6 +32,7 @@ import (
 	"d7y.io/dragonfly/v2/client/daemon/peer"
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 )
 
 type subscriber struct {
@@ -76,32 +78,45 @@ func sendExistPieces(
 	if request.Limit <= 0 {
 		request.Limit = 16
 	}
-	var pp *commonv1.PiecePacket
-	for {
-		pp, err = get(ctx, request)
-		if err != nil {
-			log.Errorf("get piece error: %s", err)
-			return -1, err
-		}
+
+	// the get piece func always return sorted pieces, the iterator pages through the exist pieces from the start num
+	it := rpc.NewPieceTaskIterator(ctx, func(ctx context.Context, request *commonv1.PieceTaskRequest, _ ...grpc.CallOption) (*commonv1.PiecePacket, error) {
+		return get(ctx, request)
+	}, request, rpc.WithPieceTaskPageSize(request.Limit))
+
+	var (
+		sent    bool
+		count   int
+		sendErr error
+	)
+	total = -1
+	if err = it.Range(func(pp *commonv1.PiecePacket) bool {
+		total = pp.TotalPiece
 		// when ContentLength is zero, it's an empty file, need send metadata
-		if pp.ContentLength != 0 && len(pp.PieceInfos) == 0 && skipSendZeroPiece {
-			return pp.TotalPiece, nil
+		if len(pp.PieceInfos) == 0 && (sent || pp.ContentLength != 0 && skipSendZeroPiece) {
+			return false
 		}
-		if err = sync.Send(pp); err != nil {
-			log.Errorf("send pieces error: %s", err)
-			return pp.TotalPiece, err
+		if sendErr = sync.Send(pp); sendErr != nil {
+			log.Errorf("send pieces error: %s", sendErr)
+			return false
 		}
+		sent = true
+		count += len(pp.PieceInfos)
 		for _, p := range pp.PieceInfos {
 			log.Infof("send ready piece %d", p.PieceNum)
 			sentMap[p.PieceNum] = struct{}{}
 		}
-		if uint32(len(pp.PieceInfos)) < request.Limit {
-			log.Infof("sent %d pieces, total: %d", len(pp.PieceInfos), pp.TotalPiece)
-			return pp.TotalPiece, nil
-		}
-		// the get piece func always return sorted pieces, use last piece num + 1 to get more pieces
-		request.StartNum = uint32(pp.PieceInfos[request.Limit-1].PieceNum + 1)
+		return true
+	}); err != nil {
+		log.Errorf("get piece error: %s", err)
+		return -1, err
 	}
+	if sendErr != nil {
+		return total, sendErr
+	}
+
+	log.Infof("sent %d pieces, total: %d", count, total)
+	return total, nil
 }
 
 func searchNextPieceNum(sentMap map[int32]struct{}, cur uint32) (nextPieceNum uint32) {
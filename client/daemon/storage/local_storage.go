@@ -58,6 +58,10 @@ type localTaskStore struct {
 	reclaimMarked atomic.Bool
 	gcCallback    func(CommonTaskRequest)
 
+	// written is the bytes of pieces written to data file, it is counted in quota
+	written atomic.Int64
+	quota   *quota
+
 	// when digest not match, invalid will be set
 	invalid atomic.Bool
 
@@ -75,6 +79,21 @@ func (t *localTaskStore) touch() {
 	t.lastAccess.Store(access)
 }
 
+// hasPiece returns whether the piece is written.
+func (t *localTaskStore) hasPiece(num int32) bool {
+	t.RLock()
+	defer t.RUnlock()
+	_, ok := t.Pieces[num]
+	return ok
+}
+
+// pinned returns whether the task is used by sub tasks, the sub tasks read data from the task.
+func (t *localTaskStore) pinned() bool {
+	t.RLock()
+	defer t.RUnlock()
+	return len(t.subtasks) > 0
+}
+
 func (t *localTaskStore) SubTask(req *RegisterSubTaskRequest) *localSubTaskStore {
 	subtask := &localSubTaskStore{
 		parent: t,
@@ -189,6 +208,8 @@ func (t *localTaskStore) WritePiece(ctx context.Context, req *WritePieceRequest)
 	}
 	req.PieceMetadata.Cost = uint64(time.Now().UnixNano() - start)
 	t.Pieces[req.Num] = req.PieceMetadata
+	t.written.Add(n)
+	t.quota.add(n)
 	t.genMetadata(n, req)
 	return n, nil
 }
@@ -572,6 +593,7 @@ func (t *localTaskStore) Reclaim() error {
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
+	t.quota.add(-t.written.Swap(0))
 
 	// close and remove metadata
 	err = t.reclaimMeta()
@@ -685,7 +707,7 @@ func (t *localTaskStore) partialCompleted(rg *http.Range) bool {
 		realRange.Length = t.ContentLength - realRange.Start
 	}
 
-	start, end := computePiecePosition(t.ContentLength, realRange, util.ComputePieceSize)
+	start, end := computePiecePosition(t.ContentLength, realRange, t.pieceSize)
 	// fix int overflow
 	if start < 0 || end < 0 {
 		t.Warnf("wrong start and end piece num, %d, %d", start, end)
@@ -699,6 +721,23 @@ func (t *localTaskStore) partialCompleted(rg *http.Range) bool {
 	return true
 }
 
+// pieceSize returns the piece size of the stored pieces, it may differ from the computed
+// one when the task is downloaded back-to-source with piece size hint. The caller must hold the lock.
+func (t *localTaskStore) pieceSize(length int64) uint32 {
+	for num, piece := range t.Pieces {
+		if num > 0 && piece.Range.Start > 0 {
+			return uint32(piece.Range.Start / int64(num))
+		}
+
+		// the first piece is a full piece unless it's the only piece
+		if num == 0 && t.TotalPieces > 1 && piece.Range.Length > 0 {
+			return uint32(piece.Range.Length)
+		}
+	}
+
+	return util.ComputePieceSize(length)
+}
+
 func computePiecePosition(total int64, rg *http.Range, compute func(length int64) uint32) (start, end int32) {
 	pieceSize := compute(total)
 	start = int32(math.Floor(float64(rg.Start) / float64(pieceSize)))
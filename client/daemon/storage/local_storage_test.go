@@ -36,12 +36,12 @@ import (
 
 	"d7y.io/dragonfly/v2/client/config"
 	"d7y.io/dragonfly/v2/client/daemon/test"
-	clientutil "d7y.io/dragonfly/v2/client/util"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/internal/util"
 	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/net/http"
 	_ "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/server"
+	"d7y.io/dragonfly/v2/pkg/types"
 )
 
 func TestLocalTaskStore_PutAndGetPiece(t *testing.T) {
@@ -138,7 +138,7 @@ func TestLocalTaskStore_PutAndGetPiece(t *testing.T) {
 			sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy,
 				&config.StorageOption{
 					DataPath: path.Join(test.DataDir, "storage-test"),
-					TaskExpireTime: clientutil.Duration{
+					TaskExpireTime: types.Duration{
 						Duration: time.Minute,
 					},
 				}, func(request CommonTaskRequest) {
@@ -486,6 +486,7 @@ func TestLocalTaskStore_partialCompleted(t *testing.T) {
 	var testCases = []struct {
 		name            string
 		ContentLength   int64
+		PieceSize       int64
 		ReadyPieceCount int32
 		Range           http.Range
 		Found           bool
@@ -520,6 +521,17 @@ func TestLocalTaskStore_partialCompleted(t *testing.T) {
 			},
 			Found: false,
 		},
+		{
+			name:            "range bytes=x-y partial completed with piece size hint",
+			ContentLength:   util.DefaultPieceSize * 8,
+			PieceSize:       util.DefaultPieceSize * 4,
+			ReadyPieceCount: 1,
+			Range: http.Range{
+				Start:  1,
+				Length: util.DefaultPieceSize * 2,
+			},
+			Found: true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -531,8 +543,17 @@ func TestLocalTaskStore_partialCompleted(t *testing.T) {
 					Pieces:        map[int32]PieceMetadata{},
 				},
 			}
+			if tc.PieceSize > 0 {
+				lts.TotalPieces = int32((tc.ContentLength + tc.PieceSize - 1) / tc.PieceSize)
+			}
 			for i := int32(0); i < tc.ReadyPieceCount; i++ {
-				lts.Pieces[i] = PieceMetadata{}
+				lts.Pieces[i] = PieceMetadata{
+					Num: i,
+					Range: http.Range{
+						Start:  int64(i) * tc.PieceSize,
+						Length: tc.PieceSize,
+					},
+				}
 			}
 			ok := lts.partialCompleted(&tc.Range)
 			assert.Equal(tc.Found, ok)
@@ -0,0 +1,171 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/docker/go-units"
+	"go.uber.org/atomic"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+// defaultDataDirLowWatermarkPercent is the default percent of the data directory quota
+// which the tasks are evicted down to.
+const defaultDataDirLowWatermarkPercent = 90
+
+// quota tracks the bytes used by tasks in data directory.
+type quota struct {
+	maxBytes     int64
+	taskMaxBytes int64
+	lowWatermark int64
+	used         atomic.Int64
+}
+
+// newQuota returns a quota of data directory, it returns nil if no quota is set.
+func newQuota(opt *config.StorageOption) *quota {
+	metrics.StorageQuotaBytesGauge.Set(float64(opt.DataDirMaxBytes))
+	if opt.DataDirMaxBytes <= 0 && opt.TaskMaxBytes <= 0 {
+		return nil
+	}
+
+	percent := opt.DataDirLowWatermarkPercent
+	if percent <= 0 || percent > 100 {
+		percent = defaultDataDirLowWatermarkPercent
+	}
+
+	return &quota{
+		maxBytes:     int64(opt.DataDirMaxBytes),
+		taskMaxBytes: int64(opt.TaskMaxBytes),
+		lowWatermark: int64(float64(opt.DataDirMaxBytes) * percent / 100),
+	}
+}
+
+// add adds delta to the used bytes.
+func (q *quota) add(delta int64) {
+	if q == nil || delta == 0 {
+		return
+	}
+
+	metrics.StorageUsedBytesGauge.Set(float64(q.used.Add(delta)))
+}
+
+// exceeded returns whether the used bytes exceed the quota of data directory after n bytes are written.
+func (q *quota) exceeded(n int64) bool {
+	return q != nil && q.maxBytes > 0 && q.used.Load()+n > q.maxBytes
+}
+
+// taskExceeded returns whether n bytes of one task exceed the quota of task.
+func (q *quota) taskExceeded(n int64) bool {
+	return q != nil && q.taskMaxBytes > 0 && n > q.taskMaxBytes
+}
+
+// admitTask checks the known content length of task against the quota before the task is registered,
+// the expired tasks are evicted when the task does not fit in. The caller must hold s.Lock.
+func (s *storageManager) admitTask(req *RegisterTaskRequest) error {
+	if req.ContentLength <= 0 {
+		return nil
+	}
+
+	if s.quota.taskExceeded(req.ContentLength) {
+		return fmt.Errorf("%w: task %s content length %d exceeds task quota %d",
+			ErrQuotaExceeded, req.TaskID, req.ContentLength, s.quota.taskMaxBytes)
+	}
+
+	if !s.quota.exceeded(req.ContentLength) {
+		return nil
+	}
+
+	s.evictTasks(s.quota.maxBytes-req.ContentLength, func(task *localTaskStore) bool {
+		return task.CanReclaim()
+	})
+
+	if s.quota.exceeded(req.ContentLength) {
+		return fmt.Errorf("%w: task %s content length %d exceeds free quota, used %d bytes, quota %d bytes",
+			ErrQuotaExceeded, req.TaskID, req.ContentLength, s.quota.used.Load(), s.quota.maxBytes)
+	}
+
+	return nil
+}
+
+// reservePiece makes room for the piece before it is written, when the quota is exceeded,
+// the least recently used completed tasks are evicted down to the low watermark.
+func (s *storageManager) reservePiece(task *localTaskStore, req *WritePieceRequest) error {
+	if s.quota == nil || task.hasPiece(req.Num) {
+		return nil
+	}
+
+	if s.quota.taskExceeded(task.written.Load() + req.Range.Length) {
+		return fmt.Errorf("%w: task %s exceeds task quota %d", ErrQuotaExceeded, task.TaskID, s.quota.taskMaxBytes)
+	}
+
+	if !s.quota.exceeded(req.Range.Length) {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if s.quota.exceeded(req.Range.Length) {
+		s.evictTasks(s.quota.lowWatermark-req.Range.Length, func(t *localTaskStore) bool {
+			return t != task && t.Done && !t.pinned()
+		})
+	}
+
+	if s.quota.exceeded(req.Range.Length) {
+		return fmt.Errorf("%w: no completed task can be evicted for task %s, used %d bytes, quota %d bytes",
+			ErrQuotaExceeded, task.TaskID, s.quota.used.Load(), s.quota.maxBytes)
+	}
+
+	return nil
+}
+
+// evictTasks evicts the least recently used evictable tasks until the used bytes are not greater than target,
+// the evicted tasks leave the scheduler and are broadcast as deleted. The caller must hold s.Lock.
+func (s *storageManager) evictTasks(target int64, evictable func(task *localTaskStore) bool) {
+	var tasks []*localTaskStore
+	s.tasks.Range(func(_, val any) bool {
+		task, ok := val.(*localTaskStore)
+		if !ok || task.reclaimMarked.Load() || !evictable(task) {
+			return true
+		}
+
+		tasks = append(tasks, task)
+		return true
+	})
+
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].lastAccess.Load() < tasks[j].lastAccess.Load()
+	})
+
+	for _, task := range tasks {
+		if s.quota.used.Load() <= target {
+			return
+		}
+
+		logger.Infof("storage quota exceeded, evict task %s/%s, last access: %s, size: %s",
+			task.TaskID, task.PeerID, time.Unix(0, task.lastAccess.Load()).Format(time.RFC3339Nano),
+			units.BytesSize(float64(task.written.Load())))
+		if err := s.deleteTask(PeerTaskMetadata{PeerID: task.PeerID, TaskID: task.TaskID}); err != nil {
+			logger.Errorf("evict task %s/%s error: %s", task.TaskID, task.PeerID, err)
+		}
+	}
+}
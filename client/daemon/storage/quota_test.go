@@ -0,0 +1,197 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	testifyassert "github.com/stretchr/testify/assert"
+
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/pkg/unit"
+)
+
+func TestStorageManager_Quota(t *testing.T) {
+	var (
+		taskA = PeerTaskMetadata{TaskID: "task-a", PeerID: "peer-a"}
+		taskB = PeerTaskMetadata{TaskID: "task-b", PeerID: "peer-b"}
+	)
+
+	tests := []struct {
+		name   string
+		option config.StorageOption
+		run    func(t *testing.T, s *storageManager, left *[]CommonTaskRequest)
+	}{
+		{
+			name:   "register task exceeds task quota",
+			option: config.StorageOption{TaskMaxBytes: 100 * unit.B},
+			run: func(t *testing.T, s *storageManager, left *[]CommonTaskRequest) {
+				assert := testifyassert.New(t)
+				_, err := registerQuotaTask(s, taskA, 200)
+				assert.ErrorIs(err, ErrQuotaExceeded)
+
+				_, err = registerQuotaTask(s, taskB, 100)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:   "write piece exceeds task quota",
+			option: config.StorageOption{TaskMaxBytes: 100 * unit.B},
+			run: func(t *testing.T, s *storageManager, left *[]CommonTaskRequest) {
+				assert := testifyassert.New(t)
+				_, err := registerQuotaTask(s, taskA, -1)
+				assert.NoError(err)
+				assert.NoError(writeQuotaPiece(s, taskA, 0, 80))
+				assert.ErrorIs(writeQuotaPiece(s, taskA, 1, 80), ErrQuotaExceeded)
+			},
+		},
+		{
+			name:   "register task is rejected when quota is used by unexpired tasks",
+			option: config.StorageOption{DataDirMaxBytes: 100 * unit.B},
+			run: func(t *testing.T, s *storageManager, left *[]CommonTaskRequest) {
+				assert := testifyassert.New(t)
+				_, err := registerQuotaTask(s, taskA, 80)
+				assert.NoError(err)
+				assert.NoError(writeQuotaPiece(s, taskA, 0, 80))
+				assert.Equal(int64(80), s.quota.used.Load())
+
+				_, err = registerQuotaTask(s, taskB, 50)
+				assert.ErrorIs(err, ErrQuotaExceeded)
+				assert.Empty(*left)
+			},
+		},
+		{
+			name: "register task evicts expired tasks",
+			option: config.StorageOption{
+				DataDirMaxBytes: 100 * unit.B,
+				TaskExpireTime:  types.Duration{Duration: 10 * time.Millisecond},
+			},
+			run: func(t *testing.T, s *storageManager, left *[]CommonTaskRequest) {
+				assert := testifyassert.New(t)
+				_, err := registerQuotaTask(s, taskA, 80)
+				assert.NoError(err)
+				assert.NoError(writeQuotaPiece(s, taskA, 0, 80))
+
+				time.Sleep(20 * time.Millisecond)
+				_, err = registerQuotaTask(s, taskB, 50)
+				assert.NoError(err)
+
+				_, ok := s.LoadTask(taskA)
+				assert.False(ok)
+				assert.Equal([]CommonTaskRequest{{TaskID: taskA.TaskID, PeerID: taskA.PeerID}}, *left)
+				assert.Equal(int64(0), s.quota.used.Load())
+			},
+		},
+		{
+			name: "write piece evicts completed tasks down to low watermark",
+			option: config.StorageOption{
+				DataDirMaxBytes:            100 * unit.B,
+				DataDirLowWatermarkPercent: 80,
+			},
+			run: func(t *testing.T, s *storageManager, left *[]CommonTaskRequest) {
+				assert := testifyassert.New(t)
+				_, err := registerQuotaTask(s, taskA, 60)
+				assert.NoError(err)
+				assert.NoError(writeQuotaPiece(s, taskA, 0, 60))
+
+				// The registered task is wrapped by keepAliveTaskStorageDriver, load the local task store directly.
+				ts, ok := s.LoadTask(taskA)
+				assert.True(ok)
+				lts, ok := ts.(*localTaskStore)
+				if !ok {
+					t.Fatalf("unexpected task storage driver %T", ts)
+				}
+				lts.Done = true
+
+				_, err = registerQuotaTask(s, taskB, -1)
+				assert.NoError(err)
+				assert.NoError(writeQuotaPiece(s, taskB, 0, 30))
+				assert.Empty(*left)
+
+				assert.NoError(writeQuotaPiece(s, taskB, 1, 30))
+				_, ok = s.LoadTask(taskA)
+				assert.False(ok)
+				assert.Equal([]CommonTaskRequest{{TaskID: taskA.TaskID, PeerID: taskA.PeerID}}, *left)
+				assert.Equal(int64(60), s.quota.used.Load())
+			},
+		},
+		{
+			name:   "write piece is rejected when no completed task can be evicted",
+			option: config.StorageOption{DataDirMaxBytes: 100 * unit.B},
+			run: func(t *testing.T, s *storageManager, left *[]CommonTaskRequest) {
+				assert := testifyassert.New(t)
+				_, err := registerQuotaTask(s, taskA, -1)
+				assert.NoError(err)
+				assert.NoError(writeQuotaPiece(s, taskA, 0, 60))
+
+				_, err = registerQuotaTask(s, taskB, -1)
+				assert.NoError(err)
+				assert.ErrorIs(writeQuotaPiece(s, taskB, 0, 60), ErrQuotaExceeded)
+
+				_, ok := s.LoadTask(taskA)
+				assert.True(ok)
+				assert.Empty(*left)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var left []CommonTaskRequest
+			option := tc.option
+			option.DataPath = t.TempDir()
+			sm, err := NewStorageManager(config.SimpleLocalTaskStoreStrategy, &option, func(request CommonTaskRequest) {
+				left = append(left, request)
+			}, defaultDirectoryMode)
+			testifyassert.NoError(t, err)
+
+			tc.run(t, sm.(*storageManager), &left)
+		})
+	}
+}
+
+func registerQuotaTask(s *storageManager, meta PeerTaskMetadata, contentLength int64) (TaskStorageDriver, error) {
+	return s.RegisterTask(context.Background(), &RegisterTaskRequest{
+		PeerTaskMetadata: meta,
+		ContentLength:    contentLength,
+		TotalPieces:      -1,
+	})
+}
+
+func writeQuotaPiece(s *storageManager, meta PeerTaskMetadata, num int32, length int64) error {
+	_, err := s.WritePiece(context.Background(), &WritePieceRequest{
+		PeerTaskMetadata: meta,
+		PieceMetadata: PieceMetadata{
+			Num:    num,
+			Offset: uint64(int64(num) * length),
+			Range: http.Range{
+				Start:  int64(num) * length,
+				Length: length,
+			},
+			Style: commonv1.PieceStyle_PLAIN,
+		},
+		Reader: bytes.NewBuffer(make([]byte, length)),
+	})
+	return err
+}
@@ -119,6 +119,7 @@ var (
 	ErrDigestNotSet     = errors.New("digest not set")
 	ErrInvalidDigest    = errors.New("invalid digest")
 	ErrBadRequest       = errors.New("bad request")
+	ErrQuotaExceeded    = errors.New("storage quota exceeded")
 )
 
 const (
@@ -153,6 +154,9 @@ type storageManager struct {
 	subIndexTask2PeerTask map[string][]*localSubTaskStore // key: task id, value: slice of localSubTaskStore
 
 	peerSearchBroadcaster pex.PeerSearchBroadcaster
+
+	// quota is the quota of data directory, nil means no quota.
+	quota *quota
 }
 
 var _ gc.GC = (*storageManager)(nil)
@@ -212,6 +216,7 @@ func NewStorageManager(storeStrategy config.StoreStrategy, opt *config.StorageOp
 	if s.storeOption.ReloadGoroutineCount <= 0 {
 		s.storeOption.ReloadGoroutineCount = 64
 	}
+	s.quota = newQuota(s.storeOption)
 	s.ReloadPersistentTask(gcCallback)
 
 	gc.Register(GCName, s)
@@ -270,6 +275,11 @@ func (s *storageManager) RegisterTask(ctx context.Context, req *RegisterTaskRequ
 		}); ok {
 		return s.keepAliveTaskStorageDriver(ts), nil
 	}
+
+	if err := s.admitTask(req); err != nil {
+		return nil, err
+	}
+
 	// still not exist, create a new task store
 	ts, err := s.CreateTask(req)
 	if err != nil {
@@ -317,6 +327,12 @@ func (s *storageManager) WritePiece(ctx context.Context, req *WritePieceRequest)
 	if !ok {
 		return 0, ErrTaskNotFound
 	}
+
+	if lts, ok := t.(*localTaskStore); ok {
+		if err := s.reservePiece(lts, req); err != nil {
+			return 0, err
+		}
+	}
 	return t.WritePiece(ctx, req)
 }
 
@@ -442,6 +458,7 @@ func (s *storageManager) CreateTask(req *RegisterTaskRequest) (TaskStorageDriver
 			Pieces:        map[int32]PieceMetadata{},
 		},
 		gcCallback:       s.gcCallback,
+		quota:            s.quota,
 		dataDir:          dataDir,
 		metadataFilePath: path.Join(dataDir, taskMetadata),
 		expireTime:       s.storeOption.TaskExpireTime.Duration,
@@ -796,6 +813,7 @@ func (s *storageManager) reloadPersistentTaskByPeerDir(gcCallback GCCallback, ta
 		metadataFilePath:    path.Join(dataDir, taskMetadata),
 		expireTime:          s.storeOption.TaskExpireTime.Duration,
 		gcCallback:          gcCallback,
+		quota:               s.quota,
 		SugaredLoggerOnWith: logger.With("task", taskID, "peer", peerID, "component", s.storeStrategy),
 	}
 	t.touch()
@@ -812,6 +830,13 @@ func (s *storageManager) reloadPersistentTaskByPeerDir(gcCallback GCCallback, ta
 			Warnf("load task from disk error: %s, data base64 encode: %s", err, base64.StdEncoding.EncodeToString(bytes))
 		return err
 	}
+	var written int64
+	for _, piece := range t.Pieces {
+		written += piece.Range.Length
+	}
+	t.written.Store(written)
+	t.quota.add(written)
+
 	logger.Debugf("load task %s/%s from disk, metadata %s, last access: %v, expire time: %s",
 		t.persistentMetadata.TaskID, t.persistentMetadata.PeerID, t.metadataFilePath, time.Unix(0, t.lastAccess.Load()), t.expireTime)
 	s.tasks.Store(PeerTaskMetadata{
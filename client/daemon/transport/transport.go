@@ -49,6 +49,7 @@ import (
 	"d7y.io/dragonfly/v2/client/daemon/peer"
 	"d7y.io/dragonfly/v2/client/daemon/pex"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/idgen"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 )
 
@@ -59,6 +60,9 @@ var (
 	layerReg     = regexp.MustCompile("^.+/blobs/sha256.*$")
 	traceContext = propagation.TraceContext{}
 	proxyTTL     = 30 * time.Minute
+
+	// manifestReg the regex to determine if it is an image manifest download
+	manifestReg = regexp.MustCompile("^.+/manifests/[^/]+$")
 )
 
 // transport implements RoundTripper for dragonfly.
@@ -90,6 +94,9 @@ type transport struct {
 	// defaultPriority is used when http request without X-Dragonfly-Priority Header
 	defaultPriority commonv1.Priority
 
+	// identityHeaders returns the identity headers of the http request without X-Dragonfly-Identity-Headers Header
+	identityHeaders func(req *http.Request) []string
+
 	// dumpHTTPContent indicates to dump http request header and response header
 	dumpHTTPContent bool
 
@@ -164,6 +171,14 @@ func WithDefaultPriority(p commonv1.Priority) Option {
 
 }
 
+// WithIdentityHeaders sets the identity headers for http requests without X-Dragonfly-Identity-Headers Header
+func WithIdentityHeaders(f func(req *http.Request) []string) Option {
+	return func(rt *transport) *transport {
+		rt.identityHeaders = f
+		return rt
+	}
+}
+
 func WithDumpHTTPContent(b bool) Option {
 	return func(rt *transport) *transport {
 		rt.dumpHTTPContent = b
@@ -270,6 +285,21 @@ func (rt *transport) roundTripWithDragonfly(req *http.Request) (*http.Response,
 	return rt.download(ctx, req)
 }
 
+// defaultIdentityHeaders returns the comma separated identity headers of the request,
+// the registry manifest uses the default identity headers.
+func (rt *transport) defaultIdentityHeaders(req *http.Request) string {
+	var identityHeaders []string
+	if rt.identityHeaders != nil {
+		identityHeaders = rt.identityHeaders(req)
+	}
+
+	if len(identityHeaders) == 0 && manifestReg.MatchString(req.URL.Path) {
+		identityHeaders = config.DefaultManifestIdentityHeaders
+	}
+
+	return strings.Join(identityHeaders, idgen.IdentityHeadersSeparator)
+}
+
 // NeedUseDragonfly is the default value for shouldUseDragonfly, which downloads all
 // images layers with dragonfly.
 func NeedUseDragonfly(req *http.Request) bool {
@@ -318,6 +348,7 @@ func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Res
 	tag := nethttp.PickHeader(req.Header, config.HeaderDragonflyTag, rt.defaultTag)
 	application := nethttp.PickHeader(req.Header, config.HeaderDragonflyApplication, rt.defaultApplication)
 	forwarded := nethttp.PickHeader(req.Header, config.HeaderDragonflyForwardedFor, "")
+	identityHeaders := nethttp.PickHeader(req.Header, nethttp.HeaderIdentityHeaders, rt.defaultIdentityHeaders(req))
 	var priority = rt.defaultPriority
 	priorityString := nethttp.PickHeader(req.Header, config.HeaderDragonflyPriority, fmt.Sprintf("%d", rt.defaultPriority))
 	priorityInt, err := strconv.ParseInt(priorityString, 10, 32)
@@ -329,6 +360,9 @@ func (rt *transport) download(ctx context.Context, req *http.Request) (*http.Res
 	delHopHeaders(req.Header)
 
 	meta.Header = nethttp.HeaderToMap(req.Header)
+	if identityHeaders != "" {
+		meta.Header[nethttp.HeaderIdentityHeaders] = identityHeaders
+	}
 	meta.Tag = tag
 	meta.Filter = filter
 	meta.Application = application
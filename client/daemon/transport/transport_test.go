@@ -29,6 +29,7 @@ import (
 
 	"d7y.io/dragonfly/v2/client/daemon/peer"
 	"d7y.io/dragonfly/v2/client/daemon/test"
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 )
 
 func TestTransport_RoundTrip(t *testing.T) {
@@ -66,3 +67,100 @@ func TestTransport_RoundTrip(t *testing.T) {
 	}
 	assert.Equal(testData, output)
 }
+
+func TestTransport_IdentityHeaders(t *testing.T) {
+	var (
+		manifestURL  = "http://x/v2/library/alpine/manifests/latest"
+		blobURL      = "http://x/v2/library/alpine/blobs/sha256:c5b1261d6d3e43071626931fc004f70149baeba2c8ec672bd4f27761f8e1ad6b"
+		manifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+		manifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	)
+
+	tests := []struct {
+		name            string
+		url             string
+		header          http.Header
+		identityHeaders func(req *http.Request) []string
+		expect          func(t *testing.T, reqs []*peer.StreamTaskRequest)
+	}{
+		{
+			name: "manifest uses the default identity headers",
+			url:  manifestURL,
+			expect: func(t *testing.T, reqs []*peer.StreamTaskRequest) {
+				assert := testifyassert.New(t)
+				assert.Equal("Accept", reqs[0].URLMeta.Header[nethttp.HeaderIdentityHeaders])
+				assert.NotEqual(reqs[0].TaskID(), reqs[1].TaskID())
+			},
+		},
+		{
+			name: "blob is not varied by the identity headers",
+			url:  blobURL,
+			expect: func(t *testing.T, reqs []*peer.StreamTaskRequest) {
+				assert := testifyassert.New(t)
+				assert.Empty(reqs[0].URLMeta.Header[nethttp.HeaderIdentityHeaders])
+				assert.Equal(reqs[0].TaskID(), reqs[1].TaskID())
+			},
+		},
+		{
+			name: "identity headers of the proxy rule",
+			url:  "http://x/y",
+			identityHeaders: func(req *http.Request) []string {
+				return []string{"Accept", "X-Foo"}
+			},
+			expect: func(t *testing.T, reqs []*peer.StreamTaskRequest) {
+				assert := testifyassert.New(t)
+				assert.Equal("Accept,X-Foo", reqs[0].URLMeta.Header[nethttp.HeaderIdentityHeaders])
+				assert.NotEqual(reqs[0].TaskID(), reqs[1].TaskID())
+			},
+		},
+		{
+			name:   "identity headers of the request header",
+			url:    manifestURL,
+			header: http.Header{nethttp.HeaderIdentityHeaders: []string{"X-Foo"}},
+			expect: func(t *testing.T, reqs []*peer.StreamTaskRequest) {
+				assert := testifyassert.New(t)
+				assert.Equal("X-Foo", reqs[0].URLMeta.Header[nethttp.HeaderIdentityHeaders])
+				assert.Equal(reqs[0].TaskID(), reqs[1].TaskID())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			var reqs []*peer.StreamTaskRequest
+			peerTaskManager := peer.NewMockTaskManager(ctrl)
+			peerTaskManager.EXPECT().StartStreamTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *peer.StreamTaskRequest) (io.ReadCloser, map[string]string, error) {
+					reqs = append(reqs, req)
+					return io.NopCloser(bytes.NewBufferString("foo")), nil, nil
+				},
+			).Times(2)
+
+			rt := New(
+				WithPeerIDGenerator(peer.NewPeerIDGenerator("127.0.0.1")),
+				WithPeerTaskManager(peerTaskManager),
+				WithCondition(func(r *http.Request) bool {
+					return true
+				}),
+				WithIdentityHeaders(tc.identityHeaders))
+
+			for _, accept := range []string{manifestList, manifest} {
+				req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, tc.url, nil)
+				for k, v := range tc.header {
+					req.Header[k] = v
+				}
+				req.Header.Set("Accept", accept)
+
+				resp, err := rt.RoundTrip(req)
+				assert.NoError(err)
+				resp.Body.Close()
+			}
+
+			tc.expect(t, reqs)
+		})
+	}
+}
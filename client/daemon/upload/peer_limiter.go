@@ -0,0 +1,215 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upload
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/manager/types"
+)
+
+// PeerLimiter shares the total upload rate limit fairly among the peers
+// downloading from the daemon. Every active peer gets total / active,
+// bounded by the floor and the ceiling of a single peer.
+type PeerLimiter struct {
+	mu sync.Mutex
+
+	// limit is the total upload rate limit.
+	limit rate.Limit
+
+	// floor is the minimum rate limit of a single peer.
+	floor rate.Limit
+
+	// ceiling is the maximum rate limit of a single peer, zero means no ceiling.
+	ceiling rate.Limit
+
+	// localCeiling is the ceiling from local config, it is used
+	// when the scheduler cluster client config does not set one.
+	localCeiling rate.Limit
+
+	// burst is the maximum bytes a single peer can upload at once.
+	burst int
+
+	// peers is the rate limiters of the active peers.
+	peers map[string]*peerRateLimiter
+}
+
+// peerRateLimiter is the rate limiter of a peer with the count of its active streams.
+type peerRateLimiter struct {
+	*rate.Limiter
+	streams int
+}
+
+// NewPeerLimiter returns a new PeerLimiter.
+func NewPeerLimiter(limit, floor, ceiling rate.Limit, burst int) *PeerLimiter {
+	return &PeerLimiter{
+		limit:        limit,
+		floor:        floor,
+		ceiling:      ceiling,
+		localCeiling: ceiling,
+		burst:        burst,
+		peers:        map[string]*peerRateLimiter{},
+	}
+}
+
+// Acquire registers an active upload stream of the peer and
+// returns the func to release it.
+func (l *PeerLimiter) Acquire(peer string) func() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, ok := l.peers[peer]
+	if !ok {
+		p = &peerRateLimiter{Limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.peers[peer] = p
+	}
+	p.streams++
+	l.rebalance()
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		p.streams--
+		if p.streams <= 0 {
+			delete(l.peers, peer)
+			l.rebalance()
+		}
+	}
+}
+
+// WaitN blocks until the peer is permitted to upload n bytes. Bytes that have
+// to wait for tokens are recorded as throttled.
+func (l *PeerLimiter) WaitN(ctx context.Context, peer string, n int) error {
+	l.mu.Lock()
+	p, ok := l.peers[peer]
+	l.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("peer %s is not active", peer)
+	}
+
+	for n > 0 {
+		chunk := n
+		if burst := p.Burst(); chunk > burst {
+			chunk = burst
+		}
+
+		r := p.ReserveN(time.Now(), chunk)
+		if !r.OK() {
+			return fmt.Errorf("peer %s exceeds limiter's burst %d", peer, p.Burst())
+		}
+
+		if delay := r.Delay(); delay > 0 {
+			metrics.UploadPeerThrottledBytesCount.Add(float64(chunk))
+
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				r.Cancel()
+				return ctx.Err()
+			}
+		}
+
+		n -= chunk
+	}
+
+	return nil
+}
+
+// Limit returns the current rate limit of the peer, zero means the peer is not active.
+func (l *PeerLimiter) Limit(peer string) rate.Limit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	p, ok := l.peers[peer]
+	if !ok {
+		return 0
+	}
+
+	return p.Limiter.Limit()
+}
+
+// SetCeiling sets the maximum rate limit of a single peer, zero means no ceiling.
+func (l *PeerLimiter) SetCeiling(ceiling rate.Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ceiling = ceiling
+	l.rebalance()
+}
+
+// OnNotify applies the peer upload rate ceiling of the scheduler cluster client config,
+// it falls back to the local ceiling when the client config does not set one.
+func (l *PeerLimiter) OnNotify(data *config.DynconfigData) {
+	ceiling := l.localCeiling
+	for _, scheduler := range data.Schedulers {
+		if scheduler.SchedulerCluster == nil || len(scheduler.SchedulerCluster.ClientConfig) == 0 {
+			continue
+		}
+
+		var clientConfig types.SchedulerClusterClientConfig
+		if err := json.Unmarshal(scheduler.SchedulerCluster.ClientConfig, &clientConfig); err != nil {
+			logger.Errorf("unmarshal scheduler cluster client config failed: %s", err)
+			continue
+		}
+
+		if clientConfig.PeerUploadRateCeiling > 0 {
+			ceiling = rate.Limit(clientConfig.PeerUploadRateCeiling)
+		}
+		break
+	}
+
+	l.SetCeiling(ceiling)
+}
+
+// rebalance recalculates the fair share of the active peers, it must be called with lock held.
+func (l *PeerLimiter) rebalance() {
+	if len(l.peers) == 0 {
+		return
+	}
+
+	share := l.limit / rate.Limit(len(l.peers))
+	if share < l.floor {
+		share = l.floor
+	}
+
+	if l.ceiling > 0 && share > l.ceiling {
+		share = l.ceiling
+	}
+
+	burst := l.burst
+	if burst <= 0 {
+		burst = max(int(share), 1)
+	}
+
+	now := time.Now()
+	for _, p := range l.peers {
+		p.SetLimitAt(now, share)
+		p.SetBurstAt(now, burst)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package upload
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+
+	managerv1 "d7y.io/api/v2/pkg/apis/manager/v1"
+
+	"d7y.io/dragonfly/v2/client/config"
+)
+
+func TestPeerLimiter_Acquire(t *testing.T) {
+	tests := []struct {
+		name    string
+		limit   rate.Limit
+		floor   rate.Limit
+		ceiling rate.Limit
+		peers   []string
+		expect  func(t *testing.T, l *PeerLimiter)
+	}{
+		{
+			name:  "share limit between peers",
+			limit: 100,
+			peers: []string{"foo", "bar"},
+			expect: func(t *testing.T, l *PeerLimiter) {
+				assert := assert.New(t)
+				assert.Equal(rate.Limit(50), l.Limit("foo"))
+				assert.Equal(rate.Limit(50), l.Limit("bar"))
+			},
+		},
+		{
+			name:  "share limit between streams of the same peer",
+			limit: 100,
+			peers: []string{"foo", "foo", "bar"},
+			expect: func(t *testing.T, l *PeerLimiter) {
+				assert := assert.New(t)
+				assert.Equal(rate.Limit(50), l.Limit("foo"))
+				assert.Equal(rate.Limit(50), l.Limit("bar"))
+			},
+		},
+		{
+			name:  "share limit with floor",
+			limit: 100,
+			floor: 40,
+			peers: []string{"foo", "bar", "baz"},
+			expect: func(t *testing.T, l *PeerLimiter) {
+				assert := assert.New(t)
+				assert.Equal(rate.Limit(40), l.Limit("foo"))
+			},
+		},
+		{
+			name:    "share limit with ceiling",
+			limit:   100,
+			ceiling: 20,
+			peers:   []string{"foo", "bar"},
+			expect: func(t *testing.T, l *PeerLimiter) {
+				assert := assert.New(t)
+				assert.Equal(rate.Limit(20), l.Limit("foo"))
+				l.SetCeiling(0)
+				assert.Equal(rate.Limit(50), l.Limit("foo"))
+			},
+		},
+		{
+			name:  "peer is not active",
+			limit: 100,
+			expect: func(t *testing.T, l *PeerLimiter) {
+				assert := assert.New(t)
+				assert.Equal(rate.Limit(0), l.Limit("foo"))
+				assert.Error(l.WaitN(context.Background(), "foo", 1))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l := NewPeerLimiter(tc.limit, tc.floor, tc.ceiling, 10)
+			for _, peer := range tc.peers {
+				l.Acquire(peer)
+			}
+
+			tc.expect(t, l)
+		})
+	}
+}
+
+func TestPeerLimiter_Release(t *testing.T) {
+	assert := assert.New(t)
+	l := NewPeerLimiter(100, 0, 0, 10)
+	releaseFoo := l.Acquire("foo")
+	releaseBar := l.Acquire("bar")
+	assert.Equal(rate.Limit(50), l.Limit("foo"))
+
+	releaseBar()
+	assert.Equal(rate.Limit(100), l.Limit("foo"))
+	assert.Equal(rate.Limit(0), l.Limit("bar"))
+
+	releaseFoo()
+	assert.Len(l.peers, 0)
+}
+
+func TestPeerLimiter_OnNotify(t *testing.T) {
+	assert := assert.New(t)
+	l := NewPeerLimiter(100, 0, 80, 10)
+	l.Acquire("foo")
+	assert.Equal(rate.Limit(80), l.Limit("foo"))
+
+	l.OnNotify(&config.DynconfigData{
+		Schedulers: []*managerv1.Scheduler{
+			{
+				SchedulerCluster: &managerv1.SchedulerCluster{
+					ClientConfig: []byte(`{"peer_upload_rate_ceiling":30}`),
+				},
+			},
+		},
+	})
+	assert.Equal(rate.Limit(30), l.Limit("foo"))
+
+	l.OnNotify(&config.DynconfigData{})
+	assert.Equal(rate.Limit(80), l.Limit("foo"))
+}
+
+func TestPeerLimiter_WaitN(t *testing.T) {
+	const (
+		limit    = 64 * 1024
+		burst    = 4 * 1024
+		duration = 500 * time.Millisecond
+	)
+
+	assert := assert.New(t)
+	l := NewPeerLimiter(limit, 0, 0, burst)
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		counts = map[string]int{}
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+
+	start := time.Now()
+	for _, peer := range []string{"foo", "bar"} {
+		wg.Add(1)
+		go func(peer string) {
+			defer wg.Done()
+			release := l.Acquire(peer)
+			defer release()
+
+			for {
+				if err := l.WaitN(ctx, peer, burst); err != nil {
+					return
+				}
+
+				mu.Lock()
+				counts[peer] += burst
+				mu.Unlock()
+			}
+		}(peer)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	// Total bytes must not exceed the total limit, allowing the initial bursts.
+	total := counts["foo"] + counts["bar"]
+	assert.LessOrEqual(float64(total), limit*elapsed.Seconds()+2*burst)
+
+	// Every peer gets a rough fair share of the total bytes.
+	assert.InDelta(0.5, float64(counts["foo"])/float64(total), 0.2)
+	assert.InDelta(0.5, float64(counts["bar"])/float64(total), 0.2)
+}
@@ -39,8 +39,10 @@ import (
 	"golang.org/x/time/rate"
 
 	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/client/daemon/metrics"
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/compression"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 )
 
@@ -68,8 +70,13 @@ type Manager interface {
 type uploadManager struct {
 	*http.Server
 	*rate.Limiter
+	peerLimiter    *PeerLimiter
 	storageManager storage.Manager
 	certify        *certify.Certify
+
+	// pieceCompressionLevel is the compression level of pieces,
+	// pieces are not compressed if it is zero.
+	pieceCompressionLevel int
 }
 
 // Option is a functional option for configuring the upload manager.
@@ -82,12 +89,26 @@ func WithLimiter(limiter *rate.Limiter) func(*uploadManager) {
 	}
 }
 
+// WithPeerLimiter sets upload rate limiter of every peer, which shares the upload rate limit fairly among peers.
+func WithPeerLimiter(peerLimiter *PeerLimiter) func(*uploadManager) {
+	return func(manager *uploadManager) {
+		manager.peerLimiter = peerLimiter
+	}
+}
+
 func WithCertify(ct *certify.Certify) func(manager *uploadManager) {
 	return func(manager *uploadManager) {
 		manager.certify = ct
 	}
 }
 
+// WithPieceCompression compresses pieces with the level when the peer accepts the codec.
+func WithPieceCompression(level int) func(manager *uploadManager) {
+	return func(manager *uploadManager) {
+		manager.pieceCompressionLevel = level
+	}
+}
+
 // New returns a new Manager instance.
 func NewUploadManager(cfg *config.DaemonOption, storageManager storage.Manager, logDir string, opts ...Option) (Manager, error) {
 	um := &uploadManager{
@@ -179,6 +200,7 @@ func (um *uploadManager) initRouter(cfg *config.DaemonOption, logDir string) *gi
 
 	// Health Check.
 	r.GET("/healthy", um.getHealth)
+	r.HEAD("/healthy", um.getHealth)
 
 	// Peer download task.
 	d := r.Group(RouterGroupDownload)
@@ -242,13 +264,37 @@ func (um *uploadManager) getDownload(ctx *gin.Context) {
 	}
 	defer closer.Close()
 
-	// Add header "Content-Length" to avoid chunked body in http client.
-	ctx.Header(headers.ContentLength, fmt.Sprintf("%d", rg[0].Length))
+	// Compress piece if both peers support the codec, the length of compressed piece
+	// is unknown, so the body is chunked.
+	var codec string
+	if um.pieceCompressionLevel > 0 {
+		codec = compression.Negotiate(compression.ParseCodecs(ctx.Request.Header.Values(headers.AcceptEncoding)...))
+	}
+
+	if codec != "" {
+		ctx.Header(headers.ContentEncoding, codec)
+		ctx.Header(headers.Vary, headers.AcceptEncoding)
+	} else {
+		// Add header "Content-Length" to avoid chunked body in http client.
+		ctx.Header(headers.ContentLength, fmt.Sprintf("%d", rg[0].Length))
+	}
 
 	// write header immediately, prevent client disconnecting after limiter.Wait() due to response header timeout
 	ctx.Writer.WriteHeaderNow()
 	ctx.Writer.Flush()
 
+	if um.peerLimiter != nil {
+		peer := ctx.ClientIP()
+		release := um.peerLimiter.Acquire(peer)
+		defer release()
+
+		if err = um.peerLimiter.WaitN(ctx, peer, int(rg[0].Length)); err != nil {
+			log.Errorf("get peer limit failed: %s", err)
+			ctx.JSON(http.StatusInternalServerError, gin.H{"errors": err.Error()})
+			return
+		}
+	}
+
 	if um.Limiter != nil {
 		if err = um.Limiter.WaitN(ctx, int(rg[0].Length)); err != nil {
 			log.Errorf("get limit failed: %s", err)
@@ -257,9 +303,36 @@ func (um *uploadManager) getDownload(ctx *gin.Context) {
 		}
 	}
 
+	if codec != "" {
+		writer, err := compression.NewWriter(ctx.Writer, codec, um.pieceCompressionLevel)
+		if err != nil {
+			log.Errorf("init %s writer failed: %s", codec, err)
+			return
+		}
+
+		n, err := io.Copy(writer, reader)
+		if err != nil {
+			log.Errorf("transfer %s data failed: %s", codec, err)
+			return
+		} else if n != rg[0].Length {
+			log.Errorf("transferred data length not match request, request: %d, transferred: %d",
+				rg[0].Length, n)
+			return
+		}
+
+		if err := writer.Close(); err != nil {
+			log.Errorf("flush %s data failed: %s", codec, err)
+			return
+		}
+
+		metrics.UploadTraffic.Add(float64(n))
+		return
+	}
+
 	// If w is a socket, golang will use sendfile or splice syscall for zero copy feature
 	// when start to transfer data, we could not call http.Error with header.
-	if n, err := io.Copy(ctx.Writer, reader); err != nil {
+	n, err := io.Copy(ctx.Writer, reader)
+	if err != nil {
 		log.Errorf("transfer data failed: %s", err)
 		return
 	} else if n != rg[0].Length {
@@ -267,4 +340,6 @@ func (um *uploadManager) getDownload(ctx *gin.Context) {
 			rg[0].Length, n)
 		return
 	}
+
+	metrics.UploadTraffic.Add(float64(n))
 }
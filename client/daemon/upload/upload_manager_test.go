@@ -34,6 +34,7 @@ import (
 	"d7y.io/dragonfly/v2/client/daemon/storage"
 	"d7y.io/dragonfly/v2/client/daemon/storage/mocks"
 	"d7y.io/dragonfly/v2/client/daemon/test"
+	"d7y.io/dragonfly/v2/pkg/compression"
 	_ "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/server"
 )
 
@@ -104,3 +105,111 @@ func TestUploadManager_Serve(t *testing.T) {
 		assert.Equal(tt.targetPieceData, data)
 	}
 }
+
+func TestUploadManager_PieceCompression(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	testData, err := os.ReadFile(test.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockStorageManager := mocks.NewMockManager(ctrl)
+	mockStorageManager.EXPECT().ReadPiece(gomock.Any(), gomock.Any()).AnyTimes().
+		DoAndReturn(func(ctx context.Context, req *storage.ReadPieceRequest) (io.Reader, io.Closer, error) {
+			return bytes.NewBuffer(testData[req.Range.Start : req.Range.Start+req.Range.Length]),
+				io.NopCloser(nil), nil
+		})
+
+	tests := []struct {
+		name           string
+		options        []Option
+		acceptEncoding string
+		expect         func(t *testing.T, resp *http.Response)
+	}{
+		{
+			name:           "compress piece with common codec",
+			options:        []Option{WithPieceCompression(compression.MinLevel)},
+			acceptEncoding: "gzip, zstd",
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := testifyassert.New(t)
+				assert.Equal(compression.CodecZstd, resp.Header.Get("Content-Encoding"))
+
+				reader, err := compression.NewReader(resp.Body, compression.CodecZstd)
+				assert.NoError(err)
+				defer reader.Close()
+
+				data, err := io.ReadAll(reader)
+				assert.NoError(err)
+				assert.Equal(testData[0:1024], data)
+			},
+		},
+		{
+			name:           "peer does not accept compressed piece",
+			options:        []Option{WithPieceCompression(compression.MinLevel)},
+			acceptEncoding: "",
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := testifyassert.New(t)
+				assert.Empty(resp.Header.Get("Content-Encoding"))
+				assert.Equal(int64(1024), resp.ContentLength)
+
+				data, err := io.ReadAll(resp.Body)
+				assert.NoError(err)
+				assert.Equal(testData[0:1024], data)
+			},
+		},
+		{
+			name:           "no common codec",
+			options:        []Option{WithPieceCompression(compression.MinLevel)},
+			acceptEncoding: "gzip",
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := testifyassert.New(t)
+				assert.Empty(resp.Header.Get("Content-Encoding"))
+
+				data, err := io.ReadAll(resp.Body)
+				assert.NoError(err)
+				assert.Equal(testData[0:1024], data)
+			},
+		},
+		{
+			name:           "piece compression is disabled",
+			acceptEncoding: "zstd",
+			expect: func(t *testing.T, resp *http.Response) {
+				assert := testifyassert.New(t)
+				assert.Empty(resp.Header.Get("Content-Encoding"))
+
+				data, err := io.ReadAll(resp.Body)
+				assert.NoError(err)
+				assert.Equal(testData[0:1024], data)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := testifyassert.New(t)
+			um, err := NewUploadManager(config.NewDaemonConfig(), mockStorageManager, os.TempDir(), tc.options...)
+			assert.NoError(err)
+
+			listen, err := net.Listen("tcp4", "127.0.0.1:0")
+			assert.NoError(err)
+			defer um.Stop()
+
+			go um.Serve(listen) // nolint: errcheck
+
+			req, _ := http.NewRequest(http.MethodGet,
+				fmt.Sprintf("http://%s/%s/%s/%s?peerId=%s", listen.Addr().String(), "download", "666", "task-0", "peer-0"), nil)
+			req.Header.Add("Range", "bytes=0-1023")
+			if tc.acceptEncoding != "" {
+				req.Header.Add("Accept-Encoding", tc.acceptEncoding)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			assert.NoError(err)
+			defer resp.Body.Close()
+
+			tc.expect(t, resp)
+		})
+	}
+}
@@ -17,7 +17,9 @@
 package dfget
 
 import (
+	"bufio"
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
@@ -26,6 +28,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -37,13 +40,19 @@ import (
 	dfdaemonv1 "d7y.io/api/v2/pkg/apis/dfdaemon/v1"
 
 	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/digest"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 	dfdaemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
 	"d7y.io/dragonfly/v2/pkg/source"
 	pkgstrings "d7y.io/dragonfly/v2/pkg/strings"
 )
 
+// ErrDigestNotMatched represents the digest of the downloaded file is not matched.
+var ErrDigestNotMatched = errors.New("digest is not matched")
+
 func Download(cfg *config.DfgetConfig, client dfdaemonclient.V1) error {
 	var (
 		ctx       = context.Background()
@@ -53,7 +62,9 @@ func Download(cfg *config.DfgetConfig, client dfdaemonclient.V1) error {
 	)
 
 	wLog.Info("init success and start to download")
-	fmt.Println("init success and start to download")
+	if !cfg.DryRun {
+		fmt.Println("init success and start to download")
+	}
 
 	if cfg.Timeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, cfg.Timeout)
@@ -75,6 +86,10 @@ func Download(cfg *config.DfgetConfig, client dfdaemonclient.V1) error {
 }
 
 func download(ctx context.Context, client dfdaemonclient.V1, cfg *config.DfgetConfig, wLog *logger.SugaredLoggerOnWith) error {
+	if cfg.DryRun {
+		return dryRun(ctx, client, cfg, os.Stdout)
+	}
+
 	if cfg.Recursive {
 		return recursiveDownload(ctx, client, cfg)
 	}
@@ -82,7 +97,10 @@ func download(ctx context.Context, client dfdaemonclient.V1, cfg *config.DfgetCo
 }
 
 func singleDownload(ctx context.Context, client dfdaemonclient.V1, cfg *config.DfgetConfig, wLog *logger.SugaredLoggerOnWith) error {
-	hdr := parseHeader(cfg.Header)
+	hdr, err := newHeader(cfg)
+	if err != nil {
+		return err
+	}
 
 	if client == nil {
 		return downloadFromSource(ctx, cfg, hdr)
@@ -121,6 +139,15 @@ func singleDownload(ctx context.Context, client dfdaemonclient.V1, cfg *config.D
 				_ = pb.Close()
 			}
 
+			// The output file with the original offset has the other ranges of the url,
+			// so the digest can not be verified.
+			if !cfg.KeepOriginalOffset {
+				if err := verifyOutput(cfg, cfg.Output); err != nil {
+					wLog.Errorf("verify output failed: %s", err.Error())
+					return err
+				}
+			}
+
 			wLog.Infof("download from daemon success, length: %d bytes, cost: %d ms", result.CompletedLength, time.Since(start).Milliseconds())
 			fmt.Printf("finish total length %d bytes\n", result.CompletedLength)
 
@@ -138,6 +165,59 @@ processError:
 	return downError
 }
 
+// dryRun resolves the task of url and prints the download plan without downloading,
+// it only stats the task in daemon, so no peer is registered to scheduler.
+func dryRun(ctx context.Context, client dfdaemonclient.V1, cfg *config.DfgetConfig, w io.Writer) error {
+	hdr, err := newHeader(cfg)
+	if err != nil {
+		return err
+	}
+
+	request := newDownRequest(cfg, hdr)
+	fmt.Fprintf(w, "url: %s\n", request.Url)
+	fmt.Fprintf(w, "task id: %s\n", idgen.TaskIDV1(request.Url, request.UrlMeta))
+
+	contentLength := int64(-1)
+	sourceRequest, err := source.NewRequestWithContext(ctx, request.Url, hdr)
+	if err == nil {
+		contentLength, err = source.GetContentLength(sourceRequest)
+	}
+
+	if err != nil {
+		fmt.Fprintf(w, "content length: unknown, %s\n", err.Error())
+	} else {
+		fmt.Fprintf(w, "content length: %d\n", contentLength)
+	}
+
+	if client == nil {
+		fmt.Fprintln(w, "daemon: unavailable")
+		if cfg.DisableBackSource {
+			fmt.Fprintln(w, "plan: fail, daemon is unavailable and back source is disabled")
+			return nil
+		}
+
+		fmt.Fprintln(w, "plan: download from source")
+		return nil
+	}
+
+	statError := client.StatTask(ctx, &dfdaemonv1.StatTaskRequest{
+		Url:     request.Url,
+		UrlMeta: request.UrlMeta,
+	})
+	switch {
+	case statError == nil:
+		fmt.Fprintln(w, "p2p: available")
+		fmt.Fprintln(w, "plan: download from p2p network")
+	case dferrors.CheckError(statError, commonv1.Code_PeerTaskNotFound):
+		fmt.Fprintf(w, "p2p: not available, %s\n", statError.Error())
+		fmt.Fprintln(w, "plan: download by daemon, scheduler decides the parents or back-to-source")
+	default:
+		return fmt.Errorf("daemon stat task error: %w", statError)
+	}
+
+	return nil
+}
+
 func downloadFromSource(ctx context.Context, cfg *config.DfgetConfig, hdr map[string]string) (err error) {
 	if cfg.DisableBackSource {
 		return errors.New("try to download from source but back source is disabled")
@@ -160,7 +240,7 @@ func downloadFromSource(ctx context.Context, cfg *config.DfgetConfig, hdr map[st
 	}
 	defer func() {
 		if !renameOK {
-			tempPath := path.Join(filepath.Dir(cfg.Output), tempFile.Name())
+			tempPath := tempFile.Name()
 			removeErr := os.Remove(tempPath)
 			if removeErr != nil {
 				wLog.Infof("remove temporary file %s error: %s", tempPath, removeErr)
@@ -192,38 +272,98 @@ func downloadFromSource(ctx context.Context, cfg *config.DfgetConfig, hdr map[st
 		return err
 	}
 
-	if !pkgstrings.IsBlank(cfg.Digest) {
-		d, err := digest.Parse(cfg.Digest)
-		if err != nil {
-			return err
-		}
-
-		encoded, err := digest.HashFile(tempFile.Name(), d.Algorithm)
-		if err != nil {
-			return err
-		}
-
-		if encoded != "" && encoded != d.Encoded {
-			return fmt.Errorf("%s digest is not matched: real[%s] expected[%s]", d.Algorithm, encoded, d.Encoded)
-		}
-	}
-
 	// change file owner
 	if err = os.Chown(tempFile.Name(), os.Getuid(), os.Getgid()); err != nil {
 		return fmt.Errorf("change file owner to uid[%d] gid[%d]: %w", os.Getuid(), os.Getgid(), err)
 	}
 
+	// The corrupted file is renamed to the output only if it is kept,
+	// otherwise it is removed with the temporary file.
+	var verifyErr error
+	if !pkgstrings.IsBlank(cfg.Digest) && !cfg.SkipVerify {
+		verifyErr = verifyDigest(tempFile.Name(), cfg.Digest, cfg.ShowProgress)
+		if verifyErr != nil && !(errors.Is(verifyErr, ErrDigestNotMatched) && cfg.KeepCorrupted) {
+			return verifyErr
+		}
+	}
+
 	if err = os.Rename(tempFile.Name(), cfg.Output); err != nil {
 		return err
 	}
 	renameOK = true
 
+	if verifyErr != nil {
+		return verifyErr
+	}
+
 	wLog.Infof("download from source success, length: %d bytes, cost: %d ms", written, time.Since(start).Milliseconds())
 	fmt.Printf("finish total length %d bytes\n", written)
 
 	return nil
 }
 
+// verifyOutput verifies the digest of the downloaded file if the digest is provided, the file is removed
+// when its digest is not matched unless KeepCorrupted is set.
+func verifyOutput(cfg *config.DfgetConfig, name string) error {
+	if pkgstrings.IsBlank(cfg.Digest) || cfg.SkipVerify {
+		return nil
+	}
+
+	if err := verifyDigest(name, cfg.Digest, cfg.ShowProgress); err != nil {
+		if errors.Is(err, ErrDigestNotMatched) && !cfg.KeepCorrupted {
+			if removeErr := os.Remove(name); removeErr != nil {
+				logger.Errorf("remove corrupted file %s error: %s", name, removeErr)
+			}
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+// verifyDigest reads the whole file and verifies its digest, in format of md5:xxx or sha256:yyy.
+// The progress of verification is shown for the very large files if showProgress is set.
+func verifyDigest(name string, expected string, showProgress bool) error {
+	d, err := digest.Parse(expected)
+	if err != nil {
+		return err
+	}
+
+	h, err := digest.NewHash(d.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var w io.Writer = h
+	if showProgress {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		pb := progressbar.DefaultBytes(info.Size(), "Verifying")
+		defer pb.Close()
+		w = io.MultiWriter(h, pb)
+	}
+
+	if _, err := io.Copy(w, bufio.NewReader(f)); err != nil {
+		return err
+	}
+
+	if encoded := hex.EncodeToString(h.Sum(nil)); encoded != d.Encoded {
+		return fmt.Errorf("%w, %s real[%s] expected[%s]", ErrDigestNotMatched, d.Algorithm, encoded, d.Encoded)
+	}
+
+	return nil
+}
+
 func parseHeader(s []string) map[string]string {
 	hdr := make(map[string]string)
 	var key, value string
@@ -239,6 +379,28 @@ func parseHeader(s []string) map[string]string {
 	return hdr
 }
 
+// newHeader returns the header of the task, the client certificate is set
+// to the header for the source which requires client certificate.
+func newHeader(cfg *config.DfgetConfig) (map[string]string, error) {
+	hdr := parseHeader(cfg.Header)
+	if cfg.ClientCert == "" {
+		return hdr, nil
+	}
+
+	certPEM, err := os.ReadFile(cfg.ClientCert)
+	if err != nil {
+		return nil, fmt.Errorf("read client certificate: %w", err)
+	}
+
+	keyPEM, err := os.ReadFile(cfg.ClientKey)
+	if err != nil {
+		return nil, fmt.Errorf("read client key: %w", err)
+	}
+
+	source.SetClientCertificate(hdr, certPEM, keyPEM)
+	return hdr, nil
+}
+
 func newDownRequest(cfg *config.DfgetConfig, hdr map[string]string) *dfdaemonv1.DownRequest {
 	var rg string
 	if r, ok := hdr[headers.Range]; ok {
@@ -246,6 +408,26 @@ func newDownRequest(cfg *config.DfgetConfig, hdr map[string]string) *dfdaemonv1.
 	} else {
 		rg = cfg.Range
 	}
+
+	// The dragonfly only headers are added to the copy of the header,
+	// the header is still used by downloading from source directly.
+	if cfg.PreferSeed || len(cfg.IdentityHeaders) > 0 {
+		h := make(map[string]string, len(hdr)+2)
+		for k, v := range hdr {
+			h[k] = v
+		}
+
+		if cfg.PreferSeed {
+			h[nethttp.HeaderPreferSeed] = strconv.FormatBool(true)
+		}
+
+		if len(cfg.IdentityHeaders) > 0 {
+			h[nethttp.HeaderIdentityHeaders] = strings.Join(cfg.IdentityHeaders, idgen.IdentityHeadersSeparator)
+		}
+
+		hdr = h
+	}
+
 	request := &dfdaemonv1.DownRequest{
 		Url:               cfg.URL,
 		Output:            cfg.Output,
@@ -335,7 +517,12 @@ func recursiveDownload(ctx context.Context, client dfdaemonclient.V1, cfg *confi
 			}
 			parentCfg.RecursiveLevel--
 		}
-		request, err := source.NewRequestWithContext(ctx, parentCfg.URL, parseHeader(parentCfg.Header))
+		hdr, err := newHeader(parentCfg)
+		if err != nil {
+			return err
+		}
+
+		request, err := source.NewRequestWithContext(ctx, parentCfg.URL, hdr)
 		if err != nil {
 			return err
 		}
@@ -17,7 +17,10 @@
 package dfget
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
 	"io"
 	"os"
 	"path/filepath"
@@ -29,8 +32,17 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/mock/gomock"
 
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+	dfdaemonv1 "d7y.io/api/v2/pkg/apis/dfdaemon/v1"
+	dfdaemonv1mocks "d7y.io/api/v2/pkg/apis/dfdaemon/v1/mocks"
+
 	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/internal/dferrors"
+	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/digest"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
+	dfdaemonmocks "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client/mocks"
 	"d7y.io/dragonfly/v2/pkg/source"
 	"d7y.io/dragonfly/v2/pkg/source/mocks"
 )
@@ -61,6 +73,323 @@ func Test_downloadFromSource(t *testing.T) {
 	assert.Nil(t, err)
 }
 
+func Test_downloadFromSourceWithCorruptedContent(t *testing.T) {
+	content := uuid.New().String()
+	tests := []struct {
+		name   string
+		cfg    *config.DfgetConfig
+		expect func(t *testing.T, output string, err error)
+	}{
+		{
+			name: "corrupted file is removed",
+			cfg:  &config.DfgetConfig{},
+			expect: func(t *testing.T, output string, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrDigestNotMatched)
+				assert.NoFileExists(output)
+
+				entries, err := os.ReadDir(filepath.Dir(output))
+				assert.NoError(err)
+				assert.Empty(entries)
+			},
+		},
+		{
+			name: "corrupted file is kept",
+			cfg:  &config.DfgetConfig{KeepCorrupted: true},
+			expect: func(t *testing.T, output string, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrDigestNotMatched)
+				data, err := os.ReadFile(output)
+				assert.NoError(err)
+				assert.Equal(content, string(data))
+			},
+		},
+		{
+			name: "skip verify",
+			cfg:  &config.DfgetConfig{SkipVerify: true},
+			expect: func(t *testing.T, output string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.FileExists(output)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			sourceClient := mocks.NewMockResourceClient(ctl)
+			require.Nil(t, source.Register("http", sourceClient, func(request *source.Request) *source.Request {
+				return request
+			}))
+			defer source.UnRegister("http")
+
+			tc.cfg.URL = "http://a.b.c/xx"
+			tc.cfg.Output = filepath.Join(t.TempDir(), "output")
+			tc.cfg.Digest = strings.Join([]string{digest.AlgorithmSHA256, digest.SHA256FromStrings("corrupted")}, ":")
+			sourceClient.EXPECT().Download(gomock.Any()).Return(source.NewResponse(io.NopCloser(strings.NewReader(content))), nil).Times(1)
+
+			tc.expect(t, tc.cfg.Output, downloadFromSource(context.Background(), tc.cfg, nil))
+		})
+	}
+}
+
+func Test_singleDownload(t *testing.T) {
+	content := uuid.New().String()
+	tests := []struct {
+		name   string
+		cfg    *config.DfgetConfig
+		data   string
+		expect func(t *testing.T, output string, err error)
+	}{
+		{
+			name: "digest is matched",
+			cfg:  &config.DfgetConfig{Digest: strings.Join([]string{digest.AlgorithmSHA256, digest.SHA256FromStrings(content)}, ":")},
+			data: content,
+			expect: func(t *testing.T, output string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.FileExists(output)
+			},
+		},
+		{
+			name: "corrupted file is removed without back-to-source",
+			cfg:  &config.DfgetConfig{Digest: strings.Join([]string{digest.AlgorithmMD5, digest.MD5FromBytes([]byte(content))}, ":")},
+			data: "corrupted",
+			expect: func(t *testing.T, output string, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrDigestNotMatched)
+				assert.NoFileExists(output)
+			},
+		},
+		{
+			name: "corrupted file is kept",
+			cfg: &config.DfgetConfig{
+				Digest:        strings.Join([]string{digest.AlgorithmSHA256, digest.SHA256FromStrings(content)}, ":"),
+				KeepCorrupted: true,
+			},
+			data: "corrupted",
+			expect: func(t *testing.T, output string, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrDigestNotMatched)
+				data, err := os.ReadFile(output)
+				assert.NoError(err)
+				assert.Equal("corrupted", string(data))
+			},
+		},
+		{
+			name: "skip verify",
+			cfg: &config.DfgetConfig{
+				Digest:     strings.Join([]string{digest.AlgorithmSHA256, digest.SHA256FromStrings(content)}, ":"),
+				SkipVerify: true,
+			},
+			data: "corrupted",
+			expect: func(t *testing.T, output string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.FileExists(output)
+			},
+		},
+		{
+			name: "digest is not provided",
+			cfg:  &config.DfgetConfig{},
+			data: "corrupted",
+			expect: func(t *testing.T, output string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.FileExists(output)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			daemon := dfdaemonmocks.NewMockV1(ctl)
+			stream := dfdaemonv1mocks.NewMockDaemon_DownloadClient(ctl)
+
+			tc.cfg.URL = "http://a.b.c/xx"
+			tc.cfg.Output = filepath.Join(t.TempDir(), "output")
+			gomock.InOrder(
+				daemon.EXPECT().Download(gomock.Any(), gomock.Any()).Return(stream, nil).Times(1),
+				stream.EXPECT().Recv().DoAndReturn(func() (*dfdaemonv1.DownResult, error) {
+					// The daemon writes the output file before it reports done.
+					if err := os.WriteFile(tc.cfg.Output, []byte(tc.data), 0600); err != nil {
+						return nil, err
+					}
+
+					return &dfdaemonv1.DownResult{CompletedLength: uint64(len(tc.data)), Done: true}, nil
+				}).Times(1),
+			)
+
+			tc.expect(t, tc.cfg.Output, singleDownload(context.Background(), daemon, tc.cfg, logger.With("url", tc.cfg.URL)))
+		})
+	}
+}
+
+func Test_verifyDigest(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "foo")
+	require.Nil(t, os.WriteFile(name, []byte("foo"), 0600))
+
+	tests := []struct {
+		name         string
+		path         string
+		digest       string
+		showProgress bool
+		expect       func(t *testing.T, err error)
+	}{
+		{
+			name:   "digest is matched",
+			path:   name,
+			digest: strings.Join([]string{digest.AlgorithmSHA256, digest.SHA256FromStrings("foo")}, ":"),
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:         "digest is matched with progress",
+			path:         name,
+			digest:       strings.Join([]string{digest.AlgorithmMD5, digest.MD5FromBytes([]byte("foo"))}, ":"),
+			showProgress: true,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:   "digest is not matched",
+			path:   name,
+			digest: strings.Join([]string{digest.AlgorithmSHA256, digest.SHA256FromStrings("bar")}, ":"),
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrDigestNotMatched)
+			},
+		},
+		{
+			name:   "digest is invalid",
+			path:   name,
+			digest: "foo",
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+				assert.NotErrorIs(err, ErrDigestNotMatched)
+			},
+		},
+		{
+			name:   "file not found",
+			path:   filepath.Join(filepath.Dir(name), "bar"),
+			digest: strings.Join([]string{digest.AlgorithmSHA256, digest.SHA256FromStrings("foo")}, ":"),
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, os.ErrNotExist)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, verifyDigest(tc.path, tc.digest, tc.showProgress))
+		})
+	}
+}
+
+func Test_dryRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *config.DfgetConfig
+		noDaemon bool
+		mock     func(daemon *dfdaemonmocks.MockV1MockRecorder, sourceClient *mocks.MockResourceClientMockRecorder)
+		expect   func(t *testing.T, out string, err error)
+	}{
+		{
+			name: "task is available in p2p network",
+			cfg:  &config.DfgetConfig{URL: "http://a.b.c/xx"},
+			mock: func(daemon *dfdaemonmocks.MockV1MockRecorder, sourceClient *mocks.MockResourceClientMockRecorder) {
+				sourceClient.GetContentLength(gomock.Any()).Return(int64(1024), nil).Times(1)
+				daemon.StatTask(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				request := newDownRequest(&config.DfgetConfig{URL: "http://a.b.c/xx"}, map[string]string{})
+				assert.Contains(out, "task id: "+idgen.TaskIDV1(request.Url, request.UrlMeta))
+				assert.Contains(out, "content length: 1024")
+				assert.Contains(out, "plan: download from p2p network")
+			},
+		},
+		{
+			name: "task is not available in p2p network",
+			cfg:  &config.DfgetConfig{URL: "http://a.b.c/xx"},
+			mock: func(daemon *dfdaemonmocks.MockV1MockRecorder, sourceClient *mocks.MockResourceClientMockRecorder) {
+				sourceClient.GetContentLength(gomock.Any()).Return(int64(-1), errors.New("foo")).Times(1)
+				daemon.StatTask(gomock.Any(), gomock.Any()).Return(dferrors.New(commonv1.Code_PeerTaskNotFound, "task not found")).Times(1)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Contains(out, "content length: unknown, foo")
+				assert.Contains(out, "p2p: not available")
+				assert.Contains(out, "plan: download by daemon")
+			},
+		},
+		{
+			name: "daemon stats task failed",
+			cfg:  &config.DfgetConfig{URL: "http://a.b.c/xx"},
+			mock: func(daemon *dfdaemonmocks.MockV1MockRecorder, sourceClient *mocks.MockResourceClientMockRecorder) {
+				sourceClient.GetContentLength(gomock.Any()).Return(int64(1024), nil).Times(1)
+				daemon.StatTask(gomock.Any(), gomock.Any()).Return(errors.New("bar")).Times(1)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "daemon stat task error: bar")
+			},
+		},
+		{
+			name:     "daemon is unavailable",
+			cfg:      &config.DfgetConfig{URL: "http://a.b.c/xx", DisableBackSource: true},
+			noDaemon: true,
+			mock: func(daemon *dfdaemonmocks.MockV1MockRecorder, sourceClient *mocks.MockResourceClientMockRecorder) {
+				sourceClient.GetContentLength(gomock.Any()).Return(int64(1024), nil).Times(1)
+			},
+			expect: func(t *testing.T, out string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Contains(out, "daemon: unavailable")
+				assert.Contains(out, "plan: fail, daemon is unavailable and back source is disabled")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			daemon := dfdaemonmocks.NewMockV1(ctl)
+			sourceClient := mocks.NewMockResourceClient(ctl)
+			require.Nil(t, source.Register("http", sourceClient, func(request *source.Request) *source.Request {
+				return request
+			}))
+			defer source.UnRegister("http")
+
+			tc.mock(daemon.EXPECT(), sourceClient.EXPECT())
+
+			var (
+				out bytes.Buffer
+				err error
+			)
+			if tc.noDaemon {
+				err = dryRun(context.Background(), nil, tc.cfg, &out)
+			} else {
+				err = dryRun(context.Background(), daemon, tc.cfg, &out)
+			}
+			tc.expect(t, out.String(), err)
+		})
+	}
+}
+
 func Test_parseHeader(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -88,3 +417,105 @@ func Test_parseHeader(t *testing.T) {
 		})
 	}
 }
+
+func Test_newDownRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    *config.DfgetConfig
+		hdr    map[string]string
+		expect func(t *testing.T, request *dfdaemonv1.DownRequest, hdr map[string]string)
+	}{
+		{
+			name: "new down request with identity headers",
+			cfg: &config.DfgetConfig{
+				URL:             "http://a.b.c/v2/foo/manifests/latest",
+				IdentityHeaders: []string{"Accept", "X-Foo"},
+			},
+			hdr: map[string]string{"Accept": "foo"},
+			expect: func(t *testing.T, request *dfdaemonv1.DownRequest, hdr map[string]string) {
+				assert := assert.New(t)
+				assert.Equal(map[string]string{"Accept": "foo", nethttp.HeaderIdentityHeaders: "Accept,X-Foo"}, request.UrlMeta.Header)
+				assert.Equal(map[string]string{"Accept": "foo"}, hdr)
+				assert.NotEqual(idgen.TaskIDV1(request.Url, &commonv1.UrlMeta{Header: hdr}), idgen.TaskIDV1(request.Url, request.UrlMeta))
+			},
+		},
+		{
+			name: "new down request with seed peer preference",
+			cfg: &config.DfgetConfig{
+				URL:        "http://a.b.c/xx",
+				PreferSeed: true,
+			},
+			expect: func(t *testing.T, request *dfdaemonv1.DownRequest, hdr map[string]string) {
+				assert := assert.New(t)
+				assert.Equal(map[string]string{nethttp.HeaderPreferSeed: "true"}, request.UrlMeta.Header)
+				assert.Nil(hdr)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, newDownRequest(tc.cfg, tc.hdr), tc.hdr)
+		})
+	}
+}
+
+func Test_newHeader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.Nil(t, os.WriteFile(certFile, []byte("foo"), 0600))
+	require.Nil(t, os.WriteFile(keyFile, []byte("bar"), 0600))
+
+	tests := []struct {
+		name   string
+		cfg    *config.DfgetConfig
+		expect func(t *testing.T, hdr map[string]string, err error)
+	}{
+		{
+			name: "header without client certificate",
+			cfg: &config.DfgetConfig{
+				Header: []string{"Accept: *"},
+			},
+			expect: func(t *testing.T, hdr map[string]string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(map[string]string{"Accept": "*"}, hdr)
+			},
+		},
+		{
+			name: "header with client certificate",
+			cfg: &config.DfgetConfig{
+				Header:     []string{"Accept: *"},
+				ClientCert: certFile,
+				ClientKey:  keyFile,
+			},
+			expect: func(t *testing.T, hdr map[string]string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(map[string]string{
+					"Accept":                        "*",
+					nethttp.HeaderClientCertificate: base64.StdEncoding.EncodeToString([]byte("foo")),
+					nethttp.HeaderClientKey:         base64.StdEncoding.EncodeToString([]byte("bar")),
+				}, hdr)
+			},
+		},
+		{
+			name: "client key not found",
+			cfg: &config.DfgetConfig{
+				ClientCert: certFile,
+				ClientKey:  filepath.Join(dir, "foo.pem"),
+			},
+			expect: func(t *testing.T, hdr map[string]string, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, os.ErrNotExist)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			hdr, err := newHeader(tc.cfg)
+			tc.expect(t, hdr, err)
+		})
+	}
+}
@@ -433,6 +433,10 @@ type PutObjectInput struct {
 	// replicas of an object cache in seed peers.
 	MaxReplicas int
 
+	// Digest is the expected digest of object in format of algorithm:encoded,
+	// the upload is rejected if the content does not match it.
+	Digest string
+
 	// Reader is reader of object.
 	Reader io.Reader
 }
@@ -484,6 +488,12 @@ func (dfs *dfstore) PutObjectRequestWithContext(ctx context.Context, input *PutO
 		}
 	}
 
+	if input.Digest != "" {
+		if err := writer.WriteField("digest", input.Digest); err != nil {
+			return nil, err
+		}
+	}
+
 	part, err := writer.CreateFormFile("file", filepath.Base(input.ObjectKey))
 	if err != nil {
 		return nil, err
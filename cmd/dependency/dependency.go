@@ -44,7 +44,6 @@ import (
 	"gopkg.in/yaml.v3"
 
 	"d7y.io/dragonfly/v2/client/config"
-	"d7y.io/dragonfly/v2/client/util"
 	"d7y.io/dragonfly/v2/cmd/dependency/base"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/dfnet"
@@ -189,11 +188,26 @@ func initConfig(useConfigFile bool, name string, config any) {
 	}
 }
 
+// SecretResolver is implemented by the config which references the secrets by
+// ${ENV_VAR} or file://, the secrets are re-read every time the config is loaded.
+type SecretResolver interface {
+	ResolveSecrets() error
+}
+
 func LoadConfig(config any) error {
 	if err := viper.ReadInConfig(); err != nil {
 		return err
 	}
-	return viper.Unmarshal(config, initDecoderConfig)
+
+	if err := viper.Unmarshal(config, initDecoderConfig); err != nil {
+		return err
+	}
+
+	if resolver, ok := config.(SecretResolver); ok {
+		return resolver.ResolveSecrets()
+	}
+
+	return nil
 }
 
 func WatchConfig(interval time.Duration, newConfig func() (cfg any), watcher func(cfg any)) {
@@ -236,8 +250,8 @@ func initDecoderConfig(dc *mapstructure.DecoderConfig) {
 		switch to {
 		case reflect.TypeOf(unit.B),
 			reflect.TypeOf(dfnet.NetAddr{}),
-			reflect.TypeOf(util.RateLimit{}),
-			reflect.TypeOf(util.Duration{}),
+			reflect.TypeOf(types.RateLimit{}),
+			reflect.TypeOf(types.Duration{}),
 			reflect.TypeOf(&config.ProxyOption{}),
 			reflect.TypeOf(config.TCPListenPortRange{}),
 			reflect.TypeOf(types.PEMContent("")),
@@ -253,6 +267,20 @@ func initDecoderConfig(dc *mapstructure.DecoderConfig) {
 			}
 
 			return p.Interface(), nil
+		case reflect.TypeOf(time.Duration(0)):
+			// The duration strings are parsed in the syntax of types.Duration,
+			// it accepts the integer of nanoseconds besides the go duration string.
+			s, ok := v.(string)
+			if !ok {
+				return v, nil
+			}
+
+			var d types.Duration
+			if err := d.Set(s); err != nil {
+				return nil, err
+			}
+
+			return d.Duration, nil
 		default:
 			return v, nil
 		}
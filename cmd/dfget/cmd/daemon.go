@@ -99,7 +99,11 @@ func init() {
 		flags := daemonCmd.Flags()
 		flags.Int("launcher", -1, "pid of process launching daemon, a negative number implies that the daemon is started directly by the user")
 		flags.Lookup("launcher").Hidden = true
+		flags.String("metrics-addr", "", "address to serve prometheus metrics on, e.g. :8000, disabled when empty")
+		flags.String("ready-file", "", "path of the file created once the daemon is ready to serve and removed on shutdown, disabled when empty")
 		_ = viper.BindPFlags(flags)
+		_ = viper.BindPFlag("metrics", flags.Lookup("metrics-addr"))
+		_ = viper.BindPFlag("readyFile", flags.Lookup("ready-file"))
 	}
 }
 
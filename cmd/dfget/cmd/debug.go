@@ -0,0 +1,125 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	debugScheduler string
+	debugToken     string
+	debugTimeout   time.Duration
+)
+
+// debugCmd represents the debug command
+var debugCmd = &cobra.Command{
+	Use:   "debug",
+	Short: "inspect the scheduler's current view of a peer or host",
+	Long: `debug queries the debug service of scheduler and prints the current state of a peer
+or host in JSON, the debug service of scheduler must be enabled.`,
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+}
+
+// debugPeerCmd represents the debug peer command
+var debugPeerCmd = &cobra.Command{
+	Use:               "peer <peerID>",
+	Short:             "print the state, parents, children and recent scheduling decisions of the peer",
+	Args:              cobra.ExactArgs(1),
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDebug(cmd.Context(), "/debug/peers/"+url.PathEscape(args[0]))
+	},
+}
+
+// debugHostCmd represents the debug host command
+var debugHostCmd = &cobra.Command{
+	Use:               "host <hostID>",
+	Short:             "print the upload counts and limits of the host and all peers on the host",
+	Args:              cobra.ExactArgs(1),
+	DisableAutoGenTag: true,
+	SilenceUsage:      true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDebug(cmd.Context(), "/debug/hosts/"+url.PathEscape(args[0]))
+	},
+}
+
+func init() {
+	// Add the command to parent
+	rootCmd.AddCommand(debugCmd)
+	debugCmd.AddCommand(debugPeerCmd)
+	debugCmd.AddCommand(debugHostCmd)
+
+	flags := debugCmd.PersistentFlags()
+	flags.StringVar(&debugScheduler, "scheduler", "127.0.0.1:8004", "address of the scheduler debug service")
+	flags.StringVar(&debugToken, "token", "", "token of the scheduler debug service, required if the request is not from the scheduler host")
+	flags.DurationVar(&debugTimeout, "timeout", 10*time.Second, "timeout of the request to the scheduler debug service")
+}
+
+// runDebug requests the scheduler debug service and prints the indented response.
+func runDebug(ctx context.Context, path string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, debugTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("http://%s%s", debugScheduler, path), nil)
+	if err != nil {
+		return err
+	}
+
+	if debugToken != "" {
+		req.Header.Set("Authorization", "Bearer "+debugToken)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("scheduler debug service returns %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		return err
+	}
+
+	_, err = out.WriteTo(os.Stdout)
+	return err
+}
@@ -42,10 +42,13 @@ import (
 	"d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
 	"d7y.io/dragonfly/v2/pkg/source"
 	_ "d7y.io/dragonfly/v2/pkg/source/loader" // register all source clients
-	"d7y.io/dragonfly/v2/pkg/unit"
 	"d7y.io/dragonfly/v2/version"
 )
 
+// exitCodeDigestNotMatched is the exit code when the digest of the downloaded file is not matched,
+// it distinguishes the corrupted file from the other failures.
+const exitCodeDigestNotMatched = 3
+
 var (
 	dfgetConfig *config.DfgetConfig
 )
@@ -104,7 +107,7 @@ var rootCmd = &cobra.Command{
 		fmt.Printf("output path: %s\n", dfgetConfig.Output)
 
 		// do get file
-		err = runDfget(cmd, d.DfgetLockPath(), d.DaemonSockPath())
+		err = runDfget(cmd, d.DfgetLockPath(), d.DaemonSockPath(), d.DaemonReadyPath())
 		if err != nil {
 			msg := fmt.Sprintf("download success: %t, cost: %d ms error: %s", false, time.Since(start).Milliseconds(), err.Error())
 			logger.With("url", dfgetConfig.URL).Info(msg)
@@ -124,6 +127,10 @@ var rootCmd = &cobra.Command{
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		logger.Error(err)
+		if errors.Is(err, dfget.ErrDigestNotMatched) {
+			os.Exit(exitCodeDigestNotMatched)
+		}
+
 		os.Exit(1)
 	}
 }
@@ -143,14 +150,21 @@ func init() {
 	flagSet.StringP("output", "O", dfgetConfig.Output,
 		"Destination path which is used to store the downloaded file, it must be a full path")
 
-	flagSet.Duration("timeout", dfgetConfig.Timeout, "Timeout for the downloading task, 0 is infinite")
+	flagSet.String("timeout", dfgetConfig.Timeout.String(),
+		"Timeout for the downloading task in format of 30s or 1h30m, pure number will be parsed as nanoseconds, 0 is infinite")
 
-	flagSet.String("ratelimit", unit.Bytes(dfgetConfig.RateLimit.Limit).String(),
-		"The downloading network bandwidth limit per second in format of G(B)/g/M(B)/m/K(B)/k/B, pure number will be parsed as Byte, 0 is infinite")
+	flagSet.String("ratelimit", dfgetConfig.RateLimit.String(),
+		"The downloading network bandwidth limit per second in format of 100MiB, 100MB, 100M or 1.5G, the units are powers of 1024, pure number will be parsed as Byte, 0 is infinite")
 
 	flagSet.String("digest", dfgetConfig.Digest,
 		"Check the integrity of the downloaded file with digest, in format of md5:xxx or sha256:yyy")
 
+	flagSet.Bool("skip-verify", dfgetConfig.SkipVerify,
+		"Skip verifying the digest of the downloaded file, the verification reads the whole file again which may be slow for very large files")
+
+	flagSet.Bool("keep-corrupted", dfgetConfig.KeepCorrupted,
+		"Keep the downloaded file when its digest is not matched, the file is removed by default")
+
 	flagSet.String("tag", dfgetConfig.Tag,
 		"Different tags for the same url will be divided into different P2P overlay, it conflicts with --digest")
 
@@ -160,9 +174,15 @@ func init() {
 
 	flagSet.StringSliceP("header", "H", dfgetConfig.Header, "url header, eg: --header='Accept: *' --header='Host: abc'")
 
+	flagSet.StringSlice("identity-header", dfgetConfig.IdentityHeaders,
+		"The values of the url headers are folded into the task id, P2P overlay is different if the values are different, eg: --identity-header=Accept")
+
 	flagSet.Bool("disable-back-source", dfgetConfig.DisableBackSource,
 		"Disable downloading directly from source when the daemon fails to download file")
 
+	flagSet.Bool("prefer-seed", dfgetConfig.PreferSeed,
+		"Prefer seed peers to normal peers as parents, for the low latency of the first byte")
+
 	flagSet.Int32P("priority", "P", dfgetConfig.Priority, "Scheduler will schedule task according to priority")
 
 	flagSet.BoolP("show-progress", "b", dfgetConfig.ShowProgress, "Show progress bar, it conflicts with --console")
@@ -200,6 +220,14 @@ func init() {
 	flagSet.String("range", dfgetConfig.Range,
 		`Download range. Like: 0-9, stands download 10 bytes from 0 -9, [0:9] in real url`)
 
+	flagSet.String("client-cert", dfgetConfig.ClientCert,
+		"PEM encoded client certificate file for the source which requires client certificate, it is only used for the requests of this task")
+
+	flagSet.String("client-key", dfgetConfig.ClientKey, "PEM encoded private key file of the client certificate")
+
+	flagSet.Bool("dry-run", dfgetConfig.DryRun,
+		"Resolve the task of the url and print the download plan, including task id, content length and whether the task is available in P2P network, without downloading")
+
 	// Bind cmd flags
 	if err := viper.BindPFlags(flagSet); err != nil {
 		panic(fmt.Errorf("bind dfget flags to viper: %w", err))
@@ -232,7 +260,7 @@ func initDfgetDfpath(cfg *config.ClientOption) (dfpath.Dfpath, error) {
 }
 
 // runDfget does some init operations and starts to download.
-func runDfget(cmd *cobra.Command, dfgetLockPath, daemonSockPath string) error {
+func runDfget(cmd *cobra.Command, dfgetLockPath, daemonSockPath, daemonReadyPath string) error {
 	logger.Infof("version:\n%s", version.Version())
 
 	ff := dependency.InitMonitor(dfgetConfig.PProfPort, dfgetConfig.Telemetry)
@@ -248,7 +276,7 @@ func runDfget(cmd *cobra.Command, dfgetLockPath, daemonSockPath string) error {
 	}
 
 	logger.Info("start to check and spawn daemon")
-	if dfdaemonClient, err = checkAndSpawnDaemon(dfgetLockPath, daemonSockPath); err != nil {
+	if dfdaemonClient, err = checkAndSpawnDaemon(dfgetLockPath, daemonSockPath, daemonReadyPath); err != nil {
 		logger.Errorf("check and spawn daemon error: %v", err)
 	} else {
 		logger.Info("check and spawn daemon success")
@@ -281,7 +309,7 @@ func loadSourceClients(cmd *cobra.Command) error {
 }
 
 // checkAndSpawnDaemon do checking at three checkpoints
-func checkAndSpawnDaemon(dfgetLockPath, daemonSockPath string) (client.V1, error) {
+func checkAndSpawnDaemon(dfgetLockPath, daemonSockPath, daemonReadyPath string) (client.V1, error) {
 	netAddr := &dfnet.NetAddr{Type: dfnet.UNIX, Addr: daemonSockPath}
 	dfdaemonClient, err := client.GetInsecureV1(context.Background(), netAddr.String())
 	if err != nil {
@@ -310,7 +338,13 @@ func checkAndSpawnDaemon(dfgetLockPath, daemonSockPath string) (client.V1, error
 		return dfdaemonClient, nil
 	}
 
-	cmd := exec.Command(os.Args[0], "daemon", "--launcher", strconv.Itoa(os.Getpid()), "--config", viper.GetString("config"))
+	// Remove the stale ready file left by the daemon which exited unexpectedly.
+	if err := os.Remove(daemonReadyPath); err != nil && !os.IsNotExist(err) {
+		logger.Warnf("remove stale ready file failed %s", err)
+	}
+
+	cmd := exec.Command(os.Args[0], "daemon", "--launcher", strconv.Itoa(os.Getpid()), "--config", viper.GetString("config"),
+		"--ready-file", daemonReadyPath)
 	cmd.Stdin = nil
 	cmd.Stdout = nil
 	cmd.Stderr = nil
@@ -323,7 +357,8 @@ func checkAndSpawnDaemon(dfgetLockPath, daemonSockPath string) (client.V1, error
 		return nil, err
 	}
 
-	// 3. check health with at least 5s timeout
+	// 3. wait for the ready file with at least 5s timeout, the daemon creates it
+	// after the unix socket is listening and the scheduler is connected.
 	tick := time.NewTicker(50 * time.Millisecond)
 	defer tick.Stop()
 	timeout := time.After(5 * time.Second)
@@ -331,10 +366,17 @@ func checkAndSpawnDaemon(dfgetLockPath, daemonSockPath string) (client.V1, error
 	for {
 		select {
 		case <-timeout:
+			// The daemon may be serving but not connected to the scheduler yet,
+			// it still can download from source.
+			if err = dfdaemonClient.CheckHealth(context.Background()); err == nil {
+				logger.Warnf("daemon is not ready in time, continue with the healthy daemon")
+				return dfdaemonClient, nil
+			}
+
 			return nil, errors.Join(errors.New("the daemon is unhealthy"), err)
 		case <-tick.C:
-			if err = dfdaemonClient.CheckHealth(context.Background()); err != nil {
-				logger.Debugf("check health failed: %s", err)
+			if _, err = os.Stat(daemonReadyPath); err != nil {
+				logger.Debugf("check ready file failed: %s", err)
 				continue
 			}
 			return dfdaemonClient, nil
@@ -47,6 +47,11 @@ generate and maintain a P2P network during the download process, and push suitab
 	DisableAutoGenTag: true,
 	SilenceUsage:      true,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		// Resolve secrets of config.
+		if err := cfg.ResolveSecrets(); err != nil {
+			return err
+		}
+
 		// Convert config.
 		if err := cfg.Convert(); err != nil {
 			return err
@@ -126,6 +131,10 @@ func initDfpath(cfg *config.ServerConfig) (dfpath.Dfpath, error) {
 func runScheduler(ctx context.Context, d dfpath.Dfpath) error {
 	logger.Infof("version:\n%s", version.Version())
 
+	if dump, err := cfg.Dump(); err == nil {
+		logger.Debugf("config:\n%s", dump)
+	}
+
 	ff := dependency.InitMonitor(cfg.PProfPort, cfg.Telemetry)
 	defer ff()
 
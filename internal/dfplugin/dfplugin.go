@@ -19,6 +19,8 @@ package dfplugin
 import (
 	"errors"
 	"fmt"
+	"io"
+	"os"
 	"path"
 	"plugin"
 	"regexp"
@@ -38,7 +40,7 @@ const (
 	PluginMetaKeyName = "name"
 )
 
-var PluginFormatExpr = regexp.MustCompile("d7y-(resource|manager|scheduler)-plugin-([a-z0-9]+).so")
+var PluginFormatExpr = regexp.MustCompile("d7y-(resource|manager|scheduler|header)-plugin-([a-z0-9]+).so")
 
 type PluginType string
 
@@ -46,6 +48,7 @@ const (
 	PluginTypeResource  = PluginType("resource")
 	PluginTypeManager   = PluginType("manager")
 	PluginTypeScheduler = PluginType("scheduler")
+	PluginTypeHeader    = PluginType("header")
 )
 
 type PluginInitFunc func(option map[string]string) (plugin any, meta map[string]string, err error)
@@ -86,3 +89,44 @@ func Load(dir string, typ PluginType, name string, option map[string]string) (an
 	}
 	return i, meta, nil
 }
+
+// Reload loads the plugin from the copy of the plugin file, because the go runtime caches
+// the opened plugins by the file path and opening the same path again returns the plugin
+// loaded first. The plugin must be rebuilt from the changed source, otherwise the go runtime
+// refuses to load it because it has the same plugin path as the plugin loaded before.
+func Reload(dir string, typ PluginType, name string, option map[string]string) (any, map[string]string, error) {
+	copyDir, err := os.MkdirTemp("", fmt.Sprintf("d7y-%s-plugin-%s-*", string(typ), name))
+	if err != nil {
+		return nil, nil, err
+	}
+	// The opened plugin is mapped into memory, the copy can be removed after it is loaded.
+	defer os.RemoveAll(copyDir)
+
+	soName := fmt.Sprintf(PluginFormat, string(typ), name)
+	if err := copyFile(path.Join(dir, soName), path.Join(copyDir, soName)); err != nil {
+		return nil, nil, err
+	}
+
+	return Load(copyDir, typ, name, option)
+}
+
+// copyFile copies the file from src to dst.
+func copyFile(src, dst string) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o700)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(dstFile, srcFile); err != nil {
+		dstFile.Close()
+		return err
+	}
+
+	return dstFile.Close()
+}
@@ -109,7 +109,15 @@ type GroupJobState struct {
 	JobStates []*machineryv1tasks.TaskState
 }
 
+// GetGroupJobState returns the state of the group job, the group job fails once any job fails.
 func (t *Job) GetGroupJobState(groupID string) (*GroupJobState, error) {
+	return t.GetGroupJobStateWithFailureThreshold(groupID, 0)
+}
+
+// GetGroupJobStateWithFailureThreshold returns the state of the group job, the group job
+// fails once the ratio of the failed jobs exceeds failureThreshold, e.g. some of the
+// scheduler clusters are unavailable.
+func (t *Job) GetGroupJobStateWithFailureThreshold(groupID string, failureThreshold float64) (*GroupJobState, error) {
 	taskStates, err := t.Server.GetBackend().GroupTaskStates(groupID, 0)
 	if err != nil {
 		return nil, err
@@ -119,21 +127,31 @@ func (t *Job) GetGroupJobState(groupID string) (*GroupJobState, error) {
 		return nil, errors.New("empty group")
 	}
 
+	return newGroupJobState(groupID, taskStates, failureThreshold), nil
+}
+
+// newGroupJobState returns the state of the group job by the states of its jobs.
+func newGroupJobState(groupID string, taskStates []*machineryv1tasks.TaskState, failureThreshold float64) *GroupJobState {
+	var failed int
 	for _, taskState := range taskStates {
 		if taskState.IsFailure() {
 			logger.WithGroupAndTaskID(groupID, taskState.TaskUUID).Errorf("task is failed: %#v", taskState)
-			return &GroupJobState{
-				GroupUUID: groupID,
-				State:     machineryv1tasks.StateFailure,
-				CreatedAt: taskState.CreatedAt,
-				UpdatedAt: time.Now(),
-				JobStates: taskStates,
-			}, nil
+			failed++
+		}
+	}
+
+	if float64(failed)/float64(len(taskStates)) > failureThreshold {
+		return &GroupJobState{
+			GroupUUID: groupID,
+			State:     machineryv1tasks.StateFailure,
+			CreatedAt: taskStates[0].CreatedAt,
+			UpdatedAt: time.Now(),
+			JobStates: taskStates,
 		}
 	}
 
 	for _, taskState := range taskStates {
-		if !taskState.IsSuccess() {
+		if !taskState.IsSuccess() && !taskState.IsFailure() {
 			logger.WithGroupAndTaskID(groupID, taskState.TaskUUID).Infof("task is not succeeded: %#v", taskState)
 			return &GroupJobState{
 				GroupUUID: groupID,
@@ -141,7 +159,7 @@ func (t *Job) GetGroupJobState(groupID string) (*GroupJobState, error) {
 				CreatedAt: taskState.CreatedAt,
 				UpdatedAt: time.Now(),
 				JobStates: taskStates,
-			}, nil
+			}
 		}
 	}
 
@@ -151,7 +169,7 @@ func (t *Job) GetGroupJobState(groupID string) (*GroupJobState, error) {
 		CreatedAt: taskStates[0].CreatedAt,
 		UpdatedAt: time.Now(),
 		JobStates: taskStates,
-	}, nil
+	}
 }
 
 func MarshalResponse(v any) (string, error) {
@@ -379,3 +379,93 @@ func TestJob_UnmarshalRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestJob_newGroupJobState(t *testing.T) {
+	tests := []struct {
+		name             string
+		taskStates       []*machineryv1tasks.TaskState
+		failureThreshold float64
+		expect           func(t *testing.T, groupJobState *GroupJobState)
+	}{
+		{
+			name: "all tasks succeeded",
+			taskStates: []*machineryv1tasks.TaskState{
+				{TaskUUID: "foo", State: machineryv1tasks.StateSuccess},
+				{TaskUUID: "bar", State: machineryv1tasks.StateSuccess},
+			},
+			expect: func(t *testing.T, groupJobState *GroupJobState) {
+				assert := assert.New(t)
+				assert.Equal(groupJobState.GroupUUID, "baz")
+				assert.Equal(groupJobState.State, machineryv1tasks.StateSuccess)
+				assert.Len(groupJobState.JobStates, 2)
+			},
+		},
+		{
+			name: "task is pending",
+			taskStates: []*machineryv1tasks.TaskState{
+				{TaskUUID: "foo", State: machineryv1tasks.StateSuccess},
+				{TaskUUID: "bar", State: machineryv1tasks.StateStarted},
+			},
+			expect: func(t *testing.T, groupJobState *GroupJobState) {
+				assert := assert.New(t)
+				assert.Equal(groupJobState.State, machineryv1tasks.StatePending)
+			},
+		},
+		{
+			name: "task failed without failure threshold",
+			taskStates: []*machineryv1tasks.TaskState{
+				{TaskUUID: "foo", State: machineryv1tasks.StateFailure},
+				{TaskUUID: "bar", State: machineryv1tasks.StateStarted},
+			},
+			expect: func(t *testing.T, groupJobState *GroupJobState) {
+				assert := assert.New(t)
+				assert.Equal(groupJobState.State, machineryv1tasks.StateFailure)
+			},
+		},
+		{
+			name: "task failed within failure threshold",
+			taskStates: []*machineryv1tasks.TaskState{
+				{TaskUUID: "foo", State: machineryv1tasks.StateFailure},
+				{TaskUUID: "bar", State: machineryv1tasks.StateSuccess},
+				{TaskUUID: "baz", State: machineryv1tasks.StateSuccess},
+			},
+			failureThreshold: 0.5,
+			expect: func(t *testing.T, groupJobState *GroupJobState) {
+				assert := assert.New(t)
+				assert.Equal(groupJobState.State, machineryv1tasks.StateSuccess)
+			},
+		},
+		{
+			name: "task failed within failure threshold and task is pending",
+			taskStates: []*machineryv1tasks.TaskState{
+				{TaskUUID: "foo", State: machineryv1tasks.StateFailure},
+				{TaskUUID: "bar", State: machineryv1tasks.StateSuccess},
+				{TaskUUID: "baz", State: machineryv1tasks.StateReceived},
+			},
+			failureThreshold: 0.5,
+			expect: func(t *testing.T, groupJobState *GroupJobState) {
+				assert := assert.New(t)
+				assert.Equal(groupJobState.State, machineryv1tasks.StatePending)
+			},
+		},
+		{
+			name: "tasks failed exceed failure threshold",
+			taskStates: []*machineryv1tasks.TaskState{
+				{TaskUUID: "foo", State: machineryv1tasks.StateFailure},
+				{TaskUUID: "bar", State: machineryv1tasks.StateFailure},
+				{TaskUUID: "baz", State: machineryv1tasks.StatePending},
+			},
+			failureThreshold: 0.5,
+			expect: func(t *testing.T, groupJobState *GroupJobState) {
+				assert := assert.New(t)
+				assert.Equal(groupJobState.State, machineryv1tasks.StateFailure)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, newGroupJobState("baz", tc.taskStates, tc.failureThreshold))
+		})
+	}
+}
@@ -253,6 +253,10 @@ type RESTConfig struct {
 
 	// TLS server configuration.
 	TLS *TLSServerConfig `yaml:"tls" mapstructure:"tls"`
+
+	// IdempotencyKeyTTL is the ttl of the idempotency keys, the request with
+	// the same idempotency key replays the original response within the ttl.
+	IdempotencyKeyTTL time.Duration `yaml:"idempotencyKeyTTL" mapstructure:"idempotencyKeyTTL"`
 }
 
 type TLSServerConfig struct {
@@ -393,7 +397,8 @@ func New() *Config {
 				},
 			},
 			REST: RESTConfig{
-				Addr: DefaultRESTAddr,
+				Addr:              DefaultRESTAddr,
+				IdempotencyKeyTTL: DefaultRESTIdempotencyKeyTTL,
 			},
 			LogMaxSize:    DefaultLogRotateMaxSize,
 			LogMaxAge:     DefaultLogRotateMaxAge,
@@ -482,6 +487,10 @@ func (cfg *Config) Validate() error {
 		return errors.New("grpc requires parameter listenIP")
 	}
 
+	if cfg.Server.REST.IdempotencyKeyTTL <= 0 {
+		return errors.New("rest requires parameter idempotencyKeyTTL")
+	}
+
 	if cfg.Server.REST.TLS != nil {
 		if cfg.Server.REST.TLS.Cert == "" {
 			return errors.New("tls requires parameter cert")
@@ -130,6 +130,7 @@ func TestConfig_Load(t *testing.T) {
 					Cert: "foo",
 					Key:  "foo",
 				},
+				IdempotencyKeyTTL: 24 * time.Hour,
 			},
 		},
 		Auth: AuthConfig{
@@ -290,6 +291,17 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "grpc requires parameter listenIP")
 			},
 		},
+		{
+			name:   "rest requires parameter idempotencyKeyTTL",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Server.REST.IdempotencyKeyTTL = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "rest requires parameter idempotencyKeyTTL")
+			},
+		},
 		{
 			name:   "rest tls requires parameter cert",
 			config: New(),
@@ -43,6 +43,9 @@ const (
 
 	// DefaultRESTAddr is default address for rest server.
 	DefaultRESTAddr = ":8080"
+
+	// DefaultRESTIdempotencyKeyTTL is default ttl for idempotency keys of rest requests.
+	DefaultRESTIdempotencyKeyTTL = 24 * time.Hour
 )
 
 const (
@@ -39,7 +39,7 @@ import (
 // @Router /clusters [post]
 func (h *Handlers) CreateCluster(ctx *gin.Context) {
 	var json types.CreateClusterRequest
-	if err := ctx.ShouldBindJSON(&json); err != nil {
+	if err := h.shouldBindStrictJSON(ctx, &json); err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
 		return
 	}
@@ -99,7 +99,7 @@ func (h *Handlers) UpdateCluster(ctx *gin.Context) {
 	}
 
 	var json types.UpdateClusterRequest
-	if err := ctx.ShouldBindJSON(&json); err != nil {
+	if err := h.shouldBindStrictJSON(ctx, &json); err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
 		return
 	}
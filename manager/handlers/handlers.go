@@ -17,11 +17,14 @@
 package handlers
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 
 	"d7y.io/dragonfly/v2/manager/service"
 )
@@ -99,3 +102,19 @@ func (h *Handlers) setPaginationLinkHeader(ctx *gin.Context, page, perPage, tota
 
 	ctx.Header("Link", strings.Join(links, ","))
 }
+
+// shouldBindStrictJSON binds the json body like ShouldBindJSON, but the body with unknown
+// fields is rejected, so that a typo in the cluster config is not silently ignored.
+func (h *Handlers) shouldBindStrictJSON(ctx *gin.Context, obj any) error {
+	if ctx.Request == nil || ctx.Request.Body == nil {
+		return errors.New("invalid request")
+	}
+
+	decoder := json.NewDecoder(ctx.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+
+	return binding.Validator.ValidateStruct(obj)
+}
@@ -39,6 +39,17 @@ var (
 			"user_id": 4,
 			"bio": "bio"
 		}`
+	mockPreheatAllPeersJobReqBody = `
+		{
+			"type": "preheat",
+			"user_id": 4,
+			"bio": "bio",
+			"args": {
+				"type": "file",
+				"url": "http://example.com/foo",
+				"scope": "all_peers"
+			}
+		}`
 	mockGetTaskJobReqBody = `
 		{
 			"type": "get_task",
@@ -136,6 +147,15 @@ func TestHandlers_CreateJob(t *testing.T) {
 				assert.Equal(http.StatusUnprocessableEntity, w.Code)
 			},
 		},
+		{
+			name: "unprocessable entity by preheat scope",
+			req:  httptest.NewRequest(http.MethodPost, "/oapi/v1/jobs", strings.NewReader(mockPreheatAllPeersJobReqBody)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
 		{
 			name: "success",
 			req:  httptest.NewRequest(http.MethodPost, "/oapi/v1/jobs", strings.NewReader(mockPreheatJobReqBody)),
@@ -32,9 +32,11 @@ import (
 // @Accept json
 // @Produce json
 // @Param Scheduler body types.CreateSchedulerRequest true "Scheduler"
+// @Param Idempotency-Key header string false "the retried requests with the same key return the original scheduler"
 // @Success 200 {object} models.Scheduler
 // @Failure 400
 // @Failure 404
+// @Failure 409
 // @Failure 500
 // @Router /schedulers [post]
 func (h *Handlers) CreateScheduler(ctx *gin.Context) {
@@ -54,7 +56,7 @@ func (h *Handlers) CreateScheduler(ctx *gin.Context) {
 }
 
 // @Summary Destroy Scheduler
-// @Description Destroy by id
+// @Description Destroy by id, the Scheduler is soft deleted and can be restored
 // @Tags Scheduler
 // @Accept json
 // @Produce json
@@ -141,12 +143,13 @@ func (h *Handlers) GetScheduler(ctx *gin.Context) {
 }
 
 // @Summary Get Schedulers
-// @Description Get Schedulers
+// @Description Get Schedulers, the deleted schedulers are included if include_deleted is true
 // @Tags Scheduler
 // @Accept json
 // @Produce json
 // @Param page query int true "current page" default(0)
 // @Param per_page query int true "return max item count, default 10, max 50" default(10) minimum(2) maximum(50)
+// @Param include_deleted query bool false "include the deleted schedulers" default(false)
 // @Success 200 {object} []models.Scheduler
 // @Failure 400
 // @Failure 404
@@ -169,3 +172,30 @@ func (h *Handlers) GetSchedulers(ctx *gin.Context) {
 	h.setPaginationLinkHeader(ctx, query.Page, query.PerPage, int(count))
 	ctx.JSON(http.StatusOK, schedulers)
 }
+
+// @Summary Restore Scheduler
+// @Description Restore the deleted Scheduler by id
+// @Tags Scheduler
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Success 200 {object} models.Scheduler
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /schedulers/{id}/restore [post]
+func (h *Handlers) RestoreScheduler(ctx *gin.Context) {
+	var params types.SchedulerParams
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		return
+	}
+
+	scheduler, err := h.service.RestoreScheduler(ctx.Request.Context(), params.ID)
+	if err != nil {
+		ctx.Error(err) // nolint: errcheck
+		return
+	}
+
+	ctx.JSON(http.StatusOK, scheduler)
+}
@@ -39,7 +39,7 @@ import (
 // @Router /scheduler-clusters [post]
 func (h *Handlers) CreateSchedulerCluster(ctx *gin.Context) {
 	var json types.CreateSchedulerClusterRequest
-	if err := ctx.ShouldBindJSON(&json); err != nil {
+	if err := h.shouldBindStrictJSON(ctx, &json); err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
 		return
 	}
@@ -99,7 +99,7 @@ func (h *Handlers) UpdateSchedulerCluster(ctx *gin.Context) {
 	}
 
 	var json types.UpdateSchedulerClusterRequest
-	if err := ctx.ShouldBindJSON(&json); err != nil {
+	if err := h.shouldBindStrictJSON(ctx, &json); err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
 		return
 	}
@@ -113,6 +113,40 @@ func (h *Handlers) UpdateSchedulerCluster(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, schedulerCluster)
 }
 
+// @Summary Dry Run Update SchedulerCluster
+// @Description Report the effective config and the changed fields if the SchedulerCluster is updated by json config
+// @Tags SchedulerCluster
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param SchedulerCluster body types.UpdateSchedulerClusterRequest true "SchedulerCluster"
+// @Success 200 {object} types.DryRunUpdateSchedulerClusterResponse
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /scheduler-clusters/{id}/dry-run [post]
+func (h *Handlers) DryRunUpdateSchedulerCluster(ctx *gin.Context) {
+	var params types.SchedulerClusterParams
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		return
+	}
+
+	var json types.UpdateSchedulerClusterRequest
+	if err := h.shouldBindStrictJSON(ctx, &json); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		return
+	}
+
+	resp, err := h.service.DryRunUpdateSchedulerCluster(ctx.Request.Context(), params.ID, json)
+	if err != nil {
+		ctx.Error(err) // nolint: errcheck
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
 // @Summary Get SchedulerCluster
 // @Description Get SchedulerCluster by id
 // @Tags SchedulerCluster
@@ -170,6 +204,43 @@ func (h *Handlers) GetSchedulerClusters(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, schedulerClusters)
 }
 
+// @Summary Get Schedulers of SchedulerCluster
+// @Description Get Schedulers by SchedulerCluster id
+// @Tags SchedulerCluster
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param page query int true "current page" default(0)
+// @Param per_page query int true "return max item count, default 10, max 50" default(10) minimum(2) maximum(50)
+// @Success 200 {object} []models.Scheduler
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /scheduler-clusters/{id}/schedulers [get]
+func (h *Handlers) GetSchedulerClusterSchedulers(ctx *gin.Context) {
+	var params types.SchedulerClusterParams
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		return
+	}
+
+	var query types.GetSchedulerClusterSchedulersQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		return
+	}
+
+	h.setPaginationDefault(&query.Page, &query.PerPage)
+	schedulers, count, err := h.service.GetSchedulerClusterSchedulers(ctx.Request.Context(), params.ID, query)
+	if err != nil {
+		ctx.Error(err) // nolint: errcheck
+		return
+	}
+
+	h.setPaginationLinkHeader(ctx, query.Page, query.PerPage, int(count))
+	ctx.JSON(http.StatusOK, schedulers)
+}
+
 // @Summary Add Scheduler to schedulerCluster
 // @Description Add Scheduler to schedulerCluster
 // @Tags SchedulerCluster
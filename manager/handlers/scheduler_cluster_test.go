@@ -26,7 +26,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
 
+	"d7y.io/dragonfly/v2/manager/middlewares"
 	"d7y.io/dragonfly/v2/manager/models"
 	"d7y.io/dragonfly/v2/manager/service/mocks"
 	"d7y.io/dragonfly/v2/manager/types"
@@ -67,13 +69,16 @@ var (
 
 func mockSchedulerClusterRouter(h *Handlers) *gin.Engine {
 	r := gin.Default()
+	r.Use(middlewares.Error())
 	apiv1 := r.Group("/api/v1")
 	sc := apiv1.Group("/scheduler-clusters")
 	sc.POST("", h.CreateSchedulerCluster)
 	sc.DELETE(":id", h.DestroySchedulerCluster)
 	sc.PATCH(":id", h.UpdateSchedulerCluster)
+	sc.POST(":id/dry-run", h.DryRunUpdateSchedulerCluster)
 	sc.GET(":id", h.GetSchedulerCluster)
 	sc.GET("", h.GetSchedulerClusters)
+	sc.GET(":id/schedulers", h.GetSchedulerClusterSchedulers)
 	sc.PUT(":id/schedulers/:scheduler_id", h.AddSchedulerToSchedulerCluster)
 	return r
 }
@@ -195,6 +200,36 @@ func TestHandlers_UpdateSchedulerCluster(t *testing.T) {
 				assert.Equal(http.StatusUnprocessableEntity, w.Code)
 			},
 		},
+		{
+			name: "unprocessable entity caused by unknown field",
+			req:  httptest.NewRequest(http.MethodPatch, "/api/v1/scheduler-clusters/2", strings.NewReader(`{"config": {"candidate_parent_limt": 1}}`)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+				assert.Contains(w.Body.String(), "unknown field")
+			},
+		},
+		{
+			name: "unprocessable entity caused by invalid type",
+			req:  httptest.NewRequest(http.MethodPatch, "/api/v1/scheduler-clusters/2", strings.NewReader(`{"client_config": {"load_limit": "300"}}`)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+				assert.Contains(w.Body.String(), "load_limit")
+			},
+		},
+		{
+			name: "unprocessable entity caused by out of range",
+			req:  httptest.NewRequest(http.MethodPatch, "/api/v1/scheduler-clusters/2", strings.NewReader(`{"config": {"candidate_parent_limit": 21}}`)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+				assert.Contains(w.Body.String(), "CandidateParentLimit")
+			},
+		},
 		{
 			name: "success",
 			req:  httptest.NewRequest(http.MethodPatch, "/api/v1/scheduler-clusters/2", strings.NewReader(mockSchedulerClusterReqBody)),
@@ -227,6 +262,77 @@ func TestHandlers_UpdateSchedulerCluster(t *testing.T) {
 	}
 }
 
+func TestHandlers_DryRunUpdateSchedulerCluster(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *http.Request
+		mock   func(ms *mocks.MockServiceMockRecorder)
+		expect func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name: "unprocessable entity caused by uri",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/scheduler-clusters/test/dry-run", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
+		{
+			name: "unprocessable entity caused by unknown field",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/scheduler-clusters/2/dry-run", strings.NewReader(`{"config": {"filter_parent_limt": 10}}`)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
+		{
+			name: "success",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/scheduler-clusters/2/dry-run", strings.NewReader(mockSchedulerClusterReqBody)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {
+				ms.DryRunUpdateSchedulerCluster(gomock.Any(), gomock.Eq(uint(2)), gomock.Any()).Return(&types.DryRunUpdateSchedulerClusterResponse{
+					Config:       map[string]any{"candidate_parent_limit": 1, "filter_parent_limit": 10, "version": 3},
+					ClientConfig: map[string]any{"load_limit": 1},
+					Changes: []types.ClusterConfigChange{
+						{Field: "config.candidate_parent_limit", Old: 4, New: 1},
+						{Field: "config.version", Old: 2, New: 3},
+					},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				resp := types.DryRunUpdateSchedulerClusterResponse{}
+				err := json.Unmarshal(w.Body.Bytes(), &resp)
+				assert.NoError(err)
+				assert.Equal(types.DryRunUpdateSchedulerClusterResponse{
+					Config:       map[string]any{"candidate_parent_limit": float64(1), "filter_parent_limit": float64(10), "version": float64(3)},
+					ClientConfig: map[string]any{"load_limit": float64(1)},
+					Changes: []types.ClusterConfigChange{
+						{Field: "config.candidate_parent_limit", Old: float64(4), New: float64(1)},
+						{Field: "config.version", Old: float64(2), New: float64(3)},
+					},
+				}, resp)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			svc := mocks.NewMockService(ctl)
+			w := httptest.NewRecorder()
+			h := New(svc)
+			mockRouter := mockSchedulerClusterRouter(h)
+
+			tc.mock(svc.EXPECT())
+			mockRouter.ServeHTTP(w, tc.req)
+			tc.expect(t, w)
+		})
+	}
+}
+
 func TestHandlers_GetSchedulerCluster(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -326,6 +432,88 @@ func TestHandlers_GetSchedulerClusters(t *testing.T) {
 	}
 }
 
+func TestHandlers_GetSchedulerClusterSchedulers(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *http.Request
+		mock   func(ms *mocks.MockServiceMockRecorder)
+		expect func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name: "unprocessable entity",
+			req:  httptest.NewRequest(http.MethodGet, "/api/v1/scheduler-clusters/2/schedulers?page=-1", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
+		{
+			name: "scheduler cluster not found",
+			req:  httptest.NewRequest(http.MethodGet, "/api/v1/scheduler-clusters/2/schedulers", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {
+				ms.GetSchedulerClusterSchedulers(gomock.Any(), gomock.Eq(uint(2)), gomock.Eq(types.GetSchedulerClusterSchedulersQuery{
+					Page:    1,
+					PerPage: 10,
+				})).Return(nil, int64(0), gorm.ErrRecordNotFound).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotFound, w.Code)
+			},
+		},
+		{
+			name: "scheduler cluster has no schedulers",
+			req:  httptest.NewRequest(http.MethodGet, "/api/v1/scheduler-clusters/2/schedulers", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {
+				ms.GetSchedulerClusterSchedulers(gomock.Any(), gomock.Eq(uint(2)), gomock.Eq(types.GetSchedulerClusterSchedulersQuery{
+					Page:    1,
+					PerPage: 10,
+				})).Return([]models.Scheduler{}, int64(0), nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.Equal("[]", w.Body.String())
+			},
+		},
+		{
+			name: "success",
+			req:  httptest.NewRequest(http.MethodGet, "/api/v1/scheduler-clusters/2/schedulers?page=2&per_page=1", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {
+				ms.GetSchedulerClusterSchedulers(gomock.Any(), gomock.Eq(uint(2)), gomock.Eq(types.GetSchedulerClusterSchedulersQuery{
+					Page:    2,
+					PerPage: 1,
+				})).Return([]models.Scheduler{*mockSchedulerModel}, int64(2), nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				assert.NotEmpty(w.Header().Get("Link"))
+				schedulers := []models.Scheduler{}
+				err := json.Unmarshal(w.Body.Bytes(), &schedulers)
+				assert.NoError(err)
+				assert.Len(schedulers, 1)
+				assert.Equal(mockSchedulerModel.Hostname, schedulers[0].Hostname)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			svc := mocks.NewMockService(ctl)
+			w := httptest.NewRecorder()
+			h := New(svc)
+			mockRouter := mockSchedulerClusterRouter(h)
+
+			tc.mock(svc.EXPECT())
+			mockRouter.ServeHTTP(w, tc.req)
+			tc.expect(t, w)
+		})
+	}
+}
+
 func TestHandlers_AddSchedulerToSchedulerCluster(t *testing.T) {
 	tests := []struct {
 		name   string
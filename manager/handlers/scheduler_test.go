@@ -26,7 +26,9 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	"gorm.io/gorm"
 
+	"d7y.io/dragonfly/v2/manager/middlewares"
 	"d7y.io/dragonfly/v2/manager/models"
 	"d7y.io/dragonfly/v2/manager/service/mocks"
 	"d7y.io/dragonfly/v2/manager/types"
@@ -61,6 +63,7 @@ var (
 
 func mockSchedulerRouter(h *Handlers) *gin.Engine {
 	r := gin.Default()
+	r.Use(middlewares.Error())
 	apiv1 := r.Group("/api/v1")
 	s := apiv1.Group("/schedulers")
 	s.POST("", h.CreateScheduler)
@@ -68,6 +71,7 @@ func mockSchedulerRouter(h *Handlers) *gin.Engine {
 	s.PATCH(":id", h.UpdateScheduler)
 	s.GET(":id", h.GetScheduler)
 	s.GET("", h.GetSchedulers)
+	s.POST(":id/restore", h.RestoreScheduler)
 	return r
 }
 
@@ -302,6 +306,25 @@ func TestHandlers_GetSchedulers(t *testing.T) {
 				assert.Equal(mockSchedulerModel, &scheduler)
 			},
 		},
+		{
+			name: "success with deleted schedulers",
+			req:  httptest.NewRequest(http.MethodGet, "/api/v1/schedulers?include_deleted=true", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {
+				ms.GetSchedulers(gomock.Any(), gomock.Eq(types.GetSchedulersQuery{
+					Page:           1,
+					PerPage:        10,
+					IncludeDeleted: true,
+				})).Return([]models.Scheduler{*mockSchedulerModel}, int64(1), nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				scheduler := models.Scheduler{}
+				err := json.Unmarshal(w.Body.Bytes()[1:w.Body.Len()-1], &scheduler)
+				assert.NoError(err)
+				assert.Equal(mockSchedulerModel, &scheduler)
+			},
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
@@ -318,3 +341,117 @@ func TestHandlers_GetSchedulers(t *testing.T) {
 		})
 	}
 }
+
+func TestHandlers_RestoreScheduler(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *http.Request
+		mock   func(ms *mocks.MockServiceMockRecorder)
+		expect func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name: "unprocessable entity",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/schedulers/test/restore", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
+		{
+			name: "scheduler not found",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/schedulers/2/restore", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {
+				ms.RestoreScheduler(gomock.Any(), gomock.Eq(uint(2))).Return(nil, gorm.ErrRecordNotFound).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusNotFound, w.Code)
+			},
+		},
+		{
+			name: "success",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/schedulers/2/restore", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {
+				ms.RestoreScheduler(gomock.Any(), gomock.Eq(uint(2))).Return(mockSchedulerModel, nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				scheduler := models.Scheduler{}
+				err := json.Unmarshal(w.Body.Bytes(), &scheduler)
+				assert.NoError(err)
+				assert.Equal(mockSchedulerModel, &scheduler)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			svc := mocks.NewMockService(ctl)
+			w := httptest.NewRecorder()
+			h := New(svc)
+			mockRouter := mockSchedulerRouter(h)
+
+			tc.mock(svc.EXPECT())
+			mockRouter.ServeHTTP(w, tc.req)
+			tc.expect(t, w)
+		})
+	}
+}
+
+func TestHandlers_DestroyAndRestoreScheduler(t *testing.T) {
+	assert := assert.New(t)
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	svc := mocks.NewMockService(ctl)
+	h := New(svc)
+	mockRouter := mockSchedulerRouter(h)
+
+	ms := svc.EXPECT()
+	gomock.InOrder(
+		ms.DestroyScheduler(gomock.Any(), gomock.Eq(uint(2))).Return(nil).Times(1),
+		ms.GetSchedulers(gomock.Any(), gomock.Eq(types.GetSchedulersQuery{
+			Page:    1,
+			PerPage: 10,
+		})).Return([]models.Scheduler{}, int64(0), nil).Times(1),
+		ms.GetSchedulers(gomock.Any(), gomock.Eq(types.GetSchedulersQuery{
+			Page:           1,
+			PerPage:        10,
+			IncludeDeleted: true,
+		})).Return([]models.Scheduler{*mockSchedulerModel}, int64(1), nil).Times(1),
+		ms.RestoreScheduler(gomock.Any(), gomock.Eq(uint(2))).Return(mockSchedulerModel, nil).Times(1),
+		ms.GetSchedulers(gomock.Any(), gomock.Eq(types.GetSchedulersQuery{
+			Page:    1,
+			PerPage: 10,
+		})).Return([]models.Scheduler{*mockSchedulerModel}, int64(1), nil).Times(1),
+	)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mockRouter.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		return w
+	}
+
+	// The deleted scheduler is excluded from the list by default.
+	assert.Equal(http.StatusOK, serve(http.MethodDelete, "/api/v1/schedulers/2").Code)
+	w := serve(http.MethodGet, "/api/v1/schedulers")
+	assert.Equal(http.StatusOK, w.Code)
+	assert.Equal("[]", w.Body.String())
+
+	// The deleted scheduler is included in the list if include_deleted is true.
+	w = serve(http.MethodGet, "/api/v1/schedulers?include_deleted=true")
+	assert.Equal(http.StatusOK, w.Code)
+	schedulers := []models.Scheduler{}
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &schedulers))
+	assert.Len(schedulers, 1)
+
+	// The restored scheduler is included in the list by default.
+	assert.Equal(http.StatusOK, serve(http.MethodPost, "/api/v1/schedulers/2/restore").Code)
+	w = serve(http.MethodGet, "/api/v1/schedulers")
+	assert.Equal(http.StatusOK, w.Code)
+	schedulers = []models.Scheduler{}
+	assert.NoError(json.Unmarshal(w.Body.Bytes(), &schedulers))
+	assert.Len(schedulers, 1)
+}
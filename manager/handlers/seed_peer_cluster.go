@@ -39,7 +39,7 @@ import (
 // @Router /seed-peer-clusters [post]
 func (h *Handlers) CreateSeedPeerCluster(ctx *gin.Context) {
 	var json types.CreateSeedPeerClusterRequest
-	if err := ctx.ShouldBindJSON(&json); err != nil {
+	if err := h.shouldBindStrictJSON(ctx, &json); err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
 		return
 	}
@@ -99,7 +99,7 @@ func (h *Handlers) UpdateSeedPeerCluster(ctx *gin.Context) {
 	}
 
 	var json types.UpdateSeedPeerClusterRequest
-	if err := ctx.ShouldBindJSON(&json); err != nil {
+	if err := h.shouldBindStrictJSON(ctx, &json); err != nil {
 		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
 		return
 	}
@@ -113,6 +113,40 @@ func (h *Handlers) UpdateSeedPeerCluster(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, seedPeerCluster)
 }
 
+// @Summary Dry Run Update SeedPeerCluster
+// @Description Report the effective config and the changed fields if the SeedPeerCluster is updated by json config
+// @Tags SeedPeerCluster
+// @Accept json
+// @Produce json
+// @Param id path string true "id"
+// @Param SeedPeerCluster body types.UpdateSeedPeerClusterRequest true "SeedPeerCluster"
+// @Success 200 {object} types.DryRunUpdateSeedPeerClusterResponse
+// @Failure 400
+// @Failure 404
+// @Failure 500
+// @Router /seed-peer-clusters/{id}/dry-run [post]
+func (h *Handlers) DryRunUpdateSeedPeerCluster(ctx *gin.Context) {
+	var params types.SeedPeerClusterParams
+	if err := ctx.ShouldBindUri(&params); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		return
+	}
+
+	var json types.UpdateSeedPeerClusterRequest
+	if err := h.shouldBindStrictJSON(ctx, &json); err != nil {
+		ctx.JSON(http.StatusUnprocessableEntity, gin.H{"errors": err.Error()})
+		return
+	}
+
+	resp, err := h.service.DryRunUpdateSeedPeerCluster(ctx.Request.Context(), params.ID, json)
+	if err != nil {
+		ctx.Error(err) // nolint: errcheck
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
 // @Summary Get SeedPeerCluster
 // @Description Get SeedPeerCluster by id
 // @Tags SeedPeerCluster
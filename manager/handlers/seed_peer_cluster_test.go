@@ -62,6 +62,7 @@ func mockSeedPeerClusterRouter(h *Handlers) *gin.Engine {
 	spc.POST("", h.CreateSeedPeerCluster)
 	spc.DELETE(":id", h.DestroySeedPeerCluster)
 	spc.PATCH(":id", h.UpdateSeedPeerCluster)
+	spc.POST(":id/dry-run", h.DryRunUpdateSeedPeerCluster)
 	spc.GET(":id", h.GetSeedPeerCluster)
 	spc.GET("", h.GetSeedPeerClusters)
 	spc.PUT(":id/seed-peers/:seed_peer_id", h.AddSeedPeerToSeedPeerCluster)
@@ -186,6 +187,36 @@ func TestHandlers_UpdateSeedPeerCluster(t *testing.T) {
 				assert.Equal(http.StatusUnprocessableEntity, w.Code)
 			},
 		},
+		{
+			name: "unprocessable entity caused by unknown field",
+			req:  httptest.NewRequest(http.MethodPatch, "/api/v1/seed-peer-clusters/2", strings.NewReader(`{"config": {"loadLimit": 300}}`)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+				assert.Contains(w.Body.String(), "unknown field")
+			},
+		},
+		{
+			name: "unprocessable entity caused by invalid type",
+			req:  httptest.NewRequest(http.MethodPatch, "/api/v1/seed-peer-clusters/2", strings.NewReader(`{"config": {"load_limit": "300"}}`)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+				assert.Contains(w.Body.String(), "load_limit")
+			},
+		},
+		{
+			name: "unprocessable entity caused by out of range",
+			req:  httptest.NewRequest(http.MethodPatch, "/api/v1/seed-peer-clusters/2", strings.NewReader(`{"config": {"load_limit": 50001}}`)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+				assert.Contains(w.Body.String(), "LoadLimit")
+			},
+		},
 		{
 			name: "success",
 			req:  httptest.NewRequest(http.MethodPatch, "/api/v1/seed-peer-clusters/2", strings.NewReader(mockSeedPeerClusterReqBody)),
@@ -218,6 +249,75 @@ func TestHandlers_UpdateSeedPeerCluster(t *testing.T) {
 	}
 }
 
+func TestHandlers_DryRunUpdateSeedPeerCluster(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *http.Request
+		mock   func(ms *mocks.MockServiceMockRecorder)
+		expect func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name: "unprocessable entity caused by uri",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/seed-peer-clusters/test/dry-run", nil),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
+		{
+			name: "unprocessable entity caused by out of range",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/seed-peer-clusters/2/dry-run", strings.NewReader(`{"config": {"load_limit": 50001}}`)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusUnprocessableEntity, w.Code)
+			},
+		},
+		{
+			name: "success",
+			req:  httptest.NewRequest(http.MethodPost, "/api/v1/seed-peer-clusters/2/dry-run", strings.NewReader(mockSeedPeerClusterReqBody)),
+			mock: func(ms *mocks.MockServiceMockRecorder) {
+				ms.DryRunUpdateSeedPeerCluster(gomock.Any(), gomock.Eq(uint(2)), gomock.Any()).Return(&types.DryRunUpdateSeedPeerClusterResponse{
+					Config: map[string]any{"load_limit": 1, "version": 2},
+					Changes: []types.ClusterConfigChange{
+						{Field: "config.load_limit", Old: 300, New: 1},
+						{Field: "config.version", Old: 1, New: 2},
+					},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusOK, w.Code)
+				resp := types.DryRunUpdateSeedPeerClusterResponse{}
+				err := json.Unmarshal(w.Body.Bytes(), &resp)
+				assert.NoError(err)
+				assert.Equal(types.DryRunUpdateSeedPeerClusterResponse{
+					Config: map[string]any{"load_limit": float64(1), "version": float64(2)},
+					Changes: []types.ClusterConfigChange{
+						{Field: "config.load_limit", Old: float64(300), New: float64(1)},
+						{Field: "config.version", Old: float64(1), New: float64(2)},
+					},
+				}, resp)
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			svc := mocks.NewMockService(ctl)
+			w := httptest.NewRecorder()
+			h := New(svc)
+			mockRouter := mockSeedPeerClusterRouter(h)
+
+			tc.mock(svc.EXPECT())
+			mockRouter.ServeHTTP(w, tc.req)
+			tc.expect(t, w)
+		})
+	}
+}
+
 func TestHandlers_GetSeedPeerCluster(t *testing.T) {
 	tests := []struct {
 		name   string
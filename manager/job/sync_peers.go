@@ -196,7 +196,7 @@ func (s *syncPeers) mergePeers(ctx context.Context, scheduler models.Scheduler,
 
 		// If the peer exists in the sync peer results, update the peer data in the database with
 		// the sync peer results and delete the sync peer from the sync peers map.
-		id := idgen.HostIDV2(peer.IP, peer.Hostname)
+		id := idgen.HostID(peer.Hostname, peer.IP)
 		if syncPeer, ok := syncPeers[id]; ok {
 			if err := s.db.WithContext(ctx).First(&models.Peer{}, peer.ID).Updates(models.Peer{
 				Type:              syncPeer.Type.Name(),
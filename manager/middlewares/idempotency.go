@@ -0,0 +1,231 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-http-utils/headers"
+	"github.com/redis/go-redis/v9"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/digest"
+	pkgredis "d7y.io/dragonfly/v2/pkg/redis"
+)
+
+const (
+	// IdempotencyKeyHeader is the header of the idempotency key, the retried requests
+	// with the same idempotency key replay the original response.
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// IdempotentReplayedHeader is the header set in the replayed response.
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	// idempotencyKeyMaxLength is the max length of the idempotency key.
+	idempotencyKeyMaxLength = 255
+
+	// idempotencyPendingTTL is the ttl of the idempotency key whose request is in progress,
+	// the key is released after it if the manager exits before the request is handled.
+	idempotencyPendingTTL = time.Minute
+)
+
+// idempotentResponse is the response stored by the idempotency key, the status is zero
+// if the request is in progress.
+type idempotentResponse struct {
+	BodyHash    string `json:"body_hash"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+// Idempotency replays the original response of the successful request with the same
+// idempotency key within the ttl, instead of handling the request again. The idempotency
+// key is scoped by the user, and the retried request must have the same body as the
+// original one. The request without the idempotency key is handled as usual.
+func Idempotency(rdb redis.UniversalClient, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader(IdempotencyKeyHeader)
+		if idempotencyKey == "" {
+			c.Next()
+			return
+		}
+
+		if len(idempotencyKey) > idempotencyKeyMaxLength {
+			c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+				Message: "idempotency key is too long",
+			})
+			c.Abort()
+			return
+		}
+
+		bodyHash, err := hashRequestBody(c.Request)
+		if err != nil {
+			c.Error(err) // nolint: errcheck
+			c.Abort()
+			return
+		}
+
+		// The response is stored after the request is handled, even if the client
+		// cancels the request, so the retried request can replay it.
+		ctx := context.WithoutCancel(c.Request.Context())
+		key := pkgredis.MakeIdempotencyKeyInManager(c.Request.Method, c.FullPath(), userID(c), idempotencyKey)
+
+		pending, err := json.Marshal(&idempotentResponse{BodyHash: bodyHash})
+		if err != nil {
+			c.Error(err) // nolint: errcheck
+			c.Abort()
+			return
+		}
+
+		// Mark the idempotency key as pending, only one of the concurrent requests
+		// with the same idempotency key is handled.
+		ok, err := rdb.SetNX(ctx, key, pending, idempotencyPendingTTL).Result()
+		if err != nil {
+			c.Error(err) // nolint: errcheck
+			c.Abort()
+			return
+		}
+
+		if !ok {
+			replay(c, rdb, key, bodyHash)
+			return
+		}
+
+		w := &idempotentResponseWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Next()
+
+		// Only the successful response is stored, so the failed request can be retried.
+		status := w.Status()
+		if len(c.Errors) > 0 || !w.Written() || status < http.StatusOK || status >= http.StatusMultipleChoices {
+			if err := rdb.Del(ctx, key).Err(); err != nil {
+				logger.Errorf("delete idempotency key %s error: %s", key, err)
+			}
+
+			return
+		}
+
+		resp, err := json.Marshal(&idempotentResponse{
+			BodyHash:    bodyHash,
+			Status:      status,
+			ContentType: w.Header().Get(headers.ContentType),
+			Body:        w.body.Bytes(),
+		})
+		if err != nil {
+			logger.Errorf("marshal idempotent response error: %s", err)
+			return
+		}
+
+		if err := rdb.Set(ctx, key, resp, ttl).Err(); err != nil {
+			logger.Errorf("store idempotency key %s error: %s", key, err)
+		}
+	}
+}
+
+// replay writes the stored response of the idempotency key.
+func replay(c *gin.Context, rdb redis.UniversalClient, key, bodyHash string) {
+	defer c.Abort()
+
+	val, err := rdb.Get(c.Request.Context(), key).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		c.Error(err) // nolint: errcheck
+		return
+	}
+
+	// The idempotency key has expired right after it was checked.
+	if errors.Is(err, redis.Nil) {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Message: "request with the same idempotency key is in progress",
+		})
+		return
+	}
+
+	var resp idempotentResponse
+	if err := json.Unmarshal([]byte(val), &resp); err != nil {
+		c.Error(err) // nolint: errcheck
+		return
+	}
+
+	if resp.BodyHash != bodyHash {
+		c.JSON(http.StatusUnprocessableEntity, ErrorResponse{
+			Message: "idempotency key is reused with a different request body",
+		})
+		return
+	}
+
+	if resp.Status == 0 {
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Message: "request with the same idempotency key is in progress",
+		})
+		return
+	}
+
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Data(resp.Status, resp.ContentType, resp.Body)
+}
+
+// hashRequestBody returns the sha256 of the request body, and the body is restored for the handler.
+func hashRequestBody(req *http.Request) (string, error) {
+	if req.Body == nil {
+		return digest.SHA256FromBytes(nil), nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return "", err
+	}
+
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return digest.SHA256FromBytes(body), nil
+}
+
+// userID returns the id of the user set by the jwt middleware, it is empty if the user is anonymous.
+func userID(c *gin.Context) string {
+	id, ok := c.Get(defaultIdentityKey)
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprint(id)
+}
+
+// idempotentResponseWriter records the body of the response.
+type idempotentResponseWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write writes the data to the response and records it.
+func (w *idempotentResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString writes the string to the response and records it.
+func (w *idempotentResponseWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,255 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redismock/v9"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/manager/models"
+	"d7y.io/dragonfly/v2/pkg/digest"
+	pkgredis "d7y.io/dragonfly/v2/pkg/redis"
+)
+
+var (
+	mockIdempotencyKey      = "foo"
+	mockIdempotencyKeyTTL   = time.Hour
+	mockIdempotencyUserID   = float64(1)
+	mockIdempotencyRedisKey = pkgredis.MakeIdempotencyKeyInManager(http.MethodPost, "/api/v1/schedulers", "1", mockIdempotencyKey)
+	mockIdempotencyBody     = `{"hostname":"foo"}`
+)
+
+// mockIdempotencyRouter returns the router whose handler creates a new scheduler for every handled request.
+func mockIdempotencyRouter(rdb redis.UniversalClient, status int) (*gin.Engine, *int) {
+	var created int
+	r := gin.New()
+	r.Use(Error())
+	r.Use(func(c *gin.Context) {
+		// Identity of the user set by the jwt middleware.
+		c.Set(defaultIdentityKey, mockIdempotencyUserID)
+	})
+	r.POST("/api/v1/schedulers", Idempotency(rdb, mockIdempotencyKeyTTL), func(c *gin.Context) {
+		if status != http.StatusOK {
+			c.Error(errors.New("foo")) // nolint: errcheck
+			return
+		}
+
+		created++
+		c.JSON(http.StatusOK, models.Scheduler{BaseModel: models.BaseModel{ID: uint(created)}, Hostname: "foo"})
+	})
+
+	return r, &created
+}
+
+func TestIdempotency(t *testing.T) {
+	mockScheduler := models.Scheduler{BaseModel: models.BaseModel{ID: 1}, Hostname: "foo"}
+	mockSchedulerBody, err := json.Marshal(mockScheduler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockBodyHash := digest.SHA256FromStrings(mockIdempotencyBody)
+	mockPending, err := json.Marshal(&idempotentResponse{BodyHash: mockBodyHash})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockResponse, err := json.Marshal(&idempotentResponse{
+		BodyHash:    mockBodyHash,
+		Status:      http.StatusOK,
+		ContentType: "application/json; charset=utf-8",
+		Body:        mockSchedulerBody,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		status int
+		keys   []string
+		bodies []string
+		mock   func(mock redismock.ClientMock)
+		expect func(t *testing.T, ws []*httptest.ResponseRecorder, created int)
+	}{
+		{
+			name:   "request without idempotency key is not replayed",
+			status: http.StatusOK,
+			keys:   []string{"", ""},
+			mock:   func(mock redismock.ClientMock) {},
+			expect: func(t *testing.T, ws []*httptest.ResponseRecorder, created int) {
+				assert := assert.New(t)
+				assert.Equal(2, created)
+				assert.Equal(http.StatusOK, ws[0].Code)
+				assert.Equal(http.StatusOK, ws[1].Code)
+				assert.NotEqual(ws[0].Body.String(), ws[1].Body.String())
+				assert.Empty(ws[1].Header().Get(IdempotentReplayedHeader))
+			},
+		},
+		{
+			name:   "request with duplicate idempotency key replays the original scheduler",
+			status: http.StatusOK,
+			keys:   []string{mockIdempotencyKey, mockIdempotencyKey},
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mockPending, idempotencyPendingTTL).SetVal(true)
+				mock.ExpectSet(mockIdempotencyRedisKey, mockResponse, mockIdempotencyKeyTTL).SetVal("OK")
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mockPending, idempotencyPendingTTL).SetVal(false)
+				mock.ExpectGet(mockIdempotencyRedisKey).SetVal(string(mockResponse))
+			},
+			expect: func(t *testing.T, ws []*httptest.ResponseRecorder, created int) {
+				assert := assert.New(t)
+				assert.Equal(1, created)
+				assert.Equal(http.StatusOK, ws[0].Code)
+				assert.Equal(http.StatusOK, ws[1].Code)
+				assert.Equal(ws[0].Body.String(), ws[1].Body.String())
+				assert.Equal("application/json; charset=utf-8", ws[1].Header().Get("Content-Type"))
+				assert.Equal("true", ws[1].Header().Get(IdempotentReplayedHeader))
+
+				scheduler := models.Scheduler{}
+				assert.NoError(json.Unmarshal(ws[1].Body.Bytes(), &scheduler))
+				assert.Equal(mockScheduler.ID, scheduler.ID)
+			},
+		},
+		{
+			name:   "request with idempotency key in progress",
+			status: http.StatusOK,
+			keys:   []string{mockIdempotencyKey},
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mockPending, idempotencyPendingTTL).SetVal(false)
+				mock.ExpectGet(mockIdempotencyRedisKey).SetVal(string(mockPending))
+			},
+			expect: func(t *testing.T, ws []*httptest.ResponseRecorder, created int) {
+				assert := assert.New(t)
+				assert.Equal(0, created)
+				assert.Equal(http.StatusConflict, ws[0].Code)
+			},
+		},
+		{
+			name:   "request with in progress idempotency key expired",
+			status: http.StatusOK,
+			keys:   []string{mockIdempotencyKey},
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mockPending, idempotencyPendingTTL).SetVal(false)
+				mock.ExpectGet(mockIdempotencyRedisKey).RedisNil()
+			},
+			expect: func(t *testing.T, ws []*httptest.ResponseRecorder, created int) {
+				assert := assert.New(t)
+				assert.Equal(0, created)
+				assert.Equal(http.StatusConflict, ws[0].Code)
+			},
+		},
+		{
+			name:   "request with duplicate idempotency key and different body",
+			status: http.StatusOK,
+			keys:   []string{mockIdempotencyKey, mockIdempotencyKey},
+			bodies: []string{mockIdempotencyBody, `{"hostname":"bar"}`},
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mockPending, idempotencyPendingTTL).SetVal(true)
+				mock.ExpectSet(mockIdempotencyRedisKey, mockResponse, mockIdempotencyKeyTTL).SetVal("OK")
+				mismatch, _ := json.Marshal(&idempotentResponse{BodyHash: digest.SHA256FromStrings(`{"hostname":"bar"}`)})
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mismatch, idempotencyPendingTTL).SetVal(false)
+				mock.ExpectGet(mockIdempotencyRedisKey).SetVal(string(mockResponse))
+			},
+			expect: func(t *testing.T, ws []*httptest.ResponseRecorder, created int) {
+				assert := assert.New(t)
+				assert.Equal(1, created)
+				assert.Equal(http.StatusOK, ws[0].Code)
+				assert.Equal(http.StatusUnprocessableEntity, ws[1].Code)
+				assert.Empty(ws[1].Header().Get(IdempotentReplayedHeader))
+			},
+		},
+		{
+			name:   "failed request with idempotency key can be retried",
+			status: http.StatusInternalServerError,
+			keys:   []string{mockIdempotencyKey, mockIdempotencyKey},
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mockPending, idempotencyPendingTTL).SetVal(true)
+				mock.ExpectDel(mockIdempotencyRedisKey).SetVal(1)
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mockPending, idempotencyPendingTTL).SetVal(true)
+				mock.ExpectDel(mockIdempotencyRedisKey).SetVal(1)
+			},
+			expect: func(t *testing.T, ws []*httptest.ResponseRecorder, created int) {
+				assert := assert.New(t)
+				assert.Equal(http.StatusInternalServerError, ws[0].Code)
+				assert.Equal(http.StatusInternalServerError, ws[1].Code)
+				assert.Empty(ws[1].Header().Get(IdempotentReplayedHeader))
+			},
+		},
+		{
+			name:   "idempotency key is too long",
+			status: http.StatusOK,
+			keys:   []string{strings.Repeat("a", idempotencyKeyMaxLength+1)},
+			mock:   func(mock redismock.ClientMock) {},
+			expect: func(t *testing.T, ws []*httptest.ResponseRecorder, created int) {
+				assert := assert.New(t)
+				assert.Equal(0, created)
+				assert.Equal(http.StatusUnprocessableEntity, ws[0].Code)
+			},
+		},
+		{
+			name:   "mark idempotency key failed",
+			status: http.StatusOK,
+			keys:   []string{mockIdempotencyKey},
+			mock: func(mock redismock.ClientMock) {
+				mock.ExpectSetNX(mockIdempotencyRedisKey, mockPending, idempotencyPendingTTL).SetErr(errors.New("foo"))
+			},
+			expect: func(t *testing.T, ws []*httptest.ResponseRecorder, created int) {
+				assert := assert.New(t)
+				assert.Equal(0, created)
+				assert.Equal(http.StatusInternalServerError, ws[0].Code)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rdb, mock := redismock.NewClientMock()
+			tc.mock(mock)
+			r, created := mockIdempotencyRouter(rdb, tc.status)
+
+			var ws []*httptest.ResponseRecorder
+			for i, key := range tc.keys {
+				body := mockIdempotencyBody
+				if i < len(tc.bodies) {
+					body = tc.bodies[i]
+				}
+
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/schedulers", strings.NewReader(body))
+				if key != "" {
+					req.Header.Set(IdempotencyKeyHeader, key)
+				}
+
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				ws = append(ws, w)
+			}
+
+			tc.expect(t, ws, *created)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -147,23 +147,27 @@ func Init(cfg *config.Config, logDir string, service service.Service, database *
 	sc.POST("", h.CreateSchedulerCluster)
 	sc.DELETE(":id", h.DestroySchedulerCluster)
 	sc.PATCH(":id", h.UpdateSchedulerCluster)
+	sc.POST(":id/dry-run", h.DryRunUpdateSchedulerCluster)
 	sc.GET(":id", h.GetSchedulerCluster)
 	sc.GET("", h.GetSchedulerClusters)
+	sc.GET(":id/schedulers", h.GetSchedulerClusterSchedulers)
 	sc.PUT(":id/schedulers/:scheduler_id", h.AddSchedulerToSchedulerCluster)
 
 	// Scheduler.
 	s := apiv1.Group("/schedulers", jwt.MiddlewareFunc(), rbac)
-	s.POST("", h.CreateScheduler)
+	s.POST("", middlewares.Idempotency(database.RDB, cfg.Server.REST.IdempotencyKeyTTL), h.CreateScheduler)
 	s.DELETE(":id", h.DestroyScheduler)
 	s.PATCH(":id", h.UpdateScheduler)
 	s.GET(":id", h.GetScheduler)
 	s.GET("", h.GetSchedulers)
+	s.POST(":id/restore", h.RestoreScheduler)
 
 	// Seed Peer Cluster.
 	spc := apiv1.Group("/seed-peer-clusters", jwt.MiddlewareFunc(), rbac)
 	spc.POST("", h.CreateSeedPeerCluster)
 	spc.DELETE(":id", h.DestroySeedPeerCluster)
 	spc.PATCH(":id", h.UpdateSeedPeerCluster)
+	spc.POST(":id/dry-run", h.DryRunUpdateSeedPeerCluster)
 	spc.GET(":id", h.GetSeedPeerCluster)
 	spc.GET("", h.GetSeedPeerClusters)
 	spc.PUT(":id/seed-peers/:seed_peer_id", h.AddSeedPeerToSeedPeerCluster)
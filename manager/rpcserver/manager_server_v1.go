@@ -27,6 +27,7 @@ import (
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
 
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
 	managerv1 "d7y.io/api/v2/pkg/apis/manager/v1"
@@ -444,7 +445,7 @@ func (s *managerServerV1) GetScheduler(ctx context.Context, req *managerv1.GetSc
 func (s *managerServerV1) UpdateScheduler(ctx context.Context, req *managerv1.UpdateSchedulerRequest) (*managerv1.Scheduler, error) {
 	log := logger.WithHostnameAndIP(req.Hostname, req.Ip)
 	scheduler := models.Scheduler{}
-	if err := s.db.WithContext(ctx).First(&scheduler, models.Scheduler{
+	if err := s.db.WithContext(ctx).Unscoped().First(&scheduler, models.Scheduler{
 		Hostname:           req.Hostname,
 		IP:                 req.Ip,
 		SchedulerClusterID: uint(req.SchedulerClusterId),
@@ -456,6 +457,13 @@ func (s *managerServerV1) UpdateScheduler(ctx context.Context, req *managerv1.Up
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	// The soft-deleted scheduler is found because the unique index of the scheduler does not
+	// contain the soft delete flag, the scheduler re-registers like a new one and replaces it.
+	if scheduler.IsDel != soft_delete.DeletedAt(soft_delete.FlagActived) {
+		log.Info("scheduler is deleted, create it again")
+		return s.createScheduler(ctx, req)
+	}
+
 	if err := s.db.WithContext(ctx).Model(&scheduler).Updates(models.Scheduler{
 		IDC:                req.GetIdc(),
 		Location:           req.GetLocation(),
@@ -504,7 +512,23 @@ func (s *managerServerV1) createScheduler(ctx context.Context, req *managerv1.Up
 		SchedulerClusterID: uint(req.GetSchedulerClusterId()),
 	}
 
-	if err := s.db.WithContext(ctx).Create(&scheduler).Error; err != nil {
+	// The unique index of the scheduler does not contain the soft delete flag, so the soft-deleted
+	// scheduler with the same hostname, ip and cluster is replaced instead of inserting a new one.
+	deleted := models.Scheduler{}
+	if err := s.db.WithContext(ctx).Unscoped().Where("is_del = ?", soft_delete.FlagDeleted).First(&deleted, models.Scheduler{
+		Hostname:           req.GetHostname(),
+		IP:                 req.GetIp(),
+		SchedulerClusterID: uint(req.GetSchedulerClusterId()),
+	}).Error; err == nil {
+		scheduler.ID = deleted.ID
+		scheduler.CreatedAt = deleted.CreatedAt
+		scheduler.State = models.SchedulerStateInactive
+		if err := s.db.WithContext(ctx).Unscoped().Save(&scheduler).Error; err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Error(codes.Internal, err.Error())
+	} else if err := s.db.WithContext(ctx).Create(&scheduler).Error; err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
@@ -0,0 +1,164 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	cachev9 "github.com/go-redis/cache/v9"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	managerv1 "d7y.io/api/v2/pkg/apis/manager/v1"
+
+	"d7y.io/dragonfly/v2/manager/cache"
+	"d7y.io/dragonfly/v2/manager/models"
+)
+
+// newMockDB returns the in-memory database with the tables of the models.
+func newMockDB(t *testing.T, models ...any) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every connection of the in-memory database has its own database.
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+// newMockCache returns the cache with the local cache only.
+func newMockCache() *cache.Cache {
+	return &cache.Cache{
+		Cache: cachev9.New(&cachev9.Options{LocalCache: cachev9.NewTinyLFU(100, time.Minute)}),
+		TTL:   time.Minute,
+	}
+}
+
+func TestManagerServerV1_UpdateScheduler(t *testing.T) {
+	req := &managerv1.UpdateSchedulerRequest{
+		Hostname:           "foo",
+		Ip:                 "127.0.0.1",
+		Port:               8002,
+		Idc:                "bar",
+		SchedulerClusterId: 1,
+	}
+
+	tests := []struct {
+		name   string
+		run    func(t *testing.T, s *managerServerV1, db *gorm.DB)
+		expect func(t *testing.T, db *gorm.DB, scheduler *managerv1.Scheduler, err error)
+	}{
+		{
+			name: "scheduler registers for the first time",
+			run:  func(t *testing.T, s *managerServerV1, db *gorm.DB) {},
+			expect: func(t *testing.T, db *gorm.DB, scheduler *managerv1.Scheduler, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal("foo", scheduler.Hostname)
+				assert.Equal("bar", scheduler.Idc)
+				assert.Equal(models.SchedulerStateInactive, scheduler.State)
+			},
+		},
+		{
+			name: "scheduler registers again",
+			run: func(t *testing.T, s *managerServerV1, db *gorm.DB) {
+				if _, err := s.UpdateScheduler(context.Background(), &managerv1.UpdateSchedulerRequest{
+					Hostname:           "foo",
+					Ip:                 "127.0.0.1",
+					Port:               8002,
+					SchedulerClusterId: 1,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, db *gorm.DB, scheduler *managerv1.Scheduler, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(uint64(1), scheduler.Id)
+				assert.Equal("bar", scheduler.Idc)
+
+				var count int64
+				assert.NoError(db.Model(&models.Scheduler{}).Count(&count).Error)
+				assert.Equal(int64(1), count)
+			},
+		},
+		{
+			name: "scheduler registers again after it is deleted",
+			run: func(t *testing.T, s *managerServerV1, db *gorm.DB) {
+				scheduler, err := s.UpdateScheduler(context.Background(), &managerv1.UpdateSchedulerRequest{
+					Hostname:           "foo",
+					Ip:                 "127.0.0.1",
+					Port:               8002,
+					Location:           "baz",
+					SchedulerClusterId: 1,
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := db.Model(&models.Scheduler{}).Where("id = ?", scheduler.Id).Update("state", models.SchedulerStateActive).Error; err != nil {
+					t.Fatal(err)
+				}
+
+				if err := db.Delete(&models.Scheduler{}, scheduler.Id).Error; err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, db *gorm.DB, scheduler *managerv1.Scheduler, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(uint64(1), scheduler.Id)
+				assert.Equal("bar", scheduler.Idc)
+				assert.Empty(scheduler.Location)
+				assert.Equal(models.SchedulerStateInactive, scheduler.State)
+
+				restored := models.Scheduler{}
+				assert.NoError(db.First(&restored, scheduler.Id).Error)
+				assert.Equal(models.SchedulerStateInactive, restored.State)
+
+				var count int64
+				assert.NoError(db.Unscoped().Model(&models.Scheduler{}).Count(&count).Error)
+				assert.Equal(int64(1), count)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newMockDB(t, &models.Scheduler{})
+			s := &managerServerV1{db: db, cache: newMockCache()}
+			tc.run(t, s, db)
+
+			scheduler, err := s.UpdateScheduler(context.Background(), req)
+			tc.expect(t, db, scheduler, err)
+		})
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
 
 	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
 	managerv2 "d7y.io/api/v2/pkg/apis/manager/v2"
@@ -450,7 +451,7 @@ func (s *managerServerV2) GetScheduler(ctx context.Context, req *managerv2.GetSc
 func (s *managerServerV2) UpdateScheduler(ctx context.Context, req *managerv2.UpdateSchedulerRequest) (*managerv2.Scheduler, error) {
 	log := logger.WithHostnameAndIP(req.Hostname, req.Ip)
 	scheduler := models.Scheduler{}
-	if err := s.db.WithContext(ctx).First(&scheduler, models.Scheduler{
+	if err := s.db.WithContext(ctx).Unscoped().First(&scheduler, models.Scheduler{
 		Hostname:           req.Hostname,
 		IP:                 req.Ip,
 		Port:               req.Port,
@@ -463,6 +464,13 @@ func (s *managerServerV2) UpdateScheduler(ctx context.Context, req *managerv2.Up
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
+	// The soft-deleted scheduler is found because the unique index of the scheduler does not
+	// contain the soft delete flag, the scheduler re-registers like a new one and replaces it.
+	if scheduler.IsDel != soft_delete.DeletedAt(soft_delete.FlagActived) {
+		log.Info("scheduler is deleted, create it again")
+		return s.createScheduler(ctx, req)
+	}
+
 	if err := s.db.WithContext(ctx).Model(&scheduler).Updates(models.Scheduler{
 		IDC:                req.GetIdc(),
 		Location:           req.GetLocation(),
@@ -511,7 +519,23 @@ func (s *managerServerV2) createScheduler(ctx context.Context, req *managerv2.Up
 		SchedulerClusterID: uint(req.GetSchedulerClusterId()),
 	}
 
-	if err := s.db.WithContext(ctx).Create(&scheduler).Error; err != nil {
+	// The unique index of the scheduler does not contain the soft delete flag, so the soft-deleted
+	// scheduler with the same hostname, ip and cluster is replaced instead of inserting a new one.
+	deleted := models.Scheduler{}
+	if err := s.db.WithContext(ctx).Unscoped().Where("is_del = ?", soft_delete.FlagDeleted).First(&deleted, models.Scheduler{
+		Hostname:           req.GetHostname(),
+		IP:                 req.GetIp(),
+		SchedulerClusterID: uint(req.GetSchedulerClusterId()),
+	}).Error; err == nil {
+		scheduler.ID = deleted.ID
+		scheduler.CreatedAt = deleted.CreatedAt
+		scheduler.State = models.SchedulerStateInactive
+		if err := s.db.WithContext(ctx).Unscoped().Save(&scheduler).Error; err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Error(codes.Internal, err.Error())
+	} else if err := s.db.WithContext(ctx).Create(&scheduler).Error; err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
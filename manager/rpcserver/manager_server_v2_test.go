@@ -0,0 +1,100 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpcserver
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+
+	managerv2 "d7y.io/api/v2/pkg/apis/manager/v2"
+
+	"d7y.io/dragonfly/v2/manager/models"
+)
+
+func TestManagerServerV2_UpdateScheduler(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *managerv2.UpdateSchedulerRequest
+		expect func(t *testing.T, db *gorm.DB, scheduler *managerv2.Scheduler, err error)
+	}{
+		{
+			name: "scheduler registers again after it is deleted",
+			req: &managerv2.UpdateSchedulerRequest{
+				Hostname:           "foo",
+				Ip:                 "127.0.0.1",
+				Port:               8002,
+				SchedulerClusterId: 1,
+			},
+			expect: func(t *testing.T, db *gorm.DB, scheduler *managerv2.Scheduler, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(uint64(1), scheduler.Id)
+				assert.Equal(models.SchedulerStateInactive, scheduler.State)
+
+				var count int64
+				assert.NoError(db.Model(&models.Scheduler{}).Count(&count).Error)
+				assert.Equal(int64(1), count)
+			},
+		},
+		{
+			name: "scheduler registers again with another port after it is deleted",
+			req: &managerv2.UpdateSchedulerRequest{
+				Hostname:           "foo",
+				Ip:                 "127.0.0.1",
+				Port:               8003,
+				SchedulerClusterId: 1,
+			},
+			expect: func(t *testing.T, db *gorm.DB, scheduler *managerv2.Scheduler, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(uint64(1), scheduler.Id)
+				assert.Equal(int32(8003), scheduler.Port)
+
+				var count int64
+				assert.NoError(db.Unscoped().Model(&models.Scheduler{}).Count(&count).Error)
+				assert.Equal(int64(1), count)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newMockDB(t, &models.Scheduler{})
+			s := &managerServerV2{db: db, cache: newMockCache()}
+
+			scheduler, err := s.UpdateScheduler(context.Background(), &managerv2.UpdateSchedulerRequest{
+				Hostname:           "foo",
+				Ip:                 "127.0.0.1",
+				Port:               8002,
+				SchedulerClusterId: 1,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := db.Delete(&models.Scheduler{}, scheduler.Id).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			scheduler, err = s.UpdateScheduler(context.Background(), tc.req)
+			tc.expect(t, db, scheduler, err)
+		})
+	}
+}
@@ -19,6 +19,9 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
 
 	"d7y.io/dragonfly/v2/manager/models"
 	"d7y.io/dragonfly/v2/manager/types"
@@ -26,6 +29,8 @@ import (
 )
 
 func (s *service) CreateCluster(ctx context.Context, json types.CreateClusterRequest) (*types.CreateClusterResponse, error) {
+	json.SchedulerClusterConfig.Version = 1
+	json.SeedPeerClusterConfig.Version = 1
 	schedulerClusterConfig, err := structure.StructToMap(json.SchedulerClusterConfig)
 	if err != nil {
 		return nil, err
@@ -162,25 +167,9 @@ func (s *service) DestroyCluster(ctx context.Context, id uint) error {
 
 func (s *service) UpdateCluster(ctx context.Context, id uint, json types.UpdateClusterRequest) (*types.UpdateClusterResponse, error) {
 	var (
-		schedulerClusterConfig map[string]any
-		err                    error
+		peerClusterConfig map[string]any
+		err               error
 	)
-	if json.SchedulerClusterConfig != nil {
-		schedulerClusterConfig, err = structure.StructToMap(json.SchedulerClusterConfig)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	var seedPeerClusterConfig map[string]any
-	if json.SeedPeerClusterConfig != nil {
-		seedPeerClusterConfig, err = structure.StructToMap(json.SeedPeerClusterConfig)
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	var peerClusterConfig map[string]any
 	if json.PeerClusterConfig != nil {
 		peerClusterConfig, err = structure.StructToMap(json.PeerClusterConfig)
 		if err != nil {
@@ -209,7 +198,37 @@ func (s *service) UpdateCluster(ctx context.Context, id uint, json types.UpdateC
 	}
 
 	schedulerCluster := models.SchedulerCluster{}
-	if err := tx.WithContext(ctx).Preload("SeedPeerClusters").First(&schedulerCluster, id).Updates(models.SchedulerCluster{
+	if err := tx.WithContext(ctx).Preload("SeedPeerClusters").First(&schedulerCluster, id).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if len(schedulerCluster.SeedPeerClusters) != 1 {
+		tx.Rollback()
+		return nil, errors.New("invalid number of the seed peer cluster")
+	}
+
+	var schedulerClusterConfig map[string]any
+	if json.SchedulerClusterConfig != nil {
+		json.SchedulerClusterConfig.Version = nextClusterConfigVersion(schedulerCluster.Config)
+		schedulerClusterConfig, err = structure.StructToMap(json.SchedulerClusterConfig)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	var seedPeerClusterConfig map[string]any
+	if json.SeedPeerClusterConfig != nil {
+		json.SeedPeerClusterConfig.Version = nextClusterConfigVersion(schedulerCluster.SeedPeerClusters[0].Config)
+		seedPeerClusterConfig, err = structure.StructToMap(json.SeedPeerClusterConfig)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.WithContext(ctx).Model(&schedulerCluster).Updates(models.SchedulerCluster{
 		Name:         json.Name,
 		BIO:          json.BIO,
 		Config:       schedulerClusterConfig,
@@ -229,11 +248,6 @@ func (s *service) UpdateCluster(ctx context.Context, id uint, json types.UpdateC
 		}
 	}
 
-	if len(schedulerCluster.SeedPeerClusters) != 1 {
-		tx.Rollback()
-		return nil, errors.New("invalid number of the seed peer cluster")
-	}
-
 	seedPeerCluster := models.SeedPeerCluster{}
 	if err := tx.WithContext(ctx).First(&seedPeerCluster, schedulerCluster.SeedPeerClusters[0].ID).Updates(models.SeedPeerCluster{
 		Name:   json.Name,
@@ -363,3 +377,48 @@ func (s *service) GetClusters(ctx context.Context, q types.GetClustersQuery) ([]
 
 	return resp, count, nil
 }
+
+// nextClusterConfigVersion returns the version of the cluster config after the update,
+// the version is increased on every update so that the config rollout is traceable.
+func nextClusterConfigVersion(config models.JSONMap) uint64 {
+	var versioned struct {
+		Version uint64 `json:"version"`
+	}
+
+	if err := structure.MapToStruct(config, &versioned); err != nil {
+		return 1
+	}
+
+	return versioned.Version + 1
+}
+
+// diffClusterConfig returns the fields changed from the current config to the effective config,
+// the fields are sorted by name and prefixed with the name of the config.
+func diffClusterConfig(prefix string, current, effective map[string]any) []types.ClusterConfigChange {
+	keys := make([]string, 0, len(current)+len(effective))
+	for key := range current {
+		keys = append(keys, key)
+	}
+
+	for key := range effective {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []types.ClusterConfigChange{}
+	for _, key := range keys {
+		if reflect.DeepEqual(current[key], effective[key]) {
+			continue
+		}
+
+		changes = append(changes, types.ClusterConfigChange{
+			Field: fmt.Sprintf("%s.%s", prefix, key),
+			Old:   current[key],
+			New:   effective[key],
+		})
+	}
+
+	return changes
+}
@@ -66,7 +66,7 @@ func (s *service) CreatePreheatJob(ctx context.Context, json types.CreatePreheat
 		return nil, err
 	}
 
-	go s.pollingJob(context.Background(), job.ID, job.TaskID)
+	go s.pollingJob(context.Background(), job.ID, job.TaskID, json.Args.FailureThreshold)
 
 	return &job, nil
 }
@@ -106,7 +106,7 @@ func (s *service) CreateDeleteTaskJob(ctx context.Context, json types.CreateDele
 		return nil, err
 	}
 
-	go s.pollingJob(context.Background(), job.ID, job.TaskID)
+	go s.pollingJob(context.Background(), job.ID, job.TaskID, 0)
 
 	return &job, nil
 }
@@ -146,7 +146,7 @@ func (s *service) CreateGetTaskJob(ctx context.Context, json types.CreateGetTask
 		return nil, err
 	}
 
-	go s.pollingJob(context.Background(), job.ID, job.TaskID)
+	go s.pollingJob(context.Background(), job.ID, job.TaskID, 0)
 
 	return &job, nil
 }
@@ -210,13 +210,15 @@ func (s *service) findCandidateSchedulers(ctx context.Context, schedulerClusterI
 	return candidateSchedulers, nil
 }
 
-func (s *service) pollingJob(ctx context.Context, id uint, groupID string) {
+// pollingJob polls the state of the group job until it succeeds or fails, the group job
+// fails when the ratio of the failed jobs exceeds failureThreshold.
+func (s *service) pollingJob(ctx context.Context, id uint, groupID string, failureThreshold float64) {
 	var (
 		job models.Job
 		log = logger.WithGroupAndJobID(groupID, fmt.Sprint(id))
 	)
 	if _, _, err := retry.Run(ctx, 5, 10, 480, func() (any, bool, error) {
-		groupJob, err := s.job.GetGroupJobState(groupID)
+		groupJob, err := s.job.GetGroupJobStateWithFailureThreshold(groupID, failureThreshold)
 		if err != nil {
 			log.Errorf("polling group failed: %s", err.Error())
 			return nil, false, err
@@ -567,6 +567,36 @@ func (mr *MockServiceMockRecorder) DestroySeedPeerCluster(arg0, arg1 any) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DestroySeedPeerCluster", reflect.TypeOf((*MockService)(nil).DestroySeedPeerCluster), arg0, arg1)
 }
 
+// DryRunUpdateSchedulerCluster mocks base method.
+func (m *MockService) DryRunUpdateSchedulerCluster(arg0 context.Context, arg1 uint, arg2 types.UpdateSchedulerClusterRequest) (*types.DryRunUpdateSchedulerClusterResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DryRunUpdateSchedulerCluster", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.DryRunUpdateSchedulerClusterResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DryRunUpdateSchedulerCluster indicates an expected call of DryRunUpdateSchedulerCluster.
+func (mr *MockServiceMockRecorder) DryRunUpdateSchedulerCluster(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DryRunUpdateSchedulerCluster", reflect.TypeOf((*MockService)(nil).DryRunUpdateSchedulerCluster), arg0, arg1, arg2)
+}
+
+// DryRunUpdateSeedPeerCluster mocks base method.
+func (m *MockService) DryRunUpdateSeedPeerCluster(arg0 context.Context, arg1 uint, arg2 types.UpdateSeedPeerClusterRequest) (*types.DryRunUpdateSeedPeerClusterResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DryRunUpdateSeedPeerCluster", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*types.DryRunUpdateSeedPeerClusterResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DryRunUpdateSeedPeerCluster indicates an expected call of DryRunUpdateSeedPeerCluster.
+func (mr *MockServiceMockRecorder) DryRunUpdateSeedPeerCluster(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DryRunUpdateSeedPeerCluster", reflect.TypeOf((*MockService)(nil).DryRunUpdateSeedPeerCluster), arg0, arg1, arg2)
+}
+
 // GetApplication mocks base method.
 func (m *MockService) GetApplication(arg0 context.Context, arg1 uint) (*models.Application, error) {
 	m.ctrl.T.Helper()
@@ -901,6 +931,22 @@ func (mr *MockServiceMockRecorder) GetSchedulerCluster(arg0, arg1 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedulerCluster", reflect.TypeOf((*MockService)(nil).GetSchedulerCluster), arg0, arg1)
 }
 
+// GetSchedulerClusterSchedulers mocks base method.
+func (m *MockService) GetSchedulerClusterSchedulers(arg0 context.Context, arg1 uint, arg2 types.GetSchedulerClusterSchedulersQuery) ([]models.Scheduler, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSchedulerClusterSchedulers", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]models.Scheduler)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// GetSchedulerClusterSchedulers indicates an expected call of GetSchedulerClusterSchedulers.
+func (mr *MockServiceMockRecorder) GetSchedulerClusterSchedulers(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSchedulerClusterSchedulers", reflect.TypeOf((*MockService)(nil).GetSchedulerClusterSchedulers), arg0, arg1, arg2)
+}
+
 // GetSchedulerClusters mocks base method.
 func (m *MockService) GetSchedulerClusters(arg0 context.Context, arg1 types.GetSchedulerClustersQuery) ([]models.SchedulerCluster, int64, error) {
 	m.ctrl.T.Helper()
@@ -1085,6 +1131,21 @@ func (mr *MockServiceMockRecorder) ResetPassword(arg0, arg1, arg2 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResetPassword", reflect.TypeOf((*MockService)(nil).ResetPassword), arg0, arg1, arg2)
 }
 
+// RestoreScheduler mocks base method.
+func (m *MockService) RestoreScheduler(arg0 context.Context, arg1 uint) (*models.Scheduler, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreScheduler", arg0, arg1)
+	ret0, _ := ret[0].(*models.Scheduler)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreScheduler indicates an expected call of RestoreScheduler.
+func (mr *MockServiceMockRecorder) RestoreScheduler(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreScheduler", reflect.TypeOf((*MockService)(nil).RestoreScheduler), arg0, arg1)
+}
+
 // SignIn mocks base method.
 func (m *MockService) SignIn(arg0 context.Context, arg1 types.SignInRequest) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -18,6 +18,10 @@ package service
 
 import (
 	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
 
 	"d7y.io/dragonfly/v2/manager/models"
 	"d7y.io/dragonfly/v2/manager/types"
@@ -39,6 +43,26 @@ func (s *service) CreateScheduler(ctx context.Context, json types.CreateSchedule
 		SchedulerClusterID: json.SchedulerClusterID,
 	}
 
+	// The unique index of the scheduler does not contain the soft delete flag, so the soft-deleted
+	// scheduler with the same hostname, ip and cluster is replaced instead of inserting a new one.
+	deleted := models.Scheduler{}
+	if err := s.db.WithContext(ctx).Unscoped().Where("is_del = ?", soft_delete.FlagDeleted).First(&deleted, models.Scheduler{
+		Hostname:           json.Hostname,
+		IP:                 json.IP,
+		SchedulerClusterID: json.SchedulerClusterID,
+	}).Error; err == nil {
+		scheduler.ID = deleted.ID
+		scheduler.CreatedAt = deleted.CreatedAt
+		scheduler.State = models.SchedulerStateInactive
+		if err := s.db.WithContext(ctx).Unscoped().Save(&scheduler).Error; err != nil {
+			return nil, err
+		}
+
+		return &scheduler, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
 	if err := s.db.WithContext(ctx).Create(&scheduler).Error; err != nil {
 		return nil, err
 	}
@@ -52,13 +76,31 @@ func (s *service) DestroyScheduler(ctx context.Context, id uint) error {
 		return err
 	}
 
-	if err := s.db.WithContext(ctx).Unscoped().Unscoped().Delete(&models.Scheduler{}, id).Error; err != nil {
+	// Soft delete the scheduler for auditability, it can be restored by RestoreScheduler.
+	if err := s.db.WithContext(ctx).Delete(&models.Scheduler{}, id).Error; err != nil {
 		return err
 	}
 
 	return nil
 }
 
+func (s *service) RestoreScheduler(ctx context.Context, id uint) (*models.Scheduler, error) {
+	scheduler := models.Scheduler{}
+	if err := s.db.WithContext(ctx).Unscoped().First(&scheduler, id).Error; err != nil {
+		return nil, err
+	}
+
+	if scheduler.IsDel == soft_delete.DeletedAt(soft_delete.FlagActived) {
+		return &scheduler, nil
+	}
+
+	if err := s.db.WithContext(ctx).Unscoped().Model(&scheduler).Update("is_del", soft_delete.FlagActived).Error; err != nil {
+		return nil, err
+	}
+
+	return &scheduler, nil
+}
+
 func (s *service) UpdateScheduler(ctx context.Context, id uint, json types.UpdateSchedulerRequest) (*models.Scheduler, error) {
 	scheduler := models.Scheduler{}
 	if err := s.db.WithContext(ctx).First(&scheduler, id).Updates(models.Scheduler{
@@ -87,7 +129,12 @@ func (s *service) GetScheduler(ctx context.Context, id uint) (*models.Scheduler,
 func (s *service) GetSchedulers(ctx context.Context, q types.GetSchedulersQuery) ([]models.Scheduler, int64, error) {
 	var count int64
 	var schedulers []models.Scheduler
-	if err := s.db.WithContext(ctx).Scopes(models.Paginate(q.Page, q.PerPage)).Where(&models.Scheduler{
+	db := s.db.WithContext(ctx)
+	if q.IncludeDeleted {
+		db = db.Unscoped()
+	}
+
+	if err := db.Scopes(models.Paginate(q.Page, q.PerPage)).Where(&models.Scheduler{
 		Hostname:           q.Hostname,
 		IDC:                q.IDC,
 		Location:           q.Location,
@@ -26,6 +26,7 @@ import (
 )
 
 func (s *service) CreateSchedulerCluster(ctx context.Context, json types.CreateSchedulerClusterRequest) (*models.SchedulerCluster, error) {
+	json.Config.Version = 1
 	config, err := structure.StructToMap(json.Config)
 	if err != nil {
 		return nil, err
@@ -81,11 +82,17 @@ func (s *service) DestroySchedulerCluster(ctx context.Context, id uint) error {
 }
 
 func (s *service) UpdateSchedulerCluster(ctx context.Context, id uint, json types.UpdateSchedulerClusterRequest) (*models.SchedulerCluster, error) {
+	schedulerCluster := models.SchedulerCluster{}
+	if err := s.db.WithContext(ctx).First(&schedulerCluster, id).Error; err != nil {
+		return nil, err
+	}
+
 	var (
 		config map[string]any
 		err    error
 	)
 	if json.Config != nil {
+		json.Config.Version = nextClusterConfigVersion(schedulerCluster.Config)
 		config, err = structure.StructToMap(json.Config)
 		if err != nil {
 			return nil, err
@@ -108,8 +115,7 @@ func (s *service) UpdateSchedulerCluster(ctx context.Context, id uint, json type
 		}
 	}
 
-	schedulerCluster := models.SchedulerCluster{}
-	if err := s.db.WithContext(ctx).First(&schedulerCluster, id).Updates(models.SchedulerCluster{
+	if err := s.db.WithContext(ctx).Model(&schedulerCluster).Updates(models.SchedulerCluster{
 		Name:         json.Name,
 		BIO:          json.BIO,
 		Config:       config,
@@ -136,6 +142,39 @@ func (s *service) UpdateSchedulerCluster(ctx context.Context, id uint, json type
 	return &schedulerCluster, nil
 }
 
+func (s *service) DryRunUpdateSchedulerCluster(ctx context.Context, id uint, json types.UpdateSchedulerClusterRequest) (*types.DryRunUpdateSchedulerClusterResponse, error) {
+	schedulerCluster := models.SchedulerCluster{}
+	if err := s.db.WithContext(ctx).First(&schedulerCluster, id).Error; err != nil {
+		return nil, err
+	}
+
+	var err error
+	config := map[string]any(schedulerCluster.Config)
+	if json.Config != nil {
+		json.Config.Version = nextClusterConfigVersion(schedulerCluster.Config)
+		config, err = structure.StructToMap(json.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	clientConfig := map[string]any(schedulerCluster.ClientConfig)
+	if json.ClientConfig != nil {
+		clientConfig, err = structure.StructToMap(json.ClientConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	changes := diffClusterConfig("config", schedulerCluster.Config, config)
+	changes = append(changes, diffClusterConfig("client_config", schedulerCluster.ClientConfig, clientConfig)...)
+	return &types.DryRunUpdateSchedulerClusterResponse{
+		Config:       config,
+		ClientConfig: clientConfig,
+		Changes:      changes,
+	}, nil
+}
+
 func (s *service) GetSchedulerCluster(ctx context.Context, id uint) (*models.SchedulerCluster, error) {
 	schedulerCluster := models.SchedulerCluster{}
 	if err := s.db.WithContext(ctx).Preload("SeedPeerClusters").First(&schedulerCluster, id).Error; err != nil {
@@ -157,6 +196,23 @@ func (s *service) GetSchedulerClusters(ctx context.Context, q types.GetScheduler
 	return schedulerClusters, count, nil
 }
 
+func (s *service) GetSchedulerClusterSchedulers(ctx context.Context, id uint, q types.GetSchedulerClusterSchedulersQuery) ([]models.Scheduler, int64, error) {
+	schedulerCluster := models.SchedulerCluster{}
+	if err := s.db.WithContext(ctx).First(&schedulerCluster, id).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var count int64
+	schedulers := []models.Scheduler{}
+	if err := s.db.WithContext(ctx).Scopes(models.Paginate(q.Page, q.PerPage)).Where(&models.Scheduler{
+		SchedulerClusterID: schedulerCluster.ID,
+	}).Find(&schedulers).Limit(-1).Offset(-1).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return schedulers, count, nil
+}
+
 func (s *service) AddSchedulerToSchedulerCluster(ctx context.Context, id, schedulerID uint) error {
 	schedulerCluster := models.SchedulerCluster{}
 	if err := s.db.WithContext(ctx).First(&schedulerCluster, id).Error; err != nil {
@@ -26,6 +26,7 @@ import (
 )
 
 func (s *service) CreateSeedPeerCluster(ctx context.Context, json types.CreateSeedPeerClusterRequest) (*models.SeedPeerCluster, error) {
+	json.Config.Version = 1
 	config, err := structure.StructToMap(json.Config)
 	if err != nil {
 		return nil, err
@@ -62,19 +63,24 @@ func (s *service) DestroySeedPeerCluster(ctx context.Context, id uint) error {
 }
 
 func (s *service) UpdateSeedPeerCluster(ctx context.Context, id uint, json types.UpdateSeedPeerClusterRequest) (*models.SeedPeerCluster, error) {
+	seedPeerCluster := models.SeedPeerCluster{}
+	if err := s.db.WithContext(ctx).First(&seedPeerCluster, id).Error; err != nil {
+		return nil, err
+	}
+
 	var (
 		config map[string]any
 		err    error
 	)
 	if json.Config != nil {
+		json.Config.Version = nextClusterConfigVersion(seedPeerCluster.Config)
 		config, err = structure.StructToMap(json.Config)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	seedPeerCluster := models.SeedPeerCluster{}
-	if err := s.db.WithContext(ctx).First(&seedPeerCluster, id).Updates(models.SeedPeerCluster{
+	if err := s.db.WithContext(ctx).Model(&seedPeerCluster).Updates(models.SeedPeerCluster{
 		Name:   json.Name,
 		BIO:    json.BIO,
 		Config: config,
@@ -85,6 +91,28 @@ func (s *service) UpdateSeedPeerCluster(ctx context.Context, id uint, json types
 	return &seedPeerCluster, nil
 }
 
+func (s *service) DryRunUpdateSeedPeerCluster(ctx context.Context, id uint, json types.UpdateSeedPeerClusterRequest) (*types.DryRunUpdateSeedPeerClusterResponse, error) {
+	seedPeerCluster := models.SeedPeerCluster{}
+	if err := s.db.WithContext(ctx).First(&seedPeerCluster, id).Error; err != nil {
+		return nil, err
+	}
+
+	var err error
+	config := map[string]any(seedPeerCluster.Config)
+	if json.Config != nil {
+		json.Config.Version = nextClusterConfigVersion(seedPeerCluster.Config)
+		config, err = structure.StructToMap(json.Config)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &types.DryRunUpdateSeedPeerClusterResponse{
+		Config:  config,
+		Changes: diffClusterConfig("config", seedPeerCluster.Config, config),
+	}, nil
+}
+
 func (s *service) GetSeedPeerCluster(ctx context.Context, id uint) (*models.SeedPeerCluster, error) {
 	seedPeerCluster := models.SeedPeerCluster{}
 	if err := s.db.WithContext(ctx).First(&seedPeerCluster, id).Error; err != nil {
@@ -73,6 +73,7 @@ type Service interface {
 	CreateSeedPeerCluster(context.Context, types.CreateSeedPeerClusterRequest) (*models.SeedPeerCluster, error)
 	DestroySeedPeerCluster(context.Context, uint) error
 	UpdateSeedPeerCluster(context.Context, uint, types.UpdateSeedPeerClusterRequest) (*models.SeedPeerCluster, error)
+	DryRunUpdateSeedPeerCluster(context.Context, uint, types.UpdateSeedPeerClusterRequest) (*types.DryRunUpdateSeedPeerClusterResponse, error)
 	GetSeedPeerCluster(context.Context, uint) (*models.SeedPeerCluster, error)
 	GetSeedPeerClusters(context.Context, types.GetSeedPeerClustersQuery) ([]models.SeedPeerCluster, int64, error)
 	AddSeedPeerToSeedPeerCluster(context.Context, uint, uint) error
@@ -92,8 +93,10 @@ type Service interface {
 	CreateSchedulerCluster(context.Context, types.CreateSchedulerClusterRequest) (*models.SchedulerCluster, error)
 	DestroySchedulerCluster(context.Context, uint) error
 	UpdateSchedulerCluster(context.Context, uint, types.UpdateSchedulerClusterRequest) (*models.SchedulerCluster, error)
+	DryRunUpdateSchedulerCluster(context.Context, uint, types.UpdateSchedulerClusterRequest) (*types.DryRunUpdateSchedulerClusterResponse, error)
 	GetSchedulerCluster(context.Context, uint) (*models.SchedulerCluster, error)
 	GetSchedulerClusters(context.Context, types.GetSchedulerClustersQuery) ([]models.SchedulerCluster, int64, error)
+	GetSchedulerClusterSchedulers(context.Context, uint, types.GetSchedulerClusterSchedulersQuery) ([]models.Scheduler, int64, error)
 	AddSchedulerToSchedulerCluster(context.Context, uint, uint) error
 
 	CreateScheduler(context.Context, types.CreateSchedulerRequest) (*models.Scheduler, error)
@@ -101,6 +104,7 @@ type Service interface {
 	UpdateScheduler(context.Context, uint, types.UpdateSchedulerRequest) (*models.Scheduler, error)
 	GetScheduler(context.Context, uint) (*models.Scheduler, error)
 	GetSchedulers(context.Context, types.GetSchedulersQuery) ([]models.Scheduler, int64, error)
+	RestoreScheduler(context.Context, uint) (*models.Scheduler, error)
 
 	CreateBucket(context.Context, types.CreateBucketRequest) error
 	DestroyBucket(context.Context, string) error
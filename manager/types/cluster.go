@@ -92,3 +92,14 @@ type GetClustersQuery struct {
 	Page    int    `form:"page" binding:"omitempty,gte=1"`
 	PerPage int    `form:"per_page" binding:"omitempty,gte=1,lte=10000000"`
 }
+
+type ClusterConfigChange struct {
+	// Field is the path of the changed config field, e.g. config.load_limit.
+	Field string `json:"field"`
+
+	// Old is the value of the field before the update.
+	Old any `json:"old"`
+
+	// New is the value of the field after the update.
+	New any `json:"new"`
+}
@@ -76,6 +76,15 @@ type PreheatArgs struct {
 
 	// The image type preheating task can specify the image architecture type. eg: linux/amd64.
 	Platform string `json:"platform" binding:"omitempty"`
+
+	// FailureThreshold is the ratio of the failed preheating tasks tolerated, e.g. some of the
+	// scheduler clusters are unavailable, the job fails when the ratio exceeds it.
+	// Default is 0, which means the job fails once any task fails.
+	FailureThreshold float64 `json:"failure_threshold" binding:"omitempty,gte=0,lt=1"`
+
+	// Scope is the scope of the peers holding the preheated data, only seed_peers is supported,
+	// the schedulers trigger their seed peers to download. Default is seed_peers.
+	Scope string `json:"scope" binding:"omitempty,oneof=seed_peers"`
 }
 
 type CreateGetTaskJobRequest struct {
@@ -62,4 +62,5 @@ type GetSchedulersQuery struct {
 	IP                 string `form:"ip" binding:"omitempty"`
 	State              string `form:"state" binding:"omitempty,oneof=active inactive"`
 	SchedulerClusterID uint   `form:"scheduler_cluster_id" binding:"omitempty"`
+	IncludeDeleted     bool   `form:"include_deleted" binding:"omitempty"`
 }
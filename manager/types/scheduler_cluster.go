@@ -45,6 +45,11 @@ type UpdateSchedulerClusterRequest struct {
 	SeedPeerClusterID uint                          `json:"seed_peer_cluster_id" binding:"omitempty"`
 }
 
+type GetSchedulerClusterSchedulersQuery struct {
+	Page    int `form:"page" binding:"omitempty,gte=1"`
+	PerPage int `form:"per_page" binding:"omitempty,gte=1,lte=10000000"`
+}
+
 type GetSchedulerClustersQuery struct {
 	Name    string `form:"name" binding:"omitempty"`
 	Page    int    `form:"page" binding:"omitempty,gte=1"`
@@ -54,10 +59,69 @@ type GetSchedulerClustersQuery struct {
 type SchedulerClusterConfig struct {
 	CandidateParentLimit uint32 `yaml:"candidateParentLimit" mapstructure:"candidateParentLimit" json:"candidate_parent_limit" binding:"omitempty,gte=1,lte=20"`
 	FilterParentLimit    uint32 `yaml:"filterParentLimit" mapstructure:"filterParentLimit" json:"filter_parent_limit" binding:"omitempty,gte=10,lte=1000"`
+
+	// PieceCompressionApplications is the applications whose pieces are compressed between peers.
+	PieceCompressionApplications []string `yaml:"pieceCompressionApplications" mapstructure:"pieceCompressionApplications" json:"piece_compression_applications" binding:"omitempty"`
+
+	// SizeScopeApplications is the applications whose size scope limits override the scheduler config.
+	SizeScopeApplications []SizeScopeApplication `yaml:"sizeScopeApplications" mapstructure:"sizeScopeApplications" json:"size_scope_applications" binding:"omitempty,dive"`
+
+	// BackToSourceCount is the maximum number of the peers of a task going back-to-source,
+	// zero means using the scheduler config.
+	BackToSourceCount uint32 `yaml:"backToSourceCount" mapstructure:"backToSourceCount" json:"back_to_source_count" binding:"omitempty,gte=1"`
+
+	// BackToSourceApplications is the applications whose back-to-source count overrides BackToSourceCount.
+	BackToSourceApplications []BackToSourceApplication `yaml:"backToSourceApplications" mapstructure:"backToSourceApplications" json:"back_to_source_applications" binding:"omitempty,dive"`
+
+	// MaintenanceHosts is the hostnames or ips of the hosts in maintenance, the peers of the hosts
+	// can still download, but they are not selected as parents, e.g. before draining the node.
+	MaintenanceHosts []string `yaml:"maintenanceHosts" mapstructure:"maintenanceHosts" json:"maintenance_hosts" binding:"omitempty"`
+
+	// FeatureFlags toggles the experimental behaviors of the schedulers in the cluster by name,
+	// the behavior is disabled if its flag is not set.
+	FeatureFlags map[string]bool `yaml:"featureFlags" mapstructure:"featureFlags" json:"feature_flags" binding:"omitempty"`
+
+	// Version is the version of the config, it is increased by manager when the config is updated.
+	Version uint64 `yaml:"version" mapstructure:"version" json:"version" binding:"omitempty"`
+}
+
+type SizeScopeApplication struct {
+	// Name is the application name.
+	Name string `yaml:"name" mapstructure:"name" json:"name" binding:"required"`
+
+	// TinyFileSizeLimit is the size limit of the tiny file, zero means using the scheduler config.
+	TinyFileSizeLimit int64 `yaml:"tinyFileSizeLimit" mapstructure:"tinyFileSizeLimit" json:"tiny_file_size_limit" binding:"omitempty,gte=1,lte=1048576"`
+
+	// SmallFileSizeLimit is the size limit of the small file, zero means using the scheduler config.
+	SmallFileSizeLimit int64 `yaml:"smallFileSizeLimit" mapstructure:"smallFileSizeLimit" json:"small_file_size_limit" binding:"omitempty,gte=1,lte=15728640"`
+}
+
+type BackToSourceApplication struct {
+	// Name is the application name.
+	Name string `yaml:"name" mapstructure:"name" json:"name" binding:"required"`
+
+	// BackToSourceCount is the maximum number of the peers of a task of the application going back-to-source.
+	BackToSourceCount uint32 `yaml:"backToSourceCount" mapstructure:"backToSourceCount" json:"back_to_source_count" binding:"required,gte=1"`
 }
 
 type SchedulerClusterClientConfig struct {
-	LoadLimit uint32 `yaml:"loadLimit" mapstructure:"loadLimit" json:"load_limit" binding:"omitempty,gte=1,lte=2000"`
+	LoadLimit             uint32 `yaml:"loadLimit" mapstructure:"loadLimit" json:"load_limit" binding:"omitempty,gte=1,lte=2000"`
+	PeerUploadRateCeiling uint64 `yaml:"peerUploadRateCeiling" mapstructure:"peerUploadRateCeiling" json:"peer_upload_rate_ceiling" binding:"omitempty"`
+
+	// FeatureFlags toggles the client features of the daemons in the cluster by name, the values are
+	// bool, int or string, the schedulers validate them and forward them to the daemons.
+	FeatureFlags map[string]any `yaml:"featureFlags" mapstructure:"featureFlags" json:"feature_flags" binding:"omitempty"`
+}
+
+type DryRunUpdateSchedulerClusterResponse struct {
+	// Config is the effective scheduler cluster config after the update.
+	Config map[string]any `json:"config"`
+
+	// ClientConfig is the effective client config after the update.
+	ClientConfig map[string]any `json:"client_config"`
+
+	// Changes are the config fields changed by the update.
+	Changes []ClusterConfigChange `json:"changes"`
 }
 
 type SchedulerClusterScopes struct {
@@ -50,4 +50,15 @@ type GetSeedPeerClustersQuery struct {
 
 type SeedPeerClusterConfig struct {
 	LoadLimit uint32 `yaml:"loadLimit" mapstructure:"loadLimit" json:"load_limit" binding:"omitempty,gte=1,lte=50000"`
+
+	// Version is the version of the config, it is increased by manager when the config is updated.
+	Version uint64 `yaml:"version" mapstructure:"version" json:"version" binding:"omitempty"`
+}
+
+type DryRunUpdateSeedPeerClusterResponse struct {
+	// Config is the effective seed peer cluster config after the update.
+	Config map[string]any `json:"config"`
+
+	// Changes are the config fields changed by the update.
+	Changes []ClusterConfigChange `json:"changes"`
 }
@@ -24,6 +24,7 @@ import (
 	"google.golang.org/grpc/balancer"
 	"google.golang.org/grpc/balancer/base"
 	"google.golang.org/grpc/grpclog"
+	"google.golang.org/grpc/resolver"
 	"stathat.com/c/consistent"
 )
 
@@ -45,6 +46,30 @@ const (
 // searchCircleLimit is the limit of searching circle.
 const searchCircleLimit = 10
 
+// weightKey is the key of weight in the balancer attributes of address.
+type weightKey struct{}
+
+// SetWeight returns a copy of addr with the weight stored in the balancer attributes,
+// weight less than or equal to 1 leaves addr unchanged.
+func SetWeight(addr resolver.Address, weight int) resolver.Address {
+	if weight <= 1 {
+		return addr
+	}
+
+	addr.BalancerAttributes = addr.BalancerAttributes.WithValue(weightKey{}, weight)
+	return addr
+}
+
+// GetWeight returns the weight stored in the balancer attributes of addr, defaults to 1.
+func GetWeight(addr resolver.Address) int {
+	weight, ok := addr.BalancerAttributes.Value(weightKey{}).(int)
+	if !ok || weight <= 1 {
+		return 1
+	}
+
+	return weight
+}
+
 var logger = grpclog.Component("consistenthashing")
 
 // NewConsistentHashingBuilder creates a new consistent-hashing balancer builder.
@@ -61,6 +86,10 @@ type ConsistentHashingPickerBuilder struct {
 	hashring *consistent.Consistent
 	members  []string
 	circle   map[string]string
+
+	// elements maps the elements of hashring to the addresses they belong to,
+	// an address with weight n owns n elements in hashring.
+	elements map[string]string
 }
 
 func (b *ConsistentHashingPickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
@@ -70,13 +99,24 @@ func (b *ConsistentHashingPickerBuilder) Build(info base.PickerBuildInfo) balanc
 	}
 
 	// Build hashring and init sub connections map.
-	b.hashring = consistent.New()
+	hashring := consistent.New()
 	scs := make(map[string]balancer.SubConn, len(info.ReadySCs))
+	elements := make(map[string]string, len(info.ReadySCs))
 	for sc, scInfo := range info.ReadySCs {
-		element := fmt.Sprintf("%s:%s", scInfo.Address.Addr, scInfo.Address.ServerName)
-		b.hashring.Add(element)
-		scs[element] = sc
+		addr := fmt.Sprintf("%s:%s", scInfo.Address.Addr, scInfo.Address.ServerName)
+		for i := 0; i < GetWeight(scInfo.Address); i++ {
+			element := addr
+			if i > 0 {
+				element = fmt.Sprintf("%s#%d", addr, i)
+			}
+
+			hashring.Add(element)
+			scs[element] = sc
+			elements[element] = addr
+		}
 	}
+	b.hashring = hashring
+	b.elements = elements
 
 	return &consistentHashingPicker{
 		subConns: scs,
@@ -94,18 +134,34 @@ func (b *ConsistentHashingPickerBuilder) GetCircle() (map[string]string, error)
 		return b.circle, nil
 	}
 
-	circle := make(map[string]string, len(members))
+	addrs := make(map[string]struct{}, len(members))
+	for _, element := range members {
+		if addr, ok := b.elements[element]; ok {
+			addrs[addr] = struct{}{}
+			continue
+		}
+
+		addrs[element] = struct{}{}
+	}
+
+	circle := make(map[string]string, len(addrs))
 	for i := 0; i <= len(members)*searchCircleLimit; i++ {
 		key := fmt.Sprint(i)
-		member, err := b.hashring.Get(key)
+		element, err := b.hashring.Get(key)
 		if err != nil {
 			logger.Errorf("hashring get member failed: %s", err.Error())
 			continue
 		}
 
+		// Weighted addresses own several elements, only one key is required per address.
+		member, ok := b.elements[element]
+		if !ok {
+			member = element
+		}
+
 		if _, ok := circle[member]; !ok {
 			circle[member] = key
-			if len(circle) == len(members) {
+			if len(circle) == len(addrs) {
 				b.members = members
 				b.circle = circle
 				return circle, nil
@@ -0,0 +1,78 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package balancer
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/resolver"
+)
+
+type testSubConn struct {
+	balancer.SubConn
+	addr string
+}
+
+func TestWeight(t *testing.T) {
+	assert := assert.New(t)
+	addr := resolver.Address{Addr: "127.0.0.1:8002"}
+	assert.Equal(GetWeight(addr), 1)
+	assert.Equal(GetWeight(SetWeight(addr, 0)), 1)
+	assert.Equal(GetWeight(SetWeight(addr, 3)), 3)
+	assert.Nil(addr.BalancerAttributes)
+}
+
+func TestConsistentHashingPickerBuilder_Weighted(t *testing.T) {
+	light := &testSubConn{addr: "127.0.0.1:8002"}
+	heavy := &testSubConn{addr: "127.0.0.2:8002"}
+
+	_, pickerBuilder := NewConsistentHashingBuilder()
+	picker := pickerBuilder.Build(base.PickerBuildInfo{
+		ReadySCs: map[balancer.SubConn]base.SubConnInfo{
+			light: {Address: resolver.Address{Addr: light.addr, ServerName: "127.0.0.1"}},
+			heavy: {Address: SetWeight(resolver.Address{Addr: heavy.addr, ServerName: "127.0.0.2"}, 4)},
+		},
+	})
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		result, err := picker.Pick(balancer.PickInfo{Ctx: context.WithValue(context.Background(), ContextKey, fmt.Sprintf("task-%d", i))})
+		assert.NoError(t, err)
+		counts[result.SubConn.(*testSubConn).addr]++
+	}
+	assert.Greater(t, counts[heavy.addr], counts[light.addr]*2)
+
+	// Circle has only one key per address regardless of weight.
+	circle, err := pickerBuilder.GetCircle()
+	assert.NoError(t, err)
+	assert.Len(t, circle, 2)
+
+	members := map[string]balancer.SubConn{
+		"127.0.0.1:8002:127.0.0.1": light,
+		"127.0.0.2:8002:127.0.0.2": heavy,
+	}
+	for member, key := range circle {
+		result, err := picker.Pick(balancer.PickInfo{Ctx: context.WithValue(context.Background(), ContextKey, key)})
+		assert.NoError(t, err)
+		assert.Equal(t, members[member], result.SubConn)
+	}
+}
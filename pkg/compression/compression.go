@@ -0,0 +1,134 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compression
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// CodecZstd is the zstd codec of the piece.
+	CodecZstd = "zstd"
+)
+
+const (
+	// PieceCodecsMetadataKey is the grpc metadata key of the piece codecs supported by the peer.
+	PieceCodecsMetadataKey = "dragonfly-piece-codecs"
+
+	// PieceCodecMetadataKey is the grpc metadata key of the piece codec enabled for the task.
+	PieceCodecMetadataKey = "dragonfly-piece-codec"
+)
+
+const (
+	// MinLevel is the fastest compression level.
+	MinLevel = int(zstd.SpeedFastest)
+
+	// MaxLevel is the best compression level, it costs the most cpu.
+	MaxLevel = int(zstd.SpeedBestCompression)
+)
+
+// SupportedCodecs is the piece codecs supported by the peer.
+var SupportedCodecs = []string{CodecZstd}
+
+// IsSupported returns whether the codec is supported.
+func IsSupported(codec string) bool {
+	for _, c := range SupportedCodecs {
+		if c == codec {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Negotiate returns the first supported codec of the offered codecs,
+// it returns empty string if there is no common codec.
+func Negotiate(codecs []string) string {
+	for _, codec := range codecs {
+		if IsSupported(codec) {
+			return codec
+		}
+	}
+
+	return ""
+}
+
+// ParseCodecs parses the comma separated codecs, such as the value of header Accept-Encoding.
+func ParseCodecs(values ...string) []string {
+	var codecs []string
+	for _, value := range values {
+		for _, codec := range strings.Split(value, ",") {
+			// Ignore the quality value of the codec.
+			codec, _, _ = strings.Cut(codec, ";")
+			if codec = strings.TrimSpace(codec); codec != "" {
+				codecs = append(codecs, codec)
+			}
+		}
+	}
+
+	return codecs
+}
+
+// AppendCodecsToOutgoingContext returns a new context with the supported codecs advertised to the server.
+func AppendCodecsToOutgoingContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, PieceCodecsMetadataKey, strings.Join(SupportedCodecs, ","))
+}
+
+// CodecsFromIncomingContext returns the codecs advertised by the client.
+func CodecsFromIncomingContext(ctx context.Context) []string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	return ParseCodecs(md.Get(PieceCodecsMetadataKey)...)
+}
+
+// NewWriter returns a writer compressing data to w with the codec and level.
+func NewWriter(w io.Writer, codec string, level int) (io.WriteCloser, error) {
+	switch codec {
+	case CodecZstd:
+		if level < MinLevel || level > MaxLevel {
+			return nil, fmt.Errorf("invalid compression level %d", level)
+		}
+
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevel(level)), zstd.WithEncoderConcurrency(1))
+	default:
+		return nil, fmt.Errorf("unsupported codec %s", codec)
+	}
+}
+
+// NewReader returns a reader decompressing data from r with the codec.
+func NewReader(r io.Reader, codec string) (io.ReadCloser, error) {
+	switch codec {
+	case CodecZstd:
+		decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("unsupported codec %s", codec)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package compression
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestCompression_Negotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		codecs []string
+		expect string
+	}{
+		{
+			name:   "common codec",
+			codecs: []string{"gzip", "zstd"},
+			expect: CodecZstd,
+		},
+		{
+			name:   "no common codec",
+			codecs: []string{"gzip", "br"},
+			expect: "",
+		},
+		{
+			name:   "empty codecs",
+			expect: "",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.expect, Negotiate(tc.codecs))
+		})
+	}
+}
+
+func TestCompression_ParseCodecs(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]string{"gzip", "zstd", "br"}, ParseCodecs("gzip, zstd;q=0.8", "br"))
+	assert.Empty(ParseCodecs("", " , "))
+}
+
+func TestCompression_CodecsFromIncomingContext(t *testing.T) {
+	assert := assert.New(t)
+	assert.Empty(CodecsFromIncomingContext(context.Background()))
+
+	md, ok := metadata.FromOutgoingContext(AppendCodecsToOutgoingContext(context.Background()))
+	assert.True(ok)
+	assert.Equal(SupportedCodecs, CodecsFromIncomingContext(metadata.NewIncomingContext(context.Background(), md)))
+}
+
+func TestCompression_ReadWrite(t *testing.T) {
+	tests := []struct {
+		name   string
+		codec  string
+		level  int
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name:  "zstd with fastest level",
+			codec: CodecZstd,
+			level: MinLevel,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:  "zstd with best level",
+			codec: CodecZstd,
+			level: MaxLevel,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:  "invalid level",
+			codec: CodecZstd,
+			level: MaxLevel + 1,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid compression level 5")
+			},
+		},
+		{
+			name:  "unsupported codec",
+			codec: "gzip",
+			level: MinLevel,
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "unsupported codec gzip")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data := []byte(strings.Repeat(`{"name": "dragonfly"}`, 1024))
+
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, tc.codec, tc.level)
+			if err != nil {
+				tc.expect(t, err)
+				return
+			}
+
+			if _, err := w.Write(data); err != nil {
+				tc.expect(t, err)
+				return
+			}
+
+			if err := w.Close(); err != nil {
+				tc.expect(t, err)
+				return
+			}
+
+			assert := assert.New(t)
+			assert.Less(buf.Len(), len(data))
+
+			r, err := NewReader(&buf, tc.codec)
+			if err != nil {
+				tc.expect(t, err)
+				return
+			}
+			defer r.Close()
+
+			result, err := io.ReadAll(r)
+			tc.expect(t, err)
+			assert.Equal(data, result)
+		})
+	}
+}
@@ -20,6 +20,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -45,6 +47,42 @@ type NetAddr struct {
 
 	// Addr is the address of network.
 	Addr string `mapstructure:"addr" yaml:"addr"`
+
+	// Weight is the relative weight of address when balancing between
+	// multiple addresses, zero means the default weight.
+	Weight int `mapstructure:"weight" yaml:"weight"`
+}
+
+// DefaultWeight is the default weight of network address.
+const DefaultWeight = 1
+
+// ParseWeightedAddr parses the address in format of host:port=weight,
+// the weight is optional and defaults to zero.
+func ParseWeightedAddr(value string) (string, int, error) {
+	addr, w, ok := strings.Cut(value, "=")
+	if !ok {
+		return value, 0, nil
+	}
+
+	if addr == "" {
+		return "", 0, fmt.Errorf("invalid weighted addr %q", value)
+	}
+
+	weight, err := strconv.Atoi(w)
+	if err != nil || weight <= 0 {
+		return "", 0, fmt.Errorf("invalid weight of addr %q", value)
+	}
+
+	return addr, weight, nil
+}
+
+// GetWeight returns the weight of network address, returns DefaultWeight if not set.
+func (n *NetAddr) GetWeight() int {
+	if n.Weight <= 0 {
+		return DefaultWeight
+	}
+
+	return n.Weight
 }
 
 // String returns the endpoint of network address.
@@ -68,8 +106,14 @@ func (n *NetAddr) UnmarshalJSON(b []byte) error {
 
 	switch value := v.(type) {
 	case string:
+		addr, weight, err := ParseWeightedAddr(value)
+		if err != nil {
+			return err
+		}
+
 		n.Type = TCP
-		n.Addr = value
+		n.Addr = addr
+		n.Weight = weight
 		return nil
 	case map[string]any:
 		if err := n.unmarshal(json.Unmarshal, b); err != nil {
@@ -90,8 +134,14 @@ func (n *NetAddr) UnmarshalYAML(node *yaml.Node) error {
 			return err
 		}
 
+		addr, weight, err := ParseWeightedAddr(addr)
+		if err != nil {
+			return err
+		}
+
 		n.Type = TCP
 		n.Addr = addr
+		n.Weight = weight
 		return nil
 	case yaml.MappingNode:
 		var m = make(map[string]any)
@@ -129,15 +179,21 @@ func (n *NetAddr) UnmarshalYAML(node *yaml.Node) error {
 // unmarshal parses the encoded data and stores the result.
 func (n *NetAddr) unmarshal(unmarshal func(in []byte, out any) (err error), b []byte) error {
 	netAddr := struct {
-		Type NetworkType `json:"type" yaml:"type"`
-		Addr string      `json:"addr" yaml:"addr"`
+		Type   NetworkType `json:"type" yaml:"type"`
+		Addr   string      `json:"addr" yaml:"addr"`
+		Weight int         `json:"weight" yaml:"weight"`
 	}{}
 
 	if err := unmarshal(b, &netAddr); err != nil {
 		return err
 	}
 
+	if netAddr.Weight < 0 {
+		return fmt.Errorf("invalid weight of addr %q", netAddr.Addr)
+	}
+
 	n.Type = netAddr.Type
 	n.Addr = netAddr.Addr
+	n.Weight = netAddr.Weight
 	return nil
 }
@@ -0,0 +1,154 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dfnet
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseWeightedAddr(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect func(t *testing.T, addr string, weight int, err error)
+	}{
+		{
+			name:  "addr without weight",
+			value: "127.0.0.1:8002",
+			expect: func(t *testing.T, addr string, weight int, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(addr, "127.0.0.1:8002")
+				assert.Equal(weight, 0)
+			},
+		},
+		{
+			name:  "addr with weight",
+			value: "127.0.0.1:8002=3",
+			expect: func(t *testing.T, addr string, weight int, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(addr, "127.0.0.1:8002")
+				assert.Equal(weight, 3)
+			},
+		},
+		{
+			name:  "addr with invalid weight",
+			value: "127.0.0.1:8002=foo",
+			expect: func(t *testing.T, addr string, weight int, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid weight of addr \"127.0.0.1:8002=foo\"")
+			},
+		},
+		{
+			name:  "addr with zero weight",
+			value: "127.0.0.1:8002=0",
+			expect: func(t *testing.T, addr string, weight int, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid weight of addr \"127.0.0.1:8002=0\"")
+			},
+		},
+		{
+			name:  "weight without addr",
+			value: "=3",
+			expect: func(t *testing.T, addr string, weight int, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid weighted addr \"=3\"")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, weight, err := ParseWeightedAddr(tc.value)
+			tc.expect(t, addr, weight, err)
+		})
+	}
+}
+
+func TestNetAddr_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		unmarshal func(in []byte, out any) error
+		expect    func(t *testing.T, netAddr NetAddr, err error)
+	}{
+		{
+			name:      "unmarshal json string",
+			data:      `"127.0.0.1:8002=2"`,
+			unmarshal: json.Unmarshal,
+			expect: func(t *testing.T, netAddr NetAddr, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(netAddr, NetAddr{Type: TCP, Addr: "127.0.0.1:8002", Weight: 2})
+				assert.Equal(netAddr.GetWeight(), 2)
+			},
+		},
+		{
+			name:      "unmarshal json map",
+			data:      `{"type": "tcp", "addr": "127.0.0.1:8002", "weight": 4}`,
+			unmarshal: json.Unmarshal,
+			expect: func(t *testing.T, netAddr NetAddr, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(netAddr, NetAddr{Type: TCP, Addr: "127.0.0.1:8002", Weight: 4})
+			},
+		},
+		{
+			name:      "unmarshal yaml string",
+			data:      "127.0.0.1:8002=2",
+			unmarshal: yaml.Unmarshal,
+			expect: func(t *testing.T, netAddr NetAddr, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(netAddr, NetAddr{Type: TCP, Addr: "127.0.0.1:8002", Weight: 2})
+			},
+		},
+		{
+			name:      "unmarshal yaml map without weight",
+			data:      "type: tcp\naddr: 127.0.0.1:8002\n",
+			unmarshal: yaml.Unmarshal,
+			expect: func(t *testing.T, netAddr NetAddr, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(netAddr, NetAddr{Type: TCP, Addr: "127.0.0.1:8002"})
+				assert.Equal(netAddr.GetWeight(), DefaultWeight)
+			},
+		},
+		{
+			name:      "unmarshal yaml map with negative weight",
+			data:      "type: tcp\naddr: 127.0.0.1:8002\nweight: -1\n",
+			unmarshal: yaml.Unmarshal,
+			expect: func(t *testing.T, netAddr NetAddr, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "invalid weight of addr \"127.0.0.1:8002\"")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var netAddr NetAddr
+			err := tc.unmarshal([]byte(tc.data), &netAddr)
+			tc.expect(t, netAddr, err)
+		})
+	}
+}
@@ -40,22 +40,24 @@ type Dfpath interface {
 	PluginDir() string
 	DaemonSockPath() string
 	DaemonLockPath() string
+	DaemonReadyPath() string
 	DfgetLockPath() string
 }
 
 // Dfpath provides init project path function.
 type dfpath struct {
-	workHome       string
-	workHomeMode   fs.FileMode
-	cacheDir       string
-	cacheDirMode   fs.FileMode
-	logDir         string
-	dataDir        string
-	dataDirMode    fs.FileMode
-	pluginDir      string
-	daemonSockPath string
-	daemonLockPath string
-	dfgetLockPath  string
+	workHome        string
+	workHomeMode    fs.FileMode
+	cacheDir        string
+	cacheDirMode    fs.FileMode
+	logDir          string
+	dataDir         string
+	dataDirMode     fs.FileMode
+	pluginDir       string
+	daemonSockPath  string
+	daemonLockPath  string
+	daemonReadyPath string
+	dfgetLockPath   string
 }
 
 // Cache of the dfpath.
@@ -152,6 +154,7 @@ func New(options ...Option) (Dfpath, error) {
 
 		// Initialize dfdaemon path.
 		d.daemonLockPath = filepath.Join(d.workHome, "daemon.lock")
+		d.daemonReadyPath = filepath.Join(d.workHome, "daemon.ready")
 		d.dfgetLockPath = filepath.Join(d.workHome, "dfget.lock")
 
 		// Create workhome directory.
@@ -235,6 +238,10 @@ func (d *dfpath) DaemonLockPath() string {
 	return d.daemonLockPath
 }
 
+func (d *dfpath) DaemonReadyPath() string {
+	return d.daemonReadyPath
+}
+
 func (d *dfpath) DfgetLockPath() string {
 	return d.dfgetLockPath
 }
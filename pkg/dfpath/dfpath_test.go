@@ -18,6 +18,7 @@ package dfpath
 
 import (
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 
@@ -57,6 +58,7 @@ func TestNew(t *testing.T) {
 				assert.Equal(d.DataDirMode(), DefaultDataDirMode)
 				assert.Equal(d.PluginDir(), DefaultPluginDir)
 				assert.Equal(d.DaemonSockPath(), DefaultDownloadUnixSocketPath)
+				assert.Equal(d.DaemonReadyPath(), filepath.Join(DefaultWorkHome, "daemon.ready"))
 			},
 		},
 		{
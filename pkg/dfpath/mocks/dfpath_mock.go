@@ -81,6 +81,20 @@ func (mr *MockDfpathMockRecorder) DaemonLockPath() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DaemonLockPath", reflect.TypeOf((*MockDfpath)(nil).DaemonLockPath))
 }
 
+// DaemonReadyPath mocks base method.
+func (m *MockDfpath) DaemonReadyPath() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DaemonReadyPath")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// DaemonReadyPath indicates an expected call of DaemonReadyPath.
+func (mr *MockDfpathMockRecorder) DaemonReadyPath() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DaemonReadyPath", reflect.TypeOf((*MockDfpath)(nil).DaemonReadyPath))
+}
+
 // DaemonSockPath mocks base method.
 func (m *MockDfpath) DaemonSockPath() string {
 	m.ctrl.T.Helper()
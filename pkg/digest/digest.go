@@ -38,6 +38,9 @@ const (
 	// AlgorithmCRC32 is crc32 algorithm name of hash.
 	AlgorithmCRC32 = "crc32"
 
+	// AlgorithmCRC32C is crc32c algorithm name of hash, it uses the castagnoli polynomial.
+	AlgorithmCRC32C = "crc32c"
+
 	// AlgorithmBlake3 is blake3 algorithm name of hash.
 	AlgorithmBlake3 = "blake3"
 
@@ -54,6 +57,9 @@ const (
 	AlgorithmMD5 = "md5"
 )
 
+// crc32cTable is the castagnoli table of crc32c.
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
 // Digest provides digest operation function.
 type Digest struct {
 	// Algorithm is hash algorithm.
@@ -76,30 +82,39 @@ func New(algorithm, encoded string) *Digest {
 	}
 }
 
-// HashFile computes hash value corresponding to algorithm.
-func HashFile(path string, algorithm string) (string, error) {
-	f, err := os.Open(path)
-	if err != nil {
-		return "", err
-	}
-	defer f.Close()
-
-	var h hash.Hash
+// NewHash returns a new hash.Hash corresponding to algorithm.
+func NewHash(algorithm string) (hash.Hash, error) {
 	switch algorithm {
 	case AlgorithmCRC32:
-		h = crc32.NewIEEE()
+		return crc32.NewIEEE(), nil
+	case AlgorithmCRC32C:
+		return crc32.New(crc32cTable), nil
 	case AlgorithmBlake3:
-		h = blake3.New()
+		return blake3.New(), nil
 	case AlgorithmSHA1:
-		h = sha1.New()
+		return sha1.New(), nil
 	case AlgorithmSHA256:
-		h = sha256.New()
+		return sha256.New(), nil
 	case AlgorithmSHA512:
-		h = sha512.New()
+		return sha512.New(), nil
 	case AlgorithmMD5:
-		h = md5.New()
+		return md5.New(), nil
 	default:
-		return "", fmt.Errorf("unsupport digest method: %s", algorithm)
+		return nil, fmt.Errorf("unsupport digest method: %s", algorithm)
+	}
+}
+
+// HashFile computes hash value corresponding to algorithm.
+func HashFile(path string, algorithm string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h, err := NewHash(algorithm)
+	if err != nil {
+		return "", err
 	}
 
 	r := bufio.NewReader(f)
@@ -122,7 +137,7 @@ func Parse(digest string) (*Digest, error) {
 	encoded := values[1]
 
 	switch algorithm {
-	case AlgorithmCRC32:
+	case AlgorithmCRC32, AlgorithmCRC32C:
 		if len(encoded) != 8 {
 			return nil, errors.New("invalid encoded")
 		}
@@ -196,3 +211,21 @@ func SHA256FromBytes(bytes []byte) string {
 	h.Write(bytes)
 	return hex.EncodeToString(h.Sum(nil))
 }
+
+// CRC32CFromReader computes the CRC32C checksum with io.Reader.
+func CRC32CFromReader(reader io.Reader) string {
+	h := crc32.New(crc32cTable)
+	r := bufio.NewReader(reader)
+	if _, err := io.Copy(h, r); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// CRC32CFromBytes computes the CRC32C checksum with []byte.
+func CRC32CFromBytes(bytes []byte) string {
+	h := crc32.New(crc32cTable)
+	h.Write(bytes)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -27,6 +27,7 @@ import (
 	"errors"
 	"fmt"
 	"hash"
+	"hash/crc32"
 	"io"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
@@ -75,6 +76,8 @@ func NewReader(algorithm string, r io.Reader, options ...Option) (Reader, error)
 		h = sha512.New()
 	case AlgorithmMD5:
 		h = md5.New()
+	case AlgorithmCRC32C:
+		h = crc32.New(crc32cTable)
 	default:
 		return nil, fmt.Errorf("invalid algorithm: %s", algorithm)
 	}
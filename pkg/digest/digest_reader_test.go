@@ -96,6 +96,21 @@ func TestDigest_Reader(t *testing.T) {
 				assert.Equal(reader.Encoded(), "acbd18db4cc2f85cedef654fccc4a4d8")
 			},
 		},
+		{
+			name:      "crc32c reader",
+			algorithm: AlgorithmCRC32C,
+			data:      []byte("foo"),
+			options:   []Option{WithLogger(log)},
+			run: func(t *testing.T, data []byte, reader Reader, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(reader.Encoded(), "00000000")
+				b, err := io.ReadAll(reader)
+				assert.NoError(err)
+				assert.Equal(b, data)
+				assert.Equal(reader.Encoded(), "cfc4ae1d")
+			},
+		},
 		{
 			name:      "sha1 reader with encoded",
 			algorithm: AlgorithmSHA1,
@@ -17,6 +17,7 @@
 package digest
 
 import (
+	"bytes"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -46,6 +47,7 @@ func TestDigest_HashFile(t *testing.T) {
 		{AlgorithmSHA256, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"},
 		{AlgorithmSHA512, "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"},
 		{AlgorithmMD5, "5d41402abc4b2a76b9719d911017c592"},
+		{AlgorithmCRC32C, "9a71bb4c"},
 	}
 
 	if _, err := f.Write([]byte("hello")); err != nil {
@@ -133,6 +135,23 @@ func TestDigest_Parse(t *testing.T) {
 				assert.Error(err)
 			},
 		},
+		{
+			name:  "crc32c digest",
+			value: "crc32c:e3069283",
+			expect: func(t *testing.T, d *Digest, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.EqualValues(d, New(AlgorithmCRC32C, "e3069283"))
+			},
+		},
+		{
+			name:  "invalid crc32c encoded",
+			value: "crc32c:e306928",
+			expect: func(t *testing.T, d *Digest, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+			},
+		},
 		{
 			name:  "invalid algorithm",
 			value: "foo:5d41402abc4b2a76b9719d911017c592",
@@ -175,3 +194,23 @@ func TestDigest_SHA256FromStrings(t *testing.T) {
 func TestDigest_SHA256FromBytes(t *testing.T) {
 	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", SHA256FromBytes([]byte("hello")))
 }
+
+func TestDigest_CRC32CFromReader(t *testing.T) {
+	assert.Equal(t, "e3069283", CRC32CFromReader(strings.NewReader("123456789")))
+}
+
+func TestDigest_CRC32CFromBytes(t *testing.T) {
+	tests := []struct {
+		data    []byte
+		encoded string
+	}{
+		{[]byte{}, "00000000"},
+		{[]byte("123456789"), "e3069283"},
+		{make([]byte, 32), "8a9136aa"},
+		{bytes.Repeat([]byte{0xff}, 32), "62a8ab43"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.encoded, CRC32CFromBytes(tc.data))
+	}
+}
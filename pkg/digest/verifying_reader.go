@@ -0,0 +1,112 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package digest
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// ErrDigestMismatch is the error returned by the verifying reader if the digest
+// of the stream does not match the expected digest.
+var ErrDigestMismatch = errors.New("digest mismatch")
+
+// verifyingReader computes the digest while the stream is read, and verifies it at the end of the stream.
+type verifyingReader struct {
+	r        io.Reader
+	expected *Digest
+	hash     hash.Hash
+
+	// err is the error of the verification, it is returned by all the subsequent reads.
+	err error
+
+	// verified is whether the digest has been verified.
+	verified bool
+}
+
+// NewVerifyingReader returns a reader that computes the digest as bytes flow, and returns
+// the ErrDigestMismatch error instead of io.EOF if the digest does not match the expected digest.
+// The returned reader also implements io.Closer, Close closes the underlying reader and verifies
+// the digest of the bytes read so far, so the stream which is not read to the end does not match.
+func NewVerifyingReader(r io.Reader, expected *Digest) io.Reader {
+	vr := &verifyingReader{
+		r:        r,
+		expected: expected,
+	}
+
+	h, err := NewHash(expected.Algorithm)
+	if err != nil {
+		vr.err = err
+		return vr
+	}
+	vr.hash = h
+
+	return vr
+}
+
+// Read reads the stream and computes the digest, it verifies the digest at io.EOF.
+func (vr *verifyingReader) Read(p []byte) (int, error) {
+	if vr.err != nil {
+		return 0, vr.err
+	}
+
+	n, err := vr.r.Read(p)
+	if n > 0 {
+		vr.hash.Write(p[:n])
+	}
+
+	if errors.Is(err, io.EOF) {
+		if verr := vr.verify(); verr != nil {
+			return n, verr
+		}
+	}
+
+	return n, err
+}
+
+// Close closes the underlying reader if it is an io.Closer, and verifies the digest of the bytes read so far.
+func (vr *verifyingReader) Close() error {
+	var cerr error
+	if closer, ok := vr.r.(io.Closer); ok {
+		cerr = closer.Close()
+	}
+
+	if vr.err != nil {
+		return errors.Join(vr.err, cerr)
+	}
+
+	return errors.Join(vr.verify(), cerr)
+}
+
+// verify verifies the digest of the bytes read so far once, the error is kept for the subsequent reads.
+func (vr *verifyingReader) verify() error {
+	if vr.verified {
+		return vr.err
+	}
+	vr.verified = true
+
+	encoded := hex.EncodeToString(vr.hash.Sum(nil))
+	if !strings.EqualFold(encoded, vr.expected.Encoded) {
+		vr.err = fmt.Errorf("%w: expected %s, actual %s:%s", ErrDigestMismatch, vr.expected.String(), vr.expected.Algorithm, encoded)
+	}
+
+	return vr.err
+}
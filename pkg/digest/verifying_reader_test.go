@@ -0,0 +1,127 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package digest
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigest_NewVerifyingReader(t *testing.T) {
+	data := bytes.Repeat([]byte("foo"), 1024)
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected *Digest
+		run      func(t *testing.T, data []byte, reader io.Reader)
+	}{
+		{
+			name:     "matching stream",
+			data:     data,
+			expected: New(AlgorithmSHA256, SHA256FromBytes(data)),
+			run: func(t *testing.T, data []byte, reader io.Reader) {
+				assert := assert.New(t)
+				b, err := io.ReadAll(reader)
+				assert.NoError(err)
+				assert.Equal(data, b)
+				assert.NoError(reader.(io.Closer).Close())
+			},
+		},
+		{
+			name:     "matching stream with uppercase encoded",
+			data:     data,
+			expected: New(AlgorithmMD5, strings.ToUpper(MD5FromBytes(data))),
+			run: func(t *testing.T, data []byte, reader io.Reader) {
+				assert := assert.New(t)
+				b, err := io.ReadAll(reader)
+				assert.NoError(err)
+				assert.Equal(data, b)
+			},
+		},
+		{
+			name:     "matching empty stream",
+			data:     []byte{},
+			expected: New(AlgorithmMD5, MD5FromBytes([]byte{})),
+			run: func(t *testing.T, data []byte, reader io.Reader) {
+				assert := assert.New(t)
+				b, err := io.ReadAll(reader)
+				assert.NoError(err)
+				assert.Empty(b)
+			},
+		},
+		{
+			name:     "mismatching stream",
+			data:     data,
+			expected: New(AlgorithmSHA256, SHA256FromBytes([]byte("bar"))),
+			run: func(t *testing.T, data []byte, reader io.Reader) {
+				assert := assert.New(t)
+				b, err := io.ReadAll(reader)
+				assert.ErrorIs(err, ErrDigestMismatch)
+				assert.Equal(data, b)
+
+				// The mismatch error is kept for the subsequent reads and close.
+				_, err = reader.Read(make([]byte, 1))
+				assert.ErrorIs(err, ErrDigestMismatch)
+				assert.ErrorIs(reader.(io.Closer).Close(), ErrDigestMismatch)
+			},
+		},
+		{
+			name:     "stream is closed before the end",
+			data:     data,
+			expected: New(AlgorithmSHA256, SHA256FromBytes(data)),
+			run: func(t *testing.T, data []byte, reader io.Reader) {
+				assert := assert.New(t)
+				_, err := io.ReadFull(reader, make([]byte, 3))
+				assert.NoError(err)
+				assert.ErrorIs(reader.(io.Closer).Close(), ErrDigestMismatch)
+			},
+		},
+		{
+			name:     "stream read by one byte",
+			data:     data,
+			expected: New(AlgorithmMD5, MD5FromBytes(data)),
+			run: func(t *testing.T, data []byte, reader io.Reader) {
+				assert := assert.New(t)
+				b, err := io.ReadAll(iotest.OneByteReader(reader))
+				assert.NoError(err)
+				assert.Equal(data, b)
+			},
+		},
+		{
+			name:     "invalid algorithm",
+			data:     data,
+			expected: New("foo", "bar"),
+			run: func(t *testing.T, data []byte, reader io.Reader) {
+				assert := assert.New(t)
+				_, err := io.ReadAll(reader)
+				assert.EqualError(err, "unsupport digest method: foo")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.run(t, tc.data, NewVerifyingReader(bytes.NewReader(tc.data), tc.expected))
+		})
+	}
+}
@@ -0,0 +1,206 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package featureflag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+const (
+	// MetadataKey is the grpc metadata key of the feature flags forwarded to the daemon.
+	MetadataKey = "dragonfly-feature-flags"
+)
+
+const (
+	// PieceDownloadEngine is the engine of downloading pieces used by the daemon.
+	PieceDownloadEngine = "piece_download_engine"
+
+	// PieceCompression is the kill switch of piece compression, the daemon does not
+	// advertise the supported codecs if it is false.
+	PieceCompression = "piece_compression"
+
+	// PieceCompressionLevel overrides the compression level of the daemon if it is greater than zero.
+	PieceCompressionLevel = "piece_compression_level"
+)
+
+// Kind is the kind of the feature flag value.
+type Kind string
+
+const (
+	// KindBool is the kind of bool value.
+	KindBool Kind = "bool"
+
+	// KindInt is the kind of int64 value.
+	KindInt Kind = "int"
+
+	// KindString is the kind of string value.
+	KindString Kind = "string"
+)
+
+// Flag is the definition of a known feature flag.
+type Flag struct {
+	// Kind is the kind of the value.
+	Kind Kind
+
+	// Default is the value used when the flag is absent.
+	Default any
+}
+
+// Registry is the known feature flags of the daemon by name, the other flags are treated as absent.
+var Registry = map[string]Flag{
+	PieceDownloadEngine:   {Kind: KindString, Default: "v1"},
+	PieceCompression:      {Kind: KindBool, Default: true},
+	PieceCompressionLevel: {Kind: KindInt, Default: int64(0)},
+}
+
+// Flags is the valid feature flags by name, the values are normalized to the kind of the flags.
+type Flags map[string]any
+
+// Get returns the value of the flag, it is the default value of the flag if the flag is absent,
+// and it is the zero value if the flag is unknown.
+func (f Flags) Get(name string) Value {
+	if value, ok := f[name]; ok {
+		return Value{value: value}
+	}
+
+	if flag, ok := Registry[name]; ok {
+		return Value{value: flag.Default}
+	}
+
+	return Value{}
+}
+
+// Value is the value of the feature flag, the accessors return the zero value
+// if the value is not the kind of the accessor.
+type Value struct {
+	value any
+}
+
+// Bool returns the bool value.
+func (v Value) Bool() bool {
+	b, _ := v.value.(bool)
+	return b
+}
+
+// Int returns the int64 value.
+func (v Value) Int() int64 {
+	i, _ := v.value.(int64)
+	return i
+}
+
+// String returns the string value.
+func (v Value) String() string {
+	s, _ := v.value.(string)
+	return s
+}
+
+// Validate returns the error of the flags which are unknown or do not match the kind of the registry.
+func Validate(flags map[string]any) error {
+	var errs []error
+	for _, name := range sortedNames(flags) {
+		if _, err := normalize(name, flags[name]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Parse returns the valid flags, the flags which are unknown or do not match the kind of the registry are dropped.
+func Parse(flags map[string]any) Flags {
+	parsed := make(Flags, len(flags))
+	for name, value := range flags {
+		if value, err := normalize(name, value); err == nil {
+			parsed[name] = value
+		}
+	}
+
+	return parsed
+}
+
+// Encode encodes the flags into the value of the grpc metadata, the nil flags are encoded as empty.
+func Encode(flags Flags) (string, error) {
+	if flags == nil {
+		flags = Flags{}
+	}
+
+	b, err := json.Marshal(flags)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// Decode decodes the flags from the value of the grpc metadata, the flags unknown to the
+// registry are dropped, so the daemon treats the flags added by the newer scheduler as absent.
+func Decode(value string) (Flags, error) {
+	var flags map[string]any
+	if err := json.Unmarshal([]byte(value), &flags); err != nil {
+		return nil, err
+	}
+
+	return Parse(flags), nil
+}
+
+// normalize returns the value in the kind of the flag, the numbers decoded from json are float64,
+// so they are converted to int64 if they are integral.
+func normalize(name string, value any) (any, error) {
+	flag, ok := Registry[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown feature flag %s", name)
+	}
+
+	switch flag.Kind {
+	case KindBool:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case KindInt:
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return int64(v), nil
+			}
+		}
+	case KindString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	}
+
+	return nil, fmt.Errorf("feature flag %s requires %s value, but got %v", name, flag.Kind, value)
+}
+
+// sortedNames returns the names of flags in order, so the errors of validation are stable.
+func sortedNames(flags map[string]any) []string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
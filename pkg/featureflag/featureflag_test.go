@@ -0,0 +1,148 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package featureflag
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureFlag_Validate(t *testing.T) {
+	tests := []struct {
+		name   string
+		flags  map[string]any
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name: "valid flags",
+			flags: map[string]any{
+				PieceDownloadEngine:   "v2",
+				PieceCompression:      false,
+				PieceCompressionLevel: float64(3),
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "empty flags",
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "invalid flags",
+			flags: map[string]any{
+				"foo":                 true,
+				PieceCompression:      "false",
+				PieceCompressionLevel: 1.5,
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "unknown feature flag foo\n"+
+					"feature flag piece_compression requires bool value, but got false\n"+
+					"feature flag piece_compression_level requires int value, but got 1.5")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, Validate(tc.flags))
+		})
+	}
+}
+
+func TestFeatureFlag_Get(t *testing.T) {
+	flags := Parse(map[string]any{
+		"foo":                 true,
+		PieceCompression:      false,
+		PieceCompressionLevel: float64(3),
+	})
+
+	assert := assert.New(t)
+	assert.Len(flags, 2)
+	assert.False(flags.Get(PieceCompression).Bool())
+	assert.Equal(int64(3), flags.Get(PieceCompressionLevel).Int())
+
+	// The absent flag is the default value.
+	assert.Equal("v1", flags.Get(PieceDownloadEngine).String())
+	assert.True(Flags(nil).Get(PieceCompression).Bool())
+
+	// The unknown flag is absent.
+	assert.False(flags.Get("foo").Bool())
+
+	// The accessor of the other kind returns the zero value.
+	assert.Equal(int64(0), flags.Get(PieceCompression).Int())
+	assert.Equal("", flags.Get(PieceCompressionLevel).String())
+}
+
+func TestFeatureFlag_EncodeDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		expect func(t *testing.T, flags Flags, err error)
+	}{
+		{
+			name:  "decode flags",
+			value: `{"piece_download_engine":"v2","piece_compression_level":9}`,
+			expect: func(t *testing.T, flags Flags, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(Flags{PieceDownloadEngine: "v2", PieceCompressionLevel: int64(9)}, flags)
+			},
+		},
+		{
+			name:  "drop the flags unknown to the registry",
+			value: `{"piece_compression":false,"foo":"bar"}`,
+			expect: func(t *testing.T, flags Flags, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(Flags{PieceCompression: false}, flags)
+			},
+		},
+		{
+			name:  "decode invalid value",
+			value: "foo",
+			expect: func(t *testing.T, flags Flags, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			flags, err := Decode(tc.value)
+			tc.expect(t, flags, err)
+		})
+	}
+
+	assert := assert.New(t)
+	value, err := Encode(Flags{PieceCompression: false, PieceCompressionLevel: int64(9)})
+	assert.NoError(err)
+	flags, err := Decode(value)
+	assert.NoError(err)
+	assert.Equal(Flags{PieceCompression: false, PieceCompressionLevel: int64(9)}, flags)
+
+	value, err = Encode(nil)
+	assert.NoError(err)
+	assert.Equal("{}", value)
+}
@@ -20,11 +20,10 @@ package dag
 
 import (
 	"errors"
+	"fmt"
 	"sync"
 
 	"go.uber.org/atomic"
-
-	"d7y.io/dragonfly/v2/pkg/container/set"
 )
 
 var (
@@ -94,21 +93,44 @@ type dag[T comparable] struct {
 	vertices *sync.Map
 	count    *atomic.Uint64
 	mu       sync.RWMutex
+
+	// invariantCheck walks the whole graph after every mutation
+	// and panics if it is broken, it is only used for debugging.
+	invariantCheck bool
+}
+
+// Option is a functional option for configuring the dag.
+type Option[T comparable] func(d *dag[T])
+
+// WithInvariantCheck checks the invariants of the graph after every mutation,
+// e.g. no cycles and consistent edges between vertices. It is expensive and only
+// used for debugging and tests.
+func WithInvariantCheck[T comparable]() Option[T] {
+	return func(d *dag[T]) {
+		d.invariantCheck = true
+	}
 }
 
 // New returns a new DAG interface.
-func NewDAG[T comparable]() DAG[T] {
-	return &dag[T]{
+func NewDAG[T comparable](options ...Option[T]) DAG[T] {
+	d := &dag[T]{
 		vertices: &sync.Map{},
 		count:    atomic.NewUint64(0),
 		mu:       sync.RWMutex{},
 	}
+
+	for _, opt := range options {
+		opt(d)
+	}
+
+	return d
 }
 
 // AddVertex adds vertex to graph.
 func (d *dag[T]) AddVertex(id string, value T) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.mustCheckInvariants()
 
 	if _, loaded := d.vertices.LoadOrStore(id, NewVertex(id, value)); loaded {
 		return ErrVertexAlreadyExists
@@ -122,6 +144,7 @@ func (d *dag[T]) AddVertex(id string, value T) error {
 func (d *dag[T]) DeleteVertex(id string) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.mustCheckInvariants()
 
 	rawVertex, loaded := d.vertices.Load(id)
 	if !loaded {
@@ -166,6 +189,11 @@ func (d *dag[T]) GetVertices() map[string]*Vertex[T] {
 	d.mu.RLock()
 	defer d.mu.RUnlock()
 
+	return d.loadVertices()
+}
+
+// loadVertices returns map of vertices, the caller must hold the lock.
+func (d *dag[T]) loadVertices() map[string]*Vertex[T] {
 	vertices := make(map[string]*Vertex[T], d.count.Load())
 	d.vertices.Range(func(key, value interface{}) bool {
 		vertex, ok := value.(*Vertex[T])
@@ -217,6 +245,7 @@ func (d *dag[T]) VertexCount() uint64 {
 func (d *dag[T]) AddEdge(fromVertexID, toVertexID string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.mustCheckInvariants()
 
 	if fromVertexID == toVertexID {
 		return ErrCycleBetweenVertices
@@ -257,6 +286,7 @@ func (d *dag[T]) AddEdge(fromVertexID, toVertexID string) error {
 func (d *dag[T]) DeleteEdge(fromVertexID, toVertexID string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.mustCheckInvariants()
 
 	fromVertex, err := d.GetVertex(fromVertexID)
 	if err != nil {
@@ -308,6 +338,7 @@ func (d *dag[T]) CanAddEdge(fromVertexID, toVertexID string) bool {
 func (d *dag[T]) DeleteVertexInEdges(id string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.mustCheckInvariants()
 
 	vertex, err := d.GetVertex(id)
 	if err != nil {
@@ -318,7 +349,7 @@ func (d *dag[T]) DeleteVertexInEdges(id string) error {
 		parent.Children.Delete(vertex)
 	}
 
-	vertex.Parents = set.NewSafeSet[*Vertex[T]]()
+	vertex.Parents.Clear()
 	return nil
 }
 
@@ -326,6 +357,7 @@ func (d *dag[T]) DeleteVertexInEdges(id string) error {
 func (d *dag[T]) DeleteVertexOutEdges(id string) error {
 	d.mu.Lock()
 	defer d.mu.Unlock()
+	defer d.mustCheckInvariants()
 
 	vertex, err := d.GetVertex(id)
 	if err != nil {
@@ -336,7 +368,7 @@ func (d *dag[T]) DeleteVertexOutEdges(id string) error {
 		child.Parents.Delete(vertex)
 	}
 
-	vertex.Children = set.NewSafeSet[*Vertex[T]]()
+	vertex.Children.Clear()
 	return nil
 }
 
@@ -346,7 +378,7 @@ func (d *dag[T]) GetSourceVertices() []*Vertex[T] {
 	defer d.mu.RUnlock()
 
 	var sourceVertices []*Vertex[T]
-	for _, vertex := range d.GetVertices() {
+	for _, vertex := range d.loadVertices() {
 		if vertex.InDegree() == 0 {
 			sourceVertices = append(sourceVertices, vertex)
 		}
@@ -361,7 +393,7 @@ func (d *dag[T]) GetSinkVertices() []*Vertex[T] {
 	defer d.mu.RUnlock()
 
 	var sinkVertices []*Vertex[T]
-	for _, vertex := range d.GetVertices() {
+	for _, vertex := range d.loadVertices() {
 		if vertex.OutDegree() == 0 {
 			sinkVertices = append(sinkVertices, vertex)
 		}
@@ -392,3 +424,86 @@ func (d *dag[T]) search(vertexID string, successors map[string]struct{}) {
 		}
 	}
 }
+
+// mustCheckInvariants panics if the invariants of the graph are broken,
+// it does nothing when the invariant check is disabled.
+func (d *dag[T]) mustCheckInvariants() {
+	if !d.invariantCheck {
+		return
+	}
+
+	if err := d.checkInvariants(); err != nil {
+		panic(err)
+	}
+}
+
+// checkInvariants walks the graph and checks that edges are consistent
+// between parents and children and there are no cycles, the caller must hold the lock.
+func (d *dag[T]) checkInvariants() error {
+	vertices := d.loadVertices()
+	if uint64(len(vertices)) != d.count.Load() {
+		return fmt.Errorf("vertex count %d does not match %d vertices", d.count.Load(), len(vertices))
+	}
+
+	for id, vertex := range vertices {
+		for _, child := range vertex.Children.Values() {
+			if vertices[child.ID] != child {
+				return fmt.Errorf("child %s of vertex %s is not in graph", child.ID, id)
+			}
+
+			if !child.Parents.Contains(vertex) {
+				return fmt.Errorf("child %s does not have parent %s", child.ID, id)
+			}
+		}
+
+		for _, parent := range vertex.Parents.Values() {
+			if vertices[parent.ID] != parent {
+				return fmt.Errorf("parent %s of vertex %s is not in graph", parent.ID, id)
+			}
+
+			if !parent.Children.Contains(vertex) {
+				return fmt.Errorf("parent %s does not have child %s", parent.ID, id)
+			}
+		}
+	}
+
+	// Vertices in visiting are on the current search path,
+	// reaching one of them again means there is a cycle.
+	const (
+		visiting = iota + 1
+		visited
+	)
+
+	states := make(map[string]int, len(vertices))
+	var visit func(vertex *Vertex[T]) error
+	visit = func(vertex *Vertex[T]) error {
+		states[vertex.ID] = visiting
+		for _, child := range vertex.Children.Values() {
+			switch states[child.ID] {
+			case visiting:
+				return fmt.Errorf("%w: %s and %s", ErrCycleBetweenVertices, vertex.ID, child.ID)
+			case visited:
+				continue
+			}
+
+			if err := visit(child); err != nil {
+				return err
+			}
+		}
+
+		states[vertex.ID] = visited
+		return nil
+	}
+
+	for _, vertex := range vertices {
+		if states[vertex.ID] != 0 {
+			continue
+		}
+
+		if err := visit(vertex); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
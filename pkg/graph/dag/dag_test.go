@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -900,6 +901,119 @@ func TestDAG_SinkVertices(t *testing.T) {
 	}
 }
 
+func TestDAG_InvariantCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, d DAG[string])
+	}{
+		{
+			name: "graph is valid",
+			expect: func(t *testing.T, d DAG[string]) {
+				assert := assert.New(t)
+				for _, id := range []string{"a", "b", "c"} {
+					assert.NoError(d.AddVertex(id, mockVertexValue))
+				}
+
+				assert.NoError(d.AddEdge("a", "b"))
+				assert.NoError(d.AddEdge("b", "c"))
+				assert.ErrorIs(d.AddEdge("c", "a"), ErrCycleBetweenVertices)
+				assert.NoError(d.DeleteVertexInEdges("c"))
+				assert.NoError(d.AddEdge("a", "c"))
+				d.DeleteVertex("b")
+				assert.NoError(d.(*dag[string]).checkInvariants())
+			},
+		},
+		{
+			name: "graph has cycle",
+			expect: func(t *testing.T, d DAG[string]) {
+				assert := assert.New(t)
+				for _, id := range []string{"a", "b"} {
+					assert.NoError(d.AddVertex(id, mockVertexValue))
+				}
+
+				assert.NoError(d.AddEdge("a", "b"))
+
+				a, err := d.GetVertex("a")
+				assert.NoError(err)
+				b, err := d.GetVertex("b")
+				assert.NoError(err)
+				b.Children.Add(a)
+				a.Parents.Add(b)
+
+				assert.ErrorIs(d.(*dag[string]).checkInvariants(), ErrCycleBetweenVertices)
+				assert.Panics(func() {
+					d.AddVertex("c", mockVertexValue) // nolint: errcheck
+				})
+			},
+		},
+		{
+			name: "graph has inconsistent edge",
+			expect: func(t *testing.T, d DAG[string]) {
+				assert := assert.New(t)
+				for _, id := range []string{"a", "b"} {
+					assert.NoError(d.AddVertex(id, mockVertexValue))
+				}
+
+				a, err := d.GetVertex("a")
+				assert.NoError(err)
+				b, err := d.GetVertex("b")
+				assert.NoError(err)
+				a.Children.Add(b)
+
+				assert.EqualError(d.(*dag[string]).checkInvariants(), "child b does not have parent a")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			d := NewDAG[string](WithInvariantCheck[string]())
+			tc.expect(t, d)
+		})
+	}
+}
+
+func TestDAG_ConcurrentReplaceParent(t *testing.T) {
+	const (
+		vertexCount = 16
+		workerCount = 8
+		roundCount  = 500
+	)
+
+	d := NewDAG[string](WithInvariantCheck[string]())
+	for i := 0; i < vertexCount; i++ {
+		if err := d.AddVertex(fmt.Sprint(i), mockVertexValue); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workerCount; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < roundCount; n++ {
+				from := fmt.Sprint((w + n) % vertexCount)
+				to := fmt.Sprint((w*n + 1) % vertexCount)
+
+				// Replace parents of the vertex, as rescheduling does.
+				if err := d.DeleteVertexInEdges(to); err != nil {
+					t.Error(err)
+					return
+				}
+
+				if err := d.AddEdge(from, to); err != nil && !errors.Is(err, ErrCycleBetweenVertices) {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+	assert.NoError(t, d.(*dag[string]).checkInvariants())
+}
+
 func BenchmarkDAG_AddVertex(b *testing.B) {
 	var ids []string
 	d := NewDAG[string]()
@@ -33,8 +33,8 @@ func NewVertex[T comparable](id string, value T) *Vertex[T] {
 	return &Vertex[T]{
 		ID:       id,
 		Value:    value,
-		Parents:  set.New[*Vertex[T]](),
-		Children: set.New[*Vertex[T]](),
+		Parents:  set.NewSafeSet[*Vertex[T]](),
+		Children: set.NewSafeSet[*Vertex[T]](),
 	}
 }
 
@@ -22,6 +22,15 @@ import (
 	"d7y.io/dragonfly/v2/pkg/digest"
 )
 
+// HostID generates the canonical id of the host. It is shared by the dfdaemon announcing
+// itself, the scheduler loading seed peers from dynconfig and the manager syncing peers,
+// so the same machine always has the same id. The ports and the type of the host are
+// not part of the id, because they may differ between the config of the seed peer
+// in manager and the ports which the dfdaemon really listens on.
+func HostID(hostname, ip string) string {
+	return HostIDV2(ip, hostname)
+}
+
 // HostIDV1 generates v1 version of host id.
 func HostIDV1(hostname string, port int32) string {
 	return fmt.Sprintf("%s-%d", hostname, port)
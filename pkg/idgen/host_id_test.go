@@ -22,6 +22,42 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func TestHostID(t *testing.T) {
+	tests := []struct {
+		name     string
+		hostname string
+		ip       string
+		expect   func(t *testing.T, d string)
+	}{
+		{
+			name:     "generate HostID",
+			hostname: "foo",
+			ip:       "127.0.0.1",
+			expect: func(t *testing.T, d string) {
+				assert := assert.New(t)
+				assert.Equal(d, "52727e8408e0ee1f999086f241ec43d5b3dbda666f1a06ef1fcbe75b4e90fa17")
+				assert.Equal(d, HostIDV2("127.0.0.1", "foo"))
+				assert.NotEqual(d, HostIDV1("foo", 8002))
+			},
+		},
+		{
+			name:     "generate HostID with different hostname",
+			hostname: "bar",
+			ip:       "127.0.0.1",
+			expect: func(t *testing.T, d string) {
+				assert := assert.New(t)
+				assert.NotEqual(d, HostID("foo", "127.0.0.1"))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, HostID(tc.hostname, tc.ip))
+		})
+	}
+}
+
 func TestHostIDV1(t *testing.T) {
 	tests := []struct {
 		name     string
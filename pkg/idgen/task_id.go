@@ -17,11 +17,15 @@
 package idgen
 
 import (
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
 
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
 
 	pkgdigest "d7y.io/dragonfly/v2/pkg/digest"
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 	neturl "d7y.io/dragonfly/v2/pkg/net/url"
 	pkgstrings "d7y.io/dragonfly/v2/pkg/strings"
 )
@@ -29,8 +33,15 @@ import (
 const (
 	// FilteredQueryParamsSeparator is the separator of filtered query params.
 	FilteredQueryParamsSeparator = "&"
+
+	// IdentityHeadersSeparator is the separator of identity headers.
+	IdentityHeadersSeparator = ","
 )
 
+// contentAddressedPathReg matches the path of the content addressed registry url, e.g. the blob or the
+// manifest referenced by digest, the bytes of it are the same regardless of the identity headers.
+var contentAddressedPathReg = regexp.MustCompile(`/(blobs|manifests)/[A-Za-z0-9_+.-]+:[A-Fa-f0-9]{32,}$`)
+
 // TaskIDV1 generates v1 version of task id.
 // filter is separated by & character.
 func TaskIDV1(url string, meta *commonv1.UrlMeta) string {
@@ -50,7 +61,7 @@ func taskIDV1(url string, meta *commonv1.UrlMeta, ignoreRange bool) string {
 		return pkgdigest.SHA256FromStrings(url)
 	}
 
-	filteredQueryParams := parseFilteredQueryParams(meta.Filter)
+	filteredQueryParams := ParseFilteredQueryParams(meta.Filter)
 
 	var (
 		u   string
@@ -78,16 +89,99 @@ func taskIDV1(url string, meta *commonv1.UrlMeta, ignoreRange bool) string {
 		data = append(data, meta.Application)
 	}
 
+	if identity := identityHeaders(url, meta.Header); identity != "" {
+		data = append(data, identity)
+	}
+
 	return pkgdigest.SHA256FromStrings(data...)
 }
 
-// parseFilteredQueryParams parses filtered query params.
-func parseFilteredQueryParams(rawFilteredQueryParams string) []string {
+// ParseIdentityHeaders parses identity headers separated by comma character, the names are
+// lowercased, sorted and deduplicated, and the blank names are dropped.
+func ParseIdentityHeaders(rawIdentityHeaders string) []string {
+	if pkgstrings.IsBlank(rawIdentityHeaders) {
+		return nil
+	}
+
+	var identityHeaders []string
+	for _, identityHeader := range strings.Split(rawIdentityHeaders, IdentityHeadersSeparator) {
+		if identityHeader = strings.ToLower(strings.TrimSpace(identityHeader)); identityHeader != "" {
+			identityHeaders = append(identityHeaders, identityHeader)
+		}
+	}
+
+	identityHeaders = pkgstrings.Unique(identityHeaders)
+	sort.Strings(identityHeaders)
+	return identityHeaders
+}
+
+// identityHeaders returns the normalized values of the headers listed in the HeaderIdentityHeaders header,
+// the values of the same url with different identity headers, e.g. Accept of the manifest list and the
+// single manifest, are different tasks. The content addressed url is excluded, so the identity headers
+// do not drop the cache hit rate of the blobs.
+func identityHeaders(rawURL string, header map[string]string) string {
+	if len(header) == 0 {
+		return ""
+	}
+
+	// The keys of the header are not always canonical, e.g. the headers of dfget.
+	lowerHeader := make(map[string]string, len(header))
+	for k, v := range header {
+		lowerHeader[strings.ToLower(k)] = v
+	}
+
+	names := ParseIdentityHeaders(lowerHeader[strings.ToLower(nethttp.HeaderIdentityHeaders)])
+	if len(names) == 0 || isContentAddressedURL(rawURL) {
+		return ""
+	}
+
+	identity := make([]string, 0, len(names))
+	for _, name := range names {
+		identity = append(identity, name+":"+normalizeIdentityHeaderValue(lowerHeader[name]))
+	}
+
+	return strings.Join(identity, "\n")
+}
+
+// normalizeIdentityHeaderValue lowercases and sorts the comma separated elements of the header value,
+// e.g. the media types of Accept, so the equivalent values generate the same task id.
+func normalizeIdentityHeaderValue(value string) string {
+	var elements []string
+	for _, element := range strings.Split(value, ",") {
+		if element = strings.ToLower(strings.TrimSpace(element)); element != "" {
+			elements = append(elements, element)
+		}
+	}
+	sort.Strings(elements)
+
+	return strings.Join(elements, ",")
+}
+
+// isContentAddressedURL returns whether the url is referenced by the digest of the content.
+func isContentAddressedURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return contentAddressedPathReg.MatchString(u.Path)
+}
+
+// ParseFilteredQueryParams parses filtered query params separated by & character,
+// the blank filtered query params are dropped.
+func ParseFilteredQueryParams(rawFilteredQueryParams string) []string {
 	if pkgstrings.IsBlank(rawFilteredQueryParams) {
 		return nil
 	}
 
-	return strings.Split(rawFilteredQueryParams, FilteredQueryParamsSeparator)
+	var filteredQueryParams []string
+	for _, filteredQueryParam := range strings.Split(rawFilteredQueryParams, FilteredQueryParamsSeparator) {
+		if filteredQueryParam = strings.TrimSpace(filteredQueryParam); filteredQueryParam != "" {
+			filteredQueryParams = append(filteredQueryParams, filteredQueryParam)
+		}
+	}
+
+	return filteredQueryParams
 }
 
 // TaskIDV2 generates v2 version of task id.
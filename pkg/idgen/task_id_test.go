@@ -17,6 +17,7 @@
 package idgen
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -80,6 +81,30 @@ func TestTaskIDV1(t *testing.T) {
 				assert.Equal(d, "2773851c628744fb7933003195db436ce397c1722920696c4274ff804d86920b")
 			},
 		},
+		{
+			name: "generate taskID with unordered query and blank filter",
+			url:  "https://example.com?bar=bar&foo=foo",
+			meta: &commonv1.UrlMeta{
+				Tag:    "foo",
+				Filter: " bar && foo ",
+			},
+			expect: func(t *testing.T, d any) {
+				assert := assert.New(t)
+				assert.Equal(d, "2773851c628744fb7933003195db436ce397c1722920696c4274ff804d86920b")
+			},
+		},
+		{
+			name: "generate taskID with blank filter",
+			url:  "https://example.com",
+			meta: &commonv1.UrlMeta{
+				Tag:    "foo",
+				Filter: " & ",
+			},
+			expect: func(t *testing.T, d any) {
+				assert := assert.New(t)
+				assert.Equal(d, "2773851c628744fb7933003195db436ce397c1722920696c4274ff804d86920b")
+			},
+		},
 		{
 			name: "generate taskID with tag",
 			url:  "https://example.com",
@@ -106,6 +131,103 @@ func TestTaskIDV1(t *testing.T) {
 	}
 }
 
+func TestTaskIDV1_IdentityHeaders(t *testing.T) {
+	var (
+		manifestURL       = "https://example.com/v2/library/alpine/manifests/latest"
+		digestManifestURL = "https://example.com/v2/library/alpine/manifests/sha256:c5b1261d6d3e43071626931fc004f70149baeba2c8ec672bd4f27761f8e1ad6b"
+		blobURL           = "https://example.com/v2/library/alpine/blobs/sha256:c5b1261d6d3e43071626931fc004f70149baeba2c8ec672bd4f27761f8e1ad6b"
+		manifestList      = "application/vnd.docker.distribution.manifest.list.v2+json"
+		manifest          = "application/vnd.docker.distribution.manifest.v2+json"
+	)
+
+	newMeta := func(header map[string]string) *commonv1.UrlMeta {
+		return &commonv1.UrlMeta{Tag: "foo", Header: header}
+	}
+
+	tests := []struct {
+		name   string
+		url    string
+		metaA  *commonv1.UrlMeta
+		metaB  *commonv1.UrlMeta
+		expect func(t *testing.T, a, b string)
+	}{
+		{
+			name:  "manifest with different accept",
+			url:   manifestURL,
+			metaA: newMeta(map[string]string{"X-Dragonfly-Identity-Headers": "Accept", "Accept": manifestList}),
+			metaB: newMeta(map[string]string{"X-Dragonfly-Identity-Headers": "Accept", "Accept": manifest}),
+			expect: func(t *testing.T, a, b string) {
+				assert := assert.New(t)
+				assert.NotEqual(a, b)
+			},
+		},
+		{
+			name:  "manifest with equivalent accept",
+			url:   manifestURL,
+			metaA: newMeta(map[string]string{"X-Dragonfly-Identity-Headers": "Accept", "Accept": manifestList + ", " + manifest}),
+			metaB: newMeta(map[string]string{"x-dragonfly-identity-headers": " accept , ", "accept": strings.ToUpper(manifest) + "," + manifestList}),
+			expect: func(t *testing.T, a, b string) {
+				assert := assert.New(t)
+				assert.Equal(a, b)
+			},
+		},
+		{
+			name:  "manifest with different accept but without identity headers",
+			url:   manifestURL,
+			metaA: newMeta(map[string]string{"Accept": manifestList}),
+			metaB: newMeta(map[string]string{"Accept": manifest}),
+			expect: func(t *testing.T, a, b string) {
+				assert := assert.New(t)
+				assert.Equal(a, b)
+				assert.Equal(TaskIDV1(manifestURL, newMeta(nil)), a)
+			},
+		},
+		{
+			name:  "manifest with different header which is not identity header",
+			url:   manifestURL,
+			metaA: newMeta(map[string]string{"X-Dragonfly-Identity-Headers": "Accept", "Accept": manifest, "User-Agent": "foo"}),
+			metaB: newMeta(map[string]string{"X-Dragonfly-Identity-Headers": "Accept", "Accept": manifest, "User-Agent": "bar"}),
+			expect: func(t *testing.T, a, b string) {
+				assert := assert.New(t)
+				assert.Equal(a, b)
+			},
+		},
+		{
+			name:  "manifest referenced by digest with different accept",
+			url:   digestManifestURL,
+			metaA: newMeta(map[string]string{"X-Dragonfly-Identity-Headers": "Accept", "Accept": manifestList}),
+			metaB: newMeta(map[string]string{"X-Dragonfly-Identity-Headers": "Accept", "Accept": manifest}),
+			expect: func(t *testing.T, a, b string) {
+				assert := assert.New(t)
+				assert.Equal(a, b)
+			},
+		},
+		{
+			name:  "blob with different accept",
+			url:   blobURL,
+			metaA: newMeta(map[string]string{"X-Dragonfly-Identity-Headers": "Accept", "Accept": manifestList}),
+			metaB: newMeta(map[string]string{"Accept": manifest}),
+			expect: func(t *testing.T, a, b string) {
+				assert := assert.New(t)
+				assert.Equal(a, b)
+				assert.Equal(TaskIDV1(blobURL, newMeta(nil)), a)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, TaskIDV1(tc.url, tc.metaA), TaskIDV1(tc.url, tc.metaB))
+		})
+	}
+}
+
+func TestParseIdentityHeaders(t *testing.T) {
+	assert := assert.New(t)
+	assert.Nil(ParseIdentityHeaders(" "))
+	assert.Equal([]string{"accept", "x-foo"}, ParseIdentityHeaders("X-Foo, Accept,,accept "))
+}
+
 func TestTaskIDV2(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -164,6 +286,24 @@ func TestTaskIDV2(t *testing.T) {
 				assert.Equal(d, "100680ad546ce6a577f42f52df33b4cfdca756859e664b8d7de329b150d09ce9")
 			},
 		},
+		{
+			name:    "generate taskID with blank filters",
+			url:     "https://example.com",
+			filters: []string{"", " "},
+			expect: func(t *testing.T, d any) {
+				assert := assert.New(t)
+				assert.Equal(d, "100680ad546ce6a577f42f52df33b4cfdca756859e664b8d7de329b150d09ce9")
+			},
+		},
+		{
+			name:    "generate taskID with filters and unordered query",
+			url:     "https://example.com?b=2&foo=foo&a=1",
+			filters: []string{" foo", ""},
+			expect: func(t *testing.T, d any) {
+				assert := assert.New(t)
+				assert.Equal(d, "9319e9890ae806dcf8d074d9c5cfc8c306b35c58588fb4de5bb9d61aa2f30d6a")
+			},
+		},
 	}
 
 	for _, tc := range tests {
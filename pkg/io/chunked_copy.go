@@ -0,0 +1,60 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package io
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ChunkedCopy reads src in chunks of chunkSize and passes every chunk to dst,
+// until src reaches EOF or ctx is done. All chunks are full except the last one.
+// The chunk buffer is reused between calls, so dst must not retain it.
+func ChunkedCopy(ctx context.Context, dst func([]byte) error, src io.Reader, chunkSize int) (int64, error) {
+	if chunkSize <= 0 {
+		return 0, fmt.Errorf("invalid chunk size %d", chunkSize)
+	}
+
+	var (
+		written int64
+		buf     = make([]byte, chunkSize)
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			if err := dst(buf[:n]); err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return written, nil
+			}
+
+			return written, err
+		}
+	}
+}
@@ -0,0 +1,131 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package io
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkedCopy(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		chunkSize int
+		dst       func(chunks *[][]byte) func([]byte) error
+		ctx       func() context.Context
+		expect    func(t *testing.T, chunks [][]byte, n int64, err error)
+	}{
+		{
+			name:      "copy data in chunks",
+			data:      []byte("foobarbaz"),
+			chunkSize: 4,
+			expect: func(t *testing.T, chunks [][]byte, n int64, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(int64(9), n)
+				assert.Equal([][]byte{[]byte("foob"), []byte("arba"), []byte("z")}, chunks)
+			},
+		},
+		{
+			name:      "copy data with exact chunks",
+			data:      []byte("foobar"),
+			chunkSize: 3,
+			expect: func(t *testing.T, chunks [][]byte, n int64, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(int64(6), n)
+				assert.Equal([][]byte{[]byte("foo"), []byte("bar")}, chunks)
+			},
+		},
+		{
+			name:      "copy empty data",
+			data:      []byte{},
+			chunkSize: 3,
+			expect: func(t *testing.T, chunks [][]byte, n int64, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(int64(0), n)
+				assert.Len(chunks, 0)
+			},
+		},
+		{
+			name:      "invalid chunk size",
+			data:      []byte("foo"),
+			chunkSize: 0,
+			expect: func(t *testing.T, chunks [][]byte, n int64, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+			},
+		},
+		{
+			name:      "dst returns error",
+			data:      []byte("foobar"),
+			chunkSize: 3,
+			dst: func(chunks *[][]byte) func([]byte) error {
+				return func(b []byte) error {
+					return errors.New("foo")
+				}
+			},
+			expect: func(t *testing.T, chunks [][]byte, n int64, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "foo")
+				assert.Equal(int64(0), n)
+			},
+		},
+		{
+			name:      "context canceled",
+			data:      []byte("foobar"),
+			chunkSize: 3,
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			expect: func(t *testing.T, chunks [][]byte, n int64, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, context.Canceled)
+				assert.Equal(int64(0), n)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var chunks [][]byte
+			dst := func(b []byte) error {
+				chunks = append(chunks, append([]byte{}, b...))
+				return nil
+			}
+			if tc.dst != nil {
+				dst = tc.dst(&chunks)
+			}
+
+			ctx := context.Background()
+			if tc.ctx != nil {
+				ctx = tc.ctx()
+			}
+
+			n, err := ChunkedCopy(ctx, dst, bytes.NewReader(tc.data), tc.chunkSize)
+			tc.expect(t, chunks, n, err)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package io
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// CountingReadCloser wraps an io.ReadCloser and counts the bytes read from it.
+type CountingReadCloser struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+// NewCountingReadCloser returns a CountingReadCloser wrapping readCloser.
+func NewCountingReadCloser(readCloser io.ReadCloser) *CountingReadCloser {
+	return &CountingReadCloser{ReadCloser: readCloser}
+}
+
+// Read reads from the underlying reader and accumulates the bytes read.
+func (c *CountingReadCloser) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// BytesRead returns the number of bytes read so far.
+func (c *CountingReadCloser) BytesRead() int64 {
+	return c.n.Load()
+}
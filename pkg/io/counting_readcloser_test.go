@@ -0,0 +1,84 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package io
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountingReadCloser(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		expect func(t *testing.T, readCloser *CountingReadCloser)
+	}{
+		{
+			name: "read all data",
+			data: []byte("foo"),
+			expect: func(t *testing.T, readCloser *CountingReadCloser) {
+				assert := assert.New(t)
+				assert.Equal(int64(0), readCloser.BytesRead())
+				b, err := io.ReadAll(readCloser)
+				assert.NoError(err)
+				assert.Equal([]byte("foo"), b)
+				assert.Equal(int64(3), readCloser.BytesRead())
+				assert.NoError(readCloser.Close())
+			},
+		},
+		{
+			name: "read partial data",
+			data: []byte("foobar"),
+			expect: func(t *testing.T, readCloser *CountingReadCloser) {
+				assert := assert.New(t)
+				n, err := readCloser.Read(make([]byte, 4))
+				assert.NoError(err)
+				assert.Equal(4, n)
+				assert.Equal(int64(4), readCloser.BytesRead())
+			},
+		},
+		{
+			name: "read empty data",
+			data: []byte{},
+			expect: func(t *testing.T, readCloser *CountingReadCloser) {
+				assert := assert.New(t)
+				_, err := io.ReadAll(readCloser)
+				assert.NoError(err)
+				assert.Equal(int64(0), readCloser.BytesRead())
+			},
+		},
+		{
+			name: "read error",
+			expect: func(t *testing.T, readCloser *CountingReadCloser) {
+				assert := assert.New(t)
+				readCloser.ReadCloser = &mockReadCloserWithReadError{}
+				_, err := readCloser.Read(make([]byte, 4))
+				assert.Error(err)
+				assert.Equal(int64(0), readCloser.BytesRead())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, NewCountingReadCloser(io.NopCloser(bytes.NewReader(tc.data))))
+		})
+	}
+}
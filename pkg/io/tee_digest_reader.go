@@ -0,0 +1,57 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package io
+
+import (
+	"encoding/hex"
+	"hash"
+	"io"
+
+	"d7y.io/dragonfly/v2/pkg/digest"
+)
+
+// TeeDigestReader computes the digest of the content while it is streamed.
+type TeeDigestReader struct {
+	reader    io.Reader
+	algorithm string
+	hash      hash.Hash
+}
+
+// NewTeeDigestReader returns a TeeDigestReader that hashes everything read
+// from reader with the given algorithm.
+func NewTeeDigestReader(reader io.Reader, algorithm string) (*TeeDigestReader, error) {
+	h, err := digest.NewHash(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TeeDigestReader{
+		reader:    io.TeeReader(reader, h),
+		algorithm: algorithm,
+		hash:      h,
+	}, nil
+}
+
+// Read reads from the underlying reader and writes the content to the hash.
+func (t *TeeDigestReader) Read(p []byte) (int, error) {
+	return t.reader.Read(p)
+}
+
+// Digest returns the digest of the content read so far.
+func (t *TeeDigestReader) Digest() *digest.Digest {
+	return digest.New(t.algorithm, hex.EncodeToString(t.hash.Sum(nil)))
+}
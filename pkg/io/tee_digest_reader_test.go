@@ -0,0 +1,80 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package io
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/pkg/digest"
+)
+
+func TestTeeDigestReader(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		data      []byte
+		expect    func(t *testing.T, reader *TeeDigestReader, err error)
+	}{
+		{
+			name:      "md5 reader",
+			algorithm: digest.AlgorithmMD5,
+			data:      []byte("foo"),
+			expect: func(t *testing.T, reader *TeeDigestReader, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				b, err := io.ReadAll(reader)
+				assert.NoError(err)
+				assert.Equal([]byte("foo"), b)
+				assert.Equal("md5:acbd18db4cc2f85cedef654fccc4a4d8", reader.Digest().String())
+			},
+		},
+		{
+			name:      "sha256 reader",
+			algorithm: digest.AlgorithmSHA256,
+			data:      []byte("foo"),
+			expect: func(t *testing.T, reader *TeeDigestReader, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", reader.Digest().Encoded)
+				_, err = io.ReadAll(reader)
+				assert.NoError(err)
+				assert.Equal("2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae", reader.Digest().Encoded)
+			},
+		},
+		{
+			name:      "invalid algorithm",
+			algorithm: "foo",
+			data:      []byte("foo"),
+			expect: func(t *testing.T, reader *TeeDigestReader, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+				assert.Nil(reader)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reader, err := NewTeeDigestReader(bytes.NewReader(tc.data), tc.algorithm)
+			tc.expect(t, reader, err)
+		})
+	}
+}
@@ -29,6 +29,74 @@ const (
 	DefaultDialTimeout = 30 * time.Second
 )
 
+const (
+	// HeaderClientCertificate is the base64 encoded PEM client certificate used in the
+	// TLS handshake with the source, it is never sent to the source as a header.
+	HeaderClientCertificate = "X-Dragonfly-Client-Certificate"
+
+	// HeaderClientKey is the base64 encoded PEM private key of HeaderClientCertificate.
+	HeaderClientKey = "X-Dragonfly-Client-Key"
+
+	// HeaderAWSAccessKeyID is the aws access key id used to sign the requests to the source
+	// with aws signature version 4, it is never sent to the source as a header.
+	HeaderAWSAccessKeyID = "X-Dragonfly-Aws-Access-Key-Id"
+
+	// HeaderAWSSecretAccessKey is the aws secret access key of HeaderAWSAccessKeyID.
+	HeaderAWSSecretAccessKey = "X-Dragonfly-Aws-Secret-Access-Key"
+
+	// HeaderAWSSessionToken is the aws session token of the temporary credentials, e.g. the assumed role.
+	HeaderAWSSessionToken = "X-Dragonfly-Aws-Session-Token"
+
+	// HeaderAWSRegion is the aws region of the signature, it is derived from the host if it is empty.
+	HeaderAWSRegion = "X-Dragonfly-Aws-Region"
+
+	// HeaderAWSService is the aws service of the signature, the default service is s3.
+	HeaderAWSService = "X-Dragonfly-Aws-Service"
+
+	// HeaderPreferSeed is true if the scheduler prefers the seed peers to the normal peers
+	// as parents of the peer, it is not sent to the source.
+	HeaderPreferSeed = "X-Dragonfly-Prefer-Seed"
+
+	// HeaderIdentityHeaders is the comma separated names of the headers whose values are folded into
+	// the task id, e.g. Accept of the registry manifest, it is not sent to the source.
+	HeaderIdentityHeaders = "X-Dragonfly-Identity-Headers"
+)
+
+// sensitiveHeaders carry the credentials of the source,
+// their values must not be logged or persisted.
+var sensitiveHeaders = []string{HeaderClientCertificate, HeaderClientKey, HeaderAWSAccessKeyID, HeaderAWSSecretAccessKey, HeaderAWSSessionToken}
+
+// IsSensitiveHeader reports whether the header carries the credentials of the source.
+func IsSensitiveHeader(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	for _, h := range sensitiveHeaders {
+		if key == h {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithoutSensitiveHeaders returns a copy of the headers without the sensitive headers,
+// it is used before the headers are logged or persisted.
+func WithoutSensitiveHeaders(header map[string]string) map[string]string {
+	if header == nil {
+		return nil
+	}
+
+	m := make(map[string]string, len(header))
+	for k, v := range header {
+		if IsSensitiveHeader(k) {
+			continue
+		}
+
+		m[k] = v
+	}
+
+	return m
+}
+
 // HeaderToMap coverts request headers to map[string]string.
 func HeaderToMap(header http.Header) map[string]string {
 	m := make(map[string]string)
@@ -58,6 +126,11 @@ func PickHeader(header http.Header, key, defaultValue string) string {
 	return defaultValue
 }
 
+// IsServerError reports whether the status code is the server error, e.g. 502 Bad Gateway.
+func IsServerError(statusCode int) bool {
+	return statusCode >= http.StatusInternalServerError && statusCode < 600
+}
+
 // NewSafeDialer returns a new net.Dialer with safe socket control.
 func NewSafeDialer() *net.Dialer {
 	return &net.Dialer{
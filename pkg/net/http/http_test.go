@@ -157,6 +157,64 @@ func TestPickHeader(t *testing.T) {
 	}
 }
 
+func TestIsServerError(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		expect     bool
+	}{
+		{statusCode: 0, expect: false},
+		{statusCode: http.StatusOK, expect: false},
+		{statusCode: http.StatusNotFound, expect: false},
+		{statusCode: http.StatusInternalServerError, expect: true},
+		{statusCode: http.StatusServiceUnavailable, expect: true},
+		{statusCode: 600, expect: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(http.StatusText(tc.statusCode), func(t *testing.T) {
+			testifyassert.New(t).Equal(tc.expect, IsServerError(tc.statusCode))
+		})
+	}
+}
+
+func TestWithoutSensitiveHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		expect func(t *testing.T, header map[string]string)
+	}{
+		{
+			name: "remove sensitive headers",
+			header: map[string]string{
+				"Foo":                            "foo",
+				"x-dragonfly-client-certificate": "bar",
+				HeaderClientKey:                  "baz",
+				HeaderAWSAccessKeyID:             "qux",
+				HeaderAWSSecretAccessKey:         "quux",
+				HeaderAWSSessionToken:            "corge",
+			},
+			expect: func(t *testing.T, header map[string]string) {
+				assert := testifyassert.New(t)
+				assert.Equal(map[string]string{"Foo": "foo"}, header)
+			},
+		},
+		{
+			name:   "header is nil",
+			header: nil,
+			expect: func(t *testing.T, header map[string]string) {
+				assert := testifyassert.New(t)
+				assert.Nil(header)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, WithoutSensitiveHeaders(tc.header))
+		})
+	}
+}
+
 func Test_safeSocketControl(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -19,7 +19,9 @@ package http
 import (
 	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/textproto"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -51,6 +53,24 @@ func (r *Range) URLMetaString() string {
 	return fmt.Sprintf("%d%s%d", r.Start, RangeSeparator, r.Start+r.Length-1)
 }
 
+// ContentRange specifies the Content-Range header of the range, size is the complete length of the content.
+func (r *Range) ContentRange(size int64) string {
+	return fmt.Sprintf("bytes %d%s%d/%d", r.Start, RangeSeparator, r.Start+r.Length-1, size)
+}
+
+// MIMEHeader specifies the header of the part in multipart/byteranges.
+func (r *Range) MIMEHeader(contentType string, size int64) textproto.MIMEHeader {
+	header := textproto.MIMEHeader{
+		"Content-Range": {r.ContentRange(size)},
+	}
+
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	return header
+}
+
 // ParseRange parses a Range header string as per RFC 7233.
 // ErrNoOverlap is returned if none of the ranges overlap.
 // Example:
@@ -178,3 +198,66 @@ func ParseOneRange(s string, size int64) (Range, error) {
 func ParseURLMetaRange(s string, size int64) (Range, error) {
 	return ParseOneRange(fmt.Sprintf("%s%s", RangePrefix, s), size)
 }
+
+// CoalesceRanges sorts the ranges by start and merges the overlapping or adjacent ranges,
+// the server may coalesce the ranges regardless of the order in which they appear in the Range header,
+// refer to https://www.rfc-editor.org/rfc/rfc9110#section-14.6.
+func CoalesceRanges(ranges []Range) []Range {
+	if len(ranges) <= 1 {
+		return ranges
+	}
+
+	sorted := append([]Range(nil), ranges...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start < sorted[j].Start
+	})
+
+	coalesced := []Range{sorted[0]}
+	for _, r := range sorted[1:] {
+		last := &coalesced[len(coalesced)-1]
+		if r.Start > last.Start+last.Length {
+			coalesced = append(coalesced, r)
+			continue
+		}
+
+		if end := r.Start + r.Length; end > last.Start+last.Length {
+			last.Length = end - last.Start
+		}
+	}
+
+	return coalesced
+}
+
+// MultipartByterangesSize returns the length of the multipart/byteranges body of the ranges,
+// the body is written by multipart.Writer with the boundary and the part header of Range.MIMEHeader.
+func MultipartByterangesSize(ranges []Range, boundary, contentType string, size int64) (int64, error) {
+	var w countingWriter
+	mw := multipart.NewWriter(&w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return 0, err
+	}
+
+	var length int64
+	for _, r := range ranges {
+		if _, err := mw.CreatePart(r.MIMEHeader(contentType, size)); err != nil {
+			return 0, err
+		}
+
+		length += r.Length
+	}
+
+	if err := mw.Close(); err != nil {
+		return 0, err
+	}
+
+	return length + int64(w), nil
+}
+
+// countingWriter counts the written bytes.
+type countingWriter int64
+
+// Write implements io.Writer.
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
@@ -17,6 +17,9 @@
 package http
 
 import (
+	"bytes"
+	"io"
+	"mime/multipart"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -81,6 +84,40 @@ func TestRange_String(t *testing.T) {
 	}
 }
 
+func TestRange_ContentRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		rg     Range
+		size   int64
+		expect func(t *testing.T, s string)
+	}{
+		{
+			name: "range at the beginning",
+			rg:   Range{Start: 0, Length: 10},
+			size: 100,
+			expect: func(t *testing.T, s string) {
+				assert := assert.New(t)
+				assert.Equal("bytes 0-9/100", s)
+			},
+		},
+		{
+			name: "range at the end",
+			rg:   Range{Start: 90, Length: 10},
+			size: 100,
+			expect: func(t *testing.T, s string) {
+				assert := assert.New(t)
+				assert.Equal("bytes 90-99/100", s)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, tc.rg.ContentRange(tc.size))
+		})
+	}
+}
+
 func TestRange_URLMetaString(t *testing.T) {
 	tests := []struct {
 		s      string
@@ -399,3 +436,102 @@ func TestMustParseRang(t *testing.T) {
 		}()
 	}
 }
+
+func TestCoalesceRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		ranges []Range
+		expect []Range
+	}{
+		{
+			name:   "empty ranges",
+			ranges: nil,
+			expect: nil,
+		},
+		{
+			name:   "one range",
+			ranges: []Range{{Start: 10, Length: 10}},
+			expect: []Range{{Start: 10, Length: 10}},
+		},
+		{
+			name:   "sorted ranges without overlap",
+			ranges: []Range{{Start: 0, Length: 10}, {Start: 20, Length: 10}},
+			expect: []Range{{Start: 0, Length: 10}, {Start: 20, Length: 10}},
+		},
+		{
+			name:   "unsorted ranges without overlap",
+			ranges: []Range{{Start: 50, Length: 10}, {Start: 0, Length: 10}, {Start: 20, Length: 10}},
+			expect: []Range{{Start: 0, Length: 10}, {Start: 20, Length: 10}, {Start: 50, Length: 10}},
+		},
+		{
+			name:   "overlapping ranges",
+			ranges: []Range{{Start: 0, Length: 10}, {Start: 5, Length: 10}, {Start: 30, Length: 10}},
+			expect: []Range{{Start: 0, Length: 15}, {Start: 30, Length: 10}},
+		},
+		{
+			name:   "contained range",
+			ranges: []Range{{Start: 0, Length: 100}, {Start: 10, Length: 10}},
+			expect: []Range{{Start: 0, Length: 100}},
+		},
+		{
+			name:   "adjacent ranges",
+			ranges: []Range{{Start: 10, Length: 10}, {Start: 0, Length: 10}},
+			expect: []Range{{Start: 0, Length: 20}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.expect, CoalesceRanges(tc.ranges))
+		})
+	}
+}
+
+func TestMultipartByterangesSize(t *testing.T) {
+	content := []byte("0123456789abcdefghijklmnopqrstuvwxyz")
+	ranges := []Range{{Start: 0, Length: 5}, {Start: 10, Length: 6}, {Start: 30, Length: 6}}
+	size := int64(len(content))
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, r := range ranges {
+		part, err := mw.CreatePart(r.MIMEHeader("text/plain", size))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := part.Write(content[r.Start : r.Start+r.Length]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert := assert.New(t)
+	length, err := MultipartByterangesSize(ranges, mw.Boundary(), "text/plain", size)
+	assert.NoError(err)
+	assert.Equal(int64(body.Len()), length)
+
+	mr := multipart.NewReader(&body, mw.Boundary())
+	for _, r := range ranges {
+		part, err := mr.NextPart()
+		if !assert.NoError(err) {
+			return
+		}
+
+		assert.Equal(r.ContentRange(size), part.Header.Get("Content-Range"))
+		assert.Equal("text/plain", part.Header.Get("Content-Type"))
+		data, err := io.ReadAll(part)
+		assert.NoError(err)
+		assert.Equal(content[r.Start:r.Start+r.Length], data)
+	}
+
+	_, err = mr.NextPart()
+	assert.Equal(io.EOF, err)
+
+	_, err = MultipartByterangesSize(ranges, "invalid boundary\n", "text/plain", size)
+	assert.Error(err)
+}
@@ -0,0 +1,117 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listen
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// listenPIDEnv is the environment of the pid which the sockets are passed to.
+	listenPIDEnv = "LISTEN_PID"
+
+	// listenFDsEnv is the environment of the number of the passed sockets.
+	listenFDsEnv = "LISTEN_FDS"
+
+	// listenFDNamesEnv is the environment of the colon-separated names of the passed sockets.
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+)
+
+var (
+	// listenFDsStart is the first file descriptor of the passed sockets,
+	// refer to https://www.freedesktop.org/software/systemd/man/sd_listen_fds.html.
+	listenFDsStart = 3
+
+	// activatedFiles are the files of the sockets activated by systemd, grouped by name.
+	activatedFiles map[string][]*os.File
+
+	// activatedFilesMu protects activatedFiles.
+	activatedFilesMu sync.Mutex
+)
+
+// listenActivated returns the listener of the socket activated by systemd with the name,
+// it returns false if no socket with the name is activated.
+func listenActivated(name string) (net.Listener, bool, error) {
+	if name == "" {
+		return nil, false, nil
+	}
+
+	f := activatedFile(name)
+	if f == nil {
+		return nil, false, nil
+	}
+	defer f.Close()
+
+	ln, err := net.FileListener(f)
+	return ln, true, err
+}
+
+// activatedFile returns the file of the socket activated by systemd with the name,
+// every file is returned only once.
+func activatedFile(name string) *os.File {
+	activatedFilesMu.Lock()
+	defer activatedFilesMu.Unlock()
+
+	if activatedFiles == nil {
+		activatedFiles = loadActivatedFiles()
+	}
+
+	files := activatedFiles[name]
+	if len(files) == 0 {
+		return nil
+	}
+
+	activatedFiles[name] = files[1:]
+	return files[0]
+}
+
+// loadActivatedFiles loads the files of the sockets passed by systemd, the environments
+// are unset so that they are not inherited by the child processes.
+func loadActivatedFiles() map[string][]*os.File {
+	defer func() {
+		os.Unsetenv(listenPIDEnv)
+		os.Unsetenv(listenFDsEnv)
+		os.Unsetenv(listenFDNamesEnv)
+	}()
+
+	files := make(map[string][]*os.File)
+	pid, err := strconv.Atoi(os.Getenv(listenPIDEnv))
+	if err != nil || pid != os.Getpid() {
+		return files
+	}
+
+	n, err := strconv.Atoi(os.Getenv(listenFDsEnv))
+	if err != nil || n <= 0 {
+		return files
+	}
+
+	names := strings.Split(os.Getenv(listenFDNamesEnv), ":")
+	for i := 0; i < n; i++ {
+		var name string
+		if i < len(names) {
+			name = names[i]
+		}
+
+		files[name] = append(files[name], os.NewFile(uintptr(listenFDsStart+i), name))
+	}
+
+	return files
+}
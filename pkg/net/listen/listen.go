@@ -0,0 +1,195 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+
+	"d7y.io/dragonfly/v2/pkg/dfnet"
+)
+
+const (
+	// staleSocketDialTimeout is the timeout of dialing the existing unix socket
+	// to check whether it is still listened.
+	staleSocketDialTimeout = 500 * time.Millisecond
+)
+
+// Option is the option of the listener.
+type Option struct {
+	// Name is the name of the socket activated by systemd, it matches FileDescriptorName
+	// of systemd.socket. If the socket is activated, it is used instead of creating a new one.
+	Name string
+
+	// Network is the network of the listener, tcp and unix are supported.
+	Network dfnet.NetworkType
+
+	// Addr is the address of the listener, the host and port for tcp and
+	// the socket path for unix.
+	Addr string
+
+	// ReusePort sets SO_REUSEADDR and SO_REUSEPORT of the tcp socket where available,
+	// so that the port can be listened again while the previous process is still exiting.
+	ReusePort bool
+
+	// KeepAlive is the keep-alive period of the accepted tcp connections,
+	// if zero, the default period of go is used, if negative, keep-alive is disabled.
+	KeepAlive time.Duration
+
+	// SocketMode is the file mode of the unix socket, if zero, the mode is not changed.
+	SocketMode os.FileMode
+
+	// SocketOwner is the owner of the unix socket, if nil, the owner is not changed.
+	SocketOwner *Owner
+}
+
+// Owner is the owner of the file.
+type Owner struct {
+	// UID is the user id of the owner.
+	UID int
+
+	// GID is the group id of the owner.
+	GID int
+}
+
+// Listen returns the listener with the option, the socket activated by systemd is used first.
+func Listen(opt Option) (net.Listener, error) {
+	if ln, ok, err := listenActivated(opt.Name); ok {
+		return ln, err
+	}
+
+	switch opt.Network {
+	case dfnet.TCP:
+		return listenTCP(opt)
+	case dfnet.UNIX:
+		return listenUnix(opt)
+	default:
+		return nil, fmt.Errorf("unsupported network %s", opt.Network)
+	}
+}
+
+// ListenWithPortRange tries to listen a tcp port between startPort and endPort,
+// the address of the option is the host to listen. It returns the listener and
+// the listen port, the socket activated by systemd is used first regardless of the port range.
+func ListenWithPortRange(opt Option, startPort, endPort int) (net.Listener, int, error) {
+	if ln, ok, err := listenActivated(opt.Name); ok {
+		if err != nil {
+			return nil, -1, err
+		}
+
+		return ln, port(ln), nil
+	}
+
+	if endPort < startPort {
+		endPort = startPort
+	}
+
+	host := opt.Addr
+	for p := startPort; p <= endPort; p++ {
+		opt.Addr = net.JoinHostPort(host, strconv.Itoa(p))
+		ln, err := listenTCP(opt)
+		if err == nil {
+			return ln, port(ln), nil
+		}
+
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, -1, err
+		}
+	}
+
+	return nil, -1, fmt.Errorf("no available port to listen, port: %d - %d", startPort, endPort)
+}
+
+// listenTCP listens the tcp address with the socket options.
+func listenTCP(opt Option) (net.Listener, error) {
+	lc := net.ListenConfig{
+		KeepAlive: opt.KeepAlive,
+	}
+
+	if opt.ReusePort {
+		lc.Control = reusePort
+	}
+
+	return lc.Listen(context.Background(), string(dfnet.TCP), opt.Addr)
+}
+
+// listenUnix listens the unix socket, the stale socket left by the exited process is replaced.
+func listenUnix(opt Option) (net.Listener, error) {
+	if err := removeStaleSocket(opt.Addr); err != nil {
+		return nil, err
+	}
+
+	ln, err := net.Listen(string(dfnet.UNIX), opt.Addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if opt.SocketMode != 0 {
+		if err := os.Chmod(opt.Addr, opt.SocketMode); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+
+	if opt.SocketOwner != nil {
+		if err := os.Chown(opt.Addr, opt.SocketOwner.UID, opt.SocketOwner.GID); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+
+	return ln, nil
+}
+
+// removeStaleSocket removes the unix socket which is not listened by any process.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a unix socket", path)
+	}
+
+	conn, err := net.DialTimeout(string(dfnet.UNIX), path, staleSocketDialTimeout)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("unix socket %s is in use", path)
+	}
+
+	return os.Remove(path)
+}
+
+// port returns the port of the tcp listener, otherwise returns -1.
+func port(ln net.Listener) int {
+	if addr, ok := ln.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+
+	return -1
+}
@@ -0,0 +1,179 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package listen
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/pkg/dfnet"
+)
+
+func TestListen_Unix(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(t *testing.T, path string)
+		opt    Option
+		expect func(t *testing.T, path string, ln net.Listener, err error)
+	}{
+		{
+			name: "listen unix socket with mode and owner",
+			mock: func(t *testing.T, path string) {},
+			opt: Option{
+				SocketMode:  0600,
+				SocketOwner: &Owner{UID: os.Getuid(), GID: os.Getgid()},
+			},
+			expect: func(t *testing.T, path string, ln net.Listener, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				info, err := os.Stat(path)
+				assert.NoError(err)
+				assert.Equal(os.FileMode(0600), info.Mode().Perm())
+				assert.Equal(os.Getuid(), int(info.Sys().(*syscall.Stat_t).Uid))
+			},
+		},
+		{
+			name: "replace stale unix socket",
+			mock: func(t *testing.T, path string) {
+				ln, err := net.Listen("unix", path)
+				assert.NoError(t, err)
+				ln.(*net.UnixListener).SetUnlinkOnClose(false)
+				ln.Close()
+			},
+			expect: func(t *testing.T, path string, ln net.Listener, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				conn, err := net.Dial("unix", path)
+				assert.NoError(err)
+				conn.Close()
+			},
+		},
+		{
+			name: "unix socket is in use",
+			mock: func(t *testing.T, path string) {
+				ln, err := net.Listen("unix", path)
+				assert.NoError(t, err)
+				t.Cleanup(func() { ln.Close() })
+			},
+			expect: func(t *testing.T, path string, ln net.Listener, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "unix socket "+path+" is in use")
+			},
+		},
+		{
+			name: "path is not unix socket",
+			mock: func(t *testing.T, path string) {
+				assert.NoError(t, os.WriteFile(path, []byte("foo"), 0600))
+			},
+			expect: func(t *testing.T, path string, ln net.Listener, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, path+" exists and is not a unix socket")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "dfdaemon.sock")
+			tc.mock(t, path)
+
+			opt := tc.opt
+			opt.Network = dfnet.UNIX
+			opt.Addr = path
+			ln, err := Listen(opt)
+			if ln != nil {
+				defer ln.Close()
+			}
+
+			tc.expect(t, path, ln, err)
+		})
+	}
+}
+
+func TestListen_ReusePort(t *testing.T) {
+	assert := assert.New(t)
+	ln, err := Listen(Option{Network: dfnet.TCP, Addr: "127.0.0.1:0", ReusePort: true})
+	assert.NoError(err)
+	defer ln.Close()
+
+	another, err := Listen(Option{Network: dfnet.TCP, Addr: ln.Addr().String(), ReusePort: true})
+	assert.NoError(err)
+	defer another.Close()
+	assert.Equal(ln.Addr().String(), another.Addr().String())
+}
+
+func TestListenWithPortRange(t *testing.T) {
+	assert := assert.New(t)
+	ln, err := Listen(Option{Network: dfnet.TCP, Addr: "127.0.0.1:0"})
+	assert.NoError(err)
+	defer ln.Close()
+
+	used := ln.Addr().(*net.TCPAddr).Port
+	another, p, err := ListenWithPortRange(Option{Network: dfnet.TCP, Addr: "127.0.0.1"}, used, used+1)
+	if err != nil {
+		t.Skipf("port %d is not available: %s", used+1, err)
+	}
+	defer another.Close()
+	assert.Equal(used+1, p)
+
+	_, _, err = ListenWithPortRange(Option{Network: dfnet.TCP, Addr: "127.0.0.1"}, used, used)
+	assert.EqualError(err, "no available port to listen, port: "+strconv.Itoa(used)+" - "+strconv.Itoa(used))
+}
+
+func TestListen_Activated(t *testing.T) {
+	assert := assert.New(t)
+	activated, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+	defer activated.Close()
+
+	// Pass a duplicated fd as the socket activated by systemd.
+	f, err := activated.(*net.TCPListener).File()
+	assert.NoError(err)
+	fd, err := syscall.Dup(int(f.Fd()))
+	assert.NoError(err)
+	f.Close()
+
+	defaultListenFDsStart := listenFDsStart
+	listenFDsStart = fd
+	activatedFiles = nil
+	defer func() {
+		listenFDsStart = defaultListenFDsStart
+		activatedFiles = nil
+	}()
+
+	t.Setenv(listenPIDEnv, strconv.Itoa(os.Getpid()))
+	t.Setenv(listenFDsEnv, "1")
+	t.Setenv(listenFDNamesEnv, "upload")
+
+	ln, err := Listen(Option{Name: "upload", Network: dfnet.TCP, Addr: "127.0.0.1:0"})
+	assert.NoError(err)
+	defer ln.Close()
+	assert.Equal(activated.Addr().String(), ln.Addr().String())
+	assert.Empty(os.Getenv(listenFDsEnv))
+
+	// The activated socket is used only once.
+	another, p, err := ListenWithPortRange(Option{Name: "upload", Network: dfnet.TCP, Addr: "127.0.0.1"}, 0, 0)
+	assert.NoError(err)
+	defer another.Close()
+	assert.NotEqual(activated.Addr().(*net.TCPAddr).Port, p)
+}
@@ -18,11 +18,24 @@ package url
 
 import (
 	"net/url"
+	"strings"
 )
 
-// FilterQueryParams filters the query params in the url.
+// FilterQueryParams filters the query params in the url, the blank filtered query params are ignored.
+// If any query param is filtered, the remaining query params are sorted by key,
+// so that the urls differing only in the order of query params are the same.
 func FilterQueryParams(rawURL string, filteredQueryParams []string) (string, error) {
-	if len(filteredQueryParams) == 0 {
+	hidden := make(map[string]struct{})
+	for _, filter := range filteredQueryParams {
+		filter = strings.TrimSpace(filter)
+		if filter == "" {
+			continue
+		}
+
+		hidden[filter] = struct{}{}
+	}
+
+	if len(hidden) == 0 {
 		return rawURL, nil
 	}
 
@@ -31,11 +44,6 @@ func FilterQueryParams(rawURL string, filteredQueryParams []string) (string, err
 		return "", err
 	}
 
-	hidden := make(map[string]struct{})
-	for _, filter := range filteredQueryParams {
-		hidden[filter] = struct{}{}
-	}
-
 	var values = make(url.Values)
 	for k, v := range u.Query() {
 		if _, ok := hidden[k]; !ok {
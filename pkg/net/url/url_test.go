@@ -31,6 +31,14 @@ func TestFilterQuery(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, "http://www.xx.yy/path?u=f&x=y&m=z&x=s#size", url)
 
+	url, err = FilterQueryParams("http://www.xx.yy/path?u=f&x=y&m=z&x=s#size", []string{"", " "})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://www.xx.yy/path?u=f&x=y&m=z&x=s#size", url)
+
+	url, err = FilterQueryParams("http://www.xx.yy/path?x=y&u=f&m=z#size", []string{" m ", ""})
+	assert.Nil(t, err)
+	assert.Equal(t, "http://www.xx.yy/path?u=f&x=y#size", url)
+
 	url, err = FilterQueryParams(":error_url", []string{"x", "m"})
 	assert.NotNil(t, err)
 	assert.Equal(t, "", url)
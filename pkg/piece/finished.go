@@ -0,0 +1,140 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package piece
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/bits-and-blooms/bitset"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// FinishedTaskIDMetadataKey is the grpc metadata key of the task id of the finished pieces.
+	FinishedTaskIDMetadataKey = "dragonfly-finished-task-id"
+
+	// FinishedPieceSizeMetadataKey is the grpc metadata key of the piece size of the finished pieces.
+	FinishedPieceSizeMetadataKey = "dragonfly-finished-piece-size"
+
+	// FinishedPiecesMetadataKey is the grpc metadata key of the bitset of the finished pieces,
+	// the binary value is base64 encoded by grpc because of the -bin suffix.
+	FinishedPiecesMetadataKey = "dragonfly-finished-pieces-bin"
+)
+
+// Finished is the pieces which have been finished in the local storage of the peer,
+// it is claimed by the peer registering again after restart.
+type Finished struct {
+	// TaskID is the id of the task.
+	TaskID string
+
+	// PieceSize is the piece size of the task in the local storage.
+	PieceSize uint32
+
+	// Pieces is the bitset of the finished piece numbers.
+	Pieces *bitset.BitSet
+}
+
+// AppendFinishedToOutgoingContext returns a new context with the finished pieces claimed to the server.
+func AppendFinishedToOutgoingContext(ctx context.Context, finished *Finished) (context.Context, error) {
+	if finished == nil || finished.Pieces == nil || finished.Pieces.Count() == 0 {
+		return ctx, nil
+	}
+
+	pieces, err := finished.Pieces.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata.AppendToOutgoingContext(ctx,
+		FinishedTaskIDMetadataKey, finished.TaskID,
+		FinishedPieceSizeMetadataKey, strconv.FormatUint(uint64(finished.PieceSize), 10),
+		FinishedPiecesMetadataKey, string(pieces),
+	), nil
+}
+
+// FinishedFromIncomingContext returns the finished pieces claimed by the client,
+// it returns nil if the client does not claim any pieces.
+func FinishedFromIncomingContext(ctx context.Context) (*Finished, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	values := md.Get(FinishedPiecesMetadataKey)
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	taskIDs := md.Get(FinishedTaskIDMetadataKey)
+	if len(taskIDs) == 0 || taskIDs[0] == "" {
+		return nil, errors.New("finished pieces require task id")
+	}
+
+	pieceSizes := md.Get(FinishedPieceSizeMetadataKey)
+	if len(pieceSizes) == 0 {
+		return nil, errors.New("finished pieces require piece size")
+	}
+
+	pieceSize, err := strconv.ParseUint(pieceSizes[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid piece size %s: %w", pieceSizes[0], err)
+	}
+
+	pieces := &bitset.BitSet{}
+	if err := pieces.UnmarshalBinary([]byte(values[0])); err != nil {
+		return nil, fmt.Errorf("invalid finished pieces: %w", err)
+	}
+
+	return &Finished{
+		TaskID:    taskIDs[0],
+		PieceSize: uint32(pieceSize),
+		Pieces:    pieces,
+	}, nil
+}
+
+// Validate validates the finished pieces against the task, the pieces must be
+// finished with the same piece size and within the total piece count of the task.
+func (f *Finished) Validate(taskID string, contentLength int64, totalPieceCount int32) error {
+	if f.TaskID != taskID {
+		return fmt.Errorf("task id %s mismatch %s", f.TaskID, taskID)
+	}
+
+	if contentLength <= 0 || totalPieceCount <= 0 {
+		return errors.New("task has no content length or total piece count")
+	}
+
+	if f.PieceSize == 0 {
+		return errors.New("invalid piece size 0")
+	}
+
+	if count := (contentLength + int64(f.PieceSize) - 1) / int64(f.PieceSize); count != int64(totalPieceCount) {
+		return fmt.Errorf("piece size %d mismatch, total piece count is %d instead of %d", f.PieceSize, count, totalPieceCount)
+	}
+
+	if f.Pieces == nil || f.Pieces.Count() == 0 {
+		return errors.New("no finished pieces")
+	}
+
+	if number, ok := f.Pieces.NextSet(uint(totalPieceCount)); ok {
+		return fmt.Errorf("piece %d is out of total piece count %d", number, totalPieceCount)
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package piece
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bits-and-blooms/bitset"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestFinished_FinishedFromIncomingContext(t *testing.T) {
+	assert := assert.New(t)
+	finished, err := FinishedFromIncomingContext(context.Background())
+	assert.NoError(err)
+	assert.Nil(finished)
+
+	ctx, err := AppendFinishedToOutgoingContext(context.Background(), &Finished{
+		TaskID:    "foo",
+		PieceSize: 4,
+		Pieces:    bitset.New(8).Set(0).Set(2).Set(7),
+	})
+	assert.NoError(err)
+
+	md, ok := metadata.FromOutgoingContext(ctx)
+	assert.True(ok)
+	finished, err = FinishedFromIncomingContext(metadata.NewIncomingContext(context.Background(), md))
+	assert.NoError(err)
+	assert.Equal("foo", finished.TaskID)
+	assert.Equal(uint32(4), finished.PieceSize)
+	assert.Equal(uint(3), finished.Pieces.Count())
+	assert.True(finished.Pieces.Test(7))
+
+	ctx, err = AppendFinishedToOutgoingContext(context.Background(), &Finished{TaskID: "foo", PieceSize: 4, Pieces: &bitset.BitSet{}})
+	assert.NoError(err)
+	_, ok = metadata.FromOutgoingContext(ctx)
+	assert.False(ok)
+
+	_, err = FinishedFromIncomingContext(metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		FinishedPiecesMetadataKey, "foo",
+		FinishedTaskIDMetadataKey, "foo",
+		FinishedPieceSizeMetadataKey, "bar",
+	)))
+	assert.EqualError(err, "invalid piece size bar: strconv.ParseUint: parsing \"bar\": invalid syntax")
+}
+
+func TestFinished_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		finished *Finished
+		expect   func(t *testing.T, err error)
+	}{
+		{
+			name:     "valid",
+			finished: &Finished{TaskID: "foo", PieceSize: 4, Pieces: bitset.New(3).Set(0).Set(2)},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:     "task id mismatch",
+			finished: &Finished{TaskID: "bar", PieceSize: 4, Pieces: bitset.New(3).Set(0)},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "task id bar mismatch foo")
+			},
+		},
+		{
+			name:     "piece size mismatch",
+			finished: &Finished{TaskID: "foo", PieceSize: 2, Pieces: bitset.New(3).Set(0)},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "piece size 2 mismatch, total piece count is 5 instead of 3")
+			},
+		},
+		{
+			name:     "piece is out of bounds",
+			finished: &Finished{TaskID: "foo", PieceSize: 4, Pieces: bitset.New(3).Set(0).Set(3)},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "piece 3 is out of total piece count 3")
+			},
+		},
+		{
+			name:     "no finished pieces",
+			finished: &Finished{TaskID: "foo", PieceSize: 4, Pieces: &bitset.BitSet{}},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "no finished pieces")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, tc.finished.Validate("foo", 10, 3))
+		})
+	}
+}
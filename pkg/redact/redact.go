@@ -0,0 +1,159 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	// Redacted replaces the value of the sensitive query param and the password of the url.
+	Redacted = "REDACTED"
+
+	// HashedQueryParam is the query param of the sha256 of the entire query string.
+	HashedQueryParam = "redacted-sha256"
+)
+
+// DefaultQueryParams are the query params redacted by default, they carry the
+// signatures and tokens of the common object storages and registries.
+var DefaultQueryParams = []string{
+	"X-Amz-Signature",
+	"X-Amz-Credential",
+	"X-Amz-Security-Token",
+	"X-Goog-Signature",
+	"X-Goog-Credential",
+	"X-Obs-Signature",
+	"Signature",
+	"OSSAccessKeyId",
+	"AWSAccessKeyId",
+	"AccessKeyId",
+	"security-token",
+	"sig",
+	"token",
+	"access_token",
+}
+
+// hashedQuery matches the query string which has been hashed.
+var hashedQuery = regexp.MustCompile("^" + HashedQueryParam + "=[0-9a-f]{64}$")
+
+// defaultRedactor is the redactor used by URL.
+var defaultRedactor atomic.Pointer[Redactor]
+
+func init() {
+	SetDefault(New(DefaultQueryParams, false))
+}
+
+// Redactor redacts the sensitive parts of the urls before they are logged or stored,
+// redacting a redacted url returns the same url.
+type Redactor struct {
+	// queryParams is the set of the lower case query params redacted.
+	queryParams map[string]struct{}
+
+	// hashQuery replaces the entire query string with its sha256.
+	hashQuery bool
+}
+
+// New returns a new Redactor, the query params are matched case-insensitively.
+// If hashQuery is true, the entire query string is replaced with its sha256.
+func New(queryParams []string, hashQuery bool) *Redactor {
+	r := &Redactor{
+		queryParams: make(map[string]struct{}, len(queryParams)),
+		hashQuery:   hashQuery,
+	}
+
+	for _, param := range queryParams {
+		if param = strings.TrimSpace(param); param != "" {
+			r.queryParams[strings.ToLower(param)] = struct{}{}
+		}
+	}
+
+	return r
+}
+
+// URL returns the redacted url, the password of the url and the values of the sensitive
+// query params are replaced. The url which can not be parsed is truncated before the query.
+func (r *Redactor) URL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+			return rawURL[:i] + "?" + Redacted
+		}
+
+		return rawURL
+	}
+
+	if _, ok := u.User.Password(); ok {
+		u.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+
+	if u.RawQuery != "" {
+		if r.hashQuery {
+			u.RawQuery = r.hash(u.RawQuery)
+		} else {
+			u.RawQuery = r.query(u.RawQuery)
+		}
+	}
+
+	return u.String()
+}
+
+// query redacts the values of the sensitive query params and keeps the order of query params.
+func (r *Redactor) query(rawQuery string) string {
+	if len(r.queryParams) == 0 {
+		return rawQuery
+	}
+
+	pairs := strings.Split(rawQuery, "&")
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		unescaped, err := url.QueryUnescape(key)
+		if err != nil {
+			unescaped = key
+		}
+
+		if _, ok := r.queryParams[strings.ToLower(unescaped)]; ok {
+			pairs[i] = key + "=" + Redacted
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+// hash replaces the query string with its sha256.
+func (r *Redactor) hash(rawQuery string) string {
+	if hashedQuery.MatchString(rawQuery) {
+		return rawQuery
+	}
+
+	sum := sha256.Sum256([]byte(rawQuery))
+	return HashedQueryParam + "=" + hex.EncodeToString(sum[:])
+}
+
+// SetDefault sets the redactor used by URL.
+func SetDefault(r *Redactor) {
+	defaultRedactor.Store(r)
+}
+
+// URL returns the url redacted by the default redactor.
+func URL(rawURL string) string {
+	return defaultRedactor.Load().URL(rawURL)
+}
@@ -55,6 +55,12 @@ const (
 
 	// ProbedCountNamespace prefix of probed count namespace cache key.
 	ProbedCountNamespace = "probed-count"
+
+	// TaskSnapshotsNamespace prefix of task snapshots namespace cache key.
+	TaskSnapshotsNamespace = "task-snapshots"
+
+	// IdempotencyKeysNamespace prefix of idempotency keys namespace cache key.
+	IdempotencyKeysNamespace = "idempotency-keys"
 )
 
 // NewRedis returns a new redis client.
@@ -125,6 +131,11 @@ func MakeBucketKeyInManager(name string) string {
 	return MakeKeyInManager(BucketsNamespace, name)
 }
 
+// MakeIdempotencyKeyInManager make idempotency key of the request of the user in manager.
+func MakeIdempotencyKeyInManager(method, path, userID, key string) string {
+	return MakeKeyInManager(IdempotencyKeysNamespace, fmt.Sprintf("%s:%s:%s:%s", method, path, userID, key))
+}
+
 // MakeNamespaceKeyInScheduler make namespace key in scheduler.
 func MakeNamespaceKeyInScheduler(namespace string) string {
 	return fmt.Sprintf("%s:%s", types.SchedulerName, namespace)
@@ -169,3 +180,8 @@ func ParseProbedCountKeyInScheduler(key string) (string, string, string, error)
 func MakeProbedCountKeyInScheduler(hostID string) string {
 	return MakeKeyInScheduler(ProbedCountNamespace, hostID)
 }
+
+// MakeTaskSnapshotsKeyInScheduler make task snapshots key in scheduler.
+func MakeTaskSnapshotsKeyInScheduler(clusterID uint, hostname, ip string) string {
+	return MakeKeyInScheduler(TaskSnapshotsNamespace, fmt.Sprintf("%d-%s-%s", clusterID, hostname, ip))
+}
@@ -448,6 +448,56 @@ func Test_MakeBucketKeyInManager(t *testing.T) {
 	}
 }
 
+func Test_MakeIdempotencyKeyInManager(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		userID string
+		key    string
+		expect func(t *testing.T, s string)
+	}{
+		{
+			name:   "make idempotency key in manager",
+			method: "POST",
+			path:   "/api/v1/schedulers",
+			userID: "1",
+			key:    "foo",
+			expect: func(t *testing.T, s string) {
+				assert := assert.New(t)
+				assert.Equal(s, "manager:idempotency-keys:POST:/api/v1/schedulers:1:foo")
+			},
+		},
+		{
+			name:   "user id is empty",
+			method: "POST",
+			path:   "/api/v1/schedulers",
+			userID: "",
+			key:    "foo",
+			expect: func(t *testing.T, s string) {
+				assert := assert.New(t)
+				assert.Equal(s, "manager:idempotency-keys:POST:/api/v1/schedulers::foo")
+			},
+		},
+		{
+			name:   "key is empty",
+			method: "POST",
+			path:   "/api/v1/schedulers",
+			userID: "1",
+			key:    "",
+			expect: func(t *testing.T, s string) {
+				assert := assert.New(t)
+				assert.Equal(s, "manager:idempotency-keys:POST:/api/v1/schedulers:1:")
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, MakeIdempotencyKeyInManager(tc.method, tc.path, tc.userID, tc.key))
+		})
+	}
+}
+
 func Test_MakeNamespaceKeyInScheduler(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -736,3 +786,39 @@ func Test_ParseProbedCountKeyInScheduler(t *testing.T) {
 		})
 	}
 }
+
+func Test_MakeTaskSnapshotsKeyInScheduler(t *testing.T) {
+	tests := []struct {
+		name      string
+		clusterID uint
+		hostname  string
+		ip        string
+		expect    func(t *testing.T, s string)
+	}{
+		{
+			name:      "make task snapshots key in scheduler",
+			clusterID: 1,
+			hostname:  "bar",
+			ip:        "127.0.0.1",
+			expect: func(t *testing.T, s string) {
+				assert := assert.New(t)
+				assert.Equal(s, "scheduler:task-snapshots:1-bar-127.0.0.1")
+			},
+		},
+		{
+			name:      "hostname and ip are empty",
+			clusterID: 1,
+			hostname:  "",
+			ip:        "",
+			expect: func(t *testing.T, s string) {
+				assert := assert.New(t)
+				assert.Equal(s, "scheduler:task-snapshots:1--")
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, MakeTaskSnapshotsKeyInScheduler(tc.clusterID, tc.hostname, tc.ip))
+		})
+	}
+}
@@ -40,15 +40,36 @@ var plogger = grpclog.Component("scheduler_resolver")
 
 // SchedulerResolver implement resolver.Builder
 type SchedulerResolver struct {
-	addrs     []resolver.Address
-	cc        resolver.ClientConn
-	dynconfig config.Dynconfig
-	mu        *sync.Mutex
+	addrs         []resolver.Address
+	fallbackAddrs []resolver.Address
+	cc            resolver.ClientConn
+	dynconfig     config.Dynconfig
+	mu            *sync.Mutex
+}
+
+// SchedulerResolverOption is a functional option for configuring the scheduler resolver.
+type SchedulerResolverOption func(r *SchedulerResolver)
+
+// WithFallbackAddrs sets the static addresses used when no scheduler can be resolved dynamically.
+func WithFallbackAddrs(addrs []resolver.Address) SchedulerResolverOption {
+	return func(r *SchedulerResolver) {
+		r.fallbackAddrs = addrs
+	}
+}
+
+// NewScheduler returns the dragonfly resolver builder of the scheduler.
+func NewScheduler(dynconfig config.Dynconfig, options ...SchedulerResolverOption) *SchedulerResolver {
+	r := &SchedulerResolver{dynconfig: dynconfig, mu: &sync.Mutex{}}
+	for _, opt := range options {
+		opt(r)
+	}
+
+	return r
 }
 
 // RegisterScheduler registers the dragonfly resolver builder to the grpc with custom schema.
-func RegisterScheduler(dynconfig config.Dynconfig) {
-	resolver.Register(&SchedulerResolver{dynconfig: dynconfig, mu: &sync.Mutex{}})
+func RegisterScheduler(dynconfig config.Dynconfig, options ...SchedulerResolverOption) {
+	resolver.Register(NewScheduler(dynconfig, options...))
 }
 
 // Scheme returns the resolver scheme.
@@ -80,6 +101,11 @@ func (r *SchedulerResolver) ResolveNow(resolver.ResolveNowOptions) {
 	defer r.mu.Unlock()
 
 	addrs, err := r.dynconfig.GetResolveSchedulerAddrs()
+	if (err != nil || len(addrs) == 0) && len(r.fallbackAddrs) > 0 {
+		plogger.Warningf("resolve addresses failed, fall back to static addresses %v: %v", r.fallbackAddrs, err)
+		addrs, err = r.fallbackAddrs, nil
+	}
+
 	if err != nil {
 		plogger.Errorf("resolve addresses error %v", err)
 		return
@@ -0,0 +1,91 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+
+	configmocks "d7y.io/dragonfly/v2/client/config/mocks"
+)
+
+func TestSchedulerResolver_FallbackAddrs(t *testing.T) {
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go grpcServer.Serve(l) // nolint: errcheck
+	defer grpcServer.Stop()
+
+	tests := []struct {
+		name string
+		mock func(m *configmocks.MockDynconfigMockRecorder)
+	}{
+		{
+			name: "resolve empty addresses",
+			mock: func(m *configmocks.MockDynconfigMockRecorder) {
+				m.GetResolveSchedulerAddrs().Return([]resolver.Address{}, nil).AnyTimes()
+			},
+		},
+		{
+			name: "resolve addresses failed",
+			mock: func(m *configmocks.MockDynconfigMockRecorder) {
+				m.GetResolveSchedulerAddrs().Return(nil, errors.New("foo")).AnyTimes()
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+
+			dynconfig := configmocks.NewMockDynconfig(ctl)
+			dynconfig.EXPECT().Register(gomock.Any()).AnyTimes()
+			dynconfig.EXPECT().Deregister(gomock.Any()).AnyTimes()
+			tc.mock(dynconfig.EXPECT())
+
+			r := NewScheduler(dynconfig, WithFallbackAddrs([]resolver.Address{{ServerName: "127.0.0.1", Addr: l.Addr().String()}}))
+			conn, err := grpc.NewClient(SchedulerVirtualTarget, grpc.WithResolvers(r), grpc.WithTransportCredentials(insecure.NewCredentials()))
+			assert.NoError(err)
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+			assert.NoError(err)
+			assert.Equal(healthpb.HealthCheckResponse_SERVING, resp.Status)
+		})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// HostStartedAtMetadataKey is the key of the request metadata of AnnounceHost, which is the start time
+	// of the daemon in RFC3339Nano. The scheduler regards the daemon as restarted, when the start time is
+	// newer than the recorded one of the host.
+	HostStartedAtMetadataKey = "dragonfly-host-started-at"
+
+	// HostUptimeMetadataKey is the key of the request metadata of AnnounceHost, which is the uptime of
+	// the daemon. The scheduler reaps the peers created before the daemon restarted by its own clock,
+	// so the clock skew between the daemon and the scheduler does not matter.
+	HostUptimeMetadataKey = "dragonfly-host-uptime"
+)
+
+// AppendHostStartedAt returns the outgoing context with the start time and the uptime of the daemon.
+func AppendHostStartedAt(ctx context.Context, startedAt time.Time) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		HostStartedAtMetadataKey, startedAt.UTC().Format(time.RFC3339Nano),
+		HostUptimeMetadataKey, time.Since(startedAt).String(),
+	)
+}
+
+// HostStartedAtFromContext returns the start time of the daemon in the incoming metadata,
+// it returns false if the daemon does not announce the start time.
+func HostStartedAtFromContext(ctx context.Context) (time.Time, bool) {
+	values := metadata.ValueFromIncomingContext(ctx, HostStartedAtMetadataKey)
+	if len(values) == 0 {
+		return time.Time{}, false
+	}
+
+	startedAt, err := time.Parse(time.RFC3339Nano, values[0])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return startedAt, true
+}
+
+// HostUptimeFromContext returns the uptime of the daemon in the incoming metadata,
+// it returns false if the daemon does not announce the uptime.
+func HostUptimeFromContext(ctx context.Context) (time.Duration, bool) {
+	values := metadata.ValueFromIncomingContext(ctx, HostUptimeMetadataKey)
+	if len(values) == 0 {
+		return 0, false
+	}
+
+	uptime, err := time.ParseDuration(values[0])
+	if err != nil || uptime < 0 {
+		return 0, false
+	}
+
+	return uptime, true
+}
@@ -0,0 +1,66 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHostStartedAt(t *testing.T) {
+	assert := assert.New(t)
+	startedAt := time.Date(2024, 1, 2, 3, 4, 5, 6, time.Local)
+
+	md, ok := metadata.FromOutgoingContext(AppendHostStartedAt(context.Background(), startedAt))
+	assert.True(ok)
+
+	actual, ok := HostStartedAtFromContext(metadata.NewIncomingContext(context.Background(), md))
+	assert.True(ok)
+	assert.True(startedAt.Equal(actual))
+
+	_, ok = HostStartedAtFromContext(context.Background())
+	assert.False(ok)
+
+	_, ok = HostStartedAtFromContext(metadata.NewIncomingContext(context.Background(), metadata.Pairs(HostStartedAtMetadataKey, "foo")))
+	assert.False(ok)
+}
+
+func TestHostUptime(t *testing.T) {
+	assert := assert.New(t)
+	startedAt := time.Now().Add(-time.Minute)
+
+	md, ok := metadata.FromOutgoingContext(AppendHostStartedAt(context.Background(), startedAt))
+	assert.True(ok)
+
+	uptime, ok := HostUptimeFromContext(metadata.NewIncomingContext(context.Background(), md))
+	assert.True(ok)
+	assert.GreaterOrEqual(uptime, time.Minute)
+	assert.Less(uptime, 2*time.Minute)
+
+	_, ok = HostUptimeFromContext(context.Background())
+	assert.False(ok)
+
+	_, ok = HostUptimeFromContext(metadata.NewIncomingContext(context.Background(), metadata.Pairs(HostUptimeMetadataKey, "foo")))
+	assert.False(ok)
+
+	_, ok = HostUptimeFromContext(metadata.NewIncomingContext(context.Background(), metadata.Pairs(HostUptimeMetadataKey, "-1s")))
+	assert.False(ok)
+}
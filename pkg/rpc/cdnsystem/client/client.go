@@ -59,6 +59,7 @@ func GetClientByAddr(ctx context.Context, netAddr dfnet.NetAddr, opts ...grpc.Di
 		netAddr.Addr,
 		append([]grpc.DialOption{
 			grpc.WithIdleTimeout(0),
+			grpc.WithKeepaliveParams(rpc.DefaultClientKeepAliveParams()),
 			grpc.WithDefaultCallOptions(
 				grpc.MaxCallRecvMsgSize(math.MaxInt32),
 				grpc.MaxCallSendMsgSize(math.MaxInt32),
@@ -100,6 +101,7 @@ func GetClient(ctx context.Context, dynconfig config.DynconfigInterface, opts ..
 		resolver.SeedPeerVirtualTarget,
 		append([]grpc.DialOption{
 			grpc.WithIdleTimeout(0),
+			grpc.WithKeepaliveParams(rpc.DefaultClientKeepAliveParams()),
 			grpc.WithDefaultCallOptions(
 				grpc.MaxCallRecvMsgSize(math.MaxInt32),
 				grpc.MaxCallSendMsgSize(math.MaxInt32),
@@ -134,6 +136,13 @@ func GetClient(ctx context.Context, dynconfig config.DynconfigInterface, opts ..
 	}, nil
 }
 
+// PieceTaskIterator returns the iterator of the pieces of the task in the seed peer, it pages
+// through GetPieceTasks from the start number of the request and resumes from the failed page.
+// The pages are routed to the same seed peer by the task id.
+func PieceTaskIterator(ctx context.Context, client Client, req *commonv1.PieceTaskRequest, options ...rpc.PieceTaskIteratorOption) *rpc.PieceTaskIterator {
+	return rpc.NewPieceTaskIterator(ctx, client.GetPieceTasks, req, options...)
+}
+
 // Client is the interface for grpc client.
 type Client interface {
 	// ObtainSeeds triggers the seed peer to download task back-to-source..
@@ -69,6 +69,7 @@ func New(svr cdnsystemv1.SeederServer, opts ...grpc.ServerOption) *grpc.Server {
 			MaxConnectionAge:      DefaultMaxConnectionAge,
 			MaxConnectionAgeGrace: DefaultMaxConnectionAgeGrace,
 		}),
+		grpc.KeepaliveEnforcementPolicy(rpc.DefaultServerKeepAliveEnforcementPolicy()),
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
 			grpc_ratelimit.UnaryServerInterceptor(limiter),
 			rpc.ConvertErrorUnaryServerInterceptor,
@@ -0,0 +1,34 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+)
+
+// GzipCompressorName is the name of the gzip compressor of the grpc connection. The gzip compressor
+// is registered by importing this package, so the grpc servers decompress the gzip requests and
+// advertise the support in grpc-accept-encoding.
+const GzipCompressorName = gzip.Name
+
+// WithGzipCompression returns the dial option which compresses the messages of the client
+// connection with gzip, it reduces the bandwidth of the cross-region rpc at the cost of cpu.
+// It is off by default, the server responds with the same compressor of the request.
+func WithGzipCompression() grpc.DialOption {
+	return grpc.WithDefaultCallOptions(grpc.UseCompressor(GzipCompressorName))
+}
@@ -0,0 +1,111 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// compressionStatsHandler records the compressor of the incoming header of the server.
+type compressionStatsHandler struct {
+	mu          sync.Mutex
+	compression []string
+}
+
+func (h *compressionStatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (h *compressionStatsHandler) HandleRPC(_ context.Context, s stats.RPCStats) {
+	if header, ok := s.(*stats.InHeader); ok {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		h.compression = append(h.compression, header.Compression)
+	}
+}
+
+func (h *compressionStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (h *compressionStatsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestWithGzipCompression(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   []grpc.DialOption
+		expect func(t *testing.T, compression []string)
+	}{
+		{
+			name: "client connection with gzip compression",
+			opts: []grpc.DialOption{WithGzipCompression()},
+			expect: func(t *testing.T, compression []string) {
+				assert := assert.New(t)
+				assert.Equal([]string{GzipCompressorName}, compression)
+			},
+		},
+		{
+			name: "client connection without compression",
+			expect: func(t *testing.T, compression []string) {
+				assert := assert.New(t)
+				assert.Equal([]string{""}, compression)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.NotNil(encoding.GetCompressor(GzipCompressorName))
+
+			handler := &compressionStatsHandler{}
+			listener := bufconn.Listen(1024 * 1024)
+			server := grpc.NewServer(grpc.StatsHandler(handler))
+			healthpb.RegisterHealthServer(server, health.NewServer())
+			go server.Serve(listener)
+			defer server.Stop()
+
+			conn, err := grpc.NewClient("passthrough:///bufconn", append([]grpc.DialOption{
+				grpc.WithTransportCredentials(insecure.NewCredentials()),
+				grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+					return listener.DialContext(ctx)
+				}),
+			}, tc.opts...)...)
+			assert.NoError(err)
+			defer conn.Close()
+
+			_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+			assert.NoError(err)
+
+			handler.mu.Lock()
+			defer handler.mu.Unlock()
+			tc.expect(t, handler.compression)
+		})
+	}
+}
@@ -49,6 +49,7 @@ func GetV1(ctx context.Context, target string, opts ...grpc.DialOption) (V1, err
 		target,
 		append([]grpc.DialOption{
 			grpc.WithIdleTimeout(0),
+			grpc.WithKeepaliveParams(rpc.DefaultClientKeepAliveParams()),
 			grpc.WithDefaultCallOptions(
 				grpc.MaxCallRecvMsgSize(math.MaxInt32),
 				grpc.MaxCallSendMsgSize(math.MaxInt32),
@@ -85,6 +86,12 @@ func GetInsecureV1(ctx context.Context, target string, opts ...grpc.DialOption)
 	return GetV1(ctx, target, append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))...)
 }
 
+// PieceTaskIterator returns the iterator of the pieces of the task in the dfdaemon, it pages
+// through GetPieceTasks from the start number of the request and resumes from the failed page.
+func PieceTaskIterator(ctx context.Context, client V1, req *commonv1.PieceTaskRequest, options ...rpc.PieceTaskIteratorOption) *rpc.PieceTaskIterator {
+	return rpc.NewPieceTaskIterator(ctx, client.GetPieceTasks, req, options...)
+}
+
 // V1 is the interface for v1 version of the grpc client.
 type V1 interface {
 	// Trigger client to download file.
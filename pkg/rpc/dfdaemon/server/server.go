@@ -70,6 +70,7 @@ func New(svr dfdaemonv1.DaemonServer, healthServer healthpb.HealthServer, opts .
 			MaxConnectionAge:      DefaultMaxConnectionAge,
 			MaxConnectionAgeGrace: DefaultMaxConnectionAgeGrace,
 		}),
+		grpc.KeepaliveEnforcementPolicy(rpc.DefaultServerKeepAliveEnforcementPolicy()),
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
 			grpc_ratelimit.UnaryServerInterceptor(limiter),
 			rpc.ConvertErrorUnaryServerInterceptor,
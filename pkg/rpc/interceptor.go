@@ -18,8 +18,10 @@ package rpc
 
 import (
 	"context"
+	"time"
 
 	"github.com/juju/ratelimit"
+	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -125,3 +127,25 @@ func ConvertErrorStreamClientInterceptor(ctx context.Context, desc *grpc.StreamD
 
 	return s, nil
 }
+
+// LatencyUnaryServerInterceptor returns a new unary server interceptor that observes the handling
+// latency into histogram, the histogram is labeled by the grpc method and the status code.
+func LatencyUnaryServerInterceptor(histogram *prometheus.HistogramVec) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		h, err := handler(ctx, req)
+		histogram.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return h, err
+	}
+}
+
+// LatencyStreamServerInterceptor returns a new stream server interceptor that observes the duration
+// of the stream into histogram, the histogram is labeled by the grpc method and the status code.
+func LatencyStreamServerInterceptor(histogram *prometheus.HistogramVec) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		histogram.WithLabelValues(info.FullMethod, status.Code(err).String()).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
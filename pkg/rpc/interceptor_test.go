@@ -0,0 +1,119 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestHistogram() *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "test_grpc_handling_duration_seconds",
+	}, []string{"method", "code"})
+}
+
+func TestLatencyUnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		expect func(t *testing.T, histogram *prometheus.HistogramVec, err error)
+	}{
+		{
+			name: "handler succeeded",
+			expect: func(t *testing.T, histogram *prometheus.HistogramVec, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(observed(histogram, "/scheduler.v1.Scheduler/StatTask", codes.OK.String()))
+			},
+		},
+		{
+			name: "handler failed",
+			err:  status.Error(codes.NotFound, "foo"),
+			expect: func(t *testing.T, histogram *prometheus.HistogramVec, err error) {
+				assert := assert.New(t)
+				assert.Equal(codes.NotFound, status.Code(err))
+				assert.True(observed(histogram, "/scheduler.v1.Scheduler/StatTask", codes.NotFound.String()))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			histogram := newTestHistogram()
+			interceptor := LatencyUnaryServerInterceptor(histogram)
+			_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/scheduler.v1.Scheduler/StatTask"},
+				func(ctx context.Context, req any) (any, error) {
+					return nil, tc.err
+				})
+			tc.expect(t, histogram, err)
+		})
+	}
+}
+
+func TestLatencyStreamServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		err    error
+		expect func(t *testing.T, histogram *prometheus.HistogramVec, err error)
+	}{
+		{
+			name:   "stream of ReportPieceResult closed",
+			method: "/scheduler.v1.Scheduler/ReportPieceResult",
+			expect: func(t *testing.T, histogram *prometheus.HistogramVec, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(observed(histogram, "/scheduler.v1.Scheduler/ReportPieceResult", codes.OK.String()))
+			},
+		},
+		{
+			name:   "stream of AnnouncePeer failed with unknown error",
+			method: "/scheduler.v2.Scheduler/AnnouncePeer",
+			err:    errors.New("foo"),
+			expect: func(t *testing.T, histogram *prometheus.HistogramVec, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "foo")
+				assert.True(observed(histogram, "/scheduler.v2.Scheduler/AnnouncePeer", codes.Unknown.String()))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			histogram := newTestHistogram()
+			interceptor := LatencyStreamServerInterceptor(histogram)
+			err := interceptor(nil, nil, &grpc.StreamServerInfo{FullMethod: tc.method, IsServerStream: true, IsClientStream: true},
+				func(srv any, ss grpc.ServerStream) error {
+					return tc.err
+				})
+			tc.expect(t, histogram, err)
+		})
+	}
+}
+
+// observed returns whether the histogram has observed the method with the code.
+func observed(histogram *prometheus.HistogramVec, method, code string) bool {
+	return histogram.DeleteLabelValues(method, code)
+}
@@ -0,0 +1,55 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"time"
+
+	"google.golang.org/grpc/keepalive"
+)
+
+const (
+	// DefaultClientKeepAliveTime is default time of the grpc client pings the server when the connection is idle.
+	DefaultClientKeepAliveTime = 1 * time.Minute
+
+	// DefaultClientKeepAliveTimeout is default timeout of the grpc client waits for the ping ack.
+	DefaultClientKeepAliveTimeout = 20 * time.Second
+
+	// DefaultServerKeepAliveMinTime is default minimum time of the grpc server permits the client pings.
+	DefaultServerKeepAliveMinTime = 30 * time.Second
+)
+
+// DefaultClientKeepAliveParams returns the default keepalive parameters of the grpc client, the idle
+// connection is pinged to prevent it from being dropped silently by NAT or load balancer. Clients can
+// override it by passing grpc.WithKeepaliveParams in the dial options.
+func DefaultClientKeepAliveParams() keepalive.ClientParameters {
+	return keepalive.ClientParameters{
+		Time:                DefaultClientKeepAliveTime,
+		Timeout:             DefaultClientKeepAliveTimeout,
+		PermitWithoutStream: true,
+	}
+}
+
+// DefaultServerKeepAliveEnforcementPolicy returns the default keepalive enforcement policy of the grpc server,
+// which permits the pings of DefaultClientKeepAliveParams, otherwise the server closes the connection
+// with too_many_pings.
+func DefaultServerKeepAliveEnforcementPolicy() keepalive.EnforcementPolicy {
+	return keepalive.EnforcementPolicy{
+		MinTime:             DefaultServerKeepAliveMinTime,
+		PermitWithoutStream: true,
+	}
+}
@@ -0,0 +1,109 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// readCountingListener counts the bytes read by the server from the accepted connections.
+type readCountingListener struct {
+	net.Listener
+	n atomic.Int64
+}
+
+func (l *readCountingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return &readCountingConn{Conn: conn, n: &l.n}, nil
+}
+
+type readCountingConn struct {
+	net.Conn
+	n *atomic.Int64
+}
+
+func (c *readCountingConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+func TestKeepAlive(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping keepalive test in short mode")
+	}
+
+	assert := assert.New(t)
+	bufListener := bufconn.Listen(1024 * 1024)
+	listener := &readCountingListener{Listener: bufListener}
+	server := grpc.NewServer(grpc.KeepaliveEnforcementPolicy(DefaultServerKeepAliveEnforcementPolicy()))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	// The keepalive params passed by the client override the default params, 10s is
+	// the minimum time of the grpc client pings.
+	params := DefaultClientKeepAliveParams()
+	params.Time = 10 * time.Second
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return bufListener.DialContext(ctx)
+		}),
+		grpc.WithKeepaliveParams(DefaultClientKeepAliveParams()),
+		grpc.WithKeepaliveParams(params),
+	)
+	assert.NoError(err)
+	defer conn.Close()
+
+	// Establish the connection without any streams, then the server reads nothing
+	// but the pings of the idle connection.
+	conn.Connect()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for state := conn.GetState(); state != connectivity.Ready; state = conn.GetState() {
+		assert.True(conn.WaitForStateChange(ctx, state))
+	}
+
+	time.Sleep(time.Second)
+	n := listener.n.Load()
+	assert.Eventually(func() bool {
+		return listener.n.Load() > n
+	}, 2*params.Time, 100*time.Millisecond)
+
+	// The pings without streams are permitted by the default enforcement policy,
+	// so the connection is not closed by the server.
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(err)
+	assert.Equal(connectivity.Ready, conn.GetState())
+}
@@ -71,6 +71,7 @@ func New(managerServerV1 managerv1.ManagerServer, managerServerV2 managerv2.Mana
 			MaxConnectionAge:      DefaultMaxConnectionAge,
 			MaxConnectionAgeGrace: DefaultMaxConnectionAgeGrace,
 		}),
+		grpc.KeepaliveEnforcementPolicy(rpc.DefaultServerKeepAliveEnforcementPolicy()),
 		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
 			grpc_ratelimit.UnaryServerInterceptor(limiter),
 			grpc_prometheus.UnaryServerInterceptor,
@@ -0,0 +1,249 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/semaphore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+// RetryAfterMetadataKey is the key of the response metadata, which is the hint
+// for the client to wait before retrying the overloaded request, e.g. 500ms.
+const RetryAfterMetadataKey = "dragonfly-retry-after"
+
+// ConcurrencyLimiter limits the concurrent requests of every unary grpc method, the request waits in the queue
+// when the method reaches the limit, and it is shed with codes.ResourceExhausted after the queue timeout.
+// Streams are not limited, because they are long-lived and hold the slots for their lifetime.
+type ConcurrencyLimiter struct {
+	// limit is the default concurrent request limit of the method, zero means no limit.
+	limit int64
+
+	// methodLimits is the concurrent request limit by the full method, it overrides the limit.
+	methodLimits map[string]int64
+
+	// exemptServices is the services whose methods are not limited.
+	exemptServices []string
+
+	// exemptMethods is the full methods which are not limited.
+	exemptMethods map[string]struct{}
+
+	// queueTimeout is the timeout of the request waiting in the queue.
+	queueTimeout time.Duration
+
+	// retryAfter is the backoff hint returned to the client when the request is shed.
+	retryAfter time.Duration
+
+	// semaphores is the semaphores of the full methods.
+	semaphores sync.Map
+
+	// inFlight is the gauge of the in-flight requests by full method.
+	inFlight *prometheus.GaugeVec
+
+	// rejected is the counter of the shed requests by full method.
+	rejected *prometheus.CounterVec
+}
+
+// ConcurrencyLimiterOption is a functional option for configuring the ConcurrencyLimiter.
+type ConcurrencyLimiterOption func(l *ConcurrencyLimiter)
+
+// WithMethodLimit sets the concurrent request limit of the full method, e.g. /scheduler.Scheduler/RegisterPeerTask.
+func WithMethodLimit(fullMethod string, limit int) ConcurrencyLimiterOption {
+	return func(l *ConcurrencyLimiter) {
+		l.methodLimits[fullMethod] = int64(limit)
+	}
+}
+
+// WithExemptServices sets the services whose methods are not limited, e.g. grpc.health.v1.Health.
+func WithExemptServices(services ...string) ConcurrencyLimiterOption {
+	return func(l *ConcurrencyLimiter) {
+		l.exemptServices = append(l.exemptServices, services...)
+	}
+}
+
+// WithExemptMethods sets the full methods which are not limited, e.g. /scheduler.Scheduler/AnnounceHost.
+func WithExemptMethods(methods ...string) ConcurrencyLimiterOption {
+	return func(l *ConcurrencyLimiter) {
+		for _, method := range methods {
+			l.exemptMethods[method] = struct{}{}
+		}
+	}
+}
+
+// WithInFlightGauge sets the gauge of the in-flight requests, it is labeled by full method.
+func WithInFlightGauge(inFlight *prometheus.GaugeVec) ConcurrencyLimiterOption {
+	return func(l *ConcurrencyLimiter) {
+		l.inFlight = inFlight
+	}
+}
+
+// WithRejectedCounter sets the counter of the shed requests, it is labeled by full method.
+func WithRejectedCounter(rejected *prometheus.CounterVec) ConcurrencyLimiterOption {
+	return func(l *ConcurrencyLimiter) {
+		l.rejected = rejected
+	}
+}
+
+// NewConcurrencyLimiter returns a new ConcurrencyLimiter.
+func NewConcurrencyLimiter(limit int, queueTimeout, retryAfter time.Duration, options ...ConcurrencyLimiterOption) *ConcurrencyLimiter {
+	l := &ConcurrencyLimiter{
+		limit:         int64(limit),
+		methodLimits:  make(map[string]int64),
+		exemptMethods: make(map[string]struct{}),
+		queueTimeout:  queueTimeout,
+		retryAfter:    retryAfter,
+	}
+
+	for _, opt := range options {
+		opt(l)
+	}
+
+	return l
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that limits the concurrent requests.
+func (l *ConcurrencyLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		release, err := l.acquire(ctx, info.FullMethod)
+		if err != nil {
+			if serr := grpc.SetTrailer(ctx, l.retryAfterMetadata()); serr != nil {
+				logger.Warnf("set retry after trailer failed: %s", serr.Error())
+			}
+
+			return nil, err
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// acquire acquires the semaphore of the full method, it returns the function to release the semaphore.
+func (l *ConcurrencyLimiter) acquire(ctx context.Context, fullMethod string) (func(), error) {
+	if l.isExempt(fullMethod) {
+		return func() {}, nil
+	}
+
+	limit, ok := l.methodLimits[fullMethod]
+	if !ok {
+		limit = l.limit
+	}
+
+	if limit <= 0 {
+		return func() {}, nil
+	}
+
+	rawSemaphore, _ := l.semaphores.LoadOrStore(fullMethod, semaphore.NewWeighted(limit))
+	sem := rawSemaphore.(*semaphore.Weighted)
+
+	queueCtx, cancel := context.WithTimeout(ctx, l.queueTimeout)
+	defer cancel()
+	if err := sem.Acquire(queueCtx, 1); err != nil {
+		// Request is canceled by the client.
+		if ctx.Err() != nil {
+			return nil, status.FromContextError(ctx.Err()).Err()
+		}
+
+		if l.rejected != nil {
+			l.rejected.WithLabelValues(fullMethod).Inc()
+		}
+
+		return nil, status.Error(codes.ResourceExhausted, fmt.Sprintf("%s is overloaded, retry after %s", fullMethod, l.retryAfter))
+	}
+
+	if l.inFlight != nil {
+		l.inFlight.WithLabelValues(fullMethod).Inc()
+	}
+
+	return func() {
+		if l.inFlight != nil {
+			l.inFlight.WithLabelValues(fullMethod).Dec()
+		}
+
+		sem.Release(1)
+	}, nil
+}
+
+// isExempt returns whether the full method is not limited.
+func (l *ConcurrencyLimiter) isExempt(fullMethod string) bool {
+	service, _ := splitFullMethod(fullMethod)
+	for _, exemptService := range l.exemptServices {
+		if service == exemptService {
+			return true
+		}
+	}
+
+	_, ok := l.exemptMethods[fullMethod]
+	return ok
+}
+
+// retryAfterMetadata returns the response metadata of the backoff hint.
+func (l *ConcurrencyLimiter) retryAfterMetadata() metadata.MD {
+	return metadata.Pairs(RetryAfterMetadataKey, l.retryAfter.String())
+}
+
+// splitFullMethod splits the full method of grpc into service and method, e.g. /scheduler.Scheduler/RegisterPeerTask.
+func splitFullMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+
+	return "", fullMethod
+}
+
+// RetryAfterUnaryClientInterceptor returns a new unary client interceptor that waits for the backoff hint
+// in the response metadata when the request is shed by the overloaded server, it should be placed
+// after the retry interceptor, then the retry is delayed accordingly.
+func RetryAfterUnaryClientInterceptor(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	var trailer metadata.MD
+	err := invoker(ctx, method, req, reply, cc, append(opts, grpc.Trailer(&trailer))...)
+	if status.Code(err) != codes.ResourceExhausted {
+		return err
+	}
+
+	values := trailer.Get(RetryAfterMetadataKey)
+	if len(values) == 0 {
+		return err
+	}
+
+	retryAfter, perr := time.ParseDuration(values[0])
+	if perr != nil || retryAfter <= 0 {
+		return err
+	}
+
+	logger.Warnf("%s is overloaded, retry after %s", method, retryAfter)
+	timer := time.NewTimer(retryAfter)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	return err
+}
@@ -0,0 +1,268 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// slowHealthServer is the health server which handles the request slowly.
+type slowHealthServer struct {
+	healthpb.UnimplementedHealthServer
+	delay time.Duration
+}
+
+func (s *slowHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	time.Sleep(s.delay)
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func TestConcurrencyLimiter(t *testing.T) {
+	tests := []struct {
+		name        string
+		limiter     *ConcurrencyLimiter
+		delay       time.Duration
+		concurrency int
+		expect      func(t *testing.T, codes []codes.Code)
+	}{
+		{
+			name:        "shed requests exceeding the limit after queue timeout",
+			limiter:     NewConcurrencyLimiter(0, 50*time.Millisecond, time.Second, WithMethodLimit("/grpc.health.v1.Health/Check", 2)),
+			delay:       300 * time.Millisecond,
+			concurrency: 10,
+			expect: func(t *testing.T, cs []codes.Code) {
+				assert := assert.New(t)
+				assert.Equal(2, countCode(cs, codes.OK))
+				assert.Equal(8, countCode(cs, codes.ResourceExhausted))
+			},
+		},
+		{
+			name:        "queue requests exceeding the limit before queue timeout",
+			limiter:     NewConcurrencyLimiter(1, time.Second, time.Second),
+			delay:       10 * time.Millisecond,
+			concurrency: 5,
+			expect: func(t *testing.T, cs []codes.Code) {
+				assert := assert.New(t)
+				assert.Equal(5, countCode(cs, codes.OK))
+			},
+		},
+		{
+			name:        "limit of the method of other service is not applied",
+			limiter:     NewConcurrencyLimiter(0, 10*time.Millisecond, time.Second, WithMethodLimit("/scheduler.Scheduler/Check", 1)),
+			delay:       100 * time.Millisecond,
+			concurrency: 5,
+			expect: func(t *testing.T, cs []codes.Code) {
+				assert := assert.New(t)
+				assert.Equal(5, countCode(cs, codes.OK))
+			},
+		},
+		{
+			name:        "exempt service is not limited",
+			limiter:     NewConcurrencyLimiter(1, 10*time.Millisecond, time.Second, WithExemptServices(healthpb.Health_ServiceDesc.ServiceName)),
+			delay:       100 * time.Millisecond,
+			concurrency: 5,
+			expect: func(t *testing.T, cs []codes.Code) {
+				assert := assert.New(t)
+				assert.Equal(5, countCode(cs, codes.OK))
+			},
+		},
+		{
+			name:        "exempt method is not limited",
+			limiter:     NewConcurrencyLimiter(1, 10*time.Millisecond, time.Second, WithExemptMethods("/grpc.health.v1.Health/Check")),
+			delay:       100 * time.Millisecond,
+			concurrency: 5,
+			expect: func(t *testing.T, cs []codes.Code) {
+				assert := assert.New(t)
+				assert.Equal(5, countCode(cs, codes.OK))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestHealthClient(t, tc.limiter, tc.delay)
+
+			var (
+				wg sync.WaitGroup
+				mu sync.Mutex
+				cs []codes.Code
+			)
+			for i := 0; i < tc.concurrency; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+
+					mu.Lock()
+					defer mu.Unlock()
+					cs = append(cs, status.Code(err))
+				}()
+			}
+
+			wg.Wait()
+			tc.expect(t, cs)
+		})
+	}
+}
+
+func TestConcurrencyLimiter_RetryAfter(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewConcurrencyLimiter(1, 0, 2*time.Second)
+	client := newTestHealthClient(t, limiter, 300*time.Millisecond)
+
+	go client.Check(context.Background(), &healthpb.HealthCheckRequest{}) // nolint: errcheck
+	time.Sleep(100 * time.Millisecond)
+
+	var trailer metadata.MD
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Trailer(&trailer))
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+	assert.Equal([]string{"2s"}, trailer.Get(RetryAfterMetadataKey))
+}
+
+func TestRetryAfterUnaryClientInterceptor(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		md     metadata.MD
+		expect func(t *testing.T, err error, elapsed time.Duration)
+	}{
+		{
+			name: "wait for the backoff hint of the overloaded server",
+			err:  status.Error(codes.ResourceExhausted, "foo"),
+			md:   metadata.Pairs(RetryAfterMetadataKey, "200ms"),
+			expect: func(t *testing.T, err error, elapsed time.Duration) {
+				assert := assert.New(t)
+				assert.Equal(codes.ResourceExhausted, status.Code(err))
+				assert.GreaterOrEqual(elapsed, 200*time.Millisecond)
+			},
+		},
+		{
+			name: "resource exhausted without backoff hint",
+			err:  status.Error(codes.ResourceExhausted, "foo"),
+			expect: func(t *testing.T, err error, elapsed time.Duration) {
+				assert := assert.New(t)
+				assert.Equal(codes.ResourceExhausted, status.Code(err))
+				assert.Less(elapsed, 100*time.Millisecond)
+			},
+		},
+		{
+			name: "ignore backoff hint of other codes",
+			err:  status.Error(codes.Unavailable, "foo"),
+			md:   metadata.Pairs(RetryAfterMetadataKey, "200ms"),
+			expect: func(t *testing.T, err error, elapsed time.Duration) {
+				assert := assert.New(t)
+				assert.Equal(codes.Unavailable, status.Code(err))
+				assert.Less(elapsed, 100*time.Millisecond)
+			},
+		},
+		{
+			name: "invalid backoff hint",
+			err:  status.Error(codes.ResourceExhausted, "foo"),
+			md:   metadata.Pairs(RetryAfterMetadataKey, "foo"),
+			expect: func(t *testing.T, err error, elapsed time.Duration) {
+				assert := assert.New(t)
+				assert.Equal(codes.ResourceExhausted, status.Code(err))
+				assert.Less(elapsed, 100*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				for _, opt := range opts {
+					if trailer, ok := opt.(grpc.TrailerCallOption); ok {
+						*trailer.TrailerAddr = tc.md
+					}
+				}
+
+				return tc.err
+			}
+
+			start := time.Now()
+			err := RetryAfterUnaryClientInterceptor(context.Background(), "/grpc.health.v1.Health/Check", nil, nil, nil, invoker)
+			tc.expect(t, err, time.Since(start))
+		})
+	}
+}
+
+func TestSplitFullMethod(t *testing.T) {
+	tests := []struct {
+		fullMethod string
+		service    string
+		method     string
+	}{
+		{fullMethod: "/scheduler.Scheduler/RegisterPeerTask", service: "scheduler.Scheduler", method: "RegisterPeerTask"},
+		{fullMethod: "/scheduler.v2.Scheduler/AnnouncePeer", service: "scheduler.v2.Scheduler", method: "AnnouncePeer"},
+		{fullMethod: "RegisterPeerTask", service: "", method: "RegisterPeerTask"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.fullMethod, func(t *testing.T) {
+			assert := assert.New(t)
+			service, method := splitFullMethod(tc.fullMethod)
+			assert.Equal(tc.service, service)
+			assert.Equal(tc.method, method)
+		})
+	}
+}
+
+// newTestHealthClient returns the health client of the slow health server limited by the limiter.
+func newTestHealthClient(t *testing.T, limiter *ConcurrencyLimiter, delay time.Duration) healthpb.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+	svr := grpc.NewServer(grpc.UnaryInterceptor(limiter.UnaryServerInterceptor()))
+	healthpb.RegisterHealthServer(svr, &slowHealthServer{delay: delay})
+	go svr.Serve(lis) // nolint: errcheck
+	t.Cleanup(svr.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
+
+func countCode(cs []codes.Code, code codes.Code) int {
+	var count int
+	for _, c := range cs {
+		if c == code {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,295 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+// PeerKey is the key of the request limited by PeerRateLimiter.
+type PeerKey struct {
+	// PeerID is the id of the peer, the request is not limited by peer if it is empty.
+	PeerID string
+
+	// Host is the source host of the peer, the request is not limited by host if it is empty.
+	Host string
+}
+
+// PeerKeyFunc returns the key of the request, the request is not limited if ok is false,
+// e.g. the request of seed peers or the messages of the stream other than registration.
+type PeerKeyFunc func(ctx context.Context, req any) (key PeerKey, ok bool)
+
+// PeerRateLimiter limits the rate of requests by the peer id and by the source host of the peer,
+// it stops the registration storms of the misbehaving clients, e.g. registering the same peer
+// in a tight retry loop. The limiters of the recently used keys are kept in LRU, so the memory
+// is bounded, the evicted key starts with a full bucket when it is used again.
+type PeerRateLimiter struct {
+	// peerLimit is the rate of requests of every peer.
+	peerLimit rate.Limit
+
+	// peerBurst is the burst of requests of every peer.
+	peerBurst int
+
+	// hostLimit is the rate of requests of every host.
+	hostLimit rate.Limit
+
+	// hostBurst is the burst of requests of every host.
+	hostBurst int
+
+	// keyFunc returns the key of the request.
+	keyFunc PeerKeyFunc
+
+	// methods is the lowercase method names which are limited, all methods are limited if it is empty.
+	methods map[string]struct{}
+
+	// mu protects the limiters.
+	mu sync.Mutex
+
+	// peers is the limiters by peer id.
+	peers *limiterLRU
+
+	// hosts is the limiters by host.
+	hosts *limiterLRU
+
+	// throttled is the counter of the throttled requests by method.
+	throttled *prometheus.CounterVec
+}
+
+// PeerRateLimiterOption is a functional option for configuring the PeerRateLimiter.
+type PeerRateLimiterOption func(l *PeerRateLimiter)
+
+// WithRateLimitedMethods sets the method names which are limited, e.g. RegisterPeerTask.
+func WithRateLimitedMethods(methods ...string) PeerRateLimiterOption {
+	return func(l *PeerRateLimiter) {
+		for _, method := range methods {
+			l.methods[strings.ToLower(method)] = struct{}{}
+		}
+	}
+}
+
+// WithThrottledCounter sets the counter of the throttled requests, it is labeled by the full method,
+// the keys are not used as labels, because the count of peers and hosts is unbounded.
+func WithThrottledCounter(throttled *prometheus.CounterVec) PeerRateLimiterOption {
+	return func(l *PeerRateLimiter) {
+		l.throttled = throttled
+	}
+}
+
+// NewPeerRateLimiter returns a new PeerRateLimiter, size is the max count of the limiters kept
+// for peers and for hosts respectively.
+func NewPeerRateLimiter(peerLimit float64, peerBurst int, hostLimit float64, hostBurst int, size int, keyFunc PeerKeyFunc, options ...PeerRateLimiterOption) *PeerRateLimiter {
+	l := &PeerRateLimiter{
+		peerLimit: rate.Limit(peerLimit),
+		peerBurst: peerBurst,
+		hostLimit: rate.Limit(hostLimit),
+		hostBurst: hostBurst,
+		keyFunc:   keyFunc,
+		methods:   make(map[string]struct{}),
+		peers:     newLimiterLRU(size),
+		hosts:     newLimiterLRU(size),
+	}
+
+	for _, opt := range options {
+		opt(l)
+	}
+
+	return l
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that limits the rate of requests.
+func (l *PeerRateLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !l.isLimited(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		if retryAfter, err := l.limit(ctx, info.FullMethod, req); err != nil {
+			if serr := grpc.SetTrailer(ctx, metadata.Pairs(RetryAfterMetadataKey, retryAfter.String())); serr != nil {
+				logger.Warnf("set retry after trailer failed: %s", serr.Error())
+			}
+
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that limits the rate of the received
+// messages, the stream is closed with the error when the message is throttled.
+func (l *PeerRateLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !l.isLimited(info.FullMethod) {
+			return handler(srv, ss)
+		}
+
+		return handler(srv, &rateLimitedServerStream{ServerStream: ss, fullMethod: info.FullMethod, limiter: l})
+	}
+}
+
+// Allow reports whether the request of the key is allowed, otherwise it returns
+// the delay before the request of the key is allowed.
+func (l *PeerRateLimiter) Allow(key PeerKey) (time.Duration, bool) {
+	now := time.Now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var peerReservation *rate.Reservation
+	if key.PeerID != "" {
+		peerReservation = l.peers.get(key.PeerID, l.peerLimit, l.peerBurst).ReserveN(now, 1)
+		if delay := peerReservation.DelayFrom(now); delay > 0 {
+			peerReservation.CancelAt(now)
+			return delay, false
+		}
+	}
+
+	if key.Host != "" {
+		hostReservation := l.hosts.get(key.Host, l.hostLimit, l.hostBurst).ReserveN(now, 1)
+		if delay := hostReservation.DelayFrom(now); delay > 0 {
+			hostReservation.CancelAt(now)
+
+			// The request is throttled by host, so the token of the peer is returned.
+			if peerReservation != nil {
+				peerReservation.CancelAt(now)
+			}
+
+			return delay, false
+		}
+	}
+
+	return 0, true
+}
+
+// limit returns the status error with codes.ResourceExhausted and the retry after hint if the request is throttled.
+func (l *PeerRateLimiter) limit(ctx context.Context, fullMethod string, req any) (time.Duration, error) {
+	key, ok := l.keyFunc(ctx, req)
+	if !ok {
+		return 0, nil
+	}
+
+	retryAfter, ok := l.Allow(key)
+	if ok {
+		return 0, nil
+	}
+
+	// Round up the hint, so the client does not retry before the token is available.
+	retryAfter = retryAfter.Truncate(time.Millisecond) + time.Millisecond
+	if l.throttled != nil {
+		l.throttled.WithLabelValues(fullMethod).Inc()
+	}
+
+	return retryAfter, status.Error(codes.ResourceExhausted, fmt.Sprintf("peer %s of host %s is throttled, retry after %s", key.PeerID, key.Host, retryAfter))
+}
+
+// isLimited returns whether the method is limited.
+func (l *PeerRateLimiter) isLimited(fullMethod string) bool {
+	if len(l.methods) == 0 {
+		return true
+	}
+
+	_, method := splitFullMethod(fullMethod)
+	_, ok := l.methods[strings.ToLower(method)]
+	return ok
+}
+
+// rateLimitedServerStream is the server stream that limits the rate of the received messages.
+type rateLimitedServerStream struct {
+	grpc.ServerStream
+	fullMethod string
+	limiter    *PeerRateLimiter
+}
+
+// RecvMsg receives the message and returns the error if the message is throttled.
+func (s *rateLimitedServerStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	retryAfter, err := s.limiter.limit(s.Context(), s.fullMethod, m)
+	if err != nil {
+		s.SetTrailer(metadata.Pairs(RetryAfterMetadataKey, retryAfter.String()))
+		return err
+	}
+
+	return nil
+}
+
+// limiterLRU is the least recently used cache of the rate limiters by key, it is not goroutine safe.
+type limiterLRU struct {
+	// size is the max count of the limiters.
+	size int
+
+	// ll is the list of the entries, the front is the most recently used.
+	ll *list.List
+
+	// entries is the elements of the list by key.
+	entries map[string]*list.Element
+}
+
+// limiterEntry is the entry of limiterLRU.
+type limiterEntry struct {
+	key     string
+	limiter *rate.Limiter
+}
+
+// newLimiterLRU returns a new limiterLRU.
+func newLimiterLRU(size int) *limiterLRU {
+	return &limiterLRU{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the limiter of the key, the limiter is created if the key is absent,
+// and the least recently used limiter is evicted when the cache is full.
+func (c *limiterLRU) get(key string, limit rate.Limit, burst int) *rate.Limiter {
+	if elem, ok := c.entries[key]; ok {
+		c.ll.MoveToFront(elem)
+		return elem.Value.(*limiterEntry).limiter
+	}
+
+	limiter := rate.NewLimiter(limit, burst)
+	c.entries[key] = c.ll.PushFront(&limiterEntry{key: key, limiter: limiter})
+	for c.size > 0 && c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.entries, oldest.Value.(*limiterEntry).key)
+	}
+
+	return limiter
+}
+
+// len returns the count of the limiters.
+func (c *limiterLRU) len() int {
+	return c.ll.Len()
+}
@@ -0,0 +1,288 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthCheckPeerKey uses the service of the health check request as the peer id,
+// and the service "seed" is exempted.
+func healthCheckPeerKey(ctx context.Context, req any) (PeerKey, bool) {
+	r, ok := req.(*healthpb.HealthCheckRequest)
+	if !ok || r.Service == "seed" {
+		return PeerKey{}, false
+	}
+
+	return PeerKey{PeerID: r.Service, Host: "127.0.0.1"}, true
+}
+
+func TestPeerRateLimiter_Allow(t *testing.T) {
+	tests := []struct {
+		name    string
+		limiter *PeerRateLimiter
+		keys    []PeerKey
+		expect  func(t *testing.T, allowed []bool)
+	}{
+		{
+			name:    "throttle the same peer exceeding the burst",
+			limiter: NewPeerRateLimiter(0.001, 2, 1000, 1000, 10, healthCheckPeerKey),
+			keys:    []PeerKey{{PeerID: "foo", Host: "a"}, {PeerID: "foo", Host: "a"}, {PeerID: "foo", Host: "a"}, {PeerID: "bar", Host: "a"}},
+			expect: func(t *testing.T, allowed []bool) {
+				assert := assert.New(t)
+				assert.Equal([]bool{true, true, false, true}, allowed)
+			},
+		},
+		{
+			name:    "throttle the peers of the same host exceeding the burst",
+			limiter: NewPeerRateLimiter(1000, 1000, 0.001, 2, 10, healthCheckPeerKey),
+			keys:    []PeerKey{{PeerID: "foo", Host: "a"}, {PeerID: "bar", Host: "a"}, {PeerID: "baz", Host: "a"}, {PeerID: "baz", Host: "b"}},
+			expect: func(t *testing.T, allowed []bool) {
+				assert := assert.New(t)
+				assert.Equal([]bool{true, true, false, true}, allowed)
+			},
+		},
+		{
+			name:    "return the token of the peer throttled by host",
+			limiter: NewPeerRateLimiter(0.001, 1, 0.001, 1, 10, healthCheckPeerKey),
+			keys:    []PeerKey{{PeerID: "foo", Host: "a"}, {PeerID: "bar", Host: "a"}, {PeerID: "bar", Host: "b"}},
+			expect: func(t *testing.T, allowed []bool) {
+				assert := assert.New(t)
+				assert.Equal([]bool{true, false, true}, allowed)
+			},
+		},
+		{
+			name:    "empty key is not limited",
+			limiter: NewPeerRateLimiter(0.001, 1, 0.001, 1, 10, healthCheckPeerKey),
+			keys:    []PeerKey{{}, {}, {}},
+			expect: func(t *testing.T, allowed []bool) {
+				assert := assert.New(t)
+				assert.Equal([]bool{true, true, true}, allowed)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var allowed []bool
+			for _, key := range tc.keys {
+				_, ok := tc.limiter.Allow(key)
+				allowed = append(allowed, ok)
+			}
+
+			tc.expect(t, allowed)
+		})
+	}
+}
+
+func TestPeerRateLimiter_Eviction(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewPeerRateLimiter(0.001, 1, 1000, 1000, 2, healthCheckPeerKey)
+
+	_, ok := limiter.Allow(PeerKey{PeerID: "foo"})
+	assert.True(ok)
+	_, ok = limiter.Allow(PeerKey{PeerID: "bar"})
+	assert.True(ok)
+
+	// Use foo again, so bar is the least recently used one.
+	retryAfter, ok := limiter.Allow(PeerKey{PeerID: "foo"})
+	assert.False(ok)
+	assert.Greater(retryAfter, time.Duration(0))
+
+	// Evict bar by baz.
+	_, ok = limiter.Allow(PeerKey{PeerID: "baz"})
+	assert.True(ok)
+	assert.Equal(2, limiter.peers.len())
+
+	// The evicted bar starts with a full bucket, and foo is still throttled.
+	_, ok = limiter.Allow(PeerKey{PeerID: "bar"})
+	assert.True(ok)
+	_, ok = limiter.Allow(PeerKey{PeerID: "baz"})
+	assert.False(ok)
+	assert.Equal(2, limiter.peers.len())
+}
+
+func TestPeerRateLimiter_Concurrent(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewPeerRateLimiter(0.001, 10, 0.001, 50, 100, healthCheckPeerKey)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		allowed = map[string]int{}
+	)
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			peerID := fmt.Sprintf("peer-%d", i%4)
+			if _, ok := limiter.Allow(PeerKey{PeerID: peerID, Host: "a"}); ok {
+				mu.Lock()
+				allowed[peerID]++
+				mu.Unlock()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	assert.Len(allowed, 4)
+	for peerID, count := range allowed {
+		assert.Equal(10, count, "peer %s", peerID)
+	}
+	assert.Equal(4, limiter.peers.len())
+	assert.Equal(1, limiter.hosts.len())
+}
+
+func TestPeerRateLimiter_UnaryServerInterceptor(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []PeerRateLimiterOption
+		service string
+		expect  func(t *testing.T, cs []codes.Code, trailer metadata.MD, throttled *prometheus.CounterVec)
+	}{
+		{
+			name:    "throttle the peer exceeding the burst",
+			service: "foo",
+			expect: func(t *testing.T, cs []codes.Code, trailer metadata.MD, throttled *prometheus.CounterVec) {
+				assert := assert.New(t)
+				assert.Equal([]codes.Code{codes.OK, codes.ResourceExhausted, codes.ResourceExhausted}, cs)
+				assert.Len(trailer.Get(RetryAfterMetadataKey), 1)
+				retryAfter, err := time.ParseDuration(trailer.Get(RetryAfterMetadataKey)[0])
+				assert.NoError(err)
+				assert.Greater(retryAfter, 10*time.Second)
+				assert.Equal(float64(2), testutil.ToFloat64(throttled.WithLabelValues("/grpc.health.v1.Health/Check")))
+			},
+		},
+		{
+			name:    "exempt peer is not limited",
+			service: "seed",
+			expect: func(t *testing.T, cs []codes.Code, trailer metadata.MD, throttled *prometheus.CounterVec) {
+				assert := assert.New(t)
+				assert.Equal([]codes.Code{codes.OK, codes.OK, codes.OK}, cs)
+				assert.Empty(trailer.Get(RetryAfterMetadataKey))
+			},
+		},
+		{
+			name:    "method is not limited",
+			options: []PeerRateLimiterOption{WithRateLimitedMethods("RegisterPeerTask")},
+			service: "foo",
+			expect: func(t *testing.T, cs []codes.Code, trailer metadata.MD, throttled *prometheus.CounterVec) {
+				assert := assert.New(t)
+				assert.Equal([]codes.Code{codes.OK, codes.OK, codes.OK}, cs)
+			},
+		},
+		{
+			name:    "limited method is case-insensitive",
+			options: []PeerRateLimiterOption{WithRateLimitedMethods("check")},
+			service: "foo",
+			expect: func(t *testing.T, cs []codes.Code, trailer metadata.MD, throttled *prometheus.CounterVec) {
+				assert := assert.New(t)
+				assert.Equal([]codes.Code{codes.OK, codes.ResourceExhausted, codes.ResourceExhausted}, cs)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			throttled := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "throttled"}, []string{"method"})
+			limiter := NewPeerRateLimiter(0.01, 1, 1000, 1000, 10, healthCheckPeerKey, append(tc.options, WithThrottledCounter(throttled))...)
+			client := newPeerRateLimitedHealthClient(t, limiter)
+
+			var (
+				cs      []codes.Code
+				trailer metadata.MD
+			)
+			for i := 0; i < 3; i++ {
+				_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: tc.service}, grpc.Trailer(&trailer))
+				cs = append(cs, status.Code(err))
+			}
+
+			tc.expect(t, cs, trailer, throttled)
+		})
+	}
+}
+
+func TestPeerRateLimiter_StreamServerInterceptor(t *testing.T) {
+	assert := assert.New(t)
+	limiter := NewPeerRateLimiter(0.01, 1, 1000, 1000, 10, healthCheckPeerKey)
+	client := newPeerRateLimitedHealthClient(t, limiter)
+
+	watch := func() (*healthpb.HealthCheckResponse, metadata.MD, error) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "foo"})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		resp, err := stream.Recv()
+		return resp, stream.Trailer(), err
+	}
+
+	resp, _, err := watch()
+	assert.NoError(err)
+	assert.Equal(healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	_, trailer, err := watch()
+	assert.Equal(codes.ResourceExhausted, status.Code(err))
+	assert.Len(trailer.Get(RetryAfterMetadataKey), 1)
+}
+
+func newPeerRateLimitedHealthClient(t *testing.T, limiter *PeerRateLimiter) healthpb.HealthClient {
+	lis := bufconn.Listen(1024 * 1024)
+	svr := grpc.NewServer(
+		grpc.UnaryInterceptor(limiter.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(limiter.StreamServerInterceptor()),
+	)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("foo", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus("seed", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(svr, healthServer)
+	go svr.Serve(lis) // nolint: errcheck
+	t.Cleanup(svr.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return healthpb.NewHealthClient(conn)
+}
@@ -0,0 +1,314 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+)
+
+const (
+	// PieceSnapshotMetadataKey is the key of the response metadata of GetPieceTasks, it is true
+	// when the pieces of the task are a consistent snapshot, which means the task is completed
+	// and no new pieces will appear.
+	PieceSnapshotMetadataKey = "dragonfly-piece-snapshot"
+
+	// PiecePagingMetadataKey is the key of the request metadata of GetPieceTasks, it is true
+	// when the caller pages through the pieces with the next start number, so the server can
+	// clamp the limit of the request.
+	PiecePagingMetadataKey = "dragonfly-piece-paging"
+
+	// MaxPieceTaskPageSize is the max number of pieces in a page of GetPieceTasks, the server
+	// clamps the larger limit of the paging request. The limit of the request without the
+	// paging metadata is not clamped, because the older callers fetch all pieces in one request.
+	MaxPieceTaskPageSize = 4096
+
+	// DefaultPieceTaskPageSize is the default number of pieces in a page of the PieceTaskIterator.
+	DefaultPieceTaskPageSize = 1024
+
+	// DefaultPieceTaskMaxRetries is the default max retries of the page with the transient error.
+	DefaultPieceTaskMaxRetries = 3
+
+	// DefaultPieceTaskRetryBackoff is the default backoff between the retries of the page.
+	DefaultPieceTaskRetryBackoff = 500 * time.Millisecond
+)
+
+// GetPieceTasksFunc gets a page of the pieces of the task, it has the same signature as
+// the GetPieceTasks of the dfdaemon and cdnsystem clients.
+type GetPieceTasksFunc func(context.Context, *commonv1.PieceTaskRequest, ...grpc.CallOption) (*commonv1.PiecePacket, error)
+
+// PieceTaskIterator pages through the pieces of the task by GetPieceTasks. The page with the transient
+// error is retried from the start number of the page, so the pages already iterated are not fetched again.
+type PieceTaskIterator struct {
+	// ctx is the context of the iteration.
+	ctx context.Context
+
+	// get gets a page of the pieces.
+	get GetPieceTasksFunc
+
+	// req is the request of the next page.
+	req *commonv1.PieceTaskRequest
+
+	// maxRetries is the max retries of the page with the transient error.
+	maxRetries int
+
+	// backoff is the backoff between the retries of the page.
+	backoff time.Duration
+
+	// total is the total piece count advertised by the server, -1 means unknown.
+	total int32
+
+	// consistent is whether the pieces are a consistent snapshot since the first page.
+	consistent bool
+
+	// started is whether the first page is fetched.
+	started bool
+
+	// done is whether all the pages are iterated.
+	done bool
+}
+
+// PieceTaskIteratorOption is a functional option for configuring the PieceTaskIterator.
+type PieceTaskIteratorOption func(it *PieceTaskIterator)
+
+// WithPieceTaskPageSize sets the number of pieces in a page, it is clamped to MaxPieceTaskPageSize.
+func WithPieceTaskPageSize(pageSize uint32) PieceTaskIteratorOption {
+	return func(it *PieceTaskIterator) {
+		if pageSize > 0 {
+			it.req.Limit = min(pageSize, MaxPieceTaskPageSize)
+		}
+	}
+}
+
+// WithPieceTaskMaxRetries sets the max retries of the page with the transient error.
+func WithPieceTaskMaxRetries(maxRetries int) PieceTaskIteratorOption {
+	return func(it *PieceTaskIterator) {
+		it.maxRetries = maxRetries
+	}
+}
+
+// WithPieceTaskRetryBackoff sets the backoff between the retries of the page.
+func WithPieceTaskRetryBackoff(backoff time.Duration) PieceTaskIteratorOption {
+	return func(it *PieceTaskIterator) {
+		it.backoff = backoff
+	}
+}
+
+// NewPieceTaskIterator returns a new PieceTaskIterator, which iterates the pieces from the start number of the request.
+func NewPieceTaskIterator(ctx context.Context, get GetPieceTasksFunc, req *commonv1.PieceTaskRequest, options ...PieceTaskIteratorOption) *PieceTaskIterator {
+	it := &PieceTaskIterator{
+		ctx: metadata.AppendToOutgoingContext(ctx, PiecePagingMetadataKey, strconv.FormatBool(true)),
+		get: get,
+		req: &commonv1.PieceTaskRequest{
+			TaskId:   req.TaskId,
+			SrcPid:   req.SrcPid,
+			DstPid:   req.DstPid,
+			StartNum: req.StartNum,
+			Limit:    DefaultPieceTaskPageSize,
+		},
+		maxRetries: DefaultPieceTaskMaxRetries,
+		backoff:    DefaultPieceTaskRetryBackoff,
+		total:      -1,
+	}
+
+	for _, opt := range options {
+		opt(it)
+	}
+
+	return it
+}
+
+// Next returns the next page of the pieces, it returns io.EOF when all the pages are iterated.
+// The total piece count is unknown when the task is not completed, then the iteration stops
+// at the first empty page.
+func (it *PieceTaskIterator) Next() (*commonv1.PiecePacket, error) {
+	if it.done {
+		return nil, io.EOF
+	}
+
+	packet, snapshot, err := it.fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	if !it.started {
+		it.started = true
+		it.consistent = snapshot
+	}
+	it.total = packet.TotalPiece
+
+	// The server returns the pieces in the window of the page, use the next number of
+	// the last piece if the page is full, otherwise the pieces in the window are all returned.
+	if n := len(packet.PieceInfos); n > 0 && uint32(n) >= it.req.Limit {
+		it.req.StartNum = uint32(packet.PieceInfos[n-1].PieceNum) + 1
+	} else {
+		it.req.StartNum += it.req.Limit
+	}
+
+	if it.total >= 0 && int64(it.req.StartNum) >= int64(it.total) {
+		it.done = true
+	}
+
+	if it.total < 0 && len(packet.PieceInfos) == 0 {
+		it.done = true
+	}
+
+	return packet, nil
+}
+
+// Range calls fn for every page of the pieces, it stops the iteration if fn returns false.
+func (it *PieceTaskIterator) Range(fn func(packet *commonv1.PiecePacket) bool) error {
+	for {
+		packet, err := it.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+
+			return err
+		}
+
+		if !fn(packet) {
+			return nil
+		}
+	}
+}
+
+// Channel returns the channel of the pages of the pieces, the channel is closed when the iteration
+// is finished, and the error channel receives the error if the iteration is failed.
+func (it *PieceTaskIterator) Channel() (<-chan *commonv1.PiecePacket, <-chan error) {
+	packets := make(chan *commonv1.PiecePacket)
+	errCh := make(chan error, 1)
+	go func() {
+		defer close(packets)
+		defer close(errCh)
+
+		if err := it.Range(func(packet *commonv1.PiecePacket) bool {
+			select {
+			case packets <- packet:
+				return true
+			case <-it.ctx.Done():
+				return false
+			}
+		}); err != nil {
+			errCh <- err
+			return
+		}
+
+		if err := it.ctx.Err(); err != nil && !it.done {
+			errCh <- err
+		}
+	}()
+
+	return packets, errCh
+}
+
+// Total returns the total piece count advertised by the server, -1 means unknown.
+func (it *PieceTaskIterator) Total() int32 {
+	return it.total
+}
+
+// StartNum returns the start number of the next page.
+func (it *PieceTaskIterator) StartNum() uint32 {
+	return it.req.StartNum
+}
+
+// Consistent returns whether the pieces are a consistent snapshot since the first page,
+// if false, new pieces may have appeared after the first page.
+func (it *PieceTaskIterator) Consistent() bool {
+	return it.consistent
+}
+
+// fetch gets the page of the pieces from the start number of the request, and retries the transient error.
+func (it *PieceTaskIterator) fetch() (*commonv1.PiecePacket, bool, error) {
+	for retries := 0; ; retries++ {
+		var header metadata.MD
+		packet, err := it.get(it.ctx, it.req, grpc.Header(&header))
+		if err == nil {
+			return packet, isPieceSnapshot(header, packet), nil
+		}
+
+		if retries >= it.maxRetries || it.ctx.Err() != nil || !isTransientPieceTaskError(err) {
+			return nil, false, err
+		}
+
+		logger.Warnf("get piece tasks of task %s from %d failed: %s, retry %d", it.req.TaskId, it.req.StartNum, err.Error(), retries+1)
+		timer := time.NewTimer(it.backoff)
+		select {
+		case <-timer.C:
+		case <-it.ctx.Done():
+			timer.Stop()
+			return nil, false, it.ctx.Err()
+		}
+	}
+}
+
+// IsPiecePaging returns whether the caller of GetPieceTasks pages through the pieces,
+// by the request metadata in the incoming context of the server.
+func IsPiecePaging(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	values := md.Get(PiecePagingMetadataKey)
+	if len(values) == 0 {
+		return false
+	}
+
+	paging, err := strconv.ParseBool(values[0])
+	return err == nil && paging
+}
+
+// isPieceSnapshot returns whether the page is a consistent snapshot, it falls back to the total
+// piece count if the server does not return the metadata, the total piece count is known only
+// when the task is completed.
+func isPieceSnapshot(header metadata.MD, packet *commonv1.PiecePacket) bool {
+	if values := header.Get(PieceSnapshotMetadataKey); len(values) > 0 {
+		if snapshot, err := strconv.ParseBool(values[0]); err == nil {
+			return snapshot
+		}
+	}
+
+	return packet.TotalPiece >= 0
+}
+
+// isTransientPieceTaskError returns whether the error of GetPieceTasks is transient and can be retried.
+func isTransientPieceTaskError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
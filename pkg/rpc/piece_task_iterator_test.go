@@ -0,0 +1,339 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+)
+
+// mockPieceServer returns the pages of the pieces in the window of the request like the dfdaemon,
+// and returns the injected errors of the start number before the page.
+type mockPieceServer struct {
+	// pieces is the piece numbers stored in the server.
+	pieces map[int32]struct{}
+
+	// total is the total piece count, -1 means the task is not completed.
+	total int32
+
+	// snapshot is the metadata of the snapshot flag, empty means no metadata.
+	snapshot string
+
+	// errs is the injected errors by the start number.
+	errs map[uint32][]error
+
+	// startNums is the start numbers of the requests.
+	startNums []uint32
+
+	// paging is the paging metadata of the requests.
+	paging []bool
+}
+
+func newMockPieceServer(start, end int32, total int32) *mockPieceServer {
+	s := &mockPieceServer{
+		pieces: make(map[int32]struct{}),
+		total:  total,
+		errs:   make(map[uint32][]error),
+	}
+
+	for i := start; i < end; i++ {
+		s.pieces[i] = struct{}{}
+	}
+
+	return s
+}
+
+func (s *mockPieceServer) GetPieceTasks(ctx context.Context, req *commonv1.PieceTaskRequest, opts ...grpc.CallOption) (*commonv1.PiecePacket, error) {
+	s.startNums = append(s.startNums, req.StartNum)
+	md, _ := metadata.FromOutgoingContext(ctx)
+	s.paging = append(s.paging, IsPiecePaging(metadata.NewIncomingContext(ctx, md)))
+	if errs := s.errs[req.StartNum]; len(errs) > 0 {
+		s.errs[req.StartNum] = errs[1:]
+		return nil, errs[0]
+	}
+
+	if s.snapshot != "" {
+		for _, opt := range opts {
+			if header, ok := opt.(grpc.HeaderCallOption); ok {
+				*header.HeaderAddr = metadata.Pairs(PieceSnapshotMetadataKey, s.snapshot)
+			}
+		}
+	}
+
+	packet := &commonv1.PiecePacket{
+		TaskId:        req.TaskId,
+		DstPid:        req.DstPid,
+		TotalPiece:    s.total,
+		ContentLength: int64(s.total) * 1024,
+	}
+
+	for i := int32(0); i < int32(req.Limit); i++ {
+		num := int32(req.StartNum) + i
+		if s.total > -1 && num >= s.total {
+			break
+		}
+
+		if _, ok := s.pieces[num]; ok {
+			packet.PieceInfos = append(packet.PieceInfos, &commonv1.PieceInfo{PieceNum: num, RangeSize: 1024})
+		}
+	}
+
+	return packet, nil
+}
+
+// collectPieces returns the piece numbers of the iterator.
+func collectPieces(it *PieceTaskIterator) ([]int32, error) {
+	var nums []int32
+	err := it.Range(func(packet *commonv1.PiecePacket) bool {
+		for _, piece := range packet.PieceInfos {
+			nums = append(nums, piece.PieceNum)
+		}
+
+		return true
+	})
+
+	return nums, err
+}
+
+func TestPieceTaskIterator(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  func() *mockPieceServer
+		options []PieceTaskIteratorOption
+		expect  func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error)
+	}{
+		{
+			name: "iterate pieces of completed task by pages",
+			server: func() *mockPieceServer {
+				return newMockPieceServer(0, 10, 10)
+			},
+			options: []PieceTaskIteratorOption{WithPieceTaskPageSize(4)},
+			expect: func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Len(nums, 10)
+				assert.Equal([]uint32{0, 4, 8}, s.startNums)
+				assert.Equal([]bool{true, true, true}, s.paging)
+				assert.Equal(int32(10), it.Total())
+				assert.True(it.Consistent())
+
+				_, err = it.Next()
+				assert.ErrorIs(err, io.EOF)
+			},
+		},
+		{
+			name: "iterate pieces of running task until empty page",
+			server: func() *mockPieceServer {
+				return newMockPieceServer(0, 6, -1)
+			},
+			options: []PieceTaskIteratorOption{WithPieceTaskPageSize(4)},
+			expect: func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Len(nums, 6)
+				assert.Equal([]uint32{0, 4, 8}, s.startNums)
+				assert.Equal(int32(-1), it.Total())
+				assert.False(it.Consistent())
+			},
+		},
+		{
+			name: "iterate pieces with gaps in the window",
+			server: func() *mockPieceServer {
+				s := newMockPieceServer(0, 10, 10)
+				delete(s.pieces, 1)
+				delete(s.pieces, 5)
+				return s
+			},
+			options: []PieceTaskIteratorOption{WithPieceTaskPageSize(4)},
+			expect: func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal([]int32{0, 2, 3, 4, 6, 7, 8, 9}, nums)
+				assert.Equal([]uint32{0, 4, 8}, s.startNums)
+			},
+		},
+		{
+			name: "resume from the failed page with transient errors",
+			server: func() *mockPieceServer {
+				s := newMockPieceServer(0, 10, 10)
+				s.errs[4] = []error{status.Error(codes.Unavailable, "foo"), context.DeadlineExceeded}
+				return s
+			},
+			options: []PieceTaskIteratorOption{WithPieceTaskPageSize(4), WithPieceTaskRetryBackoff(time.Millisecond)},
+			expect: func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Len(nums, 10)
+				assert.Equal([]uint32{0, 4, 4, 4, 8}, s.startNums)
+			},
+		},
+		{
+			name: "transient errors exceed max retries",
+			server: func() *mockPieceServer {
+				s := newMockPieceServer(0, 10, 10)
+				s.errs[4] = []error{status.Error(codes.Unavailable, "foo"), status.Error(codes.Unavailable, "foo")}
+				return s
+			},
+			options: []PieceTaskIteratorOption{WithPieceTaskPageSize(4), WithPieceTaskMaxRetries(1), WithPieceTaskRetryBackoff(time.Millisecond)},
+			expect: func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error) {
+				assert := assert.New(t)
+				assert.Equal(codes.Unavailable, status.Code(err))
+				assert.Len(nums, 4)
+				assert.Equal([]uint32{0, 4, 4}, s.startNums)
+				assert.Equal(uint32(4), it.StartNum())
+			},
+		},
+		{
+			name: "non-transient error is not retried",
+			server: func() *mockPieceServer {
+				s := newMockPieceServer(0, 10, 10)
+				s.errs[0] = []error{errors.New("foo")}
+				return s
+			},
+			options: []PieceTaskIteratorOption{WithPieceTaskRetryBackoff(time.Millisecond)},
+			expect: func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "foo")
+				assert.Empty(nums)
+				assert.Equal([]uint32{0}, s.startNums)
+			},
+		},
+		{
+			name: "snapshot flag of the server overrides the total piece count",
+			server: func() *mockPieceServer {
+				s := newMockPieceServer(0, 10, 10)
+				s.snapshot = strconv.FormatBool(false)
+				return s
+			},
+			expect: func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Len(nums, 10)
+				assert.Equal([]uint32{0}, s.startNums)
+				assert.False(it.Consistent())
+			},
+		},
+		{
+			name: "page size is clamped to the max page size",
+			server: func() *mockPieceServer {
+				return newMockPieceServer(0, MaxPieceTaskPageSize+1, MaxPieceTaskPageSize+1)
+			},
+			options: []PieceTaskIteratorOption{WithPieceTaskPageSize(MaxPieceTaskPageSize * 2)},
+			expect: func(t *testing.T, it *PieceTaskIterator, s *mockPieceServer, nums []int32, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Len(nums, MaxPieceTaskPageSize+1)
+				assert.Equal([]uint32{0, MaxPieceTaskPageSize}, s.startNums)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := tc.server()
+			it := NewPieceTaskIterator(context.Background(), s.GetPieceTasks, &commonv1.PieceTaskRequest{TaskId: "foo"}, tc.options...)
+			nums, err := collectPieces(it)
+			tc.expect(t, it, s, nums, err)
+		})
+	}
+}
+
+func TestPieceTaskIterator_Channel(t *testing.T) {
+	assert := assert.New(t)
+	s := newMockPieceServer(0, 10, 10)
+	s.errs[4] = []error{status.Error(codes.Unavailable, "foo")}
+	it := NewPieceTaskIterator(context.Background(), s.GetPieceTasks, &commonv1.PieceTaskRequest{TaskId: "foo"},
+		WithPieceTaskPageSize(4), WithPieceTaskRetryBackoff(time.Millisecond))
+
+	packets, errCh := it.Channel()
+	var count int
+	for packet := range packets {
+		count += len(packet.PieceInfos)
+	}
+
+	assert.NoError(<-errCh)
+	assert.Equal(10, count)
+	assert.Equal([]uint32{0, 4, 4, 8}, s.startNums)
+}
+
+func TestPieceTaskIterator_ChannelCanceled(t *testing.T) {
+	assert := assert.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newMockPieceServer(0, 10, 10)
+	it := NewPieceTaskIterator(ctx, s.GetPieceTasks, &commonv1.PieceTaskRequest{TaskId: "foo"}, WithPieceTaskPageSize(4))
+
+	packets, errCh := it.Channel()
+	<-packets
+	cancel()
+	for range packets {
+	}
+
+	assert.ErrorIs(<-errCh, context.Canceled)
+}
+
+func TestIsPiecePaging(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		expect bool
+	}{
+		{
+			name:   "request pages through the pieces",
+			ctx:    metadata.NewIncomingContext(context.Background(), metadata.Pairs(PiecePagingMetadataKey, "true")),
+			expect: true,
+		},
+		{
+			name:   "request does not page through the pieces",
+			ctx:    metadata.NewIncomingContext(context.Background(), metadata.Pairs(PiecePagingMetadataKey, "false")),
+			expect: false,
+		},
+		{
+			name:   "paging metadata is invalid",
+			ctx:    metadata.NewIncomingContext(context.Background(), metadata.Pairs(PiecePagingMetadataKey, "foo")),
+			expect: false,
+		},
+		{
+			name:   "request of the older caller without paging metadata",
+			ctx:    metadata.NewIncomingContext(context.Background(), metadata.MD{}),
+			expect: false,
+		},
+		{
+			name:   "context without metadata",
+			ctx:    context.Background(),
+			expect: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.expect, IsPiecePaging(tc.ctx))
+		})
+	}
+}
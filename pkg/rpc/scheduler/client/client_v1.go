@@ -20,16 +20,21 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"math"
+	"sync"
+	"time"
 
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_zap "github.com/grpc-ecosystem/go-grpc-middleware/logging/zap"
 	grpc_retry "github.com/grpc-ecosystem/go-grpc-middleware/retry"
 	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/hashicorp/go-multierror"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/metadata"
 
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
 	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
@@ -37,13 +42,15 @@ import (
 	"d7y.io/dragonfly/v2/client/config"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	pkgbalancer "d7y.io/dragonfly/v2/pkg/balancer"
+	"d7y.io/dragonfly/v2/pkg/featureflag"
 	"d7y.io/dragonfly/v2/pkg/resolver"
 	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/pkg/rpc/common"
 )
 
-// GetV1 returns v1 version of the scheduler client.
-func GetV1(ctx context.Context, dynconfig config.Dynconfig, opts ...grpc.DialOption) (V1, error) {
+// GetV1 returns v1 version of the scheduler client, the timeouts are applied to
+// the requests without deadline.
+func GetV1(ctx context.Context, dynconfig config.Dynconfig, timeouts config.SchedulerTimeoutOption, opts ...grpc.DialOption) (V1, error) {
 	// Register resolver and balancer.
 	resolver.RegisterScheduler(dynconfig)
 	builder, pickerBuilder := pkgbalancer.NewConsistentHashingBuilder()
@@ -54,6 +61,7 @@ func GetV1(ctx context.Context, dynconfig config.Dynconfig, opts ...grpc.DialOpt
 		resolver.SchedulerVirtualTarget,
 		append([]grpc.DialOption{
 			grpc.WithIdleTimeout(0),
+			grpc.WithKeepaliveParams(rpc.DefaultClientKeepAliveParams()),
 			grpc.WithDefaultCallOptions(
 				grpc.MaxCallRecvMsgSize(math.MaxInt32),
 				grpc.MaxCallSendMsgSize(math.MaxInt32),
@@ -67,6 +75,7 @@ func GetV1(ctx context.Context, dynconfig config.Dynconfig, opts ...grpc.DialOpt
 					grpc_retry.WithMax(maxRetries),
 					grpc_retry.WithBackoff(grpc_retry.BackoffLinear(backoffWaitBetween)),
 				),
+				rpc.RetryAfterUnaryClientInterceptor,
 				rpc.RefresherUnaryClientInterceptor(dynconfig),
 			)),
 			grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
@@ -87,6 +96,7 @@ func GetV1(ctx context.Context, dynconfig config.Dynconfig, opts ...grpc.DialOpt
 		Dynconfig:                      dynconfig,
 		dialOptions:                    opts,
 		ConsistentHashingPickerBuilder: pickerBuilder,
+		timeouts:                       timeouts,
 	}, nil
 }
 
@@ -97,6 +107,7 @@ func GetV1ByAddr(ctx context.Context, target string, opts ...grpc.DialOption) (V
 		target,
 		append([]grpc.DialOption{
 			grpc.WithIdleTimeout(0),
+			grpc.WithKeepaliveParams(rpc.DefaultClientKeepAliveParams()),
 			grpc.WithDefaultCallOptions(
 				grpc.MaxCallRecvMsgSize(math.MaxInt32),
 				grpc.MaxCallSendMsgSize(math.MaxInt32),
@@ -111,6 +122,7 @@ func GetV1ByAddr(ctx context.Context, target string, opts ...grpc.DialOption) (V
 					grpc_retry.WithMax(maxRetries),
 					grpc_retry.WithBackoff(grpc_retry.BackoffLinear(backoffWaitBetween)),
 				),
+				rpc.RetryAfterUnaryClientInterceptor,
 			)),
 			grpc.WithStreamInterceptor(grpc_middleware.ChainStreamClient(
 				rpc.ConvertErrorStreamClientInterceptor,
@@ -150,6 +162,10 @@ type V1 interface {
 	// LeaveTask releases peer in scheduler.
 	LeaveTask(context.Context, *schedulerv1.PeerTarget, ...grpc.CallOption) error
 
+	// LeaveTasks releases peers in scheduler concurrently, the requests which are
+	// not sent before the deadline will be abandoned.
+	LeaveTasks(context.Context, []*schedulerv1.PeerTarget, ...grpc.CallOption) error
+
 	// AnnounceHost announces host to scheduler.
 	AnnounceHost(context.Context, *schedulerv1.AnnounceHostRequest, ...grpc.CallOption) error
 
@@ -170,49 +186,111 @@ type v1 struct {
 	config.Dynconfig
 	dialOptions []grpc.DialOption
 	*pkgbalancer.ConsistentHashingPickerBuilder
+	timeouts config.SchedulerTimeoutOption
+}
+
+// withTimeout returns the context with timeout if ctx has no deadline,
+// the zero timeout is replaced by the default one.
+func withTimeout(ctx context.Context, timeout, defaultTimeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+
+	return context.WithTimeout(ctx, timeout)
 }
 
 // RegisterPeerTask registers a peer into task.
 func (v *v1) RegisterPeerTask(ctx context.Context, req *schedulerv1.PeerTaskRequest, opts ...grpc.CallOption) (*schedulerv1.RegisterResult, error) {
-	ctx, cancel := context.WithTimeout(ctx, contextTimeout)
+	ctx, cancel := withTimeout(ctx, v.timeouts.Register, config.DefaultSchedulerRegisterTimeout)
 	defer cancel()
 
-	return v.SchedulerClient.RegisterPeerTask(
+	var header metadata.MD
+	resp, err := v.SchedulerClient.RegisterPeerTask(
 		context.WithValue(ctx, pkgbalancer.ContextKey, req.TaskId),
 		req,
-		opts...,
+		append(opts, grpc.Header(&header))...,
 	)
+	if err != nil {
+		return nil, wrapTimeout(ctx, "RegisterPeerTask", err)
+	}
+
+	v.setFeatureFlags(header)
+	return resp, nil
 }
 
-// ReportPieceResult reports piece results and receives peer packets.
+// setFeatureFlags applies the feature flags forwarded by the scheduler in the response header,
+// the flags are kept if the scheduler does not forward them, e.g. the scheduler of old version.
+func (v *v1) setFeatureFlags(header metadata.MD) {
+	if v.Dynconfig == nil {
+		return
+	}
+
+	values := header.Get(featureflag.MetadataKey)
+	if len(values) == 0 {
+		return
+	}
+
+	flags, err := featureflag.Decode(values[0])
+	if err != nil {
+		logger.Warnf("decode feature flags %s failed: %s", values[0], err.Error())
+		return
+	}
+
+	v.Dynconfig.SetFeatureFlags(flags)
+}
+
+// ReportPieceResult reports piece results and receives peer packets. The connect timeout
+// only limits establishing the stream, and the stream lives as long as ctx.
 func (v *v1) ReportPieceResult(ctx context.Context, req *schedulerv1.PeerTaskRequest, opts ...grpc.CallOption) (schedulerv1.Scheduler_ReportPieceResultClient, error) {
-	stream, err := v.SchedulerClient.ReportPieceResult(
-		context.WithValue(ctx, pkgbalancer.ContextKey, req.TaskId),
-		opts...,
-	)
+	connectTimeout := v.timeouts.Connect
+	if connectTimeout <= 0 {
+		connectTimeout = config.DefaultSchedulerConnectTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(ctx, pkgbalancer.ContextKey, req.TaskId))
+	timer := time.AfterFunc(connectTimeout, cancel)
+
+	stream, err := v.SchedulerClient.ReportPieceResult(ctx, opts...)
+	if err == nil {
+		// Send begin of piece.
+		err = stream.Send(&schedulerv1.PieceResult{
+			TaskId: req.TaskId,
+			SrcPid: req.PeerId,
+			PieceInfo: &commonv1.PieceInfo{
+				PieceNum: common.BeginOfPiece,
+			},
+		})
+	}
+
+	// The stream is canceled by the timer, it is not established in time.
+	if !timer.Stop() {
+		return nil, &TimeoutError{Method: "ReportPieceResult", Err: fmt.Errorf("connect in %s: %w", connectTimeout, context.DeadlineExceeded)}
+	}
+
 	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	// Send begin of piece.
-	return stream, stream.Send(&schedulerv1.PieceResult{
-		TaskId: req.TaskId,
-		SrcPid: req.PeerId,
-		PieceInfo: &commonv1.PieceInfo{
-			PieceNum: common.BeginOfPiece,
-		},
-	})
+	return stream, nil
 }
 
 // ReportPeerResult reports downloading result for the peer.
 func (v *v1) ReportPeerResult(ctx context.Context, req *schedulerv1.PeerResult, opts ...grpc.CallOption) error {
+	ctx, cancel := withTimeout(ctx, v.timeouts.Report, config.DefaultSchedulerReportTimeout)
+	defer cancel()
+
 	_, err := v.SchedulerClient.ReportPeerResult(
 		context.WithValue(ctx, pkgbalancer.ContextKey, req.TaskId),
 		req,
 		opts...,
 	)
 
-	return err
+	return wrapTimeout(ctx, "ReportPeerResult", err)
 }
 
 // A peer announces that it has the announced task to other peers.
@@ -243,7 +321,7 @@ func (v *v1) StatTask(ctx context.Context, req *schedulerv1.StatTaskRequest, opt
 
 // LeaveTask releases peer in scheduler.
 func (v *v1) LeaveTask(ctx context.Context, req *schedulerv1.PeerTarget, opts ...grpc.CallOption) error {
-	ctx, cancel := context.WithTimeout(ctx, contextTimeout)
+	ctx, cancel := withTimeout(ctx, v.timeouts.Leave, config.DefaultSchedulerLeaveTimeout)
 	defer cancel()
 
 	_, err := v.SchedulerClient.LeaveTask(
@@ -252,7 +330,58 @@ func (v *v1) LeaveTask(ctx context.Context, req *schedulerv1.PeerTarget, opts ..
 		opts...,
 	)
 
-	return err
+	return wrapTimeout(ctx, "LeaveTask", err)
+}
+
+// LeaveTasks releases peers in scheduler concurrently, the requests which are
+// not sent before the deadline will be abandoned.
+func (v *v1) LeaveTasks(ctx context.Context, reqs []*schedulerv1.PeerTarget, opts ...grpc.CallOption) error {
+	ctx, cancel := context.WithTimeout(ctx, leaveTasksTimeout)
+	defer cancel()
+
+	var (
+		mu        sync.Mutex
+		errs      *multierror.Error
+		abandoned int
+		wg        sync.WaitGroup
+	)
+
+	// Bound the in-flight requests, the slot is acquired before spawning the goroutine,
+	// so requests which are not started before the deadline can be abandoned.
+	sem := make(chan struct{}, leaveTasksConcurrency)
+	for i, req := range reqs {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			abandoned = len(reqs) - i
+			break
+		}
+
+		wg.Add(1)
+		go func(req *schedulerv1.PeerTarget) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := v.LeaveTask(ctx, req, opts...); err != nil {
+				mu.Lock()
+				errs = multierror.Append(errs, fmt.Errorf("leave task %s/%s: %w", req.TaskId, req.PeerId, err))
+				mu.Unlock()
+			}
+		}(req)
+	}
+	wg.Wait()
+
+	if abandoned > 0 {
+		logger.Warnf("abandon %d of %d leave task requests: %v", abandoned, len(reqs), ctx.Err())
+		errs = multierror.Append(errs, fmt.Errorf("abandon %d leave task requests: %w", abandoned, ctx.Err()))
+	}
+
+	return errs.ErrorOrNil()
 }
 
 // AnnounceHost announces host to scheduler.
@@ -286,7 +415,7 @@ func (v *v1) AnnounceHost(ctx context.Context, req *schedulerv1.AnnounceHostRequ
 
 // LeaveHost releases host in all schedulers.
 func (v *v1) LeaveHost(ctx context.Context, req *schedulerv1.LeaveHostRequest, opts ...grpc.CallOption) error {
-	ctx, cancel := context.WithTimeout(ctx, contextTimeout)
+	ctx, cancel := withTimeout(ctx, v.timeouts.Leave, config.DefaultSchedulerLeaveTimeout)
 	defer cancel()
 
 	circle, err := v.GetCircle()
@@ -311,7 +440,7 @@ func (v *v1) LeaveHost(ctx context.Context, req *schedulerv1.LeaveHostRequest, o
 		})
 	}
 
-	return eg.Wait()
+	return wrapTimeout(ctx, "LeaveHost", eg.Wait())
 }
 
 // SyncProbes sync probes of the host.
@@ -0,0 +1,477 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+
+	"d7y.io/dragonfly/v2/client/config"
+	"d7y.io/dragonfly/v2/pkg/featureflag"
+)
+
+// leaveTaskSchedulerClient only implements LeaveTask of the scheduler grpc client.
+type leaveTaskSchedulerClient struct {
+	schedulerv1.SchedulerClient
+	calls     *atomic.Int32
+	leaveTask func(context.Context, *schedulerv1.PeerTarget) error
+}
+
+func (c *leaveTaskSchedulerClient) LeaveTask(ctx context.Context, req *schedulerv1.PeerTarget, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	c.calls.Inc()
+	if err := c.leaveTask(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func newPeerTargets(n int) []*schedulerv1.PeerTarget {
+	targets := make([]*schedulerv1.PeerTarget, 0, n)
+	for i := 0; i < n; i++ {
+		targets = append(targets, &schedulerv1.PeerTarget{
+			TaskId: fmt.Sprintf("task-%d", i),
+			PeerId: fmt.Sprintf("peer-%d", i),
+		})
+	}
+
+	return targets
+}
+
+func TestV1_LeaveTasks(t *testing.T) {
+	tests := []struct {
+		name    string
+		targets []*schedulerv1.PeerTarget
+		timeout time.Duration
+		leave   func(ctx context.Context, req *schedulerv1.PeerTarget) error
+		expect  func(t *testing.T, calls int32, err error)
+	}{
+		{
+			name:    "leave tasks succeeded",
+			targets: newPeerTargets(64),
+			timeout: time.Minute,
+			leave: func(ctx context.Context, req *schedulerv1.PeerTarget) error {
+				return nil
+			},
+			expect: func(t *testing.T, calls int32, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(int32(64), calls)
+			},
+		},
+		{
+			name:    "leave tasks without targets",
+			targets: nil,
+			timeout: time.Minute,
+			leave: func(ctx context.Context, req *schedulerv1.PeerTarget) error {
+				return nil
+			},
+			expect: func(t *testing.T, calls int32, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(int32(0), calls)
+			},
+		},
+		{
+			name:    "leave tasks partially failed",
+			targets: newPeerTargets(8),
+			timeout: time.Minute,
+			leave: func(ctx context.Context, req *schedulerv1.PeerTarget) error {
+				if req.TaskId == "task-1" || req.TaskId == "task-5" {
+					return errors.New("foo")
+				}
+
+				return nil
+			},
+			expect: func(t *testing.T, calls int32, err error) {
+				assert := assert.New(t)
+				assert.Equal(int32(8), calls)
+				assert.Error(err)
+				assert.Contains(err.Error(), "2 errors occurred")
+				assert.Contains(err.Error(), "leave task task-1/peer-1: foo")
+				assert.Contains(err.Error(), "leave task task-5/peer-5: foo")
+			},
+		},
+		{
+			name:    "leave tasks exceeded deadline",
+			targets: newPeerTargets(leaveTasksConcurrency * 4),
+			timeout: 100 * time.Millisecond,
+			leave: func(ctx context.Context, req *schedulerv1.PeerTarget) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			expect: func(t *testing.T, calls int32, err error) {
+				assert := assert.New(t)
+				assert.Equal(int32(leaveTasksConcurrency), calls)
+				assert.Error(err)
+				assert.ErrorIs(err, context.DeadlineExceeded)
+				assert.Contains(err.Error(), fmt.Sprintf("abandon %d leave task requests", leaveTasksConcurrency*3))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			schedulerClient := &leaveTaskSchedulerClient{
+				calls:     atomic.NewInt32(0),
+				leaveTask: tc.leave,
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+
+			v := &v1{SchedulerClient: schedulerClient}
+			err := v.LeaveTasks(ctx, tc.targets)
+			tc.expect(t, schedulerClient.calls.Load(), err)
+		})
+	}
+}
+
+func TestV1_LeaveTasks_Concurrency(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu      sync.Mutex
+		running = atomic.NewInt32(0)
+		peak    int32
+	)
+
+	targets := newPeerTargets(leaveTasksConcurrency * 8)
+	schedulerClient := &leaveTaskSchedulerClient{
+		calls: atomic.NewInt32(0),
+		leaveTask: func(ctx context.Context, req *schedulerv1.PeerTarget) error {
+			n := running.Inc()
+			defer running.Dec()
+
+			mu.Lock()
+			if n > peak {
+				peak = n
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+			return nil
+		},
+	}
+
+	v := &v1{SchedulerClient: schedulerClient}
+	assert.NoError(v.LeaveTasks(context.Background(), targets))
+	assert.Equal(int32(len(targets)), schedulerClient.calls.Load())
+	assert.LessOrEqual(peak, int32(leaveTasksConcurrency))
+}
+
+// unresponsiveSchedulerServer blocks the requests until they are canceled,
+// except the requests of the rejected task.
+type unresponsiveSchedulerServer struct {
+	schedulerv1.UnimplementedSchedulerServer
+}
+
+func (s *unresponsiveSchedulerServer) RegisterPeerTask(ctx context.Context, req *schedulerv1.PeerTaskRequest) (*schedulerv1.RegisterResult, error) {
+	if req.TaskId == "rejected" {
+		return nil, status.Error(codes.FailedPrecondition, "task is rejected")
+	}
+
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (s *unresponsiveSchedulerServer) ReportPeerResult(ctx context.Context, req *schedulerv1.PeerResult) (*emptypb.Empty, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (s *unresponsiveSchedulerServer) LeaveTask(ctx context.Context, req *schedulerv1.PeerTarget) (*emptypb.Empty, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// ReportPieceResult responds the begin of piece after a while, to make sure
+// the stream lives longer than the connect timeout.
+func (s *unresponsiveSchedulerServer) ReportPieceResult(stream schedulerv1.Scheduler_ReportPieceResultServer) error {
+	if _, err := stream.Recv(); err != nil {
+		return err
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	return stream.Send(&schedulerv1.PeerPacket{TaskId: "foo"})
+}
+
+// newUnresponsiveScheduler returns v1 version of the scheduler client connected to
+// the unresponsive scheduler server.
+func newUnresponsiveScheduler(t *testing.T, timeouts config.SchedulerTimeoutOption) *v1 {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := grpc.NewServer()
+	schedulerv1.RegisterSchedulerServer(server, &unresponsiveSchedulerServer{})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	return newSchedulerByAddr(t, lis.Addr().String(), timeouts)
+}
+
+func newSchedulerByAddr(t *testing.T, addr string, timeouts config.SchedulerTimeoutOption) *v1 {
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &v1{
+		SchedulerClient: schedulerv1.NewSchedulerClient(conn),
+		ClientConn:      conn,
+		timeouts:        timeouts,
+	}
+}
+
+func TestV1_Timeouts(t *testing.T) {
+	timeouts := config.SchedulerTimeoutOption{
+		Register: 100 * time.Millisecond,
+		Report:   100 * time.Millisecond,
+		Leave:    100 * time.Millisecond,
+		Connect:  100 * time.Millisecond,
+	}
+
+	tests := []struct {
+		name     string
+		timeouts config.SchedulerTimeoutOption
+		timeout  time.Duration
+		run      func(ctx context.Context, v *v1) error
+		expect   func(t *testing.T, err error)
+	}{
+		{
+			name:     "register peer task timeout",
+			timeouts: timeouts,
+			run: func(ctx context.Context, v *v1) error {
+				_, err := v.RegisterPeerTask(ctx, &schedulerv1.PeerTaskRequest{TaskId: "foo"})
+				return err
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.True(IsTimeout(err))
+				assert.ErrorIs(err, context.DeadlineExceeded)
+				assert.Equal(codes.DeadlineExceeded, status.Code(errors.Unwrap(err)))
+			},
+		},
+		{
+			name:     "register peer task with the deadline of context",
+			timeouts: config.SchedulerTimeoutOption{Register: time.Hour},
+			timeout:  100 * time.Millisecond,
+			run: func(ctx context.Context, v *v1) error {
+				_, err := v.RegisterPeerTask(ctx, &schedulerv1.PeerTaskRequest{TaskId: "foo"})
+				return err
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.True(IsTimeout(err))
+				assert.ErrorIs(err, context.DeadlineExceeded)
+			},
+		},
+		{
+			name:     "register peer task rejected",
+			timeouts: timeouts,
+			run: func(ctx context.Context, v *v1) error {
+				_, err := v.RegisterPeerTask(ctx, &schedulerv1.PeerTaskRequest{TaskId: "rejected"})
+				return err
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.False(IsTimeout(err))
+				assert.NotErrorIs(err, context.DeadlineExceeded)
+				assert.Equal(codes.FailedPrecondition, status.Code(err))
+			},
+		},
+		{
+			name:     "report peer result timeout",
+			timeouts: timeouts,
+			run: func(ctx context.Context, v *v1) error {
+				return v.ReportPeerResult(ctx, &schedulerv1.PeerResult{TaskId: "foo"})
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.True(IsTimeout(err))
+				assert.EqualError(err, "scheduler ReportPeerResult timeout: rpc error: code = DeadlineExceeded desc = context deadline exceeded")
+			},
+		},
+		{
+			name:     "leave task timeout",
+			timeouts: timeouts,
+			run: func(ctx context.Context, v *v1) error {
+				return v.LeaveTask(ctx, &schedulerv1.PeerTarget{TaskId: "foo"})
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.True(IsTimeout(err))
+				assert.ErrorIs(err, context.DeadlineExceeded)
+			},
+		},
+		{
+			name:     "report piece result outlives the connect timeout",
+			timeouts: timeouts,
+			run: func(ctx context.Context, v *v1) error {
+				stream, err := v.ReportPieceResult(ctx, &schedulerv1.PeerTaskRequest{TaskId: "foo", PeerId: "bar"})
+				if err != nil {
+					return err
+				}
+
+				_, err = stream.Recv()
+				return err
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			v := newUnresponsiveScheduler(t, tc.timeouts)
+
+			ctx := context.Background()
+			if tc.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.timeout)
+				defer cancel()
+			}
+
+			tc.expect(t, tc.run(ctx, v))
+		})
+	}
+}
+
+func TestV1_ReportPieceResult_ConnectTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	// The listener accepts the connections but never speaks http2,
+	// so the stream can not be established.
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+
+			conns = append(conns, conn)
+		}
+	}()
+
+	v := newSchedulerByAddr(t, lis.Addr().String(), config.SchedulerTimeoutOption{Connect: 100 * time.Millisecond})
+	start := time.Now()
+	_, err = v.ReportPieceResult(context.Background(), &schedulerv1.PeerTaskRequest{TaskId: "foo", PeerId: "bar"})
+	assert.True(IsTimeout(err))
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	assert.Less(time.Since(start), 5*time.Second)
+}
+
+// featureFlagsSchedulerServer forwards the feature flags in the response header of RegisterPeerTask,
+// the header is not set if the flags are empty, as the scheduler of old version.
+type featureFlagsSchedulerServer struct {
+	schedulerv1.UnimplementedSchedulerServer
+	flags *atomic.String
+}
+
+func (s *featureFlagsSchedulerServer) RegisterPeerTask(ctx context.Context, req *schedulerv1.PeerTaskRequest) (*schedulerv1.RegisterResult, error) {
+	if flags := s.flags.Load(); flags != "" {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(featureflag.MetadataKey, flags)); err != nil {
+			return nil, err
+		}
+	}
+
+	return &schedulerv1.RegisterResult{TaskId: req.TaskId}, nil
+}
+
+func TestV1_RegisterPeerTask_FeatureFlags(t *testing.T) {
+	assert := assert.New(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flags := atomic.NewString("")
+	server := grpc.NewServer()
+	schedulerv1.RegisterSchedulerServer(server, &featureFlagsSchedulerServer{flags: flags})
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	dynconfig, err := config.NewDynconfig(config.LocalSourceType, &config.DaemonOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := newSchedulerByAddr(t, lis.Addr().String(), config.SchedulerTimeoutOption{})
+	v.Dynconfig = dynconfig
+	register := func() {
+		_, err := v.RegisterPeerTask(context.Background(), &schedulerv1.PeerTaskRequest{TaskId: "foo"})
+		assert.NoError(err)
+	}
+
+	// The flags are the defaults before the scheduler forwards them.
+	assert.True(dynconfig.GetFeatureFlag(featureflag.PieceCompression).Bool())
+	assert.Equal("v1", dynconfig.GetFeatureFlag(featureflag.PieceDownloadEngine).String())
+
+	// The unknown flags are treated as absent.
+	flags.Store(`{"piece_compression":false,"piece_compression_level":3,"foo":true}`)
+	register()
+	assert.False(dynconfig.GetFeatureFlag(featureflag.PieceCompression).Bool())
+	assert.Equal(int64(3), dynconfig.GetFeatureFlag(featureflag.PieceCompressionLevel).Int())
+	assert.False(dynconfig.GetFeatureFlag("foo").Bool())
+
+	// The updated flags take effect on the next registration without reconnecting.
+	flags.Store(`{"piece_download_engine":"v2"}`)
+	register()
+	assert.True(dynconfig.GetFeatureFlag(featureflag.PieceCompression).Bool())
+	assert.Equal(int64(0), dynconfig.GetFeatureFlag(featureflag.PieceCompressionLevel).Int())
+	assert.Equal("v2", dynconfig.GetFeatureFlag(featureflag.PieceDownloadEngine).String())
+
+	// The flags are kept if the scheduler does not forward them.
+	flags.Store("")
+	register()
+	assert.Equal("v2", dynconfig.GetFeatureFlag(featureflag.PieceDownloadEngine).String())
+}
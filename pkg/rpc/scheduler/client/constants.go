@@ -24,6 +24,12 @@ const (
 	// contextTimeout is timeout of grpc invoke.
 	contextTimeout = 2 * time.Minute
 
+	// leaveTasksTimeout is the hard deadline of leaving tasks in batch.
+	leaveTasksTimeout = 30 * time.Second
+
+	// leaveTasksConcurrency is the maximum number of concurrent leave task requests.
+	leaveTasksConcurrency = 16
+
 	// maxRetries is maximum number of retries.
 	maxRetries = 3
 
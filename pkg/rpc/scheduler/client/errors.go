@@ -0,0 +1,73 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TimeoutError is returned when scheduler does not respond before the deadline of the request,
+// it tells the slow scheduler apart from the request rejected by scheduler.
+type TimeoutError struct {
+	// Method is the name of the request.
+	Method string
+
+	// Err is the error returned by the request.
+	Err error
+}
+
+// Error implements error.
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("scheduler %s timeout: %v", e.Method, e.Err)
+}
+
+// Unwrap returns the error returned by the request.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports the TimeoutError as context.DeadlineExceeded, because the error
+// returned by the request may be converted from the grpc status.
+func (e *TimeoutError) Is(target error) bool {
+	return target == context.DeadlineExceeded
+}
+
+// IsTimeout returns whether the error is caused by the deadline of the request.
+func IsTimeout(err error) bool {
+	var timeoutErr *TimeoutError
+	return errors.As(err, &timeoutErr)
+}
+
+// IsThrottled returns whether the request is rejected by the rate limit or the overload protection
+// of scheduler, the request is expected to be retried later instead of falling back to the source.
+func IsThrottled(err error) bool {
+	return status.Code(err) == codes.ResourceExhausted
+}
+
+// wrapTimeout returns the TimeoutError if the request failed after the deadline of ctx is exceeded.
+func wrapTimeout(ctx context.Context, method string, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	return &TimeoutError{Method: method, Err: err}
+}
@@ -0,0 +1,67 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestIsThrottled(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{
+			name:   "request is throttled",
+			err:    status.Error(codes.ResourceExhausted, "foo"),
+			expect: true,
+		},
+		{
+			name:   "wrapped request is throttled",
+			err:    fmt.Errorf("foo: %w", status.Error(codes.ResourceExhausted, "bar")),
+			expect: true,
+		},
+		{
+			name:   "request is unavailable",
+			err:    status.Error(codes.Unavailable, "foo"),
+			expect: false,
+		},
+		{
+			name:   "error is not grpc status",
+			err:    errors.New("foo"),
+			expect: false,
+		},
+		{
+			name:   "error is nil",
+			expect: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.expect, IsThrottled(tc.err))
+		})
+	}
+}
@@ -131,6 +131,25 @@ func (mr *MockV1MockRecorder) LeaveTask(arg0, arg1 any, arg2 ...any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaveTask", reflect.TypeOf((*MockV1)(nil).LeaveTask), varargs...)
 }
 
+// LeaveTasks mocks base method.
+func (m *MockV1) LeaveTasks(arg0 context.Context, arg1 []*scheduler.PeerTarget, arg2 ...grpc.CallOption) error {
+	m.ctrl.T.Helper()
+	varargs := []any{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "LeaveTasks", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LeaveTasks indicates an expected call of LeaveTasks.
+func (mr *MockV1MockRecorder) LeaveTasks(arg0, arg1 any, arg2 ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaveTasks", reflect.TypeOf((*MockV1)(nil).LeaveTasks), varargs...)
+}
+
 // RegisterPeerTask mocks base method.
 func (m *MockV1) RegisterPeerTask(arg0 context.Context, arg1 *scheduler.PeerTaskRequest, arg2 ...grpc.CallOption) (*scheduler.RegisterResult, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,89 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// FilePrefix is the prefix of the value referencing a secret file, e.g. file:///etc/dragonfly/redis-password.
+const FilePrefix = "file://"
+
+// envReference matches the ${ENV_VAR} reference of the environment variable.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Field is a config field holding a secret, the value is resolved in place.
+type Field struct {
+	// Name is the name of the field in the config, e.g. database.redis.password.
+	Name string
+
+	// Value is the value of the field.
+	Value *string
+}
+
+// Resolve resolves the secret references of the value. The value prefixed with file:// is replaced
+// with the content of the file, the trailing newline of the file is trimmed. Otherwise the ${ENV_VAR}
+// references in the value are expanded with the environment variables. The returned error never
+// contains the secret.
+func Resolve(value string) (string, error) {
+	if path, ok := strings.CutPrefix(value, FilePrefix); ok {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("unreadable secret file: %w", err)
+		}
+
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+
+	var err error
+	resolved := envReference.ReplaceAllStringFunc(value, func(reference string) string {
+		name := envReference.FindStringSubmatch(reference)[1]
+		env, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("missing environment variable %s", name)
+		}
+
+		return env
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resolved, nil
+}
+
+// ResolveFields resolves the secret references of the fields in place, the error
+// indicates the name of the field which can not be resolved.
+func ResolveFields(fields ...Field) error {
+	for _, field := range fields {
+		if field.Value == nil || *field.Value == "" {
+			continue
+		}
+
+		value, err := Resolve(*field.Value)
+		if err != nil {
+			return fmt.Errorf("resolve %s: %w", field.Name, err)
+		}
+
+		*field.Value = value
+	}
+
+	return nil
+}
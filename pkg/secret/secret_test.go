@@ -0,0 +1,117 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecret_Resolve(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "password")
+	if err := os.WriteFile(file, []byte("foo\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DRAGONFLY_SECRET_FOO", "foo")
+	t.Setenv("DRAGONFLY_SECRET_EMPTY", "")
+
+	tests := []struct {
+		name   string
+		value  string
+		expect func(t *testing.T, value string, err error)
+	}{
+		{
+			name:  "value without references",
+			value: "foo$bar",
+			expect: func(t *testing.T, value string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal("foo$bar", value)
+			},
+		},
+		{
+			name:  "expand environment variables",
+			value: "${DRAGONFLY_SECRET_FOO}-${DRAGONFLY_SECRET_EMPTY}bar",
+			expect: func(t *testing.T, value string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal("foo-bar", value)
+			},
+		},
+		{
+			name:  "missing environment variable",
+			value: "${DRAGONFLY_SECRET_MISSING}",
+			expect: func(t *testing.T, value string, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "missing environment variable DRAGONFLY_SECRET_MISSING")
+				assert.Empty(value)
+			},
+		},
+		{
+			name:  "read secret file",
+			value: FilePrefix + file,
+			expect: func(t *testing.T, value string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal("foo", value)
+			},
+		},
+		{
+			name:  "unreadable secret file",
+			value: FilePrefix + filepath.Join(dir, "missing"),
+			expect: func(t *testing.T, value string, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, os.ErrNotExist)
+				assert.Empty(value)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			value, err := Resolve(tc.value)
+			tc.expect(t, value, err)
+		})
+	}
+}
+
+func TestSecret_ResolveFields(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("DRAGONFLY_SECRET_FOO", "foo")
+
+	username, password, empty := "${DRAGONFLY_SECRET_FOO}", "bar", ""
+	assert.NoError(ResolveFields(
+		Field{Name: "username", Value: &username},
+		Field{Name: "password", Value: &password},
+		Field{Name: "empty", Value: &empty},
+		Field{Name: "nil"},
+	))
+	assert.Equal("foo", username)
+	assert.Equal("bar", password)
+	assert.Empty(empty)
+
+	password = "secret-${DRAGONFLY_SECRET_MISSING}"
+	err := ResolveFields(Field{Name: "redis.password", Value: &password})
+	assert.EqualError(err, "resolve redis.password: missing environment variable DRAGONFLY_SECRET_MISSING")
+	assert.NotContains(err.Error(), "secret-")
+	assert.Equal("secret-${DRAGONFLY_SECRET_MISSING}", password)
+}
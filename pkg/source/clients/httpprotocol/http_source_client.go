@@ -17,16 +17,22 @@
 package httpprotocol
 
 import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"regexp"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/go-http-utils/headers"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 	"d7y.io/dragonfly/v2/pkg/source"
 )
 
@@ -80,6 +86,10 @@ func Adapter(request *source.Request) *source.Request {
 // httpSourceClient is an implementation of the interface of source.ResourceClient.
 type httpSourceClient struct {
 	httpClient *http.Client
+
+	// certificateClients are the http clients with client certificates,
+	// the key is the digest of the client certificate and private key.
+	certificateClients *sync.Map
 }
 
 // NewHTTPSourceClient returns a new HTTPSourceClientOption.
@@ -88,7 +98,9 @@ func NewHTTPSourceClient(opts ...HTTPSourceClientOption) source.ResourceClient {
 }
 
 func newHTTPSourceClient(opts ...HTTPSourceClientOption) *httpSourceClient {
-	client := &httpSourceClient{}
+	client := &httpSourceClient{
+		certificateClients: &sync.Map{},
+	}
 	for i := range opts {
 		opts[i](client)
 	}
@@ -260,19 +272,73 @@ func (client *httpSourceClient) doRequest(method string, request *source.Request
 		return nil, err
 	}
 	for key, values := range request.Header {
+		// Credentials of the source are used by the client, not sent as headers.
+		if nethttp.IsSensitiveHeader(key) || isAWSSigningHeader(key) {
+			continue
+		}
+
 		for i := range values {
 			req.Header.Add(key, values[i])
 		}
 	}
 
+	if err := signAWSV4(req, request.Header, time.Now()); err != nil {
+		return nil, err
+	}
+
+	httpClient, err := client.loadHTTPClient(request.Header)
+	if err != nil {
+		return nil, err
+	}
+
 	logger.Debugf("request %s %s header: %#v", method, req.URL.String(), req.Header)
-	resp, err := client.httpClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	return resp, nil
 }
 
+// loadHTTPClient returns the http client for the request, if the request has a client certificate,
+// the http client presents it in the TLS handshake, otherwise the default http client is returned.
+func (client *httpSourceClient) loadHTTPClient(header source.Header) (*http.Client, error) {
+	rawCert, rawKey := header.Get(nethttp.HeaderClientCertificate), header.Get(nethttp.HeaderClientKey)
+	if rawCert == "" && rawKey == "" {
+		return client.httpClient, nil
+	}
+
+	digest := sha256.Sum256([]byte(rawCert + rawKey))
+	key := hex.EncodeToString(digest[:])
+	if httpClient, ok := client.certificateClients.Load(key); ok {
+		return httpClient.(*http.Client), nil
+	}
+
+	cert, err := source.ClientCertificateFromHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var transport *http.Transport
+	switch t := client.httpClient.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		return nil, errors.New("transport of http client does not support client certificate")
+	}
+
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.Certificates = []tls.Certificate{*cert}
+
+	httpClient := *client.httpClient
+	httpClient.Transport = transport
+	actual, _ := client.certificateClients.LoadOrStore(key, &httpClient)
+	return actual.(*http.Client), nil
+}
+
 func exportPassThroughHeader(header http.Header) map[string]string {
 	var ph = map[string]string{}
 	for h := range PassThroughHeaders {
@@ -18,10 +18,19 @@ package httpprotocol
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"io"
 	"math"
+	"math/big"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -411,3 +420,90 @@ func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientDoRequest() {
 	suite.Nil(err)
 	suite.EqualValues("ok", string(bytes))
 }
+
+func (suite *HTTPSourceClientTestSuite) TestHttpSourceClientDoRequestWithClientCertificate() {
+	certPEM, keyPEM := generateClientCertificate(suite.T())
+	block, _ := pem.Decode(certPEM)
+	clientCert, err := x509.ParseCertificate(block.Bytes)
+	suite.Nil(err)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Client certificate must not be sent to the source as header.
+		if r.Header.Get(nethttp.HeaderClientCertificate) != "" || r.Header.Get(nethttp.HeaderClientKey) != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		_, _ = w.Write([]byte("ok"))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	sourceClient := newHTTPSourceClient(WithHTTPClient(server.Client()))
+
+	// Request without client certificate is rejected by the source.
+	request, err := source.NewRequest(server.URL)
+	suite.Nil(err)
+	if res, err := sourceClient.doRequest(http.MethodGet, request); err == nil {
+		_, err = io.ReadAll(res.Body)
+		res.Body.Close()
+		suite.NotNil(err)
+	}
+
+	// Request with client certificate only uses it for the request.
+	hdr := map[string]string{}
+	source.SetClientCertificate(hdr, certPEM, keyPEM)
+	request, err = source.NewRequestWithHeader(server.URL, hdr)
+	suite.Nil(err)
+	res, err := sourceClient.doRequest(http.MethodGet, request)
+	suite.Nil(err)
+	defer res.Body.Close()
+	suite.Equal(http.StatusOK, res.StatusCode)
+	bytes, err := io.ReadAll(res.Body)
+	suite.Nil(err)
+	suite.EqualValues("ok", string(bytes))
+	suite.Nil(sourceClient.httpClient.Transport.(*http.Transport).TLSClientConfig.Certificates)
+
+	// Request with invalid client certificate.
+	request, err = source.NewRequestWithHeader(server.URL, map[string]string{nethttp.HeaderClientCertificate: "foo", nethttp.HeaderClientKey: "bar"})
+	suite.Nil(err)
+	_, err = sourceClient.doRequest(http.MethodGet, request)
+	suite.NotNil(err)
+}
+
+func generateClientCertificate(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "dfget"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
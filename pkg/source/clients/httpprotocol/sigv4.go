@@ -0,0 +1,112 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httpprotocol
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
+
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+const (
+	// defaultAWSRegion is the region of the signature if it can not be derived from the host,
+	// e.g. the global endpoint of s3 and the s3 compatible storages.
+	defaultAWSRegion = "us-east-1"
+
+	// defaultAWSService is the service of the signature if it is not configured.
+	defaultAWSService = "s3"
+)
+
+// isAWSSigningHeader reports whether the header configures the aws signature,
+// it is not sent to the source.
+func isAWSSigningHeader(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	return key == nethttp.HeaderAWSRegion || key == nethttp.HeaderAWSService
+}
+
+// signAWSV4 signs the request with aws signature version 4 if the header has the aws credentials.
+// Every request is signed when it is sent, including the ranged requests of the pieces and the retries,
+// so the signature does not expire during the long downloads like the presigned url.
+func signAWSV4(req *http.Request, header source.Header, signTime time.Time) error {
+	accessKeyID, secretAccessKey := header.Get(nethttp.HeaderAWSAccessKeyID), header.Get(nethttp.HeaderAWSSecretAccessKey)
+	if accessKeyID == "" && secretAccessKey == "" {
+		return nil
+	}
+
+	if accessKeyID == "" || secretAccessKey == "" {
+		return errors.New("aws access key id and secret access key must be set together")
+	}
+
+	region := header.Get(nethttp.HeaderAWSRegion)
+	if region == "" {
+		region = awsRegionFromHost(req.URL.Hostname())
+	}
+
+	service := header.Get(nethttp.HeaderAWSService)
+	if service == "" {
+		service = defaultAWSService
+	}
+
+	signer := v4.NewSigner(credentials.NewStaticCredentials(accessKeyID, secretAccessKey, header.Get(nethttp.HeaderAWSSessionToken)), func(s *v4.Signer) {
+		// S3 does not normalize the path of the canonical request.
+		s.DisableURIPathEscaping = service == defaultAWSService
+	})
+
+	_, err := signer.Sign(req, nil, service, region, signTime)
+	return err
+}
+
+// awsRegionFromHost derives the region from the host of aws, e.g. s3.us-west-2.amazonaws.com,
+// bucket.s3.us-west-2.amazonaws.com, bucket.s3-us-west-2.amazonaws.com and s3.dualstack.us-west-2.amazonaws.com.
+func awsRegionFromHost(host string) string {
+	for _, suffix := range []string{".amazonaws.com", ".amazonaws.com.cn"} {
+		if !strings.HasSuffix(host, suffix) {
+			continue
+		}
+
+		labels := strings.Split(strings.TrimSuffix(host, suffix), ".")
+		for i := len(labels) - 1; i >= 0; i-- {
+			if region, ok := strings.CutPrefix(labels[i], "s3-"); ok {
+				return region
+			}
+
+			if labels[i] != "s3" {
+				continue
+			}
+
+			rest := labels[i+1:]
+			if len(rest) > 0 && rest[0] == "dualstack" {
+				rest = rest[1:]
+			}
+
+			if len(rest) > 0 {
+				return rest[0]
+			}
+
+			return defaultAWSRegion
+		}
+	}
+
+	return defaultAWSRegion
+}
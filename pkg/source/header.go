@@ -17,8 +17,13 @@
 package source
 
 import (
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
 	"net/http"
 	"net/textproto"
+
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 )
 
 const (
@@ -100,6 +105,22 @@ func (h Header) Del(key string) {
 	textproto.MIMEHeader(h).Del(key)
 }
 
+// Redact returns a copy of h without the sensitive headers, or nil if h is nil.
+func (h Header) Redact() Header {
+	if h == nil {
+		return nil
+	}
+
+	h2 := h.Clone()
+	for key := range h2 {
+		if nethttp.IsSensitiveHeader(key) {
+			delete(h2, key)
+		}
+	}
+
+	return h2
+}
+
 // Clone returns a copy of h or nil if h is nil.
 func (h Header) Clone() Header {
 	if h == nil {
@@ -122,3 +143,35 @@ func (h Header) Clone() Header {
 }
 
 func CanonicalHeaderKey(s string) string { return textproto.CanonicalMIMEHeaderKey(s) }
+
+// SetClientCertificate sets the PEM encoded client certificate and private key to the header.
+func SetClientCertificate(header map[string]string, certPEM, keyPEM []byte) {
+	header[nethttp.HeaderClientCertificate] = base64.StdEncoding.EncodeToString(certPEM)
+	header[nethttp.HeaderClientKey] = base64.StdEncoding.EncodeToString(keyPEM)
+}
+
+// ClientCertificateFromHeader parses the client certificate in the header,
+// it returns nil if there is no client certificate.
+func ClientCertificateFromHeader(header Header) (*tls.Certificate, error) {
+	rawCert, rawKey := header.Get(nethttp.HeaderClientCertificate), header.Get(nethttp.HeaderClientKey)
+	if rawCert == "" && rawKey == "" {
+		return nil, nil
+	}
+
+	certPEM, err := base64.StdEncoding.DecodeString(rawCert)
+	if err != nil {
+		return nil, fmt.Errorf("decode client certificate: %w", err)
+	}
+
+	keyPEM, err := base64.StdEncoding.DecodeString(rawKey)
+	if err != nil {
+		return nil, fmt.Errorf("decode client key: %w", err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("load client certificate: %w", err)
+	}
+
+	return &cert, nil
+}
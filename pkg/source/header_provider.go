@@ -0,0 +1,114 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/internal/dfplugin"
+)
+
+// HeaderProvider refreshes the header of back-to-source requests, such as the signed
+// credentials or cookies which expire during downloading large files.
+type HeaderProvider interface {
+	// RefreshHeader returns the new header of the url after the source rejected the header,
+	// the returned header is merged into the header of the following requests.
+	RefreshHeader(ctx context.Context, url string, header map[string]string) (map[string]string, error)
+}
+
+// HeaderProviderFunc is an adapter to allow the use of ordinary functions as HeaderProvider.
+type HeaderProviderFunc func(ctx context.Context, url string, header map[string]string) (map[string]string, error)
+
+// RefreshHeader calls f(ctx, url, header).
+func (f HeaderProviderFunc) RefreshHeader(ctx context.Context, url string, header map[string]string) (map[string]string, error) {
+	return f(ctx, url, header)
+}
+
+var (
+	headerProvidersMu sync.RWMutex
+	headerProviders   = map[string]HeaderProvider{}
+)
+
+// RegisterHeaderProvider registers the header provider with the name, the plugins or
+// the internal refreshers register themselves and the daemon selects them by name.
+func RegisterHeaderProvider(name string, provider HeaderProvider) error {
+	headerProvidersMu.Lock()
+	defer headerProvidersMu.Unlock()
+
+	if _, ok := headerProviders[name]; ok {
+		return fmt.Errorf("header provider %s already exist", name)
+	}
+
+	headerProviders[name] = provider
+	return nil
+}
+
+// UnRegisterHeaderProvider revokes the header provider with the name.
+func UnRegisterHeaderProvider(name string) {
+	headerProvidersMu.Lock()
+	defer headerProvidersMu.Unlock()
+
+	delete(headerProviders, name)
+}
+
+// LoadHeaderProvider returns the header provider with the name, the header provider which is not
+// registered is loaded from the plugin d7y-header-plugin-{name}.so in the plugin dir and registered.
+func LoadHeaderProvider(name string) (HeaderProvider, bool) {
+	headerProvidersMu.RLock()
+	provider, ok := headerProviders[name]
+	headerProvidersMu.RUnlock()
+	if ok {
+		return provider, true
+	}
+
+	headerProvidersMu.Lock()
+	defer headerProvidersMu.Unlock()
+
+	if provider, ok := headerProviders[name]; ok {
+		return provider, true
+	}
+
+	provider, err := LoadHeaderProviderPlugin(_defaultManager.(*clientManager).pluginDir, name)
+	if err != nil {
+		logger.Debugf("load header provider plugin %s failed: %s", name, err)
+		return nil, false
+	}
+
+	headerProviders[name] = provider
+	return provider, true
+}
+
+// LoadHeaderProviderPlugin loads the header provider from the plugin d7y-header-plugin-{name}.so in dir.
+func LoadHeaderProviderPlugin(dir, name string) (HeaderProvider, error) {
+	logger.Debugf("try to load header provider plugin: %s", name)
+	plugin, _, err := dfplugin.Load(dir, dfplugin.PluginTypeHeader, name, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+
+	provider, ok := plugin.(HeaderProvider)
+	if !ok {
+		return nil, errors.New("invalid header provider, not a HeaderProvider")
+	}
+
+	logger.Debugf("loaded header provider plugin %s", name)
+	return provider, nil
+}
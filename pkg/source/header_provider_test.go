@@ -0,0 +1,51 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeaderProvider(t *testing.T) {
+	assert := assert.New(t)
+	provider := HeaderProviderFunc(func(ctx context.Context, url string, header map[string]string) (map[string]string, error) {
+		return map[string]string{"Cookie": "session=" + url}, nil
+	})
+
+	assert.NoError(RegisterHeaderProvider("foo", provider))
+	defer UnRegisterHeaderProvider("foo")
+	assert.EqualError(RegisterHeaderProvider("foo", provider), "header provider foo already exist")
+
+	p, ok := LoadHeaderProvider("foo")
+	assert.True(ok)
+	header, err := p.RefreshHeader(context.Background(), "bar", nil)
+	assert.NoError(err)
+	assert.Equal(map[string]string{"Cookie": "session=bar"}, header)
+
+	UnRegisterHeaderProvider("foo")
+	_, ok = LoadHeaderProvider("foo")
+	assert.False(ok)
+}
+
+func TestLoadHeaderProviderPlugin(t *testing.T) {
+	assert := assert.New(t)
+	_, err := LoadHeaderProviderPlugin(t.TempDir(), "foo")
+	assert.Error(err)
+}
@@ -21,6 +21,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 )
 
 func TestHeader_get(t *testing.T) {
@@ -122,3 +124,31 @@ func TestHeader_Clone(t *testing.T) {
 		})
 	}
 }
+
+func TestHeader_Redact(t *testing.T) {
+	assert := assert.New(t)
+	h := Header{}
+	h.Set("aaa", "ddd")
+	h.Set(nethttp.HeaderClientCertificate, "foo")
+	h.Set(nethttp.HeaderClientKey, "bar")
+
+	redacted := h.Redact()
+	assert.Equal("ddd", redacted.Get("aaa"))
+	assert.False(redacted.has(nethttp.HeaderClientCertificate))
+	assert.False(redacted.has(nethttp.HeaderClientKey))
+	assert.Equal("foo", h.Get(nethttp.HeaderClientCertificate))
+	assert.Nil(Header(nil).Redact())
+}
+
+func TestClientCertificateFromHeader(t *testing.T) {
+	assert := assert.New(t)
+	cert, err := ClientCertificateFromHeader(Header{})
+	assert.NoError(err)
+	assert.Nil(cert)
+
+	h := Header{}
+	h.Set(nethttp.HeaderClientCertificate, "foo")
+	h.Set(nethttp.HeaderClientKey, "bar")
+	_, err = ClientCertificateFromHeader(h)
+	assert.Error(err)
+}
@@ -29,6 +29,7 @@ func Test_loadPlugin(t *testing.T) {
 	assert := testifyassert.New(t)
 	defer func() {
 		os.Remove("./testdata/d7y-resource-plugin-dfs.so")
+		os.Remove("./testdata/d7y-header-plugin-dfs.so")
 		os.Remove("./testdata/test")
 	}()
 
@@ -51,6 +52,14 @@ func Test_loadPlugin(t *testing.T) {
 		return
 	}
 
+	cmd = exec.Command("go", "build", "-buildmode=plugin", "-o=./testdata/d7y-header-plugin-dfs.so", "testdata/plugin/header.go")
+	output, err = cmd.CombinedOutput()
+	assert.Nil(err)
+	if err != nil {
+		t.Fatalf(string(output))
+		return
+	}
+
 	// build test binary
 	cmd = exec.Command("go", "build", "-o=./testdata/test", "testdata/main.go")
 	output, err = cmd.CombinedOutput()
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -61,4 +62,21 @@ func main() {
 		fmt.Printf("close error: %s\n", err)
 		os.Exit(1)
 	}
+
+	provider, err := source.LoadHeaderProviderPlugin("./testdata", "dfs")
+	if err != nil {
+		fmt.Printf("load header provider plugin error: %s\n", err)
+		os.Exit(1)
+	}
+
+	header, err := provider.RefreshHeader(context.Background(), "foo", nil)
+	if err != nil {
+		fmt.Printf("refresh header error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if header["Cookie"] != "session=foo" {
+		fmt.Printf("refreshed header mismatch\n")
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,48 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+
+	"d7y.io/dragonfly/v2/pkg/source"
+)
+
+var (
+	buildCommit = "unknown"
+	buildTime   = "unknown"
+	vendor      = "d7y"
+)
+
+var _ source.HeaderProvider = (*provider)(nil)
+
+type provider struct {
+}
+
+func (p *provider) RefreshHeader(ctx context.Context, url string, header map[string]string) (map[string]string, error) {
+	return map[string]string{"Cookie": "session=" + url}, nil
+}
+
+func DragonflyPluginInit(option map[string]string) (any, map[string]string, error) {
+	return &provider{}, map[string]string{
+		"type":        "header",
+		"name":        "dfs",
+		"buildCommit": buildCommit,
+		"buildTime":   buildTime,
+		"vendor":      vendor,
+	}, nil
+}
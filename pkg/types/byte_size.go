@@ -0,0 +1,25 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"d7y.io/dragonfly/v2/pkg/unit"
+)
+
+// ByteSize is the byte size, it accepts the iec form 100MiB, the si form 100MB
+// and the legacy form 100M, the units are powers of 1024 whatever the form is.
+type ByteSize = unit.Bytes
@@ -1,5 +1,5 @@
 /*
- *     Copyright 2020 The Dragonfly Authors
+ *     Copyright 2024 The Dragonfly Authors
  *
  * Licensed under the Apache License, Version 2.0 (the "License");
  * you may not use this file except in compliance with the License.
@@ -14,62 +14,24 @@
  * limitations under the License.
  */
 
-package util
+package types
 
 import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
-	"github.com/docker/go-units"
-	"golang.org/x/time/rate"
 	"gopkg.in/yaml.v3"
 )
 
-// RateLimit is a wrapper for rate.Limit, support json and yaml unmarshal function
+// Duration is a wrapper for time.Duration, it accepts the go duration string
+// and the integer of nanoseconds in yaml, json and command flags.
 // yaml example 1:
-// rate_limit: 2097152 # 2MiB
+// timeout: 30s
 // yaml example 2:
-// rate_limit: 2MiB
-type RateLimit struct {
-	rate.Limit
-}
-
-type Size = RateLimit
-
-func (r *RateLimit) UnmarshalJSON(b []byte) error {
-	return r.unmarshal(json.Unmarshal, b)
-}
-
-func (r *RateLimit) UnmarshalYAML(node *yaml.Node) error {
-	return r.unmarshal(yaml.Unmarshal, []byte(node.Value))
-}
-
-func (r *RateLimit) unmarshal(unmarshal func(in []byte, out any) (err error), b []byte) error {
-	var v any
-	if err := unmarshal(b, &v); err != nil {
-		return err
-	}
-	switch value := v.(type) {
-	case float64:
-		r.Limit = rate.Limit(value)
-		return nil
-	case int:
-		r.Limit = rate.Limit(value)
-		return nil
-	case string:
-		limit, err := units.RAMInBytes(value)
-		if err != nil {
-			return fmt.Errorf("invalid rate limit: %w", err)
-		}
-		r.Limit = rate.Limit(limit)
-		return nil
-	default:
-		return errors.New("invalid rate limit")
-	}
-}
-
+// timeout: 30000000000 # 30s
 type Duration struct {
 	time.Duration
 }
@@ -127,3 +89,27 @@ func (d *Duration) unmarshal(v any) error {
 		return errors.New("invalid duration")
 	}
 }
+
+// Set implements the pflag.Value interface, the syntax is the same as yaml.
+func (d *Duration) Set(s string) error {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		d.Duration = time.Duration(i)
+		return nil
+	}
+
+	return d.unmarshal(s)
+}
+
+// Type implements the pflag.Value interface.
+func (d *Duration) Type() string {
+	return "duration"
+}
+
+// Validate returns error if the duration is not between min and max.
+func (d *Duration) Validate(min, max time.Duration) error {
+	if d.Duration < min || d.Duration > max {
+		return fmt.Errorf("duration %s must be between %s and %s", d.Duration, min, max)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDuration_Set(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		expect func(t *testing.T, d Duration, err error)
+	}{
+		{
+			name:   "set go duration string",
+			values: []string{"30s", "30000ms", "0.5m"},
+			expect: func(t *testing.T, d Duration, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(30*time.Second, d.Duration)
+			},
+		},
+		{
+			name:   "set integer of nanoseconds",
+			values: []string{"30000000000"},
+			expect: func(t *testing.T, d Duration, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(30*time.Second, d.Duration)
+			},
+		},
+		{
+			name:   "set zero",
+			values: []string{"0", "0s"},
+			expect: func(t *testing.T, d Duration, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(time.Duration(0), d.Duration)
+			},
+		},
+		{
+			name:   "set invalid duration",
+			values: []string{"30x", "s", ""},
+			expect: func(t *testing.T, d Duration, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, value := range tc.values {
+				var d Duration
+				err := d.Set(value)
+				tc.expect(t, d, err)
+			}
+		})
+	}
+}
+
+func TestDuration_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		json   string
+		expect time.Duration
+	}{
+		{
+			name:   "unmarshal integer of nanoseconds",
+			yaml:   "30000000000",
+			json:   "30000000000",
+			expect: 30 * time.Second,
+		},
+		{
+			name:   "unmarshal go duration string",
+			yaml:   "30s",
+			json:   `"30s"`,
+			expect: 30 * time.Second,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var yamlDuration Duration
+			assert.NoError(yaml.Unmarshal([]byte(tc.yaml), &yamlDuration))
+			assert.Equal(tc.expect, yamlDuration.Duration)
+
+			var jsonDuration Duration
+			assert.NoError(json.Unmarshal([]byte(tc.json), &jsonDuration))
+			assert.Equal(tc.expect, jsonDuration.Duration)
+		})
+	}
+}
+
+func TestDuration_Validate(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError((&Duration{Duration: time.Minute}).Validate(time.Second, time.Hour))
+	assert.EqualError((&Duration{Duration: time.Millisecond}).Validate(time.Second, time.Hour), "duration 1ms must be between 1s and 1h0m0s")
+	assert.EqualError((&Duration{Duration: 2 * time.Hour}).Validate(time.Second, time.Hour), "duration 2h0m0s must be between 1s and 1h0m0s")
+}
@@ -0,0 +1,111 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+
+	"d7y.io/dragonfly/v2/pkg/unit"
+)
+
+// rateLimitInf is the string of the infinite rate limit.
+const rateLimitInf = "inf"
+
+// RateLimit is a wrapper for rate.Limit, the limit is the byte size per second
+// with the syntax of ByteSize, support json and yaml unmarshal function.
+// yaml example 1:
+// rate_limit: 2097152 # 2MiB
+// yaml example 2:
+// rate_limit: 2MiB
+// yaml example 3:
+// rate_limit: inf
+type RateLimit struct {
+	rate.Limit
+}
+
+func (r *RateLimit) UnmarshalJSON(b []byte) error {
+	return r.unmarshal(json.Unmarshal, b)
+}
+
+func (r *RateLimit) UnmarshalYAML(node *yaml.Node) error {
+	return r.unmarshal(yaml.Unmarshal, []byte(node.Value))
+}
+
+func (r *RateLimit) unmarshal(unmarshal func(in []byte, out any) (err error), b []byte) error {
+	var v any
+	if err := unmarshal(b, &v); err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case float64:
+		r.Limit = rate.Limit(value)
+		return nil
+	case int:
+		r.Limit = rate.Limit(value)
+		return nil
+	case string:
+		return r.Set(value)
+	default:
+		return errors.New("invalid rate limit")
+	}
+}
+
+// Set implements the pflag.Value interface, the syntax is the same as yaml.
+func (r *RateLimit) Set(s string) error {
+	if strings.EqualFold(s, rateLimitInf) {
+		r.Limit = rate.Inf
+		return nil
+	}
+
+	limit, err := unit.ParseBytes(s)
+	if err != nil {
+		return fmt.Errorf("invalid rate limit: %w", err)
+	}
+
+	r.Limit = rate.Limit(limit)
+	return nil
+}
+
+// String implements the pflag.Value interface.
+func (r *RateLimit) String() string {
+	if r.Limit == rate.Inf || r.Limit >= math.MaxInt64 {
+		return rateLimitInf
+	}
+
+	return unit.Bytes(r.Limit).String()
+}
+
+// Type implements the pflag.Value interface.
+func (r *RateLimit) Type() string {
+	return "ratelimit"
+}
+
+// Validate returns error if the rate limit is not between min and max.
+func (r *RateLimit) Validate(min, max ByteSize) error {
+	if r.Limit < rate.Limit(min) || r.Limit > rate.Limit(max) {
+		return fmt.Errorf("rate limit %s must be between %s and %s", r, min, max)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+	"gopkg.in/yaml.v3"
+
+	"d7y.io/dragonfly/v2/pkg/unit"
+)
+
+func TestRateLimit_Set(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		expect func(t *testing.T, limit RateLimit, err error)
+	}{
+		{
+			name:   "set rate limit in previously accepted forms",
+			values: []string{"20M", "20MB", "20m", "20mb", "20 M", "20Mi", "20MiB", "20971520", "20.0M"},
+			expect: func(t *testing.T, limit RateLimit, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(rate.Limit(20*unit.MB), limit.Limit)
+			},
+		},
+		{
+			name:   "set rate limit with fraction",
+			values: []string{".5G", "0.5G", "512M"},
+			expect: func(t *testing.T, limit RateLimit, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(rate.Limit(512*unit.MB), limit.Limit)
+			},
+		},
+		{
+			name:   "set rate limit with exponent",
+			values: []string{"1e6", "1E6", "1000000"},
+			expect: func(t *testing.T, limit RateLimit, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(rate.Limit(1000000), limit.Limit)
+			},
+		},
+		{
+			name:   "set infinite rate limit",
+			values: []string{"inf", "Inf", "INF"},
+			expect: func(t *testing.T, limit RateLimit, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(rate.Inf, limit.Limit)
+			},
+		},
+		{
+			name:   "set rate limit with leading zeros",
+			values: []string{"020M", "010"},
+			expect: func(t *testing.T, limit RateLimit, err error) {
+				assert := assert.New(t)
+				assert.ErrorContains(err, "leading zeros are ambiguous")
+			},
+		},
+		{
+			name:   "set invalid rate limit",
+			values: []string{"20X", "-1M", "infinite"},
+			expect: func(t *testing.T, limit RateLimit, err error) {
+				assert := assert.New(t)
+				assert.ErrorContains(err, "invalid rate limit")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, value := range tc.values {
+				var limit RateLimit
+				err := limit.Set(value)
+				tc.expect(t, limit, err)
+			}
+		})
+	}
+}
+
+func TestRateLimit_Unmarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		yaml   string
+		json   string
+		expect rate.Limit
+	}{
+		{
+			name:   "unmarshal integer",
+			yaml:   "2097152",
+			json:   "2097152",
+			expect: rate.Limit(2 * unit.MB),
+		},
+		{
+			name:   "unmarshal string",
+			yaml:   "2MiB",
+			json:   `"2MiB"`,
+			expect: rate.Limit(2 * unit.MB),
+		},
+		{
+			name:   "unmarshal legacy string",
+			yaml:   "2M",
+			json:   `"2M"`,
+			expect: rate.Limit(2 * unit.MB),
+		},
+		{
+			name:   "unmarshal infinite",
+			yaml:   "inf",
+			json:   `"inf"`,
+			expect: rate.Inf,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			var yamlLimit RateLimit
+			assert.NoError(yaml.Unmarshal([]byte(tc.yaml), &yamlLimit))
+			assert.Equal(tc.expect, yamlLimit.Limit)
+
+			var jsonLimit RateLimit
+			assert.NoError(json.Unmarshal([]byte(tc.json), &jsonLimit))
+			assert.Equal(tc.expect, jsonLimit.Limit)
+		})
+	}
+}
+
+func TestRateLimit_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  rate.Limit
+		expect string
+	}{
+		{
+			name:   "byte size",
+			limit:  rate.Limit(20 * unit.MB),
+			expect: "20.0MB",
+		},
+		{
+			name:   "zero",
+			limit:  0,
+			expect: "0.0B",
+		},
+		{
+			name:   "infinite",
+			limit:  rate.Inf,
+			expect: "inf",
+		},
+		{
+			name:   "out of byte size range",
+			limit:  rate.Limit(math.MaxFloat64),
+			expect: "inf",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			limit := RateLimit{Limit: tc.limit}
+			assert.Equal(tc.expect, limit.String())
+			assert.Equal("ratelimit", limit.Type())
+
+			var parsed RateLimit
+			assert.NoError(parsed.Set(limit.String()))
+			assert.Equal(limit.String(), parsed.String())
+		})
+	}
+}
+
+func TestRateLimit_Validate(t *testing.T) {
+	assert := assert.New(t)
+	assert.NoError((&RateLimit{Limit: rate.Limit(10 * unit.MB)}).Validate(unit.MB, unit.GB))
+	assert.EqualError((&RateLimit{Limit: rate.Limit(unit.KB)}).Validate(unit.MB, unit.GB), "rate limit 1.0KB must be between 1.0MB and 1.0GB")
+	assert.EqualError((&RateLimit{Limit: rate.Inf}).Validate(unit.MB, unit.GB), "rate limit inf must be between 1.0MB and 1.0GB")
+}
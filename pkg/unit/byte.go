@@ -20,8 +20,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
@@ -59,6 +61,21 @@ func (f *Bytes) Set(s string) (err error) {
 	return
 }
 
+// ParseBytes parses the byte size, the units are powers of 1024 whatever the form is,
+// e.g. 100MiB, 100Mi, 100MB, 100M, 1.5G, .5G, 100 M, 1e6 and 1024.
+func ParseBytes(s string) (Bytes, error) {
+	return parseSize(s)
+}
+
+// Validate returns error if the byte size is not between min and max.
+func (f Bytes) Validate(min, max Bytes) error {
+	if f < min || f > max {
+		return fmt.Errorf("byte size %s must be between %s and %s", f, min, max)
+	}
+
+	return nil
+}
+
 func (f Bytes) Type() string {
 	return "bytes"
 }
@@ -92,7 +109,12 @@ func (f Bytes) String() string {
 	return fmt.Sprintf("%.1f%s", float64(f)/float64(unit), symbol)
 }
 
-var sizeRegexp = regexp.MustCompile(`^([0-9]+)(\.0*)?([MmKkGgTtPpEe])?[iI]?[bB]?$`)
+// sizeRegexp matches the number with the optional fraction and exponent, e.g. 1.5, .5 and 1e6,
+// followed by the optional unit, e.g. M, Mi, MB and MiB.
+var sizeRegexp = regexp.MustCompile(`^([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)? ?([MmKkGgTtPpEe])?[iI]?[bB]?$`)
+
+// leadingZeroRegexp matches the integer with leading zeros, e.g. 010.
+var leadingZeroRegexp = regexp.MustCompile(`^0[0-9]`)
 
 func parseSize(fsize string) (Bytes, error) {
 	if pkgstrings.IsBlank(fsize) {
@@ -104,6 +126,12 @@ func parseSize(fsize string) (Bytes, error) {
 		return 0, fmt.Errorf("parse size %s: invalid format", fsize)
 	}
 
+	// The leading zeros meant octal for byte sizes and decimal for rate limits,
+	// reject them instead of guessing.
+	if leadingZeroRegexp.MatchString(matches[1]) {
+		return 0, fmt.Errorf("parse size %s: leading zeros are ambiguous, they were parsed as octal for byte sizes and as decimal for rate limits, remove the leading zeros", fsize)
+	}
+
 	var unit Bytes
 	switch matches[3] {
 	case "k", "K":
@@ -122,11 +150,30 @@ func parseSize(fsize string) (Bytes, error) {
 		unit = B
 	}
 
-	num, err := strconv.ParseInt(matches[1], 0, 64)
+	// The number with fraction or exponent is truncated to bytes, e.g. .5G and 1e6.
+	if strings.Contains(matches[1], ".") || matches[2] != "" {
+		num, err := strconv.ParseFloat(matches[1]+matches[2], 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse size %s: %w", fsize, err)
+		}
+
+		size := num * float64(unit)
+		if size >= math.MaxInt64 {
+			return 0, fmt.Errorf("parse size %s: out of range", fsize)
+		}
+
+		return Bytes(size), nil
+	}
+
+	num, err := strconv.ParseInt(matches[1], 10, 64)
 	if err != nil {
 		return 0, fmt.Errorf("failed to parse size %s: %w", fsize, err)
 	}
 
+	if num > int64(math.MaxInt64/unit) {
+		return 0, fmt.Errorf("parse size %s: out of range", fsize)
+	}
+
 	return ToBytes(num) * unit, nil
 }
 
@@ -294,3 +294,107 @@ func Test_String(t *testing.T) {
 		assert.Equal(tc.b.String(), tc.data)
 	}
 }
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []string
+		expect func(t *testing.T, b Bytes, err error)
+	}{
+		{
+			name: "parse iec, si and legacy forms",
+			data: []string{"100MiB", "100Mi", "100MB", "100M", "100mb", "100m", "100 M", "100.0M", "104857600"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+				assert.Equal(100*MB, b)
+			},
+		},
+		{
+			name: "parse fraction",
+			data: []string{"1.5G", "1.5GiB", "1536M"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+				assert.Equal(GB+512*MB, b)
+			},
+		},
+		{
+			name: "parse fraction without integer",
+			data: []string{".5G", ".5GiB", "0.5G", "512M"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+				assert.Equal(512*MB, b)
+			},
+		},
+		{
+			name: "parse exponent",
+			data: []string{"1e6", "1E6", "1e+6", "1000000", "1e6B"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+				assert.Equal(Bytes(1000000), b)
+			},
+		},
+		{
+			name: "parse exabyte instead of exponent",
+			data: []string{"1E", "1e", "1EB", "1EiB"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+				assert.Equal(EB, b)
+			},
+		},
+		{
+			name: "parse zero",
+			data: []string{"0", "0B", "0M", "0.0G"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+				assert.Equal(Bytes(0), b)
+			},
+		},
+		{
+			name: "parse leading zeros",
+			data: []string{"010", "010M", "00", "01.5G"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.ErrorContains(err, "leading zeros are ambiguous")
+			},
+		},
+		{
+			name: "parse out of range size",
+			data: []string{"8E", "9223372036854775807K", "8.0E", "1e19"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.ErrorContains(err, "out of range")
+			},
+		},
+		{
+			name: "parse invalid size",
+			data: []string{"-1M", "1.5.5M", "1MM", "M"},
+			expect: func(t *testing.T, b Bytes, err error) {
+				assert := testifyassert.New(t)
+				assert.ErrorContains(err, "invalid format")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, data := range tc.data {
+				b, err := ParseBytes(data)
+				tc.expect(t, b, err)
+			}
+		})
+	}
+}
+
+func TestBytes_Validate(t *testing.T) {
+	assert := testifyassert.New(t)
+	assert.NoError((10 * MB).Validate(MB, GB))
+	assert.NoError(MB.Validate(MB, GB))
+	assert.EqualError((2*GB).Validate(MB, GB), "byte size 2.0GB must be between 1.0MB and 1.0GB")
+	assert.EqualError(KB.Validate(MB, GB), "byte size 1.0KB must be between 1.0MB and 1.0GB")
+}
@@ -22,12 +22,18 @@ import (
 	"net"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"d7y.io/dragonfly/v2/cmd/dependency/base"
+	"d7y.io/dragonfly/v2/internal/util"
 	"d7y.io/dragonfly/v2/pkg/net/fqdn"
 	"d7y.io/dragonfly/v2/pkg/net/ip"
+	"d7y.io/dragonfly/v2/pkg/redact"
 	"d7y.io/dragonfly/v2/pkg/rpc"
+	"d7y.io/dragonfly/v2/pkg/secret"
 	"d7y.io/dragonfly/v2/pkg/slices"
 	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/pkg/unit"
 )
 
 type Config struct {
@@ -67,11 +73,17 @@ type Config struct {
 	// Metrics configuration.
 	Metrics MetricsConfig `yaml:"metrics" mapstructure:"metrics"`
 
+	// Debug configuration.
+	Debug DebugConfig `yaml:"debug" mapstructure:"debug"`
+
 	// Security configuration.
 	Security SecurityConfig `yaml:"security" mapstructure:"security"`
 
 	// Network configuration.
 	Network NetworkConfig `yaml:"network" mapstructure:"network"`
+
+	// Redact configuration.
+	Redact RedactConfig `yaml:"redact" mapstructure:"redact"`
 }
 
 type ServerConfig struct {
@@ -113,6 +125,60 @@ type ServerConfig struct {
 
 	// Server storage data directory.
 	DataDir string `yaml:"dataDir" mapstructure:"dataDir"`
+
+	// Overload is the overload protection configuration of grpc server.
+	Overload OverloadConfig `yaml:"overload" mapstructure:"overload"`
+
+	// PeerRateLimit is the rate limit configuration of the peer registration of grpc server.
+	PeerRateLimit PeerRateLimitConfig `yaml:"peerRateLimit" mapstructure:"peerRateLimit"`
+}
+
+type OverloadConfig struct {
+	// Enable is to enable the overload protection of grpc server.
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+
+	// Limit is the default concurrent request limit of every unary grpc method, zero means no limit.
+	Limit int `yaml:"limit" mapstructure:"limit"`
+
+	// MethodLimits is the concurrent request limits of the grpc methods, it overrides the limit.
+	// Streams are not limited.
+	MethodLimits []MethodLimitConfig `yaml:"methodLimits" mapstructure:"methodLimits"`
+
+	// QueueTimeout is the timeout of the request waiting for the concurrent request limit,
+	// the request is rejected with the retryable code after the timeout.
+	QueueTimeout time.Duration `yaml:"queueTimeout" mapstructure:"queueTimeout"`
+
+	// RetryAfter is the backoff hint of the rejected request in the response metadata.
+	RetryAfter time.Duration `yaml:"retryAfter" mapstructure:"retryAfter"`
+}
+
+type MethodLimitConfig struct {
+	// Method is the grpc full method, e.g. /scheduler.Scheduler/RegisterPeerTask.
+	Method string `yaml:"method" mapstructure:"method"`
+
+	// Limit is the concurrent request limit of the method, zero means no limit.
+	Limit int `yaml:"limit" mapstructure:"limit"`
+}
+
+type PeerRateLimitConfig struct {
+	// Enable is to enable the rate limit of the peer registration, the seed peers are exempted.
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+
+	// PeerQPS is the registration rate of every peer id.
+	PeerQPS float64 `yaml:"peerQPS" mapstructure:"peerQPS"`
+
+	// PeerBurst is the registration burst of every peer id.
+	PeerBurst int `yaml:"peerBurst" mapstructure:"peerBurst"`
+
+	// HostQPS is the registration rate of every source host.
+	HostQPS float64 `yaml:"hostQPS" mapstructure:"hostQPS"`
+
+	// HostBurst is the registration burst of every source host.
+	HostBurst int `yaml:"hostBurst" mapstructure:"hostBurst"`
+
+	// Size is the max count of the rate limiters kept for peers and for hosts respectively,
+	// the least recently used ones are evicted.
+	Size int `yaml:"size" mapstructure:"size"`
 }
 
 type SchedulerConfig struct {
@@ -122,6 +188,17 @@ type SchedulerConfig struct {
 	// BackToSourceCount is single task allows the peer to back-to-source count.
 	BackToSourceCount int `yaml:"backToSourceCount" mapstructure:"backToSourceCount"`
 
+	// BackToSourceRateLimit is the maximum number of peers in a single task allowed to
+	// back-to-source within BackToSourceRateInterval, zero means no limit.
+	BackToSourceRateLimit int `yaml:"backToSourceRateLimit" mapstructure:"backToSourceRateLimit"`
+
+	// BackToSourceRateInterval is the interval of BackToSourceRateLimit.
+	BackToSourceRateInterval types.Duration `yaml:"backToSourceRateInterval" mapstructure:"backToSourceRateInterval"`
+
+	// BackToSourceCooldown is the interval in which no new peer of a task is allowed to back-to-source
+	// after a peer of the task reports server error of the source, zero means no cooldown.
+	BackToSourceCooldown types.Duration `yaml:"backToSourceCooldown" mapstructure:"backToSourceCooldown"`
+
 	// RetryBackToSourceLimit reaches the limit, then the peer back-to-source.
 	RetryBackToSourceLimit int `yaml:"retryBackToSourceLimit" mapstructure:"retryBackToSourceLimit"`
 
@@ -129,13 +206,32 @@ type SchedulerConfig struct {
 	RetryLimit int `yaml:"retryLimit" mapstructure:"retryLimit"`
 
 	// RetryInterval is scheduling interval.
-	RetryInterval time.Duration `yaml:"retryInterval" mapstructure:"retryInterval"`
+	RetryInterval types.Duration `yaml:"retryInterval" mapstructure:"retryInterval"`
+
+	// PieceViolationLimit reaches the limit, then the peer reporting impossible
+	// piece results is quarantined and it will not be selected as a parent.
+	PieceViolationLimit int `yaml:"pieceViolationLimit" mapstructure:"pieceViolationLimit"`
+
+	// MaxPieceCost is the maximum cost of downloading a piece, the piece result
+	// with cost greater than it is rejected.
+	MaxPieceCost types.Duration `yaml:"maxPieceCost" mapstructure:"maxPieceCost"`
+
+	// TinyFileSizeLimit is the size limit of the tiny file, the content of
+	// the tiny file is returned to the peer in the register response.
+	TinyFileSizeLimit types.ByteSize `yaml:"tinyFileSizeLimit" mapstructure:"tinyFileSizeLimit"`
+
+	// SmallFileSizeLimit is the size limit of the small file, the small file
+	// has only one piece and it is downloaded from the parent directly.
+	SmallFileSizeLimit types.ByteSize `yaml:"smallFileSizeLimit" mapstructure:"smallFileSizeLimit"`
 
 	// GC configuration.
 	GC GCConfig `yaml:"gc" mapstructure:"gc"`
 
 	// NetworkTopology configuration.
 	NetworkTopology NetworkTopologyConfig `yaml:"networkTopology" mapstructure:"networkTopology"`
+
+	// Reschedule configuration.
+	Reschedule RescheduleConfig `yaml:"reschedule" mapstructure:"reschedule"`
 }
 
 type DatabaseConfig struct {
@@ -146,11 +242,60 @@ type DatabaseConfig struct {
 type ResourceConfig struct {
 	// Task resource configuration.
 	Task TaskConfig `yaml:"task" mapstructure:"task"`
+
+	// Peer resource configuration.
+	Peer PeerConfig `yaml:"peer" mapstructure:"peer"`
+}
+
+type PeerConfig struct {
+	// BlockParentTTL is the time to live of the blocked parent of the peer,
+	// the parent can be selected again after it expires. If the value is 0,
+	// the blocked parent never expires.
+	BlockParentTTL time.Duration `yaml:"blockParentTTL" mapstructure:"blockParentTTL"`
+
+	// ParentAffinityWindow is the window of the affinity of the peer to the parent hosts it downloaded
+	// pieces from successfully, the evaluator prefers these parent hosts with the bonus decaying over
+	// the window. If the value is 0, the affinity is disabled.
+	ParentAffinityWindow time.Duration `yaml:"parentAffinityWindow" mapstructure:"parentAffinityWindow"`
+
+	// ParentSwitchLimit is the maximum number of times the peer switches parents within ParentSwitchWindow,
+	// the peer exceeding it is pinned to the current parent for ParentPinCooldown unless the parent fails.
+	// If the value is 0, the peer is never pinned.
+	ParentSwitchLimit int `yaml:"parentSwitchLimit" mapstructure:"parentSwitchLimit"`
+
+	// ParentSwitchWindow is the window of counting the parent switches of the peer.
+	ParentSwitchWindow time.Duration `yaml:"parentSwitchWindow" mapstructure:"parentSwitchWindow"`
+
+	// ParentPinCooldown is the duration the peer is pinned to the current parent.
+	ParentPinCooldown time.Duration `yaml:"parentPinCooldown" mapstructure:"parentPinCooldown"`
 }
 
 type TaskConfig struct {
 	// Download tiny task configuration.
 	DownloadTiny DownloadTinyConfig `yaml:"downloadTiny" mapstructure:"downloadTiny"`
+
+	// Persistence is task metadata persistence configuration.
+	Persistence TaskPersistenceConfig `yaml:"persistence" mapstructure:"persistence"`
+}
+
+type TaskPersistenceConfig struct {
+	// Enable persisting the task metadata, the tasks are restored
+	// when the scheduler restarts.
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+
+	// Backend is the storage backend of the task snapshot, supports file and redis.
+	Backend string `yaml:"backend" mapstructure:"backend"`
+
+	// Path is the task snapshot file path of the file backend,
+	// the default path is in the data directory.
+	Path string `yaml:"path" mapstructure:"path"`
+
+	// Interval is the interval of taking the task snapshot.
+	Interval time.Duration `yaml:"interval" mapstructure:"interval"`
+
+	// TTL is time to live of the restored task without peers,
+	// then the task will be reclaimed by task gc.
+	TTL time.Duration `yaml:"ttl" mapstructure:"ttl"`
 }
 
 type DownloadTinyConfig struct {
@@ -174,6 +319,10 @@ type GCConfig struct {
 	// PieceDownloadTimeout is timeout of downloading piece.
 	PieceDownloadTimeout time.Duration `yaml:"pieceDownloadTimeout" mapstructure:"pieceDownloadTimeout"`
 
+	// TaskDownloadTimeout is timeout of downloading task by the peer, the peer and the task
+	// fail when the peer downloads no piece within it. Default is 0, which means no timeout.
+	TaskDownloadTimeout time.Duration `yaml:"taskDownloadTimeout" mapstructure:"taskDownloadTimeout"`
+
 	// PeerGCInterval is interval of peer gc.
 	PeerGCInterval time.Duration `yaml:"peerGCInterval" mapstructure:"peerGCInterval"`
 
@@ -181,6 +330,10 @@ type GCConfig struct {
 	// then PeerTTL will be reset.
 	PeerTTL time.Duration `yaml:"peerTTL" mapstructure:"peerTTL"`
 
+	// ApplicationPeerTTLs is time to live of peers by the application of the task,
+	// it overrides PeerTTL, e.g. the peers of ci caches are reclaimed sooner.
+	ApplicationPeerTTLs map[string]time.Duration `yaml:"applicationPeerTTLs" mapstructure:"applicationPeerTTLs"`
+
 	// TaskGCInterval is interval of task gc. If all the peers have been reclaimed in the task,
 	// then the task will also be reclaimed.
 	TaskGCInterval time.Duration `yaml:"taskGCInterval" mapstructure:"taskGCInterval"`
@@ -215,6 +368,10 @@ type ManagerConfig struct {
 
 	// KeepAlive configuration.
 	KeepAlive KeepAliveConfig `yaml:"keepAlive" mapstructure:"keepAlive"`
+
+	// GzipCompression is to compress the messages of the manager client with gzip,
+	// it reduces the bandwidth when the manager is in another region.
+	GzipCompression bool `yaml:"gzipCompression" mapstructure:"gzipCompression"`
 }
 
 type SeedPeerConfig struct {
@@ -223,6 +380,21 @@ type SeedPeerConfig struct {
 
 	// TaskDownloadTimeout is timeout of downloading task by seed peer.
 	TaskDownloadTimeout time.Duration `yaml:"taskDownloadTimeout" mapstructure:"taskDownloadTimeout"`
+
+	// AbortSeeding is the configuration of aborting the seeding when all interested peers leave.
+	AbortSeeding AbortSeedingConfig `yaml:"abortSeeding" mapstructure:"abortSeeding"`
+
+	// GzipCompression is to compress the messages of the seed peer client with gzip.
+	GzipCompression bool `yaml:"gzipCompression" mapstructure:"gzipCompression"`
+}
+
+type AbortSeedingConfig struct {
+	// Enable is to enable aborting the seeding when all interested peers leave.
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+
+	// Linger is the duration to wait after the last interested peer leaves before
+	// aborting the seeding, a quick re-request does not restart the seeding from scratch.
+	Linger time.Duration `yaml:"linger" mapstructure:"linger"`
 }
 
 type KeepAliveConfig struct {
@@ -257,6 +429,9 @@ type StorageConfig struct {
 	// BufferSize sets the size of buffer container,
 	// if the buffer is full, write all the records in the buffer to the file.
 	BufferSize int `yaml:"bufferSize" mapstructure:"bufferSize"`
+
+	// ListConcurrency sets the number of storage files read concurrently when listing records.
+	ListConcurrency int `yaml:"listConcurrency" mapstructure:"listConcurrency"`
 }
 
 type RedisConfig struct {
@@ -299,6 +474,18 @@ type MetricsConfig struct {
 	EnableHost bool `yaml:"enableHost" mapstructure:"enableHost"`
 }
 
+type DebugConfig struct {
+	// Enable debug service, it serves the state of peers and hosts, the dag of tasks, and evicts the peers on demand.
+	Enable bool `yaml:"enable" mapstructure:"enable"`
+
+	// Debug service address.
+	Addr string `yaml:"addr" mapstructure:"addr"`
+
+	// Token authorizes the requests, the read-only requests from localhost are allowed without it.
+	// Evicting the peers requires the token, so it is disabled if token is empty.
+	Token string `yaml:"token" mapstructure:"token"`
+}
+
 type SecurityConfig struct {
 	// AutoIssueCert indicates to issue client certificates for all grpc call
 	// if AutoIssueCert is false, any other option in Security will be ignored.
@@ -336,6 +523,15 @@ type NetworkConfig struct {
 	EnableIPv6 bool `mapstructure:"enableIPv6" yaml:"enableIPv6"`
 }
 
+type RedactConfig struct {
+	// QueryParams are the query params of the task url whose values are redacted in the logs
+	// and records, the signatures and tokens of the common object storages are redacted if it is empty.
+	QueryParams []string `mapstructure:"queryParams" yaml:"queryParams"`
+
+	// HashQuery replaces the entire query string of the task url with its sha256 in the logs and records.
+	HashQuery bool `mapstructure:"hashQuery" yaml:"hashQuery"`
+}
+
 type NetworkTopologyConfig struct {
 	// CollectInterval is the interval of collecting network topology.
 	CollectInterval time.Duration `mapstructure:"collectInterval" yaml:"collectInterval"`
@@ -347,6 +543,39 @@ type NetworkTopologyConfig struct {
 	Cache CacheConfig `yaml:"cache" mapstructure:"cache"`
 }
 
+type RescheduleConfig struct {
+	// Enable pushes better candidate parents to the running children proactively,
+	// used only in v2 version of the grpc.
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+
+	// Interval is the interval of rescheduling children.
+	Interval time.Duration `mapstructure:"interval" yaml:"interval"`
+
+	// ScoreMargin is the margin by which the score of the best candidate parent must exceed
+	// the score of the current parent, then the child is rescheduled.
+	ScoreMargin float64 `mapstructure:"scoreMargin" yaml:"scoreMargin"`
+
+	// BatchSize is the maximum number of children rescheduled in one interval.
+	BatchSize int `mapstructure:"batchSize" yaml:"batchSize"`
+
+	// SlowChild configuration.
+	SlowChild SlowChildConfig `mapstructure:"slowChild" yaml:"slowChild"`
+}
+
+type SlowChildConfig struct {
+	// Enable reschedules the slow children of the parents whose upload slots are exhausted,
+	// to free the upload slots for the faster children.
+	Enable bool `mapstructure:"enable" yaml:"enable"`
+
+	// ThroughputRatio is the ratio of the median throughput of the children of the parent,
+	// the child whose throughput in the interval of rescheduling is below it is slow.
+	ThroughputRatio float64 `mapstructure:"throughputRatio" yaml:"throughputRatio"`
+
+	// Windows is the number of consecutive intervals of rescheduling in which the child is slow,
+	// then the child is rescheduled and the parent is blocked by the child temporarily.
+	Windows int `mapstructure:"windows" yaml:"windows"`
+}
+
 type ProbeConfig struct {
 	// QueueLength is the length of probe queue.
 	QueueLength int `mapstructure:"queueLength" yaml:"queueLength"`
@@ -373,13 +602,31 @@ func New() *Config {
 			LogMaxSize:    DefaultLogRotateMaxSize,
 			LogMaxAge:     DefaultLogRotateMaxAge,
 			LogMaxBackups: DefaultLogRotateMaxBackups,
+			Overload: OverloadConfig{
+				Enable:       false,
+				QueueTimeout: DefaultServerOverloadQueueTimeout,
+				RetryAfter:   DefaultServerOverloadRetryAfter,
+			},
+			PeerRateLimit: PeerRateLimitConfig{
+				Enable:    false,
+				PeerQPS:   DefaultServerPeerRateLimitPeerQPS,
+				PeerBurst: DefaultServerPeerRateLimitPeerBurst,
+				HostQPS:   DefaultServerPeerRateLimitHostQPS,
+				HostBurst: DefaultServerPeerRateLimitHostBurst,
+				Size:      DefaultServerPeerRateLimitSize,
+			},
 		},
 		Scheduler: SchedulerConfig{
-			Algorithm:              DefaultSchedulerAlgorithm,
-			BackToSourceCount:      DefaultSchedulerBackToSourceCount,
-			RetryBackToSourceLimit: DefaultSchedulerRetryBackToSourceLimit,
-			RetryLimit:             DefaultSchedulerRetryLimit,
-			RetryInterval:          DefaultSchedulerRetryInterval,
+			Algorithm:                DefaultSchedulerAlgorithm,
+			BackToSourceCount:        DefaultSchedulerBackToSourceCount,
+			BackToSourceRateInterval: types.Duration{Duration: DefaultSchedulerBackToSourceRateInterval},
+			RetryBackToSourceLimit:   DefaultSchedulerRetryBackToSourceLimit,
+			RetryLimit:               DefaultSchedulerRetryLimit,
+			RetryInterval:            types.Duration{Duration: DefaultSchedulerRetryInterval},
+			PieceViolationLimit:      DefaultSchedulerPieceViolationLimit,
+			MaxPieceCost:             types.Duration{Duration: DefaultSchedulerMaxPieceCost},
+			TinyFileSizeLimit:        DefaultSchedulerTinyFileSizeLimit,
+			SmallFileSizeLimit:       DefaultSchedulerSmallFileSizeLimit,
 			GC: GCConfig{
 				PieceDownloadTimeout: DefaultSchedulerPieceDownloadTimeout,
 				PeerGCInterval:       DefaultSchedulerPeerGCInterval,
@@ -399,6 +646,17 @@ func New() *Config {
 					TTL:      DefaultSchedulerNetworkTopologyCacheTLL,
 				},
 			},
+			Reschedule: RescheduleConfig{
+				Enable:      false,
+				Interval:    DefaultSchedulerRescheduleInterval,
+				ScoreMargin: DefaultSchedulerRescheduleScoreMargin,
+				BatchSize:   DefaultSchedulerRescheduleBatchSize,
+				SlowChild: SlowChildConfig{
+					Enable:          false,
+					ThroughputRatio: DefaultSchedulerRescheduleSlowChildThroughputRatio,
+					Windows:         DefaultSchedulerRescheduleSlowChildWindows,
+				},
+			},
 		},
 		Database: DatabaseConfig{
 			Redis: RedisConfig{
@@ -416,6 +674,19 @@ func New() *Config {
 						InsecureSkipVerify: true,
 					},
 				},
+				Persistence: TaskPersistenceConfig{
+					Enable:   false,
+					Backend:  TaskPersistenceBackendFile,
+					Interval: DefaultResourceTaskPersistenceInterval,
+					TTL:      DefaultResourceTaskPersistenceTTL,
+				},
+			},
+			Peer: PeerConfig{
+				BlockParentTTL:       DefaultResourcePeerBlockParentTTL,
+				ParentAffinityWindow: DefaultResourcePeerParentAffinityWindow,
+				ParentSwitchLimit:    DefaultResourcePeerParentSwitchLimit,
+				ParentSwitchWindow:   DefaultResourcePeerParentSwitchWindow,
+				ParentPinCooldown:    DefaultResourcePeerParentPinCooldown,
 			},
 		},
 		DynConfig: DynConfig{
@@ -431,6 +702,10 @@ func New() *Config {
 		SeedPeer: SeedPeerConfig{
 			Enable:              true,
 			TaskDownloadTimeout: DefaultSeedPeerTaskDownloadTimeout,
+			AbortSeeding: AbortSeedingConfig{
+				Enable: false,
+				Linger: DefaultSeedPeerAbortSeedingLinger,
+			},
 		},
 		Job: JobConfig{
 			Enable:             true,
@@ -439,15 +714,20 @@ func New() *Config {
 			LocalWorkerNum:     DefaultJobLocalWorkerNum,
 		},
 		Storage: StorageConfig{
-			MaxSize:    DefaultStorageMaxSize,
-			MaxBackups: DefaultStorageMaxBackups,
-			BufferSize: DefaultStorageBufferSize,
+			MaxSize:         DefaultStorageMaxSize,
+			MaxBackups:      DefaultStorageMaxBackups,
+			BufferSize:      DefaultStorageBufferSize,
+			ListConcurrency: DefaultStorageListConcurrency,
 		},
 		Metrics: MetricsConfig{
 			Enable:     false,
 			Addr:       DefaultMetricsAddr,
 			EnableHost: false,
 		},
+		Debug: DebugConfig{
+			Enable: false,
+			Addr:   DefaultDebugAddr,
+		},
 		Security: SecurityConfig{
 			AutoIssueCert: false,
 			TLSVerify:     true,
@@ -486,6 +766,52 @@ func (cfg *Config) Validate() error {
 		return errors.New("server requires parameter host")
 	}
 
+	if cfg.Server.Overload.Enable {
+		if cfg.Server.Overload.Limit < 0 {
+			return errors.New("server requires parameter overload limit")
+		}
+
+		for _, methodLimit := range cfg.Server.Overload.MethodLimits {
+			if methodLimit.Method == "" {
+				return errors.New("server requires parameter overload methodLimits method")
+			}
+
+			if methodLimit.Limit < 0 {
+				return errors.New("server requires parameter overload methodLimits limit")
+			}
+		}
+
+		if cfg.Server.Overload.QueueTimeout < 0 {
+			return errors.New("server requires parameter overload queueTimeout")
+		}
+
+		if cfg.Server.Overload.RetryAfter <= 0 {
+			return errors.New("server requires parameter overload retryAfter")
+		}
+	}
+
+	if cfg.Server.PeerRateLimit.Enable {
+		if cfg.Server.PeerRateLimit.PeerQPS <= 0 {
+			return errors.New("server requires parameter peerRateLimit peerQPS")
+		}
+
+		if cfg.Server.PeerRateLimit.PeerBurst <= 0 {
+			return errors.New("server requires parameter peerRateLimit peerBurst")
+		}
+
+		if cfg.Server.PeerRateLimit.HostQPS <= 0 {
+			return errors.New("server requires parameter peerRateLimit hostQPS")
+		}
+
+		if cfg.Server.PeerRateLimit.HostBurst <= 0 {
+			return errors.New("server requires parameter peerRateLimit hostBurst")
+		}
+
+		if cfg.Server.PeerRateLimit.Size <= 0 {
+			return errors.New("server requires parameter peerRateLimit size")
+		}
+	}
+
 	if cfg.Scheduler.Algorithm == "" {
 		return errors.New("scheduler requires parameter algorithm")
 	}
@@ -494,6 +820,18 @@ func (cfg *Config) Validate() error {
 		return errors.New("scheduler requires parameter backToSourceCount")
 	}
 
+	if cfg.Scheduler.BackToSourceRateLimit < 0 {
+		return errors.New("scheduler backToSourceRateLimit must be greater than or equal to 0")
+	}
+
+	if cfg.Scheduler.BackToSourceRateLimit > 0 && cfg.Scheduler.BackToSourceRateInterval.Duration <= 0 {
+		return errors.New("scheduler requires parameter backToSourceRateInterval")
+	}
+
+	if cfg.Scheduler.BackToSourceCooldown.Duration < 0 {
+		return errors.New("scheduler backToSourceCooldown must be greater than or equal to 0")
+	}
+
 	if cfg.Scheduler.RetryBackToSourceLimit == 0 {
 		return errors.New("scheduler requires parameter retryBackToSourceLimit")
 	}
@@ -502,18 +840,52 @@ func (cfg *Config) Validate() error {
 		return errors.New("scheduler requires parameter retryLimit")
 	}
 
-	if cfg.Scheduler.RetryInterval <= 0 {
+	if cfg.Scheduler.RetryInterval.Duration <= 0 {
 		return errors.New("scheduler requires parameter retryInterval")
 	}
 
+	if cfg.Scheduler.PieceViolationLimit <= 0 {
+		return errors.New("scheduler requires parameter pieceViolationLimit")
+	}
+
+	if cfg.Scheduler.MaxPieceCost.Duration <= 0 {
+		return errors.New("scheduler requires parameter maxPieceCost")
+	}
+
+	if cfg.Scheduler.TinyFileSizeLimit <= 0 {
+		return errors.New("scheduler requires parameter tinyFileSizeLimit")
+	}
+
+	if cfg.Scheduler.TinyFileSizeLimit > MaxSchedulerTinyFileSizeLimit {
+		return fmt.Errorf("scheduler tinyFileSizeLimit must be less than or equal to %s", MaxSchedulerTinyFileSizeLimit)
+	}
+
+	if cfg.Scheduler.SmallFileSizeLimit <= cfg.Scheduler.TinyFileSizeLimit {
+		return errors.New("scheduler smallFileSizeLimit must be greater than tinyFileSizeLimit")
+	}
+
+	if cfg.Scheduler.SmallFileSizeLimit > util.DefaultPieceSizeLimit {
+		return fmt.Errorf("scheduler smallFileSizeLimit must be less than or equal to %s", unit.Bytes(util.DefaultPieceSizeLimit))
+	}
+
 	if cfg.Scheduler.GC.PieceDownloadTimeout <= 0 {
 		return errors.New("scheduler requires parameter pieceDownloadTimeout")
 	}
 
+	if cfg.Scheduler.GC.TaskDownloadTimeout < 0 {
+		return errors.New("scheduler requires parameter taskDownloadTimeout")
+	}
+
 	if cfg.Scheduler.GC.PeerTTL <= 0 {
 		return errors.New("scheduler requires parameter peerTTL")
 	}
 
+	for _, ttl := range cfg.Scheduler.GC.ApplicationPeerTTLs {
+		if ttl <= 0 {
+			return errors.New("scheduler requires parameter applicationPeerTTLs")
+		}
+	}
+
 	if cfg.Scheduler.GC.PeerGCInterval <= 0 {
 		return errors.New("scheduler requires parameter peerGCInterval")
 	}
@@ -550,6 +922,46 @@ func (cfg *Config) Validate() error {
 		return errors.New("downloadTiny requires parameter timeout")
 	}
 
+	if cfg.Resource.Task.Persistence.Enable {
+		if !slices.Contains([]string{TaskPersistenceBackendFile, TaskPersistenceBackendRedis}, cfg.Resource.Task.Persistence.Backend) {
+			return errors.New("persistence requires parameter backend")
+		}
+
+		if cfg.Resource.Task.Persistence.Backend == TaskPersistenceBackendRedis && len(cfg.Database.Redis.Addrs) == 0 {
+			return errors.New("persistence with redis backend requires parameter redis addrs")
+		}
+
+		if cfg.Resource.Task.Persistence.Interval <= 0 {
+			return errors.New("persistence requires parameter interval")
+		}
+
+		if cfg.Resource.Task.Persistence.TTL <= 0 {
+			return errors.New("persistence requires parameter ttl")
+		}
+	}
+
+	if cfg.Resource.Peer.BlockParentTTL < 0 {
+		return errors.New("peer blockParentTTL must be greater than or equal to 0")
+	}
+
+	if cfg.Resource.Peer.ParentAffinityWindow < 0 {
+		return errors.New("peer parentAffinityWindow must be greater than or equal to 0")
+	}
+
+	if cfg.Resource.Peer.ParentSwitchLimit < 0 {
+		return errors.New("peer parentSwitchLimit must be greater than or equal to 0")
+	}
+
+	if cfg.Resource.Peer.ParentSwitchLimit > 0 {
+		if cfg.Resource.Peer.ParentSwitchWindow <= 0 {
+			return errors.New("peer requires parameter parentSwitchWindow")
+		}
+
+		if cfg.Resource.Peer.ParentPinCooldown <= 0 {
+			return errors.New("peer requires parameter parentPinCooldown")
+		}
+	}
+
 	if cfg.DynConfig.RefreshInterval <= 0 {
 		return errors.New("dynconfig requires parameter refreshInterval")
 	}
@@ -570,6 +982,10 @@ func (cfg *Config) Validate() error {
 		return errors.New("seedPeer requires parameter taskDownloadTimeout")
 	}
 
+	if cfg.SeedPeer.AbortSeeding.Enable && cfg.SeedPeer.AbortSeeding.Linger <= 0 {
+		return errors.New("seedPeer requires parameter abortSeeding linger")
+	}
+
 	if cfg.Job.Enable {
 		if cfg.Job.GlobalWorkerNum == 0 {
 			return errors.New("job requires parameter globalWorkerNum")
@@ -596,12 +1012,22 @@ func (cfg *Config) Validate() error {
 		return errors.New("storage requires parameter bufferSize")
 	}
 
+	if cfg.Storage.ListConcurrency <= 0 {
+		return errors.New("storage requires parameter listConcurrency")
+	}
+
 	if cfg.Metrics.Enable {
 		if cfg.Metrics.Addr == "" {
 			return errors.New("metrics requires parameter addr")
 		}
 	}
 
+	if cfg.Debug.Enable {
+		if cfg.Debug.Addr == "" {
+			return errors.New("debug requires parameter addr")
+		}
+	}
+
 	if cfg.Security.AutoIssueCert {
 		if cfg.Security.CACert == "" {
 			return errors.New("security requires parameter caCert")
@@ -646,6 +1072,30 @@ func (cfg *Config) Validate() error {
 		}
 	}
 
+	if cfg.Scheduler.Reschedule.Enable {
+		if cfg.Scheduler.Reschedule.Interval <= 0 {
+			return errors.New("reschedule requires parameter interval")
+		}
+
+		if cfg.Scheduler.Reschedule.ScoreMargin < 0 {
+			return errors.New("reschedule requires parameter scoreMargin")
+		}
+
+		if cfg.Scheduler.Reschedule.BatchSize <= 0 {
+			return errors.New("reschedule requires parameter batchSize")
+		}
+
+		if cfg.Scheduler.Reschedule.SlowChild.Enable {
+			if cfg.Scheduler.Reschedule.SlowChild.ThroughputRatio <= 0 || cfg.Scheduler.Reschedule.SlowChild.ThroughputRatio >= 1 {
+				return errors.New("slowChild requires parameter throughputRatio")
+			}
+
+			if cfg.Scheduler.Reschedule.SlowChild.Windows <= 0 {
+				return errors.New("slowChild requires parameter windows")
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -707,3 +1157,37 @@ func (cfg *Config) Convert() error {
 
 	return nil
 }
+
+// ResolveSecrets resolves the ${ENV_VAR} and file:// references of the credential fields,
+// the other fields are not expanded to avoid surprising behavior in the addresses and urls.
+func (cfg *Config) ResolveSecrets() error {
+	return secret.ResolveFields(cfg.secretFields()...)
+}
+
+// Dump returns the yaml of the config with the credential fields redacted, it is safe to log.
+func (cfg *Config) Dump() (string, error) {
+	redacted := *cfg
+	for _, field := range redacted.secretFields() {
+		if *field.Value != "" {
+			*field.Value = redact.Redacted
+		}
+	}
+
+	b, err := yaml.Marshal(&redacted)
+	if err != nil {
+		return "", err
+	}
+
+	return string(b), nil
+}
+
+// secretFields returns the credential fields of the config.
+func (cfg *Config) secretFields() []secret.Field {
+	return []secret.Field{
+		{Name: "database.redis.username", Value: &cfg.Database.Redis.Username},
+		{Name: "database.redis.password", Value: &cfg.Database.Redis.Password},
+		{Name: "job.redis.username", Value: &cfg.Job.Redis.Username},
+		{Name: "job.redis.password", Value: &cfg.Job.Redis.Password},
+		{Name: "debug.token", Value: &cfg.Debug.Token},
+	}
+}
@@ -19,14 +19,18 @@ package config
 import (
 	"net"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 
+	"d7y.io/dragonfly/v2/pkg/redact"
 	"d7y.io/dragonfly/v2/pkg/rpc"
+	"d7y.io/dragonfly/v2/pkg/secret"
 	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/pkg/unit"
 )
 
 var (
@@ -75,18 +79,29 @@ var (
 func TestConfig_Load(t *testing.T) {
 	config := &Config{
 		Scheduler: SchedulerConfig{
-			Algorithm:              "default",
-			BackToSourceCount:      3,
-			RetryBackToSourceLimit: 2,
-			RetryLimit:             10,
-			RetryInterval:          10 * time.Second,
+			Algorithm:                "default",
+			BackToSourceCount:        3,
+			BackToSourceRateLimit:    10,
+			BackToSourceRateInterval: types.Duration{Duration: 2 * time.Second},
+			BackToSourceCooldown:     types.Duration{Duration: 30 * time.Second},
+			RetryBackToSourceLimit:   2,
+			RetryLimit:               10,
+			RetryInterval:            types.Duration{Duration: 10 * time.Second},
+			PieceViolationLimit:      5,
+			MaxPieceCost:             types.Duration{Duration: 30 * time.Minute},
+			TinyFileSizeLimit:        256 * unit.B,
+			SmallFileSizeLimit:       4 * unit.MB,
 			GC: GCConfig{
 				PieceDownloadTimeout: 5 * time.Second,
+				TaskDownloadTimeout:  1 * time.Hour,
 				PeerGCInterval:       10 * time.Second,
 				PeerTTL:              1 * time.Minute,
-				TaskGCInterval:       30 * time.Second,
-				HostGCInterval:       1 * time.Minute,
-				HostTTL:              1 * time.Minute,
+				ApplicationPeerTTLs: map[string]time.Duration{
+					"ci": 10 * time.Minute,
+				},
+				TaskGCInterval: 30 * time.Second,
+				HostGCInterval: 1 * time.Minute,
+				HostTTL:        1 * time.Minute,
 			},
 			NetworkTopology: NetworkTopologyConfig{
 				CollectInterval: 60 * time.Second,
@@ -99,6 +114,17 @@ func TestConfig_Load(t *testing.T) {
 					TTL:      5 * time.Minute,
 				},
 			},
+			Reschedule: RescheduleConfig{
+				Enable:      true,
+				Interval:    30 * time.Second,
+				ScoreMargin: 0.3,
+				BatchSize:   20,
+				SlowChild: SlowChildConfig{
+					Enable:          true,
+					ThroughputRatio: 0.5,
+					Windows:         5,
+				},
+			},
 		},
 		Server: ServerConfig{
 			AdvertiseIP:   net.ParseIP("127.0.0.1"),
@@ -114,6 +140,26 @@ func TestConfig_Load(t *testing.T) {
 			LogMaxBackups: 3,
 			PluginDir:     "foo",
 			DataDir:       "foo",
+			Overload: OverloadConfig{
+				Enable: true,
+				Limit:  100,
+				MethodLimits: []MethodLimitConfig{
+					{
+						Method: "/scheduler.Scheduler/RegisterPeerTask",
+						Limit:  10,
+					},
+				},
+				QueueTimeout: 200 * time.Millisecond,
+				RetryAfter:   2 * time.Second,
+			},
+			PeerRateLimit: PeerRateLimitConfig{
+				Enable:    true,
+				PeerQPS:   2,
+				PeerBurst: 10,
+				HostQPS:   50,
+				HostBurst: 100,
+				Size:      1000,
+			},
 		},
 		Database: DatabaseConfig{
 			Redis: RedisConfig{
@@ -136,6 +182,20 @@ func TestConfig_Load(t *testing.T) {
 						InsecureSkipVerify: true,
 					},
 				},
+				Persistence: TaskPersistenceConfig{
+					Enable:   true,
+					Backend:  TaskPersistenceBackendFile,
+					Path:     "/var/lib/dragonfly/task_snapshot.json",
+					Interval: DefaultResourceTaskPersistenceInterval,
+					TTL:      DefaultResourceTaskPersistenceTTL,
+				},
+			},
+			Peer: PeerConfig{
+				BlockParentTTL:       DefaultResourcePeerBlockParentTTL,
+				ParentAffinityWindow: DefaultResourcePeerParentAffinityWindow,
+				ParentSwitchLimit:    DefaultResourcePeerParentSwitchLimit,
+				ParentSwitchWindow:   DefaultResourcePeerParentSwitchWindow,
+				ParentPinCooldown:    DefaultResourcePeerParentPinCooldown,
 			},
 		},
 		DynConfig: DynConfig{
@@ -147,10 +207,16 @@ func TestConfig_Load(t *testing.T) {
 			KeepAlive: KeepAliveConfig{
 				Interval: 5 * time.Second,
 			},
+			GzipCompression: true,
 		},
 		SeedPeer: SeedPeerConfig{
 			Enable:              true,
 			TaskDownloadTimeout: 12 * time.Hour,
+			AbortSeeding: AbortSeedingConfig{
+				Enable: true,
+				Linger: 1 * time.Minute,
+			},
+			GzipCompression: true,
 		},
 		Host: HostConfig{
 			IDC:      "foo",
@@ -163,15 +229,21 @@ func TestConfig_Load(t *testing.T) {
 			LocalWorkerNum:     5,
 		},
 		Storage: StorageConfig{
-			MaxSize:    1,
-			MaxBackups: 1,
-			BufferSize: 1,
+			MaxSize:         1,
+			MaxBackups:      1,
+			BufferSize:      1,
+			ListConcurrency: 2,
 		},
 		Metrics: MetricsConfig{
 			Enable:     false,
 			Addr:       ":8000",
 			EnableHost: true,
 		},
+		Debug: DebugConfig{
+			Enable: true,
+			Addr:   "127.0.0.1:8004",
+			Token:  "foo",
+		},
 		Security: SecurityConfig{
 			AutoIssueCert: true,
 			CACert:        "foo",
@@ -186,6 +258,10 @@ func TestConfig_Load(t *testing.T) {
 		Network: NetworkConfig{
 			EnableIPv6: true,
 		},
+		Redact: RedactConfig{
+			QueryParams: []string{"foo"},
+			HashQuery:   true,
+		},
 	}
 
 	schedulerConfigYAML := &Config{}
@@ -283,6 +359,146 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "server requires parameter host")
 			},
 		},
+		{
+			name:   "server requires parameter overload limit",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.Overload.Enable = true
+				cfg.Server.Overload.Limit = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter overload limit")
+			},
+		},
+		{
+			name:   "server requires parameter overload methodLimits method",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.Overload.Enable = true
+				cfg.Server.Overload.MethodLimits = []MethodLimitConfig{{Limit: 1}}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter overload methodLimits method")
+			},
+		},
+		{
+			name:   "server requires parameter overload methodLimits limit",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.Overload.Enable = true
+				cfg.Server.Overload.MethodLimits = []MethodLimitConfig{{Method: "/scheduler.Scheduler/RegisterPeerTask", Limit: -1}}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter overload methodLimits limit")
+			},
+		},
+		{
+			name:   "server requires parameter overload queueTimeout",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.Overload.Enable = true
+				cfg.Server.Overload.QueueTimeout = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter overload queueTimeout")
+			},
+		},
+		{
+			name:   "server requires parameter overload retryAfter",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.Overload.Enable = true
+				cfg.Server.Overload.RetryAfter = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter overload retryAfter")
+			},
+		},
+		{
+			name:   "server requires parameter peerRateLimit peerQPS",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.PeerRateLimit.Enable = true
+				cfg.Server.PeerRateLimit.PeerQPS = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter peerRateLimit peerQPS")
+			},
+		},
+		{
+			name:   "server requires parameter peerRateLimit peerBurst",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.PeerRateLimit.Enable = true
+				cfg.Server.PeerRateLimit.PeerBurst = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter peerRateLimit peerBurst")
+			},
+		},
+		{
+			name:   "server requires parameter peerRateLimit hostQPS",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.PeerRateLimit.Enable = true
+				cfg.Server.PeerRateLimit.HostQPS = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter peerRateLimit hostQPS")
+			},
+		},
+		{
+			name:   "server requires parameter peerRateLimit hostBurst",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.PeerRateLimit.Enable = true
+				cfg.Server.PeerRateLimit.HostBurst = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter peerRateLimit hostBurst")
+			},
+		},
+		{
+			name:   "server requires parameter peerRateLimit size",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Job = mockJobConfig
+				cfg.Server.PeerRateLimit.Enable = true
+				cfg.Server.PeerRateLimit.Size = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "server requires parameter peerRateLimit size")
+			},
+		},
 		{
 			name:   "redis requires parameter brokerDB",
 			config: New(),
@@ -350,6 +566,49 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "scheduler requires parameter backToSourceCount")
 			},
 		},
+		{
+			name:   "backToSourceRateLimit is negative",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.BackToSourceRateLimit = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler backToSourceRateLimit must be greater than or equal to 0")
+			},
+		},
+		{
+			name:   "scheduler requires parameter backToSourceRateInterval",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.BackToSourceRateLimit = 10
+				cfg.Scheduler.BackToSourceRateInterval.Duration = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler requires parameter backToSourceRateInterval")
+			},
+		},
+		{
+			name:   "backToSourceCooldown is negative",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.BackToSourceCooldown.Duration = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler backToSourceCooldown must be greater than or equal to 0")
+			},
+		},
 		{
 			name:   "scheduler requires parameter retryBackToSourceLimit",
 			config: New(),
@@ -385,13 +644,97 @@ func TestConfig_Validate(t *testing.T) {
 				cfg.Manager = mockManagerConfig
 				cfg.Database.Redis = mockRedisConfig
 				cfg.Job = mockJobConfig
-				cfg.Scheduler.RetryInterval = 0
+				cfg.Scheduler.RetryInterval.Duration = 0
 			},
 			expect: func(t *testing.T, err error) {
 				assert := assert.New(t)
 				assert.EqualError(err, "scheduler requires parameter retryInterval")
 			},
 		},
+		{
+			name:   "scheduler requires parameter pieceViolationLimit",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.PieceViolationLimit = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler requires parameter pieceViolationLimit")
+			},
+		},
+		{
+			name:   "scheduler requires parameter maxPieceCost",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.MaxPieceCost.Duration = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler requires parameter maxPieceCost")
+			},
+		},
+		{
+			name:   "tinyFileSizeLimit = 0",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.TinyFileSizeLimit = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler requires parameter tinyFileSizeLimit")
+			},
+		},
+		{
+			name:   "tinyFileSizeLimit exceeds the max limit",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.TinyFileSizeLimit = MaxSchedulerTinyFileSizeLimit + 1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler tinyFileSizeLimit must be less than or equal to 1.0MB")
+			},
+		},
+		{
+			name:   "smallFileSizeLimit equals tinyFileSizeLimit",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.SmallFileSizeLimit = cfg.Scheduler.TinyFileSizeLimit
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler smallFileSizeLimit must be greater than tinyFileSizeLimit")
+			},
+		},
+		{
+			name:   "smallFileSizeLimit exceeds the piece size limit",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.SmallFileSizeLimit = DefaultSchedulerSmallFileSizeLimit + 1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler smallFileSizeLimit must be less than or equal to 15.0MB")
+			},
+		},
 		{
 			name:   "scheduler requires parameter pieceDownloadTimeout",
 			config: New(),
@@ -406,6 +749,20 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "scheduler requires parameter pieceDownloadTimeout")
 			},
 		},
+		{
+			name:   "scheduler requires parameter taskDownloadTimeout",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.GC.TaskDownloadTimeout = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler requires parameter taskDownloadTimeout")
+			},
+		},
 		{
 			name:   "scheduler requires parameter peerTTL",
 			config: New(),
@@ -420,6 +777,20 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "scheduler requires parameter peerTTL")
 			},
 		},
+		{
+			name:   "scheduler requires parameter applicationPeerTTLs",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.GC.ApplicationPeerTTLs = map[string]time.Duration{"foo": 0}
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "scheduler requires parameter applicationPeerTTLs")
+			},
+		},
 		{
 			name:   "scheduler requires parameter peerGCInterval",
 			config: New(),
@@ -537,6 +908,83 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "probe requires parameter count")
 			},
 		},
+		{
+			name:   "reschedule requires parameter interval",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.Reschedule.Enable = true
+				cfg.Scheduler.Reschedule.Interval = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "reschedule requires parameter interval")
+			},
+		},
+		{
+			name:   "reschedule requires parameter scoreMargin",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.Reschedule.Enable = true
+				cfg.Scheduler.Reschedule.ScoreMargin = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "reschedule requires parameter scoreMargin")
+			},
+		},
+		{
+			name:   "reschedule requires parameter batchSize",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.Reschedule.Enable = true
+				cfg.Scheduler.Reschedule.BatchSize = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "reschedule requires parameter batchSize")
+			},
+		},
+		{
+			name:   "slowChild requires parameter throughputRatio",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.Reschedule.Enable = true
+				cfg.Scheduler.Reschedule.SlowChild.Enable = true
+				cfg.Scheduler.Reschedule.SlowChild.ThroughputRatio = 1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "slowChild requires parameter throughputRatio")
+			},
+		},
+		{
+			name:   "slowChild requires parameter windows",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Scheduler.Reschedule.Enable = true
+				cfg.Scheduler.Reschedule.SlowChild.Enable = true
+				cfg.Scheduler.Reschedule.SlowChild.Windows = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "slowChild requires parameter windows")
+			},
+		},
 		{
 			name:   "downloadTiny requires parameter scheme",
 			config: New(),
@@ -565,6 +1013,138 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "downloadTiny requires parameter timeout")
 			},
 		},
+		{
+			name:   "persistence requires parameter backend",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Resource.Task.Persistence.Enable = true
+				cfg.Resource.Task.Persistence.Backend = "foo"
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "persistence requires parameter backend")
+			},
+		},
+		{
+			name:   "persistence with redis backend requires parameter redis addrs",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Database.Redis.Addrs = []string{}
+				cfg.Job = mockJobConfig
+				cfg.Job.Enable = false
+				cfg.Resource.Task.Persistence.Enable = true
+				cfg.Resource.Task.Persistence.Backend = TaskPersistenceBackendRedis
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "persistence with redis backend requires parameter redis addrs")
+			},
+		},
+		{
+			name:   "persistence requires parameter interval",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Resource.Task.Persistence.Enable = true
+				cfg.Resource.Task.Persistence.Interval = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "persistence requires parameter interval")
+			},
+		},
+		{
+			name:   "persistence requires parameter ttl",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Resource.Task.Persistence.Enable = true
+				cfg.Resource.Task.Persistence.TTL = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "persistence requires parameter ttl")
+			},
+		},
+		{
+			name:   "peer blockParentTTL must be greater than or equal to 0",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Resource.Peer.BlockParentTTL = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "peer blockParentTTL must be greater than or equal to 0")
+			},
+		},
+		{
+			name:   "peer parentAffinityWindow must be greater than or equal to 0",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Resource.Peer.ParentAffinityWindow = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "peer parentAffinityWindow must be greater than or equal to 0")
+			},
+		},
+		{
+			name:   "peer parentSwitchLimit must be greater than or equal to 0",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Resource.Peer.ParentSwitchLimit = -1
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "peer parentSwitchLimit must be greater than or equal to 0")
+			},
+		},
+		{
+			name:   "peer requires parameter parentSwitchWindow",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Resource.Peer.ParentSwitchWindow = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "peer requires parameter parentSwitchWindow")
+			},
+		},
+		{
+			name:   "peer requires parameter parentPinCooldown",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Resource.Peer.ParentPinCooldown = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "peer requires parameter parentPinCooldown")
+			},
+		},
 		{
 			name:   "scheduler requires parameter hostTTL",
 			config: New(),
@@ -649,6 +1229,21 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "seedPeer requires parameter taskDownloadTimeout")
 			},
 		},
+		{
+			name:   "seedPeer requires parameter abortSeeding linger",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.SeedPeer.AbortSeeding.Enable = true
+				cfg.SeedPeer.AbortSeeding.Linger = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "seedPeer requires parameter abortSeeding linger")
+			},
+		},
 		{
 			name:   "job requires parameter globalWorkerNum",
 			config: New(),
@@ -733,6 +1328,20 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "storage requires parameter bufferSize")
 			},
 		},
+		{
+			name:   "storage requires parameter listConcurrency",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Storage.ListConcurrency = 0
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "storage requires parameter listConcurrency")
+			},
+		},
 		{
 			name:   "metrics requires parameter addr",
 			config: New(),
@@ -748,6 +1357,21 @@ func TestConfig_Validate(t *testing.T) {
 				assert.EqualError(err, "metrics requires parameter addr")
 			},
 		},
+		{
+			name:   "debug requires parameter addr",
+			config: New(),
+			mock: func(cfg *Config) {
+				cfg.Manager = mockManagerConfig
+				cfg.Database.Redis = mockRedisConfig
+				cfg.Job = mockJobConfig
+				cfg.Debug.Enable = true
+				cfg.Debug.Addr = ""
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "debug requires parameter addr")
+			},
+		},
 		{
 			name:   "security requires parameter caCert",
 			config: New(),
@@ -836,3 +1460,116 @@ func TestConfig_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestConfig_ResolveSecrets(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(file, []byte("bar\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DRAGONFLY_REDIS_PASSWORD", "foo")
+
+	tests := []struct {
+		name   string
+		mock   func(cfg *Config)
+		expect func(t *testing.T, cfg *Config, err error)
+	}{
+		{
+			name: "expand environment variable",
+			mock: func(cfg *Config) {
+				cfg.Database.Redis.Password = "${DRAGONFLY_REDIS_PASSWORD}"
+			},
+			expect: func(t *testing.T, cfg *Config, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal("foo", cfg.Database.Redis.Password)
+			},
+		},
+		{
+			name: "read secret file",
+			mock: func(cfg *Config) {
+				cfg.Debug.Token = secret.FilePrefix + file
+			},
+			expect: func(t *testing.T, cfg *Config, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal("bar", cfg.Debug.Token)
+			},
+		},
+		{
+			name: "deprecated field is converted after resolving",
+			mock: func(cfg *Config) {
+				cfg.Job.Redis.Password = "${DRAGONFLY_REDIS_PASSWORD}"
+			},
+			expect: func(t *testing.T, cfg *Config, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.NoError(cfg.Convert())
+				assert.Equal("foo", cfg.Database.Redis.Password)
+			},
+		},
+		{
+			name: "missing environment variable",
+			mock: func(cfg *Config) {
+				cfg.Database.Redis.Password = "baz-${DRAGONFLY_REDIS_MISSING}"
+			},
+			expect: func(t *testing.T, cfg *Config, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "resolve database.redis.password: missing environment variable DRAGONFLY_REDIS_MISSING")
+			},
+		},
+		{
+			name: "unreadable secret file",
+			mock: func(cfg *Config) {
+				cfg.Debug.Token = secret.FilePrefix + file + ".missing"
+			},
+			expect: func(t *testing.T, cfg *Config, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, os.ErrNotExist)
+				assert.ErrorContains(err, "resolve debug.token")
+			},
+		},
+		{
+			name: "other fields are not expanded",
+			mock: func(cfg *Config) {
+				cfg.Database.Redis.Addrs = []string{"${DRAGONFLY_REDIS_PASSWORD}"}
+				cfg.Manager.Addr = "${DRAGONFLY_REDIS_PASSWORD}"
+			},
+			expect: func(t *testing.T, cfg *Config, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal([]string{"${DRAGONFLY_REDIS_PASSWORD}"}, cfg.Database.Redis.Addrs)
+				assert.Equal("${DRAGONFLY_REDIS_PASSWORD}", cfg.Manager.Addr)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := New()
+			tc.mock(cfg)
+			tc.expect(t, cfg, cfg.ResolveSecrets())
+		})
+	}
+}
+
+func TestConfig_Dump(t *testing.T) {
+	assert := assert.New(t)
+	t.Setenv("DRAGONFLY_REDIS_PASSWORD", "foo-secret")
+
+	cfg := New()
+	cfg.Database.Redis.Username = "baz"
+	cfg.Database.Redis.Password = "${DRAGONFLY_REDIS_PASSWORD}"
+	cfg.Debug.Token = "bar-secret"
+	assert.NoError(cfg.ResolveSecrets())
+
+	dump, err := cfg.Dump()
+	assert.NoError(err)
+	assert.NotContains(dump, "foo-secret")
+	assert.NotContains(dump, "bar-secret")
+	assert.Contains(dump, redact.Redacted)
+
+	// The config itself is not redacted.
+	assert.Equal("foo-secret", cfg.Database.Redis.Password)
+	assert.Equal("bar-secret", cfg.Debug.Token)
+}
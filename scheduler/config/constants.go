@@ -20,7 +20,9 @@ import (
 	"net"
 	"time"
 
+	"d7y.io/dragonfly/v2/internal/util"
 	"d7y.io/dragonfly/v2/pkg/net/ip"
+	"d7y.io/dragonfly/v2/pkg/unit"
 )
 
 const (
@@ -43,6 +45,27 @@ const (
 
 	// DefaultServerAdvertisePort is default advertise port for server.
 	DefaultServerAdvertisePort = 8002
+
+	// DefaultServerOverloadQueueTimeout is default timeout of the request waiting for the concurrent request limit.
+	DefaultServerOverloadQueueTimeout = 100 * time.Millisecond
+
+	// DefaultServerOverloadRetryAfter is default backoff hint of the request rejected by the overloaded server.
+	DefaultServerOverloadRetryAfter = 1 * time.Second
+
+	// DefaultServerPeerRateLimitPeerQPS is default registration rate of every peer id.
+	DefaultServerPeerRateLimitPeerQPS = 1
+
+	// DefaultServerPeerRateLimitPeerBurst is default registration burst of every peer id.
+	DefaultServerPeerRateLimitPeerBurst = 5
+
+	// DefaultServerPeerRateLimitHostQPS is default registration rate of every source host.
+	DefaultServerPeerRateLimitHostQPS = 100
+
+	// DefaultServerPeerRateLimitHostBurst is default registration burst of every source host.
+	DefaultServerPeerRateLimitHostBurst = 200
+
+	// DefaultServerPeerRateLimitSize is default max count of the rate limiters of peers and hosts.
+	DefaultServerPeerRateLimitSize = 100000
 )
 
 const (
@@ -63,6 +86,9 @@ const (
 	// DefaultSchedulerBackToSourceCount is default back-to-source count for scheduler.
 	DefaultSchedulerBackToSourceCount = 200
 
+	// DefaultSchedulerBackToSourceRateInterval is default interval of back-to-source rate limit for scheduler.
+	DefaultSchedulerBackToSourceRateInterval = 1 * time.Second
+
 	// DefaultSchedulerRetryBackToSourceLimit is default retry back-to-source limit for scheduler.
 	DefaultSchedulerRetryBackToSourceLimit = 4
 
@@ -72,6 +98,38 @@ const (
 	// DefaultSchedulerRetryInterval is default retry interval for scheduler.
 	DefaultSchedulerRetryInterval = 500 * time.Millisecond
 
+	// DefaultSchedulerPieceViolationLimit is default limit of impossible piece results before quarantining the peer.
+	DefaultSchedulerPieceViolationLimit = 10
+
+	// DefaultSchedulerMaxPieceCost is default maximum cost of downloading a piece.
+	DefaultSchedulerMaxPieceCost = 1 * time.Hour
+
+	// DefaultSchedulerRescheduleInterval is default interval of rescheduling children.
+	DefaultSchedulerRescheduleInterval = 10 * time.Second
+
+	// DefaultSchedulerRescheduleScoreMargin is default score margin of rescheduling children.
+	DefaultSchedulerRescheduleScoreMargin = 0.2
+
+	// DefaultSchedulerRescheduleBatchSize is default maximum number of children rescheduled in one interval.
+	DefaultSchedulerRescheduleBatchSize = 10
+
+	// DefaultSchedulerRescheduleSlowChildThroughputRatio is default throughput ratio of the slow child.
+	DefaultSchedulerRescheduleSlowChildThroughputRatio = 0.3
+
+	// DefaultSchedulerRescheduleSlowChildWindows is default number of consecutive windows
+	// in which the child is slow, then the child is rescheduled.
+	DefaultSchedulerRescheduleSlowChildWindows = 3
+
+	// DefaultSchedulerTinyFileSizeLimit is default size limit of the tiny file.
+	DefaultSchedulerTinyFileSizeLimit = 128 * unit.B
+
+	// MaxSchedulerTinyFileSizeLimit is max size limit of the tiny file,
+	// because the content of the tiny file is kept in memory.
+	MaxSchedulerTinyFileSizeLimit = 1 * unit.MB
+
+	// DefaultSchedulerSmallFileSizeLimit is default size limit of the small file.
+	DefaultSchedulerSmallFileSizeLimit = util.DefaultPieceSizeLimit * unit.B
+
 	// DefaultSchedulerPieceDownloadTimeout is default timeout of downloading piece.
 	DefaultSchedulerPieceDownloadTimeout = 30 * time.Minute
 
@@ -121,6 +179,35 @@ const (
 
 	// DefaultResourceTaskDownloadTinyTimeout is default timeout of downloading tiny task.
 	DefaultResourceTaskDownloadTinyTimeout = 1 * time.Minute
+
+	// DefaultResourceTaskPersistenceInterval is default interval of taking the task snapshot.
+	DefaultResourceTaskPersistenceInterval = 1 * time.Minute
+
+	// DefaultResourceTaskPersistenceTTL is default time to live of the restored task without peers.
+	DefaultResourceTaskPersistenceTTL = 30 * time.Minute
+
+	// DefaultResourcePeerBlockParentTTL is default time to live of the blocked parent of peer.
+	DefaultResourcePeerBlockParentTTL = 5 * time.Minute
+
+	// DefaultResourcePeerParentAffinityWindow is default window of the affinity of peer to the parent hosts.
+	DefaultResourcePeerParentAffinityWindow = 10 * time.Minute
+
+	// DefaultResourcePeerParentSwitchLimit is default maximum number of times the peer switches parents within the window.
+	DefaultResourcePeerParentSwitchLimit = 5
+
+	// DefaultResourcePeerParentSwitchWindow is default window of counting the parent switches of peer.
+	DefaultResourcePeerParentSwitchWindow = 1 * time.Minute
+
+	// DefaultResourcePeerParentPinCooldown is default duration the peer is pinned to the current parent.
+	DefaultResourcePeerParentPinCooldown = 2 * time.Minute
+)
+
+const (
+	// TaskPersistenceBackendFile persists the task snapshot to the local file.
+	TaskPersistenceBackendFile = "file"
+
+	// TaskPersistenceBackendRedis persists the task snapshot to redis.
+	TaskPersistenceBackendRedis = "redis"
 )
 
 const (
@@ -139,6 +226,9 @@ const (
 const (
 	// DefaultSeedTaskDownloadTimeout is default timeout of downloading task by seed peer.
 	DefaultSeedPeerTaskDownloadTimeout = 10 * time.Hour
+
+	// DefaultSeedPeerAbortSeedingLinger is default linger before aborting the seeding which has no interested peers.
+	DefaultSeedPeerAbortSeedingLinger = 30 * time.Second
 )
 
 const (
@@ -163,6 +253,11 @@ const (
 	DefaultMetricsAddr = ":8000"
 )
 
+const (
+	// DefaultDebugAddr is default address for debug server.
+	DefaultDebugAddr = "127.0.0.1:8004"
+)
+
 var (
 	// DefaultCertIPAddresses is default ip addresses of certificate.
 	DefaultCertIPAddresses = []net.IP{ip.IPv4, ip.IPv6}
@@ -188,6 +283,9 @@ const (
 
 	// DefaultStorageBufferSize is the default size of buffer container.
 	DefaultStorageBufferSize = 100
+
+	// DefaultStorageListConcurrency is the default number of storage files read concurrently.
+	DefaultStorageListConcurrency = 4
 )
 
 const (
@@ -41,6 +41,7 @@ import (
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	dc "d7y.io/dragonfly/v2/internal/dynconfig"
 	"d7y.io/dragonfly/v2/manager/types"
+	"d7y.io/dragonfly/v2/pkg/featureflag"
 	"d7y.io/dragonfly/v2/pkg/net/ip"
 	healthclient "d7y.io/dragonfly/v2/pkg/rpc/health/client"
 	managerclient "d7y.io/dragonfly/v2/pkg/rpc/manager/client"
@@ -58,6 +59,12 @@ var (
 type DynconfigData struct {
 	Scheduler    *managerv2.Scheduler
 	Applications []*managerv2.Application
+
+	// FeatureFlags is the feature flags of the scheduler cluster, parsed from the scheduler cluster config.
+	FeatureFlags map[string]bool
+
+	// ClientFeatureFlags is the valid feature flags of the daemons, parsed from the scheduler cluster client config.
+	ClientFeatureFlags featureflag.Flags
 }
 
 type DynconfigInterface interface {
@@ -85,6 +92,14 @@ type DynconfigInterface interface {
 	// GetSchedulerClusterClientConfig returns the client config.
 	GetSchedulerClusterClientConfig() (types.SchedulerClusterClientConfig, error)
 
+	// GetFeatureFlag returns whether the feature flag is enabled, it is false if the flag is not set
+	// or the dynconfig is unavailable.
+	GetFeatureFlag(name string) bool
+
+	// GetClientFeatureFlags returns the valid feature flags of the daemons, which are forwarded to the daemons,
+	// it is empty if the flags are not set or the dynconfig is unavailable.
+	GetClientFeatureFlags() featureflag.Flags
+
 	// Get returns the dynamic config from manager.
 	Get() (*DynconfigData, error)
 
@@ -337,6 +352,28 @@ func (d *dynconfig) GetSchedulerClusterClientConfig() (types.SchedulerClusterCli
 	return config, nil
 }
 
+// GetFeatureFlag returns whether the feature flag is enabled, the flags are cached
+// and refreshed from manager when the cache expires after the refresh interval.
+func (d *dynconfig) GetFeatureFlag(name string) bool {
+	data, err := d.Get()
+	if err != nil {
+		return false
+	}
+
+	return data.FeatureFlags[name]
+}
+
+// GetClientFeatureFlags returns the valid feature flags of the daemons, the flags are
+// refreshed from manager with the dynconfig data.
+func (d *dynconfig) GetClientFeatureFlags() featureflag.Flags {
+	data, err := d.Get()
+	if err != nil {
+		return nil
+	}
+
+	return data.ClientFeatureFlags
+}
+
 // Refresh refreshes dynconfig in cache.
 func (d *dynconfig) Refresh() error {
 	// If another load is in progress, return directly.
@@ -435,6 +472,13 @@ func (mc *managerClient) Get() (any, error) {
 		return nil, err
 	}
 
+	// Invalid scheduler cluster config does not fail the dynconfig, the feature flags are disabled.
+	var featureFlags map[string]bool
+	if config, err := GetSchedulerClusterConfigByScheduler(getSchedulerResp); err == nil {
+		featureFlags = config.FeatureFlags
+	}
+	clientFeatureFlags := parseClientFeatureFlags(getSchedulerResp)
+
 	listApplicationsResp, err := mc.managerClient.ListApplications(context.Background(), &managerv2.ListApplicationsRequest{
 		SourceType: managerv2.SourceType_SCHEDULER_SOURCE,
 		Hostname:   mc.config.Server.Host,
@@ -445,8 +489,10 @@ func (mc *managerClient) Get() (any, error) {
 			// TODO Compatible with old version manager.
 			if slices.Contains([]codes.Code{codes.Unimplemented, codes.NotFound}, st.Code()) {
 				return DynconfigData{
-					Scheduler:    getSchedulerResp,
-					Applications: nil,
+					Scheduler:          getSchedulerResp,
+					Applications:       nil,
+					FeatureFlags:       featureFlags,
+					ClientFeatureFlags: clientFeatureFlags,
 				}, nil
 			}
 		}
@@ -455,11 +501,32 @@ func (mc *managerClient) Get() (any, error) {
 	}
 
 	return DynconfigData{
-		Scheduler:    getSchedulerResp,
-		Applications: listApplicationsResp.Applications,
+		Scheduler:          getSchedulerResp,
+		Applications:       listApplicationsResp.Applications,
+		FeatureFlags:       featureFlags,
+		ClientFeatureFlags: clientFeatureFlags,
 	}, nil
 }
 
+// parseClientFeatureFlags validates the client feature flags of the scheduler cluster against the registry,
+// the invalid flags are dropped instead of failing the dynconfig, so the daemons use the defaults of them.
+func parseClientFeatureFlags(scheduler *managerv2.Scheduler) featureflag.Flags {
+	if scheduler.GetSchedulerCluster() == nil || len(scheduler.SchedulerCluster.ClientConfig) == 0 {
+		return nil
+	}
+
+	var config types.SchedulerClusterClientConfig
+	if err := json.Unmarshal(scheduler.SchedulerCluster.ClientConfig, &config); err != nil {
+		return nil
+	}
+
+	if err := featureflag.Validate(config.FeatureFlags); err != nil {
+		logger.Warnf("drop invalid client feature flags: %s", err.Error())
+	}
+
+	return featureflag.Parse(config.FeatureFlags)
+}
+
 // GetSeedPeerClusterConfigBySeedPeer returns the seed peer cluster config by seed peer.
 func GetSeedPeerClusterConfigBySeedPeer(seedPeer *managerv2.SeedPeer) (types.SeedPeerClusterConfig, error) {
 	if seedPeer == nil {
@@ -477,3 +544,21 @@ func GetSeedPeerClusterConfigBySeedPeer(seedPeer *managerv2.SeedPeer) (types.See
 
 	return config, nil
 }
+
+// GetSchedulerClusterConfigByScheduler returns the scheduler cluster config by scheduler.
+func GetSchedulerClusterConfigByScheduler(scheduler *managerv2.Scheduler) (types.SchedulerClusterConfig, error) {
+	if scheduler == nil {
+		return types.SchedulerClusterConfig{}, errors.New("invalid scheduler")
+	}
+
+	if scheduler.SchedulerCluster == nil {
+		return types.SchedulerClusterConfig{}, errors.New("invalid scheduler cluster")
+	}
+
+	var config types.SchedulerClusterConfig
+	if err := json.Unmarshal(scheduler.SchedulerCluster.Config, &config); err != nil {
+		return types.SchedulerClusterConfig{}, err
+	}
+
+	return config, nil
+}
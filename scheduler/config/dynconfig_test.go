@@ -29,6 +29,7 @@ import (
 	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
 	managerv2 "d7y.io/api/v2/pkg/apis/manager/v2"
 
+	"d7y.io/dragonfly/v2/pkg/featureflag"
 	"d7y.io/dragonfly/v2/pkg/rpc/manager/client/mocks"
 	"d7y.io/dragonfly/v2/pkg/types"
 )
@@ -474,3 +475,239 @@ func TestDynconfig_Get(t *testing.T) {
 		})
 	}
 }
+
+type mockFeatureFlagsObserver struct {
+	featureFlags map[string]bool
+}
+
+func (o *mockFeatureFlagsObserver) OnNotify(data *DynconfigData) {
+	o.featureFlags = data.FeatureFlags
+}
+
+func TestDynconfig_GetFeatureFlag(t *testing.T) {
+	mockCacheDir := t.TempDir()
+	mockConfig := &Config{
+		DynConfig: DynConfig{},
+		Server: ServerConfig{
+			Host: "localhost",
+		},
+		Manager: ManagerConfig{
+			SchedulerClusterID: 1,
+		},
+	}
+
+	mockScheduler := func(config []byte) *managerv2.Scheduler {
+		return &managerv2.Scheduler{
+			Id:       1,
+			Hostname: "foo",
+			Ip:       "127.0.0.1",
+			Port:     8002,
+			State:    "active",
+			SchedulerCluster: &managerv2.SchedulerCluster{
+				Id:           1,
+				Name:         "bas",
+				Config:       config,
+				ClientConfig: []byte{1},
+			},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		refreshInterval time.Duration
+		sleep           func()
+		mock            func(m *mocks.MockV2MockRecorder)
+		expect          func(t *testing.T, d DynconfigInterface, o *mockFeatureFlagsObserver)
+	}{
+		{
+			name:            "feature flags propagate via notify",
+			refreshInterval: 10 * time.Second,
+			sleep:           func() {},
+			mock: func(m *mocks.MockV2MockRecorder) {
+				m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte(`{"feature_flags":{"foo":true,"bar":false}}`)), nil).Times(1)
+				m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, d DynconfigInterface, o *mockFeatureFlagsObserver) {
+				assert := assert.New(t)
+				assert.NoError(d.Notify())
+				assert.EqualValues(map[string]bool{"foo": true, "bar": false}, o.featureFlags)
+				assert.True(d.GetFeatureFlag("foo"))
+				assert.False(d.GetFeatureFlag("bar"))
+				assert.False(d.GetFeatureFlag("baz"))
+			},
+		},
+		{
+			name:            "feature flags are refreshed after the cache expires",
+			refreshInterval: 10 * time.Millisecond,
+			sleep: func() {
+				time.Sleep(100 * time.Millisecond)
+			},
+			mock: func(m *mocks.MockV2MockRecorder) {
+				gomock.InOrder(
+					m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte(`{"feature_flags":{"foo":true}}`)), nil).Times(1),
+					m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1),
+					m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte(`{"feature_flags":{"foo":false,"bar":true}}`)), nil).Times(1),
+					m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1),
+				)
+			},
+			expect: func(t *testing.T, d DynconfigInterface, o *mockFeatureFlagsObserver) {
+				assert := assert.New(t)
+				assert.NoError(d.Notify())
+				assert.EqualValues(map[string]bool{"foo": false, "bar": true}, o.featureFlags)
+				assert.False(d.GetFeatureFlag("foo"))
+				assert.True(d.GetFeatureFlag("bar"))
+			},
+		},
+		{
+			name:            "invalid scheduler cluster config disables feature flags",
+			refreshInterval: 10 * time.Second,
+			sleep:           func() {},
+			mock: func(m *mocks.MockV2MockRecorder) {
+				m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte{1}), nil).Times(1)
+				m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, d DynconfigInterface, o *mockFeatureFlagsObserver) {
+				assert := assert.New(t)
+				assert.NoError(d.Notify())
+				assert.Empty(o.featureFlags)
+				assert.False(d.GetFeatureFlag("foo"))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			mockManagerClient := mocks.NewMockV2(ctl)
+			tc.mock(mockManagerClient.EXPECT())
+
+			mockConfig.DynConfig.RefreshInterval = tc.refreshInterval
+			d, err := NewDynconfig(mockManagerClient, mockCacheDir, mockConfig, WithTransportCredentials(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			o := &mockFeatureFlagsObserver{}
+			d.Register(o)
+			tc.sleep()
+			tc.expect(t, d, o)
+		})
+	}
+}
+
+func TestDynconfig_GetClientFeatureFlags(t *testing.T) {
+	mockCacheDir := t.TempDir()
+	mockConfig := &Config{
+		DynConfig: DynConfig{},
+		Server: ServerConfig{
+			Host: "localhost",
+		},
+		Manager: ManagerConfig{
+			SchedulerClusterID: 1,
+		},
+	}
+
+	mockScheduler := func(clientConfig []byte) *managerv2.Scheduler {
+		return &managerv2.Scheduler{
+			Id:       1,
+			Hostname: "foo",
+			Ip:       "127.0.0.1",
+			Port:     8002,
+			State:    "active",
+			SchedulerCluster: &managerv2.SchedulerCluster{
+				Id:           1,
+				Name:         "bas",
+				Config:       []byte{1},
+				ClientConfig: clientConfig,
+			},
+		}
+	}
+
+	tests := []struct {
+		name            string
+		refreshInterval time.Duration
+		sleep           func()
+		mock            func(m *mocks.MockV2MockRecorder)
+		expect          func(t *testing.T, d DynconfigInterface)
+	}{
+		{
+			name:            "client feature flags are parsed",
+			refreshInterval: 10 * time.Second,
+			sleep:           func() {},
+			mock: func(m *mocks.MockV2MockRecorder) {
+				m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte(`{"feature_flags":{"piece_download_engine":"v2","piece_compression_level":3}}`)), nil).Times(1)
+				m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, d DynconfigInterface) {
+				assert := assert.New(t)
+				flags := d.GetClientFeatureFlags()
+				assert.Equal(featureflag.Flags{featureflag.PieceDownloadEngine: "v2", featureflag.PieceCompressionLevel: int64(3)}, flags)
+				assert.True(flags.Get(featureflag.PieceCompression).Bool())
+			},
+		},
+		{
+			name:            "invalid client feature flags are dropped",
+			refreshInterval: 10 * time.Second,
+			sleep:           func() {},
+			mock: func(m *mocks.MockV2MockRecorder) {
+				m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte(`{"feature_flags":{"foo":true,"piece_compression":"false","piece_compression_level":9}}`)), nil).Times(1)
+				m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, d DynconfigInterface) {
+				assert := assert.New(t)
+				assert.Equal(featureflag.Flags{featureflag.PieceCompressionLevel: int64(9)}, d.GetClientFeatureFlags())
+			},
+		},
+		{
+			name:            "client feature flags are refreshed after the cache expires",
+			refreshInterval: 10 * time.Millisecond,
+			sleep: func() {
+				time.Sleep(100 * time.Millisecond)
+			},
+			mock: func(m *mocks.MockV2MockRecorder) {
+				gomock.InOrder(
+					m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte(`{"feature_flags":{"piece_compression":true}}`)), nil).Times(1),
+					m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1),
+					m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte(`{"feature_flags":{"piece_compression":false}}`)), nil).Times(1),
+					m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1),
+				)
+			},
+			expect: func(t *testing.T, d DynconfigInterface) {
+				assert := assert.New(t)
+				assert.False(d.GetClientFeatureFlags().Get(featureflag.PieceCompression).Bool())
+			},
+		},
+		{
+			name:            "invalid client config disables client feature flags",
+			refreshInterval: 10 * time.Second,
+			sleep:           func() {},
+			mock: func(m *mocks.MockV2MockRecorder) {
+				m.GetScheduler(gomock.Any(), gomock.Any()).Return(mockScheduler([]byte{1}), nil).Times(1)
+				m.ListApplications(gomock.Any(), gomock.Any()).Return(&managerv2.ListApplicationsResponse{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, d DynconfigInterface) {
+				assert := assert.New(t)
+				assert.Empty(d.GetClientFeatureFlags())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			mockManagerClient := mocks.NewMockV2(ctl)
+			tc.mock(mockManagerClient.EXPECT())
+
+			mockConfig.DynConfig.RefreshInterval = tc.refreshInterval
+			d, err := NewDynconfig(mockManagerClient, mockCacheDir, mockConfig, WithTransportCredentials(nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tc.sleep()
+			tc.expect(t, d)
+		})
+	}
+}
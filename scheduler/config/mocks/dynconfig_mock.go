@@ -14,6 +14,7 @@ import (
 
 	manager "d7y.io/api/v2/pkg/apis/manager/v2"
 	types "d7y.io/dragonfly/v2/manager/types"
+	featureflag "d7y.io/dragonfly/v2/pkg/featureflag"
 	config "d7y.io/dragonfly/v2/scheduler/config"
 	gomock "go.uber.org/mock/gomock"
 	resolver "google.golang.org/grpc/resolver"
@@ -84,6 +85,34 @@ func (mr *MockDynconfigInterfaceMockRecorder) GetApplications() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApplications", reflect.TypeOf((*MockDynconfigInterface)(nil).GetApplications))
 }
 
+// GetClientFeatureFlags mocks base method.
+func (m *MockDynconfigInterface) GetClientFeatureFlags() featureflag.Flags {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetClientFeatureFlags")
+	ret0, _ := ret[0].(featureflag.Flags)
+	return ret0
+}
+
+// GetClientFeatureFlags indicates an expected call of GetClientFeatureFlags.
+func (mr *MockDynconfigInterfaceMockRecorder) GetClientFeatureFlags() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetClientFeatureFlags", reflect.TypeOf((*MockDynconfigInterface)(nil).GetClientFeatureFlags))
+}
+
+// GetFeatureFlag mocks base method.
+func (m *MockDynconfigInterface) GetFeatureFlag(arg0 string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFeatureFlag", arg0)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// GetFeatureFlag indicates an expected call of GetFeatureFlag.
+func (mr *MockDynconfigInterfaceMockRecorder) GetFeatureFlag(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFeatureFlag", reflect.TypeOf((*MockDynconfigInterface)(nil).GetFeatureFlag), arg0)
+}
+
 // GetResolveSeedPeerAddrs mocks base method.
 func (m *MockDynconfigInterface) GetResolveSeedPeerAddrs() ([]resolver.Address, error) {
 	m.ctrl.T.Helper()
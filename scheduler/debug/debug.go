@@ -0,0 +1,303 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debug
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/resource"
+	"d7y.io/dragonfly/v2/scheduler/scheduling"
+)
+
+const (
+	// PeersPath is the path prefix of peers, GET /debug/peers/{id} returns the debug state
+	// of peer and POST /debug/peers/{id}/evict evicts the peer, the reason of eviction is in the query.
+	// The peer is evicted before the response, the children of the peer are rescheduled in the background.
+	PeersPath = "/debug/peers/"
+
+	// HostsPath is the path prefix of hosts, GET /debug/hosts/{id} returns the debug state of host.
+	HostsPath = "/debug/hosts/"
+
+	// TasksPath is the path prefix of tasks, GET /debug/tasks/{id}/dag returns the dag of task in Graphviz DOT.
+	TasksPath = "/debug/tasks/"
+
+	// EvictAction is the action of evicting peer.
+	EvictAction = "evict"
+
+	// DAGAction is the action of exporting the dag of task.
+	DAGAction = "dag"
+
+	// recentPieceCostLimit is the max number of recent piece costs in peer's debug state.
+	recentPieceCostLimit = 16
+)
+
+// Peer is the debug state of peer.
+type Peer struct {
+	ID                 string                  `json:"id"`
+	TaskID             string                  `json:"taskID"`
+	HostID             string                  `json:"hostID"`
+	State              string                  `json:"state"`
+	ParentIDs          []string                `json:"parentIDs"`
+	ChildIDs           []string                `json:"childIDs"`
+	FinishedPieceCount uint                    `json:"finishedPieceCount"`
+	RecentPieceCosts   []string                `json:"recentPieceCosts"`
+	BlockParents       []string                `json:"blockParents"`
+	NeedBackToSource   bool                    `json:"needBackToSource"`
+	Quarantined        bool                    `json:"quarantined"`
+	ParentSwitchCount  int32                   `json:"parentSwitchCount"`
+	Decisions          []resource.PeerDecision `json:"decisions"`
+	Host               *Host                   `json:"host,omitempty"`
+	CreatedAt          time.Time               `json:"createdAt"`
+	UpdatedAt          time.Time               `json:"updatedAt"`
+}
+
+// Host is the debug state of host.
+type Host struct {
+	ID                    string  `json:"id"`
+	Type                  string  `json:"type"`
+	Hostname              string  `json:"hostname"`
+	IP                    string  `json:"ip"`
+	Port                  int32   `json:"port"`
+	ConcurrentUploadLimit int32   `json:"concurrentUploadLimit"`
+	ConcurrentUploadCount int32   `json:"concurrentUploadCount"`
+	UploadCount           int64   `json:"uploadCount"`
+	UploadFailedCount     int64   `json:"uploadFailedCount"`
+	PeerCount             int32   `json:"peerCount"`
+	Maintenance           bool    `json:"maintenance"`
+	Peers                 []*Peer `json:"peers,omitempty"`
+}
+
+// New returns the debug server, it serves the state of peers and hosts in JSON,
+// the dag of tasks in Graphviz DOT, and evicts the peer on demand.
+func New(cfg *config.DebugConfig, res resource.Resource, evictor scheduling.Evictor) *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc(PeersPath, func(w http.ResponseWriter, r *http.Request) {
+		id, action := parsePath(r.URL.Path, PeersPath)
+		switch action {
+		case "":
+			if !allowMethod(w, r, http.MethodGet) {
+				return
+			}
+
+			peer, loaded := res.PeerManager().Load(id)
+			if !loaded {
+				http.Error(w, "peer not found", http.StatusNotFound)
+				return
+			}
+
+			state := newPeer(peer)
+			state.Host = newHost(peer.Host)
+			writeJSON(w, state)
+		case EvictAction:
+			if !allowMethod(w, r, http.MethodPost) {
+				return
+			}
+
+			if err := evictor.EvictPeer(r.Context(), id, r.URL.Query().Get("reason")); err != nil {
+				if status.Code(err) == codes.NotFound {
+					http.Error(w, "peer not found", http.StatusNotFound)
+					return
+				}
+
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	mux.HandleFunc(HostsPath, func(w http.ResponseWriter, r *http.Request) {
+		id, action := parsePath(r.URL.Path, HostsPath)
+		if action != "" {
+			http.NotFound(w, r)
+			return
+		}
+
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+
+		host, loaded := res.HostManager().Load(id)
+		if !loaded {
+			http.Error(w, "host not found", http.StatusNotFound)
+			return
+		}
+
+		state := newHost(host)
+		host.Peers.Range(func(_, value any) bool {
+			peer, ok := value.(*resource.Peer)
+			if !ok {
+				return true
+			}
+
+			state.Peers = append(state.Peers, newPeer(peer))
+			return true
+		})
+		writeJSON(w, state)
+	})
+
+	mux.HandleFunc(TasksPath, func(w http.ResponseWriter, r *http.Request) {
+		id, action := parsePath(r.URL.Path, TasksPath)
+		if action != DAGAction {
+			http.NotFound(w, r)
+			return
+		}
+
+		if !allowMethod(w, r, http.MethodGet) {
+			return
+		}
+
+		task, loaded := res.TaskManager().Load(id)
+		if !loaded {
+			http.Error(w, "task not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+		if err := WriteDOT(w, task); err != nil {
+			logger.Errorf("write task dag failed: %s", err.Error())
+		}
+	})
+
+	return &http.Server{
+		Addr:    cfg.Addr,
+		Handler: authorize(cfg.Token, mux),
+	}
+}
+
+// authorize allows the requests with the bearer token if token is set, or the read-only requests
+// from localhost. The requests changing the state, e.g. evicting peer, always require the token,
+// so that the local processes and the pages in the browser can not change the state without it.
+func authorize(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if ok && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+				if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	})
+}
+
+// parsePath parses the id and the action of the resource from the path,
+// e.g. /debug/peers/{id}/evict is parsed to the id and the evict action.
+func parsePath(path, prefix string) (string, string) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(path, prefix), "/")
+	return id, action
+}
+
+// allowMethod returns whether the method of request is allowed,
+// otherwise it responds method not allowed.
+func allowMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return false
+	}
+
+	return true
+}
+
+// newPeer returns the debug state of peer.
+func newPeer(peer *resource.Peer) *Peer {
+	state := &Peer{
+		ID:                 peer.ID,
+		TaskID:             peer.Task.ID,
+		HostID:             peer.Host.ID,
+		State:              peer.FSM.Current(),
+		ParentIDs:          []string{},
+		ChildIDs:           []string{},
+		FinishedPieceCount: peer.FinishedPieces.Count(),
+		RecentPieceCosts:   []string{},
+		BlockParents:       peer.LoadBlockParents().Values(),
+		NeedBackToSource:   peer.NeedBackToSource.Load(),
+		Quarantined:        peer.Quarantined.Load(),
+		ParentSwitchCount:  peer.ParentSwitchCount.Load(),
+		Decisions:          peer.Decisions(),
+		CreatedAt:          peer.CreatedAt.Load(),
+		UpdatedAt:          peer.UpdatedAt.Load(),
+	}
+
+	for _, parent := range peer.Parents() {
+		state.ParentIDs = append(state.ParentIDs, parent.ID)
+	}
+
+	for _, child := range peer.Children() {
+		state.ChildIDs = append(state.ChildIDs, child.ID)
+	}
+
+	pieceCosts := peer.PieceCosts()
+	if len(pieceCosts) > recentPieceCostLimit {
+		pieceCosts = pieceCosts[len(pieceCosts)-recentPieceCostLimit:]
+	}
+
+	for _, pieceCost := range pieceCosts {
+		state.RecentPieceCosts = append(state.RecentPieceCosts, pieceCost.String())
+	}
+
+	return state
+}
+
+// newHost returns the debug state of host.
+func newHost(host *resource.Host) *Host {
+	return &Host{
+		ID:                    host.ID,
+		Type:                  host.Type.Name(),
+		Hostname:              host.Hostname,
+		IP:                    host.IP,
+		Port:                  host.Port,
+		ConcurrentUploadLimit: host.ConcurrentUploadLimit.Load(),
+		ConcurrentUploadCount: host.ConcurrentUploadCount.Load(),
+		UploadCount:           host.UploadCount.Load(),
+		UploadFailedCount:     host.UploadFailedCount.Load(),
+		PeerCount:             host.PeerCount.Load(),
+		Maintenance:           host.Maintenance.Load(),
+	}
+}
+
+// writeJSON writes the value in JSON to response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Errorf("encode debug state failed: %s", err.Error())
+	}
+}
@@ -0,0 +1,321 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debug
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
+
+	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/resource"
+	"d7y.io/dragonfly/v2/scheduler/scheduling"
+	"d7y.io/dragonfly/v2/scheduler/scheduling/mocks"
+)
+
+var (
+	mockHostID         = "foo-127.0.0.1"
+	mockTaskID         = "bar"
+	mockPeerID         = "127.0.0.1-foo-bar"
+	mockParentPeerID   = "127.0.0.2-foo-bar"
+	mockToken          = "foo"
+	mockResourceConfig = &config.ResourceConfig{
+		Peer: config.PeerConfig{
+			BlockParentTTL: time.Minute,
+		},
+	}
+)
+
+func TestDebug_New(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		req    func() *http.Request
+		mock   func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer)
+		expect func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "get peer",
+			req: func() *http.Request {
+				return newLocalRequest("/debug/peers/" + mockPeerID)
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(mockPeerID)).Return(peer, true).Times(1),
+				)
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusOK)
+
+				var peer Peer
+				assert.NoError(json.Unmarshal(resp.Body.Bytes(), &peer))
+				assert.Equal(peer.ID, mockPeerID)
+				assert.Equal(peer.TaskID, mockTaskID)
+				assert.Equal(peer.State, resource.PeerStatePending)
+				assert.Equal(peer.FinishedPieceCount, uint(2))
+				assert.Equal(peer.RecentPieceCosts, []string{"1s"})
+				assert.Equal(peer.BlockParents, []string{mockParentPeerID})
+				assert.Len(peer.Decisions, 1)
+				assert.Equal(peer.Decisions[0].Type, resource.PeerDecisionBackToSource)
+				assert.Equal(peer.Host.ID, mockHostID)
+				assert.Equal(peer.Host.ConcurrentUploadLimit, int32(config.DefaultPeerConcurrentUploadLimit))
+			},
+		},
+		{
+			name: "peer not found",
+			req: func() *http.Request {
+				return newLocalRequest("/debug/peers/" + mockPeerID)
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(mockPeerID)).Return(nil, false).Times(1),
+				)
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusNotFound)
+			},
+		},
+		{
+			name: "get host",
+			req: func() *http.Request {
+				return newLocalRequest("/debug/hosts/" + mockHostID)
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+				peer.Host.StorePeer(peer)
+				gomock.InOrder(
+					mr.HostManager().Return(hostManager).Times(1),
+					mh.Load(gomock.Eq(mockHostID)).Return(peer.Host, true).Times(1),
+				)
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusOK)
+
+				var host Host
+				assert.NoError(json.Unmarshal(resp.Body.Bytes(), &host))
+				assert.Equal(host.ID, mockHostID)
+				assert.Equal(host.PeerCount, int32(1))
+				assert.Len(host.Peers, 1)
+				assert.Equal(host.Peers[0].ID, mockPeerID)
+				assert.Nil(host.Peers[0].Host)
+			},
+		},
+		{
+			name:  "evict peer",
+			token: mockToken,
+			req: func() *http.Request {
+				req := newLocalRequest("/debug/peers/" + mockPeerID + "/evict?reason=foo")
+				req.Method = http.MethodPost
+				req.Header.Set("Authorization", "Bearer "+mockToken)
+				return req
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(mockPeerID)).Return(peer, true).Times(1),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Delete(gomock.Eq(mockPeerID)).Times(1),
+				)
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusNoContent)
+			},
+		},
+		{
+			name:  "evict peer not found",
+			token: mockToken,
+			req: func() *http.Request {
+				req := newLocalRequest("/debug/peers/" + mockPeerID + "/evict")
+				req.Method = http.MethodPost
+				req.Header.Set("Authorization", "Bearer "+mockToken)
+				return req
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(mockPeerID)).Return(nil, false).Times(1),
+				)
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusNotFound)
+			},
+		},
+		{
+			name: "evict peer with method not allowed",
+			req: func() *http.Request {
+				return newLocalRequest("/debug/peers/" + mockPeerID + "/evict")
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusMethodNotAllowed)
+				assert.Equal(resp.Header().Get("Allow"), http.MethodPost)
+			},
+		},
+		{
+			name: "unknown action of peer",
+			req: func() *http.Request {
+				return newLocalRequest("/debug/peers/" + mockPeerID + "/foo")
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusNotFound)
+			},
+		},
+		{
+			name:  "evict peer from localhost without token",
+			token: mockToken,
+			req: func() *http.Request {
+				req := newLocalRequest("/debug/peers/" + mockPeerID + "/evict")
+				req.Method = http.MethodPost
+				return req
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusForbidden)
+			},
+		},
+		{
+			name: "evict peer from localhost if token is empty",
+			req: func() *http.Request {
+				req := newLocalRequest("/debug/peers/" + mockPeerID + "/evict")
+				req.Method = http.MethodPost
+				return req
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusForbidden)
+			},
+		},
+		{
+			name: "evict peer not from localhost without token",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodPost, "/debug/peers/"+mockPeerID+"/evict", nil)
+				req.RemoteAddr = "192.168.0.1:8080"
+				return req
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusForbidden)
+			},
+		},
+		{
+			name: "request not from localhost without token",
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/debug/peers/"+mockPeerID, nil)
+				req.RemoteAddr = "192.168.0.1:8080"
+				return req
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusForbidden)
+			},
+		},
+		{
+			name:  "request not from localhost with invalid token",
+			token: mockToken,
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/debug/peers/"+mockPeerID, nil)
+				req.RemoteAddr = "192.168.0.1:8080"
+				req.Header.Set("Authorization", "Bearer bar")
+				return req
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusForbidden)
+			},
+		},
+		{
+			name:  "request not from localhost with token",
+			token: mockToken,
+			req: func() *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/debug/peers/"+mockPeerID, nil)
+				req.RemoteAddr = "192.168.0.1:8080"
+				req.Header.Set("Authorization", "Bearer "+mockToken)
+				return req
+			},
+			mock: func(mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, mh *resource.MockHostManagerMockRecorder, peerManager resource.PeerManager, hostManager resource.HostManager, peer *resource.Peer) {
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(mockPeerID)).Return(peer, true).Times(1),
+				)
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusOK)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			res := resource.NewMockResource(ctl)
+			peerManager := resource.NewMockPeerManager(ctl)
+			hostManager := resource.NewMockHostManager(ctl)
+
+			mockHost := resource.NewHost(mockHostID, "127.0.0.1", "foo", 8003, 8001, types.HostTypeNormal)
+			mockTask := resource.NewTask(mockTaskID, "https://example.com", "", "", commonv2.TaskType_DFDAEMON, nil, nil, 10)
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+			peer.FinishedPieces.Set(0).Set(1)
+			peer.AppendPieceCost(time.Second)
+			peer.BlockParent(mockParentPeerID)
+			peer.AppendDecision(resource.PeerDecision{Type: resource.PeerDecisionBackToSource})
+
+			tc.mock(res.EXPECT(), peerManager.EXPECT(), hostManager.EXPECT(), peerManager, hostManager, peer)
+
+			resp := httptest.NewRecorder()
+			New(&config.DebugConfig{Addr: config.DefaultDebugAddr, Token: tc.token}, res, scheduling.NewEvictor(res, mocks.NewMockScheduling(ctl))).Handler.ServeHTTP(resp, tc.req())
+			tc.expect(t, resp)
+		})
+	}
+}
+
+// newLocalRequest returns a request from localhost.
+func newLocalRequest(target string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = "127.0.0.1:1234"
+	return req
+}
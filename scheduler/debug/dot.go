@@ -0,0 +1,65 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debug
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+
+	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/scheduler/resource"
+)
+
+// WriteDOT writes the dag of task in Graphviz DOT, the nodes are labeled with the id, host and state of peers,
+// and the edges point from the parent to the child in the direction of piece transfer.
+// The peers are sorted by id, so the output of the same dag is stable for diff.
+func WriteDOT(w io.Writer, task *resource.Task) error {
+	peers := task.LoadPeers()
+	sort.Slice(peers, func(i, j int) bool {
+		return peers[i].ID < peers[j].ID
+	})
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %q {\n", task.ID)
+	fmt.Fprintln(bw, "  rankdir=LR;")
+	fmt.Fprintln(bw, "  node [shape=ellipse];")
+	for _, peer := range peers {
+		label := fmt.Sprintf("%s\nhost: %s (%s)\nstate: %s", peer.ID, peer.Host.Hostname, peer.Host.IP, peer.FSM.Current())
+		if peer.Host.Type != types.HostTypeNormal {
+			fmt.Fprintf(bw, "  %q [label=%q, shape=box];\n", peer.ID, label)
+			continue
+		}
+
+		fmt.Fprintf(bw, "  %q [label=%q];\n", peer.ID, label)
+	}
+
+	for _, peer := range peers {
+		children := peer.Children()
+		sort.Slice(children, func(i, j int) bool {
+			return children[i].ID < children[j].ID
+		})
+
+		for _, child := range children {
+			fmt.Fprintf(bw, "  %q -> %q;\n", peer.ID, child.ID)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
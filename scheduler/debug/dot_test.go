@@ -0,0 +1,162 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package debug
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
+
+	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/resource"
+	"d7y.io/dragonfly/v2/scheduler/scheduling"
+	"d7y.io/dragonfly/v2/scheduler/scheduling/mocks"
+)
+
+// mockDOT is the dag of the task returned by newMockTree in Graphviz DOT.
+var mockDOT = `digraph "bar" {
+  rankdir=LR;
+  node [shape=ellipse];
+  "a-seed" [label="a-seed\nhost: seed (127.0.0.1)\nstate: Succeeded", shape=box];
+  "b-child" [label="b-child\nhost: foo (127.0.0.2)\nstate: Running"];
+  "c-child" [label="c-child\nhost: bar (127.0.0.3)\nstate: Running"];
+  "d-grandchild" [label="d-grandchild\nhost: baz (127.0.0.4)\nstate: Running"];
+  "a-seed" -> "b-child";
+  "a-seed" -> "c-child";
+  "b-child" -> "d-grandchild";
+}
+`
+
+func TestWriteDOT(t *testing.T) {
+	tests := []struct {
+		name   string
+		task   func(t *testing.T) *resource.Task
+		expect func(t *testing.T, dot string, err error)
+	}{
+		{
+			name: "write the dag of a small tree",
+			task: newMockTree,
+			expect: func(t *testing.T, dot string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockDOT, dot)
+			},
+		},
+		{
+			name: "write the empty dag",
+			task: func(t *testing.T) *resource.Task {
+				return resource.NewTask(mockTaskID, "https://example.com", "", "", commonv2.TaskType_DFDAEMON, nil, nil, 10)
+			},
+			expect: func(t *testing.T, dot string, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal("digraph \"bar\" {\n  rankdir=LR;\n  node [shape=ellipse];\n}\n", dot)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteDOT(&buf, tc.task(t))
+			tc.expect(t, buf.String(), err)
+		})
+	}
+}
+
+func TestDebug_TaskDAG(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(t *testing.T, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, taskManager resource.TaskManager)
+		expect func(t *testing.T, resp *httptest.ResponseRecorder)
+	}{
+		{
+			name: "get task dag",
+			mock: func(t *testing.T, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, taskManager resource.TaskManager) {
+				gomock.InOrder(
+					mr.TaskManager().Return(taskManager).Times(1),
+					mt.Load(gomock.Eq(mockTaskID)).Return(newMockTree(t), true).Times(1),
+				)
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusOK)
+				assert.Equal(resp.Header().Get("Content-Type"), "text/vnd.graphviz")
+				assert.Equal(mockDOT, resp.Body.String())
+			},
+		},
+		{
+			name: "task not found",
+			mock: func(t *testing.T, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, taskManager resource.TaskManager) {
+				gomock.InOrder(
+					mr.TaskManager().Return(taskManager).Times(1),
+					mt.Load(gomock.Eq(mockTaskID)).Return(nil, false).Times(1),
+				)
+			},
+			expect: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert := assert.New(t)
+				assert.Equal(resp.Code, http.StatusNotFound)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			res := resource.NewMockResource(ctl)
+			taskManager := resource.NewMockTaskManager(ctl)
+			tc.mock(t, res.EXPECT(), taskManager.EXPECT(), taskManager)
+
+			resp := httptest.NewRecorder()
+			New(&config.DebugConfig{Addr: config.DefaultDebugAddr}, res, scheduling.NewEvictor(res, mocks.NewMockScheduling(ctl))).Handler.ServeHTTP(resp, newLocalRequest("/debug/tasks/"+mockTaskID+"/dag"))
+			tc.expect(t, resp)
+		})
+	}
+}
+
+// newMockTree returns the task whose dag is a small tree,
+// the seed peer has two children and one of them has a child.
+func newMockTree(t *testing.T) *resource.Task {
+	task := resource.NewTask(mockTaskID, "https://example.com", "", "", commonv2.TaskType_DFDAEMON, nil, nil, 10)
+	seedPeer := resource.NewPeer("a-seed", mockResourceConfig, task, resource.NewHost("seed-127.0.0.1", "127.0.0.1", "seed", 8003, 8001, types.HostTypeSuperSeed))
+	child := resource.NewPeer("b-child", mockResourceConfig, task, resource.NewHost("foo-127.0.0.2", "127.0.0.2", "foo", 8003, 8001, types.HostTypeNormal))
+	otherChild := resource.NewPeer("c-child", mockResourceConfig, task, resource.NewHost("bar-127.0.0.3", "127.0.0.3", "bar", 8003, 8001, types.HostTypeNormal))
+	grandchild := resource.NewPeer("d-grandchild", mockResourceConfig, task, resource.NewHost("baz-127.0.0.4", "127.0.0.4", "baz", 8003, 8001, types.HostTypeNormal))
+
+	// Store the peers in reverse order to make sure the output is sorted.
+	for _, peer := range []*resource.Peer{grandchild, otherChild, child, seedPeer} {
+		task.StorePeer(peer)
+		peer.FSM.SetState(resource.PeerStateRunning)
+	}
+	seedPeer.FSM.SetState(resource.PeerStateSucceeded)
+
+	for _, edge := range [][2]*resource.Peer{{seedPeer, otherChild}, {seedPeer, child}, {child, grandchild}} {
+		if err := task.AddPeerEdge(edge[0], edge[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return task
+}
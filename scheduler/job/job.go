@@ -23,7 +23,6 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"strings"
 	"time"
 
 	"github.com/RichardKnop/machinery/v1"
@@ -39,6 +38,8 @@ import (
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	internaljob "d7y.io/dragonfly/v2/internal/job"
 	"d7y.io/dragonfly/v2/pkg/idgen"
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/redact"
 	dfdaemonclient "d7y.io/dragonfly/v2/pkg/rpc/dfdaemon/client"
 	"d7y.io/dragonfly/v2/scheduler/config"
 	"d7y.io/dragonfly/v2/scheduler/resource"
@@ -180,14 +181,14 @@ func (j *job) preheat(ctx context.Context, data string) error {
 	}
 
 	if err := validator.New().Struct(req); err != nil {
-		logger.Errorf("preheat %s validate failed: %s", req.URL, err.Error())
+		logger.Errorf("preheat %s validate failed: %s", redact.URL(req.URL), err.Error())
 		return err
 	}
 
 	// Preheat by v2 grpc protocol. If seed peer does not support
 	// v2 protocol, preheat by v1 grpc protocol.
 	if err := j.preheatV2(ctx, req); err != nil {
-		logger.Errorf("preheat %s failed: %s", req.URL, err.Error())
+		logger.Errorf("preheat %s failed: %s", redact.URL(req.URL), err.Error())
 
 		if st, ok := status.FromError(err); ok {
 			if st.Code() == codes.Unimplemented {
@@ -218,9 +219,10 @@ func (j *job) preheatV1(ctx context.Context, req *internaljob.PreheatRequest) er
 
 	// Trigger seed peer download seeds.
 	taskID := idgen.TaskIDV1(req.URL, urlMeta)
-	log := logger.WithTask(taskID, req.URL)
+	redactedURL := redact.URL(req.URL)
+	log := logger.WithTask(taskID, redactedURL)
 	log.Infof("preheat(v1) %s tag: %s, filtered query params: %s, digest: %s, headers: %#v",
-		req.URL, urlMeta.Tag, urlMeta.Filter, urlMeta.Digest, urlMeta.Header)
+		redactedURL, urlMeta.Tag, urlMeta.Filter, urlMeta.Digest, nethttp.WithoutSensitiveHeaders(urlMeta.Header))
 
 	stream, err := j.resource.SeedPeer().Client().ObtainSeeds(ctx, &cdnsystemv1.SeedRequest{
 		TaskId:  taskID,
@@ -228,19 +230,19 @@ func (j *job) preheatV1(ctx context.Context, req *internaljob.PreheatRequest) er
 		UrlMeta: urlMeta,
 	})
 	if err != nil {
-		log.Errorf("preheat(v1) %s failed: %s", req.URL, err.Error())
+		log.Errorf("preheat(v1) %s failed: %s", redactedURL, err.Error())
 		return err
 	}
 
 	for {
 		piece, err := stream.Recv()
 		if err != nil {
-			log.Errorf("preheat(v1) %s recive piece failed: %s", req.URL, err.Error())
+			log.Errorf("preheat(v1) %s recive piece failed: %s", redactedURL, err.Error())
 			return err
 		}
 
 		if piece.Done == true {
-			log.Infof("preheat(v1) %s succeeded", req.URL)
+			log.Infof("preheat(v1) %s succeeded", redactedURL)
 			return nil
 		}
 	}
@@ -248,12 +250,13 @@ func (j *job) preheatV1(ctx context.Context, req *internaljob.PreheatRequest) er
 
 // preheatV2 preheats job by v2 grpc protocol.
 func (j *job) preheatV2(ctx context.Context, req *internaljob.PreheatRequest) error {
-	filteredQueryParams := strings.Split(req.FilteredQueryParams, idgen.FilteredQueryParamsSeparator)
+	filteredQueryParams := idgen.ParseFilteredQueryParams(req.FilteredQueryParams)
 	taskID := idgen.TaskIDV2(req.URL, req.Digest, req.Tag, req.Application, filteredQueryParams)
 
-	log := logger.WithTask(taskID, req.URL)
+	redactedURL := redact.URL(req.URL)
+	log := logger.WithTask(taskID, redactedURL)
 	log.Infof("preheat(v2) %s tag: %s, filtered query params: %s, digest: %s, headers: %#v",
-		req.URL, req.Tag, req.FilteredQueryParams, req.Digest, req.Headers)
+		redactedURL, req.Tag, req.FilteredQueryParams, req.Digest, req.Headers)
 
 	stream, err := j.resource.SeedPeer().Client().DownloadTask(ctx, taskID, &dfdaemonv2.DownloadTaskRequest{
 		Download: &commonv2.Download{
@@ -267,7 +270,7 @@ func (j *job) preheatV2(ctx context.Context, req *internaljob.PreheatRequest) er
 			RequestHeader:       req.Headers,
 		}})
 	if err != nil {
-		logger.Errorf("preheat(v2) %s failed: %s", req.URL, err.Error())
+		logger.Errorf("preheat(v2) %s failed: %s", redactedURL, err.Error())
 		return err
 	}
 
@@ -276,11 +279,11 @@ func (j *job) preheatV2(ctx context.Context, req *internaljob.PreheatRequest) er
 		_, err := stream.Recv()
 		if err != nil {
 			if err == io.EOF {
-				log.Infof("preheat(v2) %s succeeded", req.URL)
+				log.Infof("preheat(v2) %s succeeded", redactedURL)
 				return nil
 			}
 
-			log.Errorf("preheat(v2) %s recive piece failed: %s", req.URL, err.Error())
+			log.Errorf("preheat(v2) %s recive piece failed: %s", redactedURL, err.Error())
 			return err
 		}
 	}
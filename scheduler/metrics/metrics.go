@@ -36,6 +36,61 @@ var (
 
 	// HostTrafficDownloadType is download traffic type for host traffic metrics.
 	HostTrafficDownloadType = "download"
+
+	// PeerBackToSourceReasonNeeded is the reason that the peer is required to back-to-source,
+	// e.g. the host is seed peer or the priority of the peer requires back-to-source.
+	PeerBackToSourceReasonNeeded = "need_back_to_source"
+
+	// PeerBackToSourceReasonSeedPeerFailed is the reason that the seed peer of the task failed.
+	PeerBackToSourceReasonSeedPeerFailed = "seed_peer_failed"
+
+	// PeerBackToSourceReasonRetryLimitExceeded is the reason that scheduling exceeded the RetryBackToSourceLimit.
+	PeerBackToSourceReasonRetryLimitExceeded = "retry_limit_exceeded"
+
+	// PeerRescheduleReasonSeedPeerSucceeded is the reason that the seed peer of the task succeeded
+	// and none of the current parents of the peer succeeded.
+	PeerRescheduleReasonSeedPeerSucceeded = "seed_peer_succeeded"
+
+	// PeerRescheduleReasonScoreMargin is the reason that the score of the best candidate parent
+	// exceeds the score of the current parent by the margin.
+	PeerRescheduleReasonScoreMargin = "score_margin"
+
+	// PeerRescheduleReasonSlowChild is the reason that the peer downloads slowly from
+	// the parent whose upload slots are exhausted.
+	PeerRescheduleReasonSlowChild = "slow_child"
+
+	// ParentFilteredReasonBlocklist is the reason that the candidate parent is in the blocklist.
+	ParentFilteredReasonBlocklist = "blocklist"
+
+	// ParentFilteredReasonSameHost is the reason that the candidate parent is on the same host as the peer.
+	ParentFilteredReasonSameHost = "same_host"
+
+	// ParentFilteredReasonNotInDAG is the reason that the candidate parent can not be found in the dag.
+	ParentFilteredReasonNotInDAG = "not_in_dag"
+
+	// ParentFilteredReasonNotReady is the reason that the candidate parent has neither parents
+	// nor finished downloading, and it is not back-to-source.
+	ParentFilteredReasonNotReady = "not_ready"
+
+	// ParentFilteredReasonQuarantined is the reason that the candidate parent is quarantined.
+	ParentFilteredReasonQuarantined = "quarantined"
+
+	// ParentFilteredReasonMaintenance is the reason that the candidate parent host is in maintenance.
+	ParentFilteredReasonMaintenance = "maintenance"
+
+	// ParentFilteredReasonPieceCodec is the reason that the candidate parent host does not support
+	// the codec of piece compression negotiated with the peer.
+	ParentFilteredReasonPieceCodec = "piece_codec"
+
+	// ParentFilteredReasonBadNode is the reason that the candidate parent is a bad node.
+	ParentFilteredReasonBadNode = "bad_node"
+
+	// ParentFilteredReasonNoFreeUpload is the reason that the free upload of the candidate parent host is empty.
+	ParentFilteredReasonNoFreeUpload = "no_free_upload"
+
+	// ParentFilteredReasonDescendant is the reason that the candidate parent is a descendant of the peer,
+	// the edge from the candidate parent to the peer makes a cycle.
+	ParentFilteredReasonDescendant = "descendant"
 )
 
 // Variables declared for metrics.
@@ -252,6 +307,27 @@ var (
 		Help:      "Counter of the number of failed of the synchronizing probes.",
 	})
 
+	PeerBackToSourceCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "peer_back_to_source_total",
+		Help:      "Counter of the number of the peer scheduled to back-to-source.",
+	}, []string{"task_type", "reason"})
+
+	PeerRescheduleCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "peer_reschedule_total",
+		Help:      "Counter of the number of the peer rescheduled proactively.",
+	}, []string{"reason"})
+
+	ParentFilteredCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "parent_filtered_total",
+		Help:      "Counter of the number of the candidate parents filtered out.",
+	}, []string{"reason"})
+
 	Traffic = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: types.MetricsNamespace,
 		Subsystem: types.SchedulerMetricsName,
@@ -259,6 +335,13 @@ var (
 		Help:      "Counter of the number of traffic.",
 	}, []string{"type", "task_type", "host_type"})
 
+	SeedPeerAbortedTraffic = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "seed_peer_aborted_traffic",
+		Help:      "Counter of the number of traffic downloaded by seed peer before the seeding is aborted.",
+	}, []string{"task_type"})
+
 	HostTraffic = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: types.MetricsNamespace,
 		Subsystem: types.SchedulerMetricsName,
@@ -289,6 +372,79 @@ var (
 		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.95: 0.005, 0.99: 0.001},
 	})
 
+	GRPCHandlingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "grpc_handling_duration_seconds",
+		Help:      "Histogram of the handling duration of the grpc method, the duration of stream method is the lifetime of the stream.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 12),
+	}, []string{"method", "code"})
+
+	GRPCInFlightRequestGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "grpc_in_flight_request_total",
+		Help:      "Gauge of the number of in-flight requests of the limited grpc method.",
+	}, []string{"method"})
+
+	GRPCOverloadRejectedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "grpc_overload_rejected_total",
+		Help:      "Counter of the number of requests rejected by the overloaded grpc method.",
+	}, []string{"method"})
+
+	PeerRateLimitThrottledCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "peer_rate_limit_throttled_total",
+		Help:      "Counter of the number of peer registrations throttled by the rate limit.",
+	}, []string{"method"})
+
+	TaskGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "task_total",
+		Help:      "Gauge of the number of the tasks held by the scheduler by state.",
+	}, []string{"state"})
+
+	PeerGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "peer_total",
+		Help:      "Gauge of the number of the peers held by the scheduler by state.",
+	}, []string{"state"})
+
+	HostGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "host_total",
+		Help:      "Gauge of the number of the hosts held by the scheduler by type.",
+	}, []string{"type"})
+
+	BackToSourcePeerGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "back_to_source_peer_total",
+		Help:      "Gauge of the number of the peers going back-to-source by the application of the task.",
+	}, []string{"application"})
+
+	PeerStateDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "peer_state_duration_seconds",
+		Help:      "Histogram of the time each peer stays in the state.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 4, 10),
+	}, []string{"state"})
+
+	PeerParentSwitchCount = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: types.MetricsNamespace,
+		Subsystem: types.SchedulerMetricsName,
+		Name:      "peer_parent_switch_count",
+		Help:      "Histogram of the number of times each succeeded peer switches parents.",
+		Buckets:   []float64{0, 1, 2, 4, 8, 16, 32, 64},
+	})
+
 	VersionGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: types.MetricsNamespace,
 		Subsystem: types.SchedulerMetricsName,
@@ -28,6 +28,11 @@ import (
 	"d7y.io/dragonfly/v2/scheduler/config"
 )
 
+const (
+	// EvictedPeerTTL is the time the evicted peer is refused to register again.
+	EvictedPeerTTL = 10 * time.Minute
+)
+
 // HostOption is a functional option for configuring the host.
 type HostOption func(h *Host)
 
@@ -122,6 +127,13 @@ func WithBuild(build Build) HostOption {
 	}
 }
 
+// WithPieceCodecs sets host's piece codecs.
+func WithPieceCodecs(codecs []string) HostOption {
+	return func(h *Host) {
+		h.PieceCodecs.Store(&codecs)
+	}
+}
+
 // WithAnnounceInterval sets host's announce interval.
 func WithAnnounceInterval(announceInterval time.Duration) HostOption {
 	return func(h *Host) {
@@ -129,6 +141,13 @@ func WithAnnounceInterval(announceInterval time.Duration) HostOption {
 	}
 }
 
+// WithStartedAt sets host's StartedAt.
+func WithStartedAt(startedAt time.Time) HostOption {
+	return func(h *Host) {
+		h.StartedAt.Store(startedAt)
+	}
+}
+
 // Host contains content for host.
 type Host struct {
 	// ID is host id.
@@ -188,6 +207,10 @@ type Host struct {
 	// AnnounceInterval is the interval between host announces to scheduler.
 	AnnounceInterval time.Duration
 
+	// PieceCodecs is the codecs of piece compression supported by host, it is updated
+	// by every registration of the peers of host.
+	PieceCodecs *atomic.Pointer[[]string]
+
 	// ConcurrentUploadLimit is concurrent upload limit count.
 	ConcurrentUploadLimit *atomic.Int32
 
@@ -206,14 +229,28 @@ type Host struct {
 	// PeerCount is peer count.
 	PeerCount *atomic.Int32
 
+	// evictedPeers is the eviction time of the peers evicted from host by the peer id.
+	evictedPeers *sync.Map
+
+	// Maintenance is set to true when the host is in maintenance, the peers of the host
+	// can still download, but they are not selected as parents.
+	Maintenance *atomic.Bool
+
 	// CreatedAt is host create time.
 	CreatedAt *atomic.Time
 
 	// UpdatedAt is host update time.
 	UpdatedAt *atomic.Time
 
+	// StartedAt is the start time of the daemon announced by the host, it is zero
+	// if the daemon does not announce it. The daemon restarted if it becomes newer.
+	StartedAt *atomic.Time
+
 	// Host log.
 	Log *logger.SugaredLoggerOnWith
+
+	// mu guards merging the announced fields of the same machine.
+	mu *sync.Mutex
 }
 
 // CPU contains content for cpu.
@@ -363,15 +400,20 @@ func NewHost(
 		Hostname:              hostname,
 		Port:                  port,
 		DownloadPort:          downloadPort,
+		PieceCodecs:           atomic.NewPointer[[]string](nil),
 		ConcurrentUploadLimit: atomic.NewInt32(int32(concurrentUploadLimit)),
 		ConcurrentUploadCount: atomic.NewInt32(0),
 		UploadCount:           atomic.NewInt64(0),
 		UploadFailedCount:     atomic.NewInt64(0),
 		Peers:                 &sync.Map{},
 		PeerCount:             atomic.NewInt32(0),
+		evictedPeers:          &sync.Map{},
+		Maintenance:           atomic.NewBool(false),
 		CreatedAt:             atomic.NewTime(time.Now()),
 		UpdatedAt:             atomic.NewTime(time.Now()),
+		StartedAt:             atomic.NewTime(time.Time{}),
 		Log:                   logger.WithHost(id, hostname, ip),
+		mu:                    &sync.Mutex{},
 	}
 
 	for _, opt := range options {
@@ -381,6 +423,89 @@ func NewHost(
 	return h
 }
 
+// merge merges the announced fields of the host, which is the same machine but has a
+// different id, into the host. The peers and the upload counters are kept, so that
+// the machine is scheduled as one host.
+func (h *Host) merge(host *Host) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if host.Type != types.HostTypeNormal {
+		h.Type = host.Type
+	}
+
+	if host.Type != types.HostTypeNormal || h.Type == types.HostTypeNormal {
+		h.ConcurrentUploadLimit.Store(host.ConcurrentUploadLimit.Load())
+	}
+
+	if host.Port != 0 {
+		h.Port = host.Port
+	}
+
+	if host.DownloadPort != 0 {
+		h.DownloadPort = host.DownloadPort
+	}
+
+	if host.ObjectStoragePort != 0 {
+		h.ObjectStoragePort = host.ObjectStoragePort
+	}
+
+	if host.OS != "" {
+		h.OS = host.OS
+		h.Platform = host.Platform
+		h.PlatformFamily = host.PlatformFamily
+		h.PlatformVersion = host.PlatformVersion
+		h.KernelVersion = host.KernelVersion
+	}
+
+	if host.CPU != (CPU{}) {
+		h.CPU = host.CPU
+	}
+
+	if host.Memory != (Memory{}) {
+		h.Memory = host.Memory
+	}
+
+	if host.Network.Location != "" {
+		h.Network.Location = host.Network.Location
+	}
+
+	if host.Network.IDC != "" {
+		h.Network.IDC = host.Network.IDC
+	}
+
+	if host.Disk != (Disk{}) {
+		h.Disk = host.Disk
+	}
+
+	if host.Build != (Build{}) {
+		h.Build = host.Build
+	}
+
+	if host.SchedulerClusterID != 0 {
+		h.SchedulerClusterID = host.SchedulerClusterID
+	}
+
+	if host.AnnounceInterval != 0 {
+		h.AnnounceInterval = host.AnnounceInterval
+	}
+
+	if codecs := host.LoadPieceCodecs(); len(codecs) > 0 {
+		h.PieceCodecs.Store(&codecs)
+	}
+
+	h.UpdatedAt.Store(time.Now())
+}
+
+// LoadPieceCodecs returns the codecs of piece compression supported by host.
+func (h *Host) LoadPieceCodecs() []string {
+	if codecs := h.PieceCodecs.Load(); codecs != nil {
+		return *codecs
+	}
+
+	return nil
+}
+
 // LoadPeer return peer for a key.
 func (h *Host) LoadPeer(key string) (*Peer, bool) {
 	rawPeer, loaded := h.Peers.Load(key)
@@ -404,6 +529,35 @@ func (h *Host) DeletePeer(key string) {
 	}
 }
 
+// EvictPeer records the peer evicted, so that the peer is refused to register again
+// within EvictedPeerTTL. The expired evictions are removed.
+func (h *Host) EvictPeer(key string) {
+	h.evictedPeers.Range(func(id, evictedAt any) bool {
+		if time.Since(evictedAt.(time.Time)) > EvictedPeerTTL {
+			h.evictedPeers.Delete(id)
+		}
+
+		return true
+	})
+
+	h.evictedPeers.Store(key, time.Now())
+}
+
+// IsPeerEvicted returns whether the peer is evicted within EvictedPeerTTL.
+func (h *Host) IsPeerEvicted(key string) bool {
+	evictedAt, loaded := h.evictedPeers.Load(key)
+	if !loaded {
+		return false
+	}
+
+	if time.Since(evictedAt.(time.Time)) > EvictedPeerTTL {
+		h.evictedPeers.Delete(key)
+		return false
+	}
+
+	return true
+}
+
 // LeavePeers set peer state to PeerStateLeave.
 func (h *Host) LeavePeers() {
 	h.Peers.Range(func(_, value any) bool {
@@ -423,6 +577,29 @@ func (h *Host) LeavePeers() {
 	})
 }
 
+// ResetUploadCounts resets the upload counters of host, the concurrent upload count is
+// recounted by the out edges of the peers in the host.
+func (h *Host) ResetUploadCounts() {
+	var concurrentUploadCount int32
+	h.Peers.Range(func(_, value any) bool {
+		peer, ok := value.(*Peer)
+		if !ok {
+			h.Log.Error("invalid peer")
+			return true
+		}
+
+		if degree, err := peer.Task.PeerOutDegree(peer.ID); err == nil {
+			concurrentUploadCount += int32(degree)
+		}
+
+		return true
+	})
+
+	h.ConcurrentUploadCount.Store(concurrentUploadCount)
+	h.UploadCount.Store(0)
+	h.UploadFailedCount.Store(0)
+}
+
 // FreeUploadCount return free upload count of host.
 func (h *Host) FreeUploadCount() int32 {
 	return h.ConcurrentUploadLimit.Load() - h.ConcurrentUploadCount.Load()
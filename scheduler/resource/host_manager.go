@@ -24,8 +24,10 @@ import (
 
 	"d7y.io/dragonfly/v2/pkg/container/set"
 	pkggc "d7y.io/dragonfly/v2/pkg/gc"
+	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/metrics"
 )
 
 const (
@@ -58,18 +60,38 @@ type HostManager interface {
 
 	// Try to reclaim host.
 	RunGC() error
+
+	// OnNotify marks the hosts in maintenance by the scheduler cluster config.
+	OnNotify(*config.DynconfigData)
 }
 
 // hostManager contains content for host manager.
 type hostManager struct {
 	// Host sync map.
 	*sync.Map
+
+	// aliases maps the other id of the same machine to the id of the stored host,
+	// e.g. the legacy id announced by the old dfdaemon.
+	aliases *sync.Map
+
+	// machines maps the canonical id of the machine to the id of the stored host,
+	// so that the same machine stored with another id is loaded without range.
+	machines map[string]string
+
+	// maintenanceHosts is the hostnames and ips of the hosts in maintenance.
+	maintenanceHosts set.SafeSet[string]
+
+	// mu guards storing and deleting of hosts, aliases and machines.
+	mu sync.Mutex
 }
 
 // New host manager interface.
 func newHostManager(cfg *config.GCConfig, gc pkggc.GC) (HostManager, error) {
 	h := &hostManager{
-		Map: &sync.Map{},
+		Map:              &sync.Map{},
+		aliases:          &sync.Map{},
+		machines:         map[string]string{},
+		maintenanceHosts: set.NewSafeSet[string](),
 	}
 
 	if err := gc.Add(pkggc.Task{
@@ -88,28 +110,158 @@ func newHostManager(cfg *config.GCConfig, gc pkggc.GC) (HostManager, error) {
 func (h *hostManager) Load(key string) (*Host, bool) {
 	rawHost, loaded := h.Map.Load(key)
 	if !loaded {
-		return nil, false
+		id, ok := h.aliases.Load(key)
+		if !ok {
+			return nil, false
+		}
+
+		if rawHost, loaded = h.Map.Load(id); !loaded {
+			return nil, false
+		}
 	}
 
 	return rawHost.(*Host), loaded
 }
 
-// Store sets host.
+// Store sets host. If the same machine has been stored with another id,
+// the host is merged into the stored host and its id becomes an alias.
 func (h *hostManager) Store(host *Host) {
-	h.Map.Store(host.ID, host)
+	h.mu.Lock()
+	if stored, ok := h.loadSameHost(host); ok {
+		h.aliases.Store(host.ID, stored.ID)
+		h.mu.Unlock()
+
+		// The stored host is locked by itself, so merging does not block the other hosts.
+		stored.Log.Infof("merge host %s into the host with the same hostname and ip", host.ID)
+		stored.merge(host)
+		return
+	}
+	defer h.mu.Unlock()
+
+	h.markMaintenance(host)
+	if rawHost, loaded := h.Map.Swap(host.ID, host); loaded {
+		h.deleteMachine(rawHost.(*Host))
+		metrics.HostGauge.WithLabelValues(rawHost.(*Host).Type.Name()).Dec()
+	}
+
+	h.machines[idgen.HostID(host.Hostname, host.IP)] = host.ID
+	metrics.HostGauge.WithLabelValues(host.Type.Name()).Inc()
 }
 
 // LoadOrStore returns host the key if present.
 // Otherwise, it stores and returns the given host.
 // The loaded result is true if the host was loaded, false if stored.
 func (h *hostManager) LoadOrStore(host *Host) (*Host, bool) {
-	rawHost, loaded := h.Map.LoadOrStore(host.ID, host)
-	return rawHost.(*Host), loaded
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if stored, ok := h.Load(host.ID); ok {
+		return stored, true
+	}
+
+	if stored, ok := h.loadSameHost(host); ok {
+		h.aliases.Store(host.ID, stored.ID)
+		return stored, true
+	}
+
+	h.markMaintenance(host)
+	h.Map.Store(host.ID, host)
+	h.machines[idgen.HostID(host.Hostname, host.IP)] = host.ID
+	metrics.HostGauge.WithLabelValues(host.Type.Name()).Inc()
+	return host, false
 }
 
-// Delete deletes host for a key.
+// Delete deletes host for a key, the aliases of the host are deleted together.
 func (h *hostManager) Delete(key string) {
-	h.Map.Delete(key)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if id, ok := h.aliases.LoadAndDelete(key); ok {
+		key = id.(string)
+	}
+
+	if rawHost, loaded := h.Map.LoadAndDelete(key); loaded {
+		h.deleteMachine(rawHost.(*Host))
+		metrics.HostGauge.WithLabelValues(rawHost.(*Host).Type.Name()).Dec()
+	}
+
+	h.aliases.Range(func(alias, id any) bool {
+		if id == key {
+			h.aliases.Delete(alias)
+		}
+
+		return true
+	})
+}
+
+// OnNotify marks the hosts in maintenance by the scheduler cluster config,
+// the hosts not in the config are restored.
+func (h *hostManager) OnNotify(data *config.DynconfigData) {
+	clusterConfig, err := config.GetSchedulerClusterConfigByScheduler(data.Scheduler)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.maintenanceHosts.Clear()
+	for _, host := range clusterConfig.MaintenanceHosts {
+		h.maintenanceHosts.Add(host)
+	}
+
+	h.Map.Range(func(_, value any) bool {
+		host, ok := value.(*Host)
+		if !ok {
+			return true
+		}
+
+		h.markMaintenance(host)
+		return true
+	})
+}
+
+// markMaintenance marks the host in maintenance if its hostname or ip is in maintenance.
+func (h *hostManager) markMaintenance(host *Host) {
+	maintenance := h.maintenanceHosts.Contains(host.Hostname) || h.maintenanceHosts.Contains(host.IP)
+	if host.Maintenance.Swap(maintenance) != maintenance {
+		host.Log.Infof("host maintenance is %t", maintenance)
+	}
+}
+
+// loadSameHost loads the stored host which is the same machine as the given host but has
+// a different id. The machine is regarded as the same only when one of the ids is canonical,
+// e.g. the seed peer loaded from dynconfig and the host announced by the legacy dfdaemon.
+func (h *hostManager) loadSameHost(host *Host) (*Host, bool) {
+	id := idgen.HostID(host.Hostname, host.IP)
+	if host.ID != id {
+		rawHost, loaded := h.Map.Load(id)
+		if !loaded {
+			return nil, false
+		}
+
+		return rawHost.(*Host), true
+	}
+
+	storedID, ok := h.machines[id]
+	if !ok || storedID == host.ID {
+		return nil, false
+	}
+
+	rawHost, loaded := h.Map.Load(storedID)
+	if !loaded {
+		return nil, false
+	}
+
+	return rawHost.(*Host), true
+}
+
+// deleteMachine deletes the machine of the host if it still points to the host.
+func (h *hostManager) deleteMachine(host *Host) {
+	id := idgen.HostID(host.Hostname, host.IP)
+	if h.machines[id] == host.ID {
+		delete(h.machines, id)
+	}
 }
 
 // Range calls f sequentially for each key and value present in the map.
@@ -13,6 +13,7 @@ import (
 	reflect "reflect"
 
 	set "d7y.io/dragonfly/v2/pkg/container/set"
+	config "d7y.io/dragonfly/v2/scheduler/config"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -95,6 +96,18 @@ func (mr *MockHostManagerMockRecorder) LoadRandomHosts(arg0, arg1 any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LoadRandomHosts", reflect.TypeOf((*MockHostManager)(nil).LoadRandomHosts), arg0, arg1)
 }
 
+// OnNotify mocks base method.
+func (m *MockHostManager) OnNotify(arg0 *config.DynconfigData) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "OnNotify", arg0)
+}
+
+// OnNotify indicates an expected call of OnNotify.
+func (mr *MockHostManagerMockRecorder) OnNotify(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OnNotify", reflect.TypeOf((*MockHostManager)(nil).OnNotify), arg0)
+}
+
 // Range mocks base method.
 func (m *MockHostManager) Range(f func(any, any) bool) {
 	m.ctrl.T.Helper()
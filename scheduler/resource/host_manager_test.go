@@ -17,7 +17,9 @@
 package resource
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -26,9 +28,13 @@ import (
 	gomock "go.uber.org/mock/gomock"
 
 	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
+	managerv2 "d7y.io/api/v2/pkg/apis/manager/v2"
 
+	managertypes "d7y.io/dragonfly/v2/manager/types"
 	"d7y.io/dragonfly/v2/pkg/container/set"
 	"d7y.io/dragonfly/v2/pkg/gc"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
 )
 
@@ -308,6 +314,281 @@ func TestHostManager_Delete(t *testing.T) {
 	}
 }
 
+func TestHostManager_OnNotify(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(m *gc.MockGCMockRecorder)
+		expect func(t *testing.T, hostManager HostManager, mockHost *Host)
+	}{
+		{
+			name: "host is in maintenance by hostname",
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, hostManager HostManager, mockHost *Host) {
+				assert := assert.New(t)
+				hostManager.Store(mockHost)
+				hostManager.OnNotify(mockMaintenanceDynconfigData(mockHost.Hostname))
+				assert.True(mockHost.Maintenance.Load())
+			},
+		},
+		{
+			name: "host is in maintenance by ip",
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, hostManager HostManager, mockHost *Host) {
+				assert := assert.New(t)
+				hostManager.Store(mockHost)
+				hostManager.OnNotify(mockMaintenanceDynconfigData(mockHost.IP))
+				assert.True(mockHost.Maintenance.Load())
+			},
+		},
+		{
+			name: "host is stored after maintenance",
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, hostManager HostManager, mockHost *Host) {
+				assert := assert.New(t)
+				hostManager.OnNotify(mockMaintenanceDynconfigData(mockHost.Hostname))
+				host, loaded := hostManager.LoadOrStore(mockHost)
+				assert.False(loaded)
+				assert.True(host.Maintenance.Load())
+			},
+		},
+		{
+			name: "host is restored from maintenance",
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, hostManager HostManager, mockHost *Host) {
+				assert := assert.New(t)
+				hostManager.Store(mockHost)
+				hostManager.OnNotify(mockMaintenanceDynconfigData(mockHost.Hostname))
+				assert.True(mockHost.Maintenance.Load())
+
+				hostManager.OnNotify(mockMaintenanceDynconfigData())
+				assert.False(mockHost.Maintenance.Load())
+			},
+		},
+		{
+			name: "scheduler cluster config is invalid",
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, hostManager HostManager, mockHost *Host) {
+				assert := assert.New(t)
+				hostManager.Store(mockHost)
+				hostManager.OnNotify(mockMaintenanceDynconfigData(mockHost.Hostname))
+				hostManager.OnNotify(&config.DynconfigData{
+					Scheduler: &managerv2.Scheduler{
+						SchedulerCluster: &managerv2.SchedulerCluster{
+							Config: []byte("foo"),
+						},
+					},
+				})
+				assert.True(mockHost.Maintenance.Load())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			gc := gc.NewMockGC(ctl)
+			tc.mock(gc.EXPECT())
+
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			hostManager, err := newHostManager(mockHostGCConfig, gc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tc.expect(t, hostManager, mockHost)
+		})
+	}
+}
+
+// mockMaintenanceDynconfigData returns the dynconfig data with the hosts in maintenance.
+func mockMaintenanceDynconfigData(hosts ...string) *config.DynconfigData {
+	b, err := json.Marshal(managertypes.SchedulerClusterConfig{MaintenanceHosts: hosts})
+	if err != nil {
+		panic(err)
+	}
+
+	return &config.DynconfigData{
+		Scheduler: &managerv2.Scheduler{
+			SchedulerCluster: &managerv2.SchedulerCluster{
+				Config: b,
+			},
+		},
+	}
+}
+
+func TestHostManager_StoreSameHost(t *testing.T) {
+	var (
+		canonicalID = idgen.HostID("foo", "127.0.0.1")
+		legacyIDs   = []string{
+			idgen.HostIDV1("foo", 8002),
+			idgen.HostIDV1("foo", 65000),
+			"foo-127.0.0.1",
+		}
+	)
+
+	countHosts := func(hostManager HostManager) int {
+		var count int
+		hostManager.Range(func(_, _ any) bool {
+			count++
+			return true
+		})
+
+		return count
+	}
+
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, hostManager HostManager, legacyID string)
+	}{
+		{
+			name: "store legacy host after canonical seed host",
+			expect: func(t *testing.T, hostManager HostManager, legacyID string) {
+				assert := assert.New(t)
+				seedHost := NewHost(canonicalID, "127.0.0.1", "foo", 8002, 8001, types.HostTypeSuperSeed,
+					WithConcurrentUploadLimit(500))
+				hostManager.Store(seedHost)
+
+				announcedHost := NewHost(legacyID, "127.0.0.1", "foo", 65000, 65001, types.HostTypeNormal,
+					WithOS("linux"))
+				hostManager.Store(announcedHost)
+
+				assert.Equal(1, countHosts(hostManager))
+				host, loaded := hostManager.Load(legacyID)
+				assert.True(loaded)
+				assert.Same(seedHost, host)
+				assert.Equal(canonicalID, host.ID)
+				assert.Equal(types.HostTypeSuperSeed, host.Type)
+				assert.Equal(int32(500), host.ConcurrentUploadLimit.Load())
+				assert.Equal(int32(65000), host.Port)
+				assert.Equal(int32(65001), host.DownloadPort)
+				assert.Equal("linux", host.OS)
+			},
+		},
+		{
+			name: "store canonical seed host after legacy host",
+			expect: func(t *testing.T, hostManager HostManager, legacyID string) {
+				assert := assert.New(t)
+				announcedHost := NewHost(legacyID, "127.0.0.1", "foo", 65000, 65001, types.HostTypeNormal,
+					WithOS("linux"))
+				hostManager.Store(announcedHost)
+
+				seedHost := NewHost(canonicalID, "127.0.0.1", "foo", 8002, 8001, types.HostTypeSuperSeed,
+					WithConcurrentUploadLimit(500))
+				hostManager.Store(seedHost)
+
+				assert.Equal(1, countHosts(hostManager))
+				host, loaded := hostManager.Load(canonicalID)
+				assert.True(loaded)
+				assert.Same(announcedHost, host)
+				assert.Equal(types.HostTypeSuperSeed, host.Type)
+				assert.Equal(int32(500), host.ConcurrentUploadLimit.Load())
+				assert.Equal("linux", host.OS)
+			},
+		},
+		{
+			name: "load or store legacy host after canonical host",
+			expect: func(t *testing.T, hostManager HostManager, legacyID string) {
+				assert := assert.New(t)
+				canonicalHost := NewHost(canonicalID, "127.0.0.1", "foo", 8002, 8001, types.HostTypeNormal)
+				hostManager.Store(canonicalHost)
+
+				host, loaded := hostManager.LoadOrStore(NewHost(legacyID, "127.0.0.1", "foo", 65000, 65001, types.HostTypeNormal))
+				assert.True(loaded)
+				assert.Same(canonicalHost, host)
+				assert.Equal(1, countHosts(hostManager))
+			},
+		},
+		{
+			name: "delete host by alias",
+			expect: func(t *testing.T, hostManager HostManager, legacyID string) {
+				assert := assert.New(t)
+				hostManager.Store(NewHost(canonicalID, "127.0.0.1", "foo", 8002, 8001, types.HostTypeSuperSeed))
+				hostManager.Store(NewHost(legacyID, "127.0.0.1", "foo", 65000, 65001, types.HostTypeNormal))
+
+				hostManager.Delete(legacyID)
+				assert.Equal(0, countHosts(hostManager))
+				_, loaded := hostManager.Load(canonicalID)
+				assert.False(loaded)
+				_, loaded = hostManager.Load(legacyID)
+				assert.False(loaded)
+
+				hostManager.Store(NewHost(legacyID, "127.0.0.1", "foo", 65000, 65001, types.HostTypeNormal))
+				host, loaded := hostManager.Load(legacyID)
+				assert.True(loaded)
+				assert.Equal(legacyID, host.ID)
+			},
+		},
+		{
+			name: "store canonical host after legacy host is deleted",
+			expect: func(t *testing.T, hostManager HostManager, legacyID string) {
+				assert := assert.New(t)
+				hostManager.Store(NewHost(legacyID, "127.0.0.1", "foo", 65000, 65001, types.HostTypeNormal))
+				hostManager.Delete(legacyID)
+
+				canonicalHost := NewHost(canonicalID, "127.0.0.1", "foo", 8002, 8001, types.HostTypeSuperSeed)
+				hostManager.Store(canonicalHost)
+
+				assert.Equal(1, countHosts(hostManager))
+				host, loaded := hostManager.Load(canonicalID)
+				assert.True(loaded)
+				assert.Same(canonicalHost, host)
+			},
+		},
+		{
+			name: "store hosts with different hostname",
+			expect: func(t *testing.T, hostManager HostManager, legacyID string) {
+				assert := assert.New(t)
+				hostManager.Store(NewHost(canonicalID, "127.0.0.1", "foo", 8002, 8001, types.HostTypeSuperSeed))
+				hostManager.Store(NewHost(idgen.HostIDV1("bar", 8002), "127.0.0.1", "bar", 8002, 8001, types.HostTypeNormal))
+				hostManager.Store(NewHost(idgen.HostID("bar", "127.0.0.1"), "127.0.0.1", "bar", 8002, 8001, types.HostTypeNormal))
+
+				assert.Equal(2, countHosts(hostManager))
+			},
+		},
+		{
+			name: "store hosts without canonical id",
+			expect: func(t *testing.T, hostManager HostManager, legacyID string) {
+				assert := assert.New(t)
+				hostManager.Store(NewHost(legacyID, "127.0.0.1", "foo", 8002, 8001, types.HostTypeNormal))
+				hostManager.Store(NewHost(legacyID+"-bar", "127.0.0.1", "foo", 8003, 8001, types.HostTypeNormal))
+
+				assert.Equal(2, countHosts(hostManager))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		for _, legacyID := range legacyIDs {
+			t.Run(fmt.Sprintf("%s with legacy id %s", tc.name, legacyID), func(t *testing.T) {
+				ctl := gomock.NewController(t)
+				defer ctl.Finish()
+				gc := gc.NewMockGC(ctl)
+				gc.EXPECT().Add(gomock.Any()).Return(nil).Times(1)
+
+				hostManager, err := newHostManager(mockHostGCConfig, gc)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				tc.expect(t, hostManager, legacyID)
+			})
+		}
+	}
+}
+
 func TestHostManager_LoadRandomHosts(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -717,6 +717,60 @@ func TestHost_DeletePeer(t *testing.T) {
 	}
 }
 
+func TestHost_EvictPeer(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, host *Host)
+	}{
+		{
+			name: "peer is evicted",
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				host.EvictPeer(mockPeerID)
+				assert.True(host.IsPeerEvicted(mockPeerID))
+				assert.False(host.IsPeerEvicted(mockSeedPeerID))
+			},
+		},
+		{
+			name: "peer is not evicted",
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				assert.False(host.IsPeerEvicted(mockPeerID))
+			},
+		},
+		{
+			name: "eviction of peer expires",
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				host.evictedPeers.Store(mockPeerID, time.Now().Add(-EvictedPeerTTL-time.Second))
+				assert.False(host.IsPeerEvicted(mockPeerID))
+				_, loaded := host.evictedPeers.Load(mockPeerID)
+				assert.False(loaded)
+			},
+		},
+		{
+			name: "expired evictions are removed when peer is evicted",
+			expect: func(t *testing.T, host *Host) {
+				assert := assert.New(t)
+				host.evictedPeers.Store(mockSeedPeerID, time.Now().Add(-EvictedPeerTTL-time.Second))
+				host.EvictPeer(mockPeerID)
+				_, loaded := host.evictedPeers.Load(mockSeedPeerID)
+				assert.False(loaded)
+				assert.True(host.IsPeerEvicted(mockPeerID))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			tc.expect(t, host)
+		})
+	}
+}
+
 func TestHost_LeavePeers(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -767,6 +821,56 @@ func TestHost_LeavePeers(t *testing.T) {
 	}
 }
 
+func TestHost_ResetUploadCounts(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, host *Host, mockTask *Task, mockPeer *Peer)
+	}{
+		{
+			name: "reset upload counts and recount uploads of peers in host",
+			expect: func(t *testing.T, host *Host, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				mockSeedPeer := NewPeer(mockSeedPeerID, mockResourceConfig, mockTask, host)
+				mockTask.StorePeer(mockSeedPeer)
+				mockTask.StorePeer(mockPeer)
+				host.StorePeer(mockSeedPeer)
+				assert.NoError(mockTask.AddPeerEdge(mockSeedPeer, mockPeer))
+				host.ConcurrentUploadCount.Add(2)
+				host.UploadFailedCount.Inc()
+
+				host.ResetUploadCounts()
+				assert.Equal(int32(1), host.ConcurrentUploadCount.Load())
+				assert.Equal(int64(0), host.UploadCount.Load())
+				assert.Equal(int64(0), host.UploadFailedCount.Load())
+			},
+		},
+		{
+			name: "reset upload counts of host without peers",
+			expect: func(t *testing.T, host *Host, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				host.ConcurrentUploadCount.Inc()
+				host.UploadCount.Inc()
+
+				host.ResetUploadCounts()
+				assert.Equal(int32(0), host.ConcurrentUploadCount.Load())
+				assert.Equal(int64(0), host.UploadCount.Load())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			host := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			mockPeer := NewPeer(mockPeerID, mockResourceConfig, mockTask, host)
+
+			tc.expect(t, host, mockTask, mockPeer)
+		})
+	}
+}
+
 func TestHost_FreeUploadCount(t *testing.T) {
 	tests := []struct {
 		name    string
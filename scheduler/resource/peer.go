@@ -19,6 +19,7 @@ package resource
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -38,16 +39,53 @@ import (
 	schedulerv2 "d7y.io/api/v2/pkg/apis/scheduler/v2"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/cache"
 	"d7y.io/dragonfly/v2/pkg/container/set"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
 	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/metrics"
 )
 
 const (
 	// Download tiny file timeout.
 	downloadTinyFileContextTimeout = 30 * time.Second
+
+	// PeerDecisionLimit is the max number of recent scheduling decisions kept by peer.
+	PeerDecisionLimit = 16
 )
 
+const (
+	// Scheduler sends parents to peer.
+	PeerDecisionScheduled = "Scheduled"
+
+	// Scheduler makes peer download back-to-source.
+	PeerDecisionBackToSource = "BackToSource"
+
+	// Scheduler fails to schedule peer.
+	PeerDecisionFailed = "Failed"
+)
+
+// PeerDecision is the scheduling decision made for peer.
+type PeerDecision struct {
+	// Type is the type of decision.
+	Type string `json:"type"`
+
+	// ParentIDs is the ids of the scheduled parents.
+	ParentIDs []string `json:"parentIDs,omitempty"`
+
+	// Reason is the reason of decision.
+	Reason string `json:"reason,omitempty"`
+
+	// Retries is the number of failed scheduling before the decision.
+	Retries int `json:"retries"`
+
+	// Rejections is the count of the candidate parents filtered out by reason in the latest scheduling.
+	Rejections map[string]int `json:"rejections,omitempty"`
+
+	// CreatedAt is decision create time.
+	CreatedAt time.Time `json:"createdAt"`
+}
+
 const (
 	// Peer has been created but did not start running.
 	PeerStatePending = "Pending"
@@ -93,6 +131,9 @@ const (
 	// Peer is registered as normal scope size.
 	PeerEventRegisterNormal = "RegisterNormal"
 
+	// Peer is registered as normal scope size with the finished pieces in local storage.
+	PeerEventRestore = "Restore"
+
 	// Peer is downloading.
 	PeerEventDownload = "Download"
 
@@ -133,6 +174,13 @@ func WithRange(rg nethttp.Range) PeerOption {
 	}
 }
 
+// WithPreferSeed set PreferSeed for peer.
+func WithPreferSeed(preferSeed bool) PeerOption {
+	return func(p *Peer) {
+		p.PreferSeed = preferSeed
+	}
+}
+
 // Peer contains content for peer.
 type Peer struct {
 	// ID is peer id.
@@ -147,6 +195,10 @@ type Peer struct {
 	// Priority is peer priority.
 	Priority commonv2.Priority
 
+	// PreferSeed is whether the seed peers are preferred to the normal peers as parents,
+	// for the low latency of the first byte.
+	PreferSeed bool
+
 	// Piece sync map.
 	Pieces *sync.Map
 
@@ -163,10 +215,18 @@ type Peer struct {
 	// Used only in v1 version of the grpc.
 	ReportPieceResultStream *atomic.Value
 
+	// reportPieceResultStreamMu serializes the sends over ReportPieceResultStream,
+	// the scheduling and the background services send to the same stream.
+	reportPieceResultStreamMu *sync.Mutex
+
 	// AnnouncePeerStream is the grpc stream of Scheduler_AnnouncePeerServer,
 	// Used only in v2 version of the grpc.
 	AnnouncePeerStream *atomic.Value
 
+	// announcePeerStreamMu serializes the sends over AnnouncePeerStream,
+	// the AnnouncePeer handler and the background services send to the same stream.
+	announcePeerStreamMu *sync.Mutex
+
 	// Task state machine.
 	FSM *fsm.FSM
 
@@ -176,8 +236,37 @@ type Peer struct {
 	// Host is peer host.
 	Host *Host
 
-	// BlockParents is bad parents ids.
-	BlockParents set.SafeSet[string]
+	// decisions is the ring of recent scheduling decisions,
+	// it holds up to PeerDecisionLimit decisions.
+	decisions   []PeerDecision
+	decisionsMu *sync.RWMutex
+
+	// parentRejections is the count of the candidate parents filtered out by reason
+	// in the latest scheduling, it is guarded by decisionsMu.
+	parentRejections map[string]int
+
+	// BlockParents is bad parents ids with the time they are blocked,
+	// the blocked parents expire after BlockParentTTL of peer config.
+	BlockParents cache.Cache
+
+	// slowParentWindows is the number of consecutive windows in which the peer
+	// downloads slowly from the parent, keyed by the parent id.
+	slowParentWindows *sync.Map
+
+	// parentHosts is the time the peer last downloaded a piece from the parent host
+	// successfully, keyed by the parent host id.
+	parentHosts *sync.Map
+
+	// ParentSwitchCount is the count of replacing the parent of the peer with a different one.
+	ParentSwitchCount *atomic.Int32
+
+	// parentID is the id of the parent the peer is scheduled to,
+	// parentSwitches is the time of the parent switches within ParentSwitchWindow
+	// of peer config and pinnedUntil is the time the pin to the parent expires.
+	parentID       string
+	parentSwitches []time.Time
+	pinnedUntil    time.Time
+	parentMu       *sync.Mutex
 
 	// NeedBackToSource needs downloaded from source.
 	//
@@ -186,6 +275,20 @@ type Peer struct {
 	// NeedBackToSource is set to true.
 	NeedBackToSource *atomic.Bool
 
+	// PieceViolationCount is the count of impossible piece results reported by the peer.
+	PieceViolationCount *atomic.Int32
+
+	// ReportedFinishedCount is the latest finished piece count reported by the peer.
+	ReportedFinishedCount *atomic.Int32
+
+	// Quarantined is set to true when the peer reports too many impossible piece results,
+	// the quarantined peer can still download, but it is not selected as a parent.
+	Quarantined *atomic.Bool
+
+	// PieceCodec is the codec of piece compression negotiated with the peer, it is empty
+	// if piece compression is disabled, then the parents are not required to support it.
+	PieceCodec *atomic.String
+
 	// PieceUpdatedAt is piece update time.
 	PieceUpdatedAt *atomic.Time
 
@@ -195,6 +298,16 @@ type Peer struct {
 	// UpdatedAt is peer update time.
 	UpdatedAt *atomic.Time
 
+	// RescheduledPeersVersion is the peers version of the task when the peer is
+	// evaluated by the rescheduler last time.
+	RescheduledPeersVersion *atomic.Uint64
+
+	// stateEnteredAt is the time the peer entered the current state.
+	stateEnteredAt *atomic.Time
+
+	// stateGauge counts the peer stored in the peer manager by state.
+	stateGauge *stateGauge
+
 	// Peer log.
 	Log *logger.SugaredLoggerOnWith
 }
@@ -202,23 +315,39 @@ type Peer struct {
 // New Peer instance.
 func NewPeer(id string, cfg *config.ResourceConfig, task *Task, host *Host, options ...PeerOption) *Peer {
 	p := &Peer{
-		ID:                      id,
-		Config:                  cfg,
-		Priority:                commonv2.Priority_LEVEL0,
-		Pieces:                  &sync.Map{},
-		FinishedPieces:          &bitset.BitSet{},
-		pieceCosts:              []time.Duration{},
-		Cost:                    atomic.NewDuration(0),
-		ReportPieceResultStream: &atomic.Value{},
-		AnnouncePeerStream:      &atomic.Value{},
-		Task:                    task,
-		Host:                    host,
-		BlockParents:            set.NewSafeSet[string](),
-		NeedBackToSource:        atomic.NewBool(false),
-		PieceUpdatedAt:          atomic.NewTime(time.Now()),
-		CreatedAt:               atomic.NewTime(time.Now()),
-		UpdatedAt:               atomic.NewTime(time.Now()),
-		Log:                     logger.WithPeer(host.ID, task.ID, id),
+		ID:                        id,
+		Config:                    cfg,
+		Priority:                  commonv2.Priority_LEVEL0,
+		Pieces:                    &sync.Map{},
+		FinishedPieces:            &bitset.BitSet{},
+		pieceCosts:                []time.Duration{},
+		Cost:                      atomic.NewDuration(0),
+		ReportPieceResultStream:   &atomic.Value{},
+		reportPieceResultStreamMu: &sync.Mutex{},
+		AnnouncePeerStream:        &atomic.Value{},
+		announcePeerStreamMu:      &sync.Mutex{},
+		Task:                      task,
+		Host:                      host,
+		decisions:                 []PeerDecision{},
+		decisionsMu:               &sync.RWMutex{},
+		BlockParents:              cache.New(cfg.Peer.BlockParentTTL, cache.NoCleanup),
+		slowParentWindows:         &sync.Map{},
+		parentHosts:               &sync.Map{},
+		ParentSwitchCount:         atomic.NewInt32(0),
+		parentSwitches:            []time.Time{},
+		parentMu:                  &sync.Mutex{},
+		NeedBackToSource:          atomic.NewBool(false),
+		PieceViolationCount:       atomic.NewInt32(0),
+		ReportedFinishedCount:     atomic.NewInt32(0),
+		Quarantined:               atomic.NewBool(false),
+		PieceCodec:                atomic.NewString(""),
+		PieceUpdatedAt:            atomic.NewTime(time.Now()),
+		CreatedAt:                 atomic.NewTime(time.Now()),
+		UpdatedAt:                 atomic.NewTime(time.Now()),
+		RescheduledPeersVersion:   atomic.NewUint64(0),
+		stateEnteredAt:            atomic.NewTime(time.Now()),
+		stateGauge:                newStateGauge(metrics.PeerGauge),
+		Log:                       logger.WithPeer(host.ID, task.ID, id),
 	}
 
 	// Initialize state machine.
@@ -229,6 +358,7 @@ func NewPeer(id string, cfg *config.ResourceConfig, task *Task, host *Host, opti
 			{Name: PeerEventRegisterTiny, Src: []string{PeerStatePending}, Dst: PeerStateReceivedTiny},
 			{Name: PeerEventRegisterSmall, Src: []string{PeerStatePending}, Dst: PeerStateReceivedSmall},
 			{Name: PeerEventRegisterNormal, Src: []string{PeerStatePending}, Dst: PeerStateReceivedNormal},
+			{Name: PeerEventRestore, Src: []string{PeerStatePending}, Dst: PeerStateRunning},
 			{Name: PeerEventDownload, Src: []string{PeerStateReceivedEmpty, PeerStateReceivedTiny, PeerStateReceivedSmall, PeerStateReceivedNormal}, Dst: PeerStateRunning},
 			{Name: PeerEventDownloadBackToSource, Src: []string{PeerStateReceivedEmpty, PeerStateReceivedTiny, PeerStateReceivedSmall, PeerStateReceivedNormal, PeerStateRunning}, Dst: PeerStateBackToSource},
 			{Name: PeerEventDownloadSucceeded, Src: []string{
@@ -263,12 +393,16 @@ func NewPeer(id string, cfg *config.ResourceConfig, task *Task, host *Host, opti
 				p.UpdatedAt.Store(time.Now())
 				p.Log.Infof("peer state is %s", e.FSM.Current())
 			},
+			PeerEventRestore: func(ctx context.Context, e *fsm.Event) {
+				p.UpdatedAt.Store(time.Now())
+				p.Log.Infof("peer state is %s with %d finished pieces", e.FSM.Current(), p.FinishedPieces.Count())
+			},
 			PeerEventDownload: func(ctx context.Context, e *fsm.Event) {
 				p.UpdatedAt.Store(time.Now())
 				p.Log.Infof("peer state is %s", e.FSM.Current())
 			},
 			PeerEventDownloadBackToSource: func(ctx context.Context, e *fsm.Event) {
-				p.Task.BackToSourcePeers.Add(p.ID)
+				p.Task.AddBackToSourcePeer(p.ID)
 
 				if err := p.Task.DeletePeerInEdges(p.ID); err != nil {
 					p.Log.Errorf("delete peer inedges failed: %s", err.Error())
@@ -279,13 +413,14 @@ func NewPeer(id string, cfg *config.ResourceConfig, task *Task, host *Host, opti
 			},
 			PeerEventDownloadSucceeded: func(ctx context.Context, e *fsm.Event) {
 				if e.Src == PeerStateBackToSource {
-					p.Task.BackToSourcePeers.Delete(p.ID)
+					p.Task.DeleteBackToSourcePeer(p.ID)
 				}
 
 				if err := p.Task.DeletePeerInEdges(p.ID); err != nil {
 					p.Log.Errorf("delete peer inedges failed: %s", err.Error())
 				}
 
+				metrics.PeerParentSwitchCount.Observe(float64(p.ParentSwitchCount.Load()))
 				p.Task.PeerFailedCount.Store(0)
 				p.UpdatedAt.Store(time.Now())
 				p.Log.Infof("peer state is %s", e.FSM.Current())
@@ -293,7 +428,7 @@ func NewPeer(id string, cfg *config.ResourceConfig, task *Task, host *Host, opti
 			PeerEventDownloadFailed: func(ctx context.Context, e *fsm.Event) {
 				if e.Src == PeerStateBackToSource {
 					p.Task.PeerFailedCount.Inc()
-					p.Task.BackToSourcePeers.Delete(p.ID)
+					p.Task.DeleteBackToSourcePeer(p.ID)
 				}
 
 				if err := p.Task.DeletePeerInEdges(p.ID); err != nil {
@@ -308,9 +443,15 @@ func NewPeer(id string, cfg *config.ResourceConfig, task *Task, host *Host, opti
 					p.Log.Errorf("delete peer inedges failed: %s", err.Error())
 				}
 
-				p.Task.BackToSourcePeers.Delete(p.ID)
+				p.Task.DeleteBackToSourcePeer(p.ID)
 				p.Log.Infof("peer state is %s", e.FSM.Current())
 			},
+			"enter_state": func(ctx context.Context, e *fsm.Event) {
+				stateEnteredAt := p.stateEnteredAt.Load()
+				p.stateEnteredAt.Store(time.Now())
+				metrics.PeerStateDuration.WithLabelValues(e.Src).Observe(time.Since(stateEnteredAt).Seconds())
+				p.Task.peersVersion.Inc()
+			},
 		},
 	)
 
@@ -331,6 +472,85 @@ func (p *Peer) PieceCosts() []time.Duration {
 	return p.pieceCosts
 }
 
+// AppendDecision appends scheduling decision to peer, the oldest
+// decision is dropped when the number of decisions exceeds PeerDecisionLimit.
+func (p *Peer) AppendDecision(decision PeerDecision) {
+	if decision.CreatedAt.IsZero() {
+		decision.CreatedAt = time.Now()
+	}
+
+	p.decisionsMu.Lock()
+	defer p.decisionsMu.Unlock()
+
+	if len(p.decisions) >= PeerDecisionLimit {
+		p.decisions = append(p.decisions[:0], p.decisions[len(p.decisions)-PeerDecisionLimit+1:]...)
+	}
+	p.decisions = append(p.decisions, decision)
+}
+
+// Decisions returns a copy of recent scheduling decisions, ordered from the oldest to the newest.
+func (p *Peer) Decisions() []PeerDecision {
+	p.decisionsMu.RLock()
+	defer p.decisionsMu.RUnlock()
+
+	decisions := make([]PeerDecision, len(p.decisions))
+	copy(decisions, p.decisions)
+	return decisions
+}
+
+// AddPieceViolation increases the count of impossible piece results, the peer is quarantined
+// when the count reaches the limit. It returns true only when the peer is newly quarantined.
+func (p *Peer) AddPieceViolation(limit int) bool {
+	if p.PieceViolationCount.Inc() < int32(limit) {
+		return false
+	}
+
+	return p.Quarantined.CompareAndSwap(false, true)
+}
+
+// StoreParentRejections stores the count of the candidate parents filtered out by reason in the latest scheduling.
+func (p *Peer) StoreParentRejections(rejections map[string]int) {
+	p.decisionsMu.Lock()
+	defer p.decisionsMu.Unlock()
+
+	p.parentRejections = rejections
+}
+
+// ParentRejections returns a copy of the count of the candidate parents filtered out by reason in the latest scheduling.
+func (p *Peer) ParentRejections() map[string]int {
+	p.decisionsMu.RLock()
+	defer p.decisionsMu.RUnlock()
+
+	if len(p.parentRejections) == 0 {
+		return nil
+	}
+
+	rejections := make(map[string]int, len(p.parentRejections))
+	for reason, count := range p.parentRejections {
+		rejections[reason] = count
+	}
+
+	return rejections
+}
+
+// BlockParent blocks the parent, it is not selected as the parent
+// of the peer until it expires.
+func (p *Peer) BlockParent(id string) {
+	p.BlockParents.SetDefault(id, time.Now())
+}
+
+// LoadBlockParents returns ids of the blocked parents which are not expired.
+func (p *Peer) LoadBlockParents() set.SafeSet[string] {
+	p.BlockParents.DeleteExpired()
+
+	blockParents := set.NewSafeSet[string]()
+	for id := range p.BlockParents.Items() {
+		blockParents.Add(id)
+	}
+
+	return blockParents
+}
+
 // LoadReportPieceResultStream return the grpc stream of Scheduler_ReportPieceResultServer,
 // Used only in v1 version of the grpc.
 func (p *Peer) LoadReportPieceResultStream() (schedulerv1.Scheduler_ReportPieceResultServer, bool) {
@@ -354,6 +574,20 @@ func (p *Peer) DeleteReportPieceResultStream() {
 	p.ReportPieceResultStream = &atomic.Value{}
 }
 
+// SendPeerPacket sends the packet over the grpc stream of Scheduler_ReportPieceResultServer,
+// the sends are serialized because the grpc stream does not support concurrent sends.
+// Used only in v1 version of the grpc.
+func (p *Peer) SendPeerPacket(packet *schedulerv1.PeerPacket) error {
+	stream, loaded := p.LoadReportPieceResultStream()
+	if !loaded {
+		return errors.New("load stream failed")
+	}
+
+	p.reportPieceResultStreamMu.Lock()
+	defer p.reportPieceResultStreamMu.Unlock()
+	return stream.Send(packet)
+}
+
 // LoadAnnouncePeerStream return the grpc stream of Scheduler_AnnouncePeerServer,
 // Used only in v2 version of the grpc.
 func (p *Peer) LoadAnnouncePeerStream() (schedulerv2.Scheduler_AnnouncePeerServer, bool) {
@@ -377,6 +611,20 @@ func (p *Peer) DeleteAnnouncePeerStream() {
 	p.AnnouncePeerStream = &atomic.Value{}
 }
 
+// SendAnnouncePeerResponse sends the response over the grpc stream of Scheduler_AnnouncePeerServer,
+// the sends are serialized because the grpc stream does not support concurrent sends.
+// Used only in v2 version of the grpc.
+func (p *Peer) SendAnnouncePeerResponse(resp *schedulerv2.AnnouncePeerResponse) error {
+	stream, loaded := p.LoadAnnouncePeerStream()
+	if !loaded {
+		return errors.New("load stream failed")
+	}
+
+	p.announcePeerStreamMu.Lock()
+	defer p.announcePeerStreamMu.Unlock()
+	return stream.Send(resp)
+}
+
 // LoadPiece return piece for a key.
 func (p *Peer) LoadPiece(key int32) (*Piece, bool) {
 	rawPiece, loaded := p.Pieces.Load(key)
@@ -397,6 +645,151 @@ func (p *Peer) DeletePiece(key int32) {
 	p.Pieces.Delete(key)
 }
 
+// ParentThroughput returns the bytes per second downloaded from the parent
+// within the window, it is calculated by the finished pieces of the peer.
+func (p *Peer) ParentThroughput(parentID string, window time.Duration) float64 {
+	if window <= 0 {
+		return 0
+	}
+
+	var (
+		length uint64
+		since  = time.Now().Add(-window)
+	)
+	p.Pieces.Range(func(_, value any) bool {
+		piece, ok := value.(*Piece)
+		if !ok {
+			return true
+		}
+
+		if piece.ParentID == parentID && piece.CreatedAt.After(since) {
+			length += piece.Length
+		}
+
+		return true
+	})
+
+	return float64(length) / window.Seconds()
+}
+
+// AddSlowParentWindow increases the number of consecutive windows in which
+// the peer downloads slowly from the parent, and returns the number.
+func (p *Peer) AddSlowParentWindow(parentID string) int {
+	n := 1
+	if rawN, loaded := p.slowParentWindows.Load(parentID); loaded {
+		n += rawN.(int)
+	}
+
+	p.slowParentWindows.Store(parentID, n)
+	return n
+}
+
+// ResetSlowParentWindow resets the number of consecutive windows in which
+// the peer downloads slowly from the parent.
+func (p *Peer) ResetSlowParentWindow(parentID string) {
+	p.slowParentWindows.Delete(parentID)
+}
+
+// StoreParentHost records that the peer downloaded a piece from the parent host successfully,
+// the parent hosts out of the affinity window are forgotten.
+func (p *Peer) StoreParentHost(hostID string) {
+	window := p.Config.Peer.ParentAffinityWindow
+	if window <= 0 {
+		return
+	}
+
+	now := time.Now()
+	p.parentHosts.Store(hostID, now)
+	p.parentHosts.Range(func(key, value any) bool {
+		if now.Sub(value.(time.Time)) > window {
+			p.parentHosts.Delete(key)
+		}
+
+		return true
+	})
+}
+
+// ParentHostAffinity returns the affinity of the peer to the parent host between 0 and 1,
+// it is 1 when the peer just downloaded a piece from the parent host successfully and decays
+// linearly to 0 over the affinity window.
+func (p *Peer) ParentHostAffinity(hostID string) float64 {
+	window := p.Config.Peer.ParentAffinityWindow
+	if window <= 0 {
+		return 0
+	}
+
+	rawUpdatedAt, loaded := p.parentHosts.Load(hostID)
+	if !loaded {
+		return 0
+	}
+
+	elapsed := time.Since(rawUpdatedAt.(time.Time))
+	if elapsed >= window {
+		return 0
+	}
+
+	return 1 - float64(elapsed)/float64(window)
+}
+
+// StoreParent stores the parent the peer is scheduled to, it returns true if the parent replaces
+// a different one. When the peer switches parents more than ParentSwitchLimit times within
+// ParentSwitchWindow, the peer is pinned to the parent for ParentPinCooldown.
+func (p *Peer) StoreParent(parentID string) bool {
+	p.parentMu.Lock()
+	defer p.parentMu.Unlock()
+
+	if p.parentID == parentID {
+		return false
+	}
+
+	previousID := p.parentID
+	p.parentID = parentID
+	if previousID == "" {
+		return false
+	}
+
+	// The pin is released, because the parent is replaced only if
+	// the pinned parent can not be scheduled.
+	p.pinnedUntil = time.Time{}
+	p.ParentSwitchCount.Inc()
+
+	limit := p.Config.Peer.ParentSwitchLimit
+	if limit <= 0 {
+		return true
+	}
+
+	now := time.Now()
+	parentSwitches := p.parentSwitches[:0]
+	for _, switchedAt := range p.parentSwitches {
+		if now.Sub(switchedAt) < p.Config.Peer.ParentSwitchWindow {
+			parentSwitches = append(parentSwitches, switchedAt)
+		}
+	}
+	p.parentSwitches = append(parentSwitches, now)
+
+	if len(p.parentSwitches) > limit {
+		p.pinnedUntil = now.Add(p.Config.Peer.ParentPinCooldown)
+		p.parentSwitches = p.parentSwitches[:0]
+		p.Log.Warnf("peer switches parents %d times within %s, pin to parent %s until %s",
+			limit+1, p.Config.Peer.ParentSwitchWindow, parentID, p.pinnedUntil.Format(time.RFC3339))
+	}
+
+	return true
+}
+
+// PinnedParent returns the id of the parent the peer is pinned to,
+// it returns false if the peer is not pinned or the pin expires.
+func (p *Peer) PinnedParent() (string, bool) {
+	p.parentMu.Lock()
+	defer p.parentMu.Unlock()
+
+	if p.pinnedUntil.IsZero() || time.Now().After(p.pinnedUntil) {
+		return "", false
+	}
+
+	return p.parentID, true
+}
+
 // Parents returns parents of peer.
 func (p *Peer) Parents() []*Peer {
 	vertex, err := p.Task.DAG.GetVertex(p.ID)
@@ -437,7 +830,9 @@ func (p *Peer) Children() []*Peer {
 	return children
 }
 
-// DownloadTinyFile downloads tiny file from peer without range.
+// DownloadTinyFile downloads tiny file from peer without range. The content may
+// be returned in several responses, so it reads the remaining range until the
+// content length is reached.
 func (p *Peer) DownloadTinyFile() ([]byte, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), downloadTinyFileContextTimeout)
 	defer cancel()
@@ -452,14 +847,6 @@ func (p *Peer) DownloadTinyFile() ([]byte, error) {
 		RawQuery: fmt.Sprintf("peerId=%s", p.ID),
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL.String(), nil)
-	if err != nil {
-		return []byte{}, err
-	}
-
-	req.Header.Set(headers.Range, fmt.Sprintf("bytes=%d-%d", 0, p.Task.ContentLength.Load()-1))
-	p.Log.Infof("download tiny file %s, header is : %#v", targetURL.String(), req.Header)
-
 	client := &http.Client{
 		Timeout: p.Config.Task.DownloadTiny.Timeout,
 		Transport: &http.Transport{
@@ -467,6 +854,37 @@ func (p *Peer) DownloadTinyFile() ([]byte, error) {
 		},
 	}
 
+	var (
+		contentLength = p.Task.ContentLength.Load()
+		data          []byte
+	)
+	for {
+		chunk, err := p.downloadTinyFileRange(ctx, client, targetURL.String(), int64(len(data)), contentLength-1)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(chunk) == 0 {
+			return nil, fmt.Errorf("unexpected EOF at offset %d, content length is %d", len(data), contentLength)
+		}
+
+		data = append(data, chunk...)
+		if int64(len(data)) >= contentLength {
+			return data, nil
+		}
+	}
+}
+
+// downloadTinyFileRange downloads the range [start, end] of tiny file from peer.
+func (p *Peer) downloadTinyFileRange(ctx context.Context, client *http.Client, targetURL string, start, end int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set(headers.Range, fmt.Sprintf("bytes=%d-%d", start, end))
+	p.Log.Infof("download tiny file %s, header is : %#v", targetURL, req.Header)
+
 	resp, err := client.Do(req)
 	if err != nil {
 		return nil, err
@@ -480,7 +898,13 @@ func (p *Peer) DownloadTinyFile() ([]byte, error) {
 		return nil, fmt.Errorf("bad response status %s", resp.Status)
 	}
 
-	return io.ReadAll(resp.Body)
+	// If the range is ignored, the body contains the whole content
+	// and it can not be appended to the downloaded content.
+	if start > 0 && resp.StatusCode != http.StatusPartialContent {
+		return nil, fmt.Errorf("range is not satisfied, response status %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, end-start+1))
 }
 
 // CalculatePriority returns priority of peer.
@@ -23,6 +23,9 @@ import (
 	"sync"
 	"time"
 
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+
 	pkggc "d7y.io/dragonfly/v2/pkg/gc"
 	"d7y.io/dragonfly/v2/scheduler/config"
 )
@@ -64,12 +67,19 @@ type peerManager struct {
 	// peerTTL is time to live of peer.
 	peerTTL time.Duration
 
+	// applicationPeerTTLs is time to live of peers by the application of the task,
+	// it overrides peerTTL.
+	applicationPeerTTLs map[string]time.Duration
+
 	// hostTTL is time to live of host.
 	hostTTL time.Duration
 
 	// pieceDownloadTimeout is timeout of downloading piece.
 	pieceDownloadTimeout time.Duration
 
+	// taskDownloadTimeout is timeout of downloading task.
+	taskDownloadTimeout time.Duration
+
 	// mu is peer mutex.
 	mu *sync.Mutex
 }
@@ -79,8 +89,10 @@ func newPeerManager(cfg *config.GCConfig, gc pkggc.GC) (PeerManager, error) {
 	p := &peerManager{
 		Map:                  &sync.Map{},
 		peerTTL:              cfg.PeerTTL,
+		applicationPeerTTLs:  cfg.ApplicationPeerTTLs,
 		hostTTL:              cfg.HostTTL,
 		pieceDownloadTimeout: cfg.PieceDownloadTimeout,
+		taskDownloadTimeout:  cfg.TaskDownloadTimeout,
 		mu:                   &sync.Mutex{},
 	}
 
@@ -111,7 +123,11 @@ func (p *peerManager) Store(peer *Peer) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.Map.Store(peer.ID, peer)
+	if rawPeer, loaded := p.Map.Swap(peer.ID, peer); loaded {
+		rawPeer.(*Peer).stateGauge.delete()
+	}
+
+	peer.stateGauge.store(peer.FSM.Current())
 	peer.Task.StorePeer(peer)
 	peer.Host.StorePeer(peer)
 }
@@ -125,6 +141,7 @@ func (p *peerManager) LoadOrStore(peer *Peer) (*Peer, bool) {
 
 	rawPeer, loaded := p.Map.LoadOrStore(peer.ID, peer)
 	if !loaded {
+		peer.stateGauge.store(peer.FSM.Current())
 		peer.Host.StorePeer(peer)
 		peer.Task.StorePeer(peer)
 	}
@@ -139,6 +156,7 @@ func (p *peerManager) Delete(key string) {
 
 	if peer, loaded := p.Load(key); loaded {
 		p.Map.Delete(key)
+		peer.stateGauge.delete()
 		peer.Task.DeletePeer(key)
 		peer.Host.DeletePeer(key)
 	}
@@ -159,6 +177,10 @@ func (p *peerManager) RunGC() error {
 			return true
 		}
 
+		// The peer is counted by the state it is in now,
+		// the state transitions are counted in the gc.
+		peer.stateGauge.update(peer.FSM.Current())
+
 		// If the peer state is PeerStateLeave,
 		// peer will be reclaimed.
 		if peer.FSM.Is(PeerStateLeave) {
@@ -167,6 +189,17 @@ func (p *peerManager) RunGC() error {
 			return true
 		}
 
+		// If the downloading peer has not downloaded any piece within the taskDownloadTimeout,
+		// then fails the peer and the task, the failed peer leaves in the next gc.
+		if p.taskDownloadTimeout > 0 && isDownloading(peer) {
+			elapsed := time.Since(peer.PieceUpdatedAt.Load())
+			if elapsed > p.taskDownloadTimeout {
+				peer.Log.Info("peer elapsed exceeds the timeout of downloading task, causing the peer to fail")
+				p.failDownload(peer)
+				return true
+			}
+		}
+
 		// If the peer's elapsed of downloading piece exceeds the pieceDownloadTimeout,
 		// then sets the peer state to PeerStateLeave and then delete peer.
 		if peer.FSM.Is(PeerStateRunning) || peer.FSM.Is(PeerStateBackToSource) {
@@ -185,7 +218,7 @@ func (p *peerManager) RunGC() error {
 		// If the peer's elapsed exceeds the peer ttl,
 		// then set the peer state to PeerStateLeave and then delete peer.
 		elapsed := time.Since(peer.UpdatedAt.Load())
-		if elapsed > p.peerTTL {
+		if elapsed > p.peerTTLOf(peer) {
 			peer.Log.Info("peer elapsed exceeds the peer ttl, causing the peer to leave")
 			if err := peer.FSM.Event(context.Background(), PeerEventLeave); err != nil {
 				peer.Log.Errorf("peer fsm event failed: %s", err.Error())
@@ -248,3 +281,49 @@ func (p *peerManager) RunGC() error {
 
 	return nil
 }
+
+// peerTTLOf returns time to live of the peer, the peer ttl of the application
+// of the task is used first.
+func (p *peerManager) peerTTLOf(peer *Peer) time.Duration {
+	if ttl, ok := p.applicationPeerTTLs[peer.Task.Application]; ok {
+		return ttl
+	}
+
+	return p.peerTTL
+}
+
+// isDownloading returns whether the peer has been registered and is downloading the task,
+// the pending peer has not been registered yet and the other peers have finished.
+func isDownloading(peer *Peer) bool {
+	switch peer.FSM.Current() {
+	case PeerStateReceivedEmpty, PeerStateReceivedTiny, PeerStateReceivedSmall, PeerStateReceivedNormal,
+		PeerStateRunning, PeerStateBackToSource:
+		return true
+	default:
+		return false
+	}
+}
+
+// failDownload notifies the peer of the task failure, and sets the state
+// of the peer and the task to failed.
+func (p *peerManager) failDownload(peer *Peer) {
+	// Only the peer using v1 version of the grpc is notified by the stream,
+	// the peer using v2 version of the grpc finds the failure when it announces again.
+	if _, loaded := peer.LoadReportPieceResultStream(); loaded {
+		if err := peer.SendPeerPacket(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedTaskStatusError}); err != nil {
+			peer.Log.Errorf("send packet failed: %s", err.Error())
+		}
+	}
+
+	if err := peer.FSM.Event(context.Background(), PeerEventDownloadFailed); err != nil {
+		peer.Log.Errorf("peer fsm event failed: %s", err.Error())
+		return
+	}
+
+	if peer.Task.FSM.Is(TaskStateRunning) {
+		peer.Task.SetFailure(NewTaskFailure(TaskFailureReasonDownloadTimeout, ""))
+		if err := peer.Task.FSM.Event(context.Background(), TaskEventDownloadFailed); err != nil {
+			peer.Task.Log.Errorf("task fsm event failed: %s", err.Error())
+		}
+	}
+}
@@ -17,19 +17,26 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
 	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+	v1mocks "d7y.io/api/v2/pkg/apis/scheduler/v1/mocks"
 
 	"d7y.io/dragonfly/v2/pkg/gc"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/metrics"
 )
 
 var (
@@ -317,6 +324,118 @@ func TestPeerManager_Delete(t *testing.T) {
 	}
 }
 
+func TestPeerManager_Metrics(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	gc := gc.NewMockGC(ctl)
+	gc.EXPECT().Add(gomock.Any()).Return(nil).Times(3)
+
+	hostManager, err := newHostManager(mockHostGCConfig, gc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	taskManager, err := newTaskManager(mockTaskGCConfig, gc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peerManager, err := newPeerManager(mockPeerGCConfig, gc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The gauges are shared by the tests, so the changes are asserted. The state
+	// transitions are counted in the gc, which is stood in for by updating the gauges.
+	baselines := make(map[*prometheus.GaugeVec]map[string]float64)
+	delta := func(gauge *prometheus.GaugeVec, label string) float64 {
+		if _, ok := baselines[gauge]; !ok {
+			baselines[gauge] = make(map[string]float64)
+		}
+
+		if _, ok := baselines[gauge][label]; !ok {
+			baselines[gauge][label] = testutil.ToFloat64(gauge.WithLabelValues(label))
+		}
+
+		return testutil.ToFloat64(gauge.WithLabelValues(label)) - baselines[gauge][label]
+	}
+
+	for _, state := range []string{PeerStatePending, PeerStateReceivedNormal, PeerStateRunning, PeerStateSucceeded, PeerStateLeave} {
+		delta(metrics.PeerGauge, state)
+	}
+
+	for _, state := range []string{TaskStatePending, TaskStateRunning, TaskStateSucceeded} {
+		delta(metrics.TaskGauge, state)
+	}
+	delta(metrics.HostGauge, mockRawHost.Type.Name())
+
+	assert := assert.New(t)
+	mockHost := NewHost(
+		mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+		mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+	hostManager.Store(mockHost)
+	assert.Equal(float64(1), delta(metrics.HostGauge, mockRawHost.Type.Name()))
+
+	mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+	taskManager.Store(mockTask)
+	assert.Equal(float64(1), delta(metrics.TaskGauge, TaskStatePending))
+
+	// The peer which is not stored is not counted.
+	unstoredPeer := NewPeer(idgen.PeerIDV2(), mockResourceConfig, mockTask, mockHost)
+	assert.NoError(unstoredPeer.FSM.Event(context.Background(), PeerEventRegisterNormal))
+	unstoredPeer.stateGauge.update(unstoredPeer.FSM.Current())
+	assert.Equal(float64(0), delta(metrics.PeerGauge, PeerStatePending))
+	assert.Equal(float64(0), delta(metrics.PeerGauge, PeerStateReceivedNormal))
+
+	// Register.
+	mockPeer := NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+	peerManager.Store(mockPeer)
+	assert.Equal(float64(1), delta(metrics.PeerGauge, PeerStatePending))
+
+	assert.NoError(mockPeer.FSM.Event(context.Background(), PeerEventRegisterNormal))
+	mockPeer.stateGauge.update(mockPeer.FSM.Current())
+	assert.Equal(float64(0), delta(metrics.PeerGauge, PeerStatePending))
+	assert.Equal(float64(1), delta(metrics.PeerGauge, PeerStateReceivedNormal))
+
+	// Download.
+	assert.NoError(mockTask.FSM.Event(context.Background(), TaskEventDownload))
+	mockTask.stateGauge.update(mockTask.FSM.Current())
+	assert.Equal(float64(0), delta(metrics.TaskGauge, TaskStatePending))
+	assert.Equal(float64(1), delta(metrics.TaskGauge, TaskStateRunning))
+
+	assert.NoError(mockPeer.FSM.Event(context.Background(), PeerEventDownload))
+	mockPeer.stateGauge.update(mockPeer.FSM.Current())
+	assert.Equal(float64(0), delta(metrics.PeerGauge, PeerStateReceivedNormal))
+	assert.Equal(float64(1), delta(metrics.PeerGauge, PeerStateRunning))
+
+	// Succeed.
+	assert.NoError(mockPeer.FSM.Event(context.Background(), PeerEventDownloadSucceeded))
+	mockPeer.stateGauge.update(mockPeer.FSM.Current())
+	assert.Equal(float64(0), delta(metrics.PeerGauge, PeerStateRunning))
+	assert.Equal(float64(1), delta(metrics.PeerGauge, PeerStateSucceeded))
+
+	assert.NoError(mockTask.FSM.Event(context.Background(), TaskEventDownloadSucceeded))
+	mockTask.stateGauge.update(mockTask.FSM.Current())
+	assert.Equal(float64(0), delta(metrics.TaskGauge, TaskStateRunning))
+	assert.Equal(float64(1), delta(metrics.TaskGauge, TaskStateSucceeded))
+
+	// Leave.
+	assert.NoError(mockPeer.FSM.Event(context.Background(), PeerEventLeave))
+	mockPeer.stateGauge.update(mockPeer.FSM.Current())
+	assert.Equal(float64(0), delta(metrics.PeerGauge, PeerStateSucceeded))
+	assert.Equal(float64(1), delta(metrics.PeerGauge, PeerStateLeave))
+	assert.Greater(testutil.CollectAndCount(metrics.PeerStateDuration), 0)
+
+	peerManager.Delete(mockPeer.ID)
+	assert.Equal(float64(0), delta(metrics.PeerGauge, PeerStateLeave))
+
+	taskManager.Delete(mockTask.ID)
+	assert.Equal(float64(0), delta(metrics.TaskGauge, TaskStateSucceeded))
+
+	hostManager.Delete(mockHost.ID)
+	assert.Equal(float64(0), delta(metrics.HostGauge, mockRawHost.Type.Name()))
+}
+
 func TestPeerManager_RunGC(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -347,6 +466,177 @@ func TestPeerManager_RunGC(t *testing.T) {
 				assert.Equal(peer.FSM.Current(), PeerStateLeave)
 			},
 		},
+		{
+			name: "peer download task timeout",
+			gcConfig: &config.GCConfig{
+				PieceDownloadTimeout: 5 * time.Minute,
+				TaskDownloadTimeout:  1 * time.Microsecond,
+				PeerGCInterval:       1 * time.Second,
+				PeerTTL:              5 * time.Minute,
+				HostTTL:              10 * time.Second,
+			},
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, peerManager PeerManager, mockHost *Host, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				ctl := gomock.NewController(t)
+				defer ctl.Finish()
+				stream := v1mocks.NewMockScheduler_ReportPieceResultServer(ctl)
+				stream.EXPECT().Send(gomock.Eq(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedTaskStatusError})).Return(nil).Times(1)
+
+				peerManager.Store(mockPeer)
+				mockPeer.FSM.SetState(PeerStateRunning)
+				mockPeer.StoreReportPieceResultStream(stream)
+				mockTask.FSM.SetState(TaskStateRunning)
+				err := peerManager.RunGC()
+				assert.NoError(err)
+
+				peer, loaded := peerManager.Load(mockPeer.ID)
+				assert.Equal(loaded, true)
+				assert.Equal(peer.FSM.Current(), PeerStateFailed)
+				assert.True(mockTask.FSM.Is(TaskStateFailed))
+				assert.Equal(mockTask.Failure().Reason, TaskFailureReasonDownloadTimeout)
+
+				err = peerManager.RunGC()
+				assert.NoError(err)
+
+				peer, loaded = peerManager.Load(mockPeer.ID)
+				assert.Equal(loaded, true)
+				assert.Equal(peer.FSM.Current(), PeerStateLeave)
+			},
+		},
+		{
+			name: "peer download task timeout and peer downloads pieces",
+			gcConfig: &config.GCConfig{
+				PieceDownloadTimeout: 5 * time.Minute,
+				TaskDownloadTimeout:  1 * time.Minute,
+				PeerGCInterval:       1 * time.Second,
+				PeerTTL:              5 * time.Minute,
+				HostTTL:              10 * time.Second,
+			},
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, peerManager PeerManager, mockHost *Host, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				peerManager.Store(mockPeer)
+				mockPeer.FSM.SetState(PeerStateRunning)
+				mockPeer.CreatedAt.Store(time.Now().Add(-2 * time.Minute))
+				mockPeer.PieceUpdatedAt.Store(time.Now())
+				mockTask.FSM.SetState(TaskStateRunning)
+				err := peerManager.RunGC()
+				assert.NoError(err)
+
+				peer, loaded := peerManager.Load(mockPeer.ID)
+				assert.Equal(loaded, true)
+				assert.Equal(peer.FSM.Current(), PeerStateRunning)
+				assert.True(mockTask.FSM.Is(TaskStateRunning))
+			},
+		},
+		{
+			name: "peer download task timeout and peer state is PeerStatePending",
+			gcConfig: &config.GCConfig{
+				PieceDownloadTimeout: 5 * time.Minute,
+				TaskDownloadTimeout:  1 * time.Microsecond,
+				PeerGCInterval:       1 * time.Second,
+				PeerTTL:              5 * time.Minute,
+				HostTTL:              10 * time.Second,
+			},
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, peerManager PeerManager, mockHost *Host, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				peerManager.Store(mockPeer)
+				mockTask.FSM.SetState(TaskStateRunning)
+				err := peerManager.RunGC()
+				assert.NoError(err)
+
+				peer, loaded := peerManager.Load(mockPeer.ID)
+				assert.Equal(loaded, true)
+				assert.Equal(peer.FSM.Current(), PeerStatePending)
+				assert.True(mockTask.FSM.Is(TaskStateRunning))
+			},
+		},
+		{
+			name: "peer download task timeout and peer state is PeerStateSucceeded",
+			gcConfig: &config.GCConfig{
+				PieceDownloadTimeout: 5 * time.Minute,
+				TaskDownloadTimeout:  1 * time.Microsecond,
+				PeerGCInterval:       1 * time.Second,
+				PeerTTL:              5 * time.Minute,
+				HostTTL:              10 * time.Second,
+			},
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, peerManager PeerManager, mockHost *Host, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				peerManager.Store(mockPeer)
+				mockPeer.FSM.SetState(PeerStateSucceeded)
+				mockTask.FSM.SetState(TaskStateSucceeded)
+				err := peerManager.RunGC()
+				assert.NoError(err)
+
+				peer, loaded := peerManager.Load(mockPeer.ID)
+				assert.Equal(loaded, true)
+				assert.Equal(peer.FSM.Current(), PeerStateSucceeded)
+				assert.True(mockTask.FSM.Is(TaskStateSucceeded))
+			},
+		},
+		{
+			name: "peer reclaimed with application peer ttl",
+			gcConfig: &config.GCConfig{
+				PieceDownloadTimeout: 5 * time.Minute,
+				PeerGCInterval:       1 * time.Second,
+				PeerTTL:              5 * time.Minute,
+				ApplicationPeerTTLs: map[string]time.Duration{
+					mockTaskApplication: 1 * time.Microsecond,
+				},
+				HostTTL: 10 * time.Second,
+			},
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, peerManager PeerManager, mockHost *Host, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				peerManager.Store(mockPeer)
+				mockPeer.FSM.SetState(PeerStateSucceeded)
+				err := peerManager.RunGC()
+				assert.NoError(err)
+
+				peer, loaded := peerManager.Load(mockPeer.ID)
+				assert.Equal(loaded, true)
+				assert.Equal(peer.FSM.Current(), PeerStateLeave)
+			},
+		},
+		{
+			name: "peer not reclaimed with peer ttl of other application",
+			gcConfig: &config.GCConfig{
+				PieceDownloadTimeout: 5 * time.Minute,
+				PeerGCInterval:       1 * time.Second,
+				PeerTTL:              5 * time.Minute,
+				ApplicationPeerTTLs: map[string]time.Duration{
+					"ci": 1 * time.Microsecond,
+				},
+				HostTTL: 10 * time.Second,
+			},
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, peerManager PeerManager, mockHost *Host, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				peerManager.Store(mockPeer)
+				mockPeer.FSM.SetState(PeerStateSucceeded)
+				err := peerManager.RunGC()
+				assert.NoError(err)
+
+				peer, loaded := peerManager.Load(mockPeer.ID)
+				assert.Equal(loaded, true)
+				assert.Equal(peer.FSM.Current(), PeerStateSucceeded)
+			},
+		},
 		{
 			name: "peer download piece timeout and peer state is PeerStateRunning",
 			gcConfig: &config.GCConfig{
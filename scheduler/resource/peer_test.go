@@ -40,6 +40,7 @@ import (
 
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/scheduler/config"
 	configmocks "d7y.io/dragonfly/v2/scheduler/config/mocks"
 )
 
@@ -76,8 +77,10 @@ func TestPeer_NewPeer(t *testing.T) {
 				assert.Equal(peer.FSM.Current(), PeerStatePending)
 				assert.EqualValues(peer.Task, mockTask)
 				assert.EqualValues(peer.Host, mockHost)
-				assert.Equal(peer.BlockParents.Len(), uint(0))
+				assert.Equal(peer.BlockParents.ItemCount(), 0)
 				assert.Equal(peer.NeedBackToSource.Load(), false)
+				assert.Equal(peer.Quarantined.Load(), false)
+				assert.Equal(peer.ParentSwitchCount.Load(), int32(0))
 				assert.NotEqual(peer.PieceUpdatedAt.Load(), 0)
 				assert.NotEqual(peer.CreatedAt.Load(), 0)
 				assert.NotEqual(peer.UpdatedAt.Load(), 0)
@@ -101,7 +104,7 @@ func TestPeer_NewPeer(t *testing.T) {
 				assert.Equal(peer.FSM.Current(), PeerStatePending)
 				assert.EqualValues(peer.Task, mockTask)
 				assert.EqualValues(peer.Host, mockHost)
-				assert.Equal(peer.BlockParents.Len(), uint(0))
+				assert.Equal(peer.BlockParents.ItemCount(), 0)
 				assert.Equal(peer.NeedBackToSource.Load(), false)
 				assert.NotEqual(peer.PieceUpdatedAt.Load(), 0)
 				assert.NotEqual(peer.CreatedAt.Load(), 0)
@@ -129,7 +132,7 @@ func TestPeer_NewPeer(t *testing.T) {
 				assert.Equal(peer.FSM.Current(), PeerStatePending)
 				assert.EqualValues(peer.Task, mockTask)
 				assert.EqualValues(peer.Host, mockHost)
-				assert.Equal(peer.BlockParents.Len(), uint(0))
+				assert.Equal(peer.BlockParents.ItemCount(), 0)
 				assert.Equal(peer.NeedBackToSource.Load(), false)
 				assert.NotEqual(peer.PieceUpdatedAt.Load(), 0)
 				assert.NotEqual(peer.CreatedAt.Load(), 0)
@@ -154,7 +157,7 @@ func TestPeer_NewPeer(t *testing.T) {
 				assert.Equal(peer.FSM.Current(), PeerStatePending)
 				assert.EqualValues(peer.Task, mockTask)
 				assert.EqualValues(peer.Host, mockHost)
-				assert.Equal(peer.BlockParents.Len(), uint(0))
+				assert.Equal(peer.BlockParents.ItemCount(), 0)
 				assert.Equal(peer.NeedBackToSource.Load(), false)
 				assert.NotEqual(peer.PieceUpdatedAt.Load(), 0)
 				assert.NotEqual(peer.CreatedAt.Load(), 0)
@@ -249,6 +252,226 @@ func TestPeer_PieceCosts(t *testing.T) {
 	}
 }
 
+func TestPeer_AddPieceViolation(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		expect func(t *testing.T, peer *Peer, limit int)
+	}{
+		{
+			name:  "violation count does not reach the limit",
+			limit: 3,
+			expect: func(t *testing.T, peer *Peer, limit int) {
+				assert := assert.New(t)
+				assert.False(peer.AddPieceViolation(limit))
+				assert.False(peer.AddPieceViolation(limit))
+				assert.Equal(peer.PieceViolationCount.Load(), int32(2))
+				assert.False(peer.Quarantined.Load())
+			},
+		},
+		{
+			name:  "violation count reaches the limit",
+			limit: 2,
+			expect: func(t *testing.T, peer *Peer, limit int) {
+				assert := assert.New(t)
+				assert.False(peer.AddPieceViolation(limit))
+				assert.True(peer.AddPieceViolation(limit))
+				assert.True(peer.Quarantined.Load())
+
+				// Peer is only quarantined once.
+				assert.False(peer.AddPieceViolation(limit))
+				assert.Equal(peer.PieceViolationCount.Load(), int32(3))
+				assert.True(peer.Quarantined.Load())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			peer := NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+
+			tc.expect(t, peer, tc.limit)
+		})
+	}
+}
+
+func TestPeer_LoadBlockParents(t *testing.T) {
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		expect func(t *testing.T, peer *Peer)
+	}{
+		{
+			name: "blocked parent expires after ttl",
+			ttl:  50 * time.Millisecond,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.BlockParent(mockSeedPeerID)
+				assert.True(peer.LoadBlockParents().Contains(mockSeedPeerID))
+
+				time.Sleep(100 * time.Millisecond)
+				assert.False(peer.LoadBlockParents().Contains(mockSeedPeerID))
+				assert.Equal(peer.BlockParents.ItemCount(), 0)
+			},
+		},
+		{
+			name: "blocked parent is refreshed when it is blocked again",
+			ttl:  100 * time.Millisecond,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.BlockParent(mockSeedPeerID)
+				time.Sleep(60 * time.Millisecond)
+				peer.BlockParent(mockSeedPeerID)
+				time.Sleep(60 * time.Millisecond)
+				assert.True(peer.LoadBlockParents().Contains(mockSeedPeerID))
+			},
+		},
+		{
+			name: "blocked parent never expires",
+			ttl:  0,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.BlockParent(mockSeedPeerID)
+				time.Sleep(50 * time.Millisecond)
+				assert.True(peer.LoadBlockParents().Contains(mockSeedPeerID))
+				assert.Equal(peer.LoadBlockParents().Len(), uint(1))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			peer := NewPeer(mockPeerID, &config.ResourceConfig{Peer: config.PeerConfig{BlockParentTTL: tc.ttl}}, mockTask, mockHost)
+
+			tc.expect(t, peer)
+		})
+	}
+}
+
+func TestPeer_AppendDecision(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, peer *Peer)
+	}{
+		{
+			name: "peer has no decisions",
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				assert.Empty(peer.Decisions())
+			},
+		},
+		{
+			name: "append decisions",
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.AppendDecision(PeerDecision{Type: PeerDecisionScheduled, ParentIDs: []string{mockSeedPeerID}})
+				peer.AppendDecision(PeerDecision{Type: PeerDecisionBackToSource, Reason: "need_back_to_source", Retries: 1})
+
+				decisions := peer.Decisions()
+				assert.Len(decisions, 2)
+				assert.Equal(decisions[0].Type, PeerDecisionScheduled)
+				assert.Equal(decisions[0].ParentIDs, []string{mockSeedPeerID})
+				assert.False(decisions[0].CreatedAt.IsZero())
+				assert.Equal(decisions[1].Type, PeerDecisionBackToSource)
+				assert.Equal(decisions[1].Reason, "need_back_to_source")
+				assert.Equal(decisions[1].Retries, 1)
+			},
+		},
+		{
+			name: "oldest decisions are dropped when decisions exceed limit",
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				for i := 0; i < PeerDecisionLimit+4; i++ {
+					peer.AppendDecision(PeerDecision{Type: PeerDecisionFailed, Retries: i})
+				}
+
+				decisions := peer.Decisions()
+				assert.Len(decisions, PeerDecisionLimit)
+				assert.Equal(decisions[0].Retries, 4)
+				assert.Equal(decisions[PeerDecisionLimit-1].Retries, PeerDecisionLimit+3)
+			},
+		},
+		{
+			name: "decisions returns a copy",
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.AppendDecision(PeerDecision{Type: PeerDecisionFailed})
+				decisions := peer.Decisions()
+				decisions[0].Type = PeerDecisionScheduled
+				assert.Equal(peer.Decisions()[0].Type, PeerDecisionFailed)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			peer := NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+
+			tc.expect(t, peer)
+		})
+	}
+}
+
+func TestPeer_ParentRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, peer *Peer)
+	}{
+		{
+			name: "peer has no parent rejections",
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				assert.Nil(peer.ParentRejections())
+			},
+		},
+		{
+			name: "store parent rejections",
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.StoreParentRejections(map[string]int{"blocklist": 2, "not_ready": 1})
+				assert.Equal(peer.ParentRejections(), map[string]int{"blocklist": 2, "not_ready": 1})
+
+				peer.StoreParentRejections(map[string]int{})
+				assert.Nil(peer.ParentRejections())
+			},
+		},
+		{
+			name: "parent rejections returns a copy",
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.StoreParentRejections(map[string]int{"blocklist": 1})
+				rejections := peer.ParentRejections()
+				rejections["blocklist"] = 3
+				assert.Equal(peer.ParentRejections()["blocklist"], 1)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			peer := NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+
+			tc.expect(t, peer)
+		})
+	}
+}
+
 func TestPeer_LoadReportPieceResultStream(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -463,6 +686,118 @@ func TestPeer_DeleteAnnouncePeerStream(t *testing.T) {
 	}
 }
 
+func TestPeer_SendPeerPacket(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(peer *Peer, stream *v1mocks.MockScheduler_ReportPieceResultServer, ms *v1mocks.MockScheduler_ReportPieceResultServerMockRecorder)
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name: "send packet",
+			mock: func(peer *Peer, stream *v1mocks.MockScheduler_ReportPieceResultServer, ms *v1mocks.MockScheduler_ReportPieceResultServerMockRecorder) {
+				peer.StoreReportPieceResultStream(stream)
+				ms.Send(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "send packet failed",
+			mock: func(peer *Peer, stream *v1mocks.MockScheduler_ReportPieceResultServer, ms *v1mocks.MockScheduler_ReportPieceResultServerMockRecorder) {
+				peer.StoreReportPieceResultStream(stream)
+				ms.Send(gomock.Any()).Return(errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "foo")
+			},
+		},
+		{
+			name: "stream does not exist",
+			mock: func(peer *Peer, stream *v1mocks.MockScheduler_ReportPieceResultServer, ms *v1mocks.MockScheduler_ReportPieceResultServerMockRecorder) {
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "load stream failed")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			stream := v1mocks.NewMockScheduler_ReportPieceResultServer(ctl)
+
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			peer := NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+			tc.mock(peer, stream, stream.EXPECT())
+			tc.expect(t, peer.SendPeerPacket(&schedulerv1.PeerPacket{}))
+		})
+	}
+}
+
+func TestPeer_SendAnnouncePeerResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(peer *Peer, stream *v2mocks.MockScheduler_AnnouncePeerServer, ms *v2mocks.MockScheduler_AnnouncePeerServerMockRecorder)
+		expect func(t *testing.T, err error)
+	}{
+		{
+			name: "send response",
+			mock: func(peer *Peer, stream *v2mocks.MockScheduler_AnnouncePeerServer, ms *v2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				peer.StoreAnnouncePeerStream(stream)
+				ms.Send(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "send response failed",
+			mock: func(peer *Peer, stream *v2mocks.MockScheduler_AnnouncePeerServer, ms *v2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				peer.StoreAnnouncePeerStream(stream)
+				ms.Send(gomock.Any()).Return(errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "foo")
+			},
+		},
+		{
+			name: "stream does not exist",
+			mock: func(peer *Peer, stream *v2mocks.MockScheduler_AnnouncePeerServer, ms *v2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "load stream failed")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			stream := v2mocks.NewMockScheduler_AnnouncePeerServer(ctl)
+
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			peer := NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+			tc.mock(peer, stream, stream.EXPECT())
+			tc.expect(t, peer.SendAnnouncePeerResponse(&schedulerv2.AnnouncePeerResponse{}))
+		})
+	}
+}
+
 func TestPeer_LoadPiece(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -608,6 +943,171 @@ func TestPeer_DeletePiece(t *testing.T) {
 	}
 }
 
+func TestPeer_ParentHostAffinity(t *testing.T) {
+	tests := []struct {
+		name   string
+		window time.Duration
+		expect func(t *testing.T, peer *Peer)
+	}{
+		{
+			name:   "parent host is stored",
+			window: time.Minute,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.StoreParentHost(mockRawSeedHost.ID)
+				assert.InDelta(1, peer.ParentHostAffinity(mockRawSeedHost.ID), 0.01)
+				assert.Equal(float64(0), peer.ParentHostAffinity(mockRawHost.ID))
+			},
+		},
+		{
+			name:   "affinity decays over the window",
+			window: time.Minute,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.parentHosts.Store(mockRawSeedHost.ID, time.Now().Add(-30*time.Second))
+				assert.InDelta(0.5, peer.ParentHostAffinity(mockRawSeedHost.ID), 0.01)
+			},
+		},
+		{
+			name:   "parent host is out of the window",
+			window: time.Minute,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.parentHosts.Store(mockRawSeedHost.ID, time.Now().Add(-2*time.Minute))
+				assert.Equal(float64(0), peer.ParentHostAffinity(mockRawSeedHost.ID))
+
+				peer.StoreParentHost(mockRawHost.ID)
+				_, loaded := peer.parentHosts.Load(mockRawSeedHost.ID)
+				assert.False(loaded)
+			},
+		},
+		{
+			name:   "affinity is disabled",
+			window: 0,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.StoreParentHost(mockRawSeedHost.ID)
+				assert.Equal(float64(0), peer.ParentHostAffinity(mockRawSeedHost.ID))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			cfg := *mockResourceConfig
+			cfg.Peer.ParentAffinityWindow = tc.window
+			peer := NewPeer(mockPeerID, &cfg, mockTask, mockHost)
+
+			tc.expect(t, peer)
+		})
+	}
+}
+
+func TestPeer_StoreParent(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		expect func(t *testing.T, peer *Peer)
+	}{
+		{
+			name:  "peer stores the first parent",
+			limit: 2,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				assert.False(peer.StoreParent("foo"))
+				assert.False(peer.StoreParent("foo"))
+				assert.Equal(peer.ParentSwitchCount.Load(), int32(0))
+			},
+		},
+		{
+			name:  "peer is pinned after switching parents exceeds the limit",
+			limit: 2,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.StoreParent("foo")
+				assert.True(peer.StoreParent("bar"))
+				assert.True(peer.StoreParent("foo"))
+				_, pinned := peer.PinnedParent()
+				assert.False(pinned)
+
+				assert.True(peer.StoreParent("bar"))
+				assert.Equal(peer.ParentSwitchCount.Load(), int32(3))
+				parentID, pinned := peer.PinnedParent()
+				assert.True(pinned)
+				assert.Equal(parentID, "bar")
+
+				// The pin is released when the parent is replaced.
+				assert.True(peer.StoreParent("baz"))
+				_, pinned = peer.PinnedParent()
+				assert.False(pinned)
+			},
+		},
+		{
+			name:  "parent switches out of the window are not counted",
+			limit: 2,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.StoreParent("foo")
+				peer.StoreParent("bar")
+				peer.StoreParent("foo")
+				peer.parentSwitches = []time.Time{time.Now().Add(-2 * time.Minute), time.Now().Add(-2 * time.Minute)}
+
+				assert.True(peer.StoreParent("bar"))
+				_, pinned := peer.PinnedParent()
+				assert.False(pinned)
+			},
+		},
+		{
+			name:  "pin expires",
+			limit: 2,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				for _, parentID := range []string{"foo", "bar", "foo", "bar"} {
+					peer.StoreParent(parentID)
+				}
+
+				peer.pinnedUntil = time.Now().Add(-time.Second)
+				_, pinned := peer.PinnedParent()
+				assert.False(pinned)
+			},
+		},
+		{
+			name:  "peer is never pinned",
+			limit: 0,
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				for _, parentID := range []string{"foo", "bar", "foo", "bar", "foo"} {
+					peer.StoreParent(parentID)
+				}
+
+				assert.Equal(peer.ParentSwitchCount.Load(), int32(4))
+				_, pinned := peer.PinnedParent()
+				assert.False(pinned)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			cfg := *mockResourceConfig
+			cfg.Peer.ParentSwitchLimit = tc.limit
+			cfg.Peer.ParentSwitchWindow = time.Minute
+			cfg.Peer.ParentPinCooldown = time.Minute
+			peer := NewPeer(mockPeerID, &cfg, mockTask, mockHost)
+
+			tc.expect(t, peer)
+		})
+	}
+}
+
 func TestPeer_Parents(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -753,6 +1253,72 @@ func TestPeer_DownloadTinyFile(t *testing.T) {
 				assert.Equal(testData[:32], data)
 			},
 		},
+		{
+			name: "download tiny file in several responses",
+			mockServer: func(t *testing.T, peer *Peer) *httptest.Server {
+				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert := assert.New(t)
+					rgs, err := nethttp.ParseRange(r.Header.Get(headers.Range), 128)
+					assert.Nil(err)
+					assert.Equal(1, len(rgs))
+					rg := rgs[0]
+
+					// Return at most 16 bytes in a response.
+					if rg.Length > 16 {
+						rg.Length = 16
+					}
+
+					w.WriteHeader(http.StatusPartialContent)
+					_, err = w.Write(testData[rg.Start : rg.Start+rg.Length])
+					assert.Nil(err)
+				}))
+			},
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.Task.ContentLength.Store(100)
+				data, err := peer.DownloadTinyFile()
+				assert.NoError(err)
+				assert.Equal(testData[:100], data)
+			},
+		},
+		{
+			name: "download tiny file failed because of empty response",
+			mockServer: func(t *testing.T, peer *Peer) *httptest.Server {
+				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusPartialContent)
+				}))
+			},
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.Task.ContentLength.Store(32)
+				_, err := peer.DownloadTinyFile()
+				assert.EqualError(err, "unexpected EOF at offset 0, content length is 32")
+			},
+		},
+		{
+			name: "download tiny file failed because of range is ignored",
+			mockServer: func(t *testing.T, peer *Peer) *httptest.Server {
+				return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					assert := assert.New(t)
+					if r.Header.Get(headers.Range) == "bytes=0-31" {
+						w.WriteHeader(http.StatusPartialContent)
+						_, err := w.Write(testData[:16])
+						assert.Nil(err)
+						return
+					}
+
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(testData[:32])
+					assert.Nil(err)
+				}))
+			},
+			expect: func(t *testing.T, peer *Peer) {
+				assert := assert.New(t)
+				peer.Task.ContentLength.Store(32)
+				_, err := peer.DownloadTinyFile()
+				assert.EqualError(err, "range is not satisfied, response status 200 OK")
+			},
+		},
 		{
 			name: "download tiny file failed because of http status code",
 			mockServer: func(t *testing.T, peer *Peer) *httptest.Server {
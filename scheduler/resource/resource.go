@@ -19,13 +19,16 @@
 package resource
 
 import (
+	"context"
 	"github.com/redis/go-redis/v9"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
 
+	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/gc"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/scheduler/config"
 )
 
@@ -67,6 +70,18 @@ type resource struct {
 	// Redis universal client interface.
 	rdb redis.UniversalClient
 
+	// taskSnapshotStore is storage of the task snapshots.
+	taskSnapshotStore TaskSnapshotStore
+
+	// taskSnapshotter takes the task snapshots periodically.
+	taskSnapshotter *taskSnapshotter
+
+	// backToSourceCount returns the back-to-source count of the tasks of the application.
+	backToSourceCount func(application string) int32
+
+	// taskOptions are the options of the tasks built by the resource.
+	taskOptions []TaskOption
+
 	// TransportCredentials stores the Authenticator required to setup a client connection.
 	transportCredentials credentials.TransportCredentials
 }
@@ -89,6 +104,24 @@ func WithRedisClient(rdb redis.UniversalClient) Option {
 	}
 }
 
+// WithTaskOptions returns a Option which configures how the resource builds the tasks, e.g. the restored tasks,
+// so that they are built the same as the tasks created by the service. The back-to-source count of the scheduler
+// config is used if backToSourceCount is not configured.
+func WithTaskOptions(backToSourceCount func(application string) int32, options ...TaskOption) Option {
+	return func(r *resource) {
+		r.backToSourceCount = backToSourceCount
+		r.taskOptions = options
+	}
+}
+
+// WithTaskSnapshotStore returns a Option which configures the storage of the task snapshots,
+// the tasks are restored from the storage and persisted to it periodically.
+func WithTaskSnapshotStore(store TaskSnapshotStore) Option {
+	return func(r *resource) {
+		r.taskSnapshotStore = store
+	}
+}
+
 // New returns Resource interface.
 func New(cfg *config.Config, gc gc.GC, dynconfig config.DynconfigInterface, options ...Option) (Resource, error) {
 	resource := &resource{config: cfg}
@@ -97,6 +130,12 @@ func New(cfg *config.Config, gc gc.GC, dynconfig config.DynconfigInterface, opti
 		opt(resource)
 	}
 
+	if resource.backToSourceCount == nil {
+		resource.backToSourceCount = func(string) int32 {
+			return int32(cfg.Scheduler.BackToSourceCount)
+		}
+	}
+
 	// Initialize host manager interface.
 	hostManager, err := newHostManager(&cfg.Scheduler.GC, gc)
 	if err != nil {
@@ -118,6 +157,16 @@ func New(cfg *config.Config, gc gc.GC, dynconfig config.DynconfigInterface, opti
 	}
 	resource.peerManager = peerManager
 
+	// Initialize task snapshotter and restore the tasks.
+	if resource.taskSnapshotStore != nil {
+		taskSnapshotter, err := newTaskSnapshotter(&cfg.Resource.Task.Persistence, resource.backToSourceCount, resource.taskOptions,
+			taskManager, resource.taskSnapshotStore, gc)
+		if err != nil {
+			return nil, err
+		}
+		resource.taskSnapshotter = taskSnapshotter
+	}
+
 	// Initialize seed peer interface.
 	if cfg.SeedPeer.Enable {
 		dialOptions := []grpc.DialOption{grpc.WithStatsHandler(otelgrpc.NewClientHandler())}
@@ -127,14 +176,27 @@ func New(cfg *config.Config, gc gc.GC, dynconfig config.DynconfigInterface, opti
 			dialOptions = append(dialOptions, grpc.WithTransportCredentials(insecure.NewCredentials()))
 		}
 
+		if cfg.SeedPeer.GzipCompression {
+			dialOptions = append(dialOptions, rpc.WithGzipCompression())
+		}
+
 		client, err := newSeedPeerClient(dynconfig, hostManager, dialOptions...)
 		if err != nil {
 			return nil, err
 		}
 
 		resource.seedPeer = newSeedPeer(cfg, client, peerManager, hostManager)
+
+		// Restore the seed peers of the restored tasks in the background, so that the startup
+		// and the registration of peers are not blocked by verifying the seed peers.
+		if resource.taskSnapshotter != nil && len(resource.taskSnapshotter.restoredTasks) > 0 {
+			go resource.seedPeer.RestoreTasks(context.Background(), resource.taskSnapshotter.restoredTasks)
+		}
 	}
 
+	// Mark the hosts in maintenance when the scheduler cluster config is updated.
+	dynconfig.Register(hostManager)
+
 	return resource, nil
 }
 
@@ -160,6 +222,13 @@ func (r *resource) TaskManager() TaskManager {
 
 // Stop resource service.
 func (r *resource) Stop() error {
+	// Take the last task snapshot before stopping.
+	if r.taskSnapshotter != nil {
+		if err := r.taskSnapshotter.RunGC(); err != nil {
+			logger.Errorf("save task snapshots failed: %s", err.Error())
+		}
+	}
+
 	if r.config.SeedPeer.Enable {
 		return r.seedPeer.Stop()
 	}
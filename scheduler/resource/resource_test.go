@@ -59,6 +59,7 @@ func TestResource_New(t *testing.T) {
 					md.Register(gomock.Any()).Return().Times(1),
 					md.GetResolveSeedPeerAddrs().Return([]resolver.Address{}, nil).Times(1),
 					md.Register(gomock.Any()).Return().Times(1),
+					md.Register(gomock.Any()).Return().Times(1),
 				)
 			},
 			expect: func(t *testing.T, resource Resource, err error) {
@@ -136,6 +137,7 @@ func TestResource_New(t *testing.T) {
 					md.Register(gomock.Any()).Return().Times(1),
 					md.GetResolveSeedPeerAddrs().Return([]resolver.Address{}, nil).Times(1),
 					md.Register(gomock.Any()).Return().Times(1),
+					md.Register(gomock.Any()).Return().Times(1),
 				)
 			},
 			expect: func(t *testing.T, resource Resource, err error) {
@@ -160,6 +162,7 @@ func TestResource_New(t *testing.T) {
 			},
 			mock: func(mg *gc.MockGCMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder) {
 				mg.Add(gomock.Any()).Return(nil).Times(3)
+				md.Register(gomock.Any()).Return().Times(1)
 			},
 			expect: func(t *testing.T, resource Resource, err error) {
 				assert := assert.New(t)
@@ -20,12 +20,15 @@ package resource
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/atomic"
+	"golang.org/x/sync/errgroup"
 
 	cdnsystemv1 "d7y.io/api/v2/pkg/apis/cdnsystem/v1"
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
@@ -33,9 +36,11 @@ import (
 	dfdaemonv2 "d7y.io/api/v2/pkg/apis/dfdaemon/v2"
 	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
 
+	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/net/http"
+	cdnsystemclient "d7y.io/dragonfly/v2/pkg/rpc/cdnsystem/client"
 	"d7y.io/dragonfly/v2/pkg/rpc/common"
 	"d7y.io/dragonfly/v2/scheduler/config"
 	"d7y.io/dragonfly/v2/scheduler/metrics"
@@ -44,6 +49,26 @@ import (
 const (
 	// Default value of seed peer failed timeout.
 	SeedPeerFailedTimeout = 30 * time.Minute
+
+	// Default value of the maximum interval for checking the interested peers of the seeding task.
+	seedPeerInterestCheckInterval = time.Second
+
+	// Default value of the timeout for verifying the restored seed peers of a task.
+	seedPeerRestoreTimeout = 5 * time.Second
+
+	// Default value of the maximum number of the restored seed peers verified for a task.
+	seedPeerRestoreLimit = 3
+
+	// Default value of the maximum number of the tasks whose seed peers are restored concurrently.
+	seedPeerRestoreConcurrency = 16
+)
+
+var (
+	// ErrSeedingAborted is the error of aborting the seeding because all interested peers left.
+	ErrSeedingAborted = errors.New("seeding aborted because all interested peers left")
+
+	// ErrRestoredSeedPeerNotFound is the error of no restored seed peer has the data of the task.
+	ErrRestoredSeedPeerNotFound = errors.New("restored seed peer not found")
 )
 
 // SeedPeer is the interface used for seed peer.
@@ -56,6 +81,11 @@ type SeedPeer interface {
 	// Used only in v1 version of the grpc.
 	TriggerTask(context.Context, *http.Range, *Task) (*Peer, *schedulerv1.PeerResult, error)
 
+	// RestoreTasks restores the seed peers of the tasks restored from the snapshot,
+	// if the seed peers still have the data of the tasks.
+	// Used only in v1 version of the grpc.
+	RestoreTasks(context.Context, []*Task)
+
 	// Client returns grpc client of seed peer.
 	Client() SeedPeerClient
 
@@ -131,6 +161,13 @@ func (s *seedPeer) TriggerTask(ctx context.Context, rg *http.Range, task *Task)
 		urlMeta.Range = rg.URLMetaString()
 	}
 
+	// Abort the seeding when all interested peers leave.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if s.config.SeedPeer.AbortSeeding.Enable {
+		go s.abortSeedingWithoutInterest(ctx, cancel, task)
+	}
+
 	stream, err := s.client.ObtainSeeds(ctx, &cdnsystemv1.SeedRequest{
 		TaskId:  task.ID,
 		Url:     task.URL,
@@ -141,13 +178,18 @@ func (s *seedPeer) TriggerTask(ctx context.Context, rg *http.Range, task *Task)
 	}
 
 	var (
-		peer        *Peer
-		initialized bool
+		peer            *Peer
+		initialized     bool
+		downloadedBytes int64
 	)
 
 	for {
 		pieceSeed, err := stream.Recv()
 		if err != nil {
+			if errors.Is(context.Cause(ctx), ErrSeedingAborted) {
+				return nil, nil, s.handleSeedingAborted(ctx, task, peer, downloadedBytes)
+			}
+
 			// If the peer initialization succeeds and the download fails,
 			// set peer status is PeerStateFailed.
 			if peer != nil {
@@ -206,6 +248,7 @@ func (s *seedPeer) TriggerTask(ctx context.Context, rg *http.Range, task *Task)
 			peer.UpdatedAt.Store(time.Now())
 			peer.PieceUpdatedAt.Store(time.Now())
 			task.StorePiece(piece)
+			downloadedBytes += int64(pieceSeed.PieceInfo.RangeSize)
 
 			// Collect Traffic metrics.
 			trafficType := commonv2.TrafficType_BACK_TO_SOURCE
@@ -227,6 +270,64 @@ func (s *seedPeer) TriggerTask(ctx context.Context, rg *http.Range, task *Task)
 	}
 }
 
+// abortSeedingWithoutInterest cancels the seeding when there are no interested peers in
+// the task during the linger, a quick re-request does not restart the seeding from scratch.
+func (s *seedPeer) abortSeedingWithoutInterest(ctx context.Context, cancel context.CancelCauseFunc, task *Task) {
+	linger := s.config.SeedPeer.AbortSeeding.Linger
+	ticker := time.NewTicker(min(linger, seedPeerInterestCheckInterval))
+	defer ticker.Stop()
+
+	var idleAt time.Time
+	for {
+		select {
+		case <-ticker.C:
+			if task.InterestedPeerCount() > 0 {
+				idleAt = time.Time{}
+				continue
+			}
+
+			if idleAt.IsZero() {
+				idleAt = time.Now()
+			}
+
+			if time.Since(idleAt) >= linger {
+				task.Log.Infof("abort seeding, because of no interested peers in %s", linger)
+				cancel(ErrSeedingAborted)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// handleSeedingAborted releases the seed peer and sets the task state to TaskStateSeedingAborted,
+// then the task can be seeded again by the next request.
+func (s *seedPeer) handleSeedingAborted(ctx context.Context, task *Task, peer *Peer, downloadedBytes int64) error {
+	// Use the context without the cancellation, because the seeding
+	// context has been canceled.
+	ctx = context.WithoutCancel(ctx)
+
+	// Seed peer leaves instead of failing, otherwise the task
+	// can not be seeded again until SeedPeerFailedTimeout.
+	if peer != nil && peer.FSM.Can(PeerEventLeave) {
+		if err := peer.FSM.Event(ctx, PeerEventLeave); err != nil {
+			return err
+		}
+	}
+
+	if task.FSM.Can(TaskEventSeedingAborted) {
+		if err := task.FSM.Event(ctx, TaskEventSeedingAborted); err != nil {
+			return err
+		}
+	}
+
+	// Collect SeedPeerAbortedTraffic metrics.
+	metrics.SeedPeerAbortedTraffic.WithLabelValues(task.Type.String()).Add(float64(downloadedBytes))
+	task.Log.Infof("seeding aborted after downloading %d bytes", downloadedBytes)
+	return ErrSeedingAborted
+}
+
 // Initialize seed peer.
 func (s *seedPeer) initSeedPeer(ctx context.Context, rg *http.Range, task *Task, hostID string, peerID string) (*Peer, error) {
 	// Load host from manager.
@@ -261,6 +362,129 @@ func (s *seedPeer) initSeedPeer(ctx context.Context, rg *http.Range, task *Task,
 	return peer, nil
 }
 
+// RestoreTasks restores the seed peers of the tasks restored from the snapshot, if the seed peers
+// still have the data of the tasks, then the tasks do not need to be seeded again. The tasks are
+// restored concurrently with the limit, and it returns after all the tasks are restored.
+// Used only in v1 version of the grpc.
+func (s *seedPeer) RestoreTasks(ctx context.Context, tasks []*Task) {
+	var (
+		restored atomic.Int32
+		g        errgroup.Group
+	)
+	g.SetLimit(seedPeerRestoreConcurrency)
+	for _, task := range tasks {
+		task := task
+		g.Go(func() error {
+			if _, err := s.restoreTask(ctx, task); err != nil {
+				task.Log.Warnf("restore seed peer failed: %s", err.Error())
+				return nil
+			}
+
+			restored.Inc()
+			return nil
+		})
+	}
+
+	// nolint: errcheck
+	g.Wait()
+	logger.Infof("restore seed peers of %d/%d tasks", restored.Load(), len(tasks))
+}
+
+// restoreTask restores the seed peer of the task restored from the snapshot, if the seed peer
+// still has the data of the task. The seed peers are verified by GetPieceTasks only once within
+// the timeout, and at most seedPeerRestoreLimit seed peers are verified.
+func (s *seedPeer) restoreTask(ctx context.Context, task *Task) (*Peer, error) {
+	ctx, cancel := context.WithTimeout(ctx, seedPeerRestoreTimeout)
+	defer cancel()
+
+	seedPeers := task.LoadAndDeleteRestoredSeedPeers()
+	if len(seedPeers) > seedPeerRestoreLimit {
+		seedPeers = seedPeers[:seedPeerRestoreLimit]
+	}
+
+	for _, seedPeer := range seedPeers {
+		peer, err := s.restoreSeedPeer(ctx, task, seedPeer)
+		if err != nil {
+			task.Log.Warnf("restore seed peer %s failed: %s", seedPeer.ID, err.Error())
+			continue
+		}
+
+		return peer, nil
+	}
+
+	return nil, ErrRestoredSeedPeerNotFound
+}
+
+// restoreSeedPeer verifies the data of the restored seed peer and stores the succeeded seed peer.
+func (s *seedPeer) restoreSeedPeer(ctx context.Context, task *Task, seedPeer *TaskSeedPeerSnapshot) (*Peer, error) {
+	// Load host from manager.
+	host, loaded := s.hostManager.Load(seedPeer.HostID)
+	if !loaded {
+		return nil, fmt.Errorf("can not find host id: %s", seedPeer.HostID)
+	}
+
+	// Page through the pieces of the seed peer, the large task has too many pieces for a single response.
+	var (
+		packet     *commonv1.PiecePacket
+		pieceInfos []*commonv1.PieceInfo
+	)
+	if err := cdnsystemclient.PieceTaskIterator(ctx, s.client, &commonv1.PieceTaskRequest{
+		TaskId:   task.ID,
+		SrcPid:   idgen.PeerIDV1(s.config.Server.AdvertiseIP.String()),
+		DstPid:   seedPeer.ID,
+		StartNum: 0,
+	}).Range(func(p *commonv1.PiecePacket) bool {
+		packet = p
+		pieceInfos = append(pieceInfos, p.PieceInfos...)
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	if packet.TotalPiece != task.TotalPieceCount.Load() || packet.ContentLength != task.ContentLength.Load() {
+		return nil, fmt.Errorf("seed peer has total piece count %d and content length %d, but task has %d and %d",
+			packet.TotalPiece, packet.ContentLength, task.TotalPieceCount.Load(), task.ContentLength.Load())
+	}
+
+	// Load peer from manager.
+	peer, loaded := s.peerManager.Load(seedPeer.ID)
+	if loaded {
+		return peer, nil
+	}
+
+	peer = NewPeer(seedPeer.ID, &s.config.Resource, task, host)
+	for _, pieceInfo := range pieceInfos {
+		piece := &Piece{
+			Number:      pieceInfo.PieceNum,
+			Offset:      pieceInfo.RangeStart,
+			Length:      uint64(pieceInfo.RangeSize),
+			TrafficType: commonv2.TrafficType_LOCAL_PEER,
+			CreatedAt:   time.Now(),
+		}
+
+		if len(pieceInfo.PieceMd5) > 0 {
+			piece.Digest = digest.New(digest.AlgorithmMD5, pieceInfo.PieceMd5)
+		}
+
+		peer.StorePiece(piece)
+		task.StorePiece(piece)
+	}
+
+	for number := int32(0); number < packet.TotalPiece; number++ {
+		peer.FinishedPieces.Set(uint(number))
+	}
+
+	s.peerManager.Store(peer)
+	for _, event := range []string{PeerEventRegisterNormal, PeerEventDownload, PeerEventDownloadSucceeded} {
+		if err := peer.FSM.Event(ctx, event); err != nil {
+			return nil, err
+		}
+	}
+
+	peer.Log.Info("seed peer has been restored")
+	return peer, nil
+}
+
 // Client is seed peer grpc client.
 func (s *seedPeer) Client() SeedPeerClient {
 	return s.client
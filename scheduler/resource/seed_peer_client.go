@@ -138,6 +138,11 @@ func (sc *seedPeerClient) OnNotify(data *config.DynconfigData) {
 		return
 	}
 
+	// Log the version of the cluster config, so that the config rollout is traceable.
+	if clusterConfig, err := config.GetSchedulerClusterConfigByScheduler(data.Scheduler); err == nil {
+		logger.Infof("apply scheduler cluster config version %d", clusterConfig.Version)
+	}
+
 	// Update seed peers for host manager.
 	sc.updateSeedPeersForHostManager(data.Scheduler.SeedPeers)
 
@@ -154,9 +159,10 @@ func (sc *seedPeerClient) updateSeedPeersForHostManager(seedPeers []*managerv2.S
 		var concurrentUploadLimit int32
 		if config, err := config.GetSeedPeerClusterConfigBySeedPeer(seedPeer); err == nil {
 			concurrentUploadLimit = int32(config.LoadLimit)
+			logger.Infof("apply seed peer cluster config version %d of seed peer %s", config.Version, seedPeer.Hostname)
 		}
 
-		id := idgen.HostIDV2(seedPeer.Ip, seedPeer.Hostname)
+		id := idgen.HostID(seedPeer.Hostname, seedPeer.Ip)
 		seedPeerHost, loaded := sc.hostManager.Load(id)
 		if !loaded {
 			options := []HostOption{WithNetwork(Network{
@@ -56,6 +56,18 @@ func (mr *MockSeedPeerMockRecorder) Client() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Client", reflect.TypeOf((*MockSeedPeer)(nil).Client))
 }
 
+// RestoreTasks mocks base method.
+func (m *MockSeedPeer) RestoreTasks(arg0 context.Context, arg1 []*Task) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RestoreTasks", arg0, arg1)
+}
+
+// RestoreTasks indicates an expected call of RestoreTasks.
+func (mr *MockSeedPeerMockRecorder) RestoreTasks(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreTasks", reflect.TypeOf((*MockSeedPeer)(nil).RestoreTasks), arg0, arg1)
+}
+
 // Stop mocks base method.
 func (m *MockSeedPeer) Stop() error {
 	m.ctrl.T.Helper()
@@ -19,15 +19,27 @@ package resource
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	gomock "go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
+	cdnsystemv1 "d7y.io/api/v2/pkg/apis/cdnsystem/v1"
+	cdnsystemv1mocks "d7y.io/api/v2/pkg/apis/cdnsystem/v1/mocks"
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
 	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
 	dfdaemonv2 "d7y.io/api/v2/pkg/apis/dfdaemon/v2"
 	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+
+	"d7y.io/dragonfly/v2/pkg/container/set"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/scheduler/config"
 )
 
 func TestSeedPeer_newSeedPeer(t *testing.T) {
@@ -124,3 +136,293 @@ func TestSeedPeer_TriggerTask(t *testing.T) {
 		})
 	}
 }
+
+func TestSeedPeer_TriggerTaskWithAbortSeeding(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(task *Task, host *Host)
+		expect func(t *testing.T, task *Task, err error)
+	}{
+		{
+			name: "all interested peers leave during seeding",
+			run: func(task *Task, host *Host) {
+				peer := NewPeer(mockPeerID, mockResourceConfig, task, host)
+				task.StorePeer(peer)
+
+				time.Sleep(20 * time.Millisecond)
+				peer.FSM.SetState(PeerStateLeave)
+			},
+			expect: func(t *testing.T, task *Task, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrSeedingAborted)
+				assert.True(task.FSM.Is(TaskStateSeedingAborted))
+				assert.True(task.FSM.Can(TaskEventDownload))
+			},
+		},
+		{
+			name: "peer registers again during linger",
+			run: func(task *Task, host *Host) {
+				peer := NewPeer(mockPeerID, mockResourceConfig, task, host)
+				task.StorePeer(peer)
+
+				time.Sleep(20 * time.Millisecond)
+				peer.FSM.SetState(PeerStateLeave)
+
+				time.Sleep(20 * time.Millisecond)
+				task.StorePeer(NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, host))
+			},
+			expect: func(t *testing.T, task *Task, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "foo")
+				assert.True(task.FSM.Is(TaskStateRunning))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			hostManager := NewMockHostManager(ctl)
+			peerManager := NewMockPeerManager(ctl)
+			client := NewMockSeedPeerClient(ctl)
+			stream := cdnsystemv1mocks.NewMockSeeder_ObtainSeedsClient(ctl)
+
+			// The seed stream finishes after 500ms unless the seeding is aborted.
+			client.EXPECT().ObtainSeeds(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, req *cdnsystemv1.SeedRequest, opts ...grpc.CallOption) (cdnsystemv1.Seeder_ObtainSeedsClient, error) {
+					stream.EXPECT().Recv().DoAndReturn(func() (*cdnsystemv1.PieceSeed, error) {
+						select {
+						case <-ctx.Done():
+							return nil, status.Error(codes.Canceled, ctx.Err().Error())
+						case <-time.After(500 * time.Millisecond):
+							return nil, errors.New("foo")
+						}
+					}).Times(1)
+					return stream, nil
+				}).Times(1)
+
+			cfg := &config.Config{
+				Resource: *mockResourceConfig,
+				SeedPeer: config.SeedPeerConfig{
+					AbortSeeding: config.AbortSeedingConfig{
+						Enable: true,
+						Linger: 100 * time.Millisecond,
+					},
+				},
+			}
+			seedPeer := newSeedPeer(cfg, client, peerManager, hostManager)
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			mockTask.FSM.SetState(TaskStateRunning)
+
+			go tc.run(mockTask, mockHost)
+			_, _, err := seedPeer.TriggerTask(context.Background(), nil, mockTask)
+			tc.expect(t, mockTask, err)
+		})
+	}
+}
+
+func TestSeedPeer_restoreTask(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(task *Task, host *Host, mc *MockSeedPeerClientMockRecorder, mh *MockHostManagerMockRecorder, mp *MockPeerManagerMockRecorder)
+		expect func(t *testing.T, task *Task, peer *Peer, err error)
+	}{
+		{
+			name: "restore seed peer without obtaining seeds",
+			mock: func(task *Task, host *Host, mc *MockSeedPeerClientMockRecorder, mh *MockHostManagerMockRecorder, mp *MockPeerManagerMockRecorder) {
+				gomock.InOrder(
+					mh.Load(gomock.Eq(host.ID)).Return(host, true).Times(1),
+					mc.GetPieceTasks(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+						func(ctx context.Context, req *commonv1.PieceTaskRequest, opts ...grpc.CallOption) (*commonv1.PiecePacket, error) {
+							assert.Equal(t, mockSeedPeerID, req.DstPid)
+							return &commonv1.PiecePacket{
+								TaskId:        task.ID,
+								DstPid:        mockSeedPeerID,
+								PieceInfos:    []*commonv1.PieceInfo{{PieceNum: 0, RangeStart: 0, RangeSize: 1024, PieceMd5: "foo"}},
+								TotalPiece:    1,
+								ContentLength: 1024,
+							}, nil
+						}).Times(1),
+					mp.Load(gomock.Eq(mockSeedPeerID)).Return(nil, false).Times(1),
+					mp.Store(gomock.Any()).Do(func(peer *Peer) { task.StorePeer(peer) }).Times(1),
+				)
+			},
+			expect: func(t *testing.T, task *Task, peer *Peer, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockSeedPeerID, peer.ID)
+				assert.True(peer.FSM.Is(PeerStateSucceeded))
+				assert.Equal(uint(1), peer.FinishedPieces.Count())
+				piece, loaded := task.LoadPiece(0)
+				assert.True(loaded)
+				assert.Equal(uint64(1024), piece.Length)
+				assert.True(task.HasAvailablePeer(set.NewSafeSet[string]()))
+				assert.Empty(task.LoadRestoredSeedPeers())
+			},
+		},
+		{
+			name: "restore seed peer after transient error of getting pieces",
+			mock: func(task *Task, host *Host, mc *MockSeedPeerClientMockRecorder, mh *MockHostManagerMockRecorder, mp *MockPeerManagerMockRecorder) {
+				gomock.InOrder(
+					mh.Load(gomock.Eq(host.ID)).Return(host, true).Times(1),
+					mc.GetPieceTasks(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Unavailable, "foo")).Times(1),
+					mc.GetPieceTasks(gomock.Any(), gomock.Any(), gomock.Any()).Return(&commonv1.PiecePacket{
+						TaskId:        task.ID,
+						DstPid:        mockSeedPeerID,
+						PieceInfos:    []*commonv1.PieceInfo{{PieceNum: 0, RangeStart: 0, RangeSize: 1024, PieceMd5: "foo"}},
+						TotalPiece:    1,
+						ContentLength: 1024,
+					}, nil).Times(1),
+					mp.Load(gomock.Eq(mockSeedPeerID)).Return(nil, false).Times(1),
+					mp.Store(gomock.Any()).Do(func(peer *Peer) { task.StorePeer(peer) }).Times(1),
+				)
+			},
+			expect: func(t *testing.T, task *Task, peer *Peer, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockSeedPeerID, peer.ID)
+				assert.Equal(uint(1), peer.FinishedPieces.Count())
+			},
+		},
+		{
+			name: "seed peer does not have the data",
+			mock: func(task *Task, host *Host, mc *MockSeedPeerClientMockRecorder, mh *MockHostManagerMockRecorder, mp *MockPeerManagerMockRecorder) {
+				gomock.InOrder(
+					mh.Load(gomock.Eq(host.ID)).Return(host, true).Times(1),
+					mc.GetPieceTasks(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("foo")).Times(1),
+				)
+			},
+			expect: func(t *testing.T, task *Task, peer *Peer, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrRestoredSeedPeerNotFound)
+				assert.Empty(task.LoadRestoredSeedPeers())
+			},
+		},
+		{
+			name: "content length of seed peer does not match",
+			mock: func(task *Task, host *Host, mc *MockSeedPeerClientMockRecorder, mh *MockHostManagerMockRecorder, mp *MockPeerManagerMockRecorder) {
+				gomock.InOrder(
+					mh.Load(gomock.Eq(host.ID)).Return(host, true).Times(1),
+					mc.GetPieceTasks(gomock.Any(), gomock.Any(), gomock.Any()).Return(&commonv1.PiecePacket{
+						TotalPiece:    1,
+						ContentLength: 512,
+					}, nil).Times(1),
+				)
+			},
+			expect: func(t *testing.T, task *Task, peer *Peer, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrRestoredSeedPeerNotFound)
+			},
+		},
+		{
+			name: "verify the limited number of seed peers",
+			mock: func(task *Task, host *Host, mc *MockSeedPeerClientMockRecorder, mh *MockHostManagerMockRecorder, mp *MockPeerManagerMockRecorder) {
+				var seedPeers []*TaskSeedPeerSnapshot
+				for i := 0; i < seedPeerRestoreLimit+2; i++ {
+					seedPeers = append(seedPeers, &TaskSeedPeerSnapshot{ID: fmt.Sprintf("%s-%d", mockSeedPeerID, i), HostID: host.ID})
+				}
+				task.StoreRestoredSeedPeers(seedPeers)
+
+				mh.Load(gomock.Eq(host.ID)).Return(nil, false).Times(seedPeerRestoreLimit)
+			},
+			expect: func(t *testing.T, task *Task, peer *Peer, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrRestoredSeedPeerNotFound)
+				assert.Empty(task.LoadRestoredSeedPeers())
+			},
+		},
+		{
+			name: "can not find seed host",
+			mock: func(task *Task, host *Host, mc *MockSeedPeerClientMockRecorder, mh *MockHostManagerMockRecorder, mp *MockPeerManagerMockRecorder) {
+				mh.Load(gomock.Eq(host.ID)).Return(nil, false).Times(1)
+			},
+			expect: func(t *testing.T, task *Task, peer *Peer, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, ErrRestoredSeedPeerNotFound)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			hostManager := NewMockHostManager(ctl)
+			peerManager := NewMockPeerManager(ctl)
+			client := NewMockSeedPeerClient(ctl)
+
+			mockSeedHost := NewHost(
+				mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+				mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+			mockTask := NewTaskFromSnapshot(&TaskSnapshot{
+				ID:              mockTaskID,
+				Type:            commonv2.TaskType_DFDAEMON,
+				URL:             mockTaskURL,
+				ContentLength:   1024,
+				TotalPieceCount: 1,
+				State:           TaskStateSucceeded,
+				SeedPeers:       []*TaskSeedPeerSnapshot{{ID: mockSeedPeerID, HostID: mockSeedHost.ID}},
+			}, mockTaskBackToSourceLimit, time.Minute)
+			tc.mock(mockTask, mockSeedHost, client.EXPECT(), hostManager.EXPECT(), peerManager.EXPECT())
+
+			peer, err := newSeedPeer(mockConfig, client, peerManager, hostManager).(*seedPeer).restoreTask(context.Background(), mockTask)
+			tc.expect(t, mockTask, peer, err)
+		})
+	}
+}
+
+func TestSeedPeer_RestoreTasks(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	hostManager := NewMockHostManager(ctl)
+	peerManager := NewMockPeerManager(ctl)
+	client := NewMockSeedPeerClient(ctl)
+
+	mockSeedHost := NewHost(
+		mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+		mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+	var tasks []*Task
+	for i := 0; i < 2; i++ {
+		tasks = append(tasks, NewTaskFromSnapshot(&TaskSnapshot{
+			ID:              fmt.Sprintf("%s-%d", mockTaskID, i),
+			Type:            commonv2.TaskType_DFDAEMON,
+			URL:             mockTaskURL,
+			ContentLength:   1024,
+			TotalPieceCount: 1,
+			State:           TaskStateSucceeded,
+			SeedPeers:       []*TaskSeedPeerSnapshot{{ID: fmt.Sprintf("%s-%d", mockSeedPeerID, i), HostID: mockSeedHost.ID}},
+		}, mockTaskBackToSourceLimit, time.Minute))
+	}
+
+	hostManager.EXPECT().Load(gomock.Eq(mockSeedHost.ID)).Return(mockSeedHost, true).Times(2)
+	client.EXPECT().GetPieceTasks(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, req *commonv1.PieceTaskRequest, opts ...grpc.CallOption) (*commonv1.PiecePacket, error) {
+			// The seed peer of the first task does not have the data.
+			if req.TaskId == tasks[0].ID {
+				return nil, errors.New("foo")
+			}
+
+			return &commonv1.PiecePacket{
+				TaskId:        req.TaskId,
+				DstPid:        req.DstPid,
+				PieceInfos:    []*commonv1.PieceInfo{{PieceNum: 0, RangeStart: 0, RangeSize: 1024, PieceMd5: "foo"}},
+				TotalPiece:    1,
+				ContentLength: 1024,
+			}, nil
+		}).AnyTimes()
+	peerManager.EXPECT().Load(gomock.Any()).Return(nil, false).Times(1)
+	peerManager.EXPECT().Store(gomock.Any()).Do(func(peer *Peer) { peer.Task.StorePeer(peer) }).Times(1)
+
+	newSeedPeer(mockConfig, client, peerManager, hostManager).RestoreTasks(context.Background(), tasks)
+
+	assert := assert.New(t)
+	assert.False(tasks[0].HasAvailablePeer(set.NewSafeSet[string]()))
+	assert.True(tasks[1].HasAvailablePeer(set.NewSafeSet[string]()))
+	for _, task := range tasks {
+		assert.Empty(task.LoadRestoredSeedPeers())
+	}
+}
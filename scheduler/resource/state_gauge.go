@@ -0,0 +1,70 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/atomic"
+)
+
+// stateGauge counts the resource stored in the manager in the gauge by its state. The manager
+// counts the resource when it is stored, uncounts it when it is deleted and moves it to the
+// current state in the gc, so the resources which are not stored are never counted.
+type stateGauge struct {
+	// gauge is the gauge by state.
+	gauge *prometheus.GaugeVec
+
+	// state is the state the resource is counted by, it is empty if the resource is not counted.
+	state *atomic.String
+}
+
+// newStateGauge returns a new stateGauge.
+func newStateGauge(gauge *prometheus.GaugeVec) *stateGauge {
+	return &stateGauge{
+		gauge: gauge,
+		state: atomic.NewString(""),
+	}
+}
+
+// store counts the resource by the state.
+func (g *stateGauge) store(state string) {
+	if previousState := g.state.Swap(state); previousState != "" {
+		g.gauge.WithLabelValues(previousState).Dec()
+	}
+
+	g.gauge.WithLabelValues(state).Inc()
+}
+
+// delete uncounts the resource.
+func (g *stateGauge) delete() {
+	if state := g.state.Swap(""); state != "" {
+		g.gauge.WithLabelValues(state).Dec()
+	}
+}
+
+// update moves the counted resource to the state, the resource deleted concurrently is not counted again.
+func (g *stateGauge) update(state string) {
+	previousState := g.state.Load()
+	if previousState == "" || previousState == state {
+		return
+	}
+
+	if g.state.CompareAndSwap(previousState, state) {
+		g.gauge.WithLabelValues(previousState).Dec()
+		g.gauge.WithLabelValues(state).Inc()
+	}
+}
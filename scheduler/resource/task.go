@@ -19,12 +19,15 @@ package resource
 import (
 	"context"
 	"errors"
+	"fmt"
+	"math"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/looplab/fsm"
 	"go.uber.org/atomic"
+	"golang.org/x/time/rate"
 
 	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
 	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
@@ -34,8 +37,10 @@ import (
 	"d7y.io/dragonfly/v2/pkg/container/set"
 	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/graph/dag"
+	"d7y.io/dragonfly/v2/pkg/redact"
 	pkgstrings "d7y.io/dragonfly/v2/pkg/strings"
 	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/scheduler/metrics"
 )
 
 const (
@@ -69,6 +74,10 @@ const (
 
 	// Task has no peers.
 	TaskStateLeave = "Leave"
+
+	// Task seeding has been aborted because all interested peers left,
+	// the task can be seeded again.
+	TaskStateSeedingAborted = "SeedingAborted"
 )
 
 const (
@@ -83,8 +92,94 @@ const (
 
 	// Task leaves.
 	TaskEventLeave = "Leave"
+
+	// Task seeding is aborted.
+	TaskEventSeedingAborted = "SeedingAborted"
+)
+
+// TaskFailureReason is the reason why the task is failed.
+type TaskFailureReason string
+
+const (
+	// The origin responds with error, e.g. 404 Not Found.
+	TaskFailureReasonSourceError TaskFailureReason = "SourceError"
+
+	// Seed peer fails to register the task.
+	TaskFailureReasonSeedPeerRegisterFailed TaskFailureReason = "SeedPeerRegisterFailed"
+
+	// Seed peer fails to download the task, e.g. the disk of seed peer is full.
+	TaskFailureReasonSeedPeerDownloadFailed TaskFailureReason = "SeedPeerDownloadFailed"
+
+	// Peer fails to back-to-source without the response of the origin.
+	TaskFailureReasonBackToSourceFailed TaskFailureReason = "BackToSourceFailed"
+
+	// Too many peers fail to download the task, e.g. all parents are unreachable.
+	TaskFailureReasonRetryExhausted TaskFailureReason = "RetryExhausted"
+
+	// Peer exceeds the timeout of downloading task.
+	TaskFailureReasonDownloadTimeout TaskFailureReason = "DownloadTimeout"
 )
 
+// TaskFailure is the failure of the task.
+type TaskFailure struct {
+	// Reason is the reason of the failure.
+	Reason TaskFailureReason
+
+	// StatusCode is the status code responded by the origin, it is zero if the origin does not respond.
+	StatusCode int32
+
+	// Temporary is whether the failure is temporary, the task is not downloaded
+	// again within the back-to-source cooldown if the failure is not temporary.
+	Temporary bool
+
+	// Message is the detail of the failure.
+	Message string
+
+	// CreatedAt is the failure create time.
+	CreatedAt time.Time
+}
+
+// NewTaskFailure returns a new temporary task failure.
+func NewTaskFailure(reason TaskFailureReason, message string) *TaskFailure {
+	return &TaskFailure{
+		Reason:    reason,
+		Temporary: true,
+		Message:   message,
+		CreatedAt: time.Now(),
+	}
+}
+
+// String returns the readable description of the failure, e.g. origin returned 404.
+func (f *TaskFailure) String() string {
+	var desc string
+	switch f.Reason {
+	case TaskFailureReasonSourceError:
+		if f.StatusCode != 0 {
+			desc = fmt.Sprintf("origin returned %d", f.StatusCode)
+		} else {
+			desc = "origin returned error"
+		}
+	case TaskFailureReasonSeedPeerRegisterFailed:
+		desc = "seed peer failed to register task"
+	case TaskFailureReasonSeedPeerDownloadFailed:
+		desc = "seed peer failed to download task"
+	case TaskFailureReasonBackToSourceFailed:
+		desc = "peer failed to back-to-source"
+	case TaskFailureReasonRetryExhausted:
+		desc = "too many peers failed to download task"
+	case TaskFailureReasonDownloadTimeout:
+		desc = "download task timed out"
+	default:
+		desc = "task failed"
+	}
+
+	if f.Message != "" {
+		return fmt.Sprintf("%s: %s", desc, f.Message)
+	}
+
+	return desc
+}
+
 // TaskOption is a functional option for task.
 type TaskOption func(task *Task)
 
@@ -102,6 +197,34 @@ func WithDigest(d *digest.Digest) TaskOption {
 	}
 }
 
+// WithBackToSourceRateLimit set the back-to-source rate limiter for task, at most
+// limit peers of the task are allowed to back-to-source within the interval.
+func WithBackToSourceRateLimit(limit int, interval time.Duration) TaskOption {
+	return func(t *Task) {
+		if limit <= 0 || interval <= 0 {
+			return
+		}
+
+		t.backToSourceLimiter = rate.NewLimiter(rate.Every(interval/time.Duration(limit)), limit)
+	}
+}
+
+// WithBackToSourceCooldown set the cooldown of the back-to-source for task, no new peer of the task
+// is allowed to back-to-source within the cooldown after the source responds with server error.
+func WithBackToSourceCooldown(cooldown time.Duration) TaskOption {
+	return func(t *Task) {
+		t.backToSourceCooldown = cooldown
+	}
+}
+
+// WithDAGInvariantCheck checks the invariants of the peer DAG after every mutation,
+// it panics if the DAG is broken and is only used for debugging and tests.
+func WithDAGInvariantCheck() TaskOption {
+	return func(t *Task) {
+		t.DAG = dag.NewDAG[*Peer](dag.WithInvariantCheck[*Peer]())
+	}
+}
+
 // Task contains content for task.
 type Task struct {
 	// ID is task id.
@@ -146,6 +269,22 @@ type Task struct {
 	// BackToSourcePeers is back-to-source sync map.
 	BackToSourcePeers set.SafeSet[string]
 
+	// backToSourceLimiter staggers the peers of the task going back-to-source,
+	// it is nil if the back-to-source rate is not limited.
+	backToSourceLimiter *rate.Limiter
+
+	// backToSourceMu serializes granting the back-to-source of the peers, so that
+	// the peers scheduled concurrently do not exceed BackToSourceLimit.
+	backToSourceMu sync.Mutex
+
+	// backToSourceCooldown is the cooldown of the back-to-source after the source
+	// responds with server error, zero means no cooldown.
+	backToSourceCooldown time.Duration
+
+	// backToSourcePausedUntil is the time until which no new peer of the task
+	// is allowed to back-to-source.
+	backToSourcePausedUntil *atomic.Time
+
 	// Task state machine.
 	FSM *fsm.FSM
 
@@ -155,16 +294,41 @@ type Task struct {
 	// DAG is directed acyclic graph of peers.
 	DAG dag.DAG[*Peer]
 
+	// peerEdgesMu serializes the edge operations of the peers in DAG,
+	// e.g. replacing the parent of a peer.
+	peerEdgesMu sync.Mutex
+
+	// peersVersion is increased when the peers, the states of the peers or the edges
+	// in DAG are changed, the children are re-evaluated only after it is changed.
+	peersVersion atomic.Uint64
+
 	// PeerFailedCount is peer failed count,
 	// if one peer succeeds, the value is reset to zero.
 	PeerFailedCount *atomic.Int32
 
+	// failure is the failure of the last download, it is reset when the task is downloaded again.
+	failure atomic.Pointer[TaskFailure]
+
 	// CreatedAt is task create time.
 	CreatedAt *atomic.Time
 
 	// UpdatedAt is task update time.
 	UpdatedAt *atomic.Time
 
+	// RestoredUntil is the time until which the task restored from the snapshot
+	// is kept without peers, it is zero if the task is not restored.
+	RestoredUntil *atomic.Time
+
+	// restoredSeedPeers are the seed peers which had downloaded the task
+	// before the task was restored, they are verified before being reused.
+	restoredSeedPeers []*TaskSeedPeerSnapshot
+
+	// restoredSeedPeersMu is the mutex of restoredSeedPeers.
+	restoredSeedPeersMu sync.Mutex
+
+	// stateGauge counts the task stored in the task manager by state.
+	stateGauge *stateGauge
+
 	// Task log.
 	Log *logger.SugaredLoggerOnWith
 }
@@ -173,52 +337,67 @@ type Task struct {
 func NewTask(id, url, tag, application string, typ commonv2.TaskType, filteredQueryParams []string,
 	header map[string]string, backToSourceLimit int32, options ...TaskOption) *Task {
 	t := &Task{
-		ID:                  id,
-		Type:                typ,
-		URL:                 url,
-		Tag:                 tag,
-		Application:         application,
-		FilteredQueryParams: filteredQueryParams,
-		Header:              header,
-		DirectPiece:         []byte{},
-		ContentLength:       atomic.NewInt64(-1),
-		TotalPieceCount:     atomic.NewInt32(0),
-		BackToSourceLimit:   atomic.NewInt32(backToSourceLimit),
-		BackToSourcePeers:   set.NewSafeSet[string](),
-		Pieces:              &sync.Map{},
-		DAG:                 dag.NewDAG[*Peer](),
-		PeerFailedCount:     atomic.NewInt32(0),
-		CreatedAt:           atomic.NewTime(time.Now()),
-		UpdatedAt:           atomic.NewTime(time.Now()),
-		Log:                 logger.WithTask(id, url),
+		ID:                      id,
+		Type:                    typ,
+		URL:                     url,
+		Tag:                     tag,
+		Application:             application,
+		FilteredQueryParams:     filteredQueryParams,
+		Header:                  header,
+		DirectPiece:             []byte{},
+		ContentLength:           atomic.NewInt64(-1),
+		TotalPieceCount:         atomic.NewInt32(0),
+		BackToSourceLimit:       atomic.NewInt32(backToSourceLimit),
+		BackToSourcePeers:       set.NewSafeSet[string](),
+		backToSourcePausedUntil: atomic.NewTime(time.Time{}),
+		Pieces:                  &sync.Map{},
+		DAG:                     dag.NewDAG[*Peer](),
+		PeerFailedCount:         atomic.NewInt32(0),
+		CreatedAt:               atomic.NewTime(time.Now()),
+		UpdatedAt:               atomic.NewTime(time.Now()),
+		RestoredUntil:           atomic.NewTime(time.Time{}),
+		stateGauge:              newStateGauge(metrics.TaskGauge),
+		Log:                     logger.WithTask(id, redact.URL(url)),
 	}
 
 	// Initialize state machine.
 	t.FSM = fsm.NewFSM(
 		TaskStatePending,
 		fsm.Events{
-			{Name: TaskEventDownload, Src: []string{TaskStatePending, TaskStateSucceeded, TaskStateFailed, TaskStateLeave}, Dst: TaskStateRunning},
-			{Name: TaskEventDownloadSucceeded, Src: []string{TaskStateLeave, TaskStateRunning, TaskStateFailed}, Dst: TaskStateSucceeded},
+			{Name: TaskEventDownload, Src: []string{TaskStatePending, TaskStateSucceeded, TaskStateFailed, TaskStateLeave, TaskStateSeedingAborted}, Dst: TaskStateRunning},
+			{Name: TaskEventDownloadSucceeded, Src: []string{TaskStateLeave, TaskStateRunning, TaskStateFailed, TaskStateSeedingAborted}, Dst: TaskStateSucceeded},
 			{Name: TaskEventDownloadFailed, Src: []string{TaskStateRunning}, Dst: TaskStateFailed},
-			{Name: TaskEventLeave, Src: []string{TaskStatePending, TaskStateRunning, TaskStateSucceeded, TaskStateFailed}, Dst: TaskStateLeave},
+			{Name: TaskEventLeave, Src: []string{TaskStatePending, TaskStateRunning, TaskStateSucceeded, TaskStateFailed, TaskStateSeedingAborted}, Dst: TaskStateLeave},
+			{Name: TaskEventSeedingAborted, Src: []string{TaskStateRunning}, Dst: TaskStateSeedingAborted},
 		},
 		fsm.Callbacks{
 			TaskEventDownload: func(ctx context.Context, e *fsm.Event) {
+				t.failure.Store(nil)
 				t.UpdatedAt.Store(time.Now())
 				t.Log.Infof("task state is %s", e.FSM.Current())
 			},
 			TaskEventDownloadSucceeded: func(ctx context.Context, e *fsm.Event) {
+				t.failure.Store(nil)
 				t.UpdatedAt.Store(time.Now())
 				t.Log.Infof("task state is %s", e.FSM.Current())
 			},
 			TaskEventDownloadFailed: func(ctx context.Context, e *fsm.Event) {
 				t.UpdatedAt.Store(time.Now())
+				if failure := t.failure.Load(); failure != nil {
+					t.Log.Infof("task state is %s, because of %s", e.FSM.Current(), failure)
+					return
+				}
+
 				t.Log.Infof("task state is %s", e.FSM.Current())
 			},
 			TaskEventLeave: func(ctx context.Context, e *fsm.Event) {
 				t.UpdatedAt.Store(time.Now())
 				t.Log.Infof("task state is %s", e.FSM.Current())
 			},
+			TaskEventSeedingAborted: func(ctx context.Context, e *fsm.Event) {
+				t.UpdatedAt.Store(time.Now())
+				t.Log.Infof("task state is %s", e.FSM.Current())
+			},
 		},
 	)
 
@@ -276,20 +455,34 @@ func (t *Task) LoadFinishedPeers() []*Peer {
 
 // StorePeer set peer.
 func (t *Task) StorePeer(peer *Peer) {
-	t.DAG.AddVertex(peer.ID, peer) // nolint: errcheck
+	if err := t.DAG.AddVertex(peer.ID, peer); err != nil {
+		return
+	}
+
+	t.peersVersion.Inc()
 }
 
 // DeletePeer deletes peer for a key.
 func (t *Task) DeletePeer(key string) {
-	if err := t.DeletePeerInEdges(key); err != nil {
+	t.peerEdgesMu.Lock()
+	defer t.peerEdgesMu.Unlock()
+
+	if err := t.deletePeerInEdges(key); err != nil {
 		t.Log.Error(err)
 	}
 
-	if err := t.DeletePeerOutEdges(key); err != nil {
+	if err := t.deletePeerOutEdges(key); err != nil {
 		t.Log.Error(err)
 	}
 
 	t.DAG.DeleteVertex(key)
+	t.peersVersion.Inc()
+}
+
+// PeersVersion returns the version of the peers, it is changed when the peers,
+// the states of the peers or the edges in DAG are changed.
+func (t *Task) PeersVersion() uint64 {
+	return t.peersVersion.Load()
 }
 
 // PeerCount returns count of peer.
@@ -297,20 +490,96 @@ func (t *Task) PeerCount() int {
 	return int(t.DAG.VertexCount())
 }
 
-// AddPeerEdge adds inedges between two peers.
+// AddPeerEdge adds inedges between two peers. If the edge makes a cycle in DAG,
+// it returns dag.ErrCycleBetweenVertices and the caller should choose another parent.
 func (t *Task) AddPeerEdge(fromPeer *Peer, toPeer *Peer) error {
+	t.peerEdgesMu.Lock()
+	defer t.peerEdgesMu.Unlock()
+
+	return t.addPeerEdge(fromPeer, toPeer)
+}
+
+// ReplacePeerParent deletes inedges of peer and adds edge from parent to peer.
+// If the edge makes a cycle in DAG, the inedges of peer are rolled back, it returns
+// dag.ErrCycleBetweenVertices and the caller should choose another parent.
+func (t *Task) ReplacePeerParent(parent *Peer, peer *Peer) error {
+	_, err := t.ReplacePeerParents(peer, []*Peer{parent})
+	return err
+}
+
+// ReplacePeerParents deletes inedges of peer and adds edges from parents to peer in one step,
+// the parents making a cycle in DAG are skipped. It returns the parents whose edges are added,
+// if no edge is added, the inedges of peer are rolled back and the error is returned.
+func (t *Task) ReplacePeerParents(peer *Peer, parents []*Peer) ([]*Peer, error) {
+	t.peerEdgesMu.Lock()
+	defer t.peerEdgesMu.Unlock()
+
+	previousParents := peer.Parents()
+	if err := t.deletePeerInEdges(peer.ID); err != nil {
+		return nil, err
+	}
+
+	var (
+		replacedParents []*Peer
+		err             = errors.New("parents not found")
+	)
+	for _, parent := range parents {
+		if err = t.addPeerEdge(parent, peer); err != nil {
+			peer.Log.Warnf("peer adds edge with parent %s failed: %s", parent.ID, err.Error())
+			continue
+		}
+
+		replacedParents = append(replacedParents, parent)
+	}
+
+	if len(replacedParents) > 0 {
+		return replacedParents, nil
+	}
+
+	// Roll back the inedges of peer, the upload count of the previous parents is not counted again.
+	for _, parent := range previousParents {
+		if err := t.DAG.AddEdge(parent.ID, peer.ID); err != nil {
+			peer.Log.Errorf("peer rolls back edge with parent %s failed: %s", parent.ID, err.Error())
+			continue
+		}
+
+		parent.Host.ConcurrentUploadCount.Inc()
+	}
+
+	return nil, err
+}
+
+// DeletePeerInEdges deletes inedges of peer.
+func (t *Task) DeletePeerInEdges(key string) error {
+	t.peerEdgesMu.Lock()
+	defer t.peerEdgesMu.Unlock()
+
+	return t.deletePeerInEdges(key)
+}
+
+// DeletePeerOutEdges deletes outedges of peer.
+func (t *Task) DeletePeerOutEdges(key string) error {
+	t.peerEdgesMu.Lock()
+	defer t.peerEdgesMu.Unlock()
+
+	return t.deletePeerOutEdges(key)
+}
+
+// addPeerEdge adds inedges between two peers, the caller must hold peerEdgesMu.
+func (t *Task) addPeerEdge(fromPeer *Peer, toPeer *Peer) error {
 	if err := t.DAG.AddEdge(fromPeer.ID, toPeer.ID); err != nil {
 		return err
 	}
 
 	fromPeer.Host.UploadCount.Inc()
 	fromPeer.Host.ConcurrentUploadCount.Inc()
+	t.peersVersion.Inc()
 	t.Log.Infof("increment %s concurrent upload count, because of add edge from %s to %s", fromPeer.Host.ID, fromPeer.ID, toPeer.ID)
 	return nil
 }
 
-// DeletePeerInEdges deletes inedges of peer.
-func (t *Task) DeletePeerInEdges(key string) error {
+// deletePeerInEdges deletes inedges of peer, the caller must hold peerEdgesMu.
+func (t *Task) deletePeerInEdges(key string) error {
 	vertex, err := t.DAG.GetVertex(key)
 	if err != nil {
 		return err
@@ -329,11 +598,12 @@ func (t *Task) DeletePeerInEdges(key string) error {
 		return err
 	}
 
+	t.peersVersion.Inc()
 	return nil
 }
 
-// DeletePeerOutEdges deletes outedges of peer.
-func (t *Task) DeletePeerOutEdges(key string) error {
+// deletePeerOutEdges deletes outedges of peer, the caller must hold peerEdgesMu.
+func (t *Task) deletePeerOutEdges(key string) error {
 	vertex, err := t.DAG.GetVertex(key)
 	if err != nil {
 		return err
@@ -350,6 +620,7 @@ func (t *Task) DeletePeerOutEdges(key string) error {
 		return err
 	}
 
+	t.peersVersion.Inc()
 	return nil
 }
 
@@ -358,6 +629,27 @@ func (t *Task) CanAddPeerEdge(fromPeerKey, toPeerKey string) bool {
 	return t.DAG.CanAddEdge(fromPeerKey, toPeerKey)
 }
 
+// CanReplacePeerParent finds whether the parent can be the parent of peer after the inedges of peer
+// are replaced, the current parent of peer can be replaced with itself.
+func (t *Task) CanReplacePeerParent(parentKey, peerKey string) bool {
+	if t.DAG.CanAddEdge(parentKey, peerKey) {
+		return true
+	}
+
+	vertex, err := t.DAG.GetVertex(peerKey)
+	if err != nil {
+		return false
+	}
+
+	for _, parent := range vertex.Parents.Values() {
+		if parent.ID == parentKey {
+			return true
+		}
+	}
+
+	return false
+}
+
 // PeerDegree returns the degree of peer.
 func (t *Task) PeerDegree(key string) (int, error) {
 	vertex, err := t.DAG.GetVertex(key)
@@ -412,6 +704,32 @@ func (t *Task) HasAvailablePeer(blocklist set.SafeSet[string]) bool {
 	return hasAvailablePeer
 }
 
+// InterestedPeerCount returns the number of normal peers which
+// are interested in the task and have not reached the terminal state.
+func (t *Task) InterestedPeerCount() int {
+	var count int
+	for _, vertex := range t.DAG.GetVertices() {
+		peer := vertex.Value
+		if peer == nil {
+			continue
+		}
+
+		if peer.Host.Type != types.HostTypeNormal {
+			continue
+		}
+
+		if peer.FSM.Is(PeerStateSucceeded) ||
+			peer.FSM.Is(PeerStateFailed) ||
+			peer.FSM.Is(PeerStateLeave) {
+			continue
+		}
+
+		count++
+	}
+
+	return count
+}
+
 // LoadSeedPeer return latest seed peer in peers sync map.
 func (t *Task) LoadSeedPeer() (*Peer, bool) {
 	var peers []*Peer
@@ -466,8 +784,15 @@ func (t *Task) DeletePiece(key int32) {
 	t.Pieces.Delete(key)
 }
 
-// SizeScope return task size scope type.
+// SizeScope return task size scope type with the default file size limits.
 func (t *Task) SizeScope() commonv2.SizeScope {
+	return t.SizeScopeWithLimits(TinyFileSize, math.MaxInt64)
+}
+
+// SizeScopeWithLimits return task size scope type, the task is tiny if the content length
+// is less than or equal to tinyFileSizeLimit, and the task is small if it has only one piece
+// and the content length is less than or equal to smallFileSizeLimit.
+func (t *Task) SizeScopeWithLimits(tinyFileSizeLimit, smallFileSizeLimit int64) commonv2.SizeScope {
 	if t.ContentLength.Load() < 0 {
 		return commonv2.SizeScope_UNKNOW
 	}
@@ -480,20 +805,129 @@ func (t *Task) SizeScope() commonv2.SizeScope {
 		return commonv2.SizeScope_EMPTY
 	}
 
-	if t.ContentLength.Load() <= TinyFileSize {
+	if t.ContentLength.Load() <= tinyFileSizeLimit {
 		return commonv2.SizeScope_TINY
 	}
 
-	if t.TotalPieceCount.Load() == 1 {
+	if t.TotalPieceCount.Load() == 1 && t.ContentLength.Load() <= smallFileSizeLimit {
 		return commonv2.SizeScope_SMALL
 	}
 
 	return commonv2.SizeScope_NORMAL
 }
 
-// CanBackToSource represents whether task can back-to-source.
+// CanBackToSource represents whether task can back-to-source, the peers going
+// back-to-source are fewer than BackToSourceLimit.
 func (t *Task) CanBackToSource() bool {
-	return int32(t.BackToSourcePeers.Len()) <= t.BackToSourceLimit.Load() && (t.Type == commonv2.TaskType_DFDAEMON || t.Type == commonv2.TaskType_DFSTORE)
+	return int32(t.BackToSourcePeers.Len()) < t.BackToSourceLimit.Load() && (t.Type == commonv2.TaskType_DFDAEMON || t.Type == commonv2.TaskType_DFSTORE)
+}
+
+// AcquireBackToSource grants the peer to back-to-source, it returns false if the peers going
+// back-to-source reach BackToSourceLimit or the back-to-source of the task is paused.
+// The check and the increment are atomic, the peer granted already is granted again.
+func (t *Task) AcquireBackToSource(peerID string) bool {
+	t.backToSourceMu.Lock()
+	defer t.backToSourceMu.Unlock()
+
+	if t.BackToSourcePeers.Contains(peerID) {
+		return true
+	}
+
+	if time.Now().Before(t.backToSourcePausedUntil.Load()) {
+		return false
+	}
+
+	if int32(t.BackToSourcePeers.Len()) >= t.BackToSourceLimit.Load() {
+		return false
+	}
+
+	t.addBackToSourcePeer(peerID)
+	return true
+}
+
+// AddBackToSourcePeer adds the peer going back-to-source.
+func (t *Task) AddBackToSourcePeer(peerID string) {
+	t.backToSourceMu.Lock()
+	defer t.backToSourceMu.Unlock()
+
+	t.addBackToSourcePeer(peerID)
+}
+
+// addBackToSourcePeer adds the peer going back-to-source, the caller must hold backToSourceMu.
+func (t *Task) addBackToSourcePeer(peerID string) {
+	if t.BackToSourcePeers.Add(peerID) {
+		metrics.BackToSourcePeerGauge.WithLabelValues(t.Application).Inc()
+	}
+}
+
+// DeleteBackToSourcePeer deletes the peer going back-to-source, the slot of the peer is released.
+func (t *Task) DeleteBackToSourcePeer(peerID string) {
+	t.backToSourceMu.Lock()
+	defer t.backToSourceMu.Unlock()
+
+	if !t.BackToSourcePeers.Contains(peerID) {
+		return
+	}
+
+	t.BackToSourcePeers.Delete(peerID)
+	metrics.BackToSourcePeerGauge.WithLabelValues(t.Application).Dec()
+}
+
+// PauseBackToSource pauses granting the peers of the task to back-to-source within the cooldown,
+// it is called when the source responds with server error, so that the source can recover.
+func (t *Task) PauseBackToSource() {
+	if t.backToSourceCooldown <= 0 {
+		return
+	}
+
+	t.backToSourcePausedUntil.Store(time.Now().Add(t.backToSourceCooldown))
+	t.Log.Infof("back-to-source is paused for %s", t.backToSourceCooldown)
+}
+
+// SetFailure records the failure of the task before the task switches to TaskStateFailed,
+// the failure is ignored if the task can not switch to TaskStateFailed.
+func (t *Task) SetFailure(failure *TaskFailure) {
+	if !t.FSM.Can(TaskEventDownloadFailed) {
+		return
+	}
+
+	t.failure.Store(failure)
+}
+
+// Failure returns the failure of the last download, it is nil if the task is not failed.
+func (t *Task) Failure() *TaskFailure {
+	return t.failure.Load()
+}
+
+// PermanentFailure returns the failure if the task failed because of the failure which is
+// not temporary within the back-to-source cooldown, so the new peers of the task are rejected
+// with the failure instead of downloading the task again.
+func (t *Task) PermanentFailure() (*TaskFailure, bool) {
+	if !t.FSM.Is(TaskStateFailed) {
+		return nil, false
+	}
+
+	failure := t.failure.Load()
+	if failure == nil || failure.Temporary {
+		return nil, false
+	}
+
+	if time.Since(failure.CreatedAt) >= t.backToSourceCooldown {
+		return nil, false
+	}
+
+	return failure, true
+}
+
+// WaitBackToSource blocks until the peer is allowed to back-to-source by
+// the back-to-source rate limiter, so that the source is not overloaded when
+// many peers of the task need to back-to-source at the same time.
+func (t *Task) WaitBackToSource(ctx context.Context) error {
+	if t.backToSourceLimiter == nil {
+		return nil
+	}
+
+	return t.backToSourceLimiter.Wait(ctx)
 }
 
 // CanReuseDirectPiece represents whether task can reuse data of direct piece.
@@ -511,12 +945,11 @@ func (t *Task) ReportPieceResultToPeers(peerPacket *schedulerv1.PeerPacket, even
 		}
 
 		if peer.FSM.Is(PeerStateRunning) {
-			stream, loaded := peer.LoadReportPieceResultStream()
-			if !loaded {
+			if _, loaded := peer.LoadReportPieceResultStream(); !loaded {
 				continue
 			}
 
-			if err := stream.Send(peerPacket); err != nil {
+			if err := peer.SendPeerPacket(peerPacket); err != nil {
 				t.Log.Errorf("send packet to peer %s failed: %s", peer.ID, err.Error())
 				continue
 			}
@@ -540,12 +973,12 @@ func (t *Task) AnnouncePeers(resp *schedulerv2.AnnouncePeerResponse, event strin
 		}
 
 		if peer.FSM.Is(PeerStateRunning) {
-			stream, loaded := peer.LoadAnnouncePeerStream()
+			_, loaded := peer.LoadAnnouncePeerStream()
 			if !loaded {
 				continue
 			}
 
-			if err := stream.Send(resp); err != nil {
+			if err := peer.SendAnnouncePeerResponse(resp); err != nil {
 				t.Log.Errorf("send response to peer %s failed: %s", peer.ID, err.Error())
 				continue
 			}
@@ -20,6 +20,7 @@ package resource
 
 import (
 	"sync"
+	"time"
 
 	pkggc "d7y.io/dragonfly/v2/pkg/gc"
 	"d7y.io/dragonfly/v2/scheduler/config"
@@ -90,7 +91,11 @@ func (t *taskManager) Load(key string) (*Task, bool) {
 
 // Store sets task.
 func (t *taskManager) Store(task *Task) {
-	t.Map.Store(task.ID, task)
+	if rawTask, loaded := t.Map.Swap(task.ID, task); loaded {
+		rawTask.(*Task).stateGauge.delete()
+	}
+
+	task.stateGauge.store(task.FSM.Current())
 }
 
 // LoadOrStore returns task the key if present.
@@ -98,12 +103,18 @@ func (t *taskManager) Store(task *Task) {
 // The loaded result is true if the task was loaded, false if stored.
 func (t *taskManager) LoadOrStore(task *Task) (*Task, bool) {
 	rawTask, loaded := t.Map.LoadOrStore(task.ID, task)
+	if !loaded {
+		task.stateGauge.store(task.FSM.Current())
+	}
+
 	return rawTask.(*Task), loaded
 }
 
 // Delete deletes task for a key.
 func (t *taskManager) Delete(key string) {
-	t.Map.Delete(key)
+	if rawTask, loaded := t.Map.LoadAndDelete(key); loaded {
+		rawTask.(*Task).stateGauge.delete()
+	}
 }
 
 // Range calls f sequentially for each key and value present in the map.
@@ -121,12 +132,15 @@ func (t *taskManager) RunGC() error {
 			return true
 		}
 
-		// If there is no peer then task will be reclaimed.
-		if task.PeerCount() == 0 {
+		// If there is no peer then task will be reclaimed,
+		// the restored task is kept until its ttl expires.
+		if task.PeerCount() == 0 && time.Now().After(task.RestoredUntil.Load()) {
 			task.Log.Info("task has been reclaimed")
 			t.Delete(task.ID)
+			return true
 		}
 
+		task.stateGauge.update(task.FSM.Current())
 		return true
 	})
 
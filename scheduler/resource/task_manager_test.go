@@ -337,6 +337,25 @@ func TestTaskManager_RunGC(t *testing.T) {
 				assert.Equal(task.FSM.Current(), TaskStatePending)
 			},
 		},
+		{
+			name: "restored task is kept until ttl expires",
+			mock: func(m *gc.MockGCMockRecorder) {
+				m.Add(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, taskManager TaskManager, mockTask *Task, mockPeer *Peer) {
+				assert := assert.New(t)
+				mockTask.RestoredUntil.Store(time.Now().Add(time.Minute))
+				taskManager.Store(mockTask)
+				assert.NoError(taskManager.RunGC())
+				_, loaded := taskManager.Load(mockTask.ID)
+				assert.Equal(loaded, true)
+
+				mockTask.RestoredUntil.Store(time.Now().Add(-time.Second))
+				assert.NoError(taskManager.RunGC())
+				_, loaded = taskManager.Load(mockTask.ID)
+				assert.Equal(loaded, false)
+			},
+		},
 	}
 
 	for _, tc := range tests {
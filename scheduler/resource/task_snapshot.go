@@ -0,0 +1,402 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/digest"
+	pkggc "d7y.io/dragonfly/v2/pkg/gc"
+	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/redact"
+	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/scheduler/config"
+)
+
+const (
+	// GC task snapshot id.
+	GCTaskSnapshotID = "task-snapshot"
+
+	// TaskSnapshotFileName is the default file name of the task snapshot in data directory.
+	TaskSnapshotFileName = "task_snapshot.json"
+)
+
+// TaskSnapshot is the metadata of the task persisted across scheduler restarts,
+// peers of the task are not persisted.
+type TaskSnapshot struct {
+	// ID is task id.
+	ID string `json:"id"`
+
+	// Type is task type.
+	Type commonv2.TaskType `json:"type"`
+
+	// URL is the redacted task download url, the signatures and tokens in the url are not
+	// persisted. The restored task gets the original url back from the next registration.
+	URL string `json:"url"`
+
+	// Digest of the task content, for example md5:xxx or sha256:yyy.
+	Digest string `json:"digest,omitempty"`
+
+	// URL tag identifies different task for same url.
+	Tag string `json:"tag,omitempty"`
+
+	// Application identifies different task for same url.
+	Application string `json:"application,omitempty"`
+
+	// FilteredQueryParams is filtered query params.
+	FilteredQueryParams []string `json:"filtered_query_params,omitempty"`
+
+	// Task request headers.
+	Header map[string]string `json:"header,omitempty"`
+
+	// Task piece length.
+	PieceLength int32 `json:"piece_length"`
+
+	// ContentLength is task total content length.
+	ContentLength int64 `json:"content_length"`
+
+	// TotalPieceCount is total piece count.
+	TotalPieceCount int32 `json:"total_piece_count"`
+
+	// DirectPiece is tiny piece data.
+	DirectPiece []byte `json:"direct_piece,omitempty"`
+
+	// State is the state of task.
+	State string `json:"state"`
+
+	// SeedPeers are the seed peers which have downloaded the task successfully.
+	SeedPeers []*TaskSeedPeerSnapshot `json:"seed_peers,omitempty"`
+
+	// CreatedAt is task create time.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TaskSeedPeerSnapshot is the seed peer which has downloaded the task successfully.
+type TaskSeedPeerSnapshot struct {
+	// ID is seed peer id.
+	ID string `json:"id"`
+
+	// HostID is host id of seed peer.
+	HostID string `json:"host_id"`
+}
+
+// Snapshot returns the snapshot of the task.
+func (t *Task) Snapshot() *TaskSnapshot {
+	snapshot := &TaskSnapshot{
+		ID:                  t.ID,
+		Type:                t.Type,
+		URL:                 redact.URL(t.URL),
+		Tag:                 t.Tag,
+		Application:         t.Application,
+		FilteredQueryParams: t.FilteredQueryParams,
+		Header:              nethttp.WithoutSensitiveHeaders(t.Header),
+		PieceLength:         t.PieceLength,
+		ContentLength:       t.ContentLength.Load(),
+		TotalPieceCount:     t.TotalPieceCount.Load(),
+		DirectPiece:         t.DirectPiece,
+		State:               t.FSM.Current(),
+		SeedPeers:           t.LoadRestoredSeedPeers(),
+		CreatedAt:           t.CreatedAt.Load(),
+	}
+
+	if t.Digest != nil {
+		snapshot.Digest = t.Digest.String()
+	}
+
+	for _, peer := range t.LoadPeers() {
+		if peer.Host.Type != types.HostTypeNormal && peer.FSM.Is(PeerStateSucceeded) {
+			snapshot.SeedPeers = append(snapshot.SeedPeers, &TaskSeedPeerSnapshot{
+				ID:     peer.ID,
+				HostID: peer.Host.ID,
+			})
+		}
+	}
+
+	return snapshot
+}
+
+// NewTaskFromSnapshot restores the task from the snapshot, the task which has been downloaded successfully
+// is restored to TaskStateSucceeded and others are restored to TaskStatePending. The restored task is kept
+// until ttl expires even if it has no peers. The options are applied before the fields of the snapshot.
+func NewTaskFromSnapshot(snapshot *TaskSnapshot, backToSourceLimit int32, ttl time.Duration, options ...TaskOption) *Task {
+	options = append(options[:len(options):len(options)], WithPieceLength(snapshot.PieceLength))
+	if d, err := digest.Parse(snapshot.Digest); err == nil {
+		options = append(options, WithDigest(d))
+	}
+
+	t := NewTask(snapshot.ID, snapshot.URL, snapshot.Tag, snapshot.Application, snapshot.Type,
+		snapshot.FilteredQueryParams, snapshot.Header, backToSourceLimit, options...)
+	t.ContentLength.Store(snapshot.ContentLength)
+	t.TotalPieceCount.Store(snapshot.TotalPieceCount)
+	if len(snapshot.DirectPiece) > 0 {
+		t.DirectPiece = snapshot.DirectPiece
+	}
+
+	if !snapshot.CreatedAt.IsZero() {
+		t.CreatedAt.Store(snapshot.CreatedAt)
+	}
+
+	if snapshot.State == TaskStateSucceeded {
+		t.FSM.SetState(TaskStateSucceeded)
+		t.StoreRestoredSeedPeers(snapshot.SeedPeers)
+	}
+
+	t.RestoredUntil.Store(time.Now().Add(ttl))
+	return t
+}
+
+// StoreRestoredSeedPeers sets the restored seed peers.
+func (t *Task) StoreRestoredSeedPeers(seedPeers []*TaskSeedPeerSnapshot) {
+	t.restoredSeedPeersMu.Lock()
+	defer t.restoredSeedPeersMu.Unlock()
+
+	t.restoredSeedPeers = seedPeers
+}
+
+// LoadRestoredSeedPeers returns the restored seed peers which have not been verified.
+func (t *Task) LoadRestoredSeedPeers() []*TaskSeedPeerSnapshot {
+	t.restoredSeedPeersMu.Lock()
+	defer t.restoredSeedPeersMu.Unlock()
+
+	return append([]*TaskSeedPeerSnapshot(nil), t.restoredSeedPeers...)
+}
+
+// LoadAndDeleteRestoredSeedPeers returns the restored seed peers and deletes them,
+// the restored seed peers are verified only once.
+func (t *Task) LoadAndDeleteRestoredSeedPeers() []*TaskSeedPeerSnapshot {
+	t.restoredSeedPeersMu.Lock()
+	defer t.restoredSeedPeersMu.Unlock()
+
+	seedPeers := t.restoredSeedPeers
+	t.restoredSeedPeers = nil
+	return seedPeers
+}
+
+// TaskSnapshotStore is the interface used for storage of the task snapshots.
+type TaskSnapshotStore interface {
+	// Save replaces all the persisted task snapshots.
+	Save(context.Context, []*TaskSnapshot) error
+
+	// Load returns all the persisted task snapshots.
+	Load(context.Context) ([]*TaskSnapshot, error)
+}
+
+// fileTaskSnapshotStore persists the task snapshots to the local file.
+type fileTaskSnapshotStore struct {
+	// path is the file path of the task snapshots.
+	path string
+}
+
+// NewFileTaskSnapshotStore returns TaskSnapshotStore persisting to the local file.
+func NewFileTaskSnapshotStore(path string) TaskSnapshotStore {
+	return &fileTaskSnapshotStore{path: path}
+}
+
+// Save replaces all the persisted task snapshots, the file is replaced atomically.
+func (f *fileTaskSnapshotStore) Save(ctx context.Context, snapshots []*TaskSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(snapshots); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// Load returns all the persisted task snapshots.
+func (f *fileTaskSnapshotStore) Load(ctx context.Context) ([]*TaskSnapshot, error) {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var snapshots []*TaskSnapshot
+	if err := json.Unmarshal(b, &snapshots); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+// redisTaskSnapshotStore persists the task snapshots to the redis hash.
+type redisTaskSnapshotStore struct {
+	// rdb is redis universal client interface.
+	rdb redis.UniversalClient
+
+	// key is the redis key of the task snapshots.
+	key string
+}
+
+// NewRedisTaskSnapshotStore returns TaskSnapshotStore persisting to redis.
+func NewRedisTaskSnapshotStore(rdb redis.UniversalClient, key string) TaskSnapshotStore {
+	return &redisTaskSnapshotStore{rdb: rdb, key: key}
+}
+
+// Save replaces all the persisted task snapshots in a transaction.
+func (r *redisTaskSnapshotStore) Save(ctx context.Context, snapshots []*TaskSnapshot) error {
+	values := make([]any, 0, 2*len(snapshots))
+	for _, snapshot := range snapshots {
+		b, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+
+		values = append(values, snapshot.ID, b)
+	}
+
+	pipe := r.rdb.TxPipeline()
+	pipe.Del(ctx, r.key)
+	if len(values) > 0 {
+		pipe.HSet(ctx, r.key, values...)
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Load returns all the persisted task snapshots.
+func (r *redisTaskSnapshotStore) Load(ctx context.Context) ([]*TaskSnapshot, error) {
+	rawSnapshots, err := r.rdb.HGetAll(ctx, r.key).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]*TaskSnapshot, 0, len(rawSnapshots))
+	for id, rawSnapshot := range rawSnapshots {
+		snapshot := &TaskSnapshot{}
+		if err := json.Unmarshal([]byte(rawSnapshot), snapshot); err != nil {
+			logger.Errorf("invalid task snapshot %s: %s", id, err.Error())
+			continue
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, nil
+}
+
+// taskSnapshotter takes the task snapshots periodically.
+type taskSnapshotter struct {
+	// config is the task persistence config.
+	config *config.TaskPersistenceConfig
+
+	// taskManager is task manager.
+	taskManager TaskManager
+
+	// store is storage of the task snapshots.
+	store TaskSnapshotStore
+
+	// restoredTasks are the restored tasks whose seed peers need to be restored.
+	restoredTasks []*Task
+}
+
+// newTaskSnapshotter restores the tasks from the store and takes the task snapshots periodically by gc,
+// the tasks are restored with the back-to-source limit of their applications and the task options.
+func newTaskSnapshotter(cfg *config.TaskPersistenceConfig, backToSourceLimit func(application string) int32, taskOptions []TaskOption,
+	taskManager TaskManager, store TaskSnapshotStore, gc pkggc.GC) (*taskSnapshotter, error) {
+	s := &taskSnapshotter{
+		config:      cfg,
+		taskManager: taskManager,
+		store:       store,
+	}
+
+	if err := s.restore(backToSourceLimit, taskOptions); err != nil {
+		return nil, err
+	}
+
+	if err := gc.Add(pkggc.Task{
+		ID:       GCTaskSnapshotID,
+		Interval: cfg.Interval,
+		Timeout:  cfg.Interval,
+		Runner:   s,
+	}); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// restore preloads the tasks from the store to task manager.
+func (s *taskSnapshotter) restore(backToSourceLimit func(application string) int32, taskOptions []TaskOption) error {
+	snapshots, err := s.store.Load(context.Background())
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range snapshots {
+		task := NewTaskFromSnapshot(snapshot, backToSourceLimit(snapshot.Application), s.config.TTL, taskOptions...)
+		if _, loaded := s.taskManager.LoadOrStore(task); !loaded {
+			task.Log.Infof("task has been restored in state %s", task.FSM.Current())
+			if len(task.LoadRestoredSeedPeers()) > 0 {
+				s.restoredTasks = append(s.restoredTasks, task)
+			}
+		}
+	}
+
+	logger.Infof("restore %d tasks from snapshot", len(snapshots))
+	return nil
+}
+
+// RunGC takes the snapshot of all the tasks in task manager, the tasks
+// reclaimed by task gc are removed from the store.
+func (s *taskSnapshotter) RunGC() error {
+	var snapshots []*TaskSnapshot
+	s.taskManager.Range(func(_, value any) bool {
+		task, ok := value.(*Task)
+		if !ok {
+			logger.Error("invalid task")
+			return true
+		}
+
+		snapshots = append(snapshots, task.Snapshot())
+		return true
+	})
+
+	if err := s.store.Save(context.Background(), snapshots); err != nil {
+		return err
+	}
+
+	logger.Debugf("save %d task snapshots", len(snapshots))
+	return nil
+}
@@ -0,0 +1,332 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resource
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v9"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
+
+	"d7y.io/dragonfly/v2/pkg/gc"
+	"d7y.io/dragonfly/v2/pkg/redact"
+	"d7y.io/dragonfly/v2/scheduler/config"
+)
+
+var (
+	mockTaskPersistenceConfig = &config.TaskPersistenceConfig{
+		Enable:   true,
+		Backend:  config.TaskPersistenceBackendFile,
+		Interval: time.Minute,
+		TTL:      time.Minute,
+	}
+
+	mockTaskSnapshot = &TaskSnapshot{
+		ID:                  mockTaskID,
+		Type:                commonv2.TaskType_DFDAEMON,
+		URL:                 mockTaskURL,
+		Digest:              mockTaskDigest.String(),
+		Tag:                 mockTaskTag,
+		Application:         mockTaskApplication,
+		FilteredQueryParams: mockTaskFilteredQueryParams,
+		Header:              mockTaskHeader,
+		PieceLength:         1024,
+		ContentLength:       2048,
+		TotalPieceCount:     2,
+		State:               TaskStateSucceeded,
+		SeedPeers:           []*TaskSeedPeerSnapshot{{ID: mockSeedPeerID, HostID: mockRawSeedHost.ID}},
+	}
+)
+
+func TestTask_Snapshot(t *testing.T) {
+	tests := []struct {
+		name   string
+		run    func(task *Task, seedHost *Host)
+		expect func(t *testing.T, snapshot *TaskSnapshot)
+	}{
+		{
+			name: "snapshot of the succeeded task",
+			run: func(task *Task, seedHost *Host) {
+				task.FSM.SetState(TaskStateSucceeded)
+				task.ContentLength.Store(2048)
+				task.TotalPieceCount.Store(2)
+
+				seedPeer := NewPeer(mockSeedPeerID, mockResourceConfig, task, seedHost)
+				seedPeer.FSM.SetState(PeerStateSucceeded)
+				task.StorePeer(seedPeer)
+			},
+			expect: func(t *testing.T, snapshot *TaskSnapshot) {
+				assert := assert.New(t)
+				assert.Equal(mockTaskID, snapshot.ID)
+				assert.Equal(mockTaskURL, snapshot.URL)
+				assert.Equal(mockTaskDigest.String(), snapshot.Digest)
+				assert.Equal(int64(2048), snapshot.ContentLength)
+				assert.Equal(int32(2), snapshot.TotalPieceCount)
+				assert.Equal(TaskStateSucceeded, snapshot.State)
+				assert.Equal([]*TaskSeedPeerSnapshot{{ID: mockSeedPeerID, HostID: mockRawSeedHost.ID}}, snapshot.SeedPeers)
+			},
+		},
+		{
+			name: "seed peer is running",
+			run: func(task *Task, seedHost *Host) {
+				task.FSM.SetState(TaskStateRunning)
+				seedPeer := NewPeer(mockSeedPeerID, mockResourceConfig, task, seedHost)
+				seedPeer.FSM.SetState(PeerStateRunning)
+				task.StorePeer(seedPeer)
+			},
+			expect: func(t *testing.T, snapshot *TaskSnapshot) {
+				assert := assert.New(t)
+				assert.Equal(TaskStateRunning, snapshot.State)
+				assert.Empty(snapshot.SeedPeers)
+			},
+		},
+		{
+			name: "redact signed url",
+			run: func(task *Task, seedHost *Host) {
+				task.URL = mockTaskURL + "?X-Amz-Signature=bar&foo=baz"
+			},
+			expect: func(t *testing.T, snapshot *TaskSnapshot) {
+				assert := assert.New(t)
+				assert.Equal(mockTaskURL+"?X-Amz-Signature="+redact.Redacted+"&foo=baz", snapshot.URL)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockSeedHost := NewHost(
+				mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+				mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+			task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDigest(mockTaskDigest))
+			tc.run(task, mockSeedHost)
+			tc.expect(t, task.Snapshot())
+		})
+	}
+}
+
+func TestTask_NewTaskFromSnapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		snapshot *TaskSnapshot
+		options  []TaskOption
+		expect   func(t *testing.T, task *Task)
+	}{
+		{
+			name:     "restore succeeded task",
+			snapshot: mockTaskSnapshot,
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(mockTaskID, task.ID)
+				assert.Equal(mockTaskDigest, task.Digest)
+				assert.Equal(int32(1024), task.PieceLength)
+				assert.Equal(int64(2048), task.ContentLength.Load())
+				assert.Equal(int32(2), task.TotalPieceCount.Load())
+				assert.True(task.FSM.Is(TaskStateSucceeded))
+				assert.Equal(mockTaskSnapshot.SeedPeers, task.LoadRestoredSeedPeers())
+				assert.True(task.RestoredUntil.Load().After(time.Now()))
+				assert.Equal(0, task.PeerCount())
+
+				// Restored seed peers are persisted again before they are verified.
+				assert.Equal(mockTaskSnapshot.SeedPeers, task.Snapshot().SeedPeers)
+			},
+		},
+		{
+			name: "restore running task",
+			snapshot: &TaskSnapshot{
+				ID:            mockTaskID,
+				URL:           mockTaskURL,
+				ContentLength: -1,
+				State:         TaskStateRunning,
+				SeedPeers:     []*TaskSeedPeerSnapshot{{ID: mockSeedPeerID, HostID: mockRawSeedHost.ID}},
+			},
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.True(task.FSM.Is(TaskStatePending))
+				assert.Nil(task.Digest)
+				assert.Equal(int64(-1), task.ContentLength.Load())
+				assert.Empty(task.LoadRestoredSeedPeers())
+			},
+		},
+		{
+			name:     "restore task with options",
+			snapshot: mockTaskSnapshot,
+			options: []TaskOption{
+				WithBackToSourceRateLimit(1, time.Second),
+				WithBackToSourceCooldown(time.Minute),
+				WithPieceLength(2048),
+			},
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.NotNil(task.backToSourceLimiter)
+				assert.Equal(time.Minute, task.backToSourceCooldown)
+
+				// Fields of the snapshot take precedence over the options.
+				assert.Equal(int32(1024), task.PieceLength)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, NewTaskFromSnapshot(tc.snapshot, mockTaskBackToSourceLimit, time.Minute, tc.options...))
+		})
+	}
+}
+
+func TestFileTaskSnapshotStore(t *testing.T) {
+	assert := assert.New(t)
+	store := NewFileTaskSnapshotStore(filepath.Join(t.TempDir(), "data", TaskSnapshotFileName))
+
+	// Load returns nothing before the first snapshot.
+	snapshots, err := store.Load(context.Background())
+	assert.NoError(err)
+	assert.Empty(snapshots)
+
+	assert.NoError(store.Save(context.Background(), []*TaskSnapshot{mockTaskSnapshot}))
+	snapshots, err = store.Load(context.Background())
+	assert.NoError(err)
+	assert.Len(snapshots, 1)
+	assert.Equal(mockTaskSnapshot.ID, snapshots[0].ID)
+	assert.Equal(mockTaskSnapshot.SeedPeers, snapshots[0].SeedPeers)
+
+	// Save replaces all the persisted snapshots.
+	assert.NoError(store.Save(context.Background(), nil))
+	snapshots, err = store.Load(context.Background())
+	assert.NoError(err)
+	assert.Empty(snapshots)
+}
+
+func TestRedisTaskSnapshotStore(t *testing.T) {
+	key := "scheduler:task-snapshots:1-foo-127.0.0.1"
+	rawSnapshot, err := json.Marshal(mockTaskSnapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T, store TaskSnapshotStore, mock redismock.ClientMock)
+	}{
+		{
+			name: "save snapshots",
+			run: func(t *testing.T, store TaskSnapshotStore, mock redismock.ClientMock) {
+				assert := assert.New(t)
+				mock.ExpectTxPipeline()
+				mock.ExpectDel(key).SetVal(1)
+				mock.ExpectHSet(key, mockTaskSnapshot.ID, rawSnapshot).SetVal(1)
+				mock.ExpectTxPipelineExec()
+				assert.NoError(store.Save(context.Background(), []*TaskSnapshot{mockTaskSnapshot}))
+			},
+		},
+		{
+			name: "save empty snapshots",
+			run: func(t *testing.T, store TaskSnapshotStore, mock redismock.ClientMock) {
+				assert := assert.New(t)
+				mock.ExpectTxPipeline()
+				mock.ExpectDel(key).SetVal(1)
+				mock.ExpectTxPipelineExec()
+				assert.NoError(store.Save(context.Background(), nil))
+			},
+		},
+		{
+			name: "load snapshots",
+			run: func(t *testing.T, store TaskSnapshotStore, mock redismock.ClientMock) {
+				assert := assert.New(t)
+				mock.ExpectHGetAll(key).SetVal(map[string]string{
+					mockTaskSnapshot.ID: string(rawSnapshot),
+					"bar":               "invalid",
+				})
+				snapshots, err := store.Load(context.Background())
+				assert.NoError(err)
+				assert.Len(snapshots, 1)
+				assert.Equal(mockTaskSnapshot.ID, snapshots[0].ID)
+			},
+		},
+		{
+			name: "load snapshots failed",
+			run: func(t *testing.T, store TaskSnapshotStore, mock redismock.ClientMock) {
+				assert := assert.New(t)
+				mock.ExpectHGetAll(key).SetErr(errors.New("foo"))
+				_, err := store.Load(context.Background())
+				assert.EqualError(err, "foo")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rdb, mock := redismock.NewClientMock()
+			tc.run(t, NewRedisTaskSnapshotStore(rdb, key), mock)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTaskSnapshotter(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	assert := assert.New(t)
+
+	store := NewFileTaskSnapshotStore(filepath.Join(t.TempDir(), TaskSnapshotFileName))
+	assert.NoError(store.Save(context.Background(), []*TaskSnapshot{mockTaskSnapshot}))
+
+	mockGC := gc.NewMockGC(ctl)
+	mockGC.EXPECT().Add(gomock.Any()).Return(nil).Times(2)
+	taskManager, err := newTaskManager(mockTaskGCConfig, mockGC)
+	assert.NoError(err)
+
+	// Tasks are restored when the snapshotter is initialized.
+	backToSourceLimit := func(application string) int32 {
+		if application == mockTaskApplication {
+			return mockTaskBackToSourceLimit + 1
+		}
+
+		return mockTaskBackToSourceLimit
+	}
+	snapshotter, err := newTaskSnapshotter(mockTaskPersistenceConfig, backToSourceLimit, []TaskOption{WithBackToSourceCooldown(time.Minute)},
+		taskManager, store, mockGC)
+	assert.NoError(err)
+	task, loaded := taskManager.Load(mockTaskID)
+	assert.True(loaded)
+	assert.True(task.FSM.Is(TaskStateSucceeded))
+	assert.Equal(int64(2048), task.ContentLength.Load())
+	assert.Equal(mockTaskBackToSourceLimit+1, task.BackToSourceLimit.Load())
+	assert.Equal(time.Minute, task.backToSourceCooldown)
+
+	// Restored task without peers is not reclaimed before ttl expires.
+	assert.NoError(taskManager.RunGC())
+	assert.NoError(snapshotter.RunGC())
+	snapshots, err := store.Load(context.Background())
+	assert.NoError(err)
+	assert.Len(snapshots, 1)
+
+	// Task reclaimed by gc is removed from the persisted snapshots.
+	task.RestoredUntil.Store(time.Now().Add(-time.Second))
+	assert.NoError(taskManager.RunGC())
+	assert.NoError(snapshotter.RunGC())
+	snapshots, err = store.Load(context.Background())
+	assert.NoError(err)
+	assert.Empty(snapshots)
+}
@@ -17,11 +17,14 @@
 package resource
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/atomic"
 	gomock "go.uber.org/mock/gomock"
 
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
@@ -33,6 +36,7 @@ import (
 
 	"d7y.io/dragonfly/v2/pkg/container/set"
 	"d7y.io/dragonfly/v2/pkg/digest"
+	"d7y.io/dragonfly/v2/pkg/graph/dag"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
@@ -370,6 +374,67 @@ func TestTask_DeletePeer(t *testing.T) {
 	}
 }
 
+func TestTask_PeersVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, mockHost *Host, task *Task)
+	}{
+		{
+			name: "version is changed when peers are changed",
+			expect: func(t *testing.T, mockHost *Host, task *Task) {
+				assert := assert.New(t)
+				mockPeerE := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerF := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+
+				version := task.PeersVersion()
+				task.StorePeer(mockPeerE)
+				task.StorePeer(mockPeerF)
+				assert.Greater(task.PeersVersion(), version)
+
+				version = task.PeersVersion()
+				assert.NoError(task.AddPeerEdge(mockPeerE, mockPeerF))
+				assert.Greater(task.PeersVersion(), version)
+
+				version = task.PeersVersion()
+				assert.NoError(task.DeletePeerInEdges(mockPeerF.ID))
+				assert.Greater(task.PeersVersion(), version)
+
+				version = task.PeersVersion()
+				assert.NoError(mockPeerE.FSM.Event(context.Background(), PeerEventRegisterNormal))
+				assert.Greater(task.PeersVersion(), version)
+
+				version = task.PeersVersion()
+				task.DeletePeer(mockPeerF.ID)
+				assert.Greater(task.PeersVersion(), version)
+			},
+		},
+		{
+			name: "version is not changed when peer is stored again",
+			expect: func(t *testing.T, mockHost *Host, task *Task) {
+				assert := assert.New(t)
+				mockPeer := NewPeer(mockPeerID, mockResourceConfig, task, mockHost)
+
+				task.StorePeer(mockPeer)
+				version := task.PeersVersion()
+				task.StorePeer(mockPeer)
+				mockPeer.FinishedPieces.Set(0)
+				assert.Equal(version, task.PeersVersion())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit)
+
+			tc.expect(t, mockHost, task)
+		})
+	}
+}
+
 func TestTask_PeerCount(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -504,6 +569,175 @@ func TestTask_AddPeerEdge(t *testing.T) {
 	}
 }
 
+func TestTask_ReplacePeerParent(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, mockHost *Host, task *Task)
+	}{
+		{
+			name: "replace peer parent",
+			expect: func(t *testing.T, mockHost *Host, task *Task) {
+				assert := assert.New(t)
+				mockPeerE := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerF := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerG := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+
+				task.StorePeer(mockPeerE)
+				task.StorePeer(mockPeerF)
+				task.StorePeer(mockPeerG)
+
+				assert.NoError(task.AddPeerEdge(mockPeerE, mockPeerG))
+				assert.NoError(task.AddPeerEdge(mockPeerF, mockPeerG))
+				assert.NoError(task.ReplacePeerParent(mockPeerE, mockPeerG))
+				assert.Equal(len(mockPeerG.Parents()), 1)
+				assert.Equal(mockPeerG.Parents()[0].ID, mockPeerE.ID)
+				assert.Equal(len(mockPeerF.Children()), 0)
+				assert.Equal(mockHost.ConcurrentUploadCount.Load(), int32(1))
+				assert.Equal(mockHost.UploadCount.Load(), int64(3))
+			},
+		},
+		{
+			name: "replace peer parent makes a cycle",
+			expect: func(t *testing.T, mockHost *Host, task *Task) {
+				assert := assert.New(t)
+				mockPeerE := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerF := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+
+				task.StorePeer(mockPeerE)
+				task.StorePeer(mockPeerF)
+
+				assert.NoError(task.AddPeerEdge(mockPeerE, mockPeerF))
+				assert.ErrorIs(task.ReplacePeerParent(mockPeerF, mockPeerE), dag.ErrCycleBetweenVertices)
+				assert.Equal(len(mockPeerE.Parents()), 0)
+				assert.Equal(mockPeerF.Parents()[0].ID, mockPeerE.ID)
+				assert.Equal(mockHost.ConcurrentUploadCount.Load(), int32(1))
+			},
+		},
+		{
+			name: "replace peer parent makes a cycle and previous parent is rolled back",
+			expect: func(t *testing.T, mockHost *Host, task *Task) {
+				assert := assert.New(t)
+				mockPeerE := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerF := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerG := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+
+				task.StorePeer(mockPeerE)
+				task.StorePeer(mockPeerF)
+				task.StorePeer(mockPeerG)
+
+				assert.NoError(task.AddPeerEdge(mockPeerG, mockPeerE))
+				assert.NoError(task.AddPeerEdge(mockPeerE, mockPeerF))
+				assert.ErrorIs(task.ReplacePeerParent(mockPeerF, mockPeerE), dag.ErrCycleBetweenVertices)
+				assert.Equal(len(mockPeerE.Parents()), 1)
+				assert.Equal(mockPeerE.Parents()[0].ID, mockPeerG.ID)
+				assert.Equal(mockHost.ConcurrentUploadCount.Load(), int32(2))
+				assert.Equal(mockHost.UploadCount.Load(), int64(2))
+			},
+		},
+		{
+			name: "replace peer parents skips parent making a cycle",
+			expect: func(t *testing.T, mockHost *Host, task *Task) {
+				assert := assert.New(t)
+				mockPeerE := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerF := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerG := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+
+				task.StorePeer(mockPeerE)
+				task.StorePeer(mockPeerF)
+				task.StorePeer(mockPeerG)
+
+				assert.NoError(task.AddPeerEdge(mockPeerE, mockPeerF))
+				parents, err := task.ReplacePeerParents(mockPeerE, []*Peer{mockPeerF, mockPeerG})
+				assert.NoError(err)
+				assert.Equal([]*Peer{mockPeerG}, parents)
+				assert.Equal(len(mockPeerE.Parents()), 1)
+				assert.Equal(mockPeerE.Parents()[0].ID, mockPeerG.ID)
+			},
+		},
+		{
+			name: "peer not found",
+			expect: func(t *testing.T, mockHost *Host, task *Task) {
+				assert := assert.New(t)
+				mockPeerE := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+				mockPeerF := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+
+				task.StorePeer(mockPeerE)
+				assert.ErrorIs(task.ReplacePeerParent(mockPeerE, mockPeerF), dag.ErrVertexNotFound)
+				assert.Equal(mockHost.ConcurrentUploadCount.Load(), int32(0))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDAGInvariantCheck())
+
+			tc.expect(t, mockHost, task)
+		})
+	}
+}
+
+func TestTask_ConcurrentReplacePeerParent(t *testing.T) {
+	const (
+		peerCount   = 16
+		workerCount = 8
+		roundCount  = 300
+	)
+
+	mockHost := NewHost(
+		mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+		mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+	task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithDAGInvariantCheck())
+
+	var peers []*Peer
+	for i := 0; i < peerCount; i++ {
+		peer := NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, task, mockHost)
+		task.StorePeer(peer)
+		peers = append(peers, peer)
+	}
+
+	// Workers re-parent peers concurrently, as ReplaceParent and the rescheduling
+	// driven by piece results do, and every mutation is checked for cycles.
+	var wg sync.WaitGroup
+	for w := 0; w < workerCount; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for n := 0; n < roundCount; n++ {
+				parent := peers[(w+n)%peerCount]
+				peer := peers[(w*n+1)%peerCount]
+
+				var err error
+				if n%2 == 0 {
+					err = task.ReplacePeerParent(parent, peer)
+				} else {
+					if err := task.DeletePeerInEdges(peer.ID); err != nil {
+						t.Error(err)
+						return
+					}
+
+					err = task.AddPeerEdge(parent, peer)
+				}
+
+				if err != nil && !errors.Is(err, dag.ErrCycleBetweenVertices) {
+					t.Error(err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var edgeCount int
+	for _, peer := range peers {
+		edgeCount += len(peer.Children())
+	}
+	assert.Equal(t, mockHost.ConcurrentUploadCount.Load(), int32(edgeCount))
+}
+
 func TestTask_DeletePeerInEdges(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -1058,6 +1292,64 @@ func TestTask_HasAvailablePeer(t *testing.T) {
 	}
 }
 
+func TestTask_InterestedPeerCount(t *testing.T) {
+	tests := []struct {
+		name   string
+		expect func(t *testing.T, task *Task, mockPeer *Peer, mockSeedPeer *Peer)
+	}{
+		{
+			name: "peer is running",
+			expect: func(t *testing.T, task *Task, mockPeer *Peer, mockSeedPeer *Peer) {
+				assert := assert.New(t)
+				mockPeer.FSM.SetState(PeerStateRunning)
+				task.StorePeer(mockPeer)
+				assert.Equal(task.InterestedPeerCount(), 1)
+			},
+		},
+		{
+			name: "peer is in the terminal state",
+			expect: func(t *testing.T, task *Task, mockPeer *Peer, mockSeedPeer *Peer) {
+				assert := assert.New(t)
+				mockPeer.FSM.SetState(PeerStateLeave)
+				task.StorePeer(mockPeer)
+				assert.Equal(task.InterestedPeerCount(), 0)
+			},
+		},
+		{
+			name: "seed peer is not interested peer",
+			expect: func(t *testing.T, task *Task, mockPeer *Peer, mockSeedPeer *Peer) {
+				assert := assert.New(t)
+				mockSeedPeer.FSM.SetState(PeerStateRunning)
+				task.StorePeer(mockSeedPeer)
+				assert.Equal(task.InterestedPeerCount(), 0)
+			},
+		},
+		{
+			name: "peer does not exist",
+			expect: func(t *testing.T, task *Task, mockPeer *Peer, mockSeedPeer *Peer) {
+				assert := assert.New(t)
+				assert.Equal(task.InterestedPeerCount(), 0)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockSeedHost := NewHost(
+				mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+				mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+			task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit)
+			mockPeer := NewPeer(mockPeerID, mockResourceConfig, task, mockHost)
+			mockSeedPeer := NewPeer(mockSeedPeerID, mockResourceConfig, task, mockSeedHost)
+
+			tc.expect(t, task, mockPeer, mockSeedPeer)
+		})
+	}
+}
+
 func TestTask_LoadSeedPeer(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -1405,6 +1697,156 @@ func TestTask_SizeScope(t *testing.T) {
 	}
 }
 
+func TestTask_SizeScopeWithLimits(t *testing.T) {
+	tests := []struct {
+		name            string
+		contentLength   int64
+		totalPieceCount int32
+		expect          func(t *testing.T, task *Task)
+	}{
+		{
+			name:            "content length is equal to tiny file size limit",
+			contentLength:   1024,
+			totalPieceCount: 1,
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(task.SizeScopeWithLimits(1024, 4096), commonv2.SizeScope_TINY)
+			},
+		},
+		{
+			name:            "content length is greater than tiny file size limit",
+			contentLength:   1025,
+			totalPieceCount: 1,
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(task.SizeScopeWithLimits(1024, 4096), commonv2.SizeScope_SMALL)
+			},
+		},
+		{
+			name:            "content length is equal to small file size limit",
+			contentLength:   4096,
+			totalPieceCount: 1,
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(task.SizeScopeWithLimits(1024, 4096), commonv2.SizeScope_SMALL)
+			},
+		},
+		{
+			name:            "content length is greater than small file size limit",
+			contentLength:   4097,
+			totalPieceCount: 1,
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(task.SizeScopeWithLimits(1024, 4096), commonv2.SizeScope_NORMAL)
+			},
+		},
+		{
+			name:            "task has more than one piece",
+			contentLength:   2048,
+			totalPieceCount: 2,
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(task.SizeScopeWithLimits(1024, 4096), commonv2.SizeScope_NORMAL)
+			},
+		},
+		{
+			name:            "scope size is empty",
+			contentLength:   0,
+			totalPieceCount: 0,
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(task.SizeScopeWithLimits(1024, 4096), commonv2.SizeScope_EMPTY)
+			},
+		},
+		{
+			name:            "invalid content length",
+			contentLength:   -1,
+			totalPieceCount: 1,
+			expect: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(task.SizeScopeWithLimits(1024, 4096), commonv2.SizeScope_UNKNOW)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit)
+			task.ContentLength.Store(tc.contentLength)
+			task.TotalPieceCount.Store(tc.totalPieceCount)
+			tc.expect(t, task)
+		})
+	}
+}
+
+func TestTask_WaitBackToSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		interval time.Duration
+		peers    int
+		expect   func(t *testing.T, allowed int32)
+	}{
+		{
+			name:     "back-to-source rate is not limited",
+			limit:    0,
+			interval: time.Hour,
+			peers:    10,
+			expect: func(t *testing.T, allowed int32) {
+				assert := assert.New(t)
+				assert.Equal(int32(10), allowed)
+			},
+		},
+		{
+			name:     "back-to-source rate is limited",
+			limit:    3,
+			interval: time.Hour,
+			peers:    10,
+			expect: func(t *testing.T, allowed int32) {
+				assert := assert.New(t)
+				assert.Equal(int32(3), allowed)
+			},
+		},
+		{
+			name:     "back-to-source rate is greater than the number of peers",
+			limit:    20,
+			interval: time.Hour,
+			peers:    10,
+			expect: func(t *testing.T, allowed int32) {
+				assert := assert.New(t)
+				assert.Equal(int32(10), allowed)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit,
+				WithBackToSourceRateLimit(tc.limit, tc.interval))
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			var (
+				wg      sync.WaitGroup
+				allowed = atomic.NewInt32(0)
+			)
+			for i := 0; i < tc.peers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					if err := task.WaitBackToSource(ctx); err == nil {
+						allowed.Inc()
+					}
+				}()
+			}
+
+			wg.Wait()
+			tc.expect(t, allowed.Load())
+		})
+	}
+}
+
 func TestTask_CanBackToSource(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -1427,6 +1869,26 @@ func TestTask_CanBackToSource(t *testing.T) {
 				assert.Equal(task.CanBackToSource(), false)
 			},
 		},
+		{
+			name:              "back-to-source peers reach the limit",
+			backToSourceLimit: 1,
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.True(task.AcquireBackToSource(mockPeerID))
+				assert.Equal(task.CanBackToSource(), false)
+
+				task.DeleteBackToSourcePeer(mockPeerID)
+				assert.Equal(task.CanBackToSource(), true)
+			},
+		},
+		{
+			name:              "task can not back-to-source without the limit",
+			backToSourceLimit: 0,
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.Equal(task.CanBackToSource(), false)
+			},
+		},
 		{
 			name:              "task can back-to-source and task type is DFSTORE",
 			backToSourceLimit: 1,
@@ -1455,6 +1917,239 @@ func TestTask_CanBackToSource(t *testing.T) {
 	}
 }
 
+func TestTaskFailure_String(t *testing.T) {
+	tests := []struct {
+		name    string
+		failure *TaskFailure
+		expect  string
+	}{
+		{
+			name:    "origin returned status code",
+			failure: &TaskFailure{Reason: TaskFailureReasonSourceError, StatusCode: 404},
+			expect:  "origin returned 404",
+		},
+		{
+			name:    "origin returned error without status code",
+			failure: &TaskFailure{Reason: TaskFailureReasonSourceError},
+			expect:  "origin returned error",
+		},
+		{
+			name:    "seed peer failed to register task",
+			failure: &TaskFailure{Reason: TaskFailureReasonSeedPeerRegisterFailed, Message: "foo"},
+			expect:  "seed peer failed to register task: foo",
+		},
+		{
+			name:    "seed peer failed to download task",
+			failure: &TaskFailure{Reason: TaskFailureReasonSeedPeerDownloadFailed, Message: "no space left on device"},
+			expect:  "seed peer failed to download task: no space left on device",
+		},
+		{
+			name:    "peer failed to back-to-source",
+			failure: &TaskFailure{Reason: TaskFailureReasonBackToSourceFailed},
+			expect:  "peer failed to back-to-source",
+		},
+		{
+			name:    "retry exhausted",
+			failure: &TaskFailure{Reason: TaskFailureReasonRetryExhausted},
+			expect:  "too many peers failed to download task",
+		},
+		{
+			name:    "download timeout",
+			failure: &TaskFailure{Reason: TaskFailureReasonDownloadTimeout},
+			expect:  "download task timed out",
+		},
+		{
+			name:    "unknown reason",
+			failure: &TaskFailure{},
+			expect:  "task failed",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert := assert.New(t)
+			assert.Equal(tc.expect, tc.failure.String())
+		})
+	}
+}
+
+func TestTask_Failure(t *testing.T) {
+	tests := []struct {
+		name     string
+		cooldown time.Duration
+		run      func(t *testing.T, task *Task)
+	}{
+		{
+			name: "set failure of running task",
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				task.FSM.SetState(TaskStateRunning)
+				task.SetFailure(NewTaskFailure(TaskFailureReasonBackToSourceFailed, ""))
+				assert.NoError(task.FSM.Event(context.Background(), TaskEventDownloadFailed))
+				assert.Equal(TaskFailureReasonBackToSourceFailed, task.Failure().Reason)
+				assert.True(task.Failure().Temporary)
+			},
+		},
+		{
+			name: "set failure of pending task",
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				task.SetFailure(NewTaskFailure(TaskFailureReasonBackToSourceFailed, ""))
+				assert.Nil(task.Failure())
+			},
+		},
+		{
+			name: "failure is reset when task is downloaded again",
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				task.FSM.SetState(TaskStateRunning)
+				task.SetFailure(NewTaskFailure(TaskFailureReasonRetryExhausted, ""))
+				assert.NoError(task.FSM.Event(context.Background(), TaskEventDownloadFailed))
+				assert.NoError(task.FSM.Event(context.Background(), TaskEventDownload))
+				assert.Nil(task.Failure())
+			},
+		},
+		{
+			name:     "task has permanent failure within cooldown",
+			cooldown: time.Minute,
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				task.FSM.SetState(TaskStateRunning)
+				failure := &TaskFailure{Reason: TaskFailureReasonSourceError, StatusCode: 404, CreatedAt: time.Now()}
+				task.SetFailure(failure)
+				assert.NoError(task.FSM.Event(context.Background(), TaskEventDownloadFailed))
+				permanentFailure, ok := task.PermanentFailure()
+				assert.True(ok)
+				assert.Equal(failure, permanentFailure)
+			},
+		},
+		{
+			name:     "task has permanent failure after cooldown",
+			cooldown: time.Minute,
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				task.FSM.SetState(TaskStateRunning)
+				task.SetFailure(&TaskFailure{Reason: TaskFailureReasonSourceError, StatusCode: 404, CreatedAt: time.Now().Add(-2 * time.Minute)})
+				assert.NoError(task.FSM.Event(context.Background(), TaskEventDownloadFailed))
+				_, ok := task.PermanentFailure()
+				assert.False(ok)
+			},
+		},
+		{
+			name: "task has permanent failure without cooldown",
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				task.FSM.SetState(TaskStateRunning)
+				task.SetFailure(&TaskFailure{Reason: TaskFailureReasonSourceError, StatusCode: 404, CreatedAt: time.Now()})
+				assert.NoError(task.FSM.Event(context.Background(), TaskEventDownloadFailed))
+				_, ok := task.PermanentFailure()
+				assert.False(ok)
+			},
+		},
+		{
+			name:     "task has temporary failure",
+			cooldown: time.Minute,
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				task.FSM.SetState(TaskStateRunning)
+				task.SetFailure(NewTaskFailure(TaskFailureReasonSourceError, ""))
+				assert.NoError(task.FSM.Event(context.Background(), TaskEventDownloadFailed))
+				_, ok := task.PermanentFailure()
+				assert.False(ok)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, WithBackToSourceCooldown(tc.cooldown))
+			tc.run(t, task)
+		})
+	}
+}
+
+func TestTask_AcquireBackToSource(t *testing.T) {
+	tests := []struct {
+		name              string
+		backToSourceLimit int32
+		options           []TaskOption
+		run               func(t *testing.T, task *Task)
+	}{
+		{
+			name:              "peers are granted until the limit is reached",
+			backToSourceLimit: 2,
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.True(task.AcquireBackToSource("foo"))
+				assert.True(task.AcquireBackToSource("bar"))
+				assert.False(task.AcquireBackToSource("baz"))
+				assert.True(task.AcquireBackToSource("foo"))
+				assert.Equal(task.BackToSourcePeers.Len(), uint(2))
+
+				task.DeleteBackToSourcePeer("foo")
+				assert.True(task.AcquireBackToSource("baz"))
+				assert.Equal(task.BackToSourcePeers.Len(), uint(2))
+			},
+		},
+		{
+			name:              "concurrent peers do not exceed the limit",
+			backToSourceLimit: 10,
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				var (
+					wg      sync.WaitGroup
+					granted = atomic.NewInt32(0)
+				)
+				for i := 0; i < 100; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						if task.AcquireBackToSource(idgen.PeerIDV2()) {
+							granted.Inc()
+						}
+					}()
+				}
+				wg.Wait()
+
+				assert.Equal(granted.Load(), int32(10))
+				assert.Equal(task.BackToSourcePeers.Len(), uint(10))
+			},
+		},
+		{
+			name:              "back-to-source is paused within the cooldown",
+			backToSourceLimit: 2,
+			options:           []TaskOption{WithBackToSourceCooldown(50 * time.Millisecond)},
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				assert.True(task.AcquireBackToSource("foo"))
+
+				task.PauseBackToSource()
+				assert.False(task.AcquireBackToSource("bar"))
+				assert.True(task.AcquireBackToSource("foo"))
+
+				time.Sleep(100 * time.Millisecond)
+				assert.True(task.AcquireBackToSource("bar"))
+			},
+		},
+		{
+			name:              "back-to-source is not paused without the cooldown",
+			backToSourceLimit: 2,
+			run: func(t *testing.T, task *Task) {
+				assert := assert.New(t)
+				task.PauseBackToSource()
+				assert.True(task.AcquireBackToSource("foo"))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			task := NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, tc.backToSourceLimit, tc.options...)
+			tc.run(t, task)
+		})
+	}
+}
+
 func TestTask_CanReuseDirectPiece(t *testing.T) {
 	tests := []struct {
 		name   string
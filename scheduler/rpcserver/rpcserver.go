@@ -17,9 +17,18 @@
 package rpcserver
 
 import (
+	"context"
+	"net"
+
 	"google.golang.org/grpc"
+	grpcpeer "google.golang.org/grpc/peer"
+
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+	schedulerv2 "d7y.io/api/v2/pkg/apis/scheduler/v2"
 
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/pkg/rpc/scheduler/server"
+	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
 	"d7y.io/dragonfly/v2/scheduler/networktopology"
 	"d7y.io/dragonfly/v2/scheduler/resource"
@@ -42,3 +51,49 @@ func New(
 		newSchedulerServerV2(cfg, resource, scheduling, dynconfig, storage, networkTopology),
 		opts...)
 }
+
+// NewPeerKeyFunc returns the key func of the peer rate limiter, only the registration of peers
+// is limited. The host of the key is the source address of the connection, because the host id
+// and the ip in the request are reported by the client. The peers of seed hosts are exempted only
+// when the request comes from the ip of the seed host, so the limit is not bypassed by claiming
+// the host id of a seed peer.
+func NewPeerKeyFunc(resource resource.Resource) rpc.PeerKeyFunc {
+	return func(ctx context.Context, req any) (rpc.PeerKey, bool) {
+		var peerID, hostID string
+		switch req := req.(type) {
+		case *schedulerv1.PeerTaskRequest:
+			peerID, hostID = req.GetPeerId(), req.GetPeerHost().GetId()
+		case *schedulerv2.AnnouncePeerRequest:
+			if _, ok := req.GetRequest().(*schedulerv2.AnnouncePeerRequest_RegisterPeerRequest); !ok {
+				return rpc.PeerKey{}, false
+			}
+
+			peerID, hostID = req.GetPeerId(), req.GetHostId()
+		default:
+			return rpc.PeerKey{}, false
+		}
+
+		ip := sourceIP(ctx)
+		if host, loaded := resource.HostManager().Load(hostID); loaded && host.Type != types.HostTypeNormal && ip != "" && host.IP == ip {
+			return rpc.PeerKey{}, false
+		}
+
+		return rpc.PeerKey{PeerID: peerID, Host: ip}, true
+	}
+}
+
+// sourceIP returns the ip of the source address of the grpc connection,
+// it is empty if the source address is not a tcp address.
+func sourceIP(ctx context.Context) string {
+	p, ok := grpcpeer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	ip, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return ""
+	}
+
+	return ip
+}
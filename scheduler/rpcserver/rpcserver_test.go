@@ -17,13 +17,21 @@
 package rpcserver
 
 import (
+	"context"
+	"net"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
+	grpcpeer "google.golang.org/grpc/peer"
 
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+	schedulerv2 "d7y.io/api/v2/pkg/apis/scheduler/v2"
+
+	"d7y.io/dragonfly/v2/pkg/rpc"
+	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
 	configmocks "d7y.io/dragonfly/v2/scheduler/config/mocks"
 	networktopologymocks "d7y.io/dragonfly/v2/scheduler/networktopology/mocks"
@@ -36,7 +44,7 @@ var (
 	mockSchedulerConfig = config.SchedulerConfig{
 		RetryLimit:             10,
 		RetryBackToSourceLimit: 3,
-		RetryInterval:          10 * time.Millisecond,
+		RetryInterval:          types.Duration{Duration: 10 * time.Millisecond},
 		BackToSourceCount:      200,
 	}
 )
@@ -70,3 +78,157 @@ func TestRPCServer_New(t *testing.T) {
 		})
 	}
 }
+
+func TestRPCServer_NewPeerKeyFunc(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      any
+		addr     net.Addr
+		hostType types.HostType
+		loaded   bool
+		expect   func(t *testing.T, key rpc.PeerKey, ok bool)
+	}{
+		{
+			name: "register peer task of normal host",
+			req: &schedulerv1.PeerTaskRequest{
+				PeerId:   "foo",
+				PeerHost: &schedulerv1.PeerHost{Id: "bar", Ip: "127.0.0.2"},
+			},
+			addr:     &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 65001},
+			hostType: types.HostTypeNormal,
+			loaded:   true,
+			expect: func(t *testing.T, key rpc.PeerKey, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(rpc.PeerKey{PeerID: "foo", Host: "127.0.0.1"}, key)
+			},
+		},
+		{
+			name: "register peer task of seed host",
+			req: &schedulerv1.PeerTaskRequest{
+				PeerId:   "foo",
+				PeerHost: &schedulerv1.PeerHost{Id: "bar", Ip: "127.0.0.1"},
+			},
+			addr:     &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 65001},
+			hostType: types.HostTypeSuperSeed,
+			loaded:   true,
+			expect: func(t *testing.T, key rpc.PeerKey, ok bool) {
+				assert := assert.New(t)
+				assert.False(ok)
+			},
+		},
+		{
+			name: "register peer task with host id of seed host from other ip",
+			req: &schedulerv1.PeerTaskRequest{
+				PeerId:   "foo",
+				PeerHost: &schedulerv1.PeerHost{Id: "bar", Ip: "127.0.0.1"},
+			},
+			addr:     &net.TCPAddr{IP: net.ParseIP("127.0.0.2"), Port: 65001},
+			hostType: types.HostTypeSuperSeed,
+			loaded:   true,
+			expect: func(t *testing.T, key rpc.PeerKey, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(rpc.PeerKey{PeerID: "foo", Host: "127.0.0.2"}, key)
+			},
+		},
+		{
+			name: "register peer task with host id of seed host without source address",
+			req: &schedulerv1.PeerTaskRequest{
+				PeerId:   "foo",
+				PeerHost: &schedulerv1.PeerHost{Id: "bar", Ip: "127.0.0.1"},
+			},
+			hostType: types.HostTypeSuperSeed,
+			loaded:   true,
+			expect: func(t *testing.T, key rpc.PeerKey, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(rpc.PeerKey{PeerID: "foo"}, key)
+			},
+		},
+		{
+			name: "register peer of host not found",
+			req: &schedulerv2.AnnouncePeerRequest{
+				HostId:  "bar",
+				PeerId:  "foo",
+				Request: &schedulerv2.AnnouncePeerRequest_RegisterPeerRequest{},
+			},
+			addr:   &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 65001},
+			loaded: false,
+			expect: func(t *testing.T, key rpc.PeerKey, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(rpc.PeerKey{PeerID: "foo", Host: "127.0.0.1"}, key)
+			},
+		},
+		{
+			name: "register peer of normal host",
+			req: &schedulerv2.AnnouncePeerRequest{
+				HostId:  "bar",
+				PeerId:  "foo",
+				Request: &schedulerv2.AnnouncePeerRequest_RegisterPeerRequest{},
+			},
+			addr:     &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 65001},
+			hostType: types.HostTypeNormal,
+			loaded:   true,
+			expect: func(t *testing.T, key rpc.PeerKey, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(rpc.PeerKey{PeerID: "foo", Host: "127.0.0.1"}, key)
+			},
+		},
+		{
+			name: "register peer of seed host",
+			req: &schedulerv2.AnnouncePeerRequest{
+				HostId:  "bar",
+				PeerId:  "foo",
+				Request: &schedulerv2.AnnouncePeerRequest_RegisterPeerRequest{},
+			},
+			addr:     &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 65001},
+			hostType: types.HostTypeStrongSeed,
+			loaded:   true,
+			expect: func(t *testing.T, key rpc.PeerKey, ok bool) {
+				assert := assert.New(t)
+				assert.False(ok)
+			},
+		},
+		{
+			name: "announce peer other than registration",
+			req: &schedulerv2.AnnouncePeerRequest{
+				HostId:  "bar",
+				PeerId:  "foo",
+				Request: &schedulerv2.AnnouncePeerRequest_DownloadPeerStartedRequest{},
+			},
+			addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 65001},
+			expect: func(t *testing.T, key rpc.PeerKey, ok bool) {
+				assert := assert.New(t)
+				assert.False(ok)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			res := resource.NewMockResource(ctl)
+			hostManager := resource.NewMockHostManager(ctl)
+			host := resource.NewHost("bar", "127.0.0.1", "baz", 8003, 8001, tc.hostType)
+			if tc.loaded {
+				res.EXPECT().HostManager().Return(hostManager).Times(1)
+				hostManager.EXPECT().Load(gomock.Eq("bar")).Return(host, true).Times(1)
+			} else {
+				res.EXPECT().HostManager().Return(hostManager).AnyTimes()
+				hostManager.EXPECT().Load(gomock.Eq("bar")).Return(nil, false).AnyTimes()
+			}
+
+			ctx := context.Background()
+			if tc.addr != nil {
+				ctx = grpcpeer.NewContext(ctx, &grpcpeer.Peer{Addr: tc.addr})
+			}
+
+			key, ok := NewPeerKeyFunc(res)(ctx, tc.req)
+			tc.expect(t, key, ok)
+		})
+	}
+}
@@ -23,7 +23,10 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/johanbrandhorst/certify"
@@ -32,8 +35,12 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	zapadapter "logur.dev/adapter/zap"
 
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+	schedulerv2 "d7y.io/api/v2/pkg/apis/scheduler/v2"
+
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	"d7y.io/dragonfly/v2/internal/dynconfig"
 	"d7y.io/dragonfly/v2/pkg/cache"
@@ -41,6 +48,7 @@ import (
 	"d7y.io/dragonfly/v2/pkg/gc"
 	"d7y.io/dragonfly/v2/pkg/issuer"
 	"d7y.io/dragonfly/v2/pkg/net/ip"
+	"d7y.io/dragonfly/v2/pkg/redact"
 	pkgredis "d7y.io/dragonfly/v2/pkg/redis"
 	"d7y.io/dragonfly/v2/pkg/rpc"
 	managerclient "d7y.io/dragonfly/v2/pkg/rpc/manager/client"
@@ -48,6 +56,7 @@ import (
 	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/announcer"
 	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/debug"
 	"d7y.io/dragonfly/v2/scheduler/job"
 	"d7y.io/dragonfly/v2/scheduler/metrics"
 	"d7y.io/dragonfly/v2/scheduler/networktopology"
@@ -55,6 +64,7 @@ import (
 	"d7y.io/dragonfly/v2/scheduler/rpcserver"
 	"d7y.io/dragonfly/v2/scheduler/scheduling"
 	"d7y.io/dragonfly/v2/scheduler/scheduling/evaluator"
+	"d7y.io/dragonfly/v2/scheduler/service"
 	"d7y.io/dragonfly/v2/scheduler/storage"
 )
 
@@ -75,6 +85,9 @@ type Server struct {
 	// Metrics server.
 	metricsServer *http.Server
 
+	// Debug server.
+	debugServer *http.Server
+
 	// Manager client.
 	managerClient managerclient.V2
 
@@ -99,6 +112,15 @@ type Server struct {
 	// Network topology interface.
 	networkTopology networktopology.NetworkTopology
 
+	// Scheduling interface.
+	scheduling scheduling.Scheduling
+
+	// Rescheduler interface.
+	rescheduler scheduling.Rescheduler
+
+	// Signals of reloading the evaluator plugin.
+	reloadSignals chan os.Signal
+
 	// GC service.
 	gc gc.GC
 }
@@ -107,12 +129,20 @@ type Server struct {
 func New(ctx context.Context, cfg *config.Config, d dfpath.Dfpath) (*Server, error) {
 	s := &Server{config: cfg}
 
+	// Initialize redactor of the task urls in logs and records.
+	queryParams := cfg.Redact.QueryParams
+	if len(queryParams) == 0 {
+		queryParams = redact.DefaultQueryParams
+	}
+	redact.SetDefault(redact.New(queryParams, cfg.Redact.HashQuery))
+
 	// Initialize Storage.
 	storage, err := storage.New(
 		d.DataDir(),
 		cfg.Storage.MaxSize,
 		cfg.Storage.MaxBackups,
 		cfg.Storage.BufferSize,
+		storage.WithListConcurrency(cfg.Storage.ListConcurrency),
 	)
 	if err != nil {
 		return nil, err
@@ -132,6 +162,10 @@ func New(ctx context.Context, cfg *config.Config, d dfpath.Dfpath) (*Server, err
 		managerDialOptions = append(managerDialOptions, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
 
+	if cfg.Manager.GzipCompression {
+		managerDialOptions = append(managerDialOptions, rpc.WithGzipCompression())
+	}
+
 	// Initialize manager client.
 	managerClient, err := managerclient.GetV2ByAddr(ctx, cfg.Manager.Addr, managerDialOptions...)
 	if err != nil {
@@ -227,6 +261,30 @@ func New(ctx context.Context, cfg *config.Config, d dfpath.Dfpath) (*Server, err
 		resourceOptions = append(resourceOptions, resource.WithRedisClient(rdb))
 	}
 
+	if cfg.Resource.Task.Persistence.Enable {
+		switch cfg.Resource.Task.Persistence.Backend {
+		case config.TaskPersistenceBackendRedis:
+			if rdb == nil {
+				return nil, errors.New("task persistence with redis backend requires redis")
+			}
+
+			resourceOptions = append(resourceOptions, resource.WithTaskSnapshotStore(resource.NewRedisTaskSnapshotStore(rdb,
+				pkgredis.MakeTaskSnapshotsKeyInScheduler(cfg.Manager.SchedulerClusterID, cfg.Server.Host, cfg.Server.AdvertiseIP.String()))))
+		default:
+			path := cfg.Resource.Task.Persistence.Path
+			if path == "" {
+				path = filepath.Join(d.DataDir(), resource.TaskSnapshotFileName)
+			}
+
+			resourceOptions = append(resourceOptions, resource.WithTaskSnapshotStore(resource.NewFileTaskSnapshotStore(path)))
+		}
+	}
+
+	// Tasks built by resource, e.g. the restored tasks, share the options of the tasks built by service.
+	resourceOptions = append(resourceOptions, resource.WithTaskOptions(func(application string) int32 {
+		return service.BackToSourceCount(cfg, dynconfig, application)
+	}, service.TaskOptions(cfg)...))
+
 	resource, err := resource.New(cfg, s.gc, dynconfig, resourceOptions...)
 	if err != nil {
 		return nil, err
@@ -254,7 +312,18 @@ func New(ctx context.Context, cfg *config.Config, d dfpath.Dfpath) (*Server, err
 	}
 
 	// Initialize scheduling.
-	scheduling := scheduling.New(&cfg.Scheduler, dynconfig, d.PluginDir(), evaluatorNetworkTopologyOptions...)
+	schedulingService := scheduling.New(&cfg.Scheduler, dynconfig, d.PluginDir(), evaluatorNetworkTopologyOptions...)
+	s.scheduling = schedulingService
+
+	// The evaluator plugin is reloaded by SIGHUP.
+	if cfg.Scheduler.Algorithm == evaluator.PluginAlgorithm {
+		s.reloadSignals = make(chan os.Signal, 1)
+	}
+
+	// Initialize rescheduler.
+	if cfg.Scheduler.Reschedule.Enable {
+		s.rescheduler = scheduling.NewRescheduler(&cfg.Scheduler.Reschedule, resource, schedulingService)
+	}
 
 	// Initialize server options of scheduler grpc server.
 	schedulerServerOptions := []grpc.ServerOption{}
@@ -269,7 +338,53 @@ func New(ctx context.Context, cfg *config.Config, d dfpath.Dfpath) (*Server, err
 		schedulerServerOptions = append(schedulerServerOptions, grpc.Creds(insecure.NewCredentials()))
 	}
 
-	svr := rpcserver.New(cfg, resource, scheduling, dynconfig, s.storage, s.networkTopology, schedulerServerOptions...)
+	// Initialize handling duration of scheduler grpc server, the duration of stream method
+	// is the lifetime of the stream.
+	schedulerServerOptions = append(schedulerServerOptions,
+		grpc.ChainUnaryInterceptor(rpc.LatencyUnaryServerInterceptor(metrics.GRPCHandlingDuration)),
+		grpc.ChainStreamInterceptor(rpc.LatencyStreamServerInterceptor(metrics.GRPCHandlingDuration)),
+	)
+
+	// Initialize peer rate limit of scheduler grpc server, it stops the registration storms
+	// of the misbehaving clients before queueing them in the overload protection.
+	if cfg.Server.PeerRateLimit.Enable {
+		limiter := rpc.NewPeerRateLimiter(cfg.Server.PeerRateLimit.PeerQPS, cfg.Server.PeerRateLimit.PeerBurst,
+			cfg.Server.PeerRateLimit.HostQPS, cfg.Server.PeerRateLimit.HostBurst, cfg.Server.PeerRateLimit.Size,
+			rpcserver.NewPeerKeyFunc(resource),
+			rpc.WithRateLimitedMethods("RegisterPeerTask", "AnnouncePeer"),
+			rpc.WithThrottledCounter(metrics.PeerRateLimitThrottledCount),
+		)
+
+		schedulerServerOptions = append(schedulerServerOptions,
+			grpc.ChainUnaryInterceptor(limiter.UnaryServerInterceptor()),
+			grpc.ChainStreamInterceptor(limiter.StreamServerInterceptor()),
+		)
+	}
+
+	// Initialize overload protection of unary methods of scheduler grpc server, health check
+	// and announcing host as keepalive are exempted.
+	if cfg.Server.Overload.Enable {
+		limiterOptions := []rpc.ConcurrencyLimiterOption{
+			rpc.WithExemptServices(healthpb.Health_ServiceDesc.ServiceName),
+			rpc.WithExemptMethods(
+				fmt.Sprintf("/%s/AnnounceHost", schedulerv1.Scheduler_ServiceDesc.ServiceName),
+				fmt.Sprintf("/%s/AnnounceHost", schedulerv2.Scheduler_ServiceDesc.ServiceName),
+			),
+			rpc.WithInFlightGauge(metrics.GRPCInFlightRequestGauge),
+			rpc.WithRejectedCounter(metrics.GRPCOverloadRejectedCount),
+		}
+		for _, methodLimit := range cfg.Server.Overload.MethodLimits {
+			limiterOptions = append(limiterOptions, rpc.WithMethodLimit(methodLimit.Method, methodLimit.Limit))
+		}
+
+		limiter := rpc.NewConcurrencyLimiter(cfg.Server.Overload.Limit, cfg.Server.Overload.QueueTimeout, cfg.Server.Overload.RetryAfter, limiterOptions...)
+
+		schedulerServerOptions = append(schedulerServerOptions,
+			grpc.ChainUnaryInterceptor(limiter.UnaryServerInterceptor()),
+		)
+	}
+
+	svr := rpcserver.New(cfg, resource, schedulingService, dynconfig, s.storage, s.networkTopology, schedulerServerOptions...)
 	s.grpcServer = svr
 
 	// Initialize metrics.
@@ -277,6 +392,11 @@ func New(ctx context.Context, cfg *config.Config, d dfpath.Dfpath) (*Server, err
 		s.metricsServer = metrics.New(&cfg.Metrics, s.grpcServer)
 	}
 
+	// Initialize debug.
+	if cfg.Debug.Enable {
+		s.debugServer = debug.New(&cfg.Debug, resource, scheduling.NewEvictor(resource, schedulingService))
+	}
+
 	return s, nil
 }
 
@@ -315,6 +435,20 @@ func (s *Server) Serve() error {
 		}()
 	}
 
+	// Started debug server.
+	if s.debugServer != nil {
+		go func() {
+			logger.Infof("started debug server at %s", s.debugServer.Addr)
+			if err := s.debugServer.ListenAndServe(); err != nil {
+				if err == http.ErrServerClosed {
+					return
+				}
+
+				logger.Fatalf("debug server closed unexpect: %s", err.Error())
+			}
+		}()
+	}
+
 	// Serve announcer.
 	go func() {
 		s.announcer.Serve()
@@ -329,6 +463,29 @@ func (s *Server) Serve() error {
 		}()
 	}
 
+	// Serve rescheduler.
+	if s.rescheduler != nil {
+		go func() {
+			s.rescheduler.Serve()
+			logger.Info("rescheduler start successfully")
+		}()
+	}
+
+	// Reload evaluator plugin.
+	if s.reloadSignals != nil {
+		signal.Notify(s.reloadSignals, syscall.SIGHUP)
+		go func() {
+			for range s.reloadSignals {
+				if err := s.scheduling.ReloadEvaluator(); err != nil {
+					logger.Errorf("reload evaluator plugin failed: %s", err.Error())
+					continue
+				}
+
+				logger.Info("reload evaluator plugin successfully")
+			}
+		}()
+	}
+
 	// Generate GRPC limit listener.
 	ip, ok := ip.FormatIP(s.config.Server.ListenIP.String())
 	if !ok {
@@ -394,6 +551,15 @@ func (s *Server) Stop() {
 		}
 	}
 
+	// Stop debug server.
+	if s.debugServer != nil {
+		if err := s.debugServer.Shutdown(context.Background()); err != nil {
+			logger.Errorf("debug server failed to stop: %s", err.Error())
+		} else {
+			logger.Info("debug server closed under request")
+		}
+	}
+
 	// Stop announcer.
 	s.announcer.Stop()
 	logger.Info("stop announcer closed")
@@ -422,6 +588,19 @@ func (s *Server) Stop() {
 		logger.Info("network topology closed")
 	}
 
+	// Stop rescheduler.
+	if s.rescheduler != nil {
+		s.rescheduler.Stop()
+		logger.Info("rescheduler closed")
+	}
+
+	// Stop reloading evaluator plugin.
+	if s.reloadSignals != nil {
+		signal.Stop(s.reloadSignals)
+		close(s.reloadSignals)
+		logger.Info("reloading evaluator plugin closed")
+	}
+
 	// Stop GRPC server.
 	stopped := make(chan struct{})
 	go func() {
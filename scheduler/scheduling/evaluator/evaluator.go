@@ -39,6 +39,12 @@ const (
 	PluginAlgorithm = "plugin"
 )
 
+const (
+	// Parent host affinity weight, it is the bonus on top of the weighted score for
+	// the parent host which the child downloaded pieces from successfully recently.
+	parentHostAffinityWeight = 0.1
+)
+
 const (
 	// Maximum score.
 	maxScore float64 = 1
@@ -69,6 +75,13 @@ type Evaluator interface {
 	IsBadNode(peer *resource.Peer) bool
 }
 
+// Scorer is an optional interface implemented by the evaluators which can
+// score a single parent, the plugin evaluator may not implement it.
+type Scorer interface {
+	// EvaluateParent returns the score of the parent for the child, the larger the better.
+	EvaluateParent(parent *resource.Peer, child *resource.Peer, taskPieceCount int32) float64
+}
+
 // evaluator is an implementation of Evaluator.
 type evaluator struct{}
 
@@ -76,9 +89,13 @@ type evaluator struct{}
 func New(algorithm string, pluginDir string, networkTopologyOptions ...NetworkTopologyOption) Evaluator {
 	switch algorithm {
 	case PluginAlgorithm:
-		if plugin, err := LoadPlugin(pluginDir); err == nil {
-			return plugin
+		plugin, err := NewPlugin(pluginDir)
+		if err != nil {
+			logger.Errorf("load evaluator plugin failed, fall back to the default algorithm: %s", err.Error())
+			return newEvaluatorBase()
 		}
+
+		return plugin
 	case NetworkTopologyAlgorithm:
 		return newEvaluatorNetworkTopology(networkTopologyOptions...)
 	// TODO Implement MLAlgorithm.
@@ -89,6 +106,22 @@ func New(algorithm string, pluginDir string, networkTopologyOptions ...NetworkTo
 	return newEvaluatorBase()
 }
 
+// rankParent reports whether the parent ranks before the other parent by their scores,
+// the parents with the same score are ranked by the creation time and then the id,
+// the earlier created parent ranks first, so that the order of the parents is deterministic.
+func rankParent(parent *resource.Peer, score float64, other *resource.Peer, otherScore float64) bool {
+	if score != otherScore {
+		return score > otherScore
+	}
+
+	createdAt, otherCreatedAt := parent.CreatedAt.Load(), other.CreatedAt.Load()
+	if !createdAt.Equal(otherCreatedAt) {
+		return createdAt.Before(otherCreatedAt)
+	}
+
+	return parent.ID < other.ID
+}
+
 // IsBadNode determine if peer is a failed node.
 func (e *evaluator) IsBadNode(peer *resource.Peer) bool {
 	if peer.FSM.Is(resource.PeerStateFailed) || peer.FSM.Is(resource.PeerStateLeave) || peer.FSM.Is(resource.PeerStatePending) ||
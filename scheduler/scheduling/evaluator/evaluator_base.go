@@ -55,18 +55,24 @@ func newEvaluatorBase() Evaluator {
 	return &evaluatorBase{}
 }
 
-// EvaluateParents sort parents by evaluating multiple feature scores.
+// EvaluateParents sort parents by evaluating multiple feature scores,
+// the parents with the same score are sorted by rankParent.
 func (e *evaluatorBase) EvaluateParents(parents []*resource.Peer, child *resource.Peer, totalPieceCount int32) []*resource.Peer {
-	sort.Slice(
+	sort.SliceStable(
 		parents,
 		func(i, j int) bool {
-			return e.evaluate(parents[i], child, totalPieceCount) > e.evaluate(parents[j], child, totalPieceCount)
+			return rankParent(parents[i], e.evaluate(parents[i], child, totalPieceCount), parents[j], e.evaluate(parents[j], child, totalPieceCount))
 		},
 	)
 
 	return parents
 }
 
+// EvaluateParent returns the score of the parent for the child.
+func (e *evaluatorBase) EvaluateParent(parent *resource.Peer, child *resource.Peer, totalPieceCount int32) float64 {
+	return e.evaluate(parent, child, totalPieceCount)
+}
+
 // The larger the value, the higher the priority.
 func (e *evaluatorBase) evaluate(parent *resource.Peer, child *resource.Peer, totalPieceCount int32) float64 {
 	parentLocation := parent.Host.Network.Location
@@ -79,7 +85,8 @@ func (e *evaluatorBase) evaluate(parent *resource.Peer, child *resource.Peer, to
 		freeUploadWeight*e.calculateFreeUploadScore(parent.Host) +
 		hostTypeWeight*e.calculateHostTypeScore(parent) +
 		idcAffinityWeight*e.calculateIDCAffinityScore(parentIDC, childIDC) +
-		locationAffinityWeight*e.calculateMultiElementAffinityScore(parentLocation, childLocation)
+		locationAffinityWeight*e.calculateMultiElementAffinityScore(parentLocation, childLocation) +
+		parentHostAffinityWeight*child.ParentHostAffinity(parent.Host.ID)
 }
 
 // calculatePieceScore 0.0~unlimited larger and better.
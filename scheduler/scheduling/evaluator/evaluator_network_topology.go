@@ -80,18 +80,24 @@ func newEvaluatorNetworkTopology(options ...NetworkTopologyOption) Evaluator {
 	return e
 }
 
-// EvaluateParents sort parents by evaluating multiple feature scores.
+// EvaluateParents sort parents by evaluating multiple feature scores,
+// the parents with the same score are sorted by rankParent.
 func (e *evaluatorNetworkTopology) EvaluateParents(parents []*resource.Peer, child *resource.Peer, totalPieceCount int32) []*resource.Peer {
-	sort.Slice(
+	sort.SliceStable(
 		parents,
 		func(i, j int) bool {
-			return e.evaluate(parents[i], child, totalPieceCount) > e.evaluate(parents[j], child, totalPieceCount)
+			return rankParent(parents[i], e.evaluate(parents[i], child, totalPieceCount), parents[j], e.evaluate(parents[j], child, totalPieceCount))
 		},
 	)
 
 	return parents
 }
 
+// EvaluateParent returns the score of the parent for the child.
+func (e *evaluatorNetworkTopology) EvaluateParent(parent *resource.Peer, child *resource.Peer, totalPieceCount int32) float64 {
+	return e.evaluate(parent, child, totalPieceCount)
+}
+
 // The larger the value, the higher the priority.
 func (e *evaluatorNetworkTopology) evaluate(parent *resource.Peer, child *resource.Peer, totalPieceCount int32) float64 {
 	parentLocation := parent.Host.Network.Location
@@ -105,7 +111,8 @@ func (e *evaluatorNetworkTopology) evaluate(parent *resource.Peer, child *resour
 		networkTopologyHostTypeWeight*e.calculateHostTypeScore(parent) +
 		networkTopologyIDCAffinityWeight*e.calculateIDCAffinityScore(parentIDC, childIDC) +
 		networkTopologyLocationAffinityWeight*e.calculateMultiElementAffinityScore(parentLocation, childLocation) +
-		networkTopologyProbeWeight*e.calculateNetworkTopologyScore(parent.ID, child.ID)
+		networkTopologyProbeWeight*e.calculateNetworkTopologyScore(child.Host.ID, parent.Host.ID) +
+		parentHostAffinityWeight*child.ParentHostAffinity(parent.Host.ID)
 }
 
 // calculatePieceScore 0.0~unlimited larger and better.
@@ -213,9 +220,10 @@ func (e *evaluatorNetworkTopology) calculateMultiElementAffinityScore(dst, src s
 	return float64(score) / float64(maxElementLen)
 }
 
-// calculateNetworkTopologyScore 0.0~1.0 larger and better.
-func (e *evaluatorNetworkTopology) calculateNetworkTopologyScore(dst, src string) float64 {
-	averageRTT, err := e.networktopology.Probes(dst, src).AverageRTT()
+// calculateNetworkTopologyScore 0.0~1.0 larger and better, the probes are stored
+// by the source host which probes the destination host.
+func (e *evaluatorNetworkTopology) calculateNetworkTopologyScore(srcHostID, destHostID string) float64 {
+	averageRTT, err := e.networktopology.Probes(srcHostID, destHostID).AverageRTT()
 	if err != nil {
 		return minScore
 	}
@@ -234,9 +234,9 @@ func TestEvaluatorNetworkTopology_EvaluateParents(t *testing.T) {
 					mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)),
 			totalPieceCount: 1,
 			mock: func(parents []*resource.Peer, child *resource.Peer, p networktopology.Probes, mn *networktopologymocks.MockNetworkTopologyMockRecorder, mp *networktopologymocks.MockProbesMockRecorder) {
-				mn.Probes(parents[0].ID, child.ID).Return(p)
+				mn.Probes(child.Host.ID, parents[0].Host.ID).Return(p)
 				mp.AverageRTT().Return(100*time.Millisecond, nil)
-				mn.Probes(parents[1].ID, child.ID).Return(p)
+				mn.Probes(child.Host.ID, parents[1].Host.ID).Return(p)
 				mp.AverageRTT().Return(200*time.Millisecond, nil)
 			},
 			expect: func(t *testing.T, parents []*resource.Peer) {
@@ -261,6 +261,43 @@ func TestEvaluatorNetworkTopology_EvaluateParents(t *testing.T) {
 	}
 }
 
+func TestEvaluatorNetworkTopology_EvaluateParentsByProbes(t *testing.T) {
+	assert := assert.New(t)
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+
+	mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+	child := resource.NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, mockTask,
+		resource.NewHost(mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname, mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type))
+
+	// The new parents have no piece cost history, so they are ranked by the stored latencies.
+	parents := []*resource.Peer{
+		resource.NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, mockTask,
+			resource.NewHost("foo", mockRawHost.IP, mockRawHost.Hostname, mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)),
+		resource.NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, mockTask,
+			resource.NewHost("bar", mockRawHost.IP, mockRawHost.Hostname, mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)),
+		resource.NewPeer(idgen.PeerIDV1("127.0.0.1"), mockResourceConfig, mockTask,
+			resource.NewHost("baz", mockRawHost.IP, mockRawHost.Hostname, mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)),
+	}
+
+	mockNetworkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+	fooProbes := networktopologymocks.NewMockProbes(ctl)
+	barProbes := networktopologymocks.NewMockProbes(ctl)
+	bazProbes := networktopologymocks.NewMockProbes(ctl)
+	mockNetworkTopology.EXPECT().Probes(child.Host.ID, "foo").Return(fooProbes).AnyTimes()
+	mockNetworkTopology.EXPECT().Probes(child.Host.ID, "bar").Return(barProbes).AnyTimes()
+	mockNetworkTopology.EXPECT().Probes(child.Host.ID, "baz").Return(bazProbes).AnyTimes()
+	fooProbes.EXPECT().AverageRTT().Return(300*time.Millisecond, nil).AnyTimes()
+	barProbes.EXPECT().AverageRTT().Return(20*time.Millisecond, nil).AnyTimes()
+	bazProbes.EXPECT().AverageRTT().Return(time.Duration(0), errors.New("probes not found")).AnyTimes()
+
+	e := newEvaluatorNetworkTopology(WithNetworkTopology(mockNetworkTopology))
+	parents = e.EvaluateParents(parents, child, 0)
+	assert.Equal("bar", parents[0].Host.ID)
+	assert.Equal("foo", parents[1].Host.ID)
+	assert.Equal("baz", parents[2].Host.ID)
+}
+
 func TestEvaluatorNetworkTopology_evaluate(t *testing.T) {
 	tests := []struct {
 		name            string
@@ -284,7 +321,7 @@ func TestEvaluatorNetworkTopology_evaluate(t *testing.T) {
 					mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)),
 			totalPieceCount: 1,
 			mock: func(parent *resource.Peer, child *resource.Peer, p networktopology.Probes, mp *networktopologymocks.MockProbesMockRecorder, mn *networktopologymocks.MockNetworkTopologyMockRecorder) {
-				mn.Probes(parent.ID, child.ID).Return(p)
+				mn.Probes(child.Host.ID, parent.Host.ID).Return(p)
 				mp.AverageRTT().Return(500*time.Millisecond, nil)
 			},
 			expect: func(t *testing.T, score float64) {
@@ -307,7 +344,7 @@ func TestEvaluatorNetworkTopology_evaluate(t *testing.T) {
 			totalPieceCount: 1,
 			mock: func(parent *resource.Peer, child *resource.Peer, p networktopology.Probes, mp *networktopologymocks.MockProbesMockRecorder, mn *networktopologymocks.MockNetworkTopologyMockRecorder) {
 				parent.FinishedPieces.Set(0)
-				mn.Probes(parent.ID, child.ID).Return(p)
+				mn.Probes(child.Host.ID, parent.Host.ID).Return(p)
 				mp.AverageRTT().Return(1000*time.Millisecond, nil)
 			},
 			expect: func(t *testing.T, score float64) {
@@ -845,7 +882,7 @@ func TestEvaluatorNetworkTopology_calculateNetworkTopologyScore(t *testing.T) {
 				resource.NewHost(mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
 					mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)),
 			mock: func(parent *resource.Peer, child *resource.Peer, p networktopology.Probes, mn *networktopologymocks.MockNetworkTopologyMockRecorder, mp *networktopologymocks.MockProbesMockRecorder) {
-				mn.Probes(parent.ID, child.ID).Return(p)
+				mn.Probes(child.Host.ID, parent.Host.ID).Return(p)
 				mp.AverageRTT().Return(100*time.Millisecond, nil)
 			},
 			expect: func(t *testing.T, parent *resource.Peer, child *resource.Peer, score float64) {
@@ -864,7 +901,7 @@ func TestEvaluatorNetworkTopology_calculateNetworkTopologyScore(t *testing.T) {
 				resource.NewHost(mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
 					mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)),
 			mock: func(parent *resource.Peer, child *resource.Peer, p networktopology.Probes, mn *networktopologymocks.MockNetworkTopologyMockRecorder, mp *networktopologymocks.MockProbesMockRecorder) {
-				mn.Probes(parent.ID, child.ID).Return(p)
+				mn.Probes(child.Host.ID, parent.Host.ID).Return(p)
 				mp.AverageRTT().Return(time.Duration(0), errors.New("foo"))
 			},
 			expect: func(t *testing.T, parent *resource.Peer, child *resource.Peer, score float64) {
@@ -882,7 +919,7 @@ func TestEvaluatorNetworkTopology_calculateNetworkTopologyScore(t *testing.T) {
 			e := newEvaluatorNetworkTopology(WithNetworkTopology(mockNetworkTopology))
 			mockProbe := networktopologymocks.NewMockProbes(ctl)
 			tc.mock(tc.parent, tc.child, mockProbe, mockNetworkTopology.EXPECT(), mockProbe.EXPECT())
-			tc.expect(t, tc.parent, tc.child, e.(*evaluatorNetworkTopology).calculateNetworkTopologyScore(tc.parent.ID, tc.child.ID))
+			tc.expect(t, tc.parent, tc.child, e.(*evaluatorNetworkTopology).calculateNetworkTopologyScore(tc.child.Host.ID, tc.parent.Host.ID))
 		})
 	}
 }
@@ -18,14 +18,26 @@ package evaluator
 
 import (
 	"errors"
+	"fmt"
+	"math"
+
+	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
 
 	"d7y.io/dragonfly/v2/internal/dfplugin"
+	"d7y.io/dragonfly/v2/pkg/types"
+	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/resource"
 )
 
 const (
 	pluginName = "evaluator"
 )
 
+const (
+	// validationPieceCount is the piece count of the synthetic task for validating plugin.
+	validationPieceCount = 4
+)
+
 func LoadPlugin(dir string) (Evaluator, error) {
 	client, _, err := dfplugin.Load(dir, dfplugin.PluginTypeScheduler, pluginName, map[string]string{})
 	if err != nil {
@@ -37,3 +49,104 @@ func LoadPlugin(dir string) (Evaluator, error) {
 	}
 	return nil, errors.New("invalid evaluator plugin")
 }
+
+// NewPlugin loads the evaluator plugin from the directory and validates it,
+// instead of failing at the first scheduling.
+func NewPlugin(dir string) (Evaluator, error) {
+	plugin, err := LoadPlugin(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validatePlugin(plugin); err != nil {
+		return nil, fmt.Errorf("invalid evaluator plugin: %w", err)
+	}
+
+	return plugin, nil
+}
+
+// ReloadPlugin loads the evaluator plugin from the copy of the plugin file in the directory
+// and validates it, the go runtime returns the plugin loaded first if the same file is loaded again.
+func ReloadPlugin(dir string) (Evaluator, error) {
+	client, _, err := dfplugin.Reload(dir, dfplugin.PluginTypeScheduler, pluginName, map[string]string{})
+	if err != nil {
+		return nil, err
+	}
+
+	plugin, ok := client.(Evaluator)
+	if !ok {
+		return nil, errors.New("invalid evaluator plugin")
+	}
+
+	if err := validatePlugin(plugin); err != nil {
+		return nil, fmt.Errorf("invalid evaluator plugin: %w", err)
+	}
+
+	return plugin, nil
+}
+
+// validatePlugin evaluates the synthetic parents and child with the plugin,
+// it returns error if the plugin panics or returns insane results.
+func validatePlugin(e Evaluator) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("evaluator plugin panics: %v", r)
+		}
+	}()
+
+	task := resource.NewTask("validation", "http://example.com/validation", "", "", commonv2.TaskType_DFDAEMON, nil, nil, 0)
+	task.TotalPieceCount.Store(validationPieceCount)
+	newPeer := func(id string, state string, finishedPieceCount uint) *resource.Peer {
+		host := resource.NewHost(id, "127.0.0.1", id, 8003, 8001, types.HostTypeNormal)
+		peer := resource.NewPeer(id, &config.ResourceConfig{}, task, host)
+		peer.FSM.SetState(state)
+		for i := uint(0); i < finishedPieceCount; i++ {
+			peer.FinishedPieces.Set(i)
+		}
+
+		return peer
+	}
+
+	parents := []*resource.Peer{
+		newPeer("validation-parent-1", resource.PeerStateSucceeded, validationPieceCount),
+		newPeer("validation-parent-2", resource.PeerStateRunning, validationPieceCount/2),
+	}
+	child := newPeer("validation-child", resource.PeerStateRunning, 0)
+
+	// The evaluated parents must be a permutation of the parents.
+	evaluatedParents := e.EvaluateParents(append([]*resource.Peer{}, parents...), child, validationPieceCount)
+	if len(evaluatedParents) != len(parents) {
+		return fmt.Errorf("evaluator plugin returns %d parents, expected %d", len(evaluatedParents), len(parents))
+	}
+
+	seen := make(map[*resource.Peer]struct{}, len(parents))
+	for _, evaluatedParent := range evaluatedParents {
+		var found bool
+		for _, parent := range parents {
+			if evaluatedParent == parent {
+				found = true
+				break
+			}
+		}
+
+		if _, ok := seen[evaluatedParent]; ok || !found {
+			return errors.New("evaluator plugin returns unknown parents")
+		}
+		seen[evaluatedParent] = struct{}{}
+	}
+
+	if scorer, ok := e.(Scorer); ok {
+		for _, parent := range parents {
+			score := scorer.EvaluateParent(parent, child, validationPieceCount)
+			if math.IsNaN(score) || math.IsInf(score, 0) {
+				return fmt.Errorf("evaluator plugin returns invalid score %f", score)
+			}
+		}
+	}
+
+	for _, parent := range parents {
+		e.IsBadNode(parent)
+	}
+
+	return nil
+}
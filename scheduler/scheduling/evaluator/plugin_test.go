@@ -17,12 +17,15 @@
 package evaluator
 
 import (
+	"math"
 	"os"
 	"os/exec"
 	"path"
 	"testing"
 
 	testifyassert "github.com/stretchr/testify/assert"
+
+	"d7y.io/dragonfly/v2/scheduler/resource"
 )
 
 func TestPlugin_Load(t *testing.T) {
@@ -70,3 +73,117 @@ func TestPlugin_Load(t *testing.T) {
 		return
 	}
 }
+
+// brokenEvaluator is a broken evaluator plugin stub.
+type brokenEvaluator struct {
+	evaluateParents func(parents []*resource.Peer) []*resource.Peer
+	score           float64
+}
+
+func (e *brokenEvaluator) EvaluateParents(parents []*resource.Peer, child *resource.Peer, taskPieceCount int32) []*resource.Peer {
+	return e.evaluateParents(parents)
+}
+
+func (e *brokenEvaluator) EvaluateParent(parent *resource.Peer, child *resource.Peer, taskPieceCount int32) float64 {
+	return e.score
+}
+
+func (e *brokenEvaluator) IsBadNode(peer *resource.Peer) bool {
+	return false
+}
+
+func TestPlugin_validatePlugin(t *testing.T) {
+	tests := []struct {
+		name      string
+		evaluator Evaluator
+		expect    func(t *testing.T, err error)
+	}{
+		{
+			name:      "validate base evaluator",
+			evaluator: newEvaluatorBase(),
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "evaluator returns reversed parents",
+			evaluator: &brokenEvaluator{
+				evaluateParents: func(parents []*resource.Peer) []*resource.Peer {
+					return []*resource.Peer{parents[1], parents[0]}
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "evaluator drops parents",
+			evaluator: &brokenEvaluator{
+				evaluateParents: func(parents []*resource.Peer) []*resource.Peer {
+					return parents[:1]
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "evaluator plugin returns 1 parents, expected 2")
+			},
+		},
+		{
+			name: "evaluator returns unknown parents",
+			evaluator: &brokenEvaluator{
+				evaluateParents: func(parents []*resource.Peer) []*resource.Peer {
+					return []*resource.Peer{{}, {}}
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "evaluator plugin returns unknown parents")
+			},
+		},
+		{
+			name: "evaluator returns duplicate parents",
+			evaluator: &brokenEvaluator{
+				evaluateParents: func(parents []*resource.Peer) []*resource.Peer {
+					return []*resource.Peer{parents[0], parents[0]}
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "evaluator plugin returns unknown parents")
+			},
+		},
+		{
+			name: "evaluator returns invalid score",
+			evaluator: &brokenEvaluator{
+				evaluateParents: func(parents []*resource.Peer) []*resource.Peer {
+					return parents
+				},
+				score: math.NaN(),
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "evaluator plugin returns invalid score NaN")
+			},
+		},
+		{
+			name: "evaluator panics",
+			evaluator: &brokenEvaluator{
+				evaluateParents: func(parents []*resource.Peer) []*resource.Peer {
+					panic("foo")
+				},
+			},
+			expect: func(t *testing.T, err error) {
+				assert := testifyassert.New(t)
+				assert.EqualError(err, "evaluator plugin panics: foo")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect(t, validatePlugin(tc.evaluator))
+		})
+	}
+}
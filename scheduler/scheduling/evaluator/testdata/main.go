@@ -41,4 +41,10 @@ func main() {
 		fmt.Println("IsBadNode failed")
 		os.Exit(1)
 	}
+
+	// The copy of the same plugin is refused by the go runtime, instead of returning the loaded plugin.
+	if _, err := evaluator.ReloadPlugin("./testdata"); err == nil {
+		fmt.Println("ReloadPlugin same plugin should fail")
+		os.Exit(1)
+	}
 }
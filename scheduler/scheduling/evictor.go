@@ -0,0 +1,117 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduling
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+
+	"d7y.io/dragonfly/v2/scheduler/metrics"
+	"d7y.io/dragonfly/v2/scheduler/resource"
+)
+
+// Evictor evicts the peers on demand, e.g. the peer of the misbehaving host.
+type Evictor interface {
+	// EvictPeer closes the stream of the peer with the reason, removes the peer from the task
+	// and the peer manager, and reschedules the children of the peer to the other parents in
+	// the background. The evicted peer is refused to register again within resource.EvictedPeerTTL.
+	EvictPeer(ctx context.Context, peerID, reason string) error
+}
+
+// evictor is an implementation of Evictor.
+type evictor struct {
+	// Resource interface.
+	resource resource.Resource
+
+	// Scheduling interface.
+	scheduling Scheduling
+}
+
+// NewEvictor returns a new Evictor.
+func NewEvictor(resource resource.Resource, scheduling Scheduling) Evictor {
+	return &evictor{
+		resource:   resource,
+		scheduling: scheduling,
+	}
+}
+
+// EvictPeer closes the stream of the peer with the reason, removes the peer from the task
+// and the peer manager, and reschedules the children of the peer to the other parents in
+// the background. The evicted peer is refused to register again within resource.EvictedPeerTTL.
+func (e *evictor) EvictPeer(ctx context.Context, peerID, reason string) error {
+	peer, loaded := e.resource.PeerManager().Load(peerID)
+	if !loaded {
+		return status.Errorf(codes.NotFound, "peer %s not found", peerID)
+	}
+	peer.Log.Infof("evict peer, reason: %s", reason)
+
+	// The children must be collected before the peer is removed from the dag.
+	children := peer.Children()
+
+	// The peer announced by v1 version of the grpc exits when it receives Code_SchedForbidden,
+	// the peer announced by v2 version of the grpc fails with codes.NotFound at the next request.
+	if _, loaded := peer.LoadReportPieceResultStream(); loaded {
+		if err := peer.SendPeerPacket(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedForbidden}); err != nil {
+			peer.Log.Errorf("send packet failed: %s", err.Error())
+		}
+
+		peer.DeleteReportPieceResultStream()
+	}
+	peer.DeleteAnnouncePeerStream()
+
+	if !peer.FSM.Is(resource.PeerStateLeave) {
+		if err := peer.FSM.Event(ctx, resource.PeerEventLeave); err != nil {
+			peer.Log.Errorf("peer fsm event failed: %s", err.Error())
+		}
+	}
+	peer.Host.EvictPeer(peer.ID)
+	e.resource.PeerManager().Delete(peer.ID)
+
+	// Scheduling the children may retry for a long time, so it does not block the caller,
+	// and it is not canceled with the context of the caller.
+	for _, child := range children {
+		child.BlockParent(peer.ID)
+	}
+	go e.rescheduleChildren(context.Background(), peer, children)
+	return nil
+}
+
+// rescheduleChildren reschedules a new parent to the children of the evicted peer to exclude the evicted peer.
+func (e *evictor) rescheduleChildren(ctx context.Context, peer *resource.Peer, children []*resource.Peer) {
+	for _, child := range children {
+		child.Log.Infof("reschedule parent because of parent peer %s is evicted", peer.ID)
+
+		// Record the start time.
+		start := time.Now()
+		if _, loaded := child.LoadAnnouncePeerStream(); loaded {
+			if err := e.scheduling.ScheduleCandidateParents(ctx, child, child.LoadBlockParents()); err != nil {
+				child.Log.Errorf("schedule candidate parents failed: %s", err.Error())
+			}
+		} else {
+			e.scheduling.ScheduleParentAndCandidateParents(ctx, child, child.LoadBlockParents())
+		}
+
+		// Collect SchedulingDuration metrics.
+		metrics.ScheduleDuration.Observe(float64(time.Since(start).Milliseconds()))
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
+	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
+	schedulerv1 "d7y.io/api/v2/pkg/apis/scheduler/v1"
+	schedulerv1mocks "d7y.io/api/v2/pkg/apis/scheduler/v1/mocks"
+	schedulerv2 "d7y.io/api/v2/pkg/apis/scheduler/v2"
+	schedulerv2mocks "d7y.io/api/v2/pkg/apis/scheduler/v2/mocks"
+
+	"d7y.io/dragonfly/v2/manager/types"
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	pkgtypes "d7y.io/dragonfly/v2/pkg/types"
+	configmocks "d7y.io/dragonfly/v2/scheduler/config/mocks"
+	"d7y.io/dragonfly/v2/scheduler/resource"
+)
+
+func TestEvictor_EvictPeer(t *testing.T) {
+	tests := []struct {
+		name string
+		mock func(parent, child *resource.Peer, parentStream *schedulerv1mocks.MockScheduler_ReportPieceResultServer, childStream *schedulerv1mocks.MockScheduler_ReportPieceResultServer,
+			childAnnounceStream *schedulerv2mocks.MockScheduler_AnnouncePeerServer, peerManager resource.PeerManager, rescheduled chan struct{}, mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder)
+		expect func(t *testing.T, parent, child, seedPeer *resource.Peer, rescheduled chan struct{}, err error)
+	}{
+		{
+			name: "peer not found",
+			mock: func(parent, child *resource.Peer, parentStream *schedulerv1mocks.MockScheduler_ReportPieceResultServer, childStream *schedulerv1mocks.MockScheduler_ReportPieceResultServer,
+				childAnnounceStream *schedulerv2mocks.MockScheduler_AnnouncePeerServer, peerManager resource.PeerManager, rescheduled chan struct{}, mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder) {
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(parent.ID)).Return(nil, false).Times(1),
+				)
+			},
+			expect: func(t *testing.T, parent, child, seedPeer *resource.Peer, rescheduled chan struct{}, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, status.Errorf(codes.NotFound, "peer %s not found", parent.ID))
+				assert.True(parent.FSM.Is(resource.PeerStateRunning))
+				assert.Len(child.Parents(), 1)
+				assert.Equal(child.Parents()[0].ID, parent.ID)
+				assert.False(parent.Host.IsPeerEvicted(parent.ID))
+			},
+		},
+		{
+			name: "evict parent and reschedule the child announced by v1 version of the grpc",
+			mock: func(parent, child *resource.Peer, parentStream *schedulerv1mocks.MockScheduler_ReportPieceResultServer, childStream *schedulerv1mocks.MockScheduler_ReportPieceResultServer,
+				childAnnounceStream *schedulerv2mocks.MockScheduler_AnnouncePeerServer, peerManager resource.PeerManager, rescheduled chan struct{}, mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder) {
+				parent.StoreReportPieceResultStream(parentStream)
+				child.StoreReportPieceResultStream(childStream)
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(parent.ID)).Return(parent, true).Times(1),
+					parentStream.EXPECT().Send(gomock.Eq(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedForbidden})).Return(nil).Times(1),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Delete(gomock.Eq(parent.ID)).Do(func(id string) {
+						parent.Task.DeletePeer(id)
+						parent.Host.DeletePeer(id)
+					}).Times(1),
+					childStream.EXPECT().Send(gomock.Any()).DoAndReturn(func(*schedulerv1.PeerPacket) error {
+						close(rescheduled)
+						return nil
+					}).Times(1),
+				)
+			},
+			expect: func(t *testing.T, parent, child, seedPeer *resource.Peer, rescheduled chan struct{}, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(parent.FSM.Is(resource.PeerStateLeave))
+				_, loaded := parent.LoadReportPieceResultStream()
+				assert.False(loaded)
+				_, loaded = parent.Task.LoadPeer(parent.ID)
+				assert.False(loaded)
+				assert.True(parent.Host.IsPeerEvicted(parent.ID))
+				assert.True(child.LoadBlockParents().Contains(parent.ID))
+
+				waitRescheduled(t, rescheduled)
+				assert.Len(child.Parents(), 1)
+				assert.Equal(child.Parents()[0].ID, seedPeer.ID)
+			},
+		},
+		{
+			name: "evict parent and reschedule the child announced by v2 version of the grpc",
+			mock: func(parent, child *resource.Peer, parentStream *schedulerv1mocks.MockScheduler_ReportPieceResultServer, childStream *schedulerv1mocks.MockScheduler_ReportPieceResultServer,
+				childAnnounceStream *schedulerv2mocks.MockScheduler_AnnouncePeerServer, peerManager resource.PeerManager, rescheduled chan struct{}, mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder) {
+				child.StoreAnnouncePeerStream(childAnnounceStream)
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(parent.ID)).Return(parent, true).Times(1),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Delete(gomock.Eq(parent.ID)).Do(func(id string) {
+						parent.Task.DeletePeer(id)
+						parent.Host.DeletePeer(id)
+					}).Times(1),
+					childAnnounceStream.EXPECT().Send(gomock.Any()).DoAndReturn(func(*schedulerv2.AnnouncePeerResponse) error {
+						close(rescheduled)
+						return nil
+					}).Times(1),
+				)
+			},
+			expect: func(t *testing.T, parent, child, seedPeer *resource.Peer, rescheduled chan struct{}, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(parent.FSM.Is(resource.PeerStateLeave))
+				_, loaded := parent.Task.LoadPeer(parent.ID)
+				assert.False(loaded)
+				assert.True(parent.Host.IsPeerEvicted(parent.ID))
+
+				waitRescheduled(t, rescheduled)
+				assert.Len(child.Parents(), 1)
+				assert.Equal(child.Parents()[0].ID, seedPeer.ID)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			res := resource.NewMockResource(ctl)
+			peerManager := resource.NewMockPeerManager(ctl)
+			parentStream := schedulerv1mocks.NewMockScheduler_ReportPieceResultServer(ctl)
+			childStream := schedulerv1mocks.NewMockScheduler_ReportPieceResultServer(ctl)
+			childAnnounceStream := schedulerv2mocks.NewMockScheduler_AnnouncePeerServer(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
+
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			mockParentHost := resource.NewHost(
+				idgen.HostIDV2("127.0.0.2", "baz"), "127.0.0.2", "baz",
+				mockRawHost.Port, mockRawHost.DownloadPort, pkgtypes.HostTypeNormal)
+			parent := resource.NewPeer(idgen.PeerIDV2(), mockResourceConfig, mockTask, mockParentHost)
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			child := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+			mockSeedHost := resource.NewHost(
+				mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+				mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+			seedPeer := resource.NewPeer(mockSeedPeerID, mockResourceConfig, mockTask, mockSeedHost)
+
+			for _, peer := range []*resource.Peer{parent, child, seedPeer} {
+				mockTask.StorePeer(peer)
+				peer.Host.StorePeer(peer)
+				peer.FSM.SetState(resource.PeerStateRunning)
+			}
+
+			if err := mockTask.AddPeerEdge(parent, child); err != nil {
+				t.Fatal(err)
+			}
+
+			rescheduled := make(chan struct{})
+			tc.mock(parent, child, parentStream, childStream, childAnnounceStream, peerManager, rescheduled, res.EXPECT(), peerManager.EXPECT())
+			evictor := NewEvictor(res, New(mockSchedulerConfig, dynconfig, mockPluginDir))
+			tc.expect(t, parent, child, seedPeer, rescheduled, evictor.EvictPeer(context.Background(), parent.ID, "foo"))
+		})
+	}
+}
+
+// waitRescheduled waits for the children of the evicted peer to be rescheduled in the background.
+func waitRescheduled(t *testing.T, rescheduled chan struct{}) {
+	select {
+	case <-rescheduled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("children of the evicted peer are not rescheduled")
+	}
+}
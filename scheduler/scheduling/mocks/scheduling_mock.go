@@ -86,6 +86,34 @@ func (mr *MockSchedulingMockRecorder) FindSuccessParent(arg0, arg1, arg2 any) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindSuccessParent", reflect.TypeOf((*MockScheduling)(nil).FindSuccessParent), arg0, arg1, arg2)
 }
 
+// ReloadEvaluator mocks base method.
+func (m *MockScheduling) ReloadEvaluator() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReloadEvaluator")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReloadEvaluator indicates an expected call of ReloadEvaluator.
+func (mr *MockSchedulingMockRecorder) ReloadEvaluator() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReloadEvaluator", reflect.TypeOf((*MockScheduling)(nil).ReloadEvaluator))
+}
+
+// RescheduleChildren mocks base method.
+func (m *MockScheduling) RescheduleChildren(arg0 context.Context, arg1 *resource.Task, arg2 int) int {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RescheduleChildren", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int)
+	return ret0
+}
+
+// RescheduleChildren indicates an expected call of RescheduleChildren.
+func (mr *MockSchedulingMockRecorder) RescheduleChildren(arg0, arg1, arg2 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RescheduleChildren", reflect.TypeOf((*MockScheduling)(nil).RescheduleChildren), arg0, arg1, arg2)
+}
+
 // ScheduleCandidateParents mocks base method.
 func (m *MockScheduling) ScheduleCandidateParents(arg0 context.Context, arg1 *resource.Peer, arg2 set.SafeSet[string]) error {
 	m.ctrl.T.Helper()
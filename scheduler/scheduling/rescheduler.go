@@ -0,0 +1,99 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduling
+
+import (
+	"context"
+	"time"
+
+	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/resource"
+)
+
+// Rescheduler pushes better candidate parents to the running children periodically.
+type Rescheduler interface {
+	// Serve starts rescheduling children.
+	Serve()
+
+	// Stop stops rescheduling children.
+	Stop()
+}
+
+// rescheduler is an implementation of Rescheduler.
+type rescheduler struct {
+	// Reschedule configuration.
+	config *config.RescheduleConfig
+
+	// Resource interface.
+	resource resource.Resource
+
+	// Scheduling interface.
+	scheduling Scheduling
+
+	// done channel will be closed when rescheduler stops.
+	done chan struct{}
+}
+
+// NewRescheduler returns a new Rescheduler.
+func NewRescheduler(cfg *config.RescheduleConfig, resource resource.Resource, scheduling Scheduling) Rescheduler {
+	return &rescheduler{
+		config:     cfg,
+		resource:   resource,
+		scheduling: scheduling,
+		done:       make(chan struct{}),
+	}
+}
+
+// Serve starts rescheduling children.
+func (r *rescheduler) Serve() {
+	logger.Info("reschedule children")
+	tick := time.NewTicker(r.config.Interval)
+	defer tick.Stop()
+
+	for {
+		select {
+		case <-tick.C:
+			r.reschedule(context.Background())
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Stop stops rescheduling children.
+func (r *rescheduler) Stop() {
+	close(r.done)
+}
+
+// reschedule reschedules at most BatchSize children of all tasks in one interval.
+func (r *rescheduler) reschedule(ctx context.Context) {
+	remaining := r.config.BatchSize
+	r.resource.TaskManager().Range(func(_, value any) bool {
+		task, ok := value.(*resource.Task)
+		if !ok {
+			return true
+		}
+
+		remaining -= r.scheduling.RescheduleChildren(ctx, task, remaining)
+		return remaining > 0
+	})
+
+	if rescheduled := r.config.BatchSize - remaining; rescheduled > 0 {
+		logger.Infof("reschedule %d children", rescheduled)
+	}
+}
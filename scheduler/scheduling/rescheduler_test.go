@@ -0,0 +1,91 @@
+/*
+ *     Copyright 2024 The Dragonfly Authors
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scheduling
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/mock/gomock"
+
+	commonv2 "d7y.io/api/v2/pkg/apis/common/v2"
+
+	"d7y.io/dragonfly/v2/pkg/idgen"
+	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/resource"
+	"d7y.io/dragonfly/v2/scheduler/scheduling/mocks"
+)
+
+func TestRescheduler_reschedule(t *testing.T) {
+	var tasks []*resource.Task
+	for i := 0; i < 3; i++ {
+		url := mockTaskURL + string(rune('a'+i))
+		tasks = append(tasks, resource.NewTask(idgen.TaskIDV2(url, mockTaskDigest.String(), mockTaskTag, mockTaskApplication, mockTaskFilteredQueryParams),
+			url, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit))
+	}
+
+	tests := []struct {
+		name      string
+		batchSize int
+		mock      func(ms *mocks.MockSchedulingMockRecorder)
+	}{
+		{
+			name:      "reschedule children within batch size",
+			batchSize: 3,
+			mock: func(ms *mocks.MockSchedulingMockRecorder) {
+				gomock.InOrder(
+					ms.RescheduleChildren(gomock.Any(), tasks[0], 3).Return(2).Times(1),
+					ms.RescheduleChildren(gomock.Any(), tasks[1], 1).Return(1).Times(1),
+				)
+			},
+		},
+		{
+			name:      "reschedule children of all tasks",
+			batchSize: 3,
+			mock: func(ms *mocks.MockSchedulingMockRecorder) {
+				gomock.InOrder(
+					ms.RescheduleChildren(gomock.Any(), tasks[0], 3).Return(0).Times(1),
+					ms.RescheduleChildren(gomock.Any(), tasks[1], 3).Return(1).Times(1),
+					ms.RescheduleChildren(gomock.Any(), tasks[2], 2).Return(0).Times(1),
+				)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			res := resource.NewMockResource(ctl)
+			taskManager := resource.NewMockTaskManager(ctl)
+			scheduling := mocks.NewMockScheduling(ctl)
+
+			res.EXPECT().TaskManager().Return(taskManager).Times(1)
+			taskManager.EXPECT().Range(gomock.Any()).Do(func(f func(any, any) bool) {
+				for _, task := range tasks {
+					if !f(task.ID, task) {
+						return
+					}
+				}
+			}).Times(1)
+			tc.mock(scheduling.EXPECT())
+
+			r := NewRescheduler(&config.RescheduleConfig{BatchSize: tc.batchSize}, res, scheduling)
+			r.(*rescheduler).reschedule(context.Background())
+		})
+	}
+}
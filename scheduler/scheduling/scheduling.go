@@ -20,7 +20,12 @@ package scheduling
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"google.golang.org/grpc/codes"
@@ -34,8 +39,10 @@ import (
 	schedulerv2 "d7y.io/api/v2/pkg/apis/scheduler/v2"
 
 	"d7y.io/dragonfly/v2/pkg/container/set"
+	"d7y.io/dragonfly/v2/pkg/slices"
 	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
+	"d7y.io/dragonfly/v2/scheduler/metrics"
 	"d7y.io/dragonfly/v2/scheduler/resource"
 	"d7y.io/dragonfly/v2/scheduler/scheduling/evaluator"
 )
@@ -59,12 +66,28 @@ type Scheduling interface {
 
 	// FindSuccessParent finds success parent for the peer.
 	FindSuccessParent(context.Context, *resource.Peer, set.SafeSet[string]) (*resource.Peer, bool)
+
+	// RescheduleChildren pushes better candidate parents to the running children of the task,
+	// it returns the number of the rescheduled children which is not greater than the limit.
+	// Used only in v2 version of the grpc.
+	RescheduleChildren(context.Context, *resource.Task, int) int
+
+	// ReloadEvaluator reloads the evaluator plugin from the plugin directory,
+	// the evaluations in flight finish with the previous evaluator.
+	ReloadEvaluator() error
 }
 
 type scheduling struct {
 	// Evaluator interface.
 	evaluator evaluator.Evaluator
 
+	// evaluatorMu guards the evaluator, the evaluations hold the read lock
+	// and reloading the evaluator holds the write lock.
+	evaluatorMu sync.RWMutex
+
+	// Plugin directory of the evaluator plugin.
+	pluginDir string
+
 	// Scheduler configuration.
 	config *config.SchedulerConfig
 
@@ -75,6 +98,7 @@ type scheduling struct {
 func New(cfg *config.SchedulerConfig, dynconfig config.DynconfigInterface, pluginDir string, networkTopologyOptions ...evaluator.NetworkTopologyOption) Scheduling {
 	return &scheduling{
 		evaluator: evaluator.New(cfg.Algorithm, pluginDir, networkTopologyOptions...),
+		pluginDir: pluginDir,
 		config:    cfg,
 		dynconfig: dynconfig,
 	}
@@ -97,19 +121,29 @@ func (s *scheduling) ScheduleCandidateParents(ctx context.Context, peer *resourc
 		// Condition 1: Peer's NeedBackToSource is true.
 		// Condition 2: Scheduling exceeds the RetryBackToSourceLimit.
 		if peer.Task.CanBackToSource() {
+			// Wait for the back-to-source rate limiter of the task, so that
+			// the peers of the task do not hit the source at the same time.
+			if peer.NeedBackToSource.Load() || n >= s.config.RetryBackToSourceLimit {
+				if err := peer.Task.WaitBackToSource(ctx); err != nil {
+					peer.Log.Errorf("wait back-to-source failed: %s", err.Error())
+					return status.Error(codes.FailedPrecondition, err.Error())
+				}
+			}
+
 			// Check condition 1:
-			// Peer's NeedBackToSource is true.
-			if peer.NeedBackToSource.Load() {
-				stream, loaded := peer.LoadAnnouncePeerStream()
+			// Peer's NeedBackToSource is true and the peer is granted to back-to-source.
+			if peer.NeedBackToSource.Load() && peer.Task.AcquireBackToSource(peer.ID) {
+				_, loaded := peer.LoadAnnouncePeerStream()
 				if !loaded {
 					peer.Log.Error("load stream failed")
+					peer.Task.DeleteBackToSourcePeer(peer.ID)
 					return status.Error(codes.FailedPrecondition, "load stream failed")
 				}
 
 				// Send NeedBackToSourceResponse to peer.
 				peer.Log.Infof("send NeedBackToSourceResponse, because of peer's NeedBackToSource is %t", peer.NeedBackToSource.Load())
 				description := fmt.Sprintf("peer's NeedBackToSource is %t", peer.NeedBackToSource.Load())
-				if err := stream.Send(&schedulerv2.AnnouncePeerResponse{
+				if err := peer.SendAnnouncePeerResponse(&schedulerv2.AnnouncePeerResponse{
 					Response: &schedulerv2.AnnouncePeerResponse_NeedBackToSourceResponse{
 						NeedBackToSourceResponse: &schedulerv2.NeedBackToSourceResponse{
 							Description: &description,
@@ -117,25 +151,30 @@ func (s *scheduling) ScheduleCandidateParents(ctx context.Context, peer *resourc
 					},
 				}); err != nil {
 					peer.Log.Error(err)
+					peer.Task.DeleteBackToSourcePeer(peer.ID)
 					return status.Error(codes.FailedPrecondition, err.Error())
 				}
 
+				collectPeerBackToSourceMetrics(peer, metrics.PeerBackToSourceReasonNeeded)
+				appendPeerDecision(peer, resource.PeerDecisionBackToSource, metrics.PeerBackToSourceReasonNeeded, n, nil)
 				return nil
 			}
 
 			// Check condition 2:
 			// The number of retry scheduling is greater than RetryBackToSourceLimit
-			if n >= s.config.RetryBackToSourceLimit {
-				stream, loaded := peer.LoadAnnouncePeerStream()
+			// and the peer is granted to back-to-source.
+			if n >= s.config.RetryBackToSourceLimit && peer.Task.AcquireBackToSource(peer.ID) {
+				_, loaded := peer.LoadAnnouncePeerStream()
 				if !loaded {
 					peer.Log.Error("load stream failed")
+					peer.Task.DeleteBackToSourcePeer(peer.ID)
 					return status.Error(codes.FailedPrecondition, "load stream failed")
 				}
 
 				// Send NeedBackToSourceResponse to peer.
 				peer.Log.Infof("send NeedBackToSourceResponse, because of scheduling exceeded RetryBackToSourceLimit %d", s.config.RetryBackToSourceLimit)
 				description := "scheduling exceeded RetryBackToSourceLimit"
-				if err := stream.Send(&schedulerv2.AnnouncePeerResponse{
+				if err := peer.SendAnnouncePeerResponse(&schedulerv2.AnnouncePeerResponse{
 					Response: &schedulerv2.AnnouncePeerResponse_NeedBackToSourceResponse{
 						NeedBackToSourceResponse: &schedulerv2.NeedBackToSourceResponse{
 							Description: &description,
@@ -143,9 +182,12 @@ func (s *scheduling) ScheduleCandidateParents(ctx context.Context, peer *resourc
 					},
 				}); err != nil {
 					peer.Log.Error(err)
+					peer.Task.DeleteBackToSourcePeer(peer.ID)
 					return status.Error(codes.FailedPrecondition, err.Error())
 				}
 
+				collectPeerBackToSourceMetrics(peer, metrics.PeerBackToSourceReasonRetryLimitExceeded)
+				appendPeerDecision(peer, resource.PeerDecisionBackToSource, metrics.PeerBackToSourceReasonRetryLimitExceeded, n, nil)
 				return nil
 			}
 		}
@@ -155,30 +197,31 @@ func (s *scheduling) ScheduleCandidateParents(ctx context.Context, peer *resourc
 		// Condition 1: Scheduling exceeds the RetryLimit.
 		if n >= s.config.RetryLimit {
 			peer.Log.Errorf("scheduling failed, because of scheduling exceeded RetryLimit %d", s.config.RetryLimit)
+			appendPeerDecision(peer, resource.PeerDecisionFailed, "retry_limit_exceeded", n, nil)
 			return status.Error(codes.FailedPrecondition, "scheduling exceeded RetryLimit")
 		}
 
 		// Scheduling will send NormalTaskResponse to peer.
 		//
 		// Condition 1: Scheduling can find candidate parents.
-		if err := peer.Task.DeletePeerInEdges(peer.ID); err != nil {
-			peer.Log.Error(err)
-			return status.Error(codes.Internal, err.Error())
-		}
-
-		// Find candidate parents.
 		candidateParents, found := s.FindCandidateParents(ctx, peer, blocklist)
 		if !found {
 			n++
-			peer.Log.Infof("scheduling failed in %d times, because of candidate parents not found", n)
+			peer.Log.Infof("scheduling failed in %d times, because of candidate parents not found, rejections: %s", n, formatParentRejections(peer.ParentRejections()))
+
+			// The previous parents are not available any more.
+			if err := peer.Task.DeletePeerInEdges(peer.ID); err != nil {
+				peer.Log.Error(err)
+				return status.Error(codes.Internal, err.Error())
+			}
 
 			// Sleep to avoid hot looping.
-			time.Sleep(s.config.RetryInterval)
+			time.Sleep(s.config.RetryInterval.Duration)
 			continue
 		}
 
 		// Load AnnouncePeerStream from peer.
-		stream, loaded := peer.LoadAnnouncePeerStream()
+		_, loaded := peer.LoadAnnouncePeerStream()
 		if !loaded {
 			if err := peer.Task.DeletePeerInEdges(peer.ID); err != nil {
 				msg := fmt.Sprintf("peer deletes inedges failed: %s", err.Error())
@@ -190,23 +233,33 @@ func (s *scheduling) ScheduleCandidateParents(ctx context.Context, peer *resourc
 			return status.Error(codes.FailedPrecondition, "load stream failed")
 		}
 
+		// Replace the parents of peer with the candidate parents, the candidate parents may become
+		// descendants of the peer after they are found, and then the previous parents are rolled back.
+		candidateParents, err := peer.Task.ReplacePeerParents(peer, candidateParents)
+		if err != nil {
+			n++
+			peer.Log.Infof("scheduling failed in %d times, because of %s", n, err.Error())
+
+			// Sleep to avoid hot looping.
+			time.Sleep(s.config.RetryInterval.Duration)
+			continue
+		}
+
 		// Send NormalTaskResponse to peer.
 		peer.Log.Info("send NormalTaskResponse")
-		if err := stream.Send(&schedulerv2.AnnouncePeerResponse{
+		if err := peer.SendAnnouncePeerResponse(&schedulerv2.AnnouncePeerResponse{
 			Response: ConstructSuccessNormalTaskResponse(candidateParents),
 		}); err != nil {
 			peer.Log.Error(err)
-			return status.Error(codes.FailedPrecondition, err.Error())
-		}
-
-		// Add edge from parent to peer.
-		for _, candidateParent := range candidateParents {
-			if err := peer.Task.AddPeerEdge(candidateParent, peer); err != nil {
-				peer.Log.Warnf("peer adds edge failed: %s", err.Error())
-				continue
+			if err := peer.Task.DeletePeerInEdges(peer.ID); err != nil {
+				peer.Log.Errorf("peer deletes inedges failed: %s", err.Error())
 			}
+
+			return status.Error(codes.FailedPrecondition, err.Error())
 		}
+		peer.StoreParent(candidateParents[0].ID)
 
+		appendPeerDecision(peer, resource.PeerDecisionScheduled, "", n, candidateParents)
 		peer.Log.Infof("scheduling success in %d times", n+1)
 		return nil
 	}
@@ -229,21 +282,27 @@ func (s *scheduling) ScheduleParentAndCandidateParents(ctx context.Context, peer
 		// Condition 1: Peer's NeedBackToSource is true.
 		// Condition 2: Scheduling exceeds the RetryBackToSourceLimit.
 		if peer.Task.CanBackToSource() {
-			// Check condition 1:
-			// Peer's NeedBackToSource is true.
-			if peer.NeedBackToSource.Load() {
-				stream, loaded := peer.LoadReportPieceResultStream()
-				if !loaded {
-					peer.Log.Error("load stream failed")
+			// Wait for the back-to-source rate limiter of the task, so that
+			// the peers of the task do not hit the source at the same time.
+			if peer.NeedBackToSource.Load() || n >= s.config.RetryBackToSourceLimit {
+				if err := peer.Task.WaitBackToSource(ctx); err != nil {
+					peer.Log.Errorf("wait back-to-source failed: %s", err.Error())
 					return
 				}
+			}
 
+			// Check condition 1:
+			// Peer's NeedBackToSource is true and the peer is granted to back-to-source.
+			if peer.NeedBackToSource.Load() && peer.Task.AcquireBackToSource(peer.ID) {
 				// Send Code_SchedNeedBackSource to peer.
-				if err := stream.Send(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedNeedBackSource}); err != nil {
+				if err := peer.SendPeerPacket(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedNeedBackSource}); err != nil {
 					peer.Log.Error(err)
+					peer.Task.DeleteBackToSourcePeer(peer.ID)
 					return
 				}
 				peer.Log.Infof("send Code_SchedNeedBackSource to peer, because of peer's NeedBackToSource is %t", peer.NeedBackToSource.Load())
+				collectPeerBackToSourceMetrics(peer, metrics.PeerBackToSourceReasonNeeded)
+				appendPeerDecision(peer, resource.PeerDecisionBackToSource, metrics.PeerBackToSourceReasonNeeded, n, nil)
 
 				if err := peer.FSM.Event(ctx, resource.PeerEventDownloadBackToSource); err != nil {
 					peer.Log.Errorf("peer fsm event failed: %s", err.Error())
@@ -264,19 +323,17 @@ func (s *scheduling) ScheduleParentAndCandidateParents(ctx context.Context, peer
 
 			// Check condition 2:
 			// The number of retry scheduling is greater than RetryBackToSourceLimit
-			if n >= s.config.RetryBackToSourceLimit {
-				stream, loaded := peer.LoadReportPieceResultStream()
-				if !loaded {
-					peer.Log.Error("load stream failed")
-					return
-				}
-
+			// and the peer is granted to back-to-source.
+			if n >= s.config.RetryBackToSourceLimit && peer.Task.AcquireBackToSource(peer.ID) {
 				// Send Code_SchedNeedBackSource peer.
-				if err := stream.Send(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedNeedBackSource}); err != nil {
+				if err := peer.SendPeerPacket(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedNeedBackSource}); err != nil {
 					peer.Log.Error(err)
+					peer.Task.DeleteBackToSourcePeer(peer.ID)
 					return
 				}
 				peer.Log.Infof("send Code_SchedNeedBackSource to peer, because of scheduling exceeded RetryBackToSourceLimit %d", s.config.RetryBackToSourceLimit)
+				collectPeerBackToSourceMetrics(peer, metrics.PeerBackToSourceReasonRetryLimitExceeded)
+				appendPeerDecision(peer, resource.PeerDecisionBackToSource, metrics.PeerBackToSourceReasonRetryLimitExceeded, n, nil)
 
 				if err := peer.FSM.Event(ctx, resource.PeerEventDownloadBackToSource); err != nil {
 					peer.Log.Errorf("peer fsm event failed: %s", err.Error())
@@ -300,48 +357,37 @@ func (s *scheduling) ScheduleParentAndCandidateParents(ctx context.Context, peer
 		//
 		// Condition 1: Scheduling exceeds the RetryLimit.
 		if n >= s.config.RetryLimit {
-			stream, loaded := peer.LoadReportPieceResultStream()
-			if !loaded {
-				peer.Log.Error("load stream failed")
-				return
-			}
-
 			// Send Code_SchedTaskStatusError to peer.
-			if err := stream.Send(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedTaskStatusError}); err != nil {
+			if err := peer.SendPeerPacket(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedTaskStatusError}); err != nil {
 				peer.Log.Error(err)
 				return
 			}
 
 			peer.Log.Errorf("send SchedulePeerFailed to peer, because of scheduling exceeded RetryLimit %d", s.config.RetryLimit)
+			appendPeerDecision(peer, resource.PeerDecisionFailed, "retry_limit_exceeded", n, nil)
 			return
 		}
 
 		// Scheduling will send PeerPacket to peer.
 		//
 		// Condition 1: Scheduling can find candidate parents.
-		if err := peer.Task.DeletePeerInEdges(peer.ID); err != nil {
-			n++
-			peer.Log.Errorf("scheduling failed in %d times, because of %s", n, err.Error())
-
-			// Sleep to avoid hot looping.
-			time.Sleep(s.config.RetryInterval)
-			continue
-		}
-
-		// Find candidate parents.
 		candidateParents, found := s.FindCandidateParents(ctx, peer, blocklist)
 		if !found {
 			n++
-			peer.Log.Infof("scheduling failed in %d times, because of candidate parents not found", n)
+			peer.Log.Infof("scheduling failed in %d times, because of candidate parents not found, rejections: %s", n, formatParentRejections(peer.ParentRejections()))
+
+			// The previous parents are not available any more.
+			if err := peer.Task.DeletePeerInEdges(peer.ID); err != nil {
+				peer.Log.Errorf("peer deletes inedges failed: %s", err.Error())
+			}
 
 			// Sleep to avoid hot looping.
-			time.Sleep(s.config.RetryInterval)
+			time.Sleep(s.config.RetryInterval.Duration)
 			continue
 		}
 
 		// Load ReportPieceResultStream from peer.
-		stream, loaded := peer.LoadReportPieceResultStream()
-		if !loaded {
+		if _, loaded := peer.LoadReportPieceResultStream(); !loaded {
 			n++
 			peer.Log.Errorf("scheduling failed in %d times, because of loading peer stream failed", n)
 
@@ -353,9 +399,21 @@ func (s *scheduling) ScheduleParentAndCandidateParents(ctx context.Context, peer
 			return
 		}
 
+		// Replace the parents of peer with the candidate parents, the candidate parents may become
+		// descendants of the peer after they are found, and then the previous parents are rolled back.
+		candidateParents, err := peer.Task.ReplacePeerParents(peer, candidateParents)
+		if err != nil {
+			n++
+			peer.Log.Infof("scheduling failed in %d times, because of %s", n, err.Error())
+
+			// Sleep to avoid hot looping.
+			time.Sleep(s.config.RetryInterval.Duration)
+			continue
+		}
+
 		// Send PeerPacket to peer.
 		peer.Log.Info("send PeerPacket to peer")
-		if err := stream.Send(ConstructSuccessPeerPacket(peer, candidateParents[0], candidateParents[1:])); err != nil {
+		if err := peer.SendPeerPacket(ConstructSuccessPeerPacket(peer, candidateParents[0], candidateParents[1:])); err != nil {
 			n++
 			peer.Log.Errorf("scheduling failed in %d times, because of %s", n, err.Error())
 
@@ -366,15 +424,9 @@ func (s *scheduling) ScheduleParentAndCandidateParents(ctx context.Context, peer
 
 			return
 		}
+		peer.StoreParent(candidateParents[0].ID)
 
-		// Add edge from parent to peer.
-		for _, candidateParent := range candidateParents {
-			if err := peer.Task.AddPeerEdge(candidateParent, peer); err != nil {
-				peer.Log.Debugf("peer adds edge failed: %s", err.Error())
-				continue
-			}
-		}
-
+		appendPeerDecision(peer, resource.PeerDecisionScheduled, "", n, candidateParents)
 		peer.Log.Infof("scheduling success in %d times", n+1)
 		return
 	}
@@ -382,6 +434,9 @@ func (s *scheduling) ScheduleParentAndCandidateParents(ctx context.Context, peer
 
 // FindCandidateParents finds candidate parents for the peer.
 func (s *scheduling) FindCandidateParents(ctx context.Context, peer *resource.Peer, blocklist set.SafeSet[string]) ([]*resource.Peer, bool) {
+	s.evaluatorMu.RLock()
+	defer s.evaluatorMu.RUnlock()
+
 	// Only PeerStateReceivedNormal and PeerStateRunning peers need to be rescheduled,
 	// and other states including the PeerStateBackToSource indicate that
 	// they have been scheduled.
@@ -393,7 +448,7 @@ func (s *scheduling) FindCandidateParents(ctx context.Context, peer *resource.Pe
 	// Find the candidate parent that can be scheduled.
 	candidateParents := s.filterCandidateParents(peer, blocklist)
 	if len(candidateParents) == 0 {
-		peer.Log.Info("can not find candidate parents")
+		peer.Log.Infof("can not find candidate parents, rejections: %s", formatParentRejections(peer.ParentRejections()))
 		return []*resource.Peer{}, false
 	}
 
@@ -401,17 +456,15 @@ func (s *scheduling) FindCandidateParents(ctx context.Context, peer *resource.Pe
 	taskTotalPieceCount := peer.Task.TotalPieceCount.Load()
 	candidateParents = s.evaluator.EvaluateParents(candidateParents, peer, taskTotalPieceCount)
 
-	// Get the parents with candidateParentLimit.
-	candidateParentLimit := config.DefaultSchedulerCandidateParentLimit
-	if config, err := s.dynconfig.GetSchedulerClusterConfig(); err == nil {
-		if config.CandidateParentLimit > 0 {
-			candidateParentLimit = int(config.CandidateParentLimit)
-		}
-	}
+	// Move the seed peers to the front if the peer prefers them.
+	candidateParents = preferSeedCandidateParents(peer, candidateParents)
 
-	if len(candidateParents) > candidateParentLimit {
-		candidateParents = candidateParents[:candidateParentLimit]
-	}
+	// Keep the pinned parent in the first place, so that the peer is
+	// not switched between the parents with oscillating scores.
+	candidateParents = pinCandidateParents(peer, candidateParents)
+
+	// Get the parents with candidateParentLimit.
+	candidateParents = s.limitCandidateParents(candidateParents)
 
 	var parentIDs []string
 	for _, candidateParent := range candidateParents {
@@ -424,6 +477,9 @@ func (s *scheduling) FindCandidateParents(ctx context.Context, peer *resource.Pe
 
 // FindParentAndCandidateParents finds a parent and candidate parents for the peer.
 func (s *scheduling) FindParentAndCandidateParents(ctx context.Context, peer *resource.Peer, blocklist set.SafeSet[string]) ([]*resource.Peer, bool) {
+	s.evaluatorMu.RLock()
+	defer s.evaluatorMu.RUnlock()
+
 	// Only PeerStateRunning peers need to be rescheduled,
 	// and other states including the PeerStateBackToSource indicate that
 	// they have been scheduled.
@@ -435,7 +491,7 @@ func (s *scheduling) FindParentAndCandidateParents(ctx context.Context, peer *re
 	// Find the candidate parent that can be scheduled.
 	candidateParents := s.filterCandidateParents(peer, blocklist)
 	if len(candidateParents) == 0 {
-		peer.Log.Info("can not find candidate parents")
+		peer.Log.Infof("can not find candidate parents, rejections: %s", formatParentRejections(peer.ParentRejections()))
 		return []*resource.Peer{}, false
 	}
 
@@ -443,17 +499,11 @@ func (s *scheduling) FindParentAndCandidateParents(ctx context.Context, peer *re
 	taskTotalPieceCount := peer.Task.TotalPieceCount.Load()
 	candidateParents = s.evaluator.EvaluateParents(candidateParents, peer, taskTotalPieceCount)
 
-	// Get the parents with candidateParentLimit.
-	candidateParentLimit := config.DefaultSchedulerCandidateParentLimit
-	if config, err := s.dynconfig.GetSchedulerClusterConfig(); err == nil {
-		if config.CandidateParentLimit > 0 {
-			candidateParentLimit = int(config.CandidateParentLimit)
-		}
-	}
+	// Move the seed peers to the front if the peer prefers them.
+	candidateParents = preferSeedCandidateParents(peer, candidateParents)
 
-	if len(candidateParents) > candidateParentLimit {
-		candidateParents = candidateParents[:candidateParentLimit]
-	}
+	// Get the parents with candidateParentLimit.
+	candidateParents = s.limitCandidateParents(candidateParents)
 
 	var parentIDs []string
 	for _, candidateParent := range candidateParents {
@@ -466,6 +516,9 @@ func (s *scheduling) FindParentAndCandidateParents(ctx context.Context, peer *re
 
 // FindSuccessParent finds success parent for the peer.
 func (s *scheduling) FindSuccessParent(ctx context.Context, peer *resource.Peer, blocklist set.SafeSet[string]) (*resource.Peer, bool) {
+	s.evaluatorMu.RLock()
+	defer s.evaluatorMu.RUnlock()
+
 	// Only PeerStateRunning peers need to be rescheduled,
 	// and other states including the PeerStateBackToSource indicate that
 	// they have been scheduled.
@@ -477,7 +530,7 @@ func (s *scheduling) FindSuccessParent(ctx context.Context, peer *resource.Peer,
 	// Find the candidate parent that can be scheduled.
 	candidateParents := s.filterCandidateParents(peer, blocklist)
 	if len(candidateParents) == 0 {
-		peer.Log.Info("can not find candidate parents")
+		peer.Log.Infof("can not find candidate parents, rejections: %s", formatParentRejections(peer.ParentRejections()))
 		return nil, false
 	}
 
@@ -496,6 +549,334 @@ func (s *scheduling) FindSuccessParent(ctx context.Context, peer *resource.Peer,
 	return successParents[0], true
 }
 
+// RescheduleChildren pushes better candidate parents to the running children of the task,
+// the children are rescheduled when the seed peer succeeded and none of their parents succeeded,
+// or the best candidate parent's score exceeds the current parent's score by the margin,
+// or the child is slow and occupies the upload slot of the saturated parent.
+// The children with the worst parents are rescheduled first and at most limit children are rescheduled.
+// Used only in v2 version of the grpc.
+func (s *scheduling) RescheduleChildren(ctx context.Context, task *resource.Task, limit int) int {
+	s.evaluatorMu.RLock()
+	defer s.evaluatorMu.RUnlock()
+
+	// The evaluator plugin may not score a single parent.
+	scorer, ok := s.evaluator.(evaluator.Scorer)
+	if !ok || limit <= 0 {
+		return 0
+	}
+
+	type rescheduledChild struct {
+		peer             *resource.Peer
+		score            float64
+		candidateParents []*resource.Peer
+		reason           string
+		slowParentID     string
+	}
+
+	var (
+		children            []rescheduledChild
+		taskTotalPieceCount = task.TotalPieceCount.Load()
+		medianThroughputs   = make(map[string]float64)
+		peersVersion        = task.PeersVersion()
+	)
+	for _, peer := range task.LoadPeers() {
+		// Only the running normal peers announced by v2 version of the grpc can be rescheduled.
+		if !peer.FSM.Is(resource.PeerStateRunning) || peer.Host.Type != types.HostTypeNormal {
+			continue
+		}
+
+		if _, loaded := peer.LoadAnnouncePeerStream(); !loaded {
+			continue
+		}
+
+		// The peer pinned to the parent is not rescheduled until the pin expires.
+		if _, pinned := peer.PinnedParent(); pinned {
+			continue
+		}
+
+		parents := peer.Parents()
+		if len(parents) == 0 {
+			continue
+		}
+
+		// The slow parent is not selected as the candidate parent of the slow child.
+		blocklist := peer.LoadBlockParents()
+		var slowParentID string
+		if s.config.Reschedule.SlowChild.Enable {
+			if slowParent, ok := s.findSlowParent(peer, parents, medianThroughputs); ok {
+				slowParentID = slowParent.ID
+				blocklist.Add(slowParentID)
+			}
+		}
+
+		// The candidate parents are re-evaluated only after the peers of the task changed
+		// since the peer was evaluated last time, unless the peer is slow.
+		if slowParentID == "" && peer.RescheduledPeersVersion.Load() == peersVersion {
+			continue
+		}
+
+		// The score of the current parents is the score of the best one.
+		var (
+			score           = math.Inf(-1)
+			parentSucceeded bool
+		)
+		for _, parent := range parents {
+			score = math.Max(score, scorer.EvaluateParent(parent, peer, taskTotalPieceCount))
+			if parent.FSM.Is(resource.PeerStateSucceeded) {
+				parentSucceeded = true
+			}
+		}
+
+		candidateParents := s.filterCandidateParents(peer, blocklist)
+		if len(candidateParents) == 0 {
+			peer.RescheduledPeersVersion.Store(peersVersion)
+			continue
+		}
+
+		candidateParents = s.evaluator.EvaluateParents(candidateParents, peer, taskTotalPieceCount)
+		bestParent := candidateParents[0]
+
+		var reason string
+		switch {
+		case slowParentID != "":
+			reason = metrics.PeerRescheduleReasonSlowChild
+		case bestParent.Host.Type != types.HostTypeNormal && bestParent.FSM.Is(resource.PeerStateSucceeded) && !parentSucceeded:
+			reason = metrics.PeerRescheduleReasonSeedPeerSucceeded
+		case scorer.EvaluateParent(bestParent, peer, taskTotalPieceCount)-score > s.config.Reschedule.ScoreMargin:
+			reason = metrics.PeerRescheduleReasonScoreMargin
+		default:
+			peer.RescheduledPeersVersion.Store(peersVersion)
+			continue
+		}
+
+		children = append(children, rescheduledChild{
+			peer:             peer,
+			score:            score,
+			candidateParents: s.limitCandidateParents(candidateParents),
+			reason:           reason,
+			slowParentID:     slowParentID,
+		})
+	}
+
+	// Reschedule the children with the worst parents first, to avoid
+	// re-parenting all children of the task at the same time.
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].score < children[j].score
+	})
+
+	var n int
+	for _, child := range children {
+		if n >= limit {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return n
+		default:
+		}
+
+		candidateParents, err := s.pushCandidateParents(child.peer, child.candidateParents)
+		if err != nil {
+			child.peer.Log.Errorf("reschedule failed: %s", err.Error())
+			continue
+		}
+
+		// Block the slow parent temporarily, the child may select it again
+		// after it expires.
+		if child.slowParentID != "" {
+			child.peer.BlockParent(child.slowParentID)
+			child.peer.ResetSlowParentWindow(child.slowParentID)
+		}
+
+		child.peer.RescheduledPeersVersion.Store(peersVersion)
+		metrics.PeerRescheduleCount.WithLabelValues(child.reason).Inc()
+		appendPeerDecision(child.peer, resource.PeerDecisionScheduled, child.reason, 0, candidateParents)
+		child.peer.Log.Infof("reschedule success, because of %s", child.reason)
+		n++
+	}
+
+	return n
+}
+
+// ReloadEvaluator reloads the evaluator plugin from the plugin directory, the previous
+// evaluator is kept if the plugin fails to load or validate. The plugin must be rebuilt from
+// the changed source, because the go runtime does not load the same plugin twice.
+func (s *scheduling) ReloadEvaluator() error {
+	if s.config.Algorithm != evaluator.PluginAlgorithm {
+		return fmt.Errorf("algorithm %s does not support reloading evaluator", s.config.Algorithm)
+	}
+
+	e, err := evaluator.ReloadPlugin(s.pluginDir)
+	if err != nil {
+		return err
+	}
+
+	s.setEvaluator(e)
+	return nil
+}
+
+// setEvaluator swaps the evaluator after the evaluations in flight finish.
+func (s *scheduling) setEvaluator(e evaluator.Evaluator) {
+	s.evaluatorMu.Lock()
+	defer s.evaluatorMu.Unlock()
+
+	s.evaluator = e
+}
+
+// findSlowParent finds the saturated parent from which the peer downloads slowly, the peer is slow
+// when its throughput from the parent is below the ratio of the median throughput of the children
+// of the parent for the consecutive windows. The median throughputs are cached by the parent id.
+func (s *scheduling) findSlowParent(peer *resource.Peer, parents []*resource.Peer, medianThroughputs map[string]float64) (*resource.Peer, bool) {
+	window := s.config.Reschedule.Interval
+	for _, parent := range parents {
+		// Only the parent whose upload slots are exhausted starves the other children.
+		if parent.Host.FreeUploadCount() > 0 {
+			peer.ResetSlowParentWindow(parent.ID)
+			continue
+		}
+
+		medianThroughput, ok := medianThroughputs[parent.ID]
+		if !ok {
+			medianThroughput = childrenMedianThroughput(parent, window)
+			medianThroughputs[parent.ID] = medianThroughput
+		}
+
+		if peer.ParentThroughput(parent.ID, window) >= medianThroughput*s.config.Reschedule.SlowChild.ThroughputRatio {
+			peer.ResetSlowParentWindow(parent.ID)
+			continue
+		}
+
+		if peer.AddSlowParentWindow(parent.ID) >= s.config.Reschedule.SlowChild.Windows {
+			peer.Log.Infof("peer downloads slowly from parent %s", parent.ID)
+			return parent, true
+		}
+	}
+
+	return nil, false
+}
+
+// childrenMedianThroughput returns the median throughput of the children
+// downloading from the parent within the window.
+func childrenMedianThroughput(parent *resource.Peer, window time.Duration) float64 {
+	var throughputs []float64
+	for _, child := range parent.Children() {
+		throughputs = append(throughputs, child.ParentThroughput(parent.ID, window))
+	}
+
+	if len(throughputs) == 0 {
+		return 0
+	}
+
+	sort.Float64s(throughputs)
+	return throughputs[len(throughputs)/2]
+}
+
+// pushCandidateParents replaces the parents of the peer with the candidate parents and sends
+// NormalTaskResponse with them to the peer over the stored AnnouncePeerStream,
+// it returns the candidate parents which are sent.
+func (s *scheduling) pushCandidateParents(peer *resource.Peer, candidateParents []*resource.Peer) ([]*resource.Peer, error) {
+	if _, loaded := peer.LoadAnnouncePeerStream(); !loaded {
+		return nil, errors.New("load stream failed")
+	}
+
+	// Candidate parents may become descendants of the peer,
+	// after the other children of the task are rescheduled.
+	var parents []*resource.Peer
+	for _, candidateParent := range candidateParents {
+		if peer.Task.CanReplacePeerParent(candidateParent.ID, peer.ID) {
+			parents = append(parents, candidateParent)
+		}
+	}
+
+	if len(parents) == 0 {
+		return nil, errors.New("candidate parents not found")
+	}
+
+	previousParents := peer.Parents()
+	parents, err := peer.Task.ReplacePeerParents(peer, parents)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := peer.SendAnnouncePeerResponse(&schedulerv2.AnnouncePeerResponse{
+		Response: ConstructSuccessNormalTaskResponse(parents),
+	}); err != nil {
+		// The peer still downloads from the previous parents.
+		if _, err := peer.Task.ReplacePeerParents(peer, previousParents); err != nil {
+			peer.Log.Errorf("peer restores parents failed: %s", err.Error())
+		}
+
+		return nil, err
+	}
+	peer.StoreParent(parents[0].ID)
+
+	return parents, nil
+}
+
+// pinCandidateParents moves the parent the peer is pinned to to the front of the candidate parents,
+// the pin does not take effect if the parent is filtered out, e.g. the parent failed or left.
+func pinCandidateParents(peer *resource.Peer, candidateParents []*resource.Peer) []*resource.Peer {
+	parentID, pinned := peer.PinnedParent()
+	if !pinned {
+		return candidateParents
+	}
+
+	for i, candidateParent := range candidateParents {
+		if candidateParent.ID != parentID {
+			continue
+		}
+
+		if i > 0 {
+			peer.Log.Infof("peer is pinned to parent %s", parentID)
+			pinnedParents := append([]*resource.Peer{candidateParent}, candidateParents[:i]...)
+			candidateParents = append(pinnedParents, candidateParents[i+1:]...)
+		}
+
+		return candidateParents
+	}
+
+	return candidateParents
+}
+
+// preferSeedCandidateParents moves the seed peers in front of the normal peers if the peer
+// prefers the seed peers, the order of the evaluation is kept within the seed peers and
+// the normal peers, so the normal peers are still selected when no seed peer is available.
+func preferSeedCandidateParents(peer *resource.Peer, candidateParents []*resource.Peer) []*resource.Peer {
+	if !peer.PreferSeed {
+		return candidateParents
+	}
+
+	seedParents := make([]*resource.Peer, 0, len(candidateParents))
+	var normalParents []*resource.Peer
+	for _, candidateParent := range candidateParents {
+		if candidateParent.Host.Type != types.HostTypeNormal {
+			seedParents = append(seedParents, candidateParent)
+			continue
+		}
+
+		normalParents = append(normalParents, candidateParent)
+	}
+
+	return append(seedParents, normalParents...)
+}
+
+// limitCandidateParents returns the candidate parents within the candidateParentLimit.
+func (s *scheduling) limitCandidateParents(candidateParents []*resource.Peer) []*resource.Peer {
+	candidateParentLimit := config.DefaultSchedulerCandidateParentLimit
+	if config, err := s.dynconfig.GetSchedulerClusterConfig(); err == nil {
+		if config.CandidateParentLimit > 0 {
+			candidateParentLimit = int(config.CandidateParentLimit)
+		}
+	}
+
+	if len(candidateParents) > candidateParentLimit {
+		return candidateParents[:candidateParentLimit]
+	}
+
+	return candidateParents
+}
+
 // filterCandidateParents filters the candidate parents that can be scheduled.
 func (s *scheduling) filterCandidateParents(peer *resource.Peer, blocklist set.SafeSet[string]) []*resource.Peer {
 	filterParentLimit := config.DefaultSchedulerFilterParentLimit
@@ -508,11 +889,13 @@ func (s *scheduling) filterCandidateParents(peer *resource.Peer, blocklist set.S
 	var (
 		candidateParents   []*resource.Peer
 		candidateParentIDs []string
+		rejections         = make(map[string]int)
 	)
 	for _, candidateParent := range peer.Task.LoadRandomPeers(uint(filterParentLimit)) {
 		// Candidate parent is in blocklist.
 		if blocklist.Contains(candidateParent.ID) {
 			peer.Log.Debugf("parent %s host %s is not selected because it is in blocklist", candidateParent.ID, candidateParent.Host.ID)
+			rejections[metrics.ParentFilteredReasonBlocklist]++
 			continue
 		}
 
@@ -521,6 +904,7 @@ func (s *scheduling) filterCandidateParents(peer *resource.Peer, blocklist set.S
 		// where two tasks are downloading and downloading each other.
 		if peer.Host.ID == candidateParent.Host.ID {
 			peer.Log.Debugf("parent %s host %s is the same as peer host", candidateParent.ID, candidateParent.Host.ID)
+			rejections[metrics.ParentFilteredReasonSameHost]++
 			continue
 		}
 
@@ -528,6 +912,7 @@ func (s *scheduling) filterCandidateParents(peer *resource.Peer, blocklist set.S
 		inDegree, err := peer.Task.PeerInDegree(candidateParent.ID)
 		if err != nil {
 			peer.Log.Debugf("can not find parent %s host %s vertex in dag", candidateParent.ID, candidateParent.Host.ID)
+			rejections[metrics.ParentFilteredReasonNotInDAG]++
 			continue
 		}
 
@@ -536,16 +921,41 @@ func (s *scheduling) filterCandidateParents(peer *resource.Peer, blocklist set.S
 		// Condition 2: Parent has been back-to-source.
 		// Condition 3: Parent has been succeeded.
 		// Condition 4: Parent is seed peer.
+		// Condition 5: Parent is running with the finished pieces, such as the restored peer.
 		if candidateParent.Host.Type == types.HostTypeNormal && inDegree == 0 && !candidateParent.FSM.Is(resource.PeerStateBackToSource) &&
-			!candidateParent.FSM.Is(resource.PeerStateSucceeded) {
+			!candidateParent.FSM.Is(resource.PeerStateSucceeded) &&
+			!(candidateParent.FSM.Is(resource.PeerStateRunning) && candidateParent.FinishedPieces.Count() > 0) {
 			peer.Log.Debugf("parent %s host %s is not selected, because its download state is %d %d %s",
 				candidateParent.ID, candidateParent.Host.ID, inDegree, int(candidateParent.Host.Type), candidateParent.FSM.Current())
+			rejections[metrics.ParentFilteredReasonNotReady]++
+			continue
+		}
+
+		// Candidate parent is quarantined because it reports impossible piece results.
+		if candidateParent.Quarantined.Load() {
+			peer.Log.Debugf("parent %s host %s is not selected because it is quarantined", candidateParent.ID, candidateParent.Host.ID)
+			rejections[metrics.ParentFilteredReasonQuarantined]++
+			continue
+		}
+
+		// Candidate parent host is in maintenance, its peers can still download but not upload.
+		if candidateParent.Host.Maintenance.Load() {
+			peer.Log.Debugf("parent %s host %s is not selected because it is in maintenance", candidateParent.ID, candidateParent.Host.ID)
+			rejections[metrics.ParentFilteredReasonMaintenance]++
+			continue
+		}
+
+		// Candidate parent host does not support the codec of piece compression negotiated with the peer.
+		if codec := peer.PieceCodec.Load(); codec != "" && !slices.Contains(candidateParent.Host.LoadPieceCodecs(), codec) {
+			peer.Log.Debugf("parent %s host %s is not selected because it does not support piece codec %s", candidateParent.ID, candidateParent.Host.ID, codec)
+			rejections[metrics.ParentFilteredReasonPieceCodec]++
 			continue
 		}
 
 		// Candidate parent is bad node.
 		if s.evaluator.IsBadNode(candidateParent) {
 			peer.Log.Debugf("parent %s host %s is not selected because it is bad node", candidateParent.ID, candidateParent.Host.ID)
+			rejections[metrics.ParentFilteredReasonBadNode]++
 			continue
 		}
 
@@ -553,12 +963,14 @@ func (s *scheduling) filterCandidateParents(peer *resource.Peer, blocklist set.S
 		if candidateParent.Host.FreeUploadCount() <= 0 {
 			peer.Log.Debugf("parent %s host %s is not selected because its free upload is empty, upload limit is %d, upload count is %d",
 				candidateParent.ID, candidateParent.Host.ID, candidateParent.Host.ConcurrentUploadLimit.Load(), candidateParent.Host.ConcurrentUploadCount.Load())
+			rejections[metrics.ParentFilteredReasonNoFreeUpload]++
 			continue
 		}
 
-		// Candidate parent can add edge with peer.
-		if !peer.Task.CanAddPeerEdge(candidateParent.ID, peer.ID) {
+		// Candidate parent can replace the parent of peer.
+		if !peer.Task.CanReplacePeerParent(candidateParent.ID, peer.ID) {
 			peer.Log.Debugf("can not add edge with parent %s host %s", candidateParent.ID, candidateParent.Host.ID)
+			rejections[metrics.ParentFilteredReasonDescendant]++
 			continue
 		}
 
@@ -566,10 +978,58 @@ func (s *scheduling) filterCandidateParents(peer *resource.Peer, blocklist set.S
 		candidateParentIDs = append(candidateParentIDs, candidateParent.ID)
 	}
 
-	peer.Log.Infof("filter candidate parents is %#v", candidateParentIDs)
+	for reason, count := range rejections {
+		metrics.ParentFilteredCount.WithLabelValues(reason).Add(float64(count))
+	}
+
+	peer.StoreParentRejections(rejections)
+	peer.Log.Infof("filter candidate parents is %#v, rejections: %s", candidateParentIDs, formatParentRejections(rejections))
 	return candidateParents
 }
 
+// formatParentRejections formats the rejection reasons of candidate parents
+// as sorted reason=count pairs.
+func formatParentRejections(rejections map[string]int) string {
+	reasons := make([]string, 0, len(rejections))
+	for reason := range rejections {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	pairs := make([]string, 0, len(reasons))
+	for _, reason := range reasons {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", reason, rejections[reason]))
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+// collectPeerBackToSourceMetrics collects PeerBackToSourceCount metrics, the reason is
+// overridden by seed peer failed if the seed peer of the task failed recently.
+func collectPeerBackToSourceMetrics(peer *resource.Peer, reason string) {
+	if peer.Task.IsSeedPeerFailed() {
+		reason = metrics.PeerBackToSourceReasonSeedPeerFailed
+	}
+
+	metrics.PeerBackToSourceCount.WithLabelValues(peer.Task.Type.String(), reason).Inc()
+}
+
+// appendPeerDecision appends the scheduling decision to the recent decisions of peer.
+func appendPeerDecision(peer *resource.Peer, decisionType, reason string, n int, parents []*resource.Peer) {
+	var parentIDs []string
+	for _, parent := range parents {
+		parentIDs = append(parentIDs, parent.ID)
+	}
+
+	peer.AppendDecision(resource.PeerDecision{
+		Type:       decisionType,
+		ParentIDs:  parentIDs,
+		Reason:     reason,
+		Retries:    n,
+		Rejections: peer.ParentRejections(),
+	})
+}
+
 // ConstructSuccessNormalTaskResponse constructs scheduling successful response of the normal task.
 // Used only in v2 version of the grpc.
 func ConstructSuccessNormalTaskResponse(candidateParents []*resource.Peer) *schedulerv2.AnnouncePeerResponse_NormalTaskResponse {
@@ -25,7 +25,9 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc/codes"
@@ -48,6 +50,7 @@ import (
 	pkgtypes "d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
 	configmocks "d7y.io/dragonfly/v2/scheduler/config/mocks"
+	"d7y.io/dragonfly/v2/scheduler/metrics"
 	"d7y.io/dragonfly/v2/scheduler/resource"
 	"d7y.io/dragonfly/v2/scheduler/scheduling/evaluator"
 )
@@ -57,7 +60,7 @@ var (
 	mockSchedulerConfig = &config.SchedulerConfig{
 		RetryLimit:             2,
 		RetryBackToSourceLimit: 1,
-		RetryInterval:          10 * time.Millisecond,
+		RetryInterval:          pkgtypes.Duration{Duration: 10 * time.Millisecond},
 		BackToSourceCount:      int(mockTaskBackToSourceLimit),
 		Algorithm:              evaluator.DefaultAlgorithm,
 	}
@@ -732,6 +735,93 @@ func TestScheduling_ScheduleParentAndCandidateParents(t *testing.T) {
 	}
 }
 
+func TestScheduling_collectPeerBackToSourceMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason string
+		mock   func(peer *resource.Peer, seedPeer *resource.Peer)
+		expect func(t *testing.T, reason string)
+	}{
+		{
+			name:   "peer needs back-to-source",
+			reason: metrics.PeerBackToSourceReasonNeeded,
+			mock:   func(peer *resource.Peer, seedPeer *resource.Peer) {},
+			expect: func(t *testing.T, reason string) {
+				assert := assert.New(t)
+				assert.Equal(metrics.PeerBackToSourceReasonNeeded, reason)
+			},
+		},
+		{
+			name:   "scheduling exceeded RetryBackToSourceLimit",
+			reason: metrics.PeerBackToSourceReasonRetryLimitExceeded,
+			mock:   func(peer *resource.Peer, seedPeer *resource.Peer) {},
+			expect: func(t *testing.T, reason string) {
+				assert := assert.New(t)
+				assert.Equal(metrics.PeerBackToSourceReasonRetryLimitExceeded, reason)
+			},
+		},
+		{
+			name:   "seed peer of the task failed",
+			reason: metrics.PeerBackToSourceReasonNeeded,
+			mock: func(peer *resource.Peer, seedPeer *resource.Peer) {
+				peer.Task.StorePeer(seedPeer)
+				seedPeer.FSM.SetState(resource.PeerStateFailed)
+			},
+			expect: func(t *testing.T, reason string) {
+				assert := assert.New(t)
+				assert.Equal(metrics.PeerBackToSourceReasonSeedPeerFailed, reason)
+			},
+		},
+		{
+			name:   "seed peer of the task succeeded",
+			reason: metrics.PeerBackToSourceReasonNeeded,
+			mock: func(peer *resource.Peer, seedPeer *resource.Peer) {
+				peer.Task.StorePeer(seedPeer)
+				seedPeer.FSM.SetState(resource.PeerStateSucceeded)
+			},
+			expect: func(t *testing.T, reason string) {
+				assert := assert.New(t)
+				assert.Equal(metrics.PeerBackToSourceReasonNeeded, reason)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+			mockSeedHost := resource.NewHost(
+				mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+				mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+			seedPeer := resource.NewPeer(mockSeedPeerID, mockResourceConfig, mockTask, mockSeedHost)
+			tc.mock(peer, seedPeer)
+
+			counts := map[string]float64{}
+			reasons := []string{
+				metrics.PeerBackToSourceReasonNeeded,
+				metrics.PeerBackToSourceReasonSeedPeerFailed,
+				metrics.PeerBackToSourceReasonRetryLimitExceeded,
+			}
+			for _, reason := range reasons {
+				counts[reason] = testutil.ToFloat64(metrics.PeerBackToSourceCount.WithLabelValues(mockTask.Type.String(), reason))
+			}
+
+			collectPeerBackToSourceMetrics(peer, tc.reason)
+			for _, reason := range reasons {
+				if testutil.ToFloat64(metrics.PeerBackToSourceCount.WithLabelValues(mockTask.Type.String(), reason)) == counts[reason]+1 {
+					tc.expect(t, reason)
+					return
+				}
+			}
+
+			t.Fatal("PeerBackToSourceCount is not increased")
+		})
+	}
+}
+
 func TestScheduling_FindCandidateParents(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -889,6 +979,56 @@ func TestScheduling_FindCandidateParents(t *testing.T) {
 				assert.Equal(mockPeers[1].ID, parents[0].ID)
 			},
 		},
+		{
+			name: "parent is quarantined",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string], md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateRunning)
+				mockPeers[0].FSM.SetState(resource.PeerStateRunning)
+				mockPeers[1].FSM.SetState(resource.PeerStateRunning)
+				peer.Task.StorePeer(peer)
+				peer.Task.StorePeer(mockPeers[0])
+				peer.Task.StorePeer(mockPeers[1])
+				mockPeers[0].Host.Type = pkgtypes.HostTypeSuperSeed
+				mockPeers[1].Host.Type = pkgtypes.HostTypeSuperSeed
+				mockPeers[0].FinishedPieces.Set(0)
+				mockPeers[1].FinishedPieces.Set(0)
+				mockPeers[1].FinishedPieces.Set(1)
+				mockPeers[1].Quarantined.Store(true)
+
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).Times(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(1, len(parents))
+				assert.Equal(mockPeers[0].ID, parents[0].ID)
+			},
+		},
+		{
+			name: "parent host is in maintenance",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string], md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateRunning)
+				mockPeers[0].FSM.SetState(resource.PeerStateRunning)
+				mockPeers[1].FSM.SetState(resource.PeerStateRunning)
+				peer.Task.StorePeer(peer)
+				peer.Task.StorePeer(mockPeers[0])
+				peer.Task.StorePeer(mockPeers[1])
+				mockPeers[0].Host.Type = pkgtypes.HostTypeSuperSeed
+				mockPeers[1].Host.Type = pkgtypes.HostTypeSuperSeed
+				mockPeers[0].FinishedPieces.Set(0)
+				mockPeers[1].FinishedPieces.Set(0)
+				mockPeers[1].FinishedPieces.Set(1)
+				mockPeers[1].Host.Maintenance.Store(true)
+
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).Times(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(1, len(parents))
+				assert.Equal(mockPeers[0].ID, parents[0].ID)
+			},
+		},
 		{
 			name: "parent state is PeerStateSucceeded",
 			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string], md *configmocks.MockDynconfigInterfaceMockRecorder) {
@@ -911,6 +1051,27 @@ func TestScheduling_FindCandidateParents(t *testing.T) {
 				assert.Equal(mockPeers[1].ID, parents[0].ID)
 			},
 		},
+		{
+			name: "find restored parent without piece reports",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string], md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateRunning)
+				mockPeers[0].FSM.SetState(resource.PeerStateRunning)
+				mockPeers[1].FSM.SetState(resource.PeerStateRunning)
+				peer.Task.StorePeer(peer)
+				peer.Task.StorePeer(mockPeers[0])
+				peer.Task.StorePeer(mockPeers[1])
+				mockPeers[0].FinishedPieces.Set(0)
+				mockPeers[0].FinishedPieces.Set(1)
+
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).Times(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer, ok bool) {
+				assert := assert.New(t)
+				assert.True(ok)
+				assert.Equal(1, len(parents))
+				assert.Equal(mockPeers[0].ID, parents[0].ID)
+			},
+		},
 		{
 			name: "find parent with ancestor",
 			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string], md *configmocks.MockDynconfigInterfaceMockRecorder) {
@@ -1049,6 +1210,216 @@ func TestScheduling_FindCandidateParents(t *testing.T) {
 	}
 }
 
+func TestScheduling_filterCandidateParents(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string])
+		expect func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer)
+	}{
+		{
+			name: "task peers is empty",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Nil(peer.ParentRejections())
+			},
+		},
+		{
+			name: "candidate parent is in blocklist",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				peer.Task.StorePeer(mockPeers[0])
+				blocklist.Add(mockPeers[0].ID)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{metrics.ParentFilteredReasonBlocklist: 1})
+			},
+		},
+		{
+			name: "candidate parent host is the same as peer host",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				peer.Task.StorePeer(peer)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{metrics.ParentFilteredReasonSameHost: 1})
+			},
+		},
+		{
+			name: "candidate parent is not ready",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				mockPeers[0].FSM.SetState(resource.PeerStateRunning)
+				peer.Task.StorePeer(mockPeers[0])
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{metrics.ParentFilteredReasonNotReady: 1})
+			},
+		},
+		{
+			name: "candidate parent is quarantined",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				mockPeers[0].FSM.SetState(resource.PeerStateBackToSource)
+				mockPeers[0].Quarantined.Store(true)
+				peer.Task.StorePeer(mockPeers[0])
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{metrics.ParentFilteredReasonQuarantined: 1})
+			},
+		},
+		{
+			name: "candidate parent host is in maintenance",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				mockPeers[0].FSM.SetState(resource.PeerStateBackToSource)
+				mockPeers[0].Host.Maintenance.Store(true)
+				peer.Task.StorePeer(mockPeers[0])
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{metrics.ParentFilteredReasonMaintenance: 1})
+			},
+		},
+		{
+			name: "candidate parent host does not support piece codec of peer",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				peer.PieceCodec.Store("zstd")
+				mockPeers[0].FSM.SetState(resource.PeerStateBackToSource)
+				peer.Task.StorePeer(mockPeers[0])
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{metrics.ParentFilteredReasonPieceCodec: 1})
+			},
+		},
+		{
+			name: "candidate parent host supports piece codec of peer",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				peer.PieceCodec.Store("zstd")
+				mockPeers[0].FSM.SetState(resource.PeerStateBackToSource)
+				mockPeers[0].Host.PieceCodecs.Store(&[]string{"zstd"})
+				peer.Task.StorePeer(peer)
+				peer.Task.StorePeer(mockPeers[0])
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				require := require.New(t)
+				require.Len(parents, 1)
+				assert.Equal(parents[0].ID, mockPeers[0].ID)
+			},
+		},
+		{
+			name: "candidate parent is bad node",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				mockSeedHost := resource.NewHost(
+					mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+					mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+				seedPeer := resource.NewPeer(mockSeedPeerID, mockResourceConfig, peer.Task, mockSeedHost)
+				seedPeer.FSM.SetState(resource.PeerStateFailed)
+				peer.Task.StorePeer(seedPeer)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{metrics.ParentFilteredReasonBadNode: 1})
+			},
+		},
+		{
+			name: "candidate parent's free upload is empty",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				mockPeers[0].FSM.SetState(resource.PeerStateBackToSource)
+				mockPeers[0].Host.ConcurrentUploadLimit.Store(0)
+				peer.Task.StorePeer(mockPeers[0])
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{metrics.ParentFilteredReasonNoFreeUpload: 1})
+			},
+		},
+		{
+			name: "candidate parent is peer's descendant",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				mockPeers[0].FSM.SetState(resource.PeerStateBackToSource)
+				peer.Task.StorePeer(peer)
+				peer.Task.StorePeer(mockPeers[0])
+				if err := peer.Task.AddPeerEdge(peer, mockPeers[0]); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Empty(parents)
+				assert.Equal(peer.ParentRejections(), map[string]int{
+					metrics.ParentFilteredReasonSameHost:   1,
+					metrics.ParentFilteredReasonDescendant: 1,
+				})
+			},
+		},
+		{
+			name: "candidate parents are filtered out by different reasons",
+			mock: func(peer *resource.Peer, mockPeers []*resource.Peer, blocklist set.SafeSet[string]) {
+				peer.Task.StorePeer(peer)
+				for _, mockPeer := range mockPeers[:3] {
+					mockPeer.FSM.SetState(resource.PeerStateBackToSource)
+					peer.Task.StorePeer(mockPeer)
+				}
+
+				blocklist.Add(mockPeers[0].ID)
+				blocklist.Add(mockPeers[1].ID)
+				mockPeers[3].FSM.SetState(resource.PeerStateRunning)
+				peer.Task.StorePeer(mockPeers[3])
+			},
+			expect: func(t *testing.T, peer *resource.Peer, mockPeers []*resource.Peer, parents []*resource.Peer) {
+				assert := assert.New(t)
+				require := require.New(t)
+				require.Len(parents, 1)
+				assert.Equal(parents[0].ID, mockPeers[2].ID)
+				assert.Equal(peer.ParentRejections(), map[string]int{
+					metrics.ParentFilteredReasonSameHost:  1,
+					metrics.ParentFilteredReasonBlocklist: 2,
+					metrics.ParentFilteredReasonNotReady:  1,
+				})
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+
+			var mockPeers []*resource.Peer
+			for i := 0; i < 4; i++ {
+				mockHost := resource.NewHost(
+					idgen.HostIDV2("127.0.0.1", uuid.New().String()), mockRawHost.IP, mockRawHost.Hostname,
+					mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+				peer := resource.NewPeer(idgen.PeerIDV1(fmt.Sprintf("127.0.0.%d", i)), mockResourceConfig, mockTask, mockHost)
+				mockPeers = append(mockPeers, peer)
+			}
+
+			blocklist := set.NewSafeSet[string]()
+			tc.mock(peer, mockPeers, blocklist)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).Times(1)
+			scheduling := New(mockSchedulerConfig, dynconfig, mockPluginDir).(*scheduling)
+			tc.expect(t, peer, mockPeers, scheduling.filterCandidateParents(peer, blocklist))
+		})
+	}
+}
+
 func TestScheduling_FindParentAndCandidateParents(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -1627,6 +1998,747 @@ func TestScheduling_FindSuccessParent(t *testing.T) {
 	}
 }
 
+func TestScheduling_RescheduleChildren(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		mock   func(parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder)
+		expect func(t *testing.T, parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, n int)
+	}{
+		{
+			name:  "limit is zero",
+			limit: 0,
+			mock: func(parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				parents[2].FSM.SetState(resource.PeerStateSucceeded)
+				setFinishedPieces(parents[2], 10)
+				storeRescheduledChild(parents[0], children[0])
+			},
+			expect: func(t *testing.T, parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, n int) {
+				assert := assert.New(t)
+				assert.Equal(0, n)
+				assert.Equal([]*resource.Peer{parents[0]}, children[0].Parents())
+			},
+		},
+		{
+			name:  "only the worst parented child is rescheduled within limit",
+			limit: 1,
+			mock: func(parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				setFinishedPieces(parents[1], 2)
+				parents[2].FSM.SetState(resource.PeerStateSucceeded)
+				setFinishedPieces(parents[2], 10)
+				storeRescheduledChild(parents[0], children[0])
+				storeRescheduledChild(parents[1], children[1])
+
+				ma[0].Send(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, n int) {
+				assert := assert.New(t)
+				assert.Equal(1, n)
+				assert.Contains(children[0].Parents(), parents[2])
+				assert.Equal(parents[2].ID, children[0].Decisions()[0].ParentIDs[0])
+				assert.Equal([]*resource.Peer{parents[1]}, children[1].Parents())
+				assert.Equal(metrics.PeerRescheduleReasonScoreMargin, children[0].Decisions()[0].Reason)
+				assert.Empty(children[1].Decisions())
+			},
+		},
+		{
+			name:  "all children are rescheduled within limit",
+			limit: 2,
+			mock: func(parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				setFinishedPieces(parents[1], 2)
+				parents[2].FSM.SetState(resource.PeerStateSucceeded)
+				setFinishedPieces(parents[2], 10)
+				storeRescheduledChild(parents[0], children[0])
+				storeRescheduledChild(parents[1], children[1])
+
+				gomock.InOrder(
+					ma[0].Send(gomock.Any()).Return(nil).Times(1),
+					ma[1].Send(gomock.Any()).Return(nil).Times(1),
+				)
+			},
+			expect: func(t *testing.T, parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, n int) {
+				assert := assert.New(t)
+				assert.Equal(2, n)
+				assert.Contains(children[0].Parents(), parents[2])
+				assert.Contains(children[1].Parents(), parents[2])
+			},
+		},
+		{
+			name:  "better parent does not exceed the margin",
+			limit: 2,
+			mock: func(parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				parents[2].FSM.SetState(resource.PeerStateSucceeded)
+				parents[2].FinishedPieces.Set(0)
+				storeRescheduledChild(parents[0], children[0])
+			},
+			expect: func(t *testing.T, parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, n int) {
+				assert := assert.New(t)
+				assert.Equal(0, n)
+				assert.Equal([]*resource.Peer{parents[0]}, children[0].Parents())
+			},
+		},
+		{
+			name:  "better parent is in blocklist of child",
+			limit: 2,
+			mock: func(parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				parents[2].FSM.SetState(resource.PeerStateSucceeded)
+				setFinishedPieces(parents[2], 10)
+				storeRescheduledChild(parents[0], children[0])
+				children[0].BlockParent(parents[2].ID)
+			},
+			expect: func(t *testing.T, parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, n int) {
+				assert := assert.New(t)
+				assert.Equal(0, n)
+				assert.Equal([]*resource.Peer{parents[0]}, children[0].Parents())
+			},
+		},
+		{
+			name:  "child is rescheduled when seed peer succeeded",
+			limit: 2,
+			mock: func(parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				setFinishedPieces(parents[0], 6)
+				seedPeer.FSM.SetState(resource.PeerStateSucceeded)
+				setFinishedPieces(seedPeer, 10)
+				seedPeer.Task.StorePeer(seedPeer)
+				storeRescheduledChild(parents[0], children[0])
+
+				ma[0].Send(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, n int) {
+				assert := assert.New(t)
+				assert.Equal(1, n)
+				assert.Contains(children[0].Parents(), seedPeer)
+				assert.Equal(metrics.PeerRescheduleReasonSeedPeerSucceeded, children[0].Decisions()[0].Reason)
+			},
+		},
+		{
+			name:  "send NormalTaskResponse failed",
+			limit: 2,
+			mock: func(parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				parents[2].FSM.SetState(resource.PeerStateSucceeded)
+				setFinishedPieces(parents[2], 10)
+				storeRescheduledChild(parents[0], children[0])
+
+				ma[0].Send(gomock.Any()).Return(errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, parents []*resource.Peer, seedPeer *resource.Peer, children []*resource.Peer, n int) {
+				assert := assert.New(t)
+				assert.Equal(0, n)
+				assert.Equal([]*resource.Peer{parents[0]}, children[0].Parents())
+				assert.Empty(children[0].Decisions())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
+
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			mockTask.TotalPieceCount.Store(10)
+
+			newMockPeer := func(hostType pkgtypes.HostType) *resource.Peer {
+				mockHost := resource.NewHost(
+					idgen.HostIDV2("127.0.0.1", uuid.New().String()), mockRawHost.IP, mockRawHost.Hostname,
+					mockRawHost.Port, mockRawHost.DownloadPort, hostType)
+				return resource.NewPeer(idgen.PeerIDV2(), mockResourceConfig, mockTask, mockHost)
+			}
+
+			var parents []*resource.Peer
+			for i := 0; i < 3; i++ {
+				parent := newMockPeer(pkgtypes.HostTypeNormal)
+				parent.FSM.SetState(resource.PeerStateBackToSource)
+				parents = append(parents, parent)
+			}
+			seedPeer := newMockPeer(pkgtypes.HostTypeSuperSeed)
+
+			var (
+				children []*resource.Peer
+				streams  []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder
+			)
+			for i := 0; i < 2; i++ {
+				stream := schedulerv2mocks.NewMockScheduler_AnnouncePeerServer(ctl)
+				child := newMockPeer(pkgtypes.HostTypeNormal)
+				child.FSM.SetState(resource.PeerStateRunning)
+				child.StoreAnnouncePeerStream(stream)
+				children = append(children, child)
+				streams = append(streams, stream.EXPECT())
+			}
+
+			tc.mock(parents, seedPeer, children, streams)
+			for _, parent := range parents {
+				mockTask.StorePeer(parent)
+			}
+
+			cfg := *mockSchedulerConfig
+			cfg.Reschedule = config.RescheduleConfig{ScoreMargin: 0.1}
+			scheduling := New(&cfg, dynconfig, mockPluginDir)
+			tc.expect(t, parents, seedPeer, children, scheduling.RescheduleChildren(context.Background(), mockTask, tc.limit))
+		})
+	}
+}
+
+func TestScheduling_RescheduleChildrenWithPeersVersion(t *testing.T) {
+	ctl := gomock.NewController(t)
+	defer ctl.Finish()
+	dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+	dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
+	stream := schedulerv2mocks.NewMockScheduler_AnnouncePeerServer(ctl)
+
+	mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+	mockTask.TotalPieceCount.Store(10)
+
+	newMockPeer := func() *resource.Peer {
+		mockHost := resource.NewHost(
+			idgen.HostIDV2("127.0.0.1", uuid.New().String()), mockRawHost.IP, mockRawHost.Hostname,
+			mockRawHost.Port, mockRawHost.DownloadPort, pkgtypes.HostTypeNormal)
+		return resource.NewPeer(idgen.PeerIDV2(), mockResourceConfig, mockTask, mockHost)
+	}
+
+	parent := newMockPeer()
+	parent.FSM.SetState(resource.PeerStateBackToSource)
+	candidateParent := newMockPeer()
+	candidateParent.FSM.SetState(resource.PeerStateBackToSource)
+	mockTask.StorePeer(candidateParent)
+
+	child := newMockPeer()
+	child.FSM.SetState(resource.PeerStateRunning)
+	child.StoreAnnouncePeerStream(stream)
+	storeRescheduledChild(parent, child)
+
+	cfg := *mockSchedulerConfig
+	cfg.Reschedule = config.RescheduleConfig{ScoreMargin: 0.1}
+	scheduling := New(&cfg, dynconfig, mockPluginDir)
+
+	assert := assert.New(t)
+	assert.Equal(0, scheduling.RescheduleChildren(context.Background(), mockTask, 10))
+	assert.Equal(mockTask.PeersVersion(), child.RescheduledPeersVersion.Load())
+
+	// The pieces of the candidate parent do not change the peers version,
+	// the child is not re-evaluated.
+	candidateParent.FSM.SetState(resource.PeerStateSucceeded)
+	setFinishedPieces(candidateParent, 10)
+	assert.Equal(0, scheduling.RescheduleChildren(context.Background(), mockTask, 10))
+	assert.Equal([]*resource.Peer{parent}, child.Parents())
+
+	// The new peer changes the peers version, the child is re-evaluated.
+	mockTask.StorePeer(newMockPeer())
+	stream.EXPECT().Send(gomock.Any()).Return(nil).Times(1)
+	assert.Equal(1, scheduling.RescheduleChildren(context.Background(), mockTask, 10))
+	assert.Contains(child.Parents(), candidateParent)
+	assert.Equal(candidateParent.ID, child.Decisions()[0].ParentIDs[0])
+}
+
+// setFinishedPieces sets the first n pieces of the peer finished.
+func setFinishedPieces(peer *resource.Peer, n uint) {
+	for i := uint(0); i < n; i++ {
+		peer.FinishedPieces.Set(i)
+	}
+}
+
+// storeRescheduledChild stores the child with the parent in the task.
+func storeRescheduledChild(parent *resource.Peer, child *resource.Peer) {
+	child.Task.StorePeer(parent)
+	child.Task.StorePeer(child)
+	if err := child.Task.AddPeerEdge(parent, child); err != nil {
+		panic(err)
+	}
+}
+
+func TestScheduling_RescheduleSlowChildren(t *testing.T) {
+	tests := []struct {
+		name                  string
+		concurrentUploadLimit int32
+		pieceCounts           []int
+		rounds                int
+		mock                  func(ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder)
+		expect                func(t *testing.T, parent *resource.Peer, candidateParent *resource.Peer, children []*resource.Peer, n []int)
+	}{
+		{
+			name:                  "slow child of saturated parent is rescheduled after consecutive windows",
+			concurrentUploadLimit: 3,
+			pieceCounts:           []int{10, 10, 1},
+			rounds:                2,
+			mock: func(ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {
+				ma[2].Send(gomock.Any()).Return(nil).Times(1)
+			},
+			expect: func(t *testing.T, parent *resource.Peer, candidateParent *resource.Peer, children []*resource.Peer, n []int) {
+				assert := assert.New(t)
+				assert.Equal([]int{0, 1}, n)
+				assert.Equal([]*resource.Peer{parent}, children[0].Parents())
+				assert.Equal([]*resource.Peer{parent}, children[1].Parents())
+				assert.Contains(children[2].Parents(), candidateParent)
+				assert.NotContains(children[2].Parents(), parent)
+				assert.True(children[2].LoadBlockParents().Contains(parent.ID))
+				assert.Equal(metrics.PeerRescheduleReasonSlowChild, children[2].Decisions()[0].Reason)
+				assert.Empty(children[0].Decisions())
+				assert.Empty(children[1].Decisions())
+			},
+		},
+		{
+			name:                  "slow child of parent with free upload slots is not rescheduled",
+			concurrentUploadLimit: 10,
+			pieceCounts:           []int{10, 10, 1},
+			rounds:                2,
+			mock:                  func(ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {},
+			expect: func(t *testing.T, parent *resource.Peer, candidateParent *resource.Peer, children []*resource.Peer, n []int) {
+				assert := assert.New(t)
+				assert.Equal([]int{0, 0}, n)
+				assert.Equal([]*resource.Peer{parent}, children[2].Parents())
+				assert.Empty(children[2].Decisions())
+			},
+		},
+		{
+			name:                  "children with similar throughputs are not rescheduled",
+			concurrentUploadLimit: 3,
+			pieceCounts:           []int{10, 10, 8},
+			rounds:                2,
+			mock:                  func(ma []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder) {},
+			expect: func(t *testing.T, parent *resource.Peer, candidateParent *resource.Peer, children []*resource.Peer, n []int) {
+				assert := assert.New(t)
+				assert.Equal([]int{0, 0}, n)
+				for _, child := range children {
+					assert.Equal([]*resource.Peer{parent}, child.Parents())
+				}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
+
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			mockTask.TotalPieceCount.Store(10)
+
+			newMockPeer := func(options ...resource.HostOption) *resource.Peer {
+				mockHost := resource.NewHost(
+					idgen.HostIDV2("127.0.0.1", uuid.New().String()), mockRawHost.IP, mockRawHost.Hostname,
+					mockRawHost.Port, mockRawHost.DownloadPort, pkgtypes.HostTypeNormal, options...)
+				return resource.NewPeer(idgen.PeerIDV2(), mockResourceConfig, mockTask, mockHost)
+			}
+
+			parent := newMockPeer(resource.WithConcurrentUploadLimit(tc.concurrentUploadLimit))
+			parent.FSM.SetState(resource.PeerStateSucceeded)
+			setFinishedPieces(parent, 10)
+
+			candidateParent := newMockPeer()
+			candidateParent.FSM.SetState(resource.PeerStateSucceeded)
+			setFinishedPieces(candidateParent, 10)
+			mockTask.StorePeer(candidateParent)
+
+			var (
+				children []*resource.Peer
+				streams  []*schedulerv2mocks.MockScheduler_AnnouncePeerServerMockRecorder
+			)
+			for _, pieceCount := range tc.pieceCounts {
+				stream := schedulerv2mocks.NewMockScheduler_AnnouncePeerServer(ctl)
+				child := newMockPeer()
+				child.FSM.SetState(resource.PeerStateRunning)
+				child.StoreAnnouncePeerStream(stream)
+				storeRescheduledChild(parent, child)
+
+				// Simulate the pieces downloaded from the parent within the window.
+				for i := 0; i < pieceCount; i++ {
+					child.StorePiece(&resource.Piece{
+						Number:    int32(i),
+						ParentID:  parent.ID,
+						Length:    uint64(mockTaskPieceLength),
+						CreatedAt: time.Now(),
+					})
+				}
+
+				children = append(children, child)
+				streams = append(streams, stream.EXPECT())
+			}
+
+			tc.mock(streams)
+			cfg := *mockSchedulerConfig
+			cfg.Reschedule = config.RescheduleConfig{
+				Interval:    time.Minute,
+				ScoreMargin: 1,
+				SlowChild: config.SlowChildConfig{
+					Enable:          true,
+					ThroughputRatio: 0.5,
+					Windows:         tc.rounds,
+				},
+			}
+			scheduling := New(&cfg, dynconfig, mockPluginDir)
+
+			var n []int
+			for i := 0; i < tc.rounds; i++ {
+				n = append(n, scheduling.RescheduleChildren(context.Background(), mockTask, 10))
+			}
+
+			tc.expect(t, parent, candidateParent, children, n)
+		})
+	}
+}
+
+// reverseEvaluator sorts the parents in the reverse order of the evaluator.
+type reverseEvaluator struct {
+	evaluator.Evaluator
+}
+
+// EvaluateParents sorts the parents in the reverse order of the evaluator.
+func (e *reverseEvaluator) EvaluateParents(parents []*resource.Peer, child *resource.Peer, taskPieceCount int32) []*resource.Peer {
+	parents = e.Evaluator.EvaluateParents(parents, child, taskPieceCount)
+	for i, j := 0, len(parents)-1; i < j; i, j = i+1, j-1 {
+		parents[i], parents[j] = parents[j], parents[i]
+	}
+
+	return parents
+}
+
+// blockingEvaluator blocks the evaluation until it is released.
+type blockingEvaluator struct {
+	evaluator.Evaluator
+	started chan struct{}
+	release chan struct{}
+}
+
+// EvaluateParents blocks until the evaluation is released.
+func (e *blockingEvaluator) EvaluateParents(parents []*resource.Peer, child *resource.Peer, taskPieceCount int32) []*resource.Peer {
+	close(e.started)
+	<-e.release
+	return e.Evaluator.EvaluateParents(parents, child, taskPieceCount)
+}
+
+func TestScheduling_ReloadEvaluator(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		run       func(t *testing.T, s *scheduling, peer *resource.Peer, mockPeers []*resource.Peer)
+	}{
+		{
+			name:      "algorithm does not support reloading evaluator",
+			algorithm: evaluator.DefaultAlgorithm,
+			run: func(t *testing.T, s *scheduling, peer *resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				assert.EqualError(s.ReloadEvaluator(), "algorithm default does not support reloading evaluator")
+			},
+		},
+		{
+			name:      "load evaluator plugin failed",
+			algorithm: evaluator.PluginAlgorithm,
+			run: func(t *testing.T, s *scheduling, peer *resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				e := s.evaluator
+				assert.Error(s.ReloadEvaluator())
+				assert.Equal(e, s.evaluator)
+			},
+		},
+		{
+			name:      "new scoring takes effect after swapping evaluator",
+			algorithm: evaluator.DefaultAlgorithm,
+			run: func(t *testing.T, s *scheduling, peer *resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				parents, ok := s.FindCandidateParents(context.Background(), peer, set.NewSafeSet[string]())
+				assert.True(ok)
+				assert.Equal([]*resource.Peer{mockPeers[1], mockPeers[0]}, parents)
+
+				s.setEvaluator(&reverseEvaluator{s.evaluator})
+				parents, ok = s.FindCandidateParents(context.Background(), peer, set.NewSafeSet[string]())
+				assert.True(ok)
+				assert.Equal([]*resource.Peer{mockPeers[0], mockPeers[1]}, parents)
+			},
+		},
+		{
+			name:      "evaluation in flight finishes with previous evaluator",
+			algorithm: evaluator.DefaultAlgorithm,
+			run: func(t *testing.T, s *scheduling, peer *resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				e := &blockingEvaluator{
+					Evaluator: s.evaluator,
+					started:   make(chan struct{}),
+					release:   make(chan struct{}),
+				}
+				s.setEvaluator(e)
+
+				found := make(chan []*resource.Peer)
+				go func() {
+					parents, _ := s.FindCandidateParents(context.Background(), peer, set.NewSafeSet[string]())
+					found <- parents
+				}()
+				<-e.started
+
+				swapped := make(chan struct{})
+				go func() {
+					s.setEvaluator(&reverseEvaluator{e.Evaluator})
+					close(swapped)
+				}()
+
+				select {
+				case <-swapped:
+					t.Fatal("evaluator is swapped while evaluation is in flight")
+				case <-time.After(100 * time.Millisecond):
+				}
+
+				close(e.release)
+				assert.Equal([]*resource.Peer{mockPeers[1], mockPeers[0]}, <-found)
+				<-swapped
+
+				parents, ok := s.FindCandidateParents(context.Background(), peer, set.NewSafeSet[string]())
+				assert.True(ok)
+				assert.Equal([]*resource.Peer{mockPeers[0], mockPeers[1]}, parents)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
+
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+			peer.FSM.SetState(resource.PeerStateRunning)
+			mockTask.StorePeer(peer)
+
+			var mockPeers []*resource.Peer
+			for i := 0; i < 2; i++ {
+				mockHost := resource.NewHost(
+					idgen.HostIDV2("127.0.0.1", uuid.New().String()), mockRawHost.IP, mockRawHost.Hostname,
+					mockRawHost.Port, mockRawHost.DownloadPort, pkgtypes.HostTypeSuperSeed)
+				mockPeer := resource.NewPeer(idgen.PeerIDV1(fmt.Sprintf("127.0.0.%d", i)), mockResourceConfig, mockTask, mockHost)
+				mockPeer.FSM.SetState(resource.PeerStateRunning)
+				mockTask.StorePeer(mockPeer)
+				mockPeers = append(mockPeers, mockPeer)
+			}
+			mockPeers[0].FinishedPieces.Set(0)
+			mockPeers[1].FinishedPieces.Set(0)
+			mockPeers[1].FinishedPieces.Set(1)
+
+			cfg := *mockSchedulerConfig
+			cfg.Algorithm = tc.algorithm
+			tc.run(t, New(&cfg, dynconfig, mockPluginDir).(*scheduling), peer, mockPeers)
+		})
+	}
+}
+
+// oscillatingEvaluator reverses the order of the evaluator in every other evaluation,
+// so the best parent oscillates between the evaluations.
+type oscillatingEvaluator struct {
+	evaluator.Evaluator
+	n int
+}
+
+// EvaluateParents reverses the order of the evaluator in every other evaluation.
+func (e *oscillatingEvaluator) EvaluateParents(parents []*resource.Peer, child *resource.Peer, taskPieceCount int32) []*resource.Peer {
+	parents = e.Evaluator.EvaluateParents(parents, child, taskPieceCount)
+	e.n++
+	if e.n%2 == 0 {
+		for i, j := 0, len(parents)-1; i < j; i, j = i+1, j-1 {
+			parents[i], parents[j] = parents[j], parents[i]
+		}
+	}
+
+	return parents
+}
+
+func TestScheduling_ParentPin(t *testing.T) {
+	tests := []struct {
+		name              string
+		parentSwitchLimit int
+		run               func(t *testing.T, schedule func() *schedulerv1.PeerPacket, peer *resource.Peer, mockPeers []*resource.Peer)
+	}{
+		{
+			name:              "peer is pinned after switching parents exceeds the limit",
+			parentSwitchLimit: 2,
+			run: func(t *testing.T, schedule func() *schedulerv1.PeerPacket, peer *resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				for _, mockPeer := range []*resource.Peer{mockPeers[1], mockPeers[0], mockPeers[1], mockPeers[0]} {
+					assert.Equal(mockPeer.ID, schedule().MainPeer.PeerId)
+				}
+				assert.Equal(int32(3), peer.ParentSwitchCount.Load())
+
+				parentID, pinned := peer.PinnedParent()
+				assert.True(pinned)
+				assert.Equal(mockPeers[0].ID, parentID)
+
+				// The scores still oscillate, but the peer keeps the pinned parent.
+				for i := 0; i < 4; i++ {
+					assert.Equal(mockPeers[0].ID, schedule().MainPeer.PeerId)
+				}
+				assert.Equal(int32(3), peer.ParentSwitchCount.Load())
+			},
+		},
+		{
+			name:              "pin is released when the pinned parent fails",
+			parentSwitchLimit: 2,
+			run: func(t *testing.T, schedule func() *schedulerv1.PeerPacket, peer *resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				for i := 0; i < 4; i++ {
+					schedule()
+				}
+
+				_, pinned := peer.PinnedParent()
+				assert.True(pinned)
+
+				mockPeers[0].FSM.SetState(resource.PeerStateFailed)
+				assert.Equal(mockPeers[1].ID, schedule().MainPeer.PeerId)
+				assert.Equal(int32(4), peer.ParentSwitchCount.Load())
+
+				_, pinned = peer.PinnedParent()
+				assert.False(pinned)
+			},
+		},
+		{
+			name:              "pin does not override back-to-source",
+			parentSwitchLimit: 2,
+			run: func(t *testing.T, schedule func() *schedulerv1.PeerPacket, peer *resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				for i := 0; i < 4; i++ {
+					schedule()
+				}
+
+				_, pinned := peer.PinnedParent()
+				assert.True(pinned)
+
+				peer.NeedBackToSource.Store(true)
+				assert.Equal(commonv1.Code_SchedNeedBackSource, schedule().Code)
+				assert.True(peer.FSM.Is(resource.PeerStateBackToSource))
+			},
+		},
+		{
+			name:              "peer is never pinned",
+			parentSwitchLimit: 0,
+			run: func(t *testing.T, schedule func() *schedulerv1.PeerPacket, peer *resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				for i := 0; i < 8; i++ {
+					schedule()
+				}
+				assert.Equal(int32(7), peer.ParentSwitchCount.Load())
+
+				_, pinned := peer.PinnedParent()
+				assert.False(pinned)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			stream := schedulerv1mocks.NewMockScheduler_ReportPieceResultServer(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
+
+			var packet *schedulerv1.PeerPacket
+			stream.EXPECT().Send(gomock.Any()).DoAndReturn(func(p *schedulerv1.PeerPacket) error {
+				packet = p
+				return nil
+			}).AnyTimes()
+
+			cfg := *mockResourceConfig
+			cfg.Peer.ParentSwitchLimit = tc.parentSwitchLimit
+			cfg.Peer.ParentSwitchWindow = time.Minute
+			cfg.Peer.ParentPinCooldown = time.Minute
+
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, &cfg, mockTask, mockHost)
+			peer.FSM.SetState(resource.PeerStateRunning)
+			peer.StoreReportPieceResultStream(stream)
+			mockTask.StorePeer(peer)
+
+			var mockPeers []*resource.Peer
+			for i := 0; i < 2; i++ {
+				mockHost := resource.NewHost(
+					idgen.HostIDV2("127.0.0.1", uuid.New().String()), mockRawHost.IP, mockRawHost.Hostname,
+					mockRawHost.Port, mockRawHost.DownloadPort, pkgtypes.HostTypeSuperSeed)
+				mockPeer := resource.NewPeer(idgen.PeerIDV1(fmt.Sprintf("127.0.0.%d", i)), mockResourceConfig, mockTask, mockHost)
+				mockPeer.FSM.SetState(resource.PeerStateRunning)
+				mockTask.StorePeer(mockPeer)
+				mockPeers = append(mockPeers, mockPeer)
+			}
+			mockPeers[0].FinishedPieces.Set(0)
+			mockPeers[1].FinishedPieces.Set(0)
+			mockPeers[1].FinishedPieces.Set(1)
+
+			s := New(mockSchedulerConfig, dynconfig, mockPluginDir).(*scheduling)
+			s.setEvaluator(&oscillatingEvaluator{Evaluator: s.evaluator})
+			tc.run(t, func() *schedulerv1.PeerPacket {
+				packet = nil
+				s.ScheduleParentAndCandidateParents(context.Background(), peer, set.NewSafeSet[string]())
+				return packet
+			}, peer, mockPeers)
+		})
+	}
+}
+
+func TestScheduling_preferSeedCandidateParents(t *testing.T) {
+	tests := []struct {
+		name       string
+		preferSeed bool
+		hostTypes  []pkgtypes.HostType
+		expect     func(t *testing.T, candidateParents []*resource.Peer, mockPeers []*resource.Peer)
+	}{
+		{
+			name:       "seed peers are moved to the front",
+			preferSeed: true,
+			hostTypes:  []pkgtypes.HostType{pkgtypes.HostTypeNormal, pkgtypes.HostTypeSuperSeed, pkgtypes.HostTypeNormal, pkgtypes.HostTypeStrongSeed},
+			expect: func(t *testing.T, candidateParents []*resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Equal([]*resource.Peer{mockPeers[1], mockPeers[3], mockPeers[0], mockPeers[2]}, candidateParents)
+			},
+		},
+		{
+			name:       "normal peers are kept without seed peers",
+			preferSeed: true,
+			hostTypes:  []pkgtypes.HostType{pkgtypes.HostTypeNormal, pkgtypes.HostTypeNormal},
+			expect: func(t *testing.T, candidateParents []*resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Equal(mockPeers, candidateParents)
+			},
+		},
+		{
+			name:       "peer does not prefer seed peers",
+			preferSeed: false,
+			hostTypes:  []pkgtypes.HostType{pkgtypes.HostTypeNormal, pkgtypes.HostTypeSuperSeed},
+			expect: func(t *testing.T, candidateParents []*resource.Peer, mockPeers []*resource.Peer) {
+				assert := assert.New(t)
+				assert.Equal(mockPeers, candidateParents)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost, resource.WithPreferSeed(tc.preferSeed))
+
+			var mockPeers []*resource.Peer
+			for i, hostType := range tc.hostTypes {
+				mockHost := resource.NewHost(
+					idgen.HostIDV2("127.0.0.1", uuid.New().String()), mockRawHost.IP, mockRawHost.Hostname,
+					mockRawHost.Port, mockRawHost.DownloadPort, hostType)
+				mockPeers = append(mockPeers, resource.NewPeer(idgen.PeerIDV1(fmt.Sprintf("127.0.0.%d", i)), mockResourceConfig, mockTask, mockHost))
+			}
+
+			candidateParents := make([]*resource.Peer, len(mockPeers))
+			copy(candidateParents, mockPeers)
+			tc.expect(t, preferSeedCandidateParents(peer, candidateParents), mockPeers)
+		})
+	}
+}
+
 func TestScheduling_ConstructSuccessNormalTaskResponse(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -22,12 +22,15 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/go-http-utils/headers"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	commonv1 "d7y.io/api/v2/pkg/apis/common/v1"
@@ -37,10 +40,15 @@ import (
 
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	logger "d7y.io/dragonfly/v2/internal/dflog"
+	"d7y.io/dragonfly/v2/pkg/compression"
 	"d7y.io/dragonfly/v2/pkg/container/set"
 	"d7y.io/dragonfly/v2/pkg/digest"
+	"d7y.io/dragonfly/v2/pkg/featureflag"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	"d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/piece"
+	"d7y.io/dragonfly/v2/pkg/redact"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/pkg/rpc/common"
 	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
@@ -94,12 +102,57 @@ func NewV1(
 // RegisterPeerTask registers peer and triggers seed peer download task.
 func (v *V1) RegisterPeerTask(ctx context.Context, req *schedulerv1.PeerTaskRequest) (*schedulerv1.RegisterResult, error) {
 	log := logger.WithPeer(req.PeerHost.GetId(), req.GetTaskId(), req.GetPeerId())
-	log.Infof("register peer task request: %#v", req)
+	log.Infof("register peer task request, url: %s, url meta: %#v, peer host: %#v, prefetch: %t",
+		redact.URL(req.GetUrl()), req.GetUrlMeta(), req.GetPeerHost(), req.GetPrefetch())
+
+	// The task id of the url with identity headers must fold the values of the identity headers,
+	// otherwise the peers of the different variants of the url share the same task.
+	if hasIdentityHeaders(req.GetUrlMeta()) {
+		if taskID := idgen.TaskIDV1(req.GetUrl(), req.GetUrlMeta()); taskID != req.GetTaskId() {
+			msg := fmt.Sprintf("task id %s does not match the identity headers, expected %s", req.GetTaskId(), taskID)
+			log.Error(msg)
+			return nil, dferrors.New(commonv1.Code_BadRequest, msg)
+		}
+	}
 
 	// Store resource.
 	task := v.storeTask(ctx, req, commonv2.TaskType_DFDAEMON)
 	host := v.storeHost(ctx, req.GetPeerHost())
-	peer := v.storePeer(ctx, req.GetPeerId(), req.UrlMeta.GetPriority(), req.UrlMeta.GetRange(), task, host)
+
+	// The evicted peer is refused to register again within the ttl of eviction.
+	if host.IsPeerEvicted(req.GetPeerId()) {
+		msg := fmt.Sprintf("peer %s is evicted", req.GetPeerId())
+		log.Error(msg)
+		return nil, dferrors.New(commonv1.Code_SchedForbidden, msg)
+	}
+
+	peer := v.storePeer(ctx, req.GetPeerId(), req.UrlMeta.GetPriority(), req.UrlMeta.GetRange(), isPreferSeed(req.UrlMeta), task, host)
+
+	// Enable piece compression if the application of task enables it and host supports the codec.
+	codecs := compression.CodecsFromIncomingContext(ctx)
+	host.PieceCodecs.Store(&codecs)
+	if codec := v.negotiatePieceCodec(peer); codec != "" {
+		if err := grpc.SetHeader(ctx, metadata.Pairs(compression.PieceCodecMetadataKey, codec)); err != nil {
+			peer.Log.Warnf("enable piece compression failed: %s", err.Error())
+		} else {
+			peer.PieceCodec.Store(codec)
+			peer.Log.Infof("enable piece compression with codec %s", codec)
+		}
+	}
+
+	// Forward the client feature flags of the scheduler cluster, the daemon applies
+	// them on every registration, so the updated flags take effect without reconnecting.
+	if err := v.setFeatureFlagsHeader(ctx); err != nil {
+		peer.Log.Warnf("forward feature flags failed: %s", err.Error())
+	}
+
+	// If the task failed permanently, e.g. the origin returned 404, the peer is rejected with
+	// the failure, so that the peer reports the reason instead of a generic scheduling error.
+	if failure, ok := task.PermanentFailure(); ok {
+		peer.Log.Warnf("task failed permanently: %s", failure)
+		v.handleRegisterFailure(ctx, peer)
+		return nil, dferrors.New(commonv1.Code_BackToSourceAborted, failure.String())
+	}
 
 	// Prefetch the entire task.
 	if req.GetPrefetch() {
@@ -133,7 +186,7 @@ func (v *V1) RegisterPeerTask(ctx context.Context, req *schedulerv1.PeerTaskRequ
 	}
 
 	// If SizeScope is SizeScope_UNKNOW, then register as SizeScope_NORMAL.
-	sizeScope := types.SizeScopeV2ToV1(task.SizeScope())
+	sizeScope := v.sizeScope(task)
 	peer.Log.Infof("task size scope is %s", sizeScope)
 
 	// The task state is TaskStateSucceeded and SizeScope is not invalid.
@@ -248,6 +301,18 @@ func (v *V1) ReportPieceResult(stream schedulerv1.Scheduler_ReportPieceResultSer
 		// Handle piece download successfully.
 		if piece.Success {
 			peer.Log.Infof("receive success piece: %#v %#v", piece, piece.PieceInfo)
+
+			// Reject the piece result which can not be recorded, the peer is still allowed to download.
+			if err := v.validatePieceResult(peer, piece); err != nil {
+				v.handlePieceViolation(peer, err)
+				continue
+			}
+
+			// Record the piece result with the impossible fields clamped.
+			if err := v.clampPieceResult(peer, piece); err != nil {
+				v.handlePieceViolation(peer, err)
+			}
+
 			v.handlePieceSuccess(ctx, peer, piece)
 
 			// Collect host traffic metrics.
@@ -293,7 +358,8 @@ func (v *V1) ReportPieceResult(stream schedulerv1.Scheduler_ReportPieceResultSer
 // ReportPeerResult handles peer result reported by dfdaemon.
 func (v *V1) ReportPeerResult(ctx context.Context, req *schedulerv1.PeerResult) error {
 	log := logger.WithTaskAndPeerID(req.GetTaskId(), req.GetPeerId())
-	log.Infof("report peer result request: %#v", req)
+	log.Infof("report peer result request, url: %s, success: %t, code: %s, content length: %d, total piece count: %d, traffic: %d, cost: %d",
+		redact.URL(req.GetUrl()), req.GetSuccess(), req.GetCode(), req.GetContentLength(), req.GetTotalPieceCount(), req.GetTraffic(), req.GetCost())
 
 	peer, loaded := v.resource.PeerManager().Load(req.GetPeerId())
 	if !loaded {
@@ -315,8 +381,9 @@ func (v *V1) ReportPeerResult(ctx context.Context, req *schedulerv1.PeerResult)
 			metrics.DownloadPeerBackToSourceFailureCount.WithLabelValues(priority.String(), peer.Task.Type.String(),
 				peer.Host.Type.Name()).Inc()
 
-			go v.createDownloadRecord(peer, parents, req)
+			// Handle the task failure first, so that the download record has the failure reason.
 			v.handleTaskFailure(ctx, peer.Task, req.GetSourceError(), nil)
+			go v.createDownloadRecord(peer, parents, req)
 			v.handlePeerFailure(ctx, peer)
 			return nil
 		}
@@ -347,22 +414,22 @@ func (v *V1) ReportPeerResult(ctx context.Context, req *schedulerv1.PeerResult)
 
 // AnnounceTask informs scheduler a peer has completed task.
 func (v *V1) AnnounceTask(ctx context.Context, req *schedulerv1.AnnounceTaskRequest) error {
-	logger.WithPeer(req.PeerHost.GetId(), req.GetTaskId(), req.PiecePacket.GetDstPid()).Infof("announce task request: %#v %#v %#v %#v",
-		req, req.GetUrlMeta(), req.GetPeerHost(), req.GetPiecePacket(),
+	logger.WithPeer(req.PeerHost.GetId(), req.GetTaskId(), req.PiecePacket.GetDstPid()).Infof("announce task request, url: %s, url meta: %#v, peer host: %#v, piece packet: %#v",
+		redact.URL(req.GetUrl()), req.GetUrlMeta(), req.GetPeerHost(), req.GetPiecePacket(),
 	)
 
 	taskID := req.GetTaskId()
 	peerID := req.PiecePacket.GetDstPid()
-	options := []resource.TaskOption{}
+	options := TaskOptions(v.config)
 	if d, err := digest.Parse(req.UrlMeta.GetDigest()); err == nil {
 		options = append(options, resource.WithDigest(d))
 	}
 
 	task := resource.NewTask(taskID, req.GetUrl(), req.UrlMeta.GetTag(), req.UrlMeta.GetApplication(), types.TaskTypeV1ToV2(req.GetTaskType()),
-		strings.Split(req.UrlMeta.GetFilter(), idgen.FilteredQueryParamsSeparator), req.UrlMeta.GetHeader(), int32(v.config.Scheduler.BackToSourceCount), options...)
+		idgen.ParseFilteredQueryParams(req.UrlMeta.GetFilter()), req.UrlMeta.GetHeader(), BackToSourceCount(v.config, v.dynconfig, req.UrlMeta.GetApplication()), options...)
 	task, _ = v.resource.TaskManager().LoadOrStore(task)
 	host := v.storeHost(ctx, req.GetPeerHost())
-	peer := v.storePeer(ctx, peerID, req.UrlMeta.GetPriority(), req.UrlMeta.GetRange(), task, host)
+	peer := v.storePeer(ctx, peerID, req.UrlMeta.GetPriority(), req.UrlMeta.GetRange(), isPreferSeed(req.UrlMeta), task, host)
 
 	// If the task state is not TaskStateSucceeded,
 	// advance the task state to TaskStateSucceeded.
@@ -567,6 +634,10 @@ func (v *V1) AnnounceHost(ctx context.Context, req *schedulerv1.AnnounceHostRequ
 			options = append(options, resource.WithObjectStoragePort(req.GetObjectStoragePort()))
 		}
 
+		if startedAt, ok := rpc.HostStartedAtFromContext(ctx); ok {
+			options = append(options, resource.WithStartedAt(startedAt))
+		}
+
 		host = resource.NewHost(
 			req.GetId(), req.GetIp(), req.GetHostname(), req.GetPort(), req.GetDownloadPort(),
 			types.ParseHostType(req.GetType()), options...,
@@ -577,6 +648,11 @@ func (v *V1) AnnounceHost(ctx context.Context, req *schedulerv1.AnnounceHostRequ
 		return nil
 	}
 
+	// Host restarted with the same id, reap the peers created before the restart.
+	if restartedAt, ok := hostRestartedAt(ctx, host); ok {
+		v.handleHostRestart(ctx, host, restartedAt)
+	}
+
 	// Host already exists and updates properties.
 	host.Port = req.GetPort()
 	host.DownloadPort = req.GetDownloadPort()
@@ -738,7 +814,7 @@ func (v *V1) SyncProbes(stream schedulerv1.Scheduler_SyncProbesServer) error {
 			// source host and destination host, and then store the value of probe.
 			log.Info("receive SyncProbesRequest_ProbeFinishedRequest")
 			for _, probe := range syncProbesRequest.ProbeFinishedRequest.Probes {
-				probedHost, loaded := v.resource.HostManager().Load(probe.Host.Id)
+				probedHost, loaded := v.loadProbedHost(probe.Host)
 				if !loaded {
 					log.Errorf("host %s not found", probe.Host.Id)
 					continue
@@ -749,7 +825,7 @@ func (v *V1) SyncProbes(stream schedulerv1.Scheduler_SyncProbesServer) error {
 					continue
 				}
 
-				if err := v.networkTopology.Probes(req.Host.GetId(), probe.Host.Id).Enqueue(&networktopology.Probe{
+				if err := v.networkTopology.Probes(req.Host.GetId(), probedHost.ID).Enqueue(&networktopology.Probe{
 					Host:      probedHost,
 					RTT:       probe.Rtt.AsDuration(),
 					CreatedAt: probe.CreatedAt.AsTime(),
@@ -777,6 +853,21 @@ func (v *V1) SyncProbes(stream schedulerv1.Scheduler_SyncProbesServer) error {
 	}
 }
 
+// loadProbedHost returns the probed host. The daemons probe the parents after scheduling
+// and only know the peer id of the parents, so the host is resolved by the peer id if it is
+// not the host id.
+func (v *V1) loadProbedHost(probeHost *commonv1.Host) (*resource.Host, bool) {
+	if host, loaded := v.resource.HostManager().Load(probeHost.GetId()); loaded {
+		return host, true
+	}
+
+	if peer, loaded := v.resource.PeerManager().Load(probeHost.GetId()); loaded {
+		return peer.Host, true
+	}
+
+	return nil, false
+}
+
 // prefetchTask prefetches the task with seed peer.
 func (v *V1) prefetchTask(ctx context.Context, rawReq *schedulerv1.PeerTaskRequest) (*resource.Task, error) {
 	// If seed peer is disabled, then return error.
@@ -904,6 +995,11 @@ func (v *V1) triggerSeedPeerTask(ctx context.Context, rg *http.Range, task *reso
 	task.Log.Info("trigger seed peer")
 	seedPeer, endOfPiece, err := v.resource.SeedPeer().TriggerTask(ctx, rg, task)
 	if err != nil {
+		if errors.Is(err, resource.ErrSeedingAborted) {
+			task.Log.Info(err.Error())
+			return
+		}
+
 		task.Log.Errorf("trigger seed peer failed: %s", err.Error())
 		v.handleTaskFailure(ctx, task, nil, err)
 		return
@@ -917,17 +1013,17 @@ func (v *V1) triggerSeedPeerTask(ctx context.Context, rg *http.Range, task *reso
 
 // storeTask stores a new task or reuses a previous task.
 func (v *V1) storeTask(ctx context.Context, req *schedulerv1.PeerTaskRequest, typ commonv2.TaskType) *resource.Task {
-	filteredQueryParams := strings.Split(req.UrlMeta.GetFilter(), idgen.FilteredQueryParamsSeparator)
+	filteredQueryParams := idgen.ParseFilteredQueryParams(req.UrlMeta.GetFilter())
 
 	task, loaded := v.resource.TaskManager().Load(req.GetTaskId())
 	if !loaded {
-		options := []resource.TaskOption{}
+		options := TaskOptions(v.config)
 		if d, err := digest.Parse(req.UrlMeta.GetDigest()); err == nil {
 			options = append(options, resource.WithDigest(d))
 		}
 
 		task := resource.NewTask(req.GetTaskId(), req.GetUrl(), req.UrlMeta.GetTag(), req.UrlMeta.GetApplication(),
-			typ, filteredQueryParams, req.UrlMeta.GetHeader(), int32(v.config.Scheduler.BackToSourceCount), options...)
+			typ, filteredQueryParams, req.UrlMeta.GetHeader(), BackToSourceCount(v.config, v.dynconfig, req.UrlMeta.GetApplication()), options...)
 		v.resource.TaskManager().Store(task)
 		task.Log.Info("create new task")
 		return task
@@ -974,8 +1070,124 @@ func (v *V1) storeHost(ctx context.Context, peerHost *schedulerv1.PeerHost) *res
 	return host
 }
 
+// negotiatePieceCodec returns the codec of piece compression for the peer, it returns
+// empty string if the application of task does not enable piece compression or host
+// does not support the codec. The peer with the codec is only scheduled with the parents
+// supporting the codec.
+func (v *V1) negotiatePieceCodec(peer *resource.Peer) string {
+	codecs := peer.Host.LoadPieceCodecs()
+	if peer.Task.Application == "" || len(codecs) == 0 {
+		return ""
+	}
+
+	clusterConfig, err := v.dynconfig.GetSchedulerClusterConfig()
+	if err != nil {
+		return ""
+	}
+
+	for _, application := range clusterConfig.PieceCompressionApplications {
+		if application == peer.Task.Application {
+			return compression.Negotiate(codecs)
+		}
+	}
+
+	return ""
+}
+
+// setFeatureFlagsHeader sets the client feature flags of the scheduler cluster in the response header.
+// The header is set even if the flags are empty, so the daemon resets the flags removed from the cluster.
+func (v *V1) setFeatureFlagsHeader(ctx context.Context) error {
+	value, err := featureflag.Encode(v.dynconfig.GetClientFeatureFlags())
+	if err != nil {
+		return err
+	}
+
+	return grpc.SetHeader(ctx, metadata.Pairs(featureflag.MetadataKey, value))
+}
+
+// sizeScope returns the size scope of the task with the file size limits of its application.
+func (v *V1) sizeScope(task *resource.Task) commonv1.SizeScope {
+	tinyFileSizeLimit, smallFileSizeLimit := fileSizeLimits(v.config, v.dynconfig, task)
+	return types.SizeScopeV2ToV1(task.SizeScopeWithLimits(tinyFileSizeLimit, smallFileSizeLimit))
+}
+
+// fileSizeLimits returns the size limits of the tiny file and the small file for the task,
+// the limits of the application in scheduler cluster config override the scheduler config.
+func fileSizeLimits(cfg *config.Config, dynconfig config.DynconfigInterface, task *resource.Task) (int64, int64) {
+	tinyFileSizeLimit := cfg.Scheduler.TinyFileSizeLimit.ToNumber()
+	smallFileSizeLimit := cfg.Scheduler.SmallFileSizeLimit.ToNumber()
+	if task.Application == "" {
+		return tinyFileSizeLimit, smallFileSizeLimit
+	}
+
+	clusterConfig, err := dynconfig.GetSchedulerClusterConfig()
+	if err != nil {
+		return tinyFileSizeLimit, smallFileSizeLimit
+	}
+
+	for _, application := range clusterConfig.SizeScopeApplications {
+		if application.Name != task.Application {
+			continue
+		}
+
+		tiny, small := tinyFileSizeLimit, smallFileSizeLimit
+		if application.TinyFileSizeLimit > 0 {
+			tiny = application.TinyFileSizeLimit
+		}
+
+		if application.SmallFileSizeLimit > 0 {
+			small = application.SmallFileSizeLimit
+		}
+
+		if tiny > config.MaxSchedulerTinyFileSizeLimit.ToNumber() || tiny >= small {
+			task.Log.Warnf("invalid size scope limits of application %s, tiny file size limit is %d, small file size limit is %d",
+				task.Application, tiny, small)
+			break
+		}
+
+		return tiny, small
+	}
+
+	return tinyFileSizeLimit, smallFileSizeLimit
+}
+
+// BackToSourceCount returns the maximum number of the peers of the task going back-to-source, the count
+// of the application in scheduler cluster config overrides the count of the cluster, then the scheduler config.
+func BackToSourceCount(cfg *config.Config, dynconfig config.DynconfigInterface, application string) int32 {
+	backToSourceCount := int32(cfg.Scheduler.BackToSourceCount)
+	clusterConfig, err := dynconfig.GetSchedulerClusterConfig()
+	if err != nil {
+		return backToSourceCount
+	}
+
+	if clusterConfig.BackToSourceCount > 0 {
+		backToSourceCount = int32(clusterConfig.BackToSourceCount)
+	}
+
+	if application == "" {
+		return backToSourceCount
+	}
+
+	for _, backToSourceApplication := range clusterConfig.BackToSourceApplications {
+		if backToSourceApplication.Name == application && backToSourceApplication.BackToSourceCount > 0 {
+			return int32(backToSourceApplication.BackToSourceCount)
+		}
+	}
+
+	return backToSourceCount
+}
+
+// TaskOptions returns the options of the scheduler config shared by all the tasks,
+// including the tasks registered by peers and the tasks restored from the snapshots.
+func TaskOptions(cfg *config.Config) []resource.TaskOption {
+	return []resource.TaskOption{
+		resource.WithBackToSourceRateLimit(cfg.Scheduler.BackToSourceRateLimit, cfg.Scheduler.BackToSourceRateInterval.Duration),
+		resource.WithBackToSourceCooldown(cfg.Scheduler.BackToSourceCooldown.Duration),
+	}
+}
+
 // storePeer stores a new peer or reuses a previous peer.
-func (v *V1) storePeer(ctx context.Context, id string, priority commonv1.Priority, rg string, task *resource.Task, host *resource.Host) *resource.Peer {
+func (v *V1) storePeer(ctx context.Context, id string, priority commonv1.Priority, rg string, preferSeed bool, task *resource.Task, host *resource.Host) *resource.Peer {
 	peer, loaded := v.resource.PeerManager().Load(id)
 	if !loaded {
 		options := []resource.PeerOption{}
@@ -983,6 +1195,10 @@ func (v *V1) storePeer(ctx context.Context, id string, priority commonv1.Priorit
 			options = append(options, resource.WithPriority(types.PriorityV1ToV2(priority)))
 		}
 
+		if preferSeed {
+			options = append(options, resource.WithPreferSeed(preferSeed))
+		}
+
 		if len(rg) > 0 {
 			if r, err := http.ParseURLMetaRange(rg, math.MaxInt64); err == nil {
 				options = append(options, resource.WithRange(r))
@@ -1001,6 +1217,23 @@ func (v *V1) storePeer(ctx context.Context, id string, priority commonv1.Priorit
 	return peer
 }
 
+// isPreferSeed returns whether the peer prefers the seed peers as parents by the header of url meta.
+func isPreferSeed(urlMeta *commonv1.UrlMeta) bool {
+	preferSeed, err := strconv.ParseBool(urlMeta.GetHeader()[http.HeaderPreferSeed])
+	return err == nil && preferSeed
+}
+
+// hasIdentityHeaders returns whether the task id folds the identity headers of the url meta.
+func hasIdentityHeaders(urlMeta *commonv1.UrlMeta) bool {
+	for k := range urlMeta.GetHeader() {
+		if strings.EqualFold(k, http.HeaderIdentityHeaders) {
+			return true
+		}
+	}
+
+	return false
+}
+
 // registerEmptyTask registers the empty task.
 func (v *V1) registerEmptyTask(ctx context.Context, peer *resource.Peer) (*schedulerv1.RegisterResult, error) {
 	if err := peer.FSM.Event(ctx, resource.PeerEventRegisterEmpty); err != nil {
@@ -1057,13 +1290,9 @@ func (v *V1) registerSmallTask(ctx context.Context, peer *resource.Peer) (*sched
 		return nil, fmt.Errorf("first piece not found")
 	}
 
-	// Delete inedges of peer.
-	if err := peer.Task.DeletePeerInEdges(peer.ID); err != nil {
-		return nil, err
-	}
-
-	// Add edges between parent and peer.
-	if err := peer.Task.AddPeerEdge(candidateParent, peer); err != nil {
+	// Replace inedges of peer with the edge between parent and peer,
+	// if the edge makes a cycle, peer falls back to be scheduled as normal task.
+	if err := peer.Task.ReplacePeerParent(candidateParent, peer); err != nil {
 		return nil, err
 	}
 
@@ -1102,7 +1331,14 @@ func (v *V1) registerSmallTask(ctx context.Context, peer *resource.Peer) (*sched
 
 // registerNormalTask registers the tiny task.
 func (v *V1) registerNormalTask(ctx context.Context, peer *resource.Peer) (*schedulerv1.RegisterResult, error) {
-	if err := peer.FSM.Event(ctx, resource.PeerEventRegisterNormal); err != nil {
+	// If the peer restores the finished pieces in local storage, it is running
+	// directly and can be the parent of other peers before reporting any piece.
+	event := resource.PeerEventRegisterNormal
+	if v.restoreFinishedPieces(ctx, peer) {
+		event = resource.PeerEventRestore
+	}
+
+	if err := peer.FSM.Event(ctx, event); err != nil {
 		return nil, err
 	}
 
@@ -1113,6 +1349,33 @@ func (v *V1) registerNormalTask(ctx context.Context, peer *resource.Peer) (*sche
 	}, nil
 }
 
+// restoreFinishedPieces restores the finished pieces claimed by the peer registering
+// again after restart, the claim is ignored if it does not match the task.
+func (v *V1) restoreFinishedPieces(ctx context.Context, peer *resource.Peer) bool {
+	if !peer.FSM.Is(resource.PeerStatePending) {
+		return false
+	}
+
+	finished, err := piece.FinishedFromIncomingContext(ctx)
+	if err != nil {
+		peer.Log.Warnf("ignore finished pieces: %s", err.Error())
+		return false
+	}
+
+	if finished == nil {
+		return false
+	}
+
+	if err := finished.Validate(peer.Task.ID, peer.Task.ContentLength.Load(), peer.Task.TotalPieceCount.Load()); err != nil {
+		peer.Log.Warnf("ignore finished pieces: %s", err.Error())
+		return false
+	}
+
+	peer.FinishedPieces.InPlaceUnion(finished.Pieces)
+	peer.Log.Infof("restore %d finished pieces", finished.Pieces.Count())
+	return true
+}
+
 // handleRegisterFailure handles failure of register.
 func (v *V1) handleRegisterFailure(ctx context.Context, peer *resource.Peer) {
 	if err := peer.FSM.Event(ctx, resource.PeerEventLeave); err != nil {
@@ -1156,6 +1419,19 @@ func (v *V1) handleBeginOfPiece(ctx context.Context, peer *resource.Peer) {
 		start := time.Now()
 		v.scheduling.ScheduleParentAndCandidateParents(ctx, peer, set.NewSafeSet[string]())
 
+		// Collect SchedulingDuration metrics.
+		metrics.ScheduleDuration.Observe(float64(time.Since(start).Milliseconds()))
+	case resource.PeerStateRunning:
+		// When the peer restores the finished pieces, it is running
+		// after registering and needs parents for the remaining pieces.
+		if len(peer.Parents()) > 0 || peer.FinishedPieces.Count() == 0 {
+			return
+		}
+
+		// Record the start time.
+		start := time.Now()
+		v.scheduling.ScheduleParentAndCandidateParents(ctx, peer, set.NewSafeSet[string]())
+
 		// Collect SchedulingDuration metrics.
 		metrics.ScheduleDuration.Observe(float64(time.Since(start).Milliseconds()))
 	default:
@@ -1206,6 +1482,7 @@ func (v *V1) handlePieceSuccess(ctx context.Context, peer *resource.Peer, pieceR
 		if destPeer, loaded := v.resource.PeerManager().Load(pieceResult.DstPid); loaded {
 			destPeer.UpdatedAt.Store(time.Now())
 			destPeer.Host.UpdatedAt.Store(time.Now())
+			peer.StoreParentHost(destPeer.Host.ID)
 		}
 	}
 
@@ -1216,6 +1493,74 @@ func (v *V1) handlePieceSuccess(ctx context.Context, peer *resource.Peer, pieceR
 	}
 }
 
+// validatePieceResult validates the successful piece result reported by the peer
+// can be recorded, the piece number must be in the range of the task.
+func (v *V1) validatePieceResult(peer *resource.Peer, pieceResult *schedulerv1.PieceResult) error {
+	if pieceResult.PieceInfo == nil {
+		return errors.New("piece info is empty")
+	}
+
+	if pieceResult.PieceInfo.PieceNum < 0 {
+		return fmt.Errorf("piece number %d is invalid", pieceResult.PieceInfo.PieceNum)
+	}
+
+	if totalPieceCount := peer.Task.TotalPieceCount.Load(); totalPieceCount > 0 && pieceResult.PieceInfo.PieceNum >= totalPieceCount {
+		return fmt.Errorf("piece number %d is out of total piece count %d", pieceResult.PieceInfo.PieceNum, totalPieceCount)
+	}
+
+	return nil
+}
+
+// clampPieceResult clamps the impossible fields of the successful piece result reported by the peer,
+// it returns the violations of the piece result. The finished count is clamped to be monotonic.
+func (v *V1) clampPieceResult(peer *resource.Peer, pieceResult *schedulerv1.PieceResult) error {
+	var errs []error
+	if totalPieceCount := peer.Task.TotalPieceCount.Load(); totalPieceCount > 0 && pieceResult.FinishedCount > totalPieceCount {
+		errs = append(errs, fmt.Errorf("finished count %d is greater than total piece count %d", pieceResult.FinishedCount, totalPieceCount))
+		pieceResult.FinishedCount = totalPieceCount
+	}
+
+	if pieceResult.EndTime < pieceResult.BeginTime {
+		errs = append(errs, fmt.Errorf("end time %d is before begin time %d", pieceResult.EndTime, pieceResult.BeginTime))
+		pieceResult.EndTime = pieceResult.BeginTime
+	}
+
+	if maxPieceCost := uint64(v.config.Scheduler.MaxPieceCost.Milliseconds()); maxPieceCost > 0 && pieceResult.PieceInfo.DownloadCost > maxPieceCost {
+		errs = append(errs, fmt.Errorf("piece cost %dms is greater than %s", pieceResult.PieceInfo.DownloadCost, v.config.Scheduler.MaxPieceCost.Duration))
+		pieceResult.PieceInfo.DownloadCost = maxPieceCost
+	}
+
+	for {
+		reportedFinishedCount := peer.ReportedFinishedCount.Load()
+		if pieceResult.FinishedCount < reportedFinishedCount {
+			errs = append(errs, fmt.Errorf("finished count %d is less than reported finished count %d", pieceResult.FinishedCount, reportedFinishedCount))
+			pieceResult.FinishedCount = reportedFinishedCount
+			break
+		}
+
+		if pieceResult.FinishedCount == reportedFinishedCount ||
+			peer.ReportedFinishedCount.CompareAndSwap(reportedFinishedCount, pieceResult.FinishedCount) {
+			break
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// handlePieceViolation handles impossible piece result, the peer is quarantined
+// when the count of impossible piece results reaches the limit.
+func (v *V1) handlePieceViolation(peer *resource.Peer, err error) {
+	peer.Log.Debugf("reject piece result: %s", err.Error())
+	if v.config.Scheduler.PieceViolationLimit <= 0 {
+		return
+	}
+
+	if peer.AddPieceViolation(v.config.Scheduler.PieceViolationLimit) {
+		peer.Log.With("pieceViolationCount", peer.PieceViolationCount.Load(), "lastViolation", err.Error()).
+			Warn("peer is quarantined because of reporting impossible piece results")
+	}
+}
+
 // handlePieceFailure handles failed piece.
 func (v *V1) handlePieceFailure(ctx context.Context, peer *resource.Peer, piece *schedulerv1.PieceResult) {
 	// Failed to download piece back-to-source.
@@ -1227,11 +1572,11 @@ func (v *V1) handlePieceFailure(ctx context.Context, peer *resource.Peer, piece
 	parent, loaded := v.resource.PeerManager().Load(piece.DstPid)
 	if !loaded {
 		peer.Log.Errorf("parent %s not found", piece.DstPid)
-		peer.BlockParents.Add(piece.DstPid)
+		peer.BlockParent(piece.DstPid)
 
 		// Record the start time.
 		start := time.Now()
-		v.scheduling.ScheduleParentAndCandidateParents(ctx, peer, peer.BlockParents)
+		v.scheduling.ScheduleParentAndCandidateParents(ctx, peer, peer.LoadBlockParents())
 
 		// Collect SchedulingDuration metrics.
 		metrics.ScheduleDuration.Observe(float64(time.Since(start).Milliseconds()))
@@ -1277,13 +1622,7 @@ func (v *V1) handlePieceFailure(ctx context.Context, peer *resource.Peer, piece
 
 		// Returns an scheduling error if the peer
 		// state is not PeerStateRunning.
-		stream, loaded := peer.LoadReportPieceResultStream()
-		if !loaded {
-			peer.Log.Error("load stream failed")
-			return
-		}
-
-		if err := stream.Send(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedError}); err != nil {
+		if err := peer.SendPeerPacket(&schedulerv1.PeerPacket{Code: commonv1.Code_SchedError}); err != nil {
 			peer.Log.Error(err)
 			return
 		}
@@ -1292,11 +1631,11 @@ func (v *V1) handlePieceFailure(ctx context.Context, peer *resource.Peer, piece
 	}
 
 	peer.Log.Infof("reschedule parent because of failed piece")
-	peer.BlockParents.Add(parent.ID)
+	peer.BlockParent(parent.ID)
 
 	// Record the start time.
 	start := time.Now()
-	v.scheduling.ScheduleParentAndCandidateParents(ctx, peer, peer.BlockParents)
+	v.scheduling.ScheduleParentAndCandidateParents(ctx, peer, peer.LoadBlockParents())
 
 	// Collect SchedulingDuration metrics.
 	metrics.ScheduleDuration.Observe(float64(time.Since(start).Milliseconds()))
@@ -1314,7 +1653,7 @@ func (v *V1) handlePeerSuccess(ctx context.Context, peer *resource.Peer) {
 
 	// If the peer type is tiny and back-to-source,
 	// it needs to directly download the tiny file and store the data in task DirectPiece.
-	if types.SizeScopeV2ToV1(peer.Task.SizeScope()) == commonv1.SizeScope_TINY && len(peer.Task.DirectPiece) == 0 {
+	if v.sizeScope(peer.Task) == commonv1.SizeScope_TINY && len(peer.Task.DirectPiece) == 0 {
 		data, err := peer.DownloadTinyFile()
 		if err != nil {
 			peer.Log.Errorf("download tiny task failed: %s", err.Error())
@@ -1344,7 +1683,7 @@ func (v *V1) handlePeerFailure(ctx context.Context, peer *resource.Peer) {
 
 		// Record the start time.
 		start := time.Now()
-		v.scheduling.ScheduleParentAndCandidateParents(ctx, child, child.BlockParents)
+		v.scheduling.ScheduleParentAndCandidateParents(ctx, child, child.LoadBlockParents())
 
 		// Collect SchedulingDuration metrics.
 		metrics.ScheduleDuration.Observe(float64(time.Since(start).Milliseconds()))
@@ -1365,13 +1704,83 @@ func (v *V1) handleLegacySeedPeer(ctx context.Context, peer *resource.Peer) {
 
 		// Record the start time.
 		start := time.Now()
-		v.scheduling.ScheduleParentAndCandidateParents(ctx, child, child.BlockParents)
+		v.scheduling.ScheduleParentAndCandidateParents(ctx, child, child.LoadBlockParents())
 
 		// Collect SchedulingDuration metrics.
 		metrics.ScheduleDuration.Observe(float64(time.Since(start).Milliseconds()))
 	}
 }
 
+// handleHostRestart handles the host restarted with the same id, the ghost peers created
+// before the restart leave like the legacy seed peer, and their children are rescheduled.
+func (v *V1) handleHostRestart(ctx context.Context, host *resource.Host, restartedAt time.Time) {
+	reapHostPeers(host, restartedAt, func(peer *resource.Peer) {
+		v.handleLegacySeedPeer(ctx, peer)
+	})
+}
+
+// hostRestartedAt returns the restart time of the host in the clock of the scheduler, if the start time
+// announced by the daemon is newer than the recorded one. The restart time is computed by the uptime of
+// the daemon, so the clock skew between the daemon and the scheduler does not matter. The host without
+// the recorded start time only records it, e.g. the host registered by the peer or the older daemon.
+func hostRestartedAt(ctx context.Context, host *resource.Host) (time.Time, bool) {
+	startedAt, ok := rpc.HostStartedAtFromContext(ctx)
+	if !ok {
+		return time.Time{}, false
+	}
+
+	recordedStartedAt := host.StartedAt.Load()
+	if !recordedStartedAt.IsZero() && !startedAt.After(recordedStartedAt) {
+		return time.Time{}, false
+	}
+
+	host.StartedAt.Store(startedAt)
+	if recordedStartedAt.IsZero() {
+		return time.Time{}, false
+	}
+
+	// The daemon without the uptime is regarded as restarted just now.
+	restartedAt := time.Now()
+	if uptime, ok := rpc.HostUptimeFromContext(ctx); ok {
+		restartedAt = restartedAt.Add(-uptime)
+	}
+
+	return restartedAt, true
+}
+
+// reapHostPeers reaps the peers of the host created before the restart. The ghost peers no longer
+// exist in the daemon, but they still occupy the upload slots of the host, so they leave by leave
+// and the edges of their children are deleted, then the upload counters of the host are reset.
+// The peers registered after the restart are kept.
+func reapHostPeers(host *resource.Host, restartedAt time.Time, leave func(peer *resource.Peer)) {
+	var count int
+	host.Peers.Range(func(_, value any) bool {
+		peer, ok := value.(*resource.Peer)
+		if !ok {
+			host.Log.Error("invalid peer")
+			return true
+		}
+
+		if peer.FSM.Is(resource.PeerStateLeave) || !peer.CreatedAt.Load().Before(restartedAt) {
+			return true
+		}
+
+		peer.Log.Info("host restarted, causing the peer to leave")
+		leave(peer)
+
+		// Delete the edges of the children which are not rescheduled.
+		if err := peer.Task.DeletePeerOutEdges(peer.ID); err != nil {
+			peer.Log.Warnf("delete peer outedges failed: %s", err.Error())
+		}
+
+		count++
+		return true
+	})
+
+	host.ResetUploadCounts()
+	host.Log.Infof("host restarted, reaped %d peers", count)
+}
+
 // Conditions for the task to switch to the TaskStateSucceeded are:
 // 1. Seed peer downloads the resource successfully.
 // 2. Dfdaemon back-to-source to download successfully.
@@ -1395,10 +1804,18 @@ func (v *V1) handleTaskSuccess(ctx context.Context, task *resource.Task, req *sc
 // 1. Seed peer downloads the resource failed.
 // 2. Dfdaemon back-to-source to download failed.
 func (v *V1) handleTaskFailure(ctx context.Context, task *resource.Task, backToSourceErr *errordetailsv1.SourceError, seedPeerErr error) {
+	// Record the failure before the failed peer count is reset.
+	failure := newTaskFailure(task, backToSourceErr, seedPeerErr)
+
 	// If peer back-to-source fails due to an unrecoverable error,
 	// notify other peers of the failure,
 	// and return the source metadata to peer.
 	if backToSourceErr != nil {
+		// Pause granting the peers to back-to-source, so that the source can recover from the server error.
+		if http.IsServerError(int(backToSourceErr.GetMetadata().GetStatusCode())) {
+			task.PauseBackToSource()
+		}
+
 		if !backToSourceErr.Temporary {
 			task.ReportPieceResultToPeers(&schedulerv1.PeerPacket{
 				Code: commonv1.Code_BackToSourceAborted,
@@ -1442,12 +1859,49 @@ func (v *V1) handleTaskFailure(ctx context.Context, task *resource.Task, backToS
 		return
 	}
 
+	task.SetFailure(failure)
 	if err := task.FSM.Event(ctx, resource.TaskEventDownloadFailed); err != nil {
 		task.Log.Errorf("task fsm event failed: %s", err.Error())
 		return
 	}
 }
 
+// newTaskFailure returns the failure of the task by the back-to-source error of peer or the error of seed peer.
+func newTaskFailure(task *resource.Task, backToSourceErr *errordetailsv1.SourceError, seedPeerErr error) *resource.TaskFailure {
+	if backToSourceErr != nil {
+		return newSourceTaskFailure(backToSourceErr)
+	}
+
+	if seedPeerErr != nil {
+		st := status.Convert(seedPeerErr)
+		for _, detail := range st.Details() {
+			if d, ok := detail.(*errordetailsv1.SourceError); ok {
+				return newSourceTaskFailure(d)
+			}
+		}
+
+		if dferr, ok := dferrors.IsGRPCDfError(seedPeerErr); ok && dferr.Code == commonv1.Code_CDNTaskRegistryFail {
+			return resource.NewTaskFailure(resource.TaskFailureReasonSeedPeerRegisterFailed, dferr.Message)
+		}
+
+		return resource.NewTaskFailure(resource.TaskFailureReasonSeedPeerDownloadFailed, st.Message())
+	}
+
+	if task.PeerFailedCount.Load() > resource.FailedPeerCountLimit {
+		return resource.NewTaskFailure(resource.TaskFailureReasonRetryExhausted, "")
+	}
+
+	return resource.NewTaskFailure(resource.TaskFailureReasonBackToSourceFailed, "")
+}
+
+// newSourceTaskFailure returns the failure of the task by the response of the origin.
+func newSourceTaskFailure(sourceErr *errordetailsv1.SourceError) *resource.TaskFailure {
+	failure := resource.NewTaskFailure(resource.TaskFailureReasonSourceError, "")
+	failure.StatusCode = sourceErr.GetMetadata().GetStatusCode()
+	failure.Temporary = sourceErr.GetTemporary()
+	return failure
+}
+
 // createDownloadRecord stores peer download records.
 func (v *V1) createDownloadRecord(peer *resource.Peer, parents []*resource.Peer, req *schedulerv1.PeerResult) {
 	var parentRecords []storage.Parent
@@ -1557,6 +2011,11 @@ func (v *V1) createDownloadRecord(peer *resource.Peer, parents []*resource.Peer,
 		parentRecords = append(parentRecords, parentRecord)
 	}
 
+	var failureReason string
+	if failure := peer.Task.Failure(); failure != nil {
+		failureReason = string(failure.Reason)
+	}
+
 	download := storage.Download{
 		ID:                 peer.ID,
 		Tag:                peer.Task.Tag,
@@ -1564,18 +2023,21 @@ func (v *V1) createDownloadRecord(peer *resource.Peer, parents []*resource.Peer,
 		State:              peer.FSM.Current(),
 		Cost:               peer.Cost.Load().Nanoseconds(),
 		FinishedPieceCount: int32(peer.FinishedPieces.Count()),
+		Quarantined:        peer.Quarantined.Load(),
+		ParentSwitchCount:  peer.ParentSwitchCount.Load(),
 		Parents:            parentRecords,
 		CreatedAt:          peer.CreatedAt.Load().UnixNano(),
 		UpdatedAt:          peer.UpdatedAt.Load().UnixNano(),
 		Task: storage.Task{
 			ID:                    peer.Task.ID,
-			URL:                   peer.Task.URL,
+			URL:                   redact.URL(peer.Task.URL),
 			Type:                  peer.Task.Type.String(),
 			ContentLength:         peer.Task.ContentLength.Load(),
 			TotalPieceCount:       peer.Task.TotalPieceCount.Load(),
 			BackToSourceLimit:     peer.Task.BackToSourceLimit.Load(),
 			BackToSourcePeerCount: int32(peer.Task.BackToSourcePeers.Len()),
 			State:                 peer.Task.FSM.Current(),
+			FailureReason:         failureReason,
 			CreatedAt:             peer.Task.CreatedAt.Load().UnixNano(),
 			UpdatedAt:             peer.Task.UpdatedAt.Load().UnixNano(),
 		},
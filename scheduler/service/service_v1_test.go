@@ -32,10 +32,13 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bits-and-blooms/bitset"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/atomic"
 	"go.uber.org/mock/gomock"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -50,10 +53,14 @@ import (
 
 	"d7y.io/dragonfly/v2/internal/dferrors"
 	"d7y.io/dragonfly/v2/manager/types"
+	"d7y.io/dragonfly/v2/pkg/compression"
 	"d7y.io/dragonfly/v2/pkg/container/set"
 	"d7y.io/dragonfly/v2/pkg/digest"
+	"d7y.io/dragonfly/v2/pkg/featureflag"
 	"d7y.io/dragonfly/v2/pkg/idgen"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/piece"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/pkg/rpc/common"
 	pkgtypes "d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
@@ -71,8 +78,12 @@ var (
 	mockSchedulerConfig = config.SchedulerConfig{
 		RetryLimit:             10,
 		RetryBackToSourceLimit: 3,
-		RetryInterval:          10 * time.Millisecond,
+		RetryInterval:          pkgtypes.Duration{Duration: 10 * time.Millisecond},
 		BackToSourceCount:      int(mockTaskBackToSourceLimit),
+		PieceViolationLimit:    2,
+		MaxPieceCost:           pkgtypes.Duration{Duration: 1 * time.Minute},
+		TinyFileSizeLimit:      config.DefaultSchedulerTinyFileSizeLimit,
+		SmallFileSizeLimit:     config.DefaultSchedulerSmallFileSizeLimit,
 	}
 
 	mockSeedPeerConfig = config.SeedPeerConfig{
@@ -360,6 +371,67 @@ func TestServiceV1_RegisterPeerTask(t *testing.T) {
 		)
 		expect func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error)
 	}{
+		{
+			name: "task id does not match the identity headers",
+			req: &schedulerv1.PeerTaskRequest{
+				TaskId: mockTaskID,
+				Url:    "https://example.com/v2/foo/manifests/latest",
+				UrlMeta: &commonv1.UrlMeta{
+					Priority: commonv1.Priority_LEVEL0,
+					Header:   map[string]string{nethttp.HeaderIdentityHeaders: "Accept", "Accept": "foo"},
+				},
+				PeerHost: &schedulerv1.PeerHost{
+					Id: mockRawHost.ID,
+				},
+			},
+			mock: func(
+				req *schedulerv1.PeerTaskRequest, mockPeer *resource.Peer, mockSeedPeer *resource.Peer,
+				scheduling scheduling.Scheduling, res resource.Resource, hostManager resource.HostManager, taskManager resource.TaskManager, peerManager resource.PeerManager,
+				ms *mocks.MockSchedulingMockRecorder, mr *resource.MockResourceMockRecorder, mh *resource.MockHostManagerMockRecorder, mt *resource.MockTaskManagerMockRecorder,
+				mp *resource.MockPeerManagerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder,
+			) {
+			},
+			expect: func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error) {
+				assert := assert.New(t)
+				dferr, ok := err.(*dferrors.DfError)
+				assert.True(ok)
+				assert.Equal(commonv1.Code_BadRequest, dferr.Code)
+				assert.Nil(result)
+			},
+		},
+		{
+			name: "peer is evicted",
+			req: &schedulerv1.PeerTaskRequest{
+				PeerId: mockPeerID,
+				UrlMeta: &commonv1.UrlMeta{
+					Priority: commonv1.Priority_LEVEL0,
+				},
+				PeerHost: &schedulerv1.PeerHost{
+					Id: mockRawHost.ID,
+				},
+			},
+			mock: func(
+				req *schedulerv1.PeerTaskRequest, mockPeer *resource.Peer, mockSeedPeer *resource.Peer,
+				scheduling scheduling.Scheduling, res resource.Resource, hostManager resource.HostManager, taskManager resource.TaskManager, peerManager resource.PeerManager,
+				ms *mocks.MockSchedulingMockRecorder, mr *resource.MockResourceMockRecorder, mh *resource.MockHostManagerMockRecorder, mt *resource.MockTaskManagerMockRecorder,
+				mp *resource.MockPeerManagerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder,
+			) {
+				mockPeer.Host.EvictPeer(mockPeerID)
+				gomock.InOrder(
+					mr.TaskManager().Return(taskManager).Times(1),
+					mt.Load(gomock.Any()).Return(mockPeer.Task, true).Times(1),
+					mr.HostManager().Return(hostManager).Times(1),
+					mh.Load(gomock.Eq(mockPeer.Host.ID)).Return(mockPeer.Host, true).Times(1),
+				)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error) {
+				assert := assert.New(t)
+				dferr, ok := err.(*dferrors.DfError)
+				assert.True(ok)
+				assert.Equal(commonv1.Code_SchedForbidden, dferr.Code)
+				assert.Nil(result)
+			},
+		},
 		{
 			name: "task state is TaskStateRunning and it has available peer",
 			req: &schedulerv1.PeerTaskRequest{
@@ -441,6 +513,50 @@ func TestServiceV1_RegisterPeerTask(t *testing.T) {
 				assert.Equal(peer.FSM.Current(), resource.PeerStateLeave)
 			},
 		},
+		{
+			name: "task state is TaskStateFailed and origin returned 404",
+			req: &schedulerv1.PeerTaskRequest{
+				UrlMeta: &commonv1.UrlMeta{
+					Priority: commonv1.Priority_LEVEL0,
+				},
+				PeerHost: &schedulerv1.PeerHost{
+					Id: mockRawHost.ID,
+				},
+			},
+			mock: func(
+				req *schedulerv1.PeerTaskRequest, mockPeer *resource.Peer, mockSeedPeer *resource.Peer,
+				scheduler scheduling.Scheduling, res resource.Resource, hostManager resource.HostManager, taskManager resource.TaskManager, peerManager resource.PeerManager,
+				ms *mocks.MockSchedulingMockRecorder, mr *resource.MockResourceMockRecorder, mh *resource.MockHostManagerMockRecorder, mt *resource.MockTaskManagerMockRecorder,
+				mp *resource.MockPeerManagerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder,
+			) {
+				mockPeer.Task = resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithBackToSourceCooldown(time.Minute))
+				mockPeer.Task.FSM.SetState(resource.TaskStateRunning)
+				failure := resource.NewTaskFailure(resource.TaskFailureReasonSourceError, "")
+				failure.StatusCode = 404
+				failure.Temporary = false
+				mockPeer.Task.SetFailure(failure)
+				mockPeer.Task.FSM.SetState(resource.TaskStateFailed)
+				gomock.InOrder(
+					mr.TaskManager().Return(taskManager).Times(1),
+					mt.Load(gomock.Any()).Return(mockPeer.Task, true).Times(1),
+					mr.HostManager().Return(hostManager).Times(1),
+					mh.Load(gomock.Eq(mockPeer.Host.ID)).Return(mockPeer.Host, true).Times(1),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Any()).Return(mockPeer, true).Times(1),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Delete(gomock.Any()).Return().Times(1),
+				)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error) {
+				assert := assert.New(t)
+				dferr, ok := err.(*dferrors.DfError)
+				assert.True(ok)
+				assert.Equal(dferr.Code, commonv1.Code_BackToSourceAborted)
+				assert.Equal(dferr.Message, "origin returned 404")
+				assert.Equal(peer.FSM.Current(), resource.PeerStateLeave)
+				assert.True(peer.Task.FSM.Is(resource.TaskStateFailed))
+			},
+		},
 		{
 			name: "task state is TaskStateRunning and peer state is PeerStateFailed",
 			req: &schedulerv1.PeerTaskRequest{
@@ -1010,6 +1126,8 @@ func TestServiceV1_RegisterPeerTask(t *testing.T) {
 			taskManager := resource.NewMockTaskManager(ctl)
 			peerManager := resource.NewMockPeerManager(ctl)
 			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
+			dynconfig.EXPECT().GetClientFeatureFlags().Return(nil).AnyTimes()
 
 			mockHost := resource.NewHost(
 				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
@@ -1208,6 +1326,49 @@ func TestServiceV1_ReportPieceResult(t *testing.T) {
 				assert.False(loaded)
 			},
 		},
+		{
+			name: "revice impossible pieces",
+			mock: func(
+				mockPeer *resource.Peer,
+				res resource.Resource, peerManager resource.PeerManager,
+				mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_ReportPieceResultServerMockRecorder,
+
+			) {
+				mockPeer.Task.TotalPieceCount.Store(2)
+				gomock.InOrder(
+					ms.Context().Return(context.Background()).Times(1),
+					ms.Recv().Return(&schedulerv1.PieceResult{
+						SrcPid:        mockPeerID,
+						Success:       true,
+						FinishedCount: 3,
+						PieceInfo: &commonv1.PieceInfo{
+							PieceNum: 1,
+						},
+					}, nil).Times(1),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(mockPeerID)).Return(mockPeer, true).Times(1),
+					ms.Recv().Return(&schedulerv1.PieceResult{
+						SrcPid:    mockPeerID,
+						Success:   true,
+						BeginTime: 2,
+						EndTime:   1,
+						PieceInfo: &commonv1.PieceInfo{
+							PieceNum: 0,
+						},
+					}, nil).Times(1),
+					ms.Recv().Return(nil, io.EOF).Times(1),
+				)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(peer.Quarantined.Load())
+				assert.Equal(peer.PieceViolationCount.Load(), int32(2))
+				assert.Equal(peer.FinishedPieces.Count(), uint(2))
+				assert.Equal(len(peer.PieceCosts()), 2)
+				assert.Equal(peer.ReportedFinishedCount.Load(), int32(2))
+			},
+		},
 		{
 			name: "revice Code_ClientWaitPieceReady code",
 			mock: func(
@@ -1341,6 +1502,36 @@ func TestServiceV1_ReportPeerResult(t *testing.T) {
 				assert.NoError(err)
 			},
 		},
+		{
+			name: "receive peer failed and peer is quarantined",
+			req: &schedulerv1.PeerResult{
+				Success: false,
+				PeerId:  mockPeerID,
+			},
+			run: func(t *testing.T, peer *resource.Peer, req *schedulerv1.PeerResult, svc *V1, mockPeer *resource.Peer, res resource.Resource, peerManager resource.PeerManager,
+				mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder, ms *storagemocks.MockStorageMockRecorder,
+				md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				var wg sync.WaitGroup
+				wg.Add(1)
+				defer wg.Wait()
+
+				assert := assert.New(t)
+				mockPeer.FSM.SetState(resource.PeerStateFailed)
+				mockPeer.Quarantined.Store(true)
+				gomock.InOrder(
+					mr.PeerManager().Return(peerManager).Times(1),
+					mp.Load(gomock.Eq(mockPeerID)).Return(mockPeer, true).Times(1),
+					md.GetApplications().Return([]*managerv2.Application{}, nil).Times(1),
+					ms.CreateDownload(gomock.Any()).Do(func(download storage.Download) {
+						defer wg.Done()
+						assert.True(download.Quarantined)
+					}).Return(nil).Times(1),
+				)
+
+				err := svc.ReportPeerResult(context.Background(), req)
+				assert.NoError(err)
+			},
+		},
 		{
 			name: "receive peer failed and peer state is PeerStateBackToSource",
 			req: &schedulerv1.PeerResult{
@@ -1458,6 +1649,7 @@ func TestServiceV1_ReportPeerResult(t *testing.T) {
 			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
 			peerManager := resource.NewMockPeerManager(ctl)
 			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
 
 			mockHost := resource.NewHost(
 				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
@@ -1820,6 +2012,7 @@ func TestServiceV1_AnnounceTask(t *testing.T) {
 			taskManager := resource.NewMockTaskManager(ctl)
 			peerManager := resource.NewMockPeerManager(ctl)
 			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig, Metrics: config.MetricsConfig{EnableHost: true}}, res, scheduling, dynconfig, storage, networkTopology)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
 			mockHost := resource.NewHost(
 				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
 				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
@@ -2459,6 +2652,193 @@ func TestServiceV1_AnnounceHost(t *testing.T) {
 	}
 }
 
+func TestServiceV1_AnnounceHostRestarted(t *testing.T) {
+	startedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		startedAt time.Time
+		mock      func(host *resource.Host, peer *resource.Peer, child *resource.Peer, ms *mocks.MockSchedulingMockRecorder)
+		expect    func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer)
+	}{
+		{
+			name:      "host restarted and peers are reaped",
+			startedAt: startedAt.Add(time.Minute),
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer, ms *mocks.MockSchedulingMockRecorder) {
+				host.StartedAt.Store(startedAt)
+				ms.ScheduleParentAndCandidateParents(gomock.Any(), gomock.Eq(child), gomock.Eq(set.NewSafeSet[string]())).Return().Times(1)
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateLeave))
+				assert.True(child.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(0), host.ConcurrentUploadCount.Load())
+				assert.Equal(int64(0), host.UploadCount.Load())
+				assert.Equal(int64(0), host.UploadFailedCount.Load())
+				assert.True(host.StartedAt.Load().Equal(startedAt.Add(time.Minute)))
+
+				degree, err := peer.Task.PeerInDegree(child.ID)
+				assert.NoError(err)
+				assert.Equal(0, degree)
+			},
+		},
+		{
+			name:      "host restarted and peers registered after the restart are kept",
+			startedAt: startedAt.Add(time.Minute),
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer, ms *mocks.MockSchedulingMockRecorder) {
+				host.StartedAt.Store(startedAt)
+
+				// The peer registers after the restart, before the host announces again.
+				newPeer := resource.NewPeer(mockPeerID+"-new", mockResourceConfig, peer.Task, host)
+				newPeer.FSM.SetState(resource.PeerStateRunning)
+				peer.Task.StorePeer(newPeer)
+				host.StorePeer(newPeer)
+				peer.Task.AddPeerEdge(newPeer, child) // nolint: errcheck
+
+				ms.ScheduleParentAndCandidateParents(gomock.Any(), gomock.Eq(child), gomock.Eq(set.NewSafeSet[string]())).Return().Times(1)
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateLeave))
+
+				newPeer, loaded := host.LoadPeer(mockPeerID + "-new")
+				assert.True(loaded)
+				assert.True(newPeer.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(1), host.ConcurrentUploadCount.Load())
+				assert.Equal(int64(0), host.UploadCount.Load())
+				assert.Equal(int64(0), host.UploadFailedCount.Load())
+
+				degree, err := peer.Task.PeerInDegree(child.ID)
+				assert.NoError(err)
+				assert.Equal(1, degree)
+			},
+		},
+		{
+			name:      "host restarted and clock of the daemon is ahead of the scheduler",
+			startedAt: startedAt.Add(24 * time.Hour),
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer, ms *mocks.MockSchedulingMockRecorder) {
+				host.StartedAt.Store(startedAt)
+
+				// The peer registers after the restart, before the host announces again.
+				newPeer := resource.NewPeer(mockPeerID+"-new", mockResourceConfig, peer.Task, host)
+				newPeer.FSM.SetState(resource.PeerStateRunning)
+				peer.Task.StorePeer(newPeer)
+				host.StorePeer(newPeer)
+
+				ms.ScheduleParentAndCandidateParents(gomock.Any(), gomock.Eq(child), gomock.Eq(set.NewSafeSet[string]())).Return().Times(1)
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateLeave))
+
+				newPeer, loaded := host.LoadPeer(mockPeerID + "-new")
+				assert.True(loaded)
+				assert.True(newPeer.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(0), host.ConcurrentUploadCount.Load())
+				assert.True(host.StartedAt.Load().Equal(startedAt.Add(24 * time.Hour)))
+			},
+		},
+		{
+			name:      "host is not restarted",
+			startedAt: startedAt,
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer, ms *mocks.MockSchedulingMockRecorder) {
+				host.StartedAt.Store(startedAt)
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(1), host.ConcurrentUploadCount.Load())
+				assert.Equal(int64(1), host.UploadCount.Load())
+				assert.Equal(int64(1), host.UploadFailedCount.Load())
+				assert.True(host.StartedAt.Load().Equal(startedAt))
+			},
+		},
+		{
+			name:      "start time of host is older than the recorded one",
+			startedAt: startedAt.Add(-time.Minute),
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer, ms *mocks.MockSchedulingMockRecorder) {
+				host.StartedAt.Store(startedAt)
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(1), host.ConcurrentUploadCount.Load())
+				assert.True(host.StartedAt.Load().Equal(startedAt))
+			},
+		},
+		{
+			name:      "start time of host is not recorded",
+			startedAt: startedAt,
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer, ms *mocks.MockSchedulingMockRecorder) {
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(1), host.ConcurrentUploadCount.Load())
+				assert.True(host.StartedAt.Load().Equal(startedAt))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := mocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			hostManager := resource.NewMockHostManager(ctl)
+			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig, Metrics: config.MetricsConfig{EnableHost: true}}, res, scheduling, dynconfig, storage, networkTopology)
+
+			host := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			childHost := resource.NewHost(
+				mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+				mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+
+			// The peer of the host before the restart uploads to the child.
+			peer := resource.NewPeer(mockSeedPeerID, mockResourceConfig, mockTask, host)
+			peer.CreatedAt.Store(startedAt)
+			child := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, childHost)
+			mockTask.StorePeer(peer)
+			mockTask.StorePeer(child)
+			host.StorePeer(peer)
+			if err := mockTask.AddPeerEdge(peer, child); err != nil {
+				t.Fatal(err)
+			}
+			peer.FSM.SetState(resource.PeerStateRunning)
+			child.FSM.SetState(resource.PeerStateRunning)
+			host.UploadFailedCount.Inc()
+
+			tc.mock(host, peer, child, scheduling.EXPECT())
+			gomock.InOrder(
+				dynconfig.EXPECT().GetSchedulerClusterClientConfig().Return(types.SchedulerClusterClientConfig{}, errors.New("foo")).Times(1),
+				res.EXPECT().HostManager().Return(hostManager).Times(1),
+				hostManager.EXPECT().Load(gomock.Eq(host.ID)).Return(host, true).Times(1),
+			)
+
+			// The daemon restarted half an hour ago, after the peer of the host was created.
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				rpc.HostStartedAtMetadataKey, tc.startedAt.Format(time.RFC3339Nano),
+				rpc.HostUptimeMetadataKey, (30*time.Minute).String(),
+			))
+			assert.NoError(t, svc.AnnounceHost(ctx, &schedulerv1.AnnounceHostRequest{
+				Id:           host.ID,
+				Type:         pkgtypes.HostTypeNormal.Name(),
+				Hostname:     host.Hostname,
+				Ip:           host.IP,
+				Port:         host.Port,
+				DownloadPort: host.DownloadPort,
+			}))
+			tc.expect(t, host, peer, child)
+		})
+	}
+}
+
 func TestServiceV1_LeaveHost(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -2693,14 +3073,14 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 		name string
 		mock func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 			mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-			ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder)
+			peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder)
 		expect func(t *testing.T, err error)
 	}{
 		{
 			name: "network topology is not enabled",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				svc.networkTopology = nil
 			},
 			expect: func(t *testing.T, err error) {
@@ -2712,7 +3092,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "synchronize probes when receive ProbeStartedRequest",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				gomock.InOrder(
 					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
 						Host: &commonv1.Host{
@@ -2754,7 +3134,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "synchronize probes when receive ProbeFinishedRequest",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				gomock.InOrder(
 					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
 						Host: &commonv1.Host{
@@ -2790,10 +3170,12 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			},
 		},
 		{
-			name: "synchronize probes when receive ProbeFailedRequest",
+			name: "synchronize probes of parent when receive ProbeFinishedRequest",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+				mockPeer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, &mockRawHost)
 				gomock.InOrder(
 					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
 						Host: &commonv1.Host{
@@ -2805,25 +3187,33 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 							Location:     mockRawSeedHost.Network.Location,
 							Idc:          mockRawSeedHost.Network.IDC,
 						},
-						Request: &schedulerv1.SyncProbesRequest_ProbeFailedRequest{
-							ProbeFailedRequest: &schedulerv1.ProbeFailedRequest{
-								Probes: []*schedulerv1.FailedProbe{
+						Request: &schedulerv1.SyncProbesRequest_ProbeFinishedRequest{
+							ProbeFinishedRequest: &schedulerv1.ProbeFinishedRequest{
+								Probes: []*schedulerv1.Probe{
 									{
 										Host: &commonv1.Host{
-											Id:           mockRawHost.ID,
+											Id:           mockPeerID,
 											Ip:           mockRawHost.IP,
-											Hostname:     mockRawHost.Hostname,
-											Port:         mockRawHost.Port,
 											DownloadPort: mockRawHost.DownloadPort,
-											Location:     mockRawHost.Network.Location,
-											Idc:          mockRawHost.Network.IDC,
 										},
-										Description: "foo",
+										Rtt:       mockV1Probe.Rtt,
+										CreatedAt: mockV1Probe.CreatedAt,
 									},
 								},
 							},
 						},
 					}, nil).Times(1),
+					mr.HostManager().Return(hostManager).Times(1),
+					mh.Load(gomock.Eq(mockPeerID)).Return(nil, false),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mpm.Load(gomock.Eq(mockPeerID)).Return(mockPeer, true).Times(1),
+					mn.Store(gomock.Eq(mockRawSeedHost.ID), gomock.Eq(mockRawHost.ID)).Return(nil).Times(1),
+					mn.Probes(gomock.Eq(mockRawSeedHost.ID), gomock.Eq(mockRawHost.ID)).Return(probes).Times(1),
+					mp.Enqueue(gomock.Eq(&networktopology.Probe{
+						Host:      &mockRawHost,
+						RTT:       mockV1Probe.Rtt.AsDuration(),
+						CreatedAt: mockV1Probe.CreatedAt.AsTime(),
+					})).Return(nil).Times(1),
 					ms.Recv().Return(nil, io.EOF).Times(1),
 				)
 			},
@@ -2833,19 +3223,62 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			},
 		},
 		{
-			name: "synchronize probes when receive fail type request",
+			name: "synchronize probes when receive ProbeFailedRequest",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
-				ms.Recv().Return(&schedulerv1.SyncProbesRequest{
-					Host: &commonv1.Host{
-						Id:           mockRawSeedHost.ID,
-						Ip:           mockRawSeedHost.IP,
-						Hostname:     mockRawSeedHost.Hostname,
-						Port:         mockRawSeedHost.Port,
-						DownloadPort: mockRawSeedHost.DownloadPort,
-						Location:     mockRawSeedHost.Network.Location,
-						Idc:          mockRawSeedHost.Network.IDC,
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				gomock.InOrder(
+					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
+						Host: &commonv1.Host{
+							Id:           mockRawSeedHost.ID,
+							Ip:           mockRawSeedHost.IP,
+							Hostname:     mockRawSeedHost.Hostname,
+							Port:         mockRawSeedHost.Port,
+							DownloadPort: mockRawSeedHost.DownloadPort,
+							Location:     mockRawSeedHost.Network.Location,
+							Idc:          mockRawSeedHost.Network.IDC,
+						},
+						Request: &schedulerv1.SyncProbesRequest_ProbeFailedRequest{
+							ProbeFailedRequest: &schedulerv1.ProbeFailedRequest{
+								Probes: []*schedulerv1.FailedProbe{
+									{
+										Host: &commonv1.Host{
+											Id:           mockRawHost.ID,
+											Ip:           mockRawHost.IP,
+											Hostname:     mockRawHost.Hostname,
+											Port:         mockRawHost.Port,
+											DownloadPort: mockRawHost.DownloadPort,
+											Location:     mockRawHost.Network.Location,
+											Idc:          mockRawHost.Network.IDC,
+										},
+										Description: "foo",
+									},
+								},
+							},
+						},
+					}, nil).Times(1),
+					ms.Recv().Return(nil, io.EOF).Times(1),
+				)
+			},
+			expect: func(t *testing.T, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name: "synchronize probes when receive fail type request",
+			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
+				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				ms.Recv().Return(&schedulerv1.SyncProbesRequest{
+					Host: &commonv1.Host{
+						Id:           mockRawSeedHost.ID,
+						Ip:           mockRawSeedHost.IP,
+						Hostname:     mockRawSeedHost.Hostname,
+						Port:         mockRawSeedHost.Port,
+						DownloadPort: mockRawSeedHost.DownloadPort,
+						Location:     mockRawSeedHost.Network.Location,
+						Idc:          mockRawSeedHost.Network.IDC,
 					},
 					Request: nil,
 				}, nil).Times(1)
@@ -2859,7 +3292,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "receive error",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				ms.Recv().Return(nil, errors.New("receive error")).Times(1)
 			},
 			expect: func(t *testing.T, err error) {
@@ -2871,7 +3304,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "receive end of file",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				ms.Recv().Return(nil, io.EOF).Times(1)
 			},
 			expect: func(t *testing.T, err error) {
@@ -2883,7 +3316,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "find probed host ids error",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				gomock.InOrder(
 					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
 						Host: &commonv1.Host{
@@ -2911,7 +3344,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "send synchronize probes response error",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				gomock.InOrder(
 					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
 						Host: &commonv1.Host{
@@ -2952,7 +3385,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "load host error when receive ProbeFinishedRequest",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				gomock.InOrder(
 					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
 						Host: &commonv1.Host{
@@ -2972,6 +3405,8 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 					}, nil).Times(1),
 					mr.HostManager().Return(hostManager).Times(1),
 					mh.Load(gomock.Eq(mockRawHost.ID)).Return(nil, false),
+					mr.PeerManager().Return(peerManager).Times(1),
+					mpm.Load(gomock.Eq(mockRawHost.ID)).Return(nil, false).Times(1),
 					ms.Recv().Return(nil, io.EOF).Times(1),
 				)
 			},
@@ -2984,7 +3419,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "store error when receive ProbeFinishedRequest",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				gomock.InOrder(
 					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
 						Host: &commonv1.Host{
@@ -3017,7 +3452,7 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			name: "enqueue probe error when receive ProbeFinishedRequest",
 			mock: func(svc *V1, mr *resource.MockResourceMockRecorder, probes *networktopologymocks.MockProbes, mp *networktopologymocks.MockProbesMockRecorder,
 				mn *networktopologymocks.MockNetworkTopologyMockRecorder, hostManager resource.HostManager, mh *resource.MockHostManagerMockRecorder,
-				ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
+				peerManager resource.PeerManager, mpm *resource.MockPeerManagerMockRecorder, ms *schedulerv1mocks.MockScheduler_SyncProbesServerMockRecorder) {
 				gomock.InOrder(
 					ms.Recv().Return(&schedulerv1.SyncProbesRequest{
 						Host: &commonv1.Host{
@@ -3062,10 +3497,11 @@ func TestServiceV1_SyncProbes(t *testing.T) {
 			probes := networktopologymocks.NewMockProbes(ctl)
 			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
 			hostManager := resource.NewMockHostManager(ctl)
+			peerManager := resource.NewMockPeerManager(ctl)
 			stream := schedulerv1mocks.NewMockScheduler_SyncProbesServer(ctl)
 			svc := NewV1(&config.Config{Scheduler: config.SchedulerConfig{NetworkTopology: mockNetworkTopologyConfig}, Metrics: config.MetricsConfig{EnableHost: true}}, res, scheduling, dynconfig, storage, networkTopology)
 
-			tc.mock(svc, res.EXPECT(), probes, probes.EXPECT(), networkTopology.EXPECT(), hostManager, hostManager.EXPECT(), stream.EXPECT())
+			tc.mock(svc, res.EXPECT(), probes, probes.EXPECT(), networkTopology.EXPECT(), hostManager, hostManager.EXPECT(), peerManager, peerManager.EXPECT(), stream.EXPECT())
 			tc.expect(t, svc.SyncProbes(stream))
 		})
 	}
@@ -3077,7 +3513,7 @@ func TestServiceV1_prefetchTask(t *testing.T) {
 		name   string
 		config *config.Config
 		req    *schedulerv1.PeerTaskRequest
-		mock   func(task *resource.Task, peer *resource.Peer, taskManager resource.TaskManager, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, mc *resource.MockSeedPeerMockRecorder)
+		mock   func(task *resource.Task, peer *resource.Peer, taskManager resource.TaskManager, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, mc *resource.MockSeedPeerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder)
 		expect func(t *testing.T, task *resource.Task, err error)
 	}{
 		{
@@ -3103,7 +3539,7 @@ func TestServiceV1_prefetchTask(t *testing.T) {
 				IsMigrating: false,
 				TaskId:      mockTaskID,
 			},
-			mock: func(task *resource.Task, peer *resource.Peer, taskManager resource.TaskManager, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, mc *resource.MockSeedPeerMockRecorder) {
+			mock: func(task *resource.Task, peer *resource.Peer, taskManager resource.TaskManager, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, mc *resource.MockSeedPeerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder) {
 				task.FSM.SetState(resource.TaskStateRunning)
 				peer.FSM.SetState(resource.PeerStateRunning)
 				gomock.InOrder(
@@ -3111,6 +3547,7 @@ func TestServiceV1_prefetchTask(t *testing.T) {
 					mt.Load(gomock.Eq("7aecbd0437cf6b429dc623686d36208135b3d2d1831a90b644458964297943a4")).Return(task, true).Times(1),
 					mr.SeedPeer().Return(seedPeer).Times(1),
 					mc.TriggerTask(gomock.Any(), gomock.Any(), gomock.Any()).Return(peer, &schedulerv1.PeerResult{}, nil).Times(1),
+					md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, nil).Times(1),
 				)
 			},
 			expect: func(t *testing.T, task *resource.Task, err error) {
@@ -3141,7 +3578,7 @@ func TestServiceV1_prefetchTask(t *testing.T) {
 				IsMigrating: false,
 				TaskId:      mockTaskID,
 			},
-			mock: func(task *resource.Task, peer *resource.Peer, taskManager resource.TaskManager, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, mc *resource.MockSeedPeerMockRecorder) {
+			mock: func(task *resource.Task, peer *resource.Peer, taskManager resource.TaskManager, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, mc *resource.MockSeedPeerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder) {
 				task.FSM.SetState(resource.TaskStateRunning)
 				peer.FSM.SetState(resource.PeerStateRunning)
 			},
@@ -3170,7 +3607,7 @@ func TestServiceV1_prefetchTask(t *testing.T) {
 			svc := NewV1(tc.config, res, scheduling, dynconfig, storage, networkTopology)
 			taskManager := resource.NewMockTaskManager(ctl)
 
-			tc.mock(task, peer, taskManager, seedPeer, res.EXPECT(), taskManager.EXPECT(), seedPeer.EXPECT())
+			tc.mock(task, peer, taskManager, seedPeer, res.EXPECT(), taskManager.EXPECT(), seedPeer.EXPECT(), dynconfig.EXPECT())
 			task, err := svc.prefetchTask(context.Background(), tc.req)
 			tc.expect(t, task, err)
 		})
@@ -3363,6 +3800,31 @@ func TestServiceV1_triggerTask(t *testing.T) {
 				assert.Equal(mockTask.FSM.Current(), resource.TaskStateRunning)
 			},
 		},
+		{
+			name: "task is restored and seed peer has been restored",
+			config: &config.Config{
+				Scheduler: mockSchedulerConfig,
+				SeedPeer: config.SeedPeerConfig{
+					Enable: true,
+				},
+			},
+			run: func(t *testing.T, svc *V1, mockTask *resource.Task, mockHost *resource.Host, mockPeer *resource.Peer, mockSeedPeer *resource.Peer, dynconfig config.DynconfigInterface, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mc *resource.MockSeedPeerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				// The seed peer of the restored task has been restored in the background.
+				mockTask.FSM.SetState(resource.TaskStateSucceeded)
+				mockSeedPeer.FSM.SetState(resource.PeerStateSucceeded)
+				mockTask.StorePeer(mockSeedPeer)
+
+				err := svc.triggerTask(context.Background(), &schedulerv1.PeerTaskRequest{
+					UrlMeta: &commonv1.UrlMeta{
+						Priority: commonv1.Priority_LEVEL0,
+					},
+				}, mockTask, mockHost, mockPeer, dynconfig)
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockPeer.NeedBackToSource.Load(), false)
+				assert.Equal(mockTask.FSM.Current(), resource.TaskStateSucceeded)
+			},
+		},
 		{
 			name: "priority is Priority_LEVEL6 and seed peer downloads failed",
 			config: &config.Config{
@@ -3629,6 +4091,7 @@ func TestServiceV1_triggerTask(t *testing.T) {
 			storage := storagemocks.NewMockStorage(ctl)
 			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
 			svc := NewV1(tc.config, res, scheduling, dynconfig, storage, networkTopology)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
 
 			mockHost := resource.NewHost(
 				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
@@ -3648,11 +4111,11 @@ func TestServiceV1_triggerTask(t *testing.T) {
 func TestServiceV1_storeTask(t *testing.T) {
 	tests := []struct {
 		name string
-		run  func(t *testing.T, svc *V1, taskManager resource.TaskManager, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder)
+		run  func(t *testing.T, svc *V1, taskManager resource.TaskManager, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder)
 	}{
 		{
 			name: "task already exists",
-			run: func(t *testing.T, svc *V1, taskManager resource.TaskManager, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder) {
+			run: func(t *testing.T, svc *V1, taskManager resource.TaskManager, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder) {
 				mockTask := resource.NewTask(mockTaskID, "", mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, nil, nil, mockTaskBackToSourceLimit)
 
 				gomock.InOrder(
@@ -3677,10 +4140,11 @@ func TestServiceV1_storeTask(t *testing.T) {
 		},
 		{
 			name: "task does not exist",
-			run: func(t *testing.T, svc *V1, taskManager resource.TaskManager, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder) {
+			run: func(t *testing.T, svc *V1, taskManager resource.TaskManager, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder) {
 				gomock.InOrder(
 					mr.TaskManager().Return(taskManager).Times(1),
 					mt.Load(gomock.Eq(mockTaskID)).Return(nil, false).Times(1),
+					md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).Times(1),
 					mr.TaskManager().Return(taskManager).Times(1),
 					mt.Store(gomock.Any()).Return().Times(1),
 				)
@@ -3722,6 +4186,66 @@ func TestServiceV1_storeTask(t *testing.T) {
 				assert.NotNil(task.Log)
 			},
 		},
+		{
+			name: "task does not exist and back-to-source count of the cluster is set",
+			run: func(t *testing.T, svc *V1, taskManager resource.TaskManager, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				gomock.InOrder(
+					mr.TaskManager().Return(taskManager).Times(1),
+					mt.Load(gomock.Eq(mockTaskID)).Return(nil, false).Times(1),
+					md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{
+						BackToSourceCount: 10,
+						BackToSourceApplications: []types.BackToSourceApplication{
+							{Name: "bar", BackToSourceCount: 1},
+						},
+					}, nil).Times(1),
+					mr.TaskManager().Return(taskManager).Times(1),
+					mt.Store(gomock.Any()).Return().Times(1),
+				)
+
+				task := svc.storeTask(context.Background(), &schedulerv1.PeerTaskRequest{
+					TaskId: mockTaskID,
+					Url:    mockTaskURL,
+					UrlMeta: &commonv1.UrlMeta{
+						Priority:    commonv1.Priority_LEVEL0,
+						Application: mockTaskApplication,
+					},
+					PeerHost: mockPeerHost,
+				}, commonv2.TaskType_DFDAEMON)
+
+				assert := assert.New(t)
+				assert.Equal(task.BackToSourceLimit.Load(), int32(10))
+			},
+		},
+		{
+			name: "task does not exist and back-to-source count of the application is set",
+			run: func(t *testing.T, svc *V1, taskManager resource.TaskManager, mr *resource.MockResourceMockRecorder, mt *resource.MockTaskManagerMockRecorder, md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				gomock.InOrder(
+					mr.TaskManager().Return(taskManager).Times(1),
+					mt.Load(gomock.Eq(mockTaskID)).Return(nil, false).Times(1),
+					md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{
+						BackToSourceCount: 10,
+						BackToSourceApplications: []types.BackToSourceApplication{
+							{Name: mockTaskApplication, BackToSourceCount: 1},
+						},
+					}, nil).Times(1),
+					mr.TaskManager().Return(taskManager).Times(1),
+					mt.Store(gomock.Any()).Return().Times(1),
+				)
+
+				task := svc.storeTask(context.Background(), &schedulerv1.PeerTaskRequest{
+					TaskId: mockTaskID,
+					Url:    mockTaskURL,
+					UrlMeta: &commonv1.UrlMeta{
+						Priority:    commonv1.Priority_LEVEL0,
+						Application: mockTaskApplication,
+					},
+					PeerHost: mockPeerHost,
+				}, commonv2.TaskType_DFDAEMON)
+
+				assert := assert.New(t)
+				assert.Equal(task.BackToSourceLimit.Load(), int32(1))
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -3735,7 +4259,7 @@ func TestServiceV1_storeTask(t *testing.T) {
 			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
 			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
 			taskManager := resource.NewMockTaskManager(ctl)
-			tc.run(t, svc, taskManager, res.EXPECT(), taskManager.EXPECT())
+			tc.run(t, svc, taskManager, res.EXPECT(), taskManager.EXPECT(), dynconfig.EXPECT())
 		})
 	}
 }
@@ -3844,7 +4368,7 @@ func TestServiceV1_storePeer(t *testing.T) {
 					mp.Load(gomock.Eq(mockPeerID)).Return(mockPeer, true).Times(1),
 				)
 
-				peer := svc.storePeer(context.Background(), mockPeerID, commonv1.Priority_LEVEL0, mockURLMetaRange, mockTask, mockHost)
+				peer := svc.storePeer(context.Background(), mockPeerID, commonv1.Priority_LEVEL0, mockURLMetaRange, false, mockTask, mockHost)
 
 				assert := assert.New(t)
 				assert.EqualValues(peer, mockPeer)
@@ -3864,12 +4388,13 @@ func TestServiceV1_storePeer(t *testing.T) {
 					mp.Store(gomock.Any()).Return().Times(1),
 				)
 
-				peer := svc.storePeer(context.Background(), mockPeerID, commonv1.Priority_LEVEL1, mockURLMetaRange, mockTask, mockHost)
+				peer := svc.storePeer(context.Background(), mockPeerID, commonv1.Priority_LEVEL1, mockURLMetaRange, true, mockTask, mockHost)
 
 				assert := assert.New(t)
 				assert.Equal(peer.ID, mockPeerID)
 				assert.EqualValues(peer.Range, &mockPeerRange)
 				assert.Equal(peer.Priority, commonv2.Priority_LEVEL1)
+				assert.True(peer.PreferSeed)
 				assert.Empty(peer.Pieces)
 				assert.Empty(peer.FinishedPieces)
 				assert.Equal(len(peer.PieceCosts()), 0)
@@ -3878,7 +4403,7 @@ func TestServiceV1_storePeer(t *testing.T) {
 				assert.Equal(peer.FSM.Current(), resource.PeerStatePending)
 				assert.EqualValues(peer.Task, mockTask)
 				assert.EqualValues(peer.Host, mockHost)
-				assert.Equal(peer.BlockParents.Len(), uint(0))
+				assert.Equal(peer.BlockParents.ItemCount(), 0)
 				assert.Equal(peer.NeedBackToSource.Load(), false)
 				assert.NotEqual(peer.PieceUpdatedAt.Load(), 0)
 				assert.NotEqual(peer.CreatedAt.Load(), 0)
@@ -3905,45 +4430,64 @@ func TestServiceV1_storePeer(t *testing.T) {
 	}
 }
 
-func TestServiceV1_triggerSeedPeerTask(t *testing.T) {
+func TestServiceV1_negotiatePieceCodec(t *testing.T) {
 	tests := []struct {
 		name   string
-		mock   func(task *resource.Task, peer *resource.Peer, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mc *resource.MockSeedPeerMockRecorder)
-		expect func(t *testing.T, task *resource.Task, peer *resource.Peer)
+		codecs []string
+		mock   func(md *configmocks.MockDynconfigInterfaceMockRecorder)
+		expect func(t *testing.T, codec string)
 	}{
 		{
-			name: "trigger seed peer task",
-			mock: func(task *resource.Task, peer *resource.Peer, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mc *resource.MockSeedPeerMockRecorder) {
-				task.FSM.SetState(resource.TaskStateRunning)
-				peer.FSM.SetState(resource.PeerStateRunning)
-				gomock.InOrder(
-					mr.SeedPeer().Return(seedPeer).Times(1),
-					mc.TriggerTask(gomock.Any(), gomock.Any(), gomock.Any()).Return(peer, &schedulerv1.PeerResult{
-						TotalPieceCount: 3,
-						ContentLength:   1024,
-					}, nil).Times(1),
-				)
+			name:   "application enables piece compression and host supports the codec",
+			codecs: []string{"br", compression.CodecZstd},
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{PieceCompressionApplications: []string{mockTaskApplication}}, nil).Times(1)
 			},
-			expect: func(t *testing.T, task *resource.Task, peer *resource.Peer) {
+			expect: func(t *testing.T, codec string) {
 				assert := assert.New(t)
-				assert.True(task.FSM.Is(resource.TaskStateSucceeded))
-				assert.Equal(task.TotalPieceCount.Load(), int32(3))
-				assert.Equal(task.ContentLength.Load(), int64(1024))
-				assert.True(peer.FSM.Is(resource.PeerStateSucceeded))
+				assert.Equal(compression.CodecZstd, codec)
 			},
 		},
 		{
-			name: "trigger seed peer task failed",
-			mock: func(task *resource.Task, peer *resource.Peer, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mc *resource.MockSeedPeerMockRecorder) {
-				task.FSM.SetState(resource.TaskStateRunning)
-				gomock.InOrder(
-					mr.SeedPeer().Return(seedPeer).Times(1),
-					mc.TriggerTask(gomock.Any(), gomock.Any(), gomock.Any()).Return(peer, &schedulerv1.PeerResult{}, errors.New("foo")).Times(1),
-				)
+			name:   "host does not support any codec",
+			codecs: []string{},
+			mock:   func(md *configmocks.MockDynconfigInterfaceMockRecorder) {},
+			expect: func(t *testing.T, codec string) {
+				assert := assert.New(t)
+				assert.Empty(codec)
 			},
-			expect: func(t *testing.T, task *resource.Task, peer *resource.Peer) {
+		},
+		{
+			name:   "host does not support the codec",
+			codecs: []string{"br"},
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{PieceCompressionApplications: []string{mockTaskApplication}}, nil).Times(1)
+			},
+			expect: func(t *testing.T, codec string) {
 				assert := assert.New(t)
-				assert.True(task.FSM.Is(resource.TaskStateFailed))
+				assert.Empty(codec)
+			},
+		},
+		{
+			name:   "application does not enable piece compression",
+			codecs: []string{compression.CodecZstd},
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{PieceCompressionApplications: []string{"bar"}}, nil).Times(1)
+			},
+			expect: func(t *testing.T, codec string) {
+				assert := assert.New(t)
+				assert.Empty(codec)
+			},
+		},
+		{
+			name:   "dynconfig get scheduler cluster config failed",
+			codecs: []string{compression.CodecZstd},
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, codec string) {
+				assert := assert.New(t)
+				assert.Empty(codec)
 			},
 		},
 	}
@@ -3957,76 +4501,46 @@ func TestServiceV1_triggerSeedPeerTask(t *testing.T) {
 			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
 			storage := storagemocks.NewMockStorage(ctl)
 			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
-			seedPeer := resource.NewMockSeedPeer(ctl)
+			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+
 			mockHost := resource.NewHost(
 				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
-				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
-			task := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
-			peer := resource.NewPeer(mockPeerID, mockResourceConfig, task, mockHost)
-			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig, SeedPeer: mockSeedPeerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type, resource.WithPieceCodecs(tc.codecs))
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			mockPeer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
 
-			tc.mock(task, peer, seedPeer, res.EXPECT(), seedPeer.EXPECT())
-			svc.triggerSeedPeerTask(context.Background(), &mockPeerRange, task)
-			tc.expect(t, task, peer)
+			tc.mock(dynconfig.EXPECT())
+			tc.expect(t, svc.negotiatePieceCodec(mockPeer))
 		})
 	}
 }
 
-func TestServiceV1_handleBeginOfPiece(t *testing.T) {
+func TestServiceV1_setFeatureFlagsHeader(t *testing.T) {
 	tests := []struct {
 		name   string
-		mock   func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder)
-		expect func(t *testing.T, peer *resource.Peer)
+		mock   func(md *configmocks.MockDynconfigInterfaceMockRecorder)
+		expect func(t *testing.T, header metadata.MD, err error)
 	}{
 		{
-			name: "peer state is PeerStateBackToSource",
-			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
-				peer.FSM.SetState(resource.PeerStateBackToSource)
-			},
-			expect: func(t *testing.T, peer *resource.Peer) {
-				assert := assert.New(t)
-				assert.True(peer.FSM.Is(resource.PeerStateBackToSource))
-			},
-		},
-		{
-			name: "peer state is PeerStateReceivedTiny",
-			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
-				peer.FSM.SetState(resource.PeerStateReceivedTiny)
-			},
-			expect: func(t *testing.T, peer *resource.Peer) {
-				assert := assert.New(t)
-				assert.True(peer.FSM.Is(resource.PeerStateRunning))
-			},
-		},
-		{
-			name: "peer state is PeerStateReceivedSmall",
-			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
-				peer.FSM.SetState(resource.PeerStateReceivedSmall)
-			},
-			expect: func(t *testing.T, peer *resource.Peer) {
-				assert := assert.New(t)
-				assert.True(peer.FSM.Is(resource.PeerStateRunning))
-			},
-		},
-		{
-			name: "peer state is PeerStateReceivedNormal",
-			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
-				peer.FSM.SetState(resource.PeerStateReceivedNormal)
-				scheduling.ScheduleParentAndCandidateParents(gomock.Any(), gomock.Eq(peer), gomock.Eq(set.NewSafeSet[string]())).Return().Times(1)
+			name: "forward client feature flags",
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetClientFeatureFlags().Return(featureflag.Flags{featureflag.PieceCompression: false}).Times(1)
 			},
-			expect: func(t *testing.T, peer *resource.Peer) {
+			expect: func(t *testing.T, header metadata.MD, err error) {
 				assert := assert.New(t)
-				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+				assert.NoError(err)
+				assert.Equal([]string{`{"piece_compression":false}`}, header.Get(featureflag.MetadataKey))
 			},
 		},
 		{
-			name: "peer state is PeerStateSucceeded",
-			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
-				peer.FSM.SetState(resource.PeerStateSucceeded)
+			name: "forward empty client feature flags",
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetClientFeatureFlags().Return(nil).Times(1)
 			},
-			expect: func(t *testing.T, peer *resource.Peer) {
+			expect: func(t *testing.T, header metadata.MD, err error) {
 				assert := assert.New(t)
-				assert.True(peer.FSM.Is(resource.PeerStateSucceeded))
+				assert.NoError(err)
+				assert.Equal([]string{"{}"}, header.Get(featureflag.MetadataKey))
 			},
 		},
 	}
@@ -4040,41 +4554,450 @@ func TestServiceV1_handleBeginOfPiece(t *testing.T) {
 			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
 			storage := storagemocks.NewMockStorage(ctl)
 			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
-			mockHost := resource.NewHost(
-				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
-				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
-			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
-			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
 			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
 
-			tc.mock(peer, scheduling.EXPECT())
-			svc.handleBeginOfPiece(context.Background(), peer)
-			tc.expect(t, peer)
+			stream := &mockServerTransportStream{}
+			tc.mock(dynconfig.EXPECT())
+			err := svc.setFeatureFlagsHeader(grpc.NewContextWithServerTransportStream(context.Background(), stream))
+			tc.expect(t, stream.header, err)
 		})
 	}
 }
 
-func TestServiceV1_handlePieceSuccess(t *testing.T) {
-	mockHost := resource.NewHost(
-		mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
-		mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
-	mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+// mockServerTransportStream records the header set by the service.
+type mockServerTransportStream struct {
+	header metadata.MD
+}
+
+func (s *mockServerTransportStream) Method() string {
+	return "/scheduler.Scheduler/RegisterPeerTask"
+}
+
+func (s *mockServerTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
 
+func (s *mockServerTransportStream) SendHeader(md metadata.MD) error {
+	return s.SetHeader(md)
+}
+
+func (s *mockServerTransportStream) SetTrailer(md metadata.MD) error {
+	return nil
+}
+
+func TestServiceV1_sizeScope(t *testing.T) {
 	tests := []struct {
-		name   string
-		piece  *schedulerv1.PieceResult
-		peer   *resource.Peer
-		mock   func(peer *resource.Peer, peerManager resource.PeerManager, mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder)
-		expect func(t *testing.T, peer *resource.Peer)
+		name          string
+		contentLength int64
+		mock          func(md *configmocks.MockDynconfigInterfaceMockRecorder)
+		expect        func(t *testing.T, sizeScope commonv1.SizeScope)
 	}{
 		{
-			name: "piece success",
-			piece: &schedulerv1.PieceResult{
-				DstPid: mockSeedPeerID,
-				PieceInfo: &commonv1.PieceInfo{
-					PieceNum:     1,
-					RangeStart:   2,
-					RangeSize:    10,
+			name:          "content length is equal to tiny file size limit",
+			contentLength: config.DefaultSchedulerTinyFileSizeLimit.ToNumber(),
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv1.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv1.SizeScope_TINY, sizeScope)
+			},
+		},
+		{
+			name:          "content length is greater than tiny file size limit",
+			contentLength: config.DefaultSchedulerTinyFileSizeLimit.ToNumber() + 1,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv1.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv1.SizeScope_SMALL, sizeScope)
+			},
+		},
+		{
+			name:          "content length is equal to tiny file size limit of application",
+			contentLength: 256 * 1024,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{
+					SizeScopeApplications: []types.SizeScopeApplication{{Name: mockTaskApplication, TinyFileSizeLimit: 256 * 1024}},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv1.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv1.SizeScope_TINY, sizeScope)
+			},
+		},
+		{
+			name:          "content length is greater than small file size limit of application",
+			contentLength: 1024*1024 + 1,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{
+					SizeScopeApplications: []types.SizeScopeApplication{{Name: mockTaskApplication, SmallFileSizeLimit: 1024 * 1024}},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv1.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv1.SizeScope_NORMAL, sizeScope)
+			},
+		},
+		{
+			name:          "size scope limits of other application are ignored",
+			contentLength: 256 * 1024,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{
+					SizeScopeApplications: []types.SizeScopeApplication{{Name: "bar", TinyFileSizeLimit: 256 * 1024}},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv1.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv1.SizeScope_SMALL, sizeScope)
+			},
+		},
+		{
+			name:          "invalid size scope limits of application are ignored",
+			contentLength: 256 * 1024,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{
+					SizeScopeApplications: []types.SizeScopeApplication{{Name: mockTaskApplication, TinyFileSizeLimit: 256 * 1024, SmallFileSizeLimit: 256 * 1024}},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv1.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv1.SizeScope_SMALL, sizeScope)
+			},
+		},
+		{
+			name:          "dynconfig get scheduler cluster config failed",
+			contentLength: 256 * 1024,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv1.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv1.SizeScope_SMALL, sizeScope)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := mocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			mockTask.ContentLength.Store(tc.contentLength)
+			mockTask.TotalPieceCount.Store(1)
+
+			tc.mock(dynconfig.EXPECT())
+			tc.expect(t, svc.sizeScope(mockTask))
+		})
+	}
+}
+
+func TestServiceV1_triggerSeedPeerTask(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(task *resource.Task, peer *resource.Peer, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mc *resource.MockSeedPeerMockRecorder)
+		expect func(t *testing.T, task *resource.Task, peer *resource.Peer)
+	}{
+		{
+			name: "trigger seed peer task",
+			mock: func(task *resource.Task, peer *resource.Peer, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mc *resource.MockSeedPeerMockRecorder) {
+				task.FSM.SetState(resource.TaskStateRunning)
+				peer.FSM.SetState(resource.PeerStateRunning)
+				gomock.InOrder(
+					mr.SeedPeer().Return(seedPeer).Times(1),
+					mc.TriggerTask(gomock.Any(), gomock.Any(), gomock.Any()).Return(peer, &schedulerv1.PeerResult{
+						TotalPieceCount: 3,
+						ContentLength:   1024,
+					}, nil).Times(1),
+				)
+			},
+			expect: func(t *testing.T, task *resource.Task, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(task.FSM.Is(resource.TaskStateSucceeded))
+				assert.Equal(task.TotalPieceCount.Load(), int32(3))
+				assert.Equal(task.ContentLength.Load(), int64(1024))
+				assert.True(peer.FSM.Is(resource.PeerStateSucceeded))
+			},
+		},
+		{
+			name: "trigger seed peer task failed",
+			mock: func(task *resource.Task, peer *resource.Peer, seedPeer resource.SeedPeer, mr *resource.MockResourceMockRecorder, mc *resource.MockSeedPeerMockRecorder) {
+				task.FSM.SetState(resource.TaskStateRunning)
+				gomock.InOrder(
+					mr.SeedPeer().Return(seedPeer).Times(1),
+					mc.TriggerTask(gomock.Any(), gomock.Any(), gomock.Any()).Return(peer, &schedulerv1.PeerResult{}, errors.New("foo")).Times(1),
+				)
+			},
+			expect: func(t *testing.T, task *resource.Task, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(task.FSM.Is(resource.TaskStateFailed))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := mocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			seedPeer := resource.NewMockSeedPeer(ctl)
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			task := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, task, mockHost)
+			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig, SeedPeer: mockSeedPeerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
+
+			tc.mock(task, peer, seedPeer, res.EXPECT(), seedPeer.EXPECT())
+			svc.triggerSeedPeerTask(context.Background(), &mockPeerRange, task)
+			tc.expect(t, task, peer)
+		})
+	}
+}
+
+func TestServiceV1_registerNormalTask(t *testing.T) {
+	tests := []struct {
+		name     string
+		finished *piece.Finished
+		expect   func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error)
+	}{
+		{
+			name: "peer registers without finished pieces",
+			expect: func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(commonv1.SizeScope_NORMAL, result.SizeScope)
+				assert.True(peer.FSM.Is(resource.PeerStateReceivedNormal))
+				assert.Equal(uint(0), peer.FinishedPieces.Count())
+			},
+		},
+		{
+			name: "peer restores finished pieces",
+			finished: &piece.Finished{
+				TaskID:    mockTaskID,
+				PieceSize: uint32(mockTaskPieceLength),
+				Pieces:    bitset.New(3).Set(0).Set(2),
+			},
+			expect: func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(commonv1.SizeScope_NORMAL, result.SizeScope)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(uint(2), peer.FinishedPieces.Count())
+				assert.True(peer.FinishedPieces.Test(2))
+			},
+		},
+		{
+			name: "finished pieces of other task are ignored",
+			finished: &piece.Finished{
+				TaskID:    "foo",
+				PieceSize: uint32(mockTaskPieceLength),
+				Pieces:    bitset.New(3).Set(0),
+			},
+			expect: func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(peer.FSM.Is(resource.PeerStateReceivedNormal))
+				assert.Equal(uint(0), peer.FinishedPieces.Count())
+			},
+		},
+		{
+			name: "finished pieces with different piece size are ignored",
+			finished: &piece.Finished{
+				TaskID:    mockTaskID,
+				PieceSize: uint32(mockTaskPieceLength) / 2,
+				Pieces:    bitset.New(3).Set(0),
+			},
+			expect: func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(peer.FSM.Is(resource.PeerStateReceivedNormal))
+				assert.Equal(uint(0), peer.FinishedPieces.Count())
+			},
+		},
+		{
+			name: "finished pieces out of task bounds are ignored",
+			finished: &piece.Finished{
+				TaskID:    mockTaskID,
+				PieceSize: uint32(mockTaskPieceLength),
+				Pieces:    bitset.New(4).Set(0).Set(3),
+			},
+			expect: func(t *testing.T, peer *resource.Peer, result *schedulerv1.RegisterResult, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.True(peer.FSM.Is(resource.PeerStateReceivedNormal))
+				assert.Equal(uint(0), peer.FinishedPieces.Count())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := mocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			mockTask.ContentLength.Store(int64(mockTaskPieceLength) * 3)
+			mockTask.TotalPieceCount.Store(3)
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+
+			ctx, err := piece.AppendFinishedToOutgoingContext(context.Background(), tc.finished)
+			assert.NoError(t, err)
+			md, _ := metadata.FromOutgoingContext(ctx)
+
+			result, err := svc.registerNormalTask(metadata.NewIncomingContext(context.Background(), md), peer)
+			tc.expect(t, peer, result, err)
+		})
+	}
+}
+
+func TestServiceV1_handleBeginOfPiece(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder)
+		expect func(t *testing.T, peer *resource.Peer)
+	}{
+		{
+			name: "peer state is PeerStateBackToSource",
+			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateBackToSource)
+			},
+			expect: func(t *testing.T, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateBackToSource))
+			},
+		},
+		{
+			name: "peer state is PeerStateReceivedTiny",
+			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateReceivedTiny)
+			},
+			expect: func(t *testing.T, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+			},
+		},
+		{
+			name: "peer state is PeerStateReceivedSmall",
+			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateReceivedSmall)
+			},
+			expect: func(t *testing.T, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+			},
+		},
+		{
+			name: "peer state is PeerStateReceivedNormal",
+			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateReceivedNormal)
+				scheduling.ScheduleParentAndCandidateParents(gomock.Any(), gomock.Eq(peer), gomock.Eq(set.NewSafeSet[string]())).Return().Times(1)
+			},
+			expect: func(t *testing.T, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+			},
+		},
+		{
+			name: "peer state is PeerStateRunning with restored pieces",
+			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateRunning)
+				peer.FinishedPieces.Set(0)
+				scheduling.ScheduleParentAndCandidateParents(gomock.Any(), gomock.Eq(peer), gomock.Eq(set.NewSafeSet[string]())).Return().Times(1)
+			},
+			expect: func(t *testing.T, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+			},
+		},
+		{
+			name: "peer state is PeerStateRunning without finished pieces",
+			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateRunning)
+			},
+			expect: func(t *testing.T, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+			},
+		},
+		{
+			name: "peer state is PeerStateSucceeded",
+			mock: func(peer *resource.Peer, scheduling *mocks.MockSchedulingMockRecorder) {
+				peer.FSM.SetState(resource.PeerStateSucceeded)
+			},
+			expect: func(t *testing.T, peer *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateSucceeded))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := mocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+
+			tc.mock(peer, scheduling.EXPECT())
+			svc.handleBeginOfPiece(context.Background(), peer)
+			tc.expect(t, peer)
+		})
+	}
+}
+
+func TestServiceV1_handlePieceSuccess(t *testing.T) {
+	mockHost := resource.NewHost(
+		mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+		mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+	mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+
+	tests := []struct {
+		name   string
+		piece  *schedulerv1.PieceResult
+		peer   *resource.Peer
+		mock   func(peer *resource.Peer, peerManager resource.PeerManager, mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder)
+		expect func(t *testing.T, peer *resource.Peer)
+	}{
+		{
+			name: "piece success",
+			piece: &schedulerv1.PieceResult{
+				DstPid: mockSeedPeerID,
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum:     1,
+					RangeStart:   2,
+					RangeSize:    10,
 					PieceMd5:     mockPieceMD5.Encoded,
 					DownloadCost: 1,
 				},
@@ -4190,6 +5113,230 @@ func TestServiceV1_handlePieceSuccess(t *testing.T) {
 	}
 }
 
+func TestServiceV1_validatePieceResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		piece  *schedulerv1.PieceResult
+		mock   func(peer *resource.Peer)
+		expect func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error)
+	}{
+		{
+			name: "piece result is valid",
+			piece: &schedulerv1.PieceResult{
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum: 1,
+				},
+			},
+			mock: func(peer *resource.Peer) {
+				peer.Task.TotalPieceCount.Store(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+			},
+		},
+		{
+			name:  "piece info is empty",
+			piece: &schedulerv1.PieceResult{},
+			mock:  func(peer *resource.Peer) {},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "piece info is empty")
+			},
+		},
+		{
+			name: "piece number is invalid",
+			piece: &schedulerv1.PieceResult{
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum: -3,
+				},
+			},
+			mock: func(peer *resource.Peer) {},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "piece number -3 is invalid")
+			},
+		},
+		{
+			name: "piece number is out of total piece count",
+			piece: &schedulerv1.PieceResult{
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum: 2,
+				},
+			},
+			mock: func(peer *resource.Peer) {
+				peer.Task.TotalPieceCount.Store(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "piece number 2 is out of total piece count 2")
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := mocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+
+			tc.mock(peer)
+			tc.expect(t, peer, tc.piece, svc.validatePieceResult(peer, tc.piece))
+		})
+	}
+}
+
+func TestServiceV1_clampPieceResult(t *testing.T) {
+	tests := []struct {
+		name   string
+		piece  *schedulerv1.PieceResult
+		mock   func(peer *resource.Peer)
+		expect func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error)
+	}{
+		{
+			name: "piece result is possible",
+			piece: &schedulerv1.PieceResult{
+				BeginTime:     1,
+				EndTime:       2,
+				FinishedCount: 1,
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum:     1,
+					DownloadCost: 1,
+				},
+			},
+			mock: func(peer *resource.Peer) {
+				peer.Task.TotalPieceCount.Store(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(piece.FinishedCount, int32(1))
+				assert.Equal(peer.ReportedFinishedCount.Load(), int32(1))
+			},
+		},
+		{
+			name: "finished count is greater than total piece count",
+			piece: &schedulerv1.PieceResult{
+				FinishedCount: 3,
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum: 1,
+				},
+			},
+			mock: func(peer *resource.Peer) {
+				peer.Task.TotalPieceCount.Store(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "finished count 3 is greater than total piece count 2")
+				assert.Equal(piece.FinishedCount, int32(2))
+				assert.Equal(peer.ReportedFinishedCount.Load(), int32(2))
+			},
+		},
+		{
+			name: "end time is before begin time",
+			piece: &schedulerv1.PieceResult{
+				BeginTime: 2,
+				EndTime:   1,
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum: 1,
+				},
+			},
+			mock: func(peer *resource.Peer) {},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "end time 1 is before begin time 2")
+				assert.Equal(piece.EndTime, uint64(2))
+			},
+		},
+		{
+			name: "piece cost is greater than max piece cost",
+			piece: &schedulerv1.PieceResult{
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum:     1,
+					DownloadCost: uint64(2 * time.Minute / time.Millisecond),
+				},
+			},
+			mock: func(peer *resource.Peer) {},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "piece cost 120000ms is greater than 1m0s")
+				assert.Equal(piece.PieceInfo.DownloadCost, uint64(time.Minute/time.Millisecond))
+			},
+		},
+		{
+			name: "finished count is less than reported finished count",
+			piece: &schedulerv1.PieceResult{
+				FinishedCount: 1,
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum: 1,
+				},
+			},
+			mock: func(peer *resource.Peer) {
+				peer.ReportedFinishedCount.Store(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "finished count 1 is less than reported finished count 2")
+				assert.Equal(piece.FinishedCount, int32(2))
+				assert.Equal(peer.ReportedFinishedCount.Load(), int32(2))
+			},
+		},
+		{
+			name: "piece result has multiple impossible fields",
+			piece: &schedulerv1.PieceResult{
+				BeginTime:     2,
+				EndTime:       1,
+				FinishedCount: 1,
+				PieceInfo: &commonv1.PieceInfo{
+					PieceNum: 1,
+				},
+			},
+			mock: func(peer *resource.Peer) {
+				peer.ReportedFinishedCount.Store(2)
+			},
+			expect: func(t *testing.T, peer *resource.Peer, piece *schedulerv1.PieceResult, err error) {
+				assert := assert.New(t)
+				assert.EqualError(err, "end time 1 is before begin time 2\nfinished count 1 is less than reported finished count 2")
+				assert.Equal(piece.EndTime, uint64(2))
+				assert.Equal(piece.FinishedCount, int32(2))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := mocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+
+			mockHost := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
+
+			tc.mock(peer)
+			tc.expect(t, peer, tc.piece, svc.clampPieceResult(peer, tc.piece))
+		})
+	}
+}
+
 func TestServiceV1_handlePieceFail(t *testing.T) {
 
 	tests := []struct {
@@ -4503,6 +5650,7 @@ func TestServiceV1_handlePeerSuccess(t *testing.T) {
 			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
 			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
 			svc := NewV1(&config.Config{Scheduler: mockSchedulerConfig, Metrics: config.MetricsConfig{EnableHost: true}}, res, scheduling, dynconfig, storage, networkTopology)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(types.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
 
 			tc.mock(peer)
 			svc.handlePeerSuccess(context.Background(), peer)
@@ -4703,6 +5851,7 @@ func TestServiceV1_handleTaskFail(t *testing.T) {
 			expect: func(t *testing.T, task *resource.Task) {
 				assert := assert.New(t)
 				assert.True(task.FSM.Is(resource.TaskStatePending))
+				assert.Nil(task.Failure())
 			},
 		},
 		{
@@ -4723,6 +5872,7 @@ func TestServiceV1_handleTaskFail(t *testing.T) {
 			expect: func(t *testing.T, task *resource.Task) {
 				assert := assert.New(t)
 				assert.True(task.FSM.Is(resource.TaskStateFailed))
+				assert.Equal(resource.TaskFailureReasonBackToSourceFailed, task.Failure().Reason)
 			},
 		},
 		{
@@ -4745,6 +5895,8 @@ func TestServiceV1_handleTaskFail(t *testing.T) {
 				assert := assert.New(t)
 				assert.True(task.FSM.Is(resource.TaskStateFailed))
 				assert.Equal(task.PeerFailedCount.Load(), int32(0))
+				assert.Equal(resource.TaskFailureReasonSourceError, task.Failure().Reason)
+				assert.False(task.Failure().Temporary)
 			},
 		},
 		{
@@ -4756,6 +5908,8 @@ func TestServiceV1_handleTaskFail(t *testing.T) {
 			expect: func(t *testing.T, task *resource.Task) {
 				assert := assert.New(t)
 				assert.True(task.FSM.Is(resource.TaskStateFailed))
+				assert.Equal(resource.TaskFailureReasonSourceError, task.Failure().Reason)
+				assert.True(task.Failure().Temporary)
 			},
 		},
 		{
@@ -4768,6 +5922,8 @@ func TestServiceV1_handleTaskFail(t *testing.T) {
 				assert := assert.New(t)
 				assert.True(task.FSM.Is(resource.TaskStateFailed))
 				assert.Equal(task.PeerFailedCount.Load(), int32(0))
+				assert.Equal(resource.TaskFailureReasonSourceError, task.Failure().Reason)
+				assert.False(task.Failure().Temporary)
 			},
 		},
 		{
@@ -4780,6 +5936,8 @@ func TestServiceV1_handleTaskFail(t *testing.T) {
 				assert := assert.New(t)
 				assert.True(task.FSM.Is(resource.TaskStateFailed))
 				assert.Equal(task.PeerFailedCount.Load(), int32(0))
+				assert.Equal(resource.TaskFailureReasonSourceError, task.Failure().Reason)
+				assert.True(task.Failure().Temporary)
 			},
 		},
 		{
@@ -4792,6 +5950,46 @@ func TestServiceV1_handleTaskFail(t *testing.T) {
 				assert := assert.New(t)
 				assert.True(task.FSM.Is(resource.TaskStateFailed))
 				assert.Equal(task.PeerFailedCount.Load(), int32(0))
+				assert.Equal(resource.TaskFailureReasonRetryExhausted, task.Failure().Reason)
+			},
+		},
+		{
+			name:            "peer back-to-source fails because origin returned 404",
+			backToSourceErr: &errordetailsv1.SourceError{Temporary: false, Metadata: &commonv1.ExtendAttribute{StatusCode: 404, Status: "404 Not Found"}},
+			mock: func(task *resource.Task) {
+				task.FSM.SetState(resource.TaskStateRunning)
+			},
+			expect: func(t *testing.T, task *resource.Task) {
+				assert := assert.New(t)
+				assert.True(task.FSM.Is(resource.TaskStateFailed))
+				assert.Equal(int32(404), task.Failure().StatusCode)
+				assert.Equal("origin returned 404", task.Failure().String())
+			},
+		},
+		{
+			name:        "seed peer fails to register task",
+			seedPeerErr: dferrors.ConvertDfErrorToGRPCError(dferrors.New(commonv1.Code_CDNTaskRegistryFail, "foo")),
+			mock: func(task *resource.Task) {
+				task.FSM.SetState(resource.TaskStateRunning)
+			},
+			expect: func(t *testing.T, task *resource.Task) {
+				assert := assert.New(t)
+				assert.True(task.FSM.Is(resource.TaskStateFailed))
+				assert.Equal(resource.TaskFailureReasonSeedPeerRegisterFailed, task.Failure().Reason)
+				assert.Equal("foo", task.Failure().Message)
+			},
+		},
+		{
+			name:        "seed peer fails to download task",
+			seedPeerErr: status.Error(codes.Internal, "no space left on device"),
+			mock: func(task *resource.Task) {
+				task.FSM.SetState(resource.TaskStateRunning)
+			},
+			expect: func(t *testing.T, task *resource.Task) {
+				assert := assert.New(t)
+				assert.True(task.FSM.Is(resource.TaskStateFailed))
+				assert.Equal(resource.TaskFailureReasonSeedPeerDownloadFailed, task.Failure().Reason)
+				assert.Equal("seed peer failed to download task: no space left on device", task.Failure().String())
 			},
 		},
 	}
@@ -36,6 +36,8 @@ import (
 	"d7y.io/dragonfly/v2/pkg/container/set"
 	"d7y.io/dragonfly/v2/pkg/digest"
 	"d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/redact"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	"d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
 	"d7y.io/dragonfly/v2/scheduler/metrics"
@@ -113,7 +115,7 @@ func (v *V2) AnnouncePeer(stream schedulerv2.Scheduler_AnnouncePeerServer) error
 		case *schedulerv2.AnnouncePeerRequest_RegisterPeerRequest:
 			registerPeerRequest := announcePeerRequest.RegisterPeerRequest
 			log.Infof("receive RegisterPeerRequest, url: %s, range: %#v, header: %#v, need back-to-source: %t",
-				registerPeerRequest.Download.GetUrl(), registerPeerRequest.Download.GetRange(), registerPeerRequest.Download.GetRequestHeader(), registerPeerRequest.Download.GetNeedBackToSource())
+				redact.URL(registerPeerRequest.Download.GetUrl()), registerPeerRequest.Download.GetRange(), registerPeerRequest.Download.GetRequestHeader(), registerPeerRequest.Download.GetNeedBackToSource())
 			if err := v.handleRegisterPeerRequest(ctx, stream, req.GetHostId(), req.GetTaskId(), req.GetPeerId(), registerPeerRequest); err != nil {
 				log.Error(err)
 				return err
@@ -265,7 +267,7 @@ func (v *V2) StatPeer(ctx context.Context, req *schedulerv2.StatPeerRequest) (*c
 		Tag:                 &peer.Task.Tag,
 		Application:         &peer.Task.Application,
 		FilteredQueryParams: peer.Task.FilteredQueryParams,
-		RequestHeader:       peer.Task.Header,
+		RequestHeader:       http.WithoutSensitiveHeaders(peer.Task.Header),
 		PieceLength:         uint64(peer.Task.PieceLength),
 		ContentLength:       uint64(peer.Task.ContentLength.Load()),
 		PieceCount:          uint32(peer.Task.TotalPieceCount.Load()),
@@ -414,7 +416,7 @@ func (v *V2) StatTask(ctx context.Context, req *schedulerv2.StatTaskRequest) (*c
 		Tag:                 &task.Tag,
 		Application:         &task.Application,
 		FilteredQueryParams: task.FilteredQueryParams,
-		RequestHeader:       task.Header,
+		RequestHeader:       http.WithoutSensitiveHeaders(task.Header),
 		PieceLength:         uint64(task.PieceLength),
 		ContentLength:       uint64(task.ContentLength.Load()),
 		PieceCount:          uint32(task.TotalPieceCount.Load()),
@@ -595,6 +597,10 @@ func (v *V2) AnnounceHost(ctx context.Context, req *schedulerv2.AnnounceHostRequ
 			options = append(options, resource.WithAnnounceInterval(req.GetInterval().AsDuration()))
 		}
 
+		if startedAt, ok := rpc.HostStartedAtFromContext(ctx); ok {
+			options = append(options, resource.WithStartedAt(startedAt))
+		}
+
 		host = resource.NewHost(
 			req.Host.GetId(), req.Host.GetIp(), req.Host.GetHostname(),
 			req.Host.GetPort(), req.Host.GetDownloadPort(), types.HostType(req.Host.GetType()),
@@ -606,6 +612,11 @@ func (v *V2) AnnounceHost(ctx context.Context, req *schedulerv2.AnnounceHostRequ
 		return nil
 	}
 
+	// Host restarted with the same id, reap the peers created before the restart.
+	if restartedAt, ok := hostRestartedAt(ctx, host); ok {
+		v.handleHostRestart(ctx, host, restartedAt)
+	}
+
 	// Host already exists and updates properties.
 	host.Port = req.Host.GetPort()
 	host.DownloadPort = req.Host.GetDownloadPort()
@@ -862,6 +873,16 @@ func (v *V2) SyncProbes(stream schedulerv2.Scheduler_SyncProbesServer) error {
 	}
 }
 
+// handleHostRestart handles the host restarted with the same id, the ghost peers created
+// before the restart leave, and their children reschedule by themselves.
+func (v *V2) handleHostRestart(ctx context.Context, host *resource.Host, restartedAt time.Time) {
+	reapHostPeers(host, restartedAt, func(peer *resource.Peer) {
+		if err := peer.FSM.Event(ctx, resource.PeerEventLeave); err != nil {
+			peer.Log.Errorf("peer fsm event failed: %s", err.Error())
+		}
+	})
+}
+
 // handleRegisterPeerRequest handles RegisterPeerRequest of AnnouncePeerRequest.
 func (v *V2) handleRegisterPeerRequest(ctx context.Context, stream schedulerv2.Scheduler_AnnouncePeerServer, hostID, taskID, peerID string, req *schedulerv2.RegisterPeerRequest) error {
 	// Handle resource included host, task, and peer.
@@ -884,11 +905,12 @@ func (v *V2) handleRegisterPeerRequest(ctx context.Context, stream schedulerv2.S
 	case download.GetNeedBackToSource():
 		peer.Log.Info("peer need back to source")
 		peer.NeedBackToSource.Store(true)
-	// If task is pending, failed, leave, or succeeded and has no available peer,
+	// If task is pending, failed, leave, seeding aborted, or succeeded and has no available peer,
 	// scheduler trigger seed peer download back-to-source.
 	case task.FSM.Is(resource.TaskStatePending) ||
 		task.FSM.Is(resource.TaskStateFailed) ||
 		task.FSM.Is(resource.TaskStateLeave) ||
+		task.FSM.Is(resource.TaskStateSeedingAborted) ||
 		task.FSM.Is(resource.TaskStateSucceeded) &&
 			!task.HasAvailablePeer(blocklist):
 
@@ -928,12 +950,12 @@ func (v *V2) handleRegisterPeerRequest(ctx context.Context, stream schedulerv2.S
 	}
 
 	// FSM event state transition by size scope.
-	sizeScope := peer.Task.SizeScope()
+	sizeScope := v.sizeScope(peer.Task)
 	switch sizeScope {
 	case commonv2.SizeScope_EMPTY:
 		// Return an EmptyTaskResponse directly.
 		peer.Log.Info("scheduling as SizeScope_EMPTY")
-		stream, loaded := peer.LoadAnnouncePeerStream()
+		_, loaded := peer.LoadAnnouncePeerStream()
 		if !loaded {
 			return status.Error(codes.NotFound, "AnnouncePeerStream not found")
 		}
@@ -942,7 +964,7 @@ func (v *V2) handleRegisterPeerRequest(ctx context.Context, stream schedulerv2.S
 			return status.Errorf(codes.Internal, err.Error())
 		}
 
-		if err := stream.Send(&schedulerv2.AnnouncePeerResponse{
+		if err := peer.SendAnnouncePeerResponse(&schedulerv2.AnnouncePeerResponse{
 			Response: &schedulerv2.AnnouncePeerResponse_EmptyTaskResponse{
 				EmptyTaskResponse: &schedulerv2.EmptyTaskResponse{},
 			},
@@ -959,11 +981,11 @@ func (v *V2) handleRegisterPeerRequest(ctx context.Context, stream schedulerv2.S
 		}
 
 		// Scheduling parent for the peer.
-		peer.BlockParents.Add(peer.ID)
+		peer.BlockParent(peer.ID)
 
 		// Record the start time.
 		start := time.Now()
-		if err := v.scheduling.ScheduleCandidateParents(ctx, peer, peer.BlockParents); err != nil {
+		if err := v.scheduling.ScheduleCandidateParents(ctx, peer, peer.LoadBlockParents()); err != nil {
 			// Collect RegisterPeerFailureCount metrics.
 			metrics.RegisterPeerFailureCount.WithLabelValues(priority.String(), peer.Task.Type.String(),
 				peer.Host.Type.Name()).Inc()
@@ -1037,12 +1059,12 @@ func (v *V2) handleRescheduleRequest(ctx context.Context, peerID string, candida
 
 	// Add candidate parent ids to block parents.
 	for _, candidateParent := range candidateParents {
-		peer.BlockParents.Add(candidateParent.GetId())
+		peer.BlockParent(candidateParent.GetId())
 	}
 
 	// Record the start time.
 	start := time.Now()
-	if err := v.scheduling.ScheduleCandidateParents(ctx, peer, peer.BlockParents); err != nil {
+	if err := v.scheduling.ScheduleCandidateParents(ctx, peer, peer.LoadBlockParents()); err != nil {
 		return status.Error(codes.FailedPrecondition, err.Error())
 	}
 
@@ -1148,6 +1170,7 @@ func (v *V2) handleDownloadPeerBackToSourceFailedRequest(ctx context.Context, pe
 	peer.Task.ContentLength.Store(-1)
 	peer.Task.TotalPieceCount.Store(0)
 	peer.Task.DirectPiece = []byte{}
+	peer.Task.SetFailure(resource.NewTaskFailure(resource.TaskFailureReasonBackToSourceFailed, ""))
 	if err := peer.Task.FSM.Event(ctx, resource.TaskEventDownloadFailed); err != nil {
 		return status.Error(codes.Internal, err.Error())
 	}
@@ -1189,6 +1212,12 @@ func (v *V2) handleDownloadPieceFinishedRequest(peerID string, req *schedulerv2.
 		return status.Errorf(codes.NotFound, "peer %s not found", peerID)
 	}
 
+	// Reject the impossible piece, the peer is still allowed to download.
+	if err := v.validatePiece(peer, piece); err != nil {
+		v.handlePieceViolation(peer, err)
+		return nil
+	}
+
 	// Handle peer with piece finished request. When the piece is downloaded successfully, peer.UpdatedAt needs
 	// to be updated to prevent the peer from being GC during the download process.
 	peer.StorePiece(piece)
@@ -1198,11 +1227,13 @@ func (v *V2) handleDownloadPieceFinishedRequest(peerID string, req *schedulerv2.
 	peer.UpdatedAt.Store(time.Now())
 
 	// When the piece is downloaded successfully, parent.UpdatedAt needs to be updated
-	// to prevent the parent from being GC during the download process.
+	// to prevent the parent from being GC during the download process,
+	// and the peer prefers the parent host in the following scheduling.
 	parent, loadedParent := v.resource.PeerManager().Load(piece.ParentID)
 	if loadedParent {
 		parent.UpdatedAt.Store(time.Now())
 		parent.Host.UpdatedAt.Store(time.Now())
+		peer.StoreParentHost(parent.Host.ID)
 	}
 
 	// Handle task with piece finished request.
@@ -1252,6 +1283,12 @@ func (v *V2) handleDownloadPieceBackToSourceFinishedRequest(ctx context.Context,
 		return status.Errorf(codes.NotFound, "peer %s not found", peerID)
 	}
 
+	// Reject the impossible piece, the peer is still allowed to download.
+	if err := v.validatePiece(peer, piece); err != nil {
+		v.handlePieceViolation(peer, err)
+		return nil
+	}
+
 	// Handle peer with piece back-to-source finished request. When the piece is downloaded successfully, peer.UpdatedAt
 	// needs to be updated to prevent the peer from being GC during the download process.
 	peer.StorePiece(piece)
@@ -1277,6 +1314,41 @@ func (v *V2) handleDownloadPieceBackToSourceFinishedRequest(ctx context.Context,
 	return nil
 }
 
+// validatePiece validates the finished piece reported by the peer.
+func (v *V2) validatePiece(peer *resource.Peer, piece *resource.Piece) error {
+	if piece.Number < 0 {
+		return fmt.Errorf("piece number %d is invalid", piece.Number)
+	}
+
+	if totalPieceCount := peer.Task.TotalPieceCount.Load(); totalPieceCount > 0 && piece.Number >= totalPieceCount {
+		return fmt.Errorf("piece number %d is out of total piece count %d", piece.Number, totalPieceCount)
+	}
+
+	if piece.Cost < 0 {
+		return fmt.Errorf("piece cost %s is negative", piece.Cost)
+	}
+
+	if v.config.Scheduler.MaxPieceCost.Duration > 0 && piece.Cost > v.config.Scheduler.MaxPieceCost.Duration {
+		return fmt.Errorf("piece cost %s is greater than %s", piece.Cost, v.config.Scheduler.MaxPieceCost.Duration)
+	}
+
+	return nil
+}
+
+// handlePieceViolation handles impossible piece, the peer is quarantined
+// when the count of impossible pieces reaches the limit.
+func (v *V2) handlePieceViolation(peer *resource.Peer, err error) {
+	peer.Log.Debugf("reject piece: %s", err.Error())
+	if v.config.Scheduler.PieceViolationLimit <= 0 {
+		return
+	}
+
+	if peer.AddPieceViolation(v.config.Scheduler.PieceViolationLimit) {
+		peer.Log.With("pieceViolationCount", peer.PieceViolationCount.Load(), "lastViolation", err.Error()).
+			Warn("peer is quarantined because of reporting impossible pieces")
+	}
+}
+
 // handleDownloadPieceFailedRequest handles DownloadPieceFailedRequest of AnnouncePeerRequest.
 func (v *V2) handleDownloadPieceFailedRequest(ctx context.Context, peerID string, req *schedulerv2.DownloadPieceFailedRequest) error {
 	peer, loaded := v.resource.PeerManager().Load(peerID)
@@ -1293,7 +1365,7 @@ func (v *V2) handleDownloadPieceFailedRequest(ctx context.Context, peerID string
 	if req.Temporary {
 		// Handle peer with piece temporary failed request.
 		peer.UpdatedAt.Store(time.Now())
-		peer.BlockParents.Add(req.GetParentId())
+		peer.BlockParent(req.GetParentId())
 		if parent, loaded := v.resource.PeerManager().Load(req.GetParentId()); loaded {
 			parent.Host.UploadFailedCount.Inc()
 		}
@@ -1316,8 +1388,12 @@ func (v *V2) handleDownloadPieceBackToSourceFailedRequest(ctx context.Context, p
 	// Handle peer with piece back-to-source failed request.
 	peer.UpdatedAt.Store(time.Now())
 
-	// Handle task with piece back-to-source failed request.
+	// Handle task with piece back-to-source failed request, pause granting the peers to
+	// back-to-source, so that the source can recover from the server error.
 	peer.Task.UpdatedAt.Store(time.Now())
+	if http.IsServerError(int(req.GetBackend().GetStatusCode())) {
+		peer.Task.PauseBackToSource()
+	}
 
 	// Collect DownloadPieceCount and DownloadPieceFailureCount metrics.
 	metrics.DownloadPieceCount.WithLabelValues(commonv2.TrafficType_BACK_TO_SOURCE.String(), peer.Task.Type.String(),
@@ -1337,10 +1413,15 @@ func (v *V2) handleResource(ctx context.Context, stream schedulerv2.Scheduler_An
 		return nil, nil, nil, status.Errorf(codes.NotFound, "host %s not found", hostID)
 	}
 
+	// The evicted peer is refused to register again within the ttl of eviction.
+	if host.IsPeerEvicted(peerID) {
+		return nil, nil, nil, status.Errorf(codes.PermissionDenied, "peer %s is evicted", peerID)
+	}
+
 	// Store new task or update task.
 	task, loaded := v.resource.TaskManager().Load(taskID)
 	if !loaded {
-		options := []resource.TaskOption{resource.WithPieceLength(int32(download.GetPieceLength()))}
+		options := append(TaskOptions(v.config), resource.WithPieceLength(int32(download.GetPieceLength())))
 		if download.GetDigest() != "" {
 			d, err := digest.Parse(download.GetDigest())
 			if err != nil {
@@ -1352,7 +1433,7 @@ func (v *V2) handleResource(ctx context.Context, stream schedulerv2.Scheduler_An
 		}
 
 		task = resource.NewTask(taskID, download.GetUrl(), download.GetTag(), download.GetApplication(), download.GetType(),
-			download.GetFilteredQueryParams(), download.GetRequestHeader(), int32(v.config.Scheduler.BackToSourceCount), options...)
+			download.GetFilteredQueryParams(), download.GetRequestHeader(), BackToSourceCount(v.config, v.dynconfig, download.GetApplication()), options...)
 		v.resource.TaskManager().Store(task)
 	} else {
 		task.URL = download.GetUrl()
@@ -1375,6 +1456,12 @@ func (v *V2) handleResource(ctx context.Context, stream schedulerv2.Scheduler_An
 	return host, task, peer, nil
 }
 
+// sizeScope returns the size scope of the task with the file size limits of its application.
+func (v *V2) sizeScope(task *resource.Task) commonv2.SizeScope {
+	tinyFileSizeLimit, smallFileSizeLimit := fileSizeLimits(v.config, v.dynconfig, task)
+	return task.SizeScopeWithLimits(tinyFileSizeLimit, smallFileSizeLimit)
+}
+
 // downloadTaskBySeedPeer downloads task by seed peer.
 func (v *V2) downloadTaskBySeedPeer(ctx context.Context, taskID string, download *commonv2.Download, peer *resource.Peer) error {
 	// Trigger the first download task based on different priority levels,
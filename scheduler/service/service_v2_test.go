@@ -33,6 +33,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/mock/gomock"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -45,6 +46,7 @@ import (
 
 	managertypes "d7y.io/dragonfly/v2/manager/types"
 	nethttp "d7y.io/dragonfly/v2/pkg/net/http"
+	"d7y.io/dragonfly/v2/pkg/rpc"
 	pkgtypes "d7y.io/dragonfly/v2/pkg/types"
 	"d7y.io/dragonfly/v2/scheduler/config"
 	configmocks "d7y.io/dragonfly/v2/scheduler/config/mocks"
@@ -863,6 +865,136 @@ func TestServiceV2_AnnounceHost(t *testing.T) {
 	}
 }
 
+func TestServiceV2_AnnounceHostRestarted(t *testing.T) {
+	startedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name      string
+		startedAt time.Time
+		mock      func(host *resource.Host, peer *resource.Peer, child *resource.Peer)
+		expect    func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer)
+	}{
+		{
+			name:      "host restarted and peers are reaped",
+			startedAt: startedAt.Add(time.Minute),
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				host.StartedAt.Store(startedAt)
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateLeave))
+				assert.True(child.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(0), host.ConcurrentUploadCount.Load())
+				assert.Equal(int64(0), host.UploadCount.Load())
+				assert.Equal(int64(0), host.UploadFailedCount.Load())
+				assert.True(host.StartedAt.Load().Equal(startedAt.Add(time.Minute)))
+
+				degree, err := peer.Task.PeerInDegree(child.ID)
+				assert.NoError(err)
+				assert.Equal(0, degree)
+			},
+		},
+		{
+			name:      "host restarted and peers registered after the restart are kept",
+			startedAt: startedAt.Add(time.Minute),
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				host.StartedAt.Store(startedAt)
+
+				// The peer registers after the restart, before the host announces again.
+				newPeer := resource.NewPeer(mockPeerID+"-new", mockResourceConfig, peer.Task, host)
+				newPeer.FSM.SetState(resource.PeerStateRunning)
+				peer.Task.StorePeer(newPeer)
+				host.StorePeer(newPeer)
+				peer.Task.AddPeerEdge(newPeer, child) // nolint: errcheck
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateLeave))
+
+				newPeer, loaded := host.LoadPeer(mockPeerID + "-new")
+				assert.True(loaded)
+				assert.True(newPeer.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(1), host.ConcurrentUploadCount.Load())
+				assert.Equal(int64(0), host.UploadCount.Load())
+			},
+		},
+		{
+			name:      "host is not restarted",
+			startedAt: startedAt,
+			mock: func(host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				host.StartedAt.Store(startedAt)
+			},
+			expect: func(t *testing.T, host *resource.Host, peer *resource.Peer, child *resource.Peer) {
+				assert := assert.New(t)
+				assert.True(peer.FSM.Is(resource.PeerStateRunning))
+				assert.Equal(int32(1), host.ConcurrentUploadCount.Load())
+				assert.Equal(int64(1), host.UploadCount.Load())
+				assert.Equal(int64(1), host.UploadFailedCount.Load())
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := schedulingmocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			hostManager := resource.NewMockHostManager(ctl)
+			svc := NewV2(&config.Config{Scheduler: mockSchedulerConfig, Metrics: config.MetricsConfig{EnableHost: true}}, res, scheduling, dynconfig, storage, networkTopology)
+
+			host := resource.NewHost(
+				mockRawHost.ID, mockRawHost.IP, mockRawHost.Hostname,
+				mockRawHost.Port, mockRawHost.DownloadPort, mockRawHost.Type)
+			childHost := resource.NewHost(
+				mockRawSeedHost.ID, mockRawSeedHost.IP, mockRawSeedHost.Hostname,
+				mockRawSeedHost.Port, mockRawSeedHost.DownloadPort, mockRawSeedHost.Type)
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+
+			// The peer of the host before the restart uploads to the child.
+			peer := resource.NewPeer(mockSeedPeerID, mockResourceConfig, mockTask, host)
+			peer.CreatedAt.Store(startedAt)
+			child := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, childHost)
+			mockTask.StorePeer(peer)
+			mockTask.StorePeer(child)
+			host.StorePeer(peer)
+			if err := mockTask.AddPeerEdge(peer, child); err != nil {
+				t.Fatal(err)
+			}
+			peer.FSM.SetState(resource.PeerStateRunning)
+			child.FSM.SetState(resource.PeerStateRunning)
+			host.UploadFailedCount.Inc()
+
+			tc.mock(host, peer, child)
+			gomock.InOrder(
+				dynconfig.EXPECT().GetSchedulerClusterClientConfig().Return(managertypes.SchedulerClusterClientConfig{}, errors.New("foo")).Times(1),
+				res.EXPECT().HostManager().Return(hostManager).Times(1),
+				hostManager.EXPECT().Load(gomock.Eq(host.ID)).Return(host, true).Times(1),
+			)
+
+			// The daemon restarted half an hour ago, after the peer of the host was created.
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				rpc.HostStartedAtMetadataKey, tc.startedAt.Format(time.RFC3339Nano),
+				rpc.HostUptimeMetadataKey, (30*time.Minute).String(),
+			))
+			assert.NoError(t, svc.AnnounceHost(ctx, &schedulerv2.AnnounceHostRequest{
+				Host: &commonv2.Host{
+					Id:           host.ID,
+					Type:         uint32(pkgtypes.HostTypeNormal),
+					Hostname:     host.Hostname,
+					Ip:           host.IP,
+					Port:         host.Port,
+					DownloadPort: host.DownloadPort,
+				},
+			}))
+			tc.expect(t, host, peer, child)
+		})
+	}
+}
+
 func TestServiceV2_DeleteHost(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -1724,6 +1856,7 @@ func TestServiceV2_handleRegisterPeerRequest(t *testing.T) {
 			peer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
 			seedPeer := resource.NewPeer(mockSeedPeerID, mockResourceConfig, mockTask, mockHost)
 			svc := NewV2(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(managertypes.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
 
 			tc.run(t, svc, tc.req, peer, seedPeer, hostManager, taskManager, peerManager, stream, res.EXPECT(), hostManager.EXPECT(), taskManager.EXPECT(), peerManager.EXPECT(), stream.EXPECT(), scheduling.EXPECT())
 		})
@@ -2425,6 +2558,7 @@ func TestServiceV2_handleDownloadPeerBackToSourceFailedRequest(t *testing.T) {
 				assert.NoError(svc.handleDownloadPeerBackToSourceFailedRequest(context.Background(), peer.ID))
 				assert.Equal(peer.FSM.Current(), resource.PeerStateFailed)
 				assert.Equal(peer.Task.FSM.Current(), resource.TaskStateFailed)
+				assert.Equal(peer.Task.Failure().Reason, resource.TaskFailureReasonBackToSourceFailed)
 				assert.Equal(peer.Task.ContentLength.Load(), int64(-1))
 				assert.Equal(peer.Task.TotalPieceCount.Load(), int32(0))
 				assert.Equal(peer.Task.DirectPiece, []byte{})
@@ -2504,6 +2638,38 @@ func TestServiceV2_handleDownloadPieceFinishedRequest(t *testing.T) {
 				assert.ErrorIs(svc.handleDownloadPieceFinishedRequest(peer.ID, req), status.Errorf(codes.NotFound, "peer %s not found", peer.ID))
 			},
 		},
+		{
+			name: "piece cost is impossible",
+			req: &schedulerv2.DownloadPieceFinishedRequest{
+				Piece: &commonv2.Piece{
+					Number:      uint32(mockPiece.Number),
+					ParentId:    &mockPiece.ParentID,
+					Offset:      mockPiece.Offset,
+					Length:      mockPiece.Length,
+					Digest:      mockPiece.Digest.String(),
+					TrafficType: &mockPiece.TrafficType,
+					Cost:        durationpb.New(-1 * time.Second),
+					CreatedAt:   timestamppb.New(mockPiece.CreatedAt),
+				},
+			},
+			run: func(t *testing.T, svc *V2, req *schedulerv2.DownloadPieceFinishedRequest, peer *resource.Peer, peerManager resource.PeerManager, mr *resource.MockResourceMockRecorder, mp *resource.MockPeerManagerMockRecorder) {
+				mr.PeerManager().Return(peerManager).Times(2)
+				mp.Load(gomock.Eq(peer.ID)).Return(peer, true).Times(2)
+
+				assert := assert.New(t)
+				assert.NoError(svc.handleDownloadPieceFinishedRequest(peer.ID, req))
+				assert.False(peer.Quarantined.Load())
+
+				req.Piece.Cost = durationpb.New(2 * time.Hour)
+				assert.NoError(svc.handleDownloadPieceFinishedRequest(peer.ID, req))
+				assert.True(peer.Quarantined.Load())
+
+				_, loaded := peer.LoadPiece(int32(req.Piece.Number))
+				assert.False(loaded)
+				assert.Equal(peer.FinishedPieces.Count(), uint(0))
+				assert.Equal(len(peer.PieceCosts()), 0)
+			},
+		},
 		{
 			name: "parent can not be loaded",
 			req: &schedulerv2.DownloadPieceFinishedRequest{
@@ -2799,7 +2965,7 @@ func TestServiceV2_handleDownloadPieceFailedRequest(t *testing.T) {
 				assert := assert.New(t)
 				assert.NoError(svc.handleDownloadPieceFailedRequest(context.Background(), peer.ID, req))
 				assert.NotEqual(peer.UpdatedAt.Load(), 0)
-				assert.True(peer.BlockParents.Contains(req.GetParentId()))
+				assert.True(peer.LoadBlockParents().Contains(req.GetParentId()))
 				assert.NotEqual(peer.Task.UpdatedAt.Load(), 0)
 			},
 		},
@@ -2821,7 +2987,7 @@ func TestServiceV2_handleDownloadPieceFailedRequest(t *testing.T) {
 				assert := assert.New(t)
 				assert.NoError(svc.handleDownloadPieceFailedRequest(context.Background(), peer.ID, req))
 				assert.NotEqual(peer.UpdatedAt.Load(), 0)
-				assert.True(peer.BlockParents.Contains(req.GetParentId()))
+				assert.True(peer.LoadBlockParents().Contains(req.GetParentId()))
 				assert.NotEqual(peer.Task.UpdatedAt.Load(), 0)
 				assert.Equal(peer.Host.UploadFailedCount.Load(), int64(1))
 			},
@@ -2944,6 +3110,23 @@ func TestServiceV2_handleResource(t *testing.T) {
 				assert.ErrorIs(err, status.Errorf(codes.NotFound, "host %s not found", mockHost.ID))
 			},
 		},
+		{
+			name:     "peer is evicted",
+			download: &commonv2.Download{},
+			run: func(t *testing.T, svc *V2, download *commonv2.Download, stream schedulerv2.Scheduler_AnnouncePeerServer, mockHost *resource.Host, mockTask *resource.Task, mockPeer *resource.Peer,
+				hostManager resource.HostManager, taskManager resource.TaskManager, peerManager resource.PeerManager, mr *resource.MockResourceMockRecorder, mh *resource.MockHostManagerMockRecorder,
+				mt *resource.MockTaskManagerMockRecorder, mp *resource.MockPeerManagerMockRecorder) {
+				mockHost.EvictPeer(mockPeer.ID)
+				gomock.InOrder(
+					mr.HostManager().Return(hostManager).Times(1),
+					mh.Load(gomock.Eq(mockHost.ID)).Return(mockHost, true).Times(1),
+				)
+
+				assert := assert.New(t)
+				_, _, _, err := svc.handleResource(context.Background(), stream, mockHost.ID, mockTask.ID, mockPeer.ID, download)
+				assert.ErrorIs(err, status.Errorf(codes.PermissionDenied, "peer %s is evicted", mockPeer.ID))
+			},
+		},
 		{
 			name: "task can be loaded",
 			download: &commonv2.Download{
@@ -3125,6 +3308,7 @@ func TestServiceV2_handleResource(t *testing.T) {
 			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
 			mockPeer := resource.NewPeer(mockPeerID, mockResourceConfig, mockTask, mockHost)
 			svc := NewV2(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+			dynconfig.EXPECT().GetSchedulerClusterConfig().Return(managertypes.SchedulerClusterConfig{}, errors.New("foo")).AnyTimes()
 
 			tc.run(t, svc, tc.download, stream, mockHost, mockTask, mockPeer, hostManager, taskManager, peerManager, res.EXPECT(), hostManager.EXPECT(), taskManager.EXPECT(), peerManager.EXPECT())
 		})
@@ -3407,3 +3591,70 @@ func TestServiceV2_downloadTaskBySeedPeer(t *testing.T) {
 		})
 	}
 }
+
+func TestServiceV2_sizeScope(t *testing.T) {
+	tests := []struct {
+		name          string
+		contentLength int64
+		mock          func(md *configmocks.MockDynconfigInterfaceMockRecorder)
+		expect        func(t *testing.T, sizeScope commonv2.SizeScope)
+	}{
+		{
+			name:          "content length is greater than tiny file size limit",
+			contentLength: 256 * 1024,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(managertypes.SchedulerClusterConfig{}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv2.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv2.SizeScope_SMALL, sizeScope)
+			},
+		},
+		{
+			name:          "content length is equal to tiny file size limit of application",
+			contentLength: 256 * 1024,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(managertypes.SchedulerClusterConfig{
+					SizeScopeApplications: []managertypes.SizeScopeApplication{{Name: mockTaskApplication, TinyFileSizeLimit: 256 * 1024}},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv2.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv2.SizeScope_TINY, sizeScope)
+			},
+		},
+		{
+			name:          "content length is greater than small file size limit of application",
+			contentLength: 1024*1024 + 1,
+			mock: func(md *configmocks.MockDynconfigInterfaceMockRecorder) {
+				md.GetSchedulerClusterConfig().Return(managertypes.SchedulerClusterConfig{
+					SizeScopeApplications: []managertypes.SizeScopeApplication{{Name: mockTaskApplication, SmallFileSizeLimit: 1024 * 1024}},
+				}, nil).Times(1)
+			},
+			expect: func(t *testing.T, sizeScope commonv2.SizeScope) {
+				assert := assert.New(t)
+				assert.Equal(commonv2.SizeScope_NORMAL, sizeScope)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctl := gomock.NewController(t)
+			defer ctl.Finish()
+			scheduling := schedulingmocks.NewMockScheduling(ctl)
+			res := resource.NewMockResource(ctl)
+			dynconfig := configmocks.NewMockDynconfigInterface(ctl)
+			storage := storagemocks.NewMockStorage(ctl)
+			networkTopology := networktopologymocks.NewMockNetworkTopology(ctl)
+			svc := NewV2(&config.Config{Scheduler: mockSchedulerConfig}, res, scheduling, dynconfig, storage, networkTopology)
+
+			mockTask := resource.NewTask(mockTaskID, mockTaskURL, mockTaskTag, mockTaskApplication, commonv2.TaskType_DFDAEMON, mockTaskFilteredQueryParams, mockTaskHeader, mockTaskBackToSourceLimit, resource.WithDigest(mockTaskDigest), resource.WithPieceLength(mockTaskPieceLength))
+			mockTask.ContentLength.Store(tc.contentLength)
+			mockTask.TotalPieceCount.Store(1)
+
+			tc.mock(dynconfig.EXPECT())
+			tc.expect(t, svc.sizeScope(mockTask))
+		})
+	}
+}
@@ -12,6 +12,7 @@ package mocks
 import (
 	io "io"
 	reflect "reflect"
+	time "time"
 
 	storage "d7y.io/dragonfly/v2/scheduler/storage"
 	gomock "go.uber.org/mock/gomock"
@@ -54,6 +55,20 @@ func (mr *MockStorageMockRecorder) ClearDownload() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearDownload", reflect.TypeOf((*MockStorage)(nil).ClearDownload))
 }
 
+// ClearDownloadBefore mocks base method.
+func (m *MockStorage) ClearDownloadBefore(arg0 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearDownloadBefore", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearDownloadBefore indicates an expected call of ClearDownloadBefore.
+func (mr *MockStorageMockRecorder) ClearDownloadBefore(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearDownloadBefore", reflect.TypeOf((*MockStorage)(nil).ClearDownloadBefore), arg0)
+}
+
 // ClearNetworkTopology mocks base method.
 func (m *MockStorage) ClearNetworkTopology() error {
 	m.ctrl.T.Helper()
@@ -68,6 +83,20 @@ func (mr *MockStorageMockRecorder) ClearNetworkTopology() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearNetworkTopology", reflect.TypeOf((*MockStorage)(nil).ClearNetworkTopology))
 }
 
+// ClearNetworkTopologyBefore mocks base method.
+func (m *MockStorage) ClearNetworkTopologyBefore(arg0 time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClearNetworkTopologyBefore", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ClearNetworkTopologyBefore indicates an expected call of ClearNetworkTopologyBefore.
+func (mr *MockStorageMockRecorder) ClearNetworkTopologyBefore(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearNetworkTopologyBefore", reflect.TypeOf((*MockStorage)(nil).ClearNetworkTopologyBefore), arg0)
+}
+
 // CreateDownload mocks base method.
 func (m *MockStorage) CreateDownload(arg0 storage.Download) error {
 	m.ctrl.T.Helper()
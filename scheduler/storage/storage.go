@@ -19,6 +19,10 @@
 package storage
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
@@ -31,6 +35,7 @@ import (
 	"time"
 
 	"github.com/gocarina/gocsv"
+	"golang.org/x/sync/errgroup"
 
 	logger "d7y.io/dragonfly/v2/internal/dflog"
 	pkgio "d7y.io/dragonfly/v2/pkg/io"
@@ -53,8 +58,14 @@ const (
 
 	// backupTimeFormat is the timestamp format of backup filename.
 	backupTimeFormat = "2006-01-02T15-04-05.000"
+
+	// defaultListConcurrency is the default number of files read concurrently by list.
+	defaultListConcurrency = 4
 )
 
+// gzipMagic is the magic bytes of the gzip file header.
+var gzipMagic = []byte{0x1f, 0x8b}
+
 // Storage is the interface used for storage.
 type Storage interface {
 	// CreateDownload inserts the download into csv file.
@@ -84,8 +95,16 @@ type Storage interface {
 	// ClearDownload removes all download files.
 	ClearDownload() error
 
+	// ClearDownloadBefore removes download backup files modified before the time,
+	// the active download file is never removed.
+	ClearDownloadBefore(time.Time) error
+
 	// ClearNetworkTopology removes all network topology files.
 	ClearNetworkTopology() error
+
+	// ClearNetworkTopologyBefore removes network topology backup files modified before the time,
+	// the active network topology file is never removed.
+	ClearNetworkTopologyBefore(time.Time) error
 }
 
 // storage provides storage function.
@@ -95,6 +114,9 @@ type storage struct {
 	maxBackups int
 	bufferSize int
 
+	// listConcurrency is the number of files read concurrently by list.
+	listConcurrency int
+
 	downloadMu       *sync.RWMutex
 	downloadFilename string
 	downloadBuffer   []Download
@@ -106,13 +128,25 @@ type storage struct {
 	networkTopologyCount    int64
 }
 
+// Option is a functional option for storage.
+type Option func(s *storage)
+
+// WithListConcurrency sets the number of files read concurrently by list,
+// the files are read sequentially if it is less than or equal to 1.
+func WithListConcurrency(concurrency int) Option {
+	return func(s *storage) {
+		s.listConcurrency = concurrency
+	}
+}
+
 // New returns a new Storage instance.
-func New(baseDir string, maxSize, maxBackups, bufferSize int) (Storage, error) {
+func New(baseDir string, maxSize, maxBackups, bufferSize int, options ...Option) (Storage, error) {
 	s := &storage{
-		baseDir:    baseDir,
-		maxSize:    int64(maxSize * megabyte),
-		maxBackups: maxBackups,
-		bufferSize: bufferSize,
+		baseDir:         baseDir,
+		maxSize:         int64(maxSize * megabyte),
+		maxBackups:      maxBackups,
+		bufferSize:      bufferSize,
+		listConcurrency: defaultListConcurrency,
 
 		downloadMu:       &sync.RWMutex{},
 		downloadFilename: filepath.Join(baseDir, fmt.Sprintf("%s.%s", DownloadFilePrefix, CSVFileExt)),
@@ -123,6 +157,10 @@ func New(baseDir string, maxSize, maxBackups, bufferSize int) (Storage, error) {
 		networkTopologyBuffer:   make([]NetworkTopology, 0, bufferSize),
 	}
 
+	for _, opt := range options {
+		opt(s)
+	}
+
 	downloadFile, err := os.OpenFile(s.downloadFilename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return nil, err
@@ -216,32 +254,7 @@ func (s *storage) ListDownload() ([]Download, error) {
 		return nil, err
 	}
 
-	var readers []io.Reader
-	var readClosers []io.ReadCloser
-	defer func() {
-		for _, readCloser := range readClosers {
-			if err := readCloser.Close(); err != nil {
-				logger.Error(err)
-			}
-		}
-	}()
-
-	for _, fileInfo := range fileInfos {
-		file, err := os.Open(filepath.Join(s.baseDir, fileInfo.Name()))
-		if err != nil {
-			return nil, err
-		}
-
-		readers = append(readers, file)
-		readClosers = append(readClosers, file)
-	}
-
-	var downloads []Download
-	if err := gocsv.UnmarshalWithoutHeaders(io.MultiReader(readers...), &downloads); err != nil {
-		return nil, err
-	}
-
-	return downloads, nil
+	return listRecords[Download](s.baseDir, fileInfos, s.listConcurrency)
 }
 
 // ListNetworkTopology returns all network topologies in csv file.
@@ -254,32 +267,7 @@ func (s *storage) ListNetworkTopology() ([]NetworkTopology, error) {
 		return nil, err
 	}
 
-	var readers []io.Reader
-	var readClosers []io.ReadCloser
-	defer func() {
-		for _, readCloser := range readClosers {
-			if err := readCloser.Close(); err != nil {
-				logger.Error(err)
-			}
-		}
-	}()
-
-	for _, fileInfo := range fileInfos {
-		file, err := os.Open(filepath.Join(s.baseDir, fileInfo.Name()))
-		if err != nil {
-			return nil, err
-		}
-
-		readers = append(readers, file)
-		readClosers = append(readClosers, file)
-	}
-
-	var networkTopologies []NetworkTopology
-	if err := gocsv.UnmarshalWithoutHeaders(io.MultiReader(readers...), &networkTopologies); err != nil {
-		return nil, err
-	}
-
-	return networkTopologies, nil
+	return listRecords[NetworkTopology](s.baseDir, fileInfos, s.listConcurrency)
 }
 
 // DownloadCount returns the count of downloads.
@@ -348,14 +336,21 @@ func (s *storage) ClearDownload() error {
 		return err
 	}
 
-	for _, fileInfo := range fileInfos {
-		filename := filepath.Join(s.baseDir, fileInfo.Name())
-		if err := os.Remove(filename); err != nil {
-			return err
-		}
+	return s.removeFiles(fileInfos)
+}
+
+// ClearDownloadBefore removes download backup files modified before the time,
+// the active download file is never removed.
+func (s *storage) ClearDownloadBefore(t time.Time) error {
+	s.downloadMu.Lock()
+	defer s.downloadMu.Unlock()
+
+	fileInfos, err := s.downloadBackups()
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return s.removeFiles(filterBackupsBefore(fileInfos, filepath.Base(s.downloadFilename), t))
 }
 
 // ClearNetworkTopology removes all network topologies.
@@ -368,13 +363,38 @@ func (s *storage) ClearNetworkTopology() error {
 		return err
 	}
 
+	return s.removeFiles(fileInfos)
+}
+
+// ClearNetworkTopologyBefore removes network topology backup files modified before the time,
+// the active network topology file is never removed.
+func (s *storage) ClearNetworkTopologyBefore(t time.Time) error {
+	s.networkTopologyMu.Lock()
+	defer s.networkTopologyMu.Unlock()
+
+	fileInfos, err := s.networkTopologyBackups()
+	if err != nil {
+		return err
+	}
+
+	return s.removeFiles(filterBackupsBefore(fileInfos, filepath.Base(s.networkTopologyFilename), t))
+}
+
+// removeFiles removes the files in base directory, it continues past the files which
+// can not be removed and returns the aggregated error of them.
+func (s *storage) removeFiles(fileInfos []fs.FileInfo) error {
+	var errs []error
 	for _, fileInfo := range fileInfos {
 		filename := filepath.Join(s.baseDir, fileInfo.Name())
 		if err := os.Remove(filename); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
 
+	if len(errs) > 0 {
+		return fmt.Errorf("remove %d of %d files failed: %w", len(errs), len(fileInfos), errors.Join(errs...))
+	}
+
 	return nil
 }
 
@@ -390,7 +410,7 @@ func (s *storage) createDownload(downloads ...Download) (err error) {
 		}
 	}()
 
-	return gocsv.MarshalWithoutHeaders(downloads, file)
+	return writeRecords(file, downloads)
 }
 
 // createNetworkTopology inserts the network topologies into csv file.
@@ -405,7 +425,7 @@ func (s *storage) createNetworkTopology(networkTopologies ...NetworkTopology) (e
 		}
 	}()
 
-	return gocsv.MarshalWithoutHeaders(networkTopologies, file)
+	return writeRecords(file, networkTopologies)
 }
 
 // openDownloadFile opens the download file and removes download files that exceed the total size.
@@ -539,3 +559,267 @@ func (s *storage) networkTopologyBackups() ([]fs.FileInfo, error) {
 
 	return backups, nil
 }
+
+// listRecords reads the records of the files with at most concurrency workers. Each file is decoded
+// into its own slice, then the slices are merged in the order of files which are sorted by modification time.
+func listRecords[T any](baseDir string, fileInfos []fs.FileInfo, concurrency int) ([]T, error) {
+	// The empty files are skipped, e.g. the active file after rotation.
+	var filenames []string
+	for _, fileInfo := range fileInfos {
+		if fileInfo.Size() > 0 {
+			filenames = append(filenames, filepath.Join(baseDir, fileInfo.Name()))
+		}
+	}
+
+	if len(filenames) == 0 {
+		return nil, gocsv.ErrEmptyCSVFile
+	}
+
+	records := make([][]T, len(filenames))
+	if len(filenames) == 1 || concurrency <= 1 {
+		for i, filename := range filenames {
+			var err error
+			if records[i], err = readRecords[T](filename); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		eg := errgroup.Group{}
+		eg.SetLimit(concurrency)
+		for i, filename := range filenames {
+			i, filename := i, filename
+			eg.Go(func() error {
+				var err error
+				records[i], err = readRecords[T](filename)
+				return err
+			})
+		}
+
+		if err := eg.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	var count int
+	for _, r := range records {
+		count += len(r)
+	}
+
+	// The files only contain the torn records.
+	if count == 0 {
+		return nil, gocsv.ErrEmptyCSVFile
+	}
+
+	merged := make([]T, 0, count)
+	for _, r := range records {
+		merged = append(merged, r...)
+	}
+
+	return merged, nil
+}
+
+// readRecords decodes the records of the csv file, the torn final record
+// left by the interrupted write is skipped. The file is decompressed if it
+// starts with the gzip magic bytes, so the plain and gzip backups can be
+// listed together.
+func readRecords[T any](filename string) ([]T, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			logger.Error(err)
+		}
+	}()
+
+	bufReader := bufio.NewReader(file)
+	var src io.Reader = bufReader
+	if magic, err := bufReader.Peek(len(gzipMagic)); err == nil && bytes.Equal(magic, gzipMagic) {
+		gzipReader, err := gzip.NewReader(bufReader)
+		if err != nil {
+			return nil, err
+		}
+		defer gzipReader.Close()
+
+		src = gzipReader
+	}
+
+	reader := newRecordReader(src)
+	var records []T
+	if err := gocsv.UnmarshalCSVWithoutHeaders(reader, &records); err != nil {
+		if reader.torn && errors.Is(err, gocsv.ErrEmptyCSVFile) {
+			logger.Warnf("skip the torn record of %s", filename)
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	if reader.torn {
+		logger.Warnf("skip the torn record of %s", filename)
+	}
+
+	return records, nil
+}
+
+// writeRecords encodes the records and appends them to the file by one write. If the write fails,
+// the file is truncated back to its original size, so the partial records are not followed by
+// the next write. The file is synced before return, so the records survive the crash.
+func writeRecords[T any](file *os.File, records []T) error {
+	var buf bytes.Buffer
+	if err := gocsv.MarshalWithoutHeaders(records, &buf); err != nil {
+		return err
+	}
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		if terr := file.Truncate(fileInfo.Size()); terr != nil {
+			return errors.Join(err, terr)
+		}
+
+		return err
+	}
+
+	return file.Sync()
+}
+
+// recordReader reads the csv records and detects the torn final record. Every record written by
+// csv.Writer ends with the newline, so the final record is torn if the stream does not end with the
+// newline, or it can not be parsed and no record follows it. The next record is read ahead to know
+// whether the record is the final one, as the size of the decompressed stream is unknown.
+type recordReader struct {
+	*csv.Reader
+
+	// src is the source of csv.Reader.
+	src *tailReader
+
+	// next is the record read ahead.
+	next []string
+
+	// nextErr is the error of the record read ahead.
+	nextErr error
+
+	// peeked is whether the next record is read ahead.
+	peeked bool
+
+	// torn is whether the torn final record is skipped.
+	torn bool
+}
+
+// newRecordReader returns a new recordReader of the stream.
+func newRecordReader(r io.Reader) *recordReader {
+	src := &tailReader{Reader: r}
+	return &recordReader{Reader: csv.NewReader(src), src: src}
+}
+
+// Read reads a record, it returns io.EOF instead of the torn final record.
+func (r *recordReader) Read() ([]string, error) {
+	record, err := r.read()
+	if err == io.EOF {
+		return nil, err
+	}
+
+	if err != nil {
+		// The record which can not be parsed is torn if it is the final one.
+		if _, nextErr := r.read(); nextErr == io.EOF {
+			r.torn = true
+			return nil, io.EOF
+		}
+
+		return nil, err
+	}
+
+	if _, nextErr := r.peek(); nextErr == io.EOF && !r.src.terminated() {
+		r.torn = true
+		return nil, io.EOF
+	}
+
+	return record, nil
+}
+
+// ReadAll reads the remaining records.
+func (r *recordReader) ReadAll() ([][]string, error) {
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+}
+
+// read returns the record read ahead if it exists, otherwise it reads a record.
+func (r *recordReader) read() ([]string, error) {
+	if r.peeked {
+		r.peeked = false
+		return r.next, r.nextErr
+	}
+
+	return r.Reader.Read()
+}
+
+// peek reads ahead the next record.
+func (r *recordReader) peek() ([]string, error) {
+	if !r.peeked {
+		r.next, r.nextErr = r.Reader.Read()
+		r.peeked = true
+	}
+
+	return r.next, r.nextErr
+}
+
+// tailReader records the last byte of the stream. The truncated gzip stream
+// ends like the plain file left by the interrupted write, so the partial
+// record is detected as the torn final record.
+type tailReader struct {
+	io.Reader
+
+	// last is the last byte read.
+	last byte
+}
+
+// Read reads the stream and records the last byte.
+func (t *tailReader) Read(p []byte) (int, error) {
+	n, err := t.Reader.Read(p)
+	if n > 0 {
+		t.last = p[n-1]
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+
+	return n, err
+}
+
+// terminated returns whether the stream read ends with the newline.
+func (t *tailReader) terminated() bool {
+	return t.last == '\n'
+}
+
+// filterBackupsBefore returns the backup files modified before the time, excluding the active file.
+func filterBackupsBefore(fileInfos []fs.FileInfo, activeFilename string, t time.Time) []fs.FileInfo {
+	var backups []fs.FileInfo
+	for _, fileInfo := range fileInfos {
+		if fileInfo.Name() == activeFilename {
+			continue
+		}
+
+		if fileInfo.ModTime().Before(t) {
+			backups = append(backups, fileInfo)
+		}
+	}
+
+	return backups
+}
@@ -17,6 +17,7 @@
 package storage
 
 import (
+	"compress/gzip"
 	"fmt"
 	"io/fs"
 	"os"
@@ -522,6 +523,39 @@ func TestStorage_ListDownload(t *testing.T) {
 				assert.Equal(downloads[1].ID, "1")
 			},
 		},
+		{
+			name:       "list downloads with the torn final record",
+			baseDir:    os.TempDir(),
+			bufferSize: 0,
+			download:   mockDownload,
+			mock: func(t *testing.T, s Storage, baseDir string, download Download) {
+				for _, id := range []string{"1", "2", "3"} {
+					download.ID = id
+					if err := s.CreateDownload(download); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				// Truncate the file in the middle of the final record.
+				filename := filepath.Join(baseDir, "download.csv")
+				fileInfo, err := os.Stat(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.Truncate(filename, fileInfo.Size()-10); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, s Storage, baseDir string, download Download) {
+				assert := assert.New(t)
+				downloads, err := s.ListDownload()
+				assert.NoError(err)
+				assert.Equal([]string{"1", "2"}, downloadIDs(downloads))
+				assert.EqualValues(downloads[1].Task, download.Task)
+				assert.EqualValues(downloads[1].Host, download.Host)
+			},
+		},
 	}
 
 	for _, tc := range tests {
@@ -1071,6 +1105,479 @@ func TestStorage_ClearNetworkTopology(t *testing.T) {
 	}
 }
 
+func TestStorage_ClearDownloadBefore(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(t *testing.T, s Storage, baseDir string, before time.Time)
+		expect func(t *testing.T, s Storage, baseDir string, before time.Time)
+	}{
+		{
+			name: "clear backups modified before the time",
+			mock: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				mockBackupFile(t, baseDir, "download_old.csv", before.Add(-time.Hour))
+				mockBackupFile(t, baseDir, "download_new.csv", before.Add(time.Hour))
+			},
+			expect: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				assert := assert.New(t)
+				assert.NoError(s.ClearDownloadBefore(before))
+				assert.NoFileExists(filepath.Join(baseDir, "download_old.csv"))
+				assert.FileExists(filepath.Join(baseDir, "download_new.csv"))
+				assert.FileExists(s.(*storage).downloadFilename)
+			},
+		},
+		{
+			name: "active file modified before the time survives",
+			mock: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				if err := os.Chtimes(s.(*storage).downloadFilename, before.Add(-time.Hour), before.Add(-time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				assert := assert.New(t)
+				assert.NoError(s.ClearDownloadBefore(before))
+				assert.FileExists(s.(*storage).downloadFilename)
+				assert.NoError(s.CreateDownload(Download{}))
+			},
+		},
+		{
+			name: "backup modified at the time survives",
+			mock: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				mockBackupFile(t, baseDir, "download_old.csv", before)
+			},
+			expect: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				assert := assert.New(t)
+				assert.NoError(s.ClearDownloadBefore(before))
+				assert.FileExists(filepath.Join(baseDir, "download_old.csv"))
+			},
+		},
+		{
+			name: "continue past the backups which can not be removed",
+			mock: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				if os.Geteuid() == 0 {
+					t.Skip("root can remove files in read-only directory")
+				}
+
+				mockBackupFile(t, baseDir, "download_old.csv", before.Add(-time.Hour))
+				if err := os.Chmod(baseDir, 0500); err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { os.Chmod(baseDir, 0700) })
+			},
+			expect: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				assert := assert.New(t)
+				err := s.ClearDownloadBefore(before)
+				assert.ErrorContains(err, "remove 1 of 1 files failed")
+				assert.ErrorContains(err, "download_old.csv")
+				assert.FileExists(filepath.Join(baseDir, "download_old.csv"))
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			s, err := New(baseDir, config.DefaultStorageMaxSize, config.DefaultStorageMaxBackups, config.DefaultStorageBufferSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			before := time.Now().Add(-time.Minute)
+			tc.mock(t, s, baseDir, before)
+			tc.expect(t, s, baseDir, before)
+		})
+	}
+}
+
+func TestStorage_ClearNetworkTopologyBefore(t *testing.T) {
+	tests := []struct {
+		name   string
+		mock   func(t *testing.T, s Storage, baseDir string, before time.Time)
+		expect func(t *testing.T, s Storage, baseDir string, before time.Time)
+	}{
+		{
+			name: "clear backups modified before the time",
+			mock: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				mockBackupFile(t, baseDir, "networktopology_old.csv", before.Add(-time.Hour))
+				mockBackupFile(t, baseDir, "networktopology_new.csv", before.Add(time.Hour))
+			},
+			expect: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				assert := assert.New(t)
+				assert.NoError(s.ClearNetworkTopologyBefore(before))
+				assert.NoFileExists(filepath.Join(baseDir, "networktopology_old.csv"))
+				assert.FileExists(filepath.Join(baseDir, "networktopology_new.csv"))
+				assert.FileExists(s.(*storage).networkTopologyFilename)
+			},
+		},
+		{
+			name: "active file modified before the time survives",
+			mock: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				if err := os.Chtimes(s.(*storage).networkTopologyFilename, before.Add(-time.Hour), before.Add(-time.Hour)); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, s Storage, baseDir string, before time.Time) {
+				assert := assert.New(t)
+				assert.NoError(s.ClearNetworkTopologyBefore(before))
+				assert.FileExists(s.(*storage).networkTopologyFilename)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			s, err := New(baseDir, config.DefaultStorageMaxSize, config.DefaultStorageMaxBackups, config.DefaultStorageBufferSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			before := time.Now().Add(-time.Minute)
+			tc.mock(t, s, baseDir, before)
+			tc.expect(t, s, baseDir, before)
+		})
+	}
+}
+
+func mockBackupFile(t *testing.T, baseDir, name string, modTime time.Time) {
+	filename := filepath.Join(baseDir, name)
+	if err := os.WriteFile(filename, []byte{}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorage_listRecords(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		mock        func(t *testing.T, baseDir string)
+		expect      func(t *testing.T, downloads []Download, err error)
+	}{
+		{
+			name:        "list records sequentially",
+			concurrency: 1,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 20, 3)
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockRecordIDs(20, 3), downloadIDs(downloads))
+			},
+		},
+		{
+			name:        "list records concurrently",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 20, 3)
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockRecordIDs(20, 3), downloadIDs(downloads))
+			},
+		},
+		{
+			name:        "list records with more workers than files",
+			concurrency: 32,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 5, 2)
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockRecordIDs(5, 2), downloadIDs(downloads))
+			},
+		},
+		{
+			name:        "skip empty files",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 2, 2)
+				mockBackupFile(t, baseDir, "download.csv", time.Now())
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockRecordIDs(2, 2), downloadIDs(downloads))
+			},
+		},
+		{
+			name:        "all files are empty",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				mockBackupFile(t, baseDir, "download.csv", time.Now())
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, gocsv.ErrEmptyCSVFile)
+			},
+		},
+		{
+			name:        "skip the torn final record",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 2, 3)
+				filename := filepath.Join(baseDir, fmt.Sprintf("%s_%03d.%s", DownloadFilePrefix, 1, CSVFileExt))
+				fileInfo, err := os.Stat(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if err := os.Truncate(filename, fileInfo.Size()-5); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal([]string{"0-0", "0-1", "0-2", "1-0", "1-1"}, downloadIDs(downloads))
+			},
+		},
+		{
+			name:        "skip the torn final record in quoted field",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 2, 2)
+				file, err := os.OpenFile(filepath.Join(baseDir, fmt.Sprintf("%s_%03d.%s", DownloadFilePrefix, 1, CSVFileExt)), os.O_WRONLY|os.O_APPEND, 0600)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer file.Close()
+
+				if _, err := file.WriteString("\"foo\n"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockRecordIDs(2, 2), downloadIDs(downloads))
+			},
+		},
+		{
+			name:        "all files only contain the torn record",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				if err := os.WriteFile(filepath.Join(baseDir, "download.csv"), []byte("\"foo\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.ErrorIs(err, gocsv.ErrEmptyCSVFile)
+			},
+		},
+		{
+			name:        "list mixed plain and gzip files",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 6, 2)
+				for i := 1; i <= 6; i += 2 {
+					gzipRecordFile(t, filepath.Join(baseDir, fmt.Sprintf("%s_%03d.%s", DownloadFilePrefix, i, CSVFileExt)))
+				}
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockRecordIDs(6, 2), downloadIDs(downloads))
+			},
+		},
+		{
+			name:        "skip the torn final record of gzip file",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 2, 2)
+
+				// The gzip writer is interrupted after the partial record is flushed.
+				file, err := os.Create(filepath.Join(baseDir, fmt.Sprintf("%s_%03d.%s.gz", DownloadFilePrefix, 0, CSVFileExt)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer file.Close()
+
+				gzipWriter := gzip.NewWriter(file)
+				if err := gocsv.MarshalWithoutHeaders([]Download{{ID: "2-0"}, {ID: "2-1"}}, gzipWriter); err != nil {
+					t.Fatal(err)
+				}
+
+				if _, err := gzipWriter.Write([]byte("2-2,")); err != nil {
+					t.Fatal(err)
+				}
+
+				if err := gzipWriter.Flush(); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.NoError(err)
+				assert.Equal(mockRecordIDs(3, 2), downloadIDs(downloads))
+			},
+		},
+		{
+			name:        "decode file failed",
+			concurrency: 4,
+			mock: func(t *testing.T, baseDir string) {
+				mockRecordFiles(t, baseDir, 10, 2)
+				// The record which can not be parsed is followed by another record, so it is not torn.
+				if err := os.WriteFile(filepath.Join(baseDir, "download.csv"), []byte("fo\"o\nbar\n"), 0600); err != nil {
+					t.Fatal(err)
+				}
+			},
+			expect: func(t *testing.T, downloads []Download, err error) {
+				assert := assert.New(t)
+				assert.Error(err)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			tc.mock(t, baseDir)
+
+			s := &storage{baseDir: baseDir}
+			fileInfos, err := s.downloadBackups()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			downloads, err := listRecords[Download](baseDir, fileInfos, tc.concurrency)
+			tc.expect(t, downloads, err)
+		})
+	}
+}
+
+func BenchmarkStorage_ListDownload(b *testing.B) {
+	for _, concurrency := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("concurrency %d", concurrency), func(b *testing.B) {
+			baseDir := b.TempDir()
+			s, err := New(baseDir, config.DefaultStorageMaxSize, config.DefaultStorageMaxBackups, config.DefaultStorageBufferSize, WithListConcurrency(concurrency))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			downloads := make([]Download, 500)
+			for i := range downloads {
+				downloads[i] = mockDownload
+			}
+
+			modTime := time.Now().Add(-time.Hour)
+			for i := 0; i < 32; i++ {
+				filename := filepath.Join(baseDir, fmt.Sprintf("%s_%d.%s", DownloadFilePrefix, i, CSVFileExt))
+				if err := writeRecordFile(filename, modTime.Add(time.Duration(i)*time.Second), downloads); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := s.ListDownload(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// mockRecordFiles writes the download backup files whose modification time is in the order of file index,
+// the ids of downloads are in format of file index-record index.
+func mockRecordFiles(t *testing.T, baseDir string, fileCount, recordCount int) {
+	modTime := time.Now().Add(-time.Hour)
+	for i := 0; i < fileCount; i++ {
+		var downloads []Download
+		for j := 0; j < recordCount; j++ {
+			downloads = append(downloads, Download{ID: fmt.Sprintf("%d-%d", i, j)})
+		}
+
+		// The names are in reverse order of modification time to make sure the files are sorted by modification time.
+		filename := filepath.Join(baseDir, fmt.Sprintf("%s_%03d.%s", DownloadFilePrefix, fileCount-i, CSVFileExt))
+		if err := writeRecordFile(filename, modTime.Add(time.Duration(i)*time.Second), downloads); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// mockRecordIDs returns the ids of downloads written by mockRecordFiles.
+func mockRecordIDs(fileCount, recordCount int) []string {
+	var ids []string
+	for i := 0; i < fileCount; i++ {
+		for j := 0; j < recordCount; j++ {
+			ids = append(ids, fmt.Sprintf("%d-%d", i, j))
+		}
+	}
+
+	return ids
+}
+
+// writeRecordFile writes the records to the csv file and sets its modification time.
+func writeRecordFile[T any](filename string, modTime time.Time, records []T) error {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+
+	if err := gocsv.MarshalWithoutHeaders(records, file); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Chtimes(filename, modTime, modTime)
+}
+
+// gzipRecordFile replaces the csv file with the gzip file of the same modification time.
+func gzipRecordFile(t *testing.T, filename string) {
+	fileInfo, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Create(filename + ".gz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	if _, err := gzipWriter.Write(data); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Remove(filename); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(filename+".gz", fileInfo.ModTime(), fileInfo.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func downloadIDs(downloads []Download) []string {
+	var ids []string
+	for _, download := range downloads {
+		ids = append(ids, download.ID)
+	}
+
+	return ids
+}
+
 func TestStorage_createDownload(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -53,6 +53,9 @@ type Task struct {
 
 	// UpdatedAt is peer update nanosecond time.
 	UpdatedAt int64 `csv:"updatedAt"`
+
+	// FailureReason is the reason why the task is failed, it is empty if the task is not failed.
+	FailureReason string `csv:"failureReason"`
 }
 
 // Host contains content for host.
@@ -222,6 +225,13 @@ type Download struct {
 
 	// UpdatedAt is peer update nanosecond time.
 	UpdatedAt int64 `csv:"updatedAt"`
+
+	// Quarantined is whether the peer is quarantined for reporting impossible piece results.
+	// The columns are positional, so the new fields are appended at the end of the record.
+	Quarantined bool `csv:"quarantined"`
+
+	// ParentSwitchCount is the count of replacing the parent of the peer with a different one.
+	ParentSwitchCount int32 `csv:"parentSwitchCount"`
 }
 
 // Probes contains content for probes.